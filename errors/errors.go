@@ -179,4 +179,36 @@ var (
 	ErrInvalidBearerToken             = errors.New("invalid bearer token given")
 	ErrInsufficientScope              = errors.New("bearer token does not have sufficient scope")
 	ErrCouldNotLoadCertificate        = errors.New("failed to load certificate")
+	ErrInvalidAPIKeyScope             = errors.New("invalid api key scope")
+	ErrStorageQuotaExceeded           = errors.New("storage quota exceeded")
+	ErrImmutableTag                   = errors.New("tag is immutable")
+	ErrUnexpectedWebhookStatus        = errors.New("webhook returned an unexpected status code")
+	ErrTagHistoryNotFound             = errors.New("tag history not found")
+	ErrDigestNotInTagHistory          = errors.New("digest was never pointed to by the tag")
+	ErrTagHistoryConflict             = errors.New("tag history kept being updated concurrently")
+	ErrBearerKeyNotFound              = errors.New("no key found to verify the bearer token")
+	ErrInvalidTokenScope              = errors.New("invalid token scope")
+	ErrUnknownTokenService            = errors.New("token requested for an unknown service")
+	ErrInvalidTokenSigningKey         = errors.New("invalid token signing key")
+	ErrInvalidJWKS                    = errors.New("invalid json web key set")
+	ErrUnknownTokenIssuer             = errors.New("token signed by an unknown issuer")
+	ErrNoWorkloadIdentityRuleMatched  = errors.New("no workload identity rule matches the token claims")
+	ErrAuthzWebhookFailed             = errors.New("failed to get a decision from the authorization webhook")
+	ErrUntrustedProxy                 = errors.New("proxy authentication headers sent from an untrusted source")
+	ErrMissingProxyUserHeader         = errors.New("proxy authentication user header is missing")
+	ErrUnknownCertificateAttribute    = errors.New("unknown client certificate attribute")
+	ErrUnsupportedHashAlgorithm       = errors.New("unsupported password hash algorithm")
+	ErrPasswordMismatch               = errors.New("password does not match its hash")
+	ErrInvalidUsername                = errors.New("invalid username")
+	ErrUserAlreadyExists              = errors.New("user already exists")
+	ErrGroupNotFound                  = errors.New("group not found")
+	ErrHTPasswdNotConfigured          = errors.New("htpasswd authentication is not configured")
+	ErrGroupsFileNotConfigured        = errors.New("access control groups file is not configured")
+	ErrServiceAccountNotFound         = errors.New("service account not found")
+	ErrServiceAccountLogin            = errors.New("service accounts can not log in")
+	ErrServiceAccountAPIKey           = errors.New("service account api keys are managed by the admins")
+	ErrOpenIDSessionInvalid           = errors.New("openid session is no longer valid with its provider")
+	ErrInvalidAuditRecord             = errors.New("invalid audit record")
+	ErrAuditChainBroken               = errors.New("audit records hash chain is broken")
+	ErrUnexpectedAuditSinkStatus      = errors.New("audit collector returned an unexpected status code")
 )
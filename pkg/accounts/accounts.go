@@ -0,0 +1,350 @@
+package accounts
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	zlog "zotregistry.dev/zot/pkg/log"
+)
+
+// groupsFileEntry is a group of the groups file, in the format of the groups of the access control config.
+type groupsFileEntry struct {
+	Users []string `json:"users"`
+}
+
+// Manager manages the users of the htpasswd file and the access control groups of the groups file.
+// Every change is written atomically to these files, applied right away and recorded in the audit log.
+type Manager struct {
+	config *config.Config
+	// reloads the users of the htpasswd file which authenticate the requests
+	reloadUsers func() error
+	audit       *zlog.Logger
+	log         zlog.Logger
+	lock        sync.Mutex
+}
+
+func NewManager(config *config.Config, reloadUsers func() error, audit *zlog.Logger, log zlog.Logger) *Manager {
+	return &Manager{
+		config:      config,
+		reloadUsers: reloadUsers,
+		audit:       audit,
+		log:         log,
+	}
+}
+
+// Users returns the sorted names of the users of the htpasswd file.
+func (m *Manager) Users() ([]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	lines, err := m.readHTPasswd()
+	if err != nil {
+		return nil, err
+	}
+
+	users := []string{}
+
+	for _, line := range lines {
+		if username, _, ok := strings.Cut(line, ":"); ok {
+			users = append(users, username)
+		}
+	}
+
+	slices.Sort(users)
+
+	return users, nil
+}
+
+// CreateUser adds a user to the htpasswd file on behalf of actor.
+func (m *Manager) CreateUser(actor, username, password string) error {
+	if err := ValidateUsername(username); err != nil {
+		return err
+	}
+
+	return m.updateHTPasswd(actor, "createUser", username, func(lines []string, index int) ([]string, error) {
+		if index >= 0 {
+			return nil, fmt.Errorf("%w: %s", zerr.ErrUserAlreadyExists, username)
+		}
+
+		hash, err := HashPassword(m.hashAlgorithm(), password)
+		if err != nil {
+			return nil, err
+		}
+
+		return append(lines, username+":"+hash), nil
+	})
+}
+
+// UpdatePassword replaces the password of a user of the htpasswd file on behalf of actor.
+func (m *Manager) UpdatePassword(actor, username, password string) error {
+	return m.updateHTPasswd(actor, "updatePassword", username, func(lines []string, index int) ([]string, error) {
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %s", zerr.ErrBadUser, username)
+		}
+
+		hash, err := HashPassword(m.hashAlgorithm(), password)
+		if err != nil {
+			return nil, err
+		}
+
+		lines[index] = username + ":" + hash
+
+		return lines, nil
+	})
+}
+
+// DeleteUser removes a user from the htpasswd file on behalf of actor.
+func (m *Manager) DeleteUser(actor, username string) error {
+	return m.updateHTPasswd(actor, "deleteUser", username, func(lines []string, index int) ([]string, error) {
+		if index < 0 {
+			return nil, fmt.Errorf("%w: %s", zerr.ErrBadUser, username)
+		}
+
+		return slices.Delete(lines, index, index+1), nil
+	})
+}
+
+// Groups returns the access control groups of the groups file.
+func (m *Manager) Groups() (config.Groups, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	accessControl, err := m.groupsAccessControl()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := config.Groups{}
+	maps.Copy(groups, accessControl.Groups)
+
+	return groups, nil
+}
+
+// SetGroup creates or replaces a group of the groups file on behalf of actor.
+func (m *Manager) SetGroup(actor, group string, users []string) error {
+	if group == "" {
+		return fmt.Errorf("%w: empty group name", zerr.ErrGroupNotFound)
+	}
+
+	members := []string{}
+
+	for _, user := range users {
+		if user != "" && !slices.Contains(members, user) {
+			members = append(members, user)
+		}
+	}
+
+	return m.updateGroups(actor, "setGroup", group, func(groups config.Groups) error {
+		groups[group] = config.Group{Users: members}
+
+		return nil
+	})
+}
+
+// DeleteGroup removes a group from the groups file on behalf of actor.
+func (m *Manager) DeleteGroup(actor, group string) error {
+	return m.updateGroups(actor, "deleteGroup", group, func(groups config.Groups) error {
+		if _, ok := groups[group]; !ok {
+			return fmt.Errorf("%w: %s", zerr.ErrGroupNotFound, group)
+		}
+
+		delete(groups, group)
+
+		return nil
+	})
+}
+
+// ValidateUsername returns ErrInvalidUsername if username can not be written to the htpasswd file.
+func ValidateUsername(username string) error {
+	if username == "" || strings.ContainsRune(username, ':') ||
+		strings.ContainsFunc(username, func(r rune) bool { return unicode.IsSpace(r) || unicode.IsControl(r) }) {
+		return fmt.Errorf("%w: %q", zerr.ErrInvalidUsername, username)
+	}
+
+	return nil
+}
+
+// LoadGroupsFile returns the groups of a groups file, none if it does not exist yet.
+func LoadGroupsFile(filePath string) (config.Groups, error) {
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return config.Groups{}, nil
+		}
+
+		return nil, err
+	}
+
+	entries := map[string]groupsFileEntry{}
+
+	if err := json.Unmarshal(content, &entries); err != nil {
+		return nil, fmt.Errorf("%w: invalid groups file %s: %w", zerr.ErrBadConfig, filePath, err)
+	}
+
+	groups := config.Groups{}
+
+	for name, entry := range entries {
+		groups[name] = config.Group{Users: entry.Users}
+	}
+
+	return groups, nil
+}
+
+func (m *Manager) hashAlgorithm() string {
+	return m.config.HTTP.Auth.HTPasswd.HashAlgorithm
+}
+
+func (m *Manager) htpasswdPath() (string, error) {
+	if m.config.HTTP.Auth == nil || m.config.HTTP.Auth.HTPasswd.Path == "" {
+		return "", zerr.ErrHTPasswdNotConfigured
+	}
+
+	return m.config.HTTP.Auth.HTPasswd.Path, nil
+}
+
+// readHTPasswd returns the lines of the htpasswd file.
+func (m *Manager) readHTPasswd() ([]string, error) {
+	filePath, err := m.htpasswdPath()
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	lines := []string{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+
+	return lines, scanner.Err()
+}
+
+// updateHTPasswd rewrites the htpasswd file with the lines returned by update, given the index of the line
+// of username, -1 if there is none.
+func (m *Manager) updateHTPasswd(actor, action, username string,
+	update func(lines []string, index int) ([]string, error),
+) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	lines, err := m.readHTPasswd()
+	if err != nil {
+		return err
+	}
+
+	index := slices.IndexFunc(lines, func(line string) bool {
+		lineUsername, _, ok := strings.Cut(line, ":")
+
+		return ok && lineUsername == username
+	})
+
+	lines, err = update(lines, index)
+	if err != nil {
+		return err
+	}
+
+	filePath, _ := m.htpasswdPath()
+
+	content := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		content += "\n"
+	}
+
+	if err := WriteFileAtomically(filePath, []byte(content)); err != nil {
+		m.log.Error().Err(err).Str("htpasswd-file", filePath).Msg("failed to write htpasswd file")
+
+		return err
+	}
+
+	m.auditLog(actor, action, username)
+
+	if err := m.reloadUsers(); err != nil {
+		m.log.Error().Err(err).Str("htpasswd-file", filePath).Msg("failed to reload htpasswd file")
+
+		return err
+	}
+
+	return nil
+}
+
+func (m *Manager) groupsAccessControl() (*config.AccessControlConfig, error) {
+	accessControl := m.config.HTTP.AccessControl
+	if accessControl == nil || accessControl.GroupsFile == "" {
+		return nil, zerr.ErrGroupsFileNotConfigured
+	}
+
+	return accessControl, nil
+}
+
+// updateGroups writes the groups changed by update to the groups file, and applies them to the access control.
+func (m *Manager) updateGroups(actor, action, group string, update func(groups config.Groups) error) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	accessControl, err := m.groupsAccessControl()
+	if err != nil {
+		return err
+	}
+
+	groups := config.Groups{}
+	maps.Copy(groups, accessControl.Groups)
+
+	if err := update(groups); err != nil {
+		return err
+	}
+
+	entries := map[string]groupsFileEntry{}
+	for name, group := range groups {
+		entries[name] = groupsFileEntry{Users: group.Users}
+	}
+
+	content, err := json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := WriteFileAtomically(accessControl.GroupsFile, append(content, '\n')); err != nil {
+		m.log.Error().Err(err).Str("groups-file", accessControl.GroupsFile).Msg("failed to write groups file")
+
+		return err
+	}
+
+	// the access control config is replaced, not modified, as the requests being authorized read it
+	newAccessControl := *accessControl
+	newAccessControl.Groups = groups
+	m.config.HTTP.AccessControl = &newAccessControl
+
+	m.auditLog(actor, action, group)
+
+	return nil
+}
+
+func (m *Manager) auditLog(actor, action, object string) {
+	m.log.Info().Str("actor", actor).Str("action", action).Str("object", object).Msg("updated accounts")
+
+	if m.audit != nil {
+		m.audit.Info().
+			Str("component", "mgmt").
+			Str("subject", actor).
+			Str("action", action).
+			Str("object", object).
+			Msg("HTTP API Audit")
+	}
+}
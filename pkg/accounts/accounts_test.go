@@ -0,0 +1,236 @@
+package accounts_test
+
+import (
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/accounts"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+func TestHashPassword(t *testing.T) {
+	Convey("Test hashing passwords", t, func() {
+		for _, algorithm := range []string{"", accounts.BcryptAlgorithm, accounts.Argon2idAlgorithm} {
+			hash, err := accounts.HashPassword(algorithm, "password")
+			So(err, ShouldBeNil)
+			So(accounts.ComparePassword(hash, "password"), ShouldBeNil)
+			So(accounts.ComparePassword(hash, "other"), ShouldEqual, zerr.ErrPasswordMismatch)
+		}
+
+		hash, err := accounts.HashPassword(accounts.Argon2idAlgorithm, "password")
+		So(err, ShouldBeNil)
+		So(hash, ShouldStartWith, "$argon2id$v=19$m=19456,t=2,p=1$")
+
+		_, err = accounts.HashPassword("md5", "password")
+		So(err, ShouldWrap, zerr.ErrUnsupportedHashAlgorithm)
+		So(accounts.IsHashAlgorithm("md5"), ShouldBeFalse)
+		So(accounts.IsHashAlgorithm(accounts.Argon2idAlgorithm), ShouldBeTrue)
+
+		for _, invalidHash := range []string{
+			"{SHA}W6ph5Mm5Pz8GgiULbPgzG37mj9g=",
+			"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA",
+			"$argon2id$v=18$m=19456,t=2,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=x,t=2,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=19456,t=2,p=1$!$a2V5",
+			"$argon2id$v=19$m=19456,t=2,p=1$c2FsdA$",
+		} {
+			So(accounts.ComparePassword(invalidHash, "password"), ShouldWrap, zerr.ErrUnsupportedHashAlgorithm)
+		}
+	})
+}
+
+func TestWriteFileAtomically(t *testing.T) {
+	Convey("Test replacing files atomically", t, func() {
+		filePath := path.Join(t.TempDir(), "htpasswd")
+
+		err := accounts.WriteFileAtomically(filePath, []byte("a"))
+		So(err, ShouldBeNil)
+
+		info, err := os.Stat(filePath)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, 0o600)
+
+		err = os.Chmod(filePath, 0o640)
+		So(err, ShouldBeNil)
+
+		err = accounts.WriteFileAtomically(filePath, []byte("b"))
+		So(err, ShouldBeNil)
+
+		content, err := os.ReadFile(filePath)
+		So(err, ShouldBeNil)
+		So(string(content), ShouldEqual, "b")
+
+		info, err = os.Stat(filePath)
+		So(err, ShouldBeNil)
+		So(info.Mode().Perm(), ShouldEqual, 0o640)
+
+		// no temporary file is left behind
+		entries, err := os.ReadDir(path.Dir(filePath))
+		So(err, ShouldBeNil)
+		So(entries, ShouldHaveLength, 1)
+
+		err = accounts.WriteFileAtomically(path.Join(filePath, "child"), []byte("c"))
+		So(err, ShouldNotBeNil)
+	})
+}
+
+func TestManager(t *testing.T) {
+	Convey("Test managing the users and groups", t, func() {
+		dir := t.TempDir()
+		htpasswdPath := path.Join(dir, "htpasswd")
+		groupsPath := path.Join(dir, "groups.json")
+		auditPath := path.Join(dir, "audit.log")
+
+		err := os.WriteFile(htpasswdPath, []byte("# comment\nbob:$2y$05$invalid\n"), 0o600)
+		So(err, ShouldBeNil)
+
+		conf := config.New()
+		conf.HTTP.Auth = &config.AuthConfig{HTPasswd: config.AuthHTPasswd{Path: htpasswdPath}}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{GroupsFile: groupsPath}
+
+		reloads := 0
+		audit := log.NewAuditLogger("debug", auditPath)
+		manager := accounts.NewManager(conf, func() error { reloads++; return nil }, audit,
+			log.NewLogger("debug", ""))
+
+		Convey("Users are created, updated and deleted", func() {
+			err := manager.CreateUser("admin", "alice", "alice")
+			So(err, ShouldBeNil)
+			So(reloads, ShouldEqual, 1)
+
+			users, err := manager.Users()
+			So(err, ShouldBeNil)
+			So(users, ShouldResemble, []string{"alice", "bob"})
+
+			err = manager.CreateUser("admin", "alice", "other")
+			So(err, ShouldWrap, zerr.ErrUserAlreadyExists)
+
+			for _, username := range []string{"", "a:b", "a b", "a\tb"} {
+				So(manager.CreateUser("admin", username, "password"), ShouldWrap, zerr.ErrInvalidUsername)
+			}
+
+			err = manager.UpdatePassword("admin", "alice", "new")
+			So(err, ShouldBeNil)
+
+			content, err := os.ReadFile(htpasswdPath)
+			So(err, ShouldBeNil)
+
+			lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			So(lines, ShouldHaveLength, 3)
+			// the other lines are kept as they are
+			So(lines[0], ShouldEqual, "# comment")
+			So(lines[1], ShouldEqual, "bob:$2y$05$invalid")
+			So(accounts.ComparePassword(strings.TrimPrefix(lines[2], "alice:"), "new"), ShouldBeNil)
+
+			err = manager.UpdatePassword("admin", "carol", "new")
+			So(err, ShouldWrap, zerr.ErrBadUser)
+
+			err = manager.DeleteUser("admin", "bob")
+			So(err, ShouldBeNil)
+
+			err = manager.DeleteUser("admin", "bob")
+			So(err, ShouldWrap, zerr.ErrBadUser)
+
+			users, err = manager.Users()
+			So(err, ShouldBeNil)
+			So(users, ShouldResemble, []string{"alice"})
+			So(reloads, ShouldEqual, 3)
+
+			auditLog, err := os.ReadFile(auditPath)
+			So(err, ShouldBeNil)
+			So(string(auditLog), ShouldContainSubstring, `"subject":"admin","action":"createUser","object":"alice"`)
+			So(string(auditLog), ShouldContainSubstring, `"subject":"admin","action":"deleteUser","object":"bob"`)
+			So(string(auditLog), ShouldNotContainSubstring, "carol")
+		})
+
+		Convey("Users are hashed with the configured algorithm", func() {
+			conf.HTTP.Auth.HTPasswd.HashAlgorithm = accounts.Argon2idAlgorithm
+
+			err := manager.CreateUser("admin", "alice", "alice")
+			So(err, ShouldBeNil)
+
+			content, err := os.ReadFile(htpasswdPath)
+			So(err, ShouldBeNil)
+			So(string(content), ShouldContainSubstring, "alice:$argon2id$")
+		})
+
+		Convey("Groups are set and deleted", func() {
+			groups, err := manager.Groups()
+			So(err, ShouldBeNil)
+			So(groups, ShouldBeEmpty)
+
+			accessControl := conf.HTTP.AccessControl
+
+			err = manager.SetGroup("admin", "devs", []string{"alice", "bob", "alice", ""})
+			So(err, ShouldBeNil)
+			So(conf.HTTP.AccessControl.Groups, ShouldResemble, config.Groups{
+				"devs": config.Group{Users: []string{"alice", "bob"}},
+			})
+			// the previous access control config is not modified
+			So(accessControl.Groups, ShouldBeEmpty)
+
+			err = manager.SetGroup("admin", "ops", []string{"carol"})
+			So(err, ShouldBeNil)
+
+			groups, err = accounts.LoadGroupsFile(groupsPath)
+			So(err, ShouldBeNil)
+			So(groups, ShouldResemble, conf.HTTP.AccessControl.Groups)
+
+			err = manager.DeleteGroup("admin", "devs")
+			So(err, ShouldBeNil)
+
+			err = manager.DeleteGroup("admin", "devs")
+			So(err, ShouldWrap, zerr.ErrGroupNotFound)
+
+			err = manager.SetGroup("admin", "", nil)
+			So(err, ShouldWrap, zerr.ErrGroupNotFound)
+
+			groups, err = manager.Groups()
+			So(err, ShouldBeNil)
+			So(groups, ShouldResemble, config.Groups{"ops": config.Group{Users: []string{"carol"}}})
+
+			auditLog, err := os.ReadFile(auditPath)
+			So(err, ShouldBeNil)
+			So(string(auditLog), ShouldContainSubstring, `"subject":"admin","action":"setGroup","object":"devs"`)
+			So(string(auditLog), ShouldContainSubstring, `"subject":"admin","action":"deleteGroup","object":"devs"`)
+		})
+
+		Convey("Nothing is managed without the backing files", func() {
+			conf.HTTP.Auth.HTPasswd.Path = ""
+			conf.HTTP.AccessControl.GroupsFile = ""
+
+			_, err := manager.Users()
+			So(err, ShouldEqual, zerr.ErrHTPasswdNotConfigured)
+
+			err = manager.CreateUser("admin", "alice", "alice")
+			So(err, ShouldEqual, zerr.ErrHTPasswdNotConfigured)
+
+			_, err = manager.Groups()
+			So(err, ShouldEqual, zerr.ErrGroupsFileNotConfigured)
+
+			err = manager.SetGroup("admin", "devs", nil)
+			So(err, ShouldEqual, zerr.ErrGroupsFileNotConfigured)
+		})
+
+		Convey("Invalid groups files are rejected", func() {
+			groups, err := accounts.LoadGroupsFile(path.Join(dir, "missing.json"))
+			So(err, ShouldBeNil)
+			So(groups, ShouldBeEmpty)
+
+			err = os.WriteFile(groupsPath, []byte("{"), 0o600)
+			So(err, ShouldBeNil)
+
+			_, err = accounts.LoadGroupsFile(groupsPath)
+			So(err, ShouldWrap, zerr.ErrBadConfig)
+
+			_, err = accounts.LoadGroupsFile(dir)
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
@@ -0,0 +1,55 @@
+package accounts
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+const defaultFilePerms = 0o600
+
+// WriteFileAtomically replaces the content of a file with data, keeping its permissions, so that its readers
+// see either the previous or the new content but never a partially written one.
+func WriteFileAtomically(filePath string, data []byte) error {
+	perms := fs.FileMode(defaultFilePerms)
+
+	info, err := os.Stat(filePath)
+	if err == nil {
+		perms = info.Mode().Perm()
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	// the temporary file is in the same directory so that renaming it does not cross file systems
+	tmpFile, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmpFile.Name()) //nolint: errcheck // no-op once it is renamed
+
+	if _, err := tmpFile.Write(data); err != nil {
+		_ = tmpFile.Close()
+
+		return err
+	}
+
+	if err := tmpFile.Chmod(perms); err != nil {
+		_ = tmpFile.Close()
+
+		return err
+	}
+
+	if err := tmpFile.Sync(); err != nil {
+		_ = tmpFile.Close()
+
+		return err
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpFile.Name(), filePath)
+}
@@ -0,0 +1,119 @@
+package accounts
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	zerr "zotregistry.dev/zot/errors"
+)
+
+// algorithms hashing the passwords written to the htpasswd file.
+const (
+	BcryptAlgorithm   = "bcrypt"
+	Argon2idAlgorithm = "argon2id"
+)
+
+// argon2id parameters recommended by OWASP.
+const (
+	argon2idMemory      = 19 * 1024
+	argon2idIterations  = 2
+	argon2idParallelism = 1
+	argon2idSaltLength  = 16
+	argon2idKeyLength   = 32
+)
+
+// IsHashAlgorithm returns true if the passwords can be hashed with algorithm.
+func IsHashAlgorithm(algorithm string) bool {
+	return algorithm == BcryptAlgorithm || algorithm == Argon2idAlgorithm
+}
+
+// HashPassword returns the hash of password in the htpasswd format of algorithm, bcrypt if it is empty.
+func HashPassword(algorithm, password string) (string, error) {
+	switch algorithm {
+	case BcryptAlgorithm, "":
+		hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+
+		return string(hash), err
+	case Argon2idAlgorithm:
+		salt := make([]byte, argon2idSaltLength)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+
+		key := argon2.IDKey([]byte(password), salt, argon2idIterations, argon2idMemory, argon2idParallelism,
+			argon2idKeyLength)
+
+		return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version, argon2idMemory,
+			argon2idIterations, argon2idParallelism, base64.RawStdEncoding.EncodeToString(salt),
+			base64.RawStdEncoding.EncodeToString(key)), nil
+	default:
+		return "", fmt.Errorf("%w: %s", zerr.ErrUnsupportedHashAlgorithm, algorithm)
+	}
+}
+
+// ComparePassword returns nil if hash is the hash of password, ErrPasswordMismatch if it is the hash of
+// another password, and ErrUnsupportedHashAlgorithm if it is neither a bcrypt nor an argon2id hash.
+func ComparePassword(hash, password string) error {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		return compareArgon2id(hash, password)
+	}
+
+	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return zerr.ErrPasswordMismatch
+	}
+
+	if err != nil {
+		return fmt.Errorf("%w: %w", zerr.ErrUnsupportedHashAlgorithm, err)
+	}
+
+	return nil
+}
+
+// compareArgon2id compares password with a hash formatted as $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<key>.
+func compareArgon2id(hash, password string) error {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 { //nolint: mnd
+		return fmt.Errorf("%w: invalid argon2id hash", zerr.ErrUnsupportedHashAlgorithm)
+	}
+
+	var (
+		version            int
+		memory, iterations uint32
+		parallelism        uint8
+	)
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return fmt.Errorf("%w: invalid argon2id version %s", zerr.ErrUnsupportedHashAlgorithm, parts[2])
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return fmt.Errorf("%w: invalid argon2id parameters %s", zerr.ErrUnsupportedHashAlgorithm, parts[3])
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return fmt.Errorf("%w: invalid argon2id salt: %w", zerr.ErrUnsupportedHashAlgorithm, err)
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return fmt.Errorf("%w: invalid argon2id key", zerr.ErrUnsupportedHashAlgorithm)
+	}
+
+	//nolint: gosec // the key length is checked to be non zero, and is much smaller than 2^32
+	otherKey := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(key)))
+
+	if subtle.ConstantTimeCompare(key, otherKey) != 1 {
+		return zerr.ErrPasswordMismatch
+	}
+
+	return nil
+}
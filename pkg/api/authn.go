@@ -6,12 +6,12 @@ import (
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/hex"
-	"encoding/pem"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -34,6 +34,7 @@ import (
 	apiErr "zotregistry.dev/zot/pkg/api/errors"
 	zcommon "zotregistry.dev/zot/pkg/common"
 	"zotregistry.dev/zot/pkg/log"
+	mTypes "zotregistry.dev/zot/pkg/meta/types"
 	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 )
 
@@ -43,28 +44,38 @@ const (
 )
 
 type AuthnMiddleware struct {
-	htpasswd   *HTPasswd
-	ldapClient *LDAPClient
-	log        log.Logger
+	htpasswd          *HTPasswd
+	ldapClient        *LDAPClient
+	proxyHeader       *ProxyHeaderAuthenticator
+	certificateMapper *CertificateMapper
+	log               log.Logger
 }
 
 func AuthHandler(ctlr *Controller) mux.MiddlewareFunc {
+	if ctlr.Config.IsBearerAuthEnabled() {
+		return bearerAuthHandler(ctlr)
+	}
+
+	return CredentialsAuthHandler(ctlr)
+}
+
+// CredentialsAuthHandler authenticates the users with htpasswd, ldap, api keys or openid sessions,
+// with bearer authentication enabled it is only used by the built-in token server.
+func CredentialsAuthHandler(ctlr *Controller) mux.MiddlewareFunc {
 	authnMiddleware := &AuthnMiddleware{
 		htpasswd: ctlr.HTPasswd,
 		log:      ctlr.Log,
 	}
 
-	if ctlr.Config.IsBearerAuthEnabled() {
-		return bearerAuthHandler(ctlr)
-	}
-
 	return authnMiddleware.tryAuthnHandlers(ctlr)
 }
 
 func (amw *AuthnMiddleware) sessionAuthn(ctlr *Controller, userAc *reqCtx.UserAccessControl,
 	response http.ResponseWriter, request *http.Request,
 ) (bool, error) {
-	identity, ok := GetAuthUserFromRequestSession(ctlr.CookieStore, request, ctlr.Log)
+	logger := log.Ctx(request.Context(), ctlr.Log)
+
+	identity, sessionID, ok := GetAuthUserFromRequestSession(ctlr.CookieStore, request, logger)
 	if !ok {
 		// let the client know that this session is invalid/expired
 		cookie := &http.Cookie{
@@ -84,9 +95,38 @@ func (amw *AuthnMiddleware) sessionAuthn(ctlr *Controller, userAc *reqCtx.UserAc
 	userAc.SetUsername(identity)
 	userAc.SaveOnRequest(request)
 
+	// the sessions revoked by their user or by an admin are not found anymore
+	userSession, err := ctlr.MetaDB.UpdateUserSessionLastSeen(request.Context(), sessionID, clientIP(request))
+	if err != nil {
+		if errors.Is(err, zerr.ErrUserSessionNotFound) {
+			logger.Info().Str("identity", identity).Str("sessionID", sessionID).Msg("session was revoked or expired")
+
+			return false, nil
+		}
+
+		logger.Err(err).Str("identity", identity).Msg("failed to update user session in DB")
+
+		return false, err
+	}
+
+	// the users disabled or removed from groups by the openid provider lose their access
+	// without waiting for their session to expire
+	if err := revalidateOpenIDSession(request.Context(), ctlr, identity, userSession); err != nil {
+		if errors.Is(err, zerr.ErrOpenIDSessionInvalid) {
+			if err := ctlr.MetaDB.DeleteUserSession(request.Context(), sessionID); err != nil &&
+				!errors.Is(err, zerr.ErrUserSessionNotFound) {
+				logger.Error().Err(err).Str("identity", identity).Msg("failed to delete user session in DB")
+			}
+
+			return false, nil
+		}
+
+		return false, err
+	}
+
 	groups, err := ctlr.MetaDB.GetUserGroups(request.Context())
 	if err != nil {
-		ctlr.Log.Err(err).Str("identity", identity).Msg("failed to get user profile in DB")
+		logger.Err(err).Str("identity", identity).Msg("failed to get user profile in DB")
 
 		return false, err
 	}
@@ -100,11 +140,11 @@ func (amw *AuthnMiddleware) sessionAuthn(ctlr *Controller, userAc *reqCtx.UserAc
 func (amw *AuthnMiddleware) basicAuthn(ctlr *Controller, userAc *reqCtx.UserAccessControl,
 	response http.ResponseWriter, request *http.Request,
 ) (bool, error) {
-	cookieStore := ctlr.CookieStore
+	logger := log.Ctx(request.Context(), ctlr.Log)
 
 	identity, passphrase, err := getUsernamePasswordBasicAuth(request)
 	if err != nil {
-		ctlr.Log.Error().Err(err).Msg("failed to parse authorization header")
+		logger.Error().Err(err).Msg("failed to parse authorization header")
 
 		return false, nil
 	}
@@ -124,21 +164,25 @@ func (amw *AuthnMiddleware) basicAuthn(ctlr *Controller, userAc *reqCtx.UserAcce
 		userAc.AddGroups(groups)
 		userAc.SaveOnRequest(request)
 
+		if err := checkNotServiceAccount(request.Context(), ctlr, identity); err != nil {
+			return false, ignoreServiceAccountLogin(err)
+		}
+
 		// saved logged session only if the request comes from web (has UI session header value)
 		if hasSessionHeader(request) {
-			if err := saveUserLoggedSession(cookieStore, response, request, identity, ctlr.Log); err != nil {
+			if err := saveUserLoggedSession(ctlr, response, request, identity, "", nil); err != nil {
 				return false, err
 			}
 		}
 
 		// we have already populated the request context with userAc
 		if err := ctlr.MetaDB.SetUserGroups(request.Context(), groups); err != nil {
-			ctlr.Log.Error().Err(err).Str("identity", identity).Msg("failed to update user profile")
+			logger.Error().Err(err).Str("identity", identity).Msg("failed to update user profile")
 
 			return false, err
 		}
 
-		ctlr.Log.Info().Str("identity", identity).Msgf("user profile successfully set")
+		logger.Info().Str("identity", identity).Msgf("user profile successfully set")
 
 		return true, nil
 	}
@@ -161,16 +205,20 @@ func (amw *AuthnMiddleware) basicAuthn(ctlr *Controller, userAc *reqCtx.UserAcce
 			userAc.AddGroups(groups)
 			userAc.SaveOnRequest(request)
 
+			if err := checkNotServiceAccount(request.Context(), ctlr, identity); err != nil {
+				return false, ignoreServiceAccountLogin(err)
+			}
+
 			// saved logged session only if the request comes from web (has UI session header value)
 			if hasSessionHeader(request) {
-				if err := saveUserLoggedSession(cookieStore, response, request, identity, ctlr.Log); err != nil {
+				if err := saveUserLoggedSession(ctlr, response, request, identity, "", nil); err != nil {
 					return false, err
 				}
 			}
 
 			// we have already populated the request context with userAc
 			if err := ctlr.MetaDB.SetUserGroups(request.Context(), groups); err != nil {
-				ctlr.Log.Error().Err(err).Str("identity", identity).Msg("failed to update user profile")
+				logger.Error().Err(err).Str("identity", identity).Msg("failed to update user profile")
 
 				return false, err
 			}
@@ -179,12 +227,17 @@ func (amw *AuthnMiddleware) basicAuthn(ctlr *Controller, userAc *reqCtx.UserAcce
 		}
 	}
 
+	// next, CI workload identity tokens given as password
+	if ctlr.WorkloadIdentity != nil && isWorkloadIdentityToken(passphrase) {
+		return amw.workloadIdentityAuthn(ctlr, userAc, passphrase, request)
+	}
+
 	// last try API keys
 	if ctlr.Config.IsAPIKeyEnabled() {
 		apiKey := passphrase
 
 		if !strings.HasPrefix(apiKey, constants.APIKeysPrefix) {
-			ctlr.Log.Error().Msg("invalid api token format")
+			logger.Error().Msg("invalid api token format")
 
 			return false, nil
 		}
@@ -196,12 +249,12 @@ func (amw *AuthnMiddleware) basicAuthn(ctlr *Controller, userAc *reqCtx.UserAcce
 		storedIdentity, err := ctlr.MetaDB.GetUserAPIKeyInfo(hashedKey)
 		if err != nil {
 			if errors.Is(err, zerr.ErrUserAPIKeyNotFound) {
-				ctlr.Log.Info().Err(err).Msgf("failed to find any user info for hashed key %s in DB", hashedKey)
+				logger.Info().Err(err).Msgf("failed to find any user info for hashed key %s in DB", hashedKey)
 
 				return false, nil
 			}
 
-			ctlr.Log.Error().Err(err).Msgf("failed to get user info for hashed key %s in DB", hashedKey)
+			logger.Error().Err(err).Msgf("failed to get user info for hashed key %s in DB", hashedKey)
 
 			return false, err
 		}
@@ -210,10 +263,24 @@ func (amw *AuthnMiddleware) basicAuthn(ctlr *Controller, userAc *reqCtx.UserAcce
 			userAc.SetUsername(identity)
 			userAc.SaveOnRequest(request)
 
+			userData, err := ctlr.MetaDB.GetUserData(request.Context())
+			if err != nil {
+				logger.Err(err).Str("identity", identity).Msg("failed to get user profile in DB")
+
+				return false, err
+			}
+
+			// disabled service accounts keep their api keys, which are refused until an admin enables them again
+			if userData.ServiceAccount != nil && userData.ServiceAccount.Disabled {
+				logger.Info().Str("identity", identity).Msg("service account is disabled")
+
+				return false, nil
+			}
+
 			// check if api key expired
 			isExpired, err := ctlr.MetaDB.IsAPIKeyExpired(request.Context(), hashedKey)
 			if err != nil {
-				ctlr.Log.Err(err).Str("identity", identity).Msg("failed to verify if api key expired")
+				logger.Err(err).Str("identity", identity).Msg("failed to verify if api key expired")
 
 				return false, err
 			}
@@ -222,20 +289,43 @@ func (amw *AuthnMiddleware) basicAuthn(ctlr *Controller, userAc *reqCtx.UserAcce
 				return false, nil
 			}
 
-			err = ctlr.MetaDB.UpdateUserAPIKeyLastUsed(request.Context(), hashedKey)
+			scopes, err := getAPIKeyScopes(userData, hashedKey, logger)
 			if err != nil {
-				ctlr.Log.Err(err).Str("identity", identity).Msg("failed to update user profile in DB")
+				logger.Err(err).Str("identity", identity).Msg("failed to get api key scopes")
 
 				return false, err
 			}
 
-			groups, err := ctlr.MetaDB.GetUserGroups(request.Context())
+			userAc.SetScopes(scopes)
+			userAc.SetAPIKey(hashedKey)
+			userAc.SaveOnRequest(request)
+
+			err = ctlr.MetaDB.UpdateUserAPIKeyLastUsed(request.Context(), hashedKey)
 			if err != nil {
-				ctlr.Log.Err(err).Str("identity", identity).Msg("failed to get user's groups in DB")
+				logger.Err(err).Str("identity", identity).Msg("failed to update user profile in DB")
 
 				return false, err
 			}
 
+			var groups []string
+
+			if userData.ServiceAccount != nil {
+				// the groups of service accounts are set by the admins, in the account and in the config
+				groups = slices.Clone(userData.ServiceAccount.Groups)
+
+				if ctlr.Config.HTTP.AccessControl != nil {
+					ac := NewAccessController(ctlr.Config)
+					groups = append(groups, ac.getUserGroups(identity)...)
+				}
+			} else {
+				groups, err = ctlr.MetaDB.GetUserGroups(request.Context())
+				if err != nil {
+					logger.Err(err).Str("identity", identity).Msg("failed to get user's groups in DB")
+
+					return false, err
+				}
+			}
+
 			userAc.AddGroups(groups)
 			userAc.SaveOnRequest(request)
 
@@ -246,6 +336,183 @@ func (amw *AuthnMiddleware) basicAuthn(ctlr *Controller, userAc *reqCtx.UserAcce
 	return false, nil
 }
 
+// workloadIdentityAuthn authenticates the CI jobs with the OIDC ID token issued by their platform.
+func (amw *AuthnMiddleware) workloadIdentityAuthn(ctlr *Controller, userAc *reqCtx.UserAccessControl,
+	token string, request *http.Request,
+) (bool, error) {
+	logger := log.Ctx(request.Context(), ctlr.Log)
+
+	identity, groups, err := ctlr.WorkloadIdentity.Verify(token)
+	if err != nil {
+		logger.Info().Err(err).Msg("failed to verify workload identity token")
+
+		return false, nil
+	}
+
+	if ctlr.Config.HTTP.AccessControl != nil {
+		ac := NewAccessController(ctlr.Config)
+		groups = append(groups, ac.getUserGroups(identity)...)
+	}
+
+	userAc.SetUsername(identity)
+	userAc.AddGroups(groups)
+	userAc.SaveOnRequest(request)
+
+	// we have already populated the request context with userAc
+	if err := checkNotServiceAccount(request.Context(), ctlr, identity); err != nil {
+		return false, ignoreServiceAccountLogin(err)
+	}
+
+	if err := ctlr.MetaDB.SetUserGroups(request.Context(), groups); err != nil {
+		logger.Error().Err(err).Str("identity", identity).Msg("failed to update user profile")
+
+		return false, err
+	}
+
+	logger.Info().Str("identity", identity).Msg("authenticated workload identity")
+
+	return true, nil
+}
+
+func (amw *AuthnMiddleware) proxyHeaderAuthn(ctlr *Controller, userAc *reqCtx.UserAccessControl,
+	request *http.Request,
+) (bool, error) {
+	logger := log.Ctx(request.Context(), ctlr.Log)
+
+	identity, groups, err := amw.proxyHeader.Authenticate(request)
+	if err != nil {
+		logger.Warn().Err(err).Str("clientIP", clientIP(request)).Msg("rejected proxy authentication headers")
+
+		return false, nil
+	}
+
+	if ctlr.Config.HTTP.AccessControl != nil {
+		ac := NewAccessController(ctlr.Config)
+		groups = append(groups, ac.getUserGroups(identity)...)
+	}
+
+	userAc.SetUsername(identity)
+	userAc.AddGroups(groups)
+	userAc.SaveOnRequest(request)
+
+	// we have already populated the request context with userAc
+	if err := checkNotServiceAccount(request.Context(), ctlr, identity); err != nil {
+		return false, ignoreServiceAccountLogin(err)
+	}
+
+	if err := ctlr.MetaDB.SetUserGroups(request.Context(), groups); err != nil {
+		logger.Error().Err(err).Str("identity", identity).Msg("failed to update user profile")
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// certificateAuthn authenticates the requests made with a verified client certificate, its identity and groups
+// being given by the certificate mapping rules.
+func certificateAuthn(ctlr *Controller, mapper *CertificateMapper, userAc *reqCtx.UserAccessControl,
+	request *http.Request,
+) (bool, error) {
+	cert := verifiedClientCertificate(request)
+	if cert == nil {
+		return false, nil
+	}
+
+	logger := log.Ctx(request.Context(), ctlr.Log)
+
+	identity, groups := mapper.Map(cert)
+	if identity == "" {
+		logger.Info().Str("subject", cert.Subject.String()).
+			Msg("no identity mapped from the client certificate")
+
+		return false, nil
+	}
+
+	if ctlr.Config.HTTP.AccessControl != nil {
+		ac := NewAccessController(ctlr.Config)
+		groups = append(groups, ac.getUserGroups(identity)...)
+	}
+
+	userAc.SetUsername(identity)
+	userAc.AddGroups(groups)
+	userAc.SaveOnRequest(request)
+
+	// the user profile is only kept if metadb is enabled
+	if ctlr.MetaDB != nil {
+		// we have already populated the request context with userAc
+		if err := checkNotServiceAccount(request.Context(), ctlr, identity); err != nil {
+			return false, ignoreServiceAccountLogin(err)
+		}
+
+		// the certificate is presented on every request, only write the profile when its groups changed
+		storedGroups, err := ctlr.MetaDB.GetUserGroups(request.Context())
+		if err != nil || !slices.Equal(storedGroups, groups) {
+			if err := ctlr.MetaDB.SetUserGroups(request.Context(), groups); err != nil {
+				logger.Error().Err(err).Str("identity", identity).Msg("failed to update user profile")
+
+				return false, err
+			}
+		}
+	}
+
+	return true, nil
+}
+
+// checkNotServiceAccount fails the logins of the users with the identity of a service account,
+// which only authenticates with its api keys.
+func checkNotServiceAccount(ctx context.Context, ctlr *Controller, identity string) error {
+	logger := log.Ctx(ctx, ctlr.Log)
+
+	userData, err := ctlr.MetaDB.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		logger.Error().Err(err).Str("identity", identity).Msg("failed to get user profile in DB")
+
+		return err
+	}
+
+	if userData.ServiceAccount != nil {
+		logger.Info().Err(zerr.ErrServiceAccountLogin).Str("identity", identity).Msg("failed to authenticate user")
+
+		return zerr.ErrServiceAccountLogin
+	}
+
+	return nil
+}
+
+// ignoreServiceAccountLogin turns the refused service account logins into failed authentications.
+func ignoreServiceAccountLogin(err error) error {
+	if errors.Is(err, zerr.ErrServiceAccountLogin) {
+		return nil
+	}
+
+	return err
+}
+
+// getAPIKeyScopes returns the parsed scopes of the api key owned by the user. The keys created before the scopes
+// were enforced may have free-form scopes, these are skipped and a key having no valid scope is unscoped.
+func getAPIKeyScopes(userData mTypes.UserData, hashedKey string, log log.Logger) ([]reqCtx.Scope, error) {
+	apiKeyDetails, ok := userData.APIKeys[hashedKey]
+	if !ok {
+		return nil, zerr.ErrUserAPIKeyNotFound
+	}
+
+	scopes := make([]reqCtx.Scope, 0, len(apiKeyDetails.Scopes))
+
+	for _, scope := range apiKeyDetails.Scopes {
+		parsedScope, err := reqCtx.ParseScope(scope)
+		if err != nil {
+			log.Warn().Err(err).Str("apiKeyID", apiKeyDetails.UUID).Msg("ignoring legacy api key scope")
+
+			continue
+		}
+
+		scopes = append(scopes, parsedScope)
+	}
+
+	return scopes, nil
+}
+
 func (amw *AuthnMiddleware) tryAuthnHandlers(ctlr *Controller) mux.MiddlewareFunc { //nolint: gocyclo
 	// no password based authN, if neither LDAP nor HTTP BASIC is enabled
 	if !ctlr.Config.IsBasicAuthnEnabled() {
@@ -271,10 +538,21 @@ func (amw *AuthnMiddleware) tryAuthnHandlers(ctlr *Controller) mux.MiddlewareFun
 			UserFilter:         ldapConfig.UserFilter,
 			InsecureSkipVerify: ldapConfig.SkipVerify,
 			ServerName:         ldapConfig.Address,
+			Servers:            ldapConfig.LDAPServers(),
+			ServerRetry:        ldapConfig.ServerRetryInterval,
+			GroupsCacheTTL:     ldapConfig.GroupsCacheTTL,
+			Metrics:            ctlr.Metrics,
 			Log:                ctlr.Log,
 			SubtreeSearch:      ldapConfig.SubtreeSearch,
 		}
 
+		if groupSearch := ldapConfig.GroupSearch; groupSearch != nil {
+			ctlr.LDAPClient.GroupBase = groupSearch.BaseDN
+			ctlr.LDAPClient.GroupFilter = groupSearch.Filter
+			ctlr.LDAPClient.GroupAttribute = groupSearch.NameAttribute
+			ctlr.LDAPClient.NestedGroups = groupSearch.Nested
+		}
+
 		amw.ldapClient = ctlr.LDAPClient
 
 		if ctlr.Config.HTTP.Auth.LDAP.CACert != "" {
@@ -312,11 +590,53 @@ func (amw *AuthnMiddleware) tryAuthnHandlers(ctlr *Controller) mux.MiddlewareFun
 		}
 	}
 
+	// CI workload identity based authN
+	if ctlr.Config.IsWorkloadIdentityEnabled() {
+		verifier, err := NewWorkloadIdentityVerifier(ctlr.Config.HTTP.Auth.WorkloadIdentity, ctlr.Log)
+		if err != nil {
+			amw.log.Panic().Err(err).Msg("failed to load workload identity issuers")
+		}
+
+		if ctlr.WorkloadIdentity != nil {
+			ctlr.WorkloadIdentity.Close()
+		}
+
+		ctlr.WorkloadIdentity = verifier
+	}
+
+	// reverse proxy headers based authN
+	if ctlr.Config.IsProxyHeaderAuthEnabled() {
+		proxyHeader, err := NewProxyHeaderAuthenticator(ctlr.Config.HTTP.Auth.ProxyHeader)
+		if err != nil {
+			amw.log.Panic().Err(err).Msg("failed to load proxy header authentication config")
+		}
+
+		amw.proxyHeader = proxyHeader
+	}
+
+	// client certificates based authN, in addition to the other methods
+	if ctlr.Config.IsMTLSMappingEnabled() {
+		mapper, err := NewCertificateMapper(ctlr.Config.HTTP.Auth.MTLS)
+		if err != nil {
+			amw.log.Panic().Err(err).Msg("failed to load client certificate mapping rules")
+		}
+
+		amw.certificateMapper = mapper
+	}
+
 	// openid based authN
 	if ctlr.Config.IsOpenIDAuthEnabled() {
 		ctlr.RelyingParties = make(map[string]rp.RelyingParty)
+		ctlr.OpenIDGroups = make(map[string]*OpenIDGroupsMapper)
+
+		for provider, providerConfig := range ctlr.Config.HTTP.Auth.OpenID.Providers {
+			mapper, err := NewOpenIDGroupsMapper(providerConfig)
+			if err != nil {
+				amw.log.Panic().Err(err).Str("provider", provider).Msg("failed to load openid groups mapping")
+			}
+
+			ctlr.OpenIDGroups[provider] = mapper
 
-		for provider := range ctlr.Config.HTTP.Auth.OpenID.Providers {
 			if config.IsOpenIDSupported(provider) {
 				rp := NewRelyingPartyOIDC(context.TODO(), ctlr.Config, provider, ctlr.Config.HTTP.Auth.SessionHashKey,
 					ctlr.Config.HTTP.Auth.SessionEncryptKey, ctlr.Log)
@@ -331,6 +651,8 @@ func (amw *AuthnMiddleware) tryAuthnHandlers(ctlr *Controller) mux.MiddlewareFun
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			logger := log.Ctx(request.Context(), ctlr.Log)
+
 			if request.Method == http.MethodOptions {
 				next.ServeHTTP(response, request)
 				response.WriteHeader(http.StatusNoContent)
@@ -346,16 +668,90 @@ func (amw *AuthnMiddleware) tryAuthnHandlers(ctlr *Controller) mux.MiddlewareFun
 			// if it will not be populated by authn handlers, this represents an anonymous user
 			userAc.SaveOnRequest(request)
 
+			// the headers of an authenticating proxy take precedence over the other credentials,
+			// the requests sending them from untrusted sources being rejected
+			if amw.proxyHeader != nil && amw.proxyHeader.HasHeaders(request) {
+				//nolint: contextcheck
+				authenticated, err := amw.proxyHeaderAuthn(ctlr, userAc, request)
+				if err != nil {
+					response.WriteHeader(http.StatusInternalServerError)
+
+					return
+				}
+
+				if authenticated {
+					next.ServeHTTP(response, request)
+
+					return
+				}
+
+				authFail(response, request, ctlr.Config.HTTP.Realm, delay)
+
+				return
+			}
+
+			// a verified client certificate authenticates the requests sent without credentials
+			if amw.certificateMapper != nil && isAuthorizationHeaderEmpty(request) {
+				//nolint: contextcheck
+				authenticated, err := certificateAuthn(ctlr, amw.certificateMapper, userAc, request)
+				if err != nil {
+					response.WriteHeader(http.StatusInternalServerError)
+
+					return
+				}
+
+				if authenticated {
+					next.ServeHTTP(response, request)
+
+					return
+				}
+			}
+
 			// try basic auth if authorization header is given
 			if !isAuthorizationHeaderEmpty(request) { //nolint: gocritic
-				//nolint: contextcheck
-				authenticated, err := amw.basicAuthn(ctlr, userAc, response, request)
+				var (
+					authenticated bool
+					err           error
+				)
+
+				// the credentials of locked out users and client ips are not even checked
+				username, _, _ := getUsernamePasswordBasicAuth(request)
+				if ctlr.AuthLockout != nil {
+					if remaining := ctlr.AuthLockout.LockedFor(request.Context(), username,
+						clientIP(request)); remaining > 0 {
+						logger.Warn().Str("username", username).Str("clientIP", clientIP(request)).
+							Str("remaining", remaining.String()).Msg("rejected credentials during lockout")
+
+						authFail(response, request, ctlr.Config.HTTP.Realm, delay)
+
+						return
+					}
+				}
+
+				// CI jobs may also send their workload identity token as bearer token
+				if token, ok := strings.CutPrefix(request.Header.Get("Authorization"), "Bearer "); ok &&
+					ctlr.WorkloadIdentity != nil {
+					//nolint: contextcheck
+					authenticated, err = amw.workloadIdentityAuthn(ctlr, userAc, token, request)
+				} else {
+					//nolint: contextcheck
+					authenticated, err = amw.basicAuthn(ctlr, userAc, response, request)
+				}
+
 				if err != nil {
 					response.WriteHeader(http.StatusInternalServerError)
 
 					return
 				}
 
+				if ctlr.AuthLockout != nil {
+					if authenticated {
+						ctlr.AuthLockout.RecordSuccess(request.Context(), username)
+					} else {
+						ctlr.AuthLockout.RecordFailure(request.Context(), username, clientIP(request))
+					}
+				}
+
 				if authenticated {
 					next.ServeHTTP(response, request)
 
@@ -367,7 +763,7 @@ func (amw *AuthnMiddleware) tryAuthnHandlers(ctlr *Controller) mux.MiddlewareFun
 				authenticated, err := amw.sessionAuthn(ctlr, userAc, response, request)
 				if err != nil {
 					if errors.Is(err, zerr.ErrUserDataNotFound) {
-						ctlr.Log.Err(err).Msg("failed to find user profile in DB")
+						logger.Err(err).Msg("failed to find user profile in DB")
 
 						authFail(response, request, ctlr.Config.HTTP.Realm, delay)
 					}
@@ -402,19 +798,22 @@ func (amw *AuthnMiddleware) tryAuthnHandlers(ctlr *Controller) mux.MiddlewareFun
 }
 
 func bearerAuthHandler(ctlr *Controller) mux.MiddlewareFunc {
-	certificate, err := loadCertificateFromFile(ctlr.Config.HTTP.Auth.Bearer.Cert)
+	// the certificates are optional if zot issues the tokens itself
+	keySet, err := NewBearerKeySet(ctlr.Config.HTTP.Auth.Bearer, ctlr.Log)
 	if err != nil {
-		ctlr.Log.Panic().Err(err).Msg("failed to load certificate for bearer authentication")
+		ctlr.Log.Panic().Err(err).Msg("failed to load keys for bearer authentication")
 	}
 
-	authorizer := NewBearerAuthorizer(
-		ctlr.Config.HTTP.Auth.Bearer.Realm,
-		ctlr.Config.HTTP.Auth.Bearer.Service,
-		certificate.PublicKey,
-	)
+	if ctlr.BearerKeys != nil {
+		ctlr.BearerKeys.Close()
+	}
+
+	ctlr.BearerKeys = keySet
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			logger := log.Ctx(request.Context(), ctlr.Log)
+
 			if request.Method == http.MethodOptions {
 				next.ServeHTTP(response, request)
 				response.WriteHeader(http.StatusNoContent)
@@ -442,9 +841,14 @@ func bearerAuthHandler(ctlr *Controller) mux.MiddlewareFunc {
 				vars := mux.Vars(request)
 				name := vars["name"]
 
-				action := "pull"
-				if m := request.Method; m != http.MethodGet && m != http.MethodHead {
-					action = "push"
+				action := pullAction
+
+				switch request.Method {
+				case http.MethodGet, http.MethodHead:
+				case http.MethodDelete:
+					action = deleteAction
+				default:
+					action = pushAction
 				}
 
 				requestedAccess = &ResourceAction{
@@ -454,11 +858,20 @@ func bearerAuthHandler(ctlr *Controller) mux.MiddlewareFunc {
 				}
 			}
 
-			err := authorizer.Authorize(header, requestedAccess)
+			// the tokens issued by zot are told apart by the key verifying them, not by their claims
+			var issuedByZot bool
+
+			authorizer := NewBearerAuthorizerWithKeyFunc(
+				ctlr.Config.HTTP.Auth.Bearer.Realm,
+				ctlr.Config.HTTP.Auth.Bearer.Service,
+				bearerKeyFunc(ctlr.TokenIssuer, keySet, &issuedByZot),
+			)
+
+			claims, err := authorizer.authorize(header, requestedAccess)
 			if err != nil {
 				var challenge *AuthChallengeError
 				if errors.As(err, &challenge) {
-					ctlr.Log.Debug().Err(challenge).Msg("bearer token authorization failed")
+					logger.Debug().Err(challenge).Msg("bearer token authorization failed")
 					response.Header().Set("Content-Type", "application/json")
 					response.Header().Set("WWW-Authenticate", challenge.Header())
 					zcommon.WriteJSON(response, http.StatusUnauthorized, apiErr.NewError(apiErr.UNAUTHORIZED))
@@ -466,7 +879,8 @@ func bearerAuthHandler(ctlr *Controller) mux.MiddlewareFunc {
 					return
 				}
 
-				ctlr.Log.Error().Err(err).Msg("failed to parse Authorization header")
+				keyID, algorithm := bearerTokenHeader(header)
+				logger.Warn().Err(err).Str("kid", keyID).Str("alg", algorithm).Msg("failed to verify bearer token")
 				response.Header().Set("Content-Type", "application/json")
 				zcommon.WriteJSON(response, http.StatusUnauthorized, apiErr.NewError(apiErr.UNSUPPORTED))
 
@@ -474,12 +888,51 @@ func bearerAuthHandler(ctlr *Controller) mux.MiddlewareFunc {
 			}
 
 			amCtx := acCtrlr.getAuthnMiddlewareContext(BEARER, request)
-			next.ServeHTTP(response, request.WithContext(amCtx)) //nolint:contextcheck
+			request = request.WithContext(amCtx) //nolint:contextcheck
+
+			// the subject of the tokens issued by zot itself is a user known to the access control policies,
+			// or an anonymous one, the authz middlewares authorizing it like the other users
+			if issuedByZot && claims != nil {
+				// the token obtained with a scoped api key is restricted like the key
+				scopes, err := reqCtx.ParseScopes(claims.APIKeyScopes)
+				if err != nil {
+					logger.Warn().Err(err).Str("identity", claims.Subject).Msg("failed to verify bearer token")
+					response.Header().Set("Content-Type", "application/json")
+					zcommon.WriteJSON(response, http.StatusUnauthorized, apiErr.NewError(apiErr.UNSUPPORTED))
+
+					return
+				}
+
+				userAc := reqCtx.NewUserAccessControl()
+
+				if claims.Subject != "" {
+					userAc.SetUsername(claims.Subject)
+					// the groups given by the identity provider the user authenticated with when the token was issued
+					// are only known from the token
+					userAc.AddGroups(claims.Groups)
+					userAc.SetIsAdmin(acCtrlr.isAdmin(claims.Subject, userAc.GetGroups()))
+					userAc.SetScopes(scopes)
+				}
+
+				userAc.SaveOnRequest(request)
+			}
+
+			next.ServeHTTP(response, request) //nolint:contextcheck
 		})
 	}
 }
 
 func noPasswdAuth(ctlr *Controller) mux.MiddlewareFunc {
+	var mtlsConfig *config.MTLSConfig
+	if ctlr.Config.HTTP.Auth != nil {
+		mtlsConfig = ctlr.Config.HTTP.Auth.MTLS
+	}
+
+	mapper, err := NewCertificateMapper(mtlsConfig)
+	if err != nil {
+		ctlr.Log.Panic().Err(err).Msg("failed to load client certificate mapping rules")
+	}
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
 			if request.Method == http.MethodOptions {
@@ -490,19 +943,14 @@ func noPasswdAuth(ctlr *Controller) mux.MiddlewareFunc {
 			}
 
 			userAc := reqCtx.NewUserAccessControl()
+			userAc.SaveOnRequest(request)
 
 			// if no basic auth enabled then try to get identity from mTLS auth
-			if request.TLS != nil {
-				verifiedChains := request.TLS.VerifiedChains
-				if len(verifiedChains) > 0 && len(verifiedChains[0]) > 0 {
-					for _, cert := range request.TLS.PeerCertificates {
-						identity := cert.Subject.CommonName
-						if identity != "" {
-							// assign identity to authz context, needed for extensions
-							userAc.SetUsername(identity)
-						}
-					}
-				}
+			//nolint: contextcheck
+			if _, err := certificateAuthn(ctlr, mapper, userAc, request); err != nil {
+				response.WriteHeader(http.StatusInternalServerError)
+
+				return
 			}
 
 			if ctlr.Config.IsMTLSAuthEnabled() && userAc.IsAnonymous() {
@@ -511,8 +959,6 @@ func noPasswdAuth(ctlr *Controller) mux.MiddlewareFunc {
 				return
 			}
 
-			userAc.SaveOnRequest(request)
-
 			// Process request
 			next.ServeHTTP(response, request)
 		})
@@ -521,6 +967,8 @@ func noPasswdAuth(ctlr *Controller) mux.MiddlewareFunc {
 
 func (rh *RouteHandler) AuthURLHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := log.Ctx(r.Context(), rh.c.Log)
+
 		query := r.URL.Query()
 		callbackUI := query.Get(constants.CallbackUIQueryParam)
 
@@ -528,7 +976,7 @@ func (rh *RouteHandler) AuthURLHandler() http.HandlerFunc {
 
 		client, ok := rh.c.RelyingParties[provider]
 		if !ok {
-			rh.c.Log.Error().Msg("failed to authenticate due to unrecognized openid provider")
+			logger.Error().Msg("failed to authenticate due to unrecognized openid provider")
 
 			w.WriteHeader(http.StatusBadRequest)
 
@@ -552,7 +1000,7 @@ func (rh *RouteHandler) AuthURLHandler() http.HandlerFunc {
 		// let the session set its own id
 		err := session.Save(r, w)
 		if err != nil {
-			rh.c.Log.Error().Err(err).Msg("failed to save http session")
+			logger.Error().Err(err).Msg("failed to save http session")
 
 			w.WriteHeader(http.StatusInternalServerError)
 
@@ -759,21 +1207,90 @@ func GetGithubUserInfo(ctx context.Context, client *github.Client, log log.Logge
 	return primaryEmail, groups, nil
 }
 
-func saveUserLoggedSession(cookieStore sessions.Store, response http.ResponseWriter,
-	request *http.Request, identity string, log log.Logger,
+// saveUserLoggedSession sets the session cookie of a logged in user, provider and token being set for the
+// logins with an openid/oauth2 provider.
+func saveUserLoggedSession(ctlr *Controller, response http.ResponseWriter, request *http.Request,
+	identity, provider string, token *mTypes.UserSessionToken,
+) error {
+	logger := log.Ctx(request.Context(), ctlr.Log)
+
+	session, _ := ctlr.CookieStore.Get(request, "session")
+
+	// a client logging in again as the same user keeps its session, unless it was revoked meanwhile
+	if previousID, ok := session.Values["id"].(string); ok && session.Values["user"] == identity {
+		userSession, err := ctlr.MetaDB.UpdateUserSessionLastSeen(request.Context(), previousID, clientIP(request))
+		if err != nil && !errors.Is(err, zerr.ErrUserSessionNotFound) {
+			logger.Error().Err(err).Str("identity", identity).Msg("failed to update user session in DB")
+
+			return err
+		}
+
+		if err == nil {
+			// the session now holds the tokens of this login
+			if provider != "" {
+				userSession.Provider = provider
+				userSession.Token = token
+
+				if err := ctlr.MetaDB.AddUserSession(request.Context(), userSession); err != nil {
+					logger.Error().Err(err).Str("identity", identity).Msg("failed to store user session in DB")
+
+					return err
+				}
+			}
+
+			return saveUserSessionCookies(ctlr, response, request, session, identity)
+		}
+	}
+
+	// otherwise a new session is started, the previous cookie can't be reused for it
+	session.ID = ""
+
+	sessionID, err := guuid.NewV4()
+	if err != nil {
+		logger.Error().Err(err).Str("identity", identity).Msg("failed to generate session id")
+
+		return err
+	}
+
+	session.Values["authStatus"] = true
+	session.Values["user"] = identity
+	session.Values["id"] = sessionID.String()
+
+	// the session is tracked in the user profile so that it can be listed and revoked
+	now := time.Now()
+
+	err = ctlr.MetaDB.AddUserSession(request.Context(), mTypes.UserSession{
+		ID:        sessionID.String(),
+		UserAgent: request.UserAgent(),
+		ClientIP:  clientIP(request),
+		CreatedAt: now,
+		LastSeen:  now,
+		ExpiresAt: now.Add(cookiesMaxAge * time.Second),
+		Provider:  provider,
+		Token:     token,
+	})
+	if err != nil {
+		logger.Error().Err(err).Str("identity", identity).Msg("failed to store user session in DB")
+
+		return err
+	}
+
+	return saveUserSessionCookies(ctlr, response, request, session, identity)
+}
+
+func saveUserSessionCookies(ctlr *Controller, response http.ResponseWriter, request *http.Request,
+	session *sessions.Session, identity string,
 ) error {
-	session, _ := cookieStore.Get(request, "session")
+	logger := log.Ctx(request.Context(), ctlr.Log)
 
 	session.Options.Secure = true
 	session.Options.HttpOnly = true
 	session.Options.SameSite = http.SameSiteDefaultMode
-	session.Values["authStatus"] = true
-	session.Values["user"] = identity
 
 	// let the session set its own id
 	err := session.Save(request, response)
 	if err != nil {
-		log.Error().Err(err).Str("identity", identity).Msg("failed to save http session")
+		logger.Error().Err(err).Str("identity", identity).Msg("failed to save http session")
 
 		return err
 	}
@@ -791,22 +1308,25 @@ func saveUserLoggedSession(cookieStore sessions.Store, response http.ResponseWri
 	return nil
 }
 
-// OAuth2Callback is the callback logic where openid/oauth2 will redirect back to our app.
+// OAuth2Callback is the callback logic where openid/oauth2 will redirect back to our app,
+// token being kept with the session if it is re-validated with the provider.
 func OAuth2Callback(ctlr *Controller, w http.ResponseWriter, r *http.Request, state, email string,
-	groups []string,
+	groups []string, provider string, token *mTypes.UserSessionToken,
 ) (string, error) {
+	logger := log.Ctx(r.Context(), ctlr.Log)
+
 	stateCookie, _ := ctlr.CookieStore.Get(r, "statecookie")
 
 	stateOrigin, ok := stateCookie.Values["state"].(string)
 	if !ok {
-		ctlr.Log.Error().Err(zerr.ErrInvalidStateCookie).Str("component", "openID").
+		logger.Error().Err(zerr.ErrInvalidStateCookie).Str("component", "openID").
 			Msg("failed to get 'state' cookie from request")
 
 		return "", zerr.ErrInvalidStateCookie
 	}
 
 	if stateOrigin != state {
-		ctlr.Log.Error().Err(zerr.ErrInvalidStateCookie).Str("component", "openID").
+		logger.Error().Err(zerr.ErrInvalidStateCookie).Str("component", "openID").
 			Msg("'state' cookie differs from the actual one")
 
 		return "", zerr.ErrInvalidStateCookie
@@ -817,19 +1337,23 @@ func OAuth2Callback(ctlr *Controller, w http.ResponseWriter, r *http.Request, st
 	userAc.AddGroups(groups)
 	userAc.SaveOnRequest(r)
 
+	if err := checkNotServiceAccount(r.Context(), ctlr, email); err != nil {
+		return "", err
+	}
+
 	// if this line has been reached, then a new session should be created
 	// if the `session` key is already on the cookie, it's not a valid one
-	if err := saveUserLoggedSession(ctlr.CookieStore, w, r, email, ctlr.Log); err != nil {
+	if err := saveUserLoggedSession(ctlr, w, r, email, provider, token); err != nil {
 		return "", err
 	}
 
 	if err := ctlr.MetaDB.SetUserGroups(r.Context(), groups); err != nil {
-		ctlr.Log.Error().Err(err).Str("identity", email).Msg("failed to update the user profile")
+		logger.Error().Err(err).Str("identity", email).Msg("failed to update the user profile")
 
 		return "", err
 	}
 
-	ctlr.Log.Info().Msgf("user profile set successfully for email %s", email)
+	logger.Info().Msgf("user profile set successfully for email %s", email)
 
 	// redirect to UI
 	callbackUI, _ := stateCookie.Values["callback"].(string)
@@ -845,39 +1369,47 @@ func hashUUID(uuid string) string {
 }
 
 /*
-GetAuthUserFromRequestSession returns identity
+GetAuthUserFromRequestSession returns identity, session id
 and auth status if on the request's cookie session is a logged in user.
 */
 func GetAuthUserFromRequestSession(cookieStore sessions.Store, request *http.Request, log log.Logger,
-) (string, bool) {
+) (string, string, bool) {
 	session, err := cookieStore.Get(request, "session")
 	if err != nil {
 		log.Error().Err(err).Msg("failed to decode existing session")
 		// expired cookie, no need to return err
-		return "", false
+		return "", "", false
 	}
 
 	// at this point we should have a session set on cookie.
 	// if created in the earlier Get() call then user is not logged in with sessions.
 	if session.IsNew {
-		return "", false
+		return "", "", false
 	}
 
 	authenticated := session.Values["authStatus"]
 	if authenticated != true {
 		log.Error().Msg("failed to get `user` session value")
 
-		return "", false
+		return "", "", false
 	}
 
 	identity, ok := session.Values["user"].(string)
 	if !ok {
 		log.Error().Msg("failed to get `user` session value")
 
-		return "", false
+		return "", "", false
 	}
 
-	return identity, true
+	// the sessions saved before they were tracked have no id, these can't be revoked so their users log in again
+	sessionID, ok := session.Values["id"].(string)
+	if !ok {
+		log.Info().Str("identity", identity).Msg("session started before the sessions were tracked, login required")
+
+		return "", "", false
+	}
+
+	return identity, sessionID, true
 }
 
 func GenerateAPIKey(uuidGenerator guuid.Generator, log log.Logger,
@@ -901,22 +1433,3 @@ func GenerateAPIKey(uuidGenerator guuid.Generator, log log.Logger,
 
 	return apiKey, apiKeyID.String(), err
 }
-
-func loadCertificateFromFile(path string) (*x509.Certificate, error) {
-	rawCert, err := os.ReadFile(path)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w, path %s", zerr.ErrCouldNotLoadCertificate, err, path)
-	}
-
-	block, _ := pem.Decode(rawCert)
-	if block == nil {
-		return nil, fmt.Errorf("%w: no valid PEM data found", zerr.ErrCouldNotLoadCertificate)
-	}
-
-	cert, err := x509.ParseCertificate(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("%w: %w", zerr.ErrCouldNotLoadCertificate, err)
-	}
-
-	return cert, nil
-}
@@ -12,6 +12,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path"
+	"sync"
 	"testing"
 	"time"
 
@@ -34,7 +35,9 @@ import (
 	"zotregistry.dev/zot/pkg/storage/local"
 	authutils "zotregistry.dev/zot/pkg/test/auth"
 	test "zotregistry.dev/zot/pkg/test/common"
+	. "zotregistry.dev/zot/pkg/test/image-utils"
 	"zotregistry.dev/zot/pkg/test/mocks"
+	ociutils "zotregistry.dev/zot/pkg/test/oci-utils"
 )
 
 var ErrUnexpectedError = errors.New("error: unexpected error")
@@ -149,7 +152,7 @@ func TestAPIKeys(t *testing.T) {
 
 		payload := api.APIKeyPayload{
 			Label:  "test",
-			Scopes: []string{"test"},
+			Scopes: []string{"repository:**:read"},
 		}
 		reqBody, err := json.Marshal(payload)
 		So(err, ShouldBeNil)
@@ -360,7 +363,7 @@ func TestAPIKeys(t *testing.T) {
 		Convey("API key retrieved with openID and with long expire", func() {
 			payload := api.APIKeyPayload{
 				Label:          "test",
-				Scopes:         []string{"test"},
+				Scopes:         []string{"repository:**:read"},
 				ExpirationDate: time.Now().Add(time.Hour).Local().Format(constants.APIKeyTimeFormat),
 			}
 
@@ -631,7 +634,7 @@ func TestAPIKeys(t *testing.T) {
 			expirationDate := time.Now().Add(1 * time.Second).Local().Round(time.Second)
 			payload := api.APIKeyPayload{
 				Label:          "test",
-				Scopes:         []string{"test"},
+				Scopes:         []string{"repository:**:read"},
 				ExpirationDate: expirationDate.Format(constants.APIKeyTimeFormat),
 			}
 
@@ -760,7 +763,7 @@ func TestAPIKeys(t *testing.T) {
 			expirationDate := time.Now().Add(-5 * time.Second).Local().Round(time.Second)
 			payload := api.APIKeyPayload{
 				Label:          "test",
-				Scopes:         []string{"test"},
+				Scopes:         []string{"repository:**:read"},
 				ExpirationDate: expirationDate.Format(constants.APIKeyTimeFormat),
 			}
 
@@ -795,7 +798,7 @@ func TestAPIKeys(t *testing.T) {
 			expirationDate := time.Now().Add(-5 * time.Second).Local().Round(time.Second)
 			payload := api.APIKeyPayload{
 				Label:          "test",
-				Scopes:         []string{"test"},
+				Scopes:         []string{"repository:**:read"},
 				ExpirationDate: expirationDate.Format(time.RFC1123Z),
 			}
 
@@ -841,6 +844,856 @@ func TestAPIKeys(t *testing.T) {
 	})
 }
 
+func TestAPIKeyScopes(t *testing.T) {
+	Convey("Make a new controller with api keys enabled", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+
+		username, _ := test.GenerateRandomString()
+		password, _ := test.GenerateRandomString()
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(username, password))
+
+		defer os.Remove(htpasswdPath)
+
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{
+				Path: htpasswdPath,
+			},
+			APIKey: true,
+		}
+
+		ctlr := api.NewController(conf)
+		ctlr.Config.Storage.RootDirectory = t.TempDir()
+
+		cm := test.NewControllerManager(ctlr)
+
+		cm.StartAndWait(port)
+		defer cm.StopServer()
+
+		createAPIKey := func(user, passphrase string, scopes []string) *resty.Response {
+			reqBody, err := json.Marshal(api.APIKeyPayload{Label: "scoped", Scopes: scopes})
+			So(err, ShouldBeNil)
+
+			resp, err := resty.R().
+				SetBody(reqBody).
+				SetBasicAuth(user, passphrase).
+				Post(baseURL + constants.APIKeyPath)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+
+			return resp
+		}
+
+		Convey("Invalid scopes are rejected", func() {
+			resp := createAPIKey(username, password, []string{"repository:team/*:write"})
+			So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+			resp = createAPIKey(username, password, []string{"unknown"})
+			So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+		})
+
+		Convey("Scoped api key permissions are restricted", func() {
+			resp := createAPIKey(username, password, []string{"repository:team/**:read,create"})
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			var apiKeyResponse apiKeyResponse
+			err := json.Unmarshal(resp.Body(), &apiKeyResponse)
+			So(err, ShouldBeNil)
+
+			apiKey := apiKeyResponse.APIKey
+
+			img := CreateRandomImage()
+
+			err = UploadImageWithBasicAuth(img, baseURL, "other/app", "1.0", username, password)
+			So(err, ShouldBeNil)
+
+			err = UploadImageWithBasicAuth(img, baseURL, "team/app", "1.0", username, apiKey)
+			So(err, ShouldBeNil)
+
+			err = UploadImageWithBasicAuth(img, baseURL, "other/app", "2.0", username, apiKey)
+			So(err, ShouldNotBeNil)
+
+			resp, err = resty.R().SetBasicAuth(username, apiKey).
+				Get(baseURL + "/v2/team/app/manifests/1.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			resp, err = resty.R().SetBasicAuth(username, apiKey).
+				Get(baseURL + "/v2/other/app/manifests/1.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(username, apiKey).
+				Delete(baseURL + "/v2/team/app/manifests/1.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			// catalog only lists repos allowed by the scopes
+			resp, err = resty.R().SetBasicAuth(username, apiKey).Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+			So(string(resp.Body()), ShouldContainSubstring, "team/app")
+			So(string(resp.Body()), ShouldNotContainSubstring, "other/app")
+
+			// a scoped api key can't be used to manage the credentials of its user
+			resp = createAPIKey(username, apiKey, []string{})
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(username, apiKey).SetQueryParam("id", apiKeyResponse.UUID).
+				Delete(baseURL + constants.APIKeyPath)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(username, apiKey).SetQueryParam("id", "any").
+				Delete(baseURL + constants.SessionsPath)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			// listing them is allowed
+			resp, err = resty.R().SetBasicAuth(username, apiKey).Get(baseURL + constants.APIKeyPath)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			// unscoped api keys keep the permissions of their owner
+			resp = createAPIKey(username, password, []string{})
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			err = json.Unmarshal(resp.Body(), &apiKeyResponse)
+			So(err, ShouldBeNil)
+
+			resp, err = resty.R().SetBasicAuth(username, apiKeyResponse.APIKey).
+				Delete(baseURL + "/v2/other/app/manifests/1.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+		})
+
+		Convey("Legacy free-form scopes do not restrict api keys", func() {
+			resp := createAPIKey(username, password, []string{})
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			var apiKeyResponse apiKeyResponse
+			err := json.Unmarshal(resp.Body(), &apiKeyResponse)
+			So(err, ShouldBeNil)
+
+			// keys created before the scopes were enforced
+			userAc := reqCtx.NewUserAccessControl()
+			userAc.SetUsername(username)
+			ctx := userAc.DeriveContext(context.Background())
+
+			userData, err := ctlr.MetaDB.GetUserData(ctx)
+			So(err, ShouldBeNil)
+
+			for hashedKey, apiKeyDetails := range userData.APIKeys {
+				apiKeyDetails.Scopes = []string{"test"}
+				userData.APIKeys[hashedKey] = apiKeyDetails
+			}
+
+			err = ctlr.MetaDB.SetUserData(ctx, userData)
+			So(err, ShouldBeNil)
+
+			img := CreateRandomImage()
+
+			err = UploadImageWithBasicAuth(img, baseURL, "any/app", "1.0", username, apiKeyResponse.APIKey)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
+func TestServiceAccounts(t *testing.T) {
+	Convey("Make a new controller with admin managed service accounts", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		admin, adminPassword := "admin", "admin"
+		user, password := "alice", "alice"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(admin, adminPassword) + "\n" +
+			test.GetCredString(user, password))
+		defer os.Remove(htpasswdPath)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+			APIKey:   true,
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				"**": config.PolicyGroup{
+					Policies: []config.Policy{
+						{Users: []string{user}, Actions: []string{constants.ReadPermission}},
+						{Groups: []string{"ci"}, Actions: []string{constants.ReadPermission, constants.CreatePermission}},
+					},
+				},
+			},
+			AdminPolicy: config.Policy{
+				Users:   []string{admin},
+				Actions: []string{constants.ReadPermission, constants.CreatePermission},
+			},
+		}
+
+		ctlr := api.NewController(conf)
+		ctlr.Config.Storage.RootDirectory = t.TempDir()
+
+		cm := test.NewControllerManager(ctlr)
+
+		cm.StartAndWait(port)
+		defer cm.StopServer()
+
+		serviceAccountURL := baseURL + constants.ServiceAccountsPath + "/ci-bot"
+
+		createAPIKey := func() apiKeyResponse {
+			resp, err := resty.R().SetBasicAuth(admin, adminPassword).
+				SetBody(api.APIKeyPayload{Label: "pipeline"}).
+				Post(serviceAccountURL + "/apikey")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			var apiKeyResponse apiKeyResponse
+			err = json.Unmarshal(resp.Body(), &apiKeyResponse)
+			So(err, ShouldBeNil)
+
+			return apiKeyResponse
+		}
+
+		// only the admins manage the service accounts
+		resp, err := resty.R().Get(baseURL + constants.ServiceAccountsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		resp, err = resty.R().SetBasicAuth(user, password).Get(baseURL + constants.ServiceAccountsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		resp, err = resty.R().SetBasicAuth(user, password).
+			SetBody(api.ServiceAccountPayload{Name: "ci-bot"}).
+			Post(baseURL + constants.ServiceAccountsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		// the scoped api keys of admins need the admin scope
+		createAdminAPIKey := func(scopes []string) string {
+			resp, err := resty.R().SetBasicAuth(admin, adminPassword).
+				SetBody(api.APIKeyPayload{Label: "admin", Scopes: scopes}).
+				Post(baseURL + constants.APIKeyPath)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			var apiKeyResponse apiKeyResponse
+			err = json.Unmarshal(resp.Body(), &apiKeyResponse)
+			So(err, ShouldBeNil)
+
+			return apiKeyResponse.APIKey
+		}
+
+		adminAPIKey := createAdminAPIKey([]string{"repository:**:read"})
+
+		resp, err = resty.R().SetBasicAuth(admin, adminAPIKey).Get(baseURL + constants.ServiceAccountsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		resp, err = resty.R().SetBasicAuth(admin, adminAPIKey).
+			SetBody(api.ServiceAccountPayload{Name: "ci-bot"}).
+			Post(baseURL + constants.ServiceAccountsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		adminAPIKey = createAdminAPIKey([]string{"admin"})
+
+		resp, err = resty.R().SetBasicAuth(admin, adminAPIKey).Get(baseURL + constants.ServiceAccountsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).
+			SetBody(api.ServiceAccountPayload{Name: "ci-bot", Description: "pipelines", Groups: []string{"ci"}}).
+			Post(baseURL + constants.ServiceAccountsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		var serviceAccount api.ServiceAccountInfo
+		err = json.Unmarshal(resp.Body(), &serviceAccount)
+		So(err, ShouldBeNil)
+		So(serviceAccount.Name, ShouldEqual, "ci-bot")
+		So(serviceAccount.CreatedBy, ShouldEqual, admin)
+		So(serviceAccount.Groups, ShouldResemble, []string{"ci"})
+
+		// the names of the service accounts and of the users are unique
+		for name, status := range map[string]int{
+			"ci-bot": http.StatusConflict,
+			user:     http.StatusConflict,
+			"a b":    http.StatusBadRequest,
+		} {
+			resp, err = resty.R().SetBasicAuth(admin, adminPassword).
+				SetBody(api.ServiceAccountPayload{Name: name}).
+				Post(baseURL + constants.ServiceAccountsPath)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, status)
+		}
+
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).SetBody("{").
+			Post(baseURL + constants.ServiceAccountsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+		// the users which logged in are not service accounts
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).
+			Get(baseURL + constants.ServiceAccountsPath + "/" + user)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).
+			SetBody(api.APIKeyPayload{Label: "pipeline"}).
+			Post(baseURL + constants.ServiceAccountsPath + "/unknown/apikey")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+		apiKey := createAPIKey()
+
+		// the service account gets the permissions of its groups
+		img := CreateRandomImage()
+
+		err = UploadImageWithBasicAuth(img, baseURL, "app", "1.0", "ci-bot", apiKey.APIKey)
+		So(err, ShouldBeNil)
+
+		err = UploadImageWithBasicAuth(img, baseURL, "app", "2.0", user, password)
+		So(err, ShouldNotBeNil)
+
+		// the service account does not create its own api keys
+		resp, err = resty.R().SetBasicAuth("ci-bot", apiKey.APIKey).
+			SetBody(api.APIKeyPayload{Label: "other"}).
+			Post(baseURL + constants.APIKeyPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).Get(baseURL + constants.ServiceAccountsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		var serviceAccounts api.ServiceAccountsResponse
+		err = json.Unmarshal(resp.Body(), &serviceAccounts)
+		So(err, ShouldBeNil)
+		So(serviceAccounts.ServiceAccounts, ShouldHaveLength, 1)
+		So(serviceAccounts.ServiceAccounts[0].Name, ShouldEqual, "ci-bot")
+		So(serviceAccounts.ServiceAccounts[0].Description, ShouldEqual, "pipelines")
+		So(serviceAccounts.ServiceAccounts[0].LastUsed.IsZero(), ShouldBeFalse)
+		So(serviceAccounts.ServiceAccounts[0].APIKeys, ShouldHaveLength, 1)
+		So(serviceAccounts.ServiceAccounts[0].APIKeys[0].UUID, ShouldEqual, apiKey.UUID)
+
+		Convey("Disabled service accounts do not authenticate", func() {
+			resp, err := resty.R().SetBasicAuth(admin, adminPassword).
+				SetBody(map[string]bool{"disabled": true}).
+				Patch(serviceAccountURL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			resp, err = resty.R().SetBasicAuth("ci-bot", apiKey.APIKey).Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			resp, err = resty.R().SetBasicAuth(admin, adminPassword).
+				SetBody(map[string]interface{}{"disabled": false, "groups": []string{}}).
+				Patch(serviceAccountURL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			err = json.Unmarshal(resp.Body(), &serviceAccount)
+			So(err, ShouldBeNil)
+			So(serviceAccount.Disabled, ShouldBeFalse)
+			So(serviceAccount.Description, ShouldEqual, "pipelines")
+			So(serviceAccount.Groups, ShouldBeEmpty)
+
+			resp, err = resty.R().SetBasicAuth("ci-bot", apiKey.APIKey).Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			// without its groups, the service account lost its permissions
+			err = UploadImageWithBasicAuth(img, baseURL, "app", "2.0", "ci-bot", apiKey.APIKey)
+			So(err, ShouldNotBeNil)
+
+			resp, err = resty.R().SetBasicAuth(admin, adminPassword).SetBody("{").Patch(serviceAccountURL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+		})
+
+		Convey("Service accounts api keys are revoked", func() {
+			resp, err := resty.R().SetBasicAuth(admin, adminPassword).Delete(serviceAccountURL + "/apikey")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+			resp, err = resty.R().SetBasicAuth(admin, adminPassword).SetQueryParam("id", apiKey.UUID).
+				Delete(serviceAccountURL + "/apikey")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			resp, err = resty.R().SetBasicAuth("ci-bot", apiKey.APIKey).Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("Deleted service accounts lose their api keys", func() {
+			otherAPIKey := createAPIKey()
+
+			resp, err := resty.R().SetBasicAuth(admin, adminPassword).Delete(serviceAccountURL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			for _, key := range []string{apiKey.APIKey, otherAPIKey.APIKey} {
+				resp, err = resty.R().SetBasicAuth("ci-bot", key).Get(baseURL + "/v2/_catalog")
+				So(err, ShouldBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			}
+
+			resp, err = resty.R().SetBasicAuth(admin, adminPassword).Get(serviceAccountURL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+			resp, err = resty.R().SetBasicAuth(admin, adminPassword).Delete(serviceAccountURL)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+			resp, err = resty.R().SetBasicAuth(admin, adminPassword).Get(baseURL + constants.ServiceAccountsPath)
+			So(err, ShouldBeNil)
+			So(string(resp.Body()), ShouldEqual, `{"serviceAccounts":[]}`)
+		})
+
+		Convey("Users can not log in with the name of a service account", func() {
+			err := os.WriteFile(htpasswdPath, []byte(test.GetCredString(admin, adminPassword)+"\n"+
+				test.GetCredString("ci-bot", password)), 0o600)
+			So(err, ShouldBeNil)
+
+			err = ctlr.HTPasswd.Reload(htpasswdPath)
+			So(err, ShouldBeNil)
+
+			resp, err := resty.R().SetBasicAuth("ci-bot", password).Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			resp, err = resty.R().SetBasicAuth("ci-bot", apiKey.APIKey).Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		})
+	})
+}
+
+func TestUserSessions(t *testing.T) {
+	Convey("Make a new controller tracking the web sessions", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		admin, adminPassword := "admin", "admin"
+		user, password := "alice", "alice"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(admin, adminPassword) + "\n" +
+			test.GetCredString(user, password))
+		defer os.Remove(htpasswdPath)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{HTPasswd: config.AuthHTPasswd{Path: htpasswdPath}, APIKey: true}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				"**": config.PolicyGroup{
+					Policies: []config.Policy{
+						{Users: []string{user}, Actions: []string{constants.ReadPermission}},
+					},
+				},
+			},
+			AdminPolicy: config.Policy{
+				Users:   []string{admin},
+				Actions: []string{constants.ReadPermission},
+			},
+		}
+
+		ctlr := api.NewController(conf)
+		ctlr.Config.Storage.RootDirectory = t.TempDir()
+
+		cm := test.NewControllerManager(ctlr)
+
+		cm.StartAndWait(port)
+		defer cm.StopServer()
+
+		// login returns a client sending the session cookie, as the ui does
+		login := func(userAgent string) *resty.Client {
+			client := resty.New()
+			client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+
+			resp, err := client.R().SetBasicAuth(user, password).SetHeader("User-Agent", userAgent).
+				Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			client.SetCookies(resp.Cookies())
+
+			return client
+		}
+
+		getSessions := func(request *resty.Request) []api.UserSessionInfo {
+			resp, err := request.Get(baseURL + constants.SessionsPath)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			var sessionsResponse api.UserSessionsResponse
+			err = json.Unmarshal(resp.Body(), &sessionsResponse)
+			So(err, ShouldBeNil)
+
+			return sessionsResponse.Sessions
+		}
+
+		laptop := login("laptop")
+		phone := login("phone")
+
+		sessions := getSessions(laptop.R())
+		So(sessions, ShouldHaveLength, 2)
+		So(sessions[0].UserAgent, ShouldEqual, "laptop")
+		So(sessions[0].Current, ShouldBeTrue)
+		So(sessions[0].ClientIP, ShouldEqual, "127.0.0.1")
+		// the requests following closely the login are not recorded
+		So(sessions[0].LastSeen.Equal(sessions[0].CreatedAt), ShouldBeTrue)
+		So(sessions[1].UserAgent, ShouldEqual, "phone")
+		So(sessions[1].Current, ShouldBeFalse)
+
+		// the users revoke their own sessions
+		resp, err := laptop.R().SetQueryParam("id", sessions[1].ID).Delete(baseURL + constants.SessionsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		resp, err = phone.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		resp, err = laptop.R().SetQueryParam("id", sessions[1].ID).Delete(baseURL + constants.SessionsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+		resp, err = laptop.R().Delete(baseURL + constants.SessionsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+		// only the admins manage the sessions of the other users
+		resp, err = laptop.R().SetQueryParam("user", admin).Get(baseURL + constants.SessionsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		// the scoped api keys of admins need the admin scope
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).
+			SetBody(api.APIKeyPayload{Label: "admin", Scopes: []string{"repository:**:read"}}).
+			Post(baseURL + constants.APIKeyPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		var adminAPIKey apiKeyResponse
+		err = json.Unmarshal(resp.Body(), &adminAPIKey)
+		So(err, ShouldBeNil)
+
+		resp, err = resty.R().SetBasicAuth(admin, adminAPIKey.APIKey).SetQueryParam("user", user).
+			Get(baseURL + constants.SessionsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		sessions = getSessions(resty.R().SetBasicAuth(admin, adminPassword).SetQueryParam("user", user))
+		So(sessions, ShouldHaveLength, 1)
+		So(sessions[0].Current, ShouldBeFalse)
+
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).
+			SetQueryParams(map[string]string{"user": user, "id": sessions[0].ID}).
+			Delete(baseURL + constants.SessionsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		resp, err = laptop.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		// the sessions are revoked when logging out
+		laptop = login("laptop")
+
+		resp, err = laptop.R().Post(baseURL + constants.LogoutPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		sessions = getSessions(resty.R().SetBasicAuth(user, password))
+		So(sessions, ShouldBeEmpty)
+	})
+}
+
+func TestOpenIDSessionRevalidation(t *testing.T) {
+	Convey("Make a new controller re-validating the openid sessions", t, func() {
+		mockOIDCServer, err := authutils.MockOIDCRun()
+		So(err, ShouldBeNil)
+
+		defer func() {
+			err := mockOIDCServer.Shutdown()
+			So(err, ShouldBeNil)
+		}()
+
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		mockOIDCConfig := mockOIDCServer.Config()
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			OpenID: &config.OpenIDConfig{
+				Providers: map[string]config.OpenIDProviderConfig{
+					"oidc": {
+						ClientID:                  mockOIDCConfig.ClientID,
+						ClientSecret:              mockOIDCConfig.ClientSecret,
+						Issuer:                    mockOIDCConfig.Issuer,
+						Scopes:                    []string{"openid", "email", "groups"},
+						GroupsPrefix:              "/",
+						GroupsFilter:              []string{"zot-.*"},
+						SessionRevalidateInterval: time.Second,
+					},
+				},
+			},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				"**": config.PolicyGroup{
+					Policies: []config.Policy{
+						{Groups: []string{"zot-devs"}, Actions: []string{constants.ReadPermission}},
+					},
+				},
+			},
+		}
+
+		ctlr := api.NewController(conf)
+		ctlr.Config.Storage.RootDirectory = t.TempDir()
+
+		err = WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "0.0.1",
+			ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
+		So(err, ShouldBeNil)
+
+		cm := test.NewControllerManager(ctlr)
+
+		cm.StartAndWait(port)
+		defer cm.StopServer()
+
+		email := "alice@example.com"
+
+		mockUser := &mockoidc.MockUser{
+			Email:   email,
+			Subject: "alice",
+			Groups:  []string{"/zot-devs", "/other"},
+		}
+
+		mockOIDCServer.QueueUser(mockUser)
+
+		client := resty.New()
+		client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+		client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+
+		resp, err := client.R().SetQueryParam("provider", "oidc").Get(baseURL + constants.LoginPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		client.SetCookies(resp.Cookies())
+
+		userAc := reqCtx.NewUserAccessControl()
+		userAc.SetUsername(email)
+		userCtx := userAc.DeriveContext(context.Background())
+
+		// the groups of the claim are mapped
+		groups, err := ctlr.MetaDB.GetUserGroups(userCtx)
+		So(err, ShouldBeNil)
+		So(groups, ShouldResemble, []string{"zot-devs"})
+
+		resp, err = client.R().Get(baseURL + "/v2/zot-test/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		// the user is removed from the group upstream
+		mockUser.Groups = []string{"/other"}
+
+		resp, err = client.R().Get(baseURL + "/v2/zot-test/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		time.Sleep(1100 * time.Millisecond)
+
+		resp, err = client.R().Get(baseURL + "/v2/zot-test/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		groups, err = ctlr.MetaDB.GetUserGroups(userCtx)
+		So(err, ShouldBeNil)
+		So(groups, ShouldBeEmpty)
+
+		// the tokens are not listed with the sessions
+		resp, err = client.R().Get(baseURL + constants.SessionsPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(string(resp.Body()), ShouldContainSubstring, `"provider":"oidc"`)
+		So(string(resp.Body()), ShouldNotContainSubstring, "Token")
+
+		// the session is kept when the provider fails to answer
+		mockOIDCServer.QueueError(&mockoidc.ServerError{
+			Code:  http.StatusInternalServerError,
+			Error: "server_error",
+		})
+
+		time.Sleep(1100 * time.Millisecond)
+
+		resp, err = client.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		sessions, err := ctlr.MetaDB.GetUserSessions(userCtx)
+		So(err, ShouldBeNil)
+		So(sessions, ShouldNotBeEmpty)
+
+		// the provider rejects the tokens of the session, it is logged out
+		mockOIDCServer.QueueError(&mockoidc.ServerError{
+			Code:  http.StatusBadRequest,
+			Error: "invalid_grant",
+		})
+
+		// but the provider which failed to answer is not asked again on the next requests
+		resp, err = client.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		time.Sleep(1100 * time.Millisecond)
+
+		resp, err = client.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		sessions, err = ctlr.MetaDB.GetUserSessions(userCtx)
+		So(err, ShouldBeNil)
+		So(sessions, ShouldBeEmpty)
+	})
+}
+
+func TestOpenIDSessionConcurrentRevalidation(t *testing.T) {
+	Convey("Make a new controller re-validating the openid sessions with a provider rotating refresh tokens", t, func() {
+		var (
+			lock          sync.Mutex
+			refreshTokens = map[string]bool{}
+			refreshes     int
+		)
+
+		// the refresh tokens can only be used once
+		rotationMiddleware := func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+				if request.URL.Path == mockoidc.TokenEndpoint && request.ParseForm() == nil &&
+					request.Form.Get("grant_type") == "refresh_token" {
+					lock.Lock()
+					used := refreshTokens[request.Form.Get("refresh_token")]
+					refreshTokens[request.Form.Get("refresh_token")] = true
+					refreshes++
+					lock.Unlock()
+
+					if used {
+						response.Header().Set("Content-Type", "application/json")
+						response.WriteHeader(http.StatusBadRequest)
+						_, _ = response.Write([]byte(`{"error":"invalid_grant"}`))
+
+						return
+					}
+
+					// let the concurrent requests catch up
+					time.Sleep(100 * time.Millisecond)
+				}
+
+				next.ServeHTTP(response, request)
+			})
+		}
+
+		mockOIDCServer, err := authutils.MockOIDCRun(rotationMiddleware)
+		So(err, ShouldBeNil)
+
+		defer func() {
+			err := mockOIDCServer.Shutdown()
+			So(err, ShouldBeNil)
+		}()
+
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		mockOIDCConfig := mockOIDCServer.Config()
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			OpenID: &config.OpenIDConfig{
+				Providers: map[string]config.OpenIDProviderConfig{
+					"oidc": {
+						ClientID:                  mockOIDCConfig.ClientID,
+						ClientSecret:              mockOIDCConfig.ClientSecret,
+						Issuer:                    mockOIDCConfig.Issuer,
+						Scopes:                    []string{"openid", "email", "groups"},
+						SessionRevalidateInterval: time.Second,
+					},
+				},
+			},
+		}
+
+		ctlr := api.NewController(conf)
+		ctlr.Config.Storage.RootDirectory = t.TempDir()
+
+		cm := test.NewControllerManager(ctlr)
+
+		cm.StartAndWait(port)
+		defer cm.StopServer()
+
+		mockOIDCServer.QueueUser(&mockoidc.MockUser{
+			Email:   "alice@example.com",
+			Subject: "alice",
+		})
+
+		client := resty.New()
+		client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+		client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+
+		resp, err := client.R().SetQueryParam("provider", "oidc").Get(baseURL + constants.LoginPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		client.SetCookies(resp.Cookies())
+
+		time.Sleep(1100 * time.Millisecond)
+
+		// the requests made while the session is re-validated share its re-validation
+		var wg sync.WaitGroup
+
+		statusCodes := make(chan int, 10)
+
+		for range 10 {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				resp, err := client.R().Get(baseURL + "/v2/_catalog")
+				if err != nil {
+					statusCodes <- 0
+
+					return
+				}
+
+				statusCodes <- resp.StatusCode()
+			}()
+		}
+
+		wg.Wait()
+		close(statusCodes)
+
+		for statusCode := range statusCodes {
+			So(statusCode, ShouldEqual, http.StatusOK)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		So(refreshes, ShouldEqual, 1)
+	})
+}
+
 func TestAPIKeysOpenDBError(t *testing.T) {
 	Convey("Test API keys - unable to create database", t, func() {
 		conf := config.New()
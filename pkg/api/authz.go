@@ -2,10 +2,12 @@ package api
 
 import (
 	"context"
+	"net"
 	"net/http"
 
 	glob "github.com/bmatcuk/doublestar/v4"
 	"github.com/gorilla/mux"
+	godigest "github.com/opencontainers/go-digest"
 
 	"zotregistry.dev/zot/pkg/api/config"
 	"zotregistry.dev/zot/pkg/api/constants"
@@ -21,6 +23,9 @@ const (
 	OPENID = "OpenID"
 )
 
+// Everyone matches all the users, including the anonymous ones, in the users of a deny policy.
+const Everyone = "*"
+
 func AuthzFilterFunc(userAc *reqCtx.UserAccessControl) storageTypes.FilterRepoFunc {
 	return func(repo string) (bool, error) {
 		if userAc == nil {
@@ -39,6 +44,11 @@ func AuthzFilterFunc(userAc *reqCtx.UserAccessControl) storageTypes.FilterRepoFu
 type AccessController struct {
 	Config *config.AccessControlConfig
 	Log    log.Logger
+	// set when the decisions on a request are delegated to the authorization webhook
+	webhook *authzWebhookSession
+	request *http.Request
+	// the network policies are enforced on it, set with from
+	clientIP string
 }
 
 func NewAccessController(conf *config.Config) *AccessController {
@@ -55,6 +65,24 @@ func NewAccessController(conf *config.Config) *AccessController {
 	}
 }
 
+// delegateTo makes the access controller delegate its decisions on a request to the authorization webhook,
+// the deny policies still being enforced first. It is a no-op if no webhook is configured.
+func (ac *AccessController) delegateTo(webhook *AuthzWebhook, request *http.Request) *AccessController {
+	if webhook != nil {
+		ac.webhook = webhook.newSession(request.Context())
+		ac.request = request
+	}
+
+	return ac
+}
+
+// from makes the access controller enforce the network policies on the client ip of a request.
+func (ac *AccessController) from(request *http.Request) *AccessController {
+	ac.clientIP = clientIP(request)
+
+	return ac
+}
+
 // getGlobPatterns gets glob patterns from authz config on which <username> has <action> perms.
 // used to filter /v2/_catalog repositories based on user rights.
 func (ac *AccessController) getGlobPatterns(username string, groups []string, action string) map[string]bool {
@@ -98,8 +126,41 @@ func (ac *AccessController) getGlobPatterns(username string, groups []string, ac
 	return globPatterns
 }
 
+// getDeniedGlobPatterns gets glob patterns from authz config on which <username> is denied <action> perms,
+// whatever the reference, by a deny policy or from its client ip by a network policy.
+func (ac *AccessController) getDeniedGlobPatterns(username string, groups []string, action string) []string {
+	deniedGlobPatterns := []string{}
+
+	for pattern, policyGroup := range ac.Config.Repositories {
+		if networkDenies(policyGroup.NetworkPolicies, action, ac.clientIP) {
+			deniedGlobPatterns = append(deniedGlobPatterns, pattern)
+
+			continue
+		}
+
+		for _, p := range policyGroup.DenyPolicies {
+			if !p.HasReferenceConditions() && common.Contains(p.Actions, action) &&
+				denyPolicyAppliesTo(p, username, groups) {
+				deniedGlobPatterns = append(deniedGlobPatterns, pattern)
+
+				break
+			}
+		}
+	}
+
+	return deniedGlobPatterns
+}
+
 // can verifies if a user can do action on repository.
 func (ac *AccessController) can(userAc *reqCtx.UserAccessControl, action, repository string) bool {
+	return ac.canOnReference(userAc, action, repository, "")
+}
+
+// canOnReference verifies if a user can do action on a reference (tag or digest) of a repository,
+// the reference being empty for the requests not targeting a manifest, like blob uploads.
+func (ac *AccessController) canOnReference(userAc *reqCtx.UserAccessControl, action, repository,
+	reference string,
+) bool {
 	can := false
 
 	var longestMatchedPattern string
@@ -116,10 +177,20 @@ func (ac *AccessController) can(userAc *reqCtx.UserAccessControl, action, reposi
 	userGroups := userAc.GetGroups()
 	username := userAc.GetUsername()
 
+	// deny policies take precedence over all the others
+	if ac.isDenied(userGroups, username, action, repository, reference) {
+		return false
+	}
+
+	if ac.webhook != nil {
+		return ac.webhook.authorize(newAuthzWebhookRequest(ac.request,
+			AuthzWebhookRepositoryResource, username, userGroups, action, repository, reference))
+	}
+
 	// check matched repo based policy
 	pg, ok := ac.Config.Repositories[longestMatchedPattern]
 	if ok {
-		can = ac.isPermitted(userGroups, username, action, pg)
+		can = ac.isPermitted(userGroups, username, action, reference, pg)
 	}
 
 	// check admins based policy
@@ -183,6 +254,13 @@ func (ac *AccessController) updateUserAccessControl(userAc *reqCtx.UserAccessCon
 	userAc.SetGlobPatterns(constants.DeletePermission, deleteGlobPatterns)
 	userAc.SetGlobPatterns(constants.DetectManifestCollisionPermission, dmcGlobPatterns)
 
+	for _, action := range []string{
+		constants.ReadPermission, constants.CreatePermission, constants.UpdatePermission,
+		constants.DeletePermission, constants.DetectManifestCollisionPermission,
+	} {
+		userAc.SetDeniedGlobPatterns(action, ac.getDeniedGlobPatterns(identity, groups, action))
+	}
+
 	if ac.isAdmin(userAc.GetUsername(), userAc.GetGroups()) {
 		userAc.SetIsAdmin(true)
 	} else {
@@ -202,26 +280,27 @@ func (ac *AccessController) getAuthnMiddlewareContext(authnType string, request
 	return ctx
 }
 
-// isPermitted returns true if username can do action on a repository policy.
-func (ac *AccessController) isPermitted(userGroups []string, username, action string,
+// isPermitted returns true if username can do action on a reference of a repository policy.
+func (ac *AccessController) isPermitted(userGroups []string, username, action, reference string,
 	policyGroup config.PolicyGroup,
 ) bool {
-	// check repo/system based policies
-	for _, p := range policyGroup.Policies {
-		if common.Contains(p.Users, username) && common.Contains(p.Actions, action) {
-			return true
+	// the policies whose tag condition matches the tag take precedence over the others,
+	// like the longest repository pattern does over the shorter ones
+	if tagPolicies := tagConditionPolicies(policyGroup.Policies, action, reference); len(tagPolicies) > 0 {
+		for _, p := range tagPolicies {
+			if policyAppliesTo(p, username, userGroups) {
+				return true
+			}
 		}
+
+		return false
 	}
 
-	if userGroups != nil {
-		for _, p := range policyGroup.Policies {
-			if common.Contains(p.Actions, action) {
-				for _, group := range p.Groups {
-					if common.Contains(userGroups, group) {
-						return true
-					}
-				}
-			}
+	// check repo/system based policies
+	for _, p := range policyGroup.Policies {
+		if common.Contains(p.Actions, action) && allowConditionsMatch(p, action, reference) &&
+			policyAppliesTo(p, username, userGroups) {
+			return true
 		}
 	}
 
@@ -238,6 +317,152 @@ func (ac *AccessController) isPermitted(userGroups []string, username, action st
 	return false
 }
 
+// isDenied returns true if a deny policy of any repository pattern matching the repository denies the action,
+// or a network policy denies it from the client ip.
+func (ac *AccessController) isDenied(userGroups []string, username, action, repository, reference string) bool {
+	for pattern, policyGroup := range ac.Config.Repositories {
+		matched, err := glob.Match(pattern, repository)
+		if err != nil || !matched {
+			continue
+		}
+
+		if networkDenies(policyGroup.NetworkPolicies, action, ac.clientIP) {
+			return true
+		}
+
+		for _, p := range policyGroup.DenyPolicies {
+			if common.Contains(p.Actions, action) && denyConditionsMatch(p, action, reference) &&
+				denyPolicyAppliesTo(p, username, userGroups) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// networkDenies returns true if a network policy denies action to the client ip, the unknown ips being
+// outside of all the networks.
+func networkDenies(policies []config.NetworkPolicy, action, clientIP string) bool {
+	ip := net.ParseIP(clientIP)
+
+	for _, p := range policies {
+		if !common.Contains(p.Actions, action) {
+			continue
+		}
+
+		// the cidrs are parsed when loading the config
+		allowed, denied := p.Networks()
+		if networksContain(denied, ip) {
+			return true
+		}
+
+		if len(p.AllowCIDRs) > 0 && !networksContain(allowed, ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// policyAppliesTo returns true if the policy names the user or one of its groups.
+func policyAppliesTo(policy config.Policy, username string, userGroups []string) bool {
+	if username != "" && common.Contains(policy.Users, username) {
+		return true
+	}
+
+	for _, group := range policy.Groups {
+		if common.Contains(userGroups, group) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func denyPolicyAppliesTo(policy config.Policy, username string, userGroups []string) bool {
+	return common.Contains(policy.Users, Everyone) || policyAppliesTo(policy, username, userGroups)
+}
+
+// isReferenceAction returns true if the reference conditions of the policies apply to action.
+func isReferenceAction(action string) bool {
+	return action == constants.CreatePermission || action == constants.UpdatePermission ||
+		action == constants.DeletePermission
+}
+
+// tagConditionPolicies returns the policies allowing action whose tag condition matches the reference.
+func tagConditionPolicies(policies []config.Policy, action, reference string) []config.Policy {
+	tagPolicies := []config.Policy{}
+
+	if !isReferenceAction(action) || reference == "" {
+		return tagPolicies
+	}
+
+	for _, p := range policies {
+		if p.TagRegex != "" && common.Contains(p.Actions, action) && referenceConditionsMatch(p, reference) {
+			tagPolicies = append(tagPolicies, p)
+		}
+	}
+
+	return tagPolicies
+}
+
+// allowConditionsMatch returns true if the reference conditions of an allowing policy are met, the requests
+// not targeting a reference, like blob uploads, being allowed so the matching references can be pushed.
+func allowConditionsMatch(policy config.Policy, action, reference string) bool {
+	if !policy.HasReferenceConditions() || !isReferenceAction(action) || reference == "" {
+		return true
+	}
+
+	return referenceConditionsMatch(policy, reference)
+}
+
+// denyConditionsMatch returns true if the reference conditions of a deny policy are met, the requests
+// not targeting a reference only being denied by the policies without conditions.
+func denyConditionsMatch(policy config.Policy, action, reference string) bool {
+	if !policy.HasReferenceConditions() {
+		return true
+	}
+
+	if !isReferenceAction(action) || reference == "" {
+		return false
+	}
+
+	return referenceConditionsMatch(policy, reference)
+}
+
+func referenceConditionsMatch(policy config.Policy, reference string) bool {
+	_, err := godigest.Parse(reference)
+	isDigest := err == nil
+
+	if policy.DigestOnly && !isDigest {
+		return false
+	}
+
+	if policy.TagRegex != "" {
+		if isDigest {
+			return false
+		}
+
+		return policy.MatchesTag(reference)
+	}
+
+	return true
+}
+
+// isAuthorizedByBearerToken tells whether the request was authorized by the access claims of a bearer token
+// issued by an external token server. The tokens issued by zot itself have a subject known to the access control
+// policies, saved on the request by bearerAuthHandler, so they are authorized like the other users.
+func isAuthorizedByBearerToken(request *http.Request) bool {
+	authnMwCtx, err := reqCtx.GetAuthnMiddlewareContext(request.Context())
+	if err != nil {
+		return false
+	}
+
+	return authnMwCtx != nil && authnMwCtx.AuthnType == BEARER &&
+		request.Context().Value(reqCtx.GetContextKey()) == nil
+}
+
 func BaseAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
@@ -251,9 +476,8 @@ func BaseAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 				return
 			}
 
-			// request comes from bearer authn, bypass it
-			authnMwCtx, err := reqCtx.GetAuthnMiddlewareContext(request.Context())
-			if err != nil || (authnMwCtx != nil && authnMwCtx.AuthnType == BEARER) {
+			// request authorized by the access claims of an external bearer token, bypass it
+			if isAuthorizedByBearerToken(request) {
 				next.ServeHTTP(response, request)
 
 				return
@@ -266,7 +490,7 @@ func BaseAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 				return
 			}
 
-			aCtlr := NewAccessController(ctlr.Config)
+			aCtlr := NewAccessController(ctlr.Config).from(request)
 
 			// get access control context made in authn.go
 			userAc, err := reqCtx.UserAcFromContext(request.Context())
@@ -277,6 +501,18 @@ func BaseAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 			}
 
 			aCtlr.updateUserAccessControl(userAc)
+
+			// the permissions checked by the handlers, like the repositories filtered by search,
+			// are decided by the authorization webhook too
+			if ctlr.AuthzWebhook != nil {
+				aCtlr.delegateTo(ctlr.AuthzWebhook, request)
+			}
+
+			// the handlers acting on a tag, like the tag rollback, enforce the tag conditions of the policies
+			userAc.SetAuthorizer(func(action, repository, reference string) bool {
+				return aCtlr.canOnReference(userAc, action, repository, reference)
+			}, ctlr.AuthzWebhook != nil)
+
 			userAc.SaveOnRequest(request)
 
 			next.ServeHTTP(response, request) //nolint:contextcheck
@@ -293,9 +529,8 @@ func DistSpecAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 				return
 			}
 
-			// request comes from bearer authn, bypass it
-			authnMwCtx, err := reqCtx.GetAuthnMiddlewareContext(request.Context())
-			if err != nil || (authnMwCtx != nil && authnMwCtx.AuthnType == BEARER) {
+			// request authorized by the access claims of an external bearer token, bypass it
+			if isAuthorizedByBearerToken(request) {
 				next.ServeHTTP(response, request)
 
 				return
@@ -305,7 +540,7 @@ func DistSpecAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 			resource := vars["name"]
 			reference, ok := vars["reference"]
 
-			acCtrlr := NewAccessController(ctlr.Config)
+			acCtrlr := NewAccessController(ctlr.Config).from(request).delegateTo(ctlr.AuthzWebhook, request)
 
 			// get userAc built in authn and previous authz middlewares
 			userAc, err := reqCtx.UserAcFromContext(request.Context())
@@ -325,7 +560,7 @@ func DistSpecAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 				action = constants.CreatePermission
 				// if we get a reference (tag)
 				if ok {
-					is := ctlr.StoreController.GetImageStore(resource)
+					is := ctlr.StoreController.GetImageStore(resource).WithRequestLogger(request.Context())
 
 					tags, err := is.GetImageTags(resource)
 					if err == nil && common.Contains(tags, reference) && reference != "latest" {
@@ -339,7 +574,15 @@ func DistSpecAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 				action = constants.DeletePermission
 			}
 
-			can := acCtrlr.can(userAc, action, resource) //nolint:contextcheck
+			// the access control policies are not enforced if only api key scopes are configured
+			can := true
+			if ctlr.Config.IsAuthzEnabled() {
+				can = acCtrlr.canOnReference(userAc, action, resource, reference) //nolint:contextcheck
+			}
+
+			// api key scopes can only restrict the permissions given by the policies
+			can = can && userAc.ScopesAllow(action, resource)
+
 			if !can {
 				common.AuthzFail(response, request, userAc.GetUsername(), ctlr.Config.HTTP.Realm, ctlr.Config.HTTP.Auth.FailDelay)
 			} else {
@@ -359,14 +602,6 @@ func MetricsAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 				return
 			}
 
-			if len(ctlr.Config.HTTP.AccessControl.Metrics.Users) == 0 {
-				log := ctlr.Log
-				log.Warn().Msg("auth is enabled but no metrics users in accessControl: /metrics is unaccesible")
-				common.AuthzFail(response, request, "", ctlr.Config.HTTP.Realm, ctlr.Config.HTTP.Auth.FailDelay)
-
-				return
-			}
-
 			// get access control context made in authn.go
 			userAc, err := reqCtx.UserAcFromContext(request.Context())
 			if err != nil { // should never happen
@@ -376,6 +611,28 @@ func MetricsAuthzHandler(ctlr *Controller) mux.MiddlewareFunc {
 			}
 
 			username := userAc.GetUsername()
+
+			// the authorization webhook decides instead of the metrics users
+			if ctlr.AuthzWebhook != nil {
+				if !ctlr.AuthzWebhook.Authorize(request.Context(), newAuthzWebhookRequest(request, //nolint:contextcheck
+					AuthzWebhookMetricsResource, username, userAc.GetGroups(), constants.ReadPermission, "", "")) {
+					common.AuthzFail(response, request, username, ctlr.Config.HTTP.Realm, ctlr.Config.HTTP.Auth.FailDelay)
+
+					return
+				}
+
+				next.ServeHTTP(response, request) //nolint:contextcheck
+
+				return
+			}
+
+			if len(ctlr.Config.HTTP.AccessControl.Metrics.Users) == 0 {
+				logger := log.Ctx(request.Context(), ctlr.Log)
+				logger.Warn().Msg("auth is enabled but no metrics users in accessControl: /metrics is unaccesible")
+				common.AuthzFail(response, request, "", ctlr.Config.HTTP.Realm, ctlr.Config.HTTP.Auth.FailDelay)
+
+				return
+			}
 			if !common.Contains(ctlr.Config.HTTP.AccessControl.Metrics.Users, username) {
 				common.AuthzFail(response, request, username, ctlr.Config.HTTP.Realm, ctlr.Config.HTTP.Auth.FailDelay)
 
@@ -0,0 +1,269 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+const (
+	defaultAuthzWebhookTimeout  = 5 * time.Second
+	defaultAuthzWebhookCacheTTL = time.Minute
+	// the expired decisions are purged once the cache holds this many of them
+	maxAuthzWebhookCacheSize = 10000
+	maxAuthzWebhookResponse  = 1 << 16
+
+	// resource types sent to the authorization webhook
+	AuthzWebhookRepositoryResource = "repository"
+	AuthzWebhookMetricsResource    = "metrics"
+)
+
+// AuthzWebhookRequest is the body posted to the authorization webhook.
+type AuthzWebhookRequest struct {
+	Type       string   `json:"type"`
+	User       string   `json:"user"`
+	Groups     []string `json:"groups"`
+	Action     string   `json:"action"`
+	Repository string   `json:"repository,omitempty"`
+	Reference  string   `json:"reference,omitempty"`
+	ClientIP   string   `json:"clientIP"`
+}
+
+// AuthzWebhookResponse is the decision returned by the authorization webhook.
+type AuthzWebhookResponse struct {
+	Allow  bool   `json:"allow"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// AuthzWebhook delegates the authorization decisions to an external policy engine, caching them for a while.
+// The access is denied whenever the engine can not be reached or gives no valid answer.
+type AuthzWebhook struct {
+	url        string
+	timeout    time.Duration
+	cacheTTL   time.Duration
+	httpClient *http.Client
+	decisions  map[string]authzWebhookDecision
+	lock       sync.Mutex
+	log        log.Logger
+}
+
+type authzWebhookDecision struct {
+	allow     bool
+	expiresAt time.Time
+}
+
+func NewAuthzWebhook(webhookConfig *config.AuthzWebhookConfig, log log.Logger) *AuthzWebhook {
+	timeout := webhookConfig.Timeout
+	if timeout == 0 {
+		timeout = defaultAuthzWebhookTimeout
+	}
+
+	cacheTTL := webhookConfig.CacheTTL
+	if cacheTTL == 0 {
+		cacheTTL = defaultAuthzWebhookCacheTTL
+	}
+
+	return &AuthzWebhook{
+		url:        webhookConfig.URL,
+		timeout:    timeout,
+		cacheTTL:   cacheTTL,
+		httpClient: &http.Client{Timeout: timeout},
+		decisions:  map[string]authzWebhookDecision{},
+		log:        log,
+	}
+}
+
+// authzWebhookSession asks the decisions on the request handled with ctx, which may be many, e.g. one per
+// repository listed by the catalog or search. They share one timeout, which starts with the first decision
+// not cached, and once the webhook failed to answer one, the remaining ones are denied without asking it.
+type authzWebhookSession struct {
+	webhook  *AuthzWebhook
+	ctx      context.Context //nolint: containedctx // the access controller checks do not take a context
+	deadline time.Time
+	failed   bool
+	lock     sync.Mutex
+}
+
+func (wh *AuthzWebhook) newSession(ctx context.Context) *authzWebhookSession {
+	return &authzWebhookSession{webhook: wh, ctx: ctx}
+}
+
+// Authorize returns the decision of the policy engine for a request, failing closed on errors.
+func (wh *AuthzWebhook) Authorize(ctx context.Context, authzRequest AuthzWebhookRequest) bool {
+	return wh.newSession(ctx).authorize(authzRequest)
+}
+
+// start returns the deadline of the decisions of the session, or false if the webhook already failed.
+func (session *authzWebhookSession) start() (time.Time, bool) {
+	session.lock.Lock()
+	defer session.lock.Unlock()
+
+	if session.failed {
+		return time.Time{}, false
+	}
+
+	if session.deadline.IsZero() {
+		session.deadline = time.Now().Add(session.webhook.timeout)
+	}
+
+	return session.deadline, true
+}
+
+func (session *authzWebhookSession) fail() {
+	session.lock.Lock()
+	defer session.lock.Unlock()
+
+	session.failed = true
+}
+
+func (session *authzWebhookSession) authorize(authzRequest AuthzWebhookRequest) bool {
+	wh := session.webhook
+	ctx := session.ctx
+	logger := log.Ctx(ctx, wh.log)
+
+	// the same user may be listed in its groups in a different order
+	authzRequest.Groups = slices.Clone(authzRequest.Groups)
+	slices.Sort(authzRequest.Groups)
+
+	body, err := json.Marshal(authzRequest)
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to encode authorization webhook request, denying access")
+
+		return false
+	}
+
+	cacheKey := string(body)
+
+	if allow, ok := wh.cachedDecision(cacheKey); ok {
+		return allow
+	}
+
+	deadline, ok := session.start()
+	if !ok {
+		logger.Debug().Str("user", authzRequest.User).Str("action", authzRequest.Action).
+			Str("repository", authzRequest.Repository).
+			Msg("authorization webhook already failed during this request, denying access")
+
+		return false
+	}
+
+	authzResponse, err := wh.post(ctx, deadline, body)
+	if err != nil {
+		session.fail()
+
+		logger.Error().Err(err).Str("url", wh.url).Str("user", authzRequest.User).Str("action", authzRequest.Action).
+			Str("repository", authzRequest.Repository).Msg("failed to get authorization decision, denying access")
+
+		return false
+	}
+
+	if !authzResponse.Allow {
+		logger.Debug().Str("user", authzRequest.User).Str("action", authzRequest.Action).
+			Str("repository", authzRequest.Repository).Str("reason", authzResponse.Reason).
+			Msg("access denied by authorization webhook")
+	}
+
+	wh.cacheDecision(cacheKey, authzResponse.Allow)
+
+	return authzResponse.Allow
+}
+
+func (wh *AuthzWebhook) post(ctx context.Context, deadline time.Time, body []byte) (AuthzWebhookResponse, error) {
+	var authzResponse AuthzWebhookResponse
+
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, wh.url, bytes.NewReader(body))
+	if err != nil {
+		return authzResponse, fmt.Errorf("%w: %w", zerr.ErrAuthzWebhookFailed, err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := wh.httpClient.Do(request)
+	if err != nil {
+		return authzResponse, fmt.Errorf("%w: %w", zerr.ErrAuthzWebhookFailed, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return authzResponse, fmt.Errorf("%w: %w: %d", zerr.ErrAuthzWebhookFailed, zerr.ErrBadHTTPStatusCode,
+			response.StatusCode)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(response.Body, maxAuthzWebhookResponse))
+	if err != nil {
+		return authzResponse, fmt.Errorf("%w: %w", zerr.ErrAuthzWebhookFailed, err)
+	}
+
+	if err := json.Unmarshal(content, &authzResponse); err != nil {
+		return authzResponse, fmt.Errorf("%w: %w", zerr.ErrAuthzWebhookFailed, err)
+	}
+
+	return authzResponse, nil
+}
+
+func (wh *AuthzWebhook) cachedDecision(cacheKey string) (bool, bool) {
+	wh.lock.Lock()
+	defer wh.lock.Unlock()
+
+	decision, ok := wh.decisions[cacheKey]
+	if !ok || time.Now().After(decision.expiresAt) {
+		return false, false
+	}
+
+	return decision.allow, true
+}
+
+func (wh *AuthzWebhook) cacheDecision(cacheKey string, allow bool) {
+	wh.lock.Lock()
+	defer wh.lock.Unlock()
+
+	now := time.Now()
+
+	if len(wh.decisions) >= maxAuthzWebhookCacheSize {
+		for key, decision := range wh.decisions {
+			if now.After(decision.expiresAt) {
+				delete(wh.decisions, key)
+			}
+		}
+
+		// still full of valid decisions, start over
+		if len(wh.decisions) >= maxAuthzWebhookCacheSize {
+			wh.decisions = map[string]authzWebhookDecision{}
+		}
+	}
+
+	wh.decisions[cacheKey] = authzWebhookDecision{allow: allow, expiresAt: now.Add(wh.cacheTTL)}
+}
+
+// newAuthzWebhookRequest describes the request made by a user to the authorization webhook.
+func newAuthzWebhookRequest(request *http.Request, resourceType, user string, groups []string,
+	action, repository, reference string,
+) AuthzWebhookRequest {
+	if groups == nil {
+		groups = []string{}
+	}
+
+	return AuthzWebhookRequest{
+		Type:       resourceType,
+		User:       user,
+		Groups:     groups,
+		Action:     action,
+		Repository: repository,
+		Reference:  reference,
+		ClientIP:   clientIP(request),
+	}
+}
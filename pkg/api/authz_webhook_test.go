@@ -0,0 +1,115 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+func TestAuthzWebhook(t *testing.T) {
+	Convey("Test delegating the authorization decisions to a webhook", t, func() {
+		var (
+			calls    atomic.Int32
+			response atomic.Value
+		)
+
+		response.Store(`{"allow": true}`)
+
+		policyEngine := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			calls.Add(1)
+
+			body, _ := response.Load().(string)
+			if body == "" {
+				writer.WriteHeader(http.StatusInternalServerError)
+
+				return
+			}
+
+			_, _ = writer.Write([]byte(body))
+		}))
+		defer policyEngine.Close()
+
+		authzRequest := api.AuthzWebhookRequest{
+			Type: api.AuthzWebhookRepositoryResource, User: "alice", Groups: []string{"dev", "ci"},
+			Action: "read", Repository: "app", ClientIP: "127.0.0.1",
+		}
+
+		Convey("The decisions are cached", func() {
+			webhook := api.NewAuthzWebhook(&config.AuthzWebhookConfig{URL: policyEngine.URL}, log.NewLogger("debug", ""))
+
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeTrue)
+			So(calls.Load(), ShouldEqual, 1)
+
+			// the order of the groups does not matter
+			authzRequest.Groups = []string{"ci", "dev"}
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeTrue)
+			So(calls.Load(), ShouldEqual, 1)
+
+			authzRequest.Action = "create"
+			response.Store(`{"allow": false, "reason": "read only"}`)
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeFalse)
+			So(calls.Load(), ShouldEqual, 2)
+
+			response.Store(`{"allow": true}`)
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeFalse)
+			So(calls.Load(), ShouldEqual, 2)
+		})
+
+		Convey("The decisions expire", func() {
+			webhook := api.NewAuthzWebhook(&config.AuthzWebhookConfig{
+				URL: policyEngine.URL, CacheTTL: time.Millisecond,
+			}, log.NewLogger("debug", ""))
+
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeTrue)
+			time.Sleep(10 * time.Millisecond)
+
+			response.Store(`{"allow": false}`)
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeFalse)
+			So(calls.Load(), ShouldEqual, 2)
+		})
+
+		Convey("The access is denied when the webhook fails", func() {
+			webhook := api.NewAuthzWebhook(&config.AuthzWebhookConfig{URL: policyEngine.URL}, log.NewLogger("debug", ""))
+
+			response.Store("")
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeFalse)
+
+			response.Store("invalid")
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeFalse)
+
+			// the failures are not cached
+			response.Store(`{"allow": true}`)
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeTrue)
+			So(calls.Load(), ShouldEqual, 3)
+
+			// too slow
+			slowEngine := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+				time.Sleep(100 * time.Millisecond)
+
+				_ = json.NewEncoder(writer).Encode(api.AuthzWebhookResponse{Allow: true})
+			}))
+			defer slowEngine.Close()
+
+			webhook = api.NewAuthzWebhook(&config.AuthzWebhookConfig{
+				URL: slowEngine.URL, Timeout: 10 * time.Millisecond,
+			}, log.NewLogger("debug", ""))
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeFalse)
+
+			// unreachable
+			policyEngine.Close()
+
+			webhook = api.NewAuthzWebhook(&config.AuthzWebhookConfig{URL: policyEngine.URL}, log.NewLogger("debug", ""))
+			So(webhook.Authorize(context.Background(), authzRequest), ShouldBeFalse)
+		})
+	})
+}
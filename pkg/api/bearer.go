@@ -32,6 +32,11 @@ type ResourceAction struct {
 // https://distribution.github.io/distribution/spec/auth/jwt/
 type ClaimsWithAccess struct {
 	Access []ResourceAccess `json:"access"`
+	// private claim of the tokens issued by zot to the users authenticated with a scoped api key
+	APIKeyScopes []string `json:"apiKeyScopes,omitempty"`
+	// private claim of the tokens issued by zot, the groups of the user when it was issued, including the ones
+	// given by its identity provider which are not known to the access control policies
+	Groups []string `json:"groups,omitempty"`
 	jwt.RegisteredClaims
 }
 
@@ -61,14 +66,22 @@ func (c AuthChallengeError) Header() string {
 type BearerAuthorizer struct {
 	realm   string
 	service string
-	key     crypto.PublicKey
+	keyFunc jwt.Keyfunc
 }
 
 func NewBearerAuthorizer(realm string, service string, key crypto.PublicKey) BearerAuthorizer {
+	return NewBearerAuthorizerWithKeyFunc(realm, service, func(token *jwt.Token) (interface{}, error) {
+		return key, nil
+	})
+}
+
+// NewBearerAuthorizerWithKeyFunc returns a BearerAuthorizer looking up the key verifying each token with keyFunc,
+// for example based on its 'kid' header.
+func NewBearerAuthorizerWithKeyFunc(realm string, service string, keyFunc jwt.Keyfunc) BearerAuthorizer {
 	return BearerAuthorizer{
 		realm:   realm,
 		service: service,
-		key:     key,
+		keyFunc: keyFunc,
 	}
 }
 
@@ -76,6 +89,13 @@ func NewBearerAuthorizer(realm string, service string, key crypto.PublicKey) Bea
 // scope for the requested resource action. If an authorization error occurs (e.g. no token is given or the token has
 // insufficient scope), an AuthChallengeError is returned as the error.
 func (a *BearerAuthorizer) Authorize(header string, requested *ResourceAction) error {
+	_, err := a.authorize(header, requested)
+
+	return err
+}
+
+// authorize is Authorize also returning the claims of the token, once it has been verified.
+func (a *BearerAuthorizer) authorize(header string, requested *ResourceAction) (*ClaimsWithAccess, error) {
 	challenge := &AuthChallengeError{
 		realm:          a.realm,
 		service:        a.service,
@@ -86,26 +106,25 @@ func (a *BearerAuthorizer) Authorize(header string, requested *ResourceAction) e
 		// if no bearer token is set in the authorization header, return the authentication challenge
 		challenge.err = zerr.ErrNoBearerToken
 
-		return challenge
+		return nil, challenge
 	}
 
 	signedString := bearerTokenMatch.ReplaceAllString(header, "$1")
 
-	token, err := jwt.ParseWithClaims(signedString, &ClaimsWithAccess{}, func(token *jwt.Token) (interface{}, error) {
-		return a.key, nil
-	}, jwt.WithValidMethods(a.allowedSigningAlgorithms()), jwt.WithIssuedAt())
+	token, err := jwt.ParseWithClaims(signedString, &ClaimsWithAccess{}, a.keyFunc,
+		jwt.WithValidMethods(a.allowedSigningAlgorithms()), jwt.WithIssuedAt())
 	if err != nil {
-		return fmt.Errorf("%w: %w", zerr.ErrInvalidBearerToken, err)
-	}
-
-	if requested == nil {
-		// the token is valid and no access is requested, so we do not have to validate the access claim
-		return nil
+		return nil, fmt.Errorf("%w: %w", zerr.ErrInvalidBearerToken, err)
 	}
 
 	claims, ok := token.Claims.(*ClaimsWithAccess)
 	if !ok {
-		return fmt.Errorf("%w: invalid claims type", zerr.ErrInvalidBearerToken)
+		return nil, fmt.Errorf("%w: invalid claims type", zerr.ErrInvalidBearerToken)
+	}
+
+	if requested == nil {
+		// the token is valid and no access is requested, so we do not have to validate the access claim
+		return claims, nil
 	}
 
 	// check whether the requested access is allowed by the scope of the token
@@ -123,14 +142,18 @@ func (a *BearerAuthorizer) Authorize(header string, requested *ResourceAction) e
 		}
 
 		// requested action is allowed, so don't return an error
-		return nil
+		return claims, nil
 	}
 
 	challenge.err = zerr.ErrInsufficientScope
 
-	return challenge
+	return claims, challenge
 }
 
 func (a *BearerAuthorizer) allowedSigningAlgorithms() []string {
+	return asymmetricSigningAlgorithms()
+}
+
+func asymmetricSigningAlgorithms() []string {
 	return []string{"EdDSA", "RS256", "RS384", "RS512", "ES256", "ES384", "ES512", "PS256", "PS384", "PS512"}
 }
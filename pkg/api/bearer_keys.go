@@ -0,0 +1,413 @@
+package api
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	jose "github.com/go-jose/go-jose/v4"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+const (
+	defaultBearerKeysRefreshInterval = time.Hour
+	// minimum delay between two reloads triggered by tokens signed with an unknown key.
+	minBearerKeysReloadInterval = time.Minute
+	jwksRequestTimeout          = 30 * time.Second
+	maxJWKSSize                 = 1 << 20
+)
+
+// BearerKeySet holds the keys verifying the bearer tokens, loaded from the configured certificates and
+// json web key set. The keys are identified by their key id, the certificates having no key id being identified
+// by their RFC 7638 thumbprint. The set is reloaded periodically and whenever one of its files changes,
+// so the token issuer can rotate its keys without zot being restarted.
+type BearerKeySet struct {
+	certs           []string
+	jwks            string
+	refreshInterval time.Duration
+	keys            map[string]crypto.PublicKey
+	lock            sync.RWMutex
+	reloadLock      sync.Mutex
+	reloadedAt      time.Time // last reload, successful or not, guarded by reloadLock
+	httpClient      *http.Client
+	watcher         *fsnotify.Watcher
+	cancel          context.CancelFunc
+	done            chan struct{}
+	log             log.Logger
+}
+
+// NewBearerKeySet loads the keys configured for bearer authentication and starts reloading them in the background.
+// A json web key set served over http which can not be fetched yet is only logged, as it is retried on
+// the next reload, while an invalid file is returned as an error.
+func NewBearerKeySet(bearerConfig *config.BearerConfig, log log.Logger) (*BearerKeySet, error) {
+	certs := []string{}
+
+	if bearerConfig.Cert != "" {
+		certs = append(certs, bearerConfig.Cert)
+	}
+
+	certs = append(certs, bearerConfig.Certs...)
+
+	return newBearerKeySet(certs, bearerConfig.JWKS, bearerConfig.KeysRefreshInterval, log)
+}
+
+func newBearerKeySet(certs []string, jwks string, refreshInterval time.Duration, log log.Logger,
+) (*BearerKeySet, error) {
+	keySet := &BearerKeySet{
+		certs:           certs,
+		jwks:            jwks,
+		refreshInterval: refreshInterval,
+		keys:            map[string]crypto.PublicKey{},
+		httpClient:      &http.Client{Timeout: jwksRequestTimeout},
+		done:            make(chan struct{}),
+		log:             log,
+	}
+
+	if keySet.refreshInterval == 0 {
+		keySet.refreshInterval = defaultBearerKeysRefreshInterval
+	}
+
+	if err := keySet.Reload(); err != nil {
+		if !isJWKSURL(keySet.jwks) || !errors.Is(err, zerr.ErrInvalidJWKS) {
+			return nil, err
+		}
+
+		keySet.log.Warn().Err(err).Str("jwks", keySet.jwks).Msg("failed to load bearer keys, will retry")
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// watch the directories instead of the files themselves, as these are usually replaced instead of updated
+	watchedDirs := map[string]bool{}
+
+	for _, file := range keySet.files() {
+		dir := filepath.Dir(file)
+		if watchedDirs[dir] {
+			continue
+		}
+
+		if err := watcher.Add(dir); err != nil {
+			return nil, errors.Join(err, watcher.Close())
+		}
+
+		watchedDirs[dir] = true
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	keySet.watcher = watcher
+	keySet.cancel = cancel
+
+	go keySet.run(ctx)
+
+	return keySet, nil
+}
+
+// Key returns the key identified by keyID.
+func (ks *BearerKeySet) Key(keyID string) (crypto.PublicKey, bool) {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	key, ok := ks.keys[keyID]
+
+	return key, ok
+}
+
+// Keys returns all the keys of the set.
+func (ks *BearerKeySet) Keys() []crypto.PublicKey {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	keys := make([]crypto.PublicKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key)
+	}
+
+	return keys
+}
+
+// KeyIDs returns the ids of all the keys of the set.
+func (ks *BearerKeySet) KeyIDs() []string {
+	ks.lock.RLock()
+	defer ks.lock.RUnlock()
+
+	keyIDs := make([]string, 0, len(ks.keys))
+	for keyID := range ks.keys {
+		keyIDs = append(keyIDs, keyID)
+	}
+
+	return keyIDs
+}
+
+// Reload loads all the keys again, the previous keys being kept if any of the sources fails to load.
+func (ks *BearerKeySet) Reload() error {
+	ks.reloadLock.Lock()
+	defer ks.reloadLock.Unlock()
+
+	return ks.reload()
+}
+
+// reload is Reload, the caller holding reloadLock.
+func (ks *BearerKeySet) reload() error {
+	ks.reloadedAt = time.Now()
+
+	keys := map[string]crypto.PublicKey{}
+
+	for _, path := range ks.certs {
+		certificates, err := loadCertificatesFromFile(path)
+		if err != nil {
+			return err
+		}
+
+		for _, certificate := range certificates {
+			keyID, err := publicKeyThumbprint(certificate.PublicKey)
+			if err != nil {
+				return fmt.Errorf("%w: %w, path %s", zerr.ErrCouldNotLoadCertificate, err, path)
+			}
+
+			keys[keyID] = certificate.PublicKey
+		}
+	}
+
+	if ks.jwks != "" {
+		jwksKeys, err := ks.loadJWKS()
+		if err != nil {
+			return err
+		}
+
+		for keyID, key := range jwksKeys {
+			keys[keyID] = key
+		}
+	}
+
+	ks.lock.Lock()
+	ks.keys = keys
+	ks.lock.Unlock()
+
+	ks.log.Info().Int("keys", len(keys)).Msg("loaded bearer authentication keys")
+
+	return nil
+}
+
+// reloadForUnknownKey reloads the keys when a token is signed with a key which is not in the set, the issuer
+// possibly having rotated its keys since the last reload. These reloads are rate limited, failed ones included,
+// and the concurrent requests wait for a single reload instead of each sending its own.
+func (ks *BearerKeySet) reloadForUnknownKey() {
+	ks.reloadLock.Lock()
+	defer ks.reloadLock.Unlock()
+
+	if time.Since(ks.reloadedAt) < minBearerKeysReloadInterval {
+		return
+	}
+
+	if err := ks.reload(); err != nil {
+		ks.log.Warn().Err(err).Msg("failed to reload bearer authentication keys, keeping the previous keys")
+	}
+}
+
+// Close stops reloading the keys.
+func (ks *BearerKeySet) Close() {
+	if ks.cancel == nil {
+		return
+	}
+
+	ks.cancel()
+	<-ks.done
+}
+
+func (ks *BearerKeySet) run(ctx context.Context) {
+	defer close(ks.done)
+	defer ks.watcher.Close() //nolint: errcheck
+
+	ticker := time.NewTicker(ks.refreshInterval)
+	defer ticker.Stop()
+
+	files := map[string]bool{}
+	for _, file := range ks.files() {
+		files[filepath.Clean(file)] = true
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := ks.Reload(); err != nil {
+				ks.log.Warn().Err(err).Msg("failed to reload bearer authentication keys, keeping the previous keys")
+			}
+
+		case event := <-ks.watcher.Events:
+			if !files[filepath.Clean(event.Name)] || event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			ks.log.Info().Str("file", event.Name).Msg("bearer authentication keys changed, trying to reload them")
+
+			if err := ks.Reload(); err != nil {
+				ks.log.Warn().Err(err).Msg("failed to reload bearer authentication keys, keeping the previous keys")
+			}
+
+		case err := <-ks.watcher.Errors:
+			ks.log.Error().Err(err).Msg("failed to watch bearer authentication keys files")
+
+		case <-ctx.Done():
+			ks.log.Debug().Msg("bearer authentication keys watcher terminating...")
+
+			return
+		}
+	}
+}
+
+// files returns the local files the keys are loaded from.
+func (ks *BearerKeySet) files() []string {
+	files := append([]string{}, ks.certs...)
+
+	if ks.jwks != "" && !isJWKSURL(ks.jwks) {
+		files = append(files, ks.jwks)
+	}
+
+	return files
+}
+
+func (ks *BearerKeySet) loadJWKS() (map[string]crypto.PublicKey, error) {
+	var (
+		content []byte
+		err     error
+	)
+
+	if isJWKSURL(ks.jwks) {
+		content, err = ks.fetchJWKS()
+	} else {
+		content, err = os.ReadFile(ks.jwks)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w, jwks %s", zerr.ErrInvalidJWKS, err, ks.jwks)
+	}
+
+	var jwks jose.JSONWebKeySet
+
+	if err := json.Unmarshal(content, &jwks); err != nil {
+		return nil, fmt.Errorf("%w: %w, jwks %s", zerr.ErrInvalidJWKS, err, ks.jwks)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+
+	for _, jwk := range jwks.Keys {
+		// keys meant for encryption do not verify signatures
+		if jwk.Use == "enc" {
+			continue
+		}
+
+		// symmetric keys are not supported
+		publicKey := jwk.Public()
+		if publicKey.Key == nil {
+			continue
+		}
+
+		keyID := jwk.KeyID
+		if keyID == "" {
+			keyID, err = publicKeyThumbprint(publicKey.Key)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %w, jwks %s", zerr.ErrInvalidJWKS, err, ks.jwks)
+			}
+		}
+
+		keys[keyID] = publicKey.Key
+	}
+
+	return keys, nil
+}
+
+func (ks *BearerKeySet) fetchJWKS() ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jwksRequestTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.jwks, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Header.Set("Accept", "application/json")
+
+	response, err := ks.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: %d", zerr.ErrBadHTTPStatusCode, response.StatusCode)
+	}
+
+	return io.ReadAll(io.LimitReader(response.Body, maxJWKSSize))
+}
+
+func isJWKSURL(jwks string) bool {
+	return strings.HasPrefix(jwks, "http://") || strings.HasPrefix(jwks, "https://")
+}
+
+// publicKeyThumbprint returns the RFC 7638 thumbprint of key, used as the id of the keys which have none.
+func publicKeyThumbprint(key crypto.PublicKey) (string, error) {
+	jwk := jose.JSONWebKey{Key: key}
+
+	thumbprint, err := jwk.Thumbprint(crypto.SHA256)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(thumbprint), nil
+}
+
+// loadCertificatesFromFile returns all the certificates of a PEM file.
+func loadCertificatesFromFile(path string) ([]*x509.Certificate, error) {
+	rawCerts, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w, path %s", zerr.ErrCouldNotLoadCertificate, err, path)
+	}
+
+	certificates := []*x509.Certificate{}
+
+	for {
+		var block *pem.Block
+
+		block, rawCerts = pem.Decode(rawCerts)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w, path %s", zerr.ErrCouldNotLoadCertificate, err, path)
+		}
+
+		certificates = append(certificates, cert)
+	}
+
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("%w: no valid PEM data found, path %s", zerr.ErrCouldNotLoadCertificate, path)
+	}
+
+	return certificates, nil
+}
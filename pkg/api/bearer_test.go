@@ -1,16 +1,32 @@
 package api_test
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync"
 	"testing"
 	"time"
 
+	jose "github.com/go-jose/go-jose/v4"
 	"github.com/golang-jwt/jwt/v5"
 	. "github.com/smartystreets/goconvey/convey"
 
 	zerr "zotregistry.dev/zot/errors"
 	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/log"
 )
 
 func TestBearerAuthorizer(t *testing.T) {
@@ -119,3 +135,334 @@ func TestBearerAuthorizer(t *testing.T) {
 		})
 	})
 }
+
+func TestTokenIssuer(t *testing.T) {
+	Convey("Test bearer token issuer key rotation", t, func() {
+		bearerConfig := &config.BearerConfig{
+			Service: "service",
+			TokenServer: &config.TokenServerConfig{
+				Expiration:          time.Hour,
+				KeyRotationInterval: time.Nanosecond,
+			},
+		}
+
+		issuer, err := api.NewTokenIssuer(bearerConfig, log.NewLogger("debug", ""))
+		So(err, ShouldBeNil)
+		So(issuer.Issuer(), ShouldEqual, "service")
+
+		getKeyID := func(token string) string {
+			parsed, _, err := jwt.NewParser().ParseUnverified(token, &api.ClaimsWithAccess{})
+			So(err, ShouldBeNil)
+
+			keyID, ok := parsed.Header["kid"].(string)
+			So(ok, ShouldBeTrue)
+
+			return keyID
+		}
+
+		authorizer := api.NewBearerAuthorizerWithKeyFunc("realm", "service", func(token *jwt.Token) (interface{}, error) {
+			keyID, _ := token.Header["kid"].(string)
+
+			key, ok := issuer.PublicKey(keyID)
+			if !ok {
+				return nil, zerr.ErrBearerKeyNotFound
+			}
+
+			return key, nil
+		})
+
+		access := []api.ResourceAccess{{Type: "repository", Name: "repo", Actions: []string{"pull"}}}
+		requested := &api.ResourceAction{Type: "repository", Name: "repo", Action: "pull"}
+
+		token1, expiresAt, err := issuer.Issue("user", nil, access, nil)
+		So(err, ShouldBeNil)
+		So(expiresAt, ShouldHappenAfter, time.Now().Add(59*time.Minute))
+
+		token2, _, err := issuer.Issue("user", nil, access, nil)
+		So(err, ShouldBeNil)
+		So(getKeyID(token2), ShouldNotEqual, getKeyID(token1))
+
+		// the tokens signed with the previous keys are still valid
+		So(authorizer.Authorize("Bearer "+token1, requested), ShouldBeNil)
+		So(authorizer.Authorize("Bearer "+token2, requested), ShouldBeNil)
+
+		Convey("Keys are dropped once all the tokens they signed expired", func() {
+			bearerConfig.TokenServer.Expiration = time.Millisecond
+			issuer, err := api.NewTokenIssuer(bearerConfig, log.NewLogger("debug", ""))
+			So(err, ShouldBeNil)
+
+			token1, _, err := issuer.Issue("user", nil, access, nil)
+			So(err, ShouldBeNil)
+
+			token2, _, err := issuer.Issue("user", nil, access, nil)
+			So(err, ShouldBeNil)
+
+			time.Sleep(10 * time.Millisecond)
+
+			_, _, err = issuer.Issue("user", nil, access, nil)
+			So(err, ShouldBeNil)
+
+			_, ok := issuer.PublicKey(getKeyID(token1))
+			So(ok, ShouldBeFalse)
+
+			// the previous key was just retired, it may have signed a token which is still valid
+			_, ok = issuer.PublicKey(getKeyID(token2))
+			So(ok, ShouldBeTrue)
+		})
+
+		Convey("Defaults are applied", func() {
+			issuer, err := api.NewTokenIssuer(&config.BearerConfig{Service: "service", TokenServer: &config.TokenServerConfig{
+				Issuer: "issuer",
+			}}, log.NewLogger("debug", ""))
+			So(err, ShouldBeNil)
+			So(issuer.Issuer(), ShouldEqual, "issuer")
+
+			token, expiresAt, err := issuer.Issue("", nil, access, nil)
+			So(err, ShouldBeNil)
+			So(expiresAt, ShouldHappenWithin, 5*time.Minute+time.Second, time.Now())
+
+			// the key is kept until the next rotation
+			token2, _, err := issuer.Issue("", nil, access, nil)
+			So(err, ShouldBeNil)
+			So(getKeyID(token2), ShouldEqual, getKeyID(token))
+		})
+
+		Convey("A configured signing key is shared and never rotated", func() {
+			tempDir := t.TempDir()
+
+			ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			So(err, ShouldBeNil)
+
+			keyDER, err := x509.MarshalPKCS8PrivateKey(ecKey)
+			So(err, ShouldBeNil)
+
+			keyPath := path.Join(tempDir, "signing.key")
+			err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+			So(err, ShouldBeNil)
+
+			bearerConfig.TokenServer.SigningKey = keyPath
+
+			// the issuers of the members of a cluster verify the tokens of each other
+			issuer1, err := api.NewTokenIssuer(bearerConfig, log.NewLogger("debug", ""))
+			So(err, ShouldBeNil)
+
+			issuer2, err := api.NewTokenIssuer(bearerConfig, log.NewLogger("debug", ""))
+			So(err, ShouldBeNil)
+
+			token1, _, err := issuer1.Issue("user", nil, access, nil)
+			So(err, ShouldBeNil)
+
+			token2, _, err := issuer1.Issue("user", nil, access, nil)
+			So(err, ShouldBeNil)
+			So(getKeyID(token2), ShouldEqual, getKeyID(token1))
+
+			key, ok := issuer2.PublicKey(getKeyID(token1))
+			So(ok, ShouldBeTrue)
+			So(key, ShouldResemble, ecKey.Public())
+
+			// sec 1 encoded keys are accepted too
+			keyDER, err = x509.MarshalECPrivateKey(ecKey)
+			So(err, ShouldBeNil)
+
+			err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600)
+			So(err, ShouldBeNil)
+
+			issuer3, err := api.NewTokenIssuer(bearerConfig, log.NewLogger("debug", ""))
+			So(err, ShouldBeNil)
+
+			_, ok = issuer3.PublicKey(getKeyID(token1))
+			So(ok, ShouldBeTrue)
+
+			// only ECDSA P-256 keys can sign ES256 tokens
+			rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+			So(err, ShouldBeNil)
+
+			keyDER, err = x509.MarshalPKCS8PrivateKey(rsaKey)
+			So(err, ShouldBeNil)
+
+			err = os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0o600)
+			So(err, ShouldBeNil)
+
+			_, err = api.NewTokenIssuer(bearerConfig, log.NewLogger("debug", ""))
+			So(err, ShouldWrap, zerr.ErrInvalidTokenSigningKey)
+
+			err = os.WriteFile(keyPath, []byte("not a key"), 0o600)
+			So(err, ShouldBeNil)
+
+			_, err = api.NewTokenIssuer(bearerConfig, log.NewLogger("debug", ""))
+			So(err, ShouldWrap, zerr.ErrInvalidTokenSigningKey)
+
+			bearerConfig.TokenServer.SigningKey = path.Join(tempDir, "missing.key")
+
+			_, err = api.NewTokenIssuer(bearerConfig, log.NewLogger("debug", ""))
+			So(err, ShouldNotBeNil)
+		})
+	})
+}
+
+func TestBearerKeySet(t *testing.T) {
+	Convey("Test bearer keys loaded from certificates and json web key sets", t, func() {
+		logger := log.NewLogger("debug", "")
+		tempDir := t.TempDir()
+
+		ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
+
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		So(err, ShouldBeNil)
+
+		writeCertificates := func(path string, keys ...crypto.Signer) {
+			content := []byte{}
+
+			for idx, key := range keys {
+				template := &x509.Certificate{
+					SerialNumber: big.NewInt(int64(idx + 1)),
+					NotBefore:    time.Now(),
+					NotAfter:     time.Now().Add(time.Hour),
+				}
+
+				der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+				So(err, ShouldBeNil)
+
+				content = append(content, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+			}
+
+			So(os.WriteFile(path, content, 0o600), ShouldBeNil)
+		}
+
+		marshalJWKS := func(keys ...jose.JSONWebKey) []byte {
+			content, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+			So(err, ShouldBeNil)
+
+			return content
+		}
+
+		Convey("Certificate bundles are loaded", func() {
+			certPath := path.Join(tempDir, "bundle.crt")
+			writeCertificates(certPath, ecKey, rsaKey)
+
+			keySet, err := api.NewBearerKeySet(&config.BearerConfig{Certs: []string{certPath}}, logger)
+			So(err, ShouldBeNil)
+
+			defer keySet.Close()
+
+			So(keySet.Keys(), ShouldHaveLength, 2)
+
+			// the keys of the certificates are identified by their thumbprint
+			thumbprint, err := (&jose.JSONWebKey{Key: ecKey.Public()}).Thumbprint(crypto.SHA256)
+			So(err, ShouldBeNil)
+
+			key, ok := keySet.Key(base64.RawURLEncoding.EncodeToString(thumbprint))
+			So(ok, ShouldBeTrue)
+			So(key, ShouldResemble, ecKey.Public())
+		})
+
+		Convey("Invalid certificates are rejected", func() {
+			certPath := path.Join(tempDir, "invalid.crt")
+			So(os.WriteFile(certPath, []byte("invalid"), 0o600), ShouldBeNil)
+
+			_, err := api.NewBearerKeySet(&config.BearerConfig{Cert: certPath}, logger)
+			So(err, ShouldWrap, zerr.ErrCouldNotLoadCertificate)
+
+			_, err = api.NewBearerKeySet(&config.BearerConfig{Cert: path.Join(tempDir, "missing.crt")}, logger)
+			So(err, ShouldWrap, zerr.ErrCouldNotLoadCertificate)
+		})
+
+		Convey("JSON web key set files are reloaded when they change", func() {
+			jwksPath := path.Join(tempDir, "jwks.json")
+			So(os.WriteFile(jwksPath, marshalJWKS(
+				jose.JSONWebKey{Key: ecKey.Public(), KeyID: "ec", Use: "sig"},
+				jose.JSONWebKey{Key: rsaKey.Public(), KeyID: "enc", Use: "enc"},
+				jose.JSONWebKey{Key: []byte("secret"), KeyID: "symmetric"},
+			), 0o600), ShouldBeNil)
+
+			keySet, err := api.NewBearerKeySet(&config.BearerConfig{JWKS: jwksPath}, logger)
+			So(err, ShouldBeNil)
+
+			defer keySet.Close()
+
+			// only the public keys used for signatures are loaded
+			So(keySet.KeyIDs(), ShouldResemble, []string{"ec"})
+
+			So(os.WriteFile(jwksPath, marshalJWKS(
+				jose.JSONWebKey{Key: rsaKey.Public(), KeyID: "rsa"},
+			), 0o600), ShouldBeNil)
+
+			for range 50 {
+				if _, ok := keySet.Key("rsa"); ok {
+					break
+				}
+
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			So(keySet.KeyIDs(), ShouldResemble, []string{"rsa"})
+
+			// the previous keys are kept if the file becomes invalid
+			So(os.WriteFile(jwksPath, []byte("invalid"), 0o600), ShouldBeNil)
+			time.Sleep(500 * time.Millisecond)
+
+			So(keySet.KeyIDs(), ShouldResemble, []string{"rsa"})
+
+			So(keySet.Reload(), ShouldWrap, zerr.ErrInvalidJWKS)
+		})
+
+		Convey("JSON web key sets are fetched periodically", func() {
+			jwks := marshalJWKS(jose.JSONWebKey{Key: ecKey.Public(), KeyID: "ec"})
+			lock := sync.Mutex{}
+			status := http.StatusServiceUnavailable
+
+			server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				response.WriteHeader(status)
+				_, _ = response.Write(jwks)
+			}))
+			defer server.Close()
+
+			// the url not being available yet is not fatal
+			keySet, err := api.NewBearerKeySet(&config.BearerConfig{
+				JWKS:                server.URL,
+				KeysRefreshInterval: 100 * time.Millisecond,
+			}, logger)
+			So(err, ShouldBeNil)
+
+			defer keySet.Close()
+
+			So(keySet.Keys(), ShouldBeEmpty)
+
+			lock.Lock()
+			status = http.StatusOK
+			lock.Unlock()
+
+			for range 50 {
+				if len(keySet.Keys()) > 0 {
+					break
+				}
+
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			So(keySet.KeyIDs(), ShouldResemble, []string{"ec"})
+
+			// keys without a key id are identified by their thumbprint
+			lock.Lock()
+			jwks = marshalJWKS(jose.JSONWebKey{Key: rsaKey.Public()})
+			lock.Unlock()
+
+			thumbprint, err := (&jose.JSONWebKey{Key: rsaKey.Public()}).Thumbprint(crypto.SHA256)
+			So(err, ShouldBeNil)
+
+			for range 50 {
+				if _, ok := keySet.Key(base64.RawURLEncoding.EncodeToString(thumbprint)); ok {
+					break
+				}
+
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			So(keySet.KeyIDs(), ShouldResemble, []string{base64.RawURLEncoding.EncodeToString(thumbprint)})
+		})
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"zotregistry.dev/zot/pkg/api/config"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+)
+
+const forwardedForHeader = "X-Forwarded-For"
+
+// ClientIPHandler saves on the requests sent by the trusted reverse proxies the client ip given by their
+// X-Forwarded-For header: the rightmost address which is not a trusted proxy, as the clients can send
+// the header themselves and only the addresses appended by the trusted proxies can be relied on.
+func ClientIPHandler(ctlr *Controller) mux.MiddlewareFunc {
+	trustedProxies, err := config.ParseCIDRs(ctlr.Config.HTTP.TrustedProxyCIDRs)
+	if err != nil {
+		ctlr.Log.Panic().Err(err).Msg("failed to parse trusted proxy cidrs")
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if ip := forwardedClientIP(request, trustedProxies); ip != "" {
+				request = request.WithContext(reqCtx.WithClientIP(request.Context(), ip))
+			}
+
+			next.ServeHTTP(response, request)
+		})
+	}
+}
+
+// forwardedClientIP returns the client ip given by the X-Forwarded-For header of a request sent by
+// a trusted proxy, or an empty string if there is none.
+func forwardedClientIP(request *http.Request, trustedProxies []*net.IPNet) string {
+	if !networksContain(trustedProxies, net.ParseIP(remoteIP(request))) {
+		return ""
+	}
+
+	addresses := []string{}
+
+	for _, header := range request.Header.Values(forwardedForHeader) {
+		for _, address := range strings.Split(header, ",") {
+			addresses = append(addresses, strings.TrimSpace(address))
+		}
+	}
+
+	// each proxy appends the address it received the request from
+	for index := len(addresses) - 1; index >= 0; index-- {
+		ip := net.ParseIP(addresses[index])
+		if ip == nil {
+			return ""
+		}
+
+		if index == 0 || !networksContain(trustedProxies, ip) {
+			return ip.String()
+		}
+	}
+
+	return ""
+}
+
+// clientIP returns the address of the client which made the request, without its port,
+// the one given by a trusted reverse proxy if any.
+func clientIP(request *http.Request) string {
+	if ip := reqCtx.ClientIPFromContext(request.Context()); ip != "" {
+		return ip
+	}
+
+	return remoteIP(request)
+}
+
+// remoteIP returns the address the request was received from, without its port.
+func remoteIP(request *http.Request) string {
+	host, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		return strings.Trim(request.RemoteAddr, "[]")
+	}
+
+	return host
+}
+
+func networksContain(networks []*net.IPNet, ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,168 @@
+//go:build sync && scrub && metrics && search && lint && userprefs && mgmt && imagetrust && ui
+// +build sync,scrub,metrics,search,lint,userprefs,mgmt,imagetrust,ui
+
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/resty.v1"
+
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/api/constants"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+	test "zotregistry.dev/zot/pkg/test/common"
+	. "zotregistry.dev/zot/pkg/test/image-utils"
+	ociutils "zotregistry.dev/zot/pkg/test/oci-utils"
+)
+
+func TestClientIPHandler(t *testing.T) {
+	Convey("Make a new client ip handler", t, func() {
+		conf := config.New()
+		conf.HTTP.TrustedProxyCIDRs = []string{"10.0.0.0/24", "fd00::/64"}
+
+		handler := api.ClientIPHandler(api.NewController(conf))(http.HandlerFunc(
+			func(response http.ResponseWriter, request *http.Request) {
+				_, _ = response.Write([]byte(reqCtx.ClientIPFromContext(request.Context())))
+			}))
+
+		clientIP := func(remoteAddr string, forwardedFor ...string) string {
+			request := httptest.NewRequest(http.MethodGet, "/v2/", nil)
+			request.RemoteAddr = remoteAddr
+
+			for _, header := range forwardedFor {
+				request.Header.Add("X-Forwarded-For", header)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			return recorder.Body.String()
+		}
+
+		// the header is only trusted from the trusted proxies
+		So(clientIP("10.0.0.1:1234", "172.16.0.1"), ShouldEqual, "172.16.0.1")
+		So(clientIP("[fd00::1]:1234", "2001:db8::1"), ShouldEqual, "2001:db8::1")
+		So(clientIP("10.0.1.1:1234", "172.16.0.1"), ShouldEqual, "")
+		So(clientIP("10.0.0.1:1234"), ShouldEqual, "")
+
+		// the addresses sent by the clients themselves are ignored
+		So(clientIP("10.0.0.1:1234", "192.168.1.1, 172.16.0.1"), ShouldEqual, "172.16.0.1")
+		So(clientIP("10.0.0.1:1234", "192.168.1.1", "172.16.0.1, 10.0.0.2"), ShouldEqual, "172.16.0.1")
+		So(clientIP("10.0.0.1:1234", "10.0.0.3, 10.0.0.2"), ShouldEqual, "10.0.0.3")
+		So(clientIP("10.0.0.1:1234", "unknown, 10.0.0.2"), ShouldEqual, "")
+	})
+}
+
+func TestNetworkPolicies(t *testing.T) {
+	Convey("Make a new controller with network policies", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		username, password := "alice", "alice"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(username, password))
+		defer os.Remove(htpasswdPath)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+		}
+		// the requests are sent through a proxy listening on localhost
+		conf.HTTP.TrustedProxyCIDRs = []string{"127.0.0.0/8"}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{Users: []string{username}, Actions: []string{constants.ReadPermission}},
+					},
+					NetworkPolicies: []config.NetworkPolicy{
+						{Actions: []string{constants.ReadPermission}, DenyCIDRs: []string{"192.168.0.0/16"}},
+					},
+				},
+				"internal/**": config.PolicyGroup{
+					Policies: []config.Policy{
+						{Users: []string{username}, Actions: []string{constants.ReadPermission}},
+					},
+					NetworkPolicies: []config.NetworkPolicy{
+						{Actions: []string{constants.ReadPermission}, AllowCIDRs: []string{"10.0.0.0/8"}},
+					},
+				},
+			},
+			AdminPolicy: config.Policy{
+				Users:   []string{username},
+				Actions: []string{constants.ReadPermission},
+			},
+		}
+
+		ctlr := makeController(conf, t.TempDir())
+
+		for _, repo := range []string{"internal/app", "public/app"} {
+			err := WriteImageToFileSystem(CreateDefaultImage(), repo, "0.0.1",
+				ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
+			So(err, ShouldBeNil)
+		}
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		get := func(forwardedFor, path string) *resty.Response {
+			request := resty.R().SetBasicAuth(username, password)
+			if forwardedFor != "" {
+				request.SetHeader("X-Forwarded-For", forwardedFor)
+			}
+
+			resp, err := request.Get(baseURL + path)
+			So(err, ShouldBeNil)
+
+			return resp
+		}
+
+		Convey("The repositories can only be pulled from the allowed networks", func() {
+			So(get("10.1.2.3", "/v2/internal/app/tags/list").StatusCode(), ShouldEqual, http.StatusOK)
+			So(get("172.16.0.1", "/v2/internal/app/tags/list").StatusCode(), ShouldEqual, http.StatusForbidden)
+			So(get("", "/v2/internal/app/tags/list").StatusCode(), ShouldEqual, http.StatusForbidden)
+			// even by the admins
+			So(get("172.16.0.1", "/v2/internal/app/manifests/0.0.1").StatusCode(), ShouldEqual,
+				http.StatusForbidden)
+
+			// the clients can not pretend to be on an allowed network
+			So(get("10.1.2.3, 172.16.0.1", "/v2/internal/app/tags/list").StatusCode(), ShouldEqual,
+				http.StatusForbidden)
+		})
+
+		Convey("The repositories can not be pulled from the denied networks", func() {
+			So(get("172.16.0.1", "/v2/public/app/tags/list").StatusCode(), ShouldEqual, http.StatusOK)
+			So(get("192.168.1.1", "/v2/public/app/tags/list").StatusCode(), ShouldEqual, http.StatusForbidden)
+			So(get("192.168.1.1", "/v2/internal/app/tags/list").StatusCode(), ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("The catalog only lists the repositories allowed from the client ip", func() {
+			catalog := func(forwardedFor string) []string {
+				resp := get(forwardedFor, "/v2/_catalog")
+				So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+				var repositories struct {
+					Repositories []string `json:"repositories"`
+				}
+
+				So(json.Unmarshal(resp.Body(), &repositories), ShouldBeNil)
+
+				return repositories.Repositories
+			}
+
+			So(catalog("10.1.2.3"), ShouldResemble, []string{"internal/app", "public/app"})
+			So(catalog("172.16.0.1"), ShouldResemble, []string{"public/app"})
+			So(catalog("192.168.1.1"), ShouldBeEmpty)
+		})
+	})
+}
@@ -2,7 +2,11 @@ package config
 
 import (
 	"encoding/json"
+	"fmt"
+	"net"
 	"os"
+	"regexp"
+	"strconv"
 	"time"
 
 	distspec "github.com/opencontainers/distribution-spec/specs-go"
@@ -32,10 +36,27 @@ type StorageConfig struct {
 	GCDelay       time.Duration // applied for blobs
 	GCInterval    time.Duration
 	Retention     ImageRetention
+	Quotas        []StorageQuota         `mapstructure:",omitempty"`
+	ImmutableTags []ImmutableTagsPolicy  `mapstructure:",omitempty"`
 	StorageDriver map[string]interface{} `mapstructure:",omitempty"`
 	CacheDriver   map[string]interface{} `mapstructure:",omitempty"`
 }
 
+// StorageQuota limits the usage of all the repositories matching any of the given globs,
+// a single repository name gives a per-repository quota and a glob a per-namespace quota.
+type StorageQuota struct {
+	Repositories []string
+	MaxSize      int64 // bytes, 0 means unlimited
+	MaxImages    int   // tags, 0 means unlimited
+}
+
+// ImmutableTagsPolicy makes the tags matching any of the regex patterns write-once
+// in the repositories matching any of the globs, an empty list of patterns matching all tags.
+type ImmutableTagsPolicy struct {
+	Repositories []string
+	Patterns     []string
+}
+
 type ImageRetention struct {
 	DryRun   bool
 	Delay    time.Duration // applied for referrers and untagged
@@ -65,6 +86,8 @@ type TLSConfig struct {
 
 type AuthHTPasswd struct {
 	Path string
+	// algorithm hashing the passwords set through the mgmt api, bcrypt (default) or argon2id
+	HashAlgorithm string
 }
 
 type AuthConfig struct {
@@ -73,6 +96,10 @@ type AuthConfig struct {
 	LDAP              *LDAPConfig
 	Bearer            *BearerConfig
 	OpenID            *OpenIDConfig
+	WorkloadIdentity  *WorkloadIdentityConfig
+	ProxyHeader       *ProxyHeaderConfig
+	MTLS              *MTLSConfig
+	Lockout           *LockoutConfig
 	APIKey            bool
 	SessionKeysFile   string
 	SessionHashKey    []byte `json:"-"`
@@ -83,6 +110,96 @@ type BearerConfig struct {
 	Realm   string
 	Service string
 	Cert    string
+	// additional certificates, the tokens being verified with the key matching their 'kid' header
+	Certs []string
+	// path or http(s) url of a json web key set
+	JWKS string
+	// how often the certificates and the json web key set are reloaded, defaults to 1 hour
+	KeysRefreshInterval time.Duration
+	TokenServer         *TokenServerConfig
+}
+
+// TokenServerConfig makes zot issue the bearer tokens itself, the realm pointing to its token endpoint.
+type TokenServerConfig struct {
+	Issuer              string        // defaults to the service
+	Expiration          time.Duration // lifetime of the tokens, defaults to 5 minutes
+	KeyRotationInterval time.Duration // how often the signing key is replaced, defaults to 24 hours
+	// path of a PEM encoded ECDSA P-256 private key signing the tokens instead of the generated ones,
+	// it is not rotated and lets the members of a cluster verify the tokens issued by each other
+	SigningKey string
+}
+
+// WorkloadIdentityConfig lets CI jobs authenticate with the OIDC ID tokens of their platform
+// (GitHub Actions, GitLab CI, ...), given as password or bearer token, instead of long-lived credentials.
+type WorkloadIdentityConfig struct {
+	Issuers []WorkloadIdentityIssuerConfig
+}
+
+type WorkloadIdentityIssuerConfig struct {
+	Issuer   string // expected 'iss' claim
+	Audience string // expected 'aud' claim
+	// path or http(s) url of the json web key set of the issuer
+	JWKS string
+	// how often the json web key set is reloaded, defaults to 1 hour
+	KeysRefreshInterval time.Duration
+	// the first rule matching the claims of a token gives its identity, tokens matching none are rejected
+	Rules []WorkloadIdentityRule
+}
+
+// WorkloadIdentityRule maps the tokens whose claims match all its glob patterns to a zot identity.
+type WorkloadIdentityRule struct {
+	Claims map[string]string
+	// identity of the tokens, possibly referencing their claims as {claim}, defaults to the 'sub' claim
+	Identity string
+	Groups   []string
+}
+
+// ProxyHeaderConfig trusts the user and groups headers set by an authenticating reverse proxy (SSO proxy).
+// The headers are only trusted from the proxies reaching zot from TrustedCIDRs or with a client certificate
+// matching TrustedCertNames, the requests sending them from anywhere else being rejected.
+type ProxyHeaderConfig struct {
+	// defaults to X-Forwarded-User
+	UserHeader string
+	// defaults to X-Forwarded-Groups
+	GroupsHeader string
+	// separator of the groups in GroupsHeader, defaults to ","
+	GroupsSeparator string
+	TrustedCIDRs    []string
+	// common names or dns names of the verified mTLS client certificates of the proxies
+	TrustedCertNames []string
+}
+
+// MTLSConfig maps the verified client certificates to identities and groups. When it is set, the client
+// certificates also authenticate the requests sent without credentials while the other methods are enabled.
+type MTLSConfig struct {
+	// the first rule matching a value of its attribute gives the identity, defaults to the common name
+	IdentityRules []CertificateMappingRule
+	// every value matching one of the rules gives a group
+	GroupRules []CertificateMappingRule
+}
+
+type CertificateMappingRule struct {
+	// commonName, organization, organizationalUnit, email, dnsName or uri
+	Attribute string
+	// regular expression the value must fully match, any value by default
+	Pattern string
+	// mapped value, referencing the submatches of the pattern as $1 or ${name}, defaults to the value itself
+	Value string
+}
+
+// LockoutConfig rejects the credentials of the users and client addresses which failed to authenticate
+// too many times, the failures being counted in redis if it is the remote cache driver.
+type LockoutConfig struct {
+	// failures of a user within the window locking it out, defaults to 5
+	UserThreshold int
+	// failures from a client ip within the window locking it out, defaults to 20
+	IPThreshold int
+	// period the failures are counted over, defaults to 15 minutes
+	Window time.Duration
+	// duration of the first lockout, doubled by each following lockout within a day, defaults to 1 minute
+	Duration time.Duration
+	// longest lockout, defaults to 1 hour
+	MaxDuration time.Duration
 }
 
 type SessionKeys struct {
@@ -101,6 +218,16 @@ type OpenIDProviderConfig struct {
 	KeyPath      string
 	Issuer       string
 	Scopes       []string
+	// claim of the id token or of the userinfo holding the groups, nested claims being separated by dots
+	// (realm_access.roles), defaults to "groups". The github organizations are its groups.
+	GroupsClaim string
+	// prefix removed from the group names, like "/" for keycloak group paths
+	GroupsPrefix string
+	// regular expressions the groups must fully match to be kept, after the prefix removal, all by default
+	GroupsFilter []string
+	// how often the sessions are re-validated with the provider, refreshing their tokens and groups,
+	// the users disabled upstream being logged out, 0 (default) never re-validates them
+	SessionRevalidateInterval time.Duration
 }
 
 type MethodRatelimitConfig struct {
@@ -108,9 +235,30 @@ type MethodRatelimitConfig struct {
 	Rate   int
 }
 
+// ClientRatelimitConfig limits the requests of each client.
+type ClientRatelimitConfig struct {
+	// requests per second
+	Rate float64
+	// requests accepted at once before being limited to the rate, defaults to the rate rounded up
+	Burst int
+}
+
+// RepositoryRatelimitConfig limits the requests of each client to the repositories matching globs.
+type RepositoryRatelimitConfig struct {
+	Repositories  []string
+	Anonymous     *ClientRatelimitConfig `mapstructure:",omitempty"`
+	Authenticated *ClientRatelimitConfig `mapstructure:",omitempty"`
+}
+
 type RatelimitConfig struct {
 	Rate    *int                    // requests per second
 	Methods []MethodRatelimitConfig `mapstructure:",omitempty"`
+	// limits each anonymous client by source ip
+	Anonymous *ClientRatelimitConfig `mapstructure:",omitempty"`
+	// limits each authenticated client by api key, or by identity if it did not authenticate with an api key
+	Authenticated *ClientRatelimitConfig `mapstructure:",omitempty"`
+	// limits each client on the repositories matching globs, in addition to the limits above
+	Repositories []RepositoryRatelimitConfig `mapstructure:",omitempty"`
 }
 
 //nolint:maligned
@@ -125,6 +273,8 @@ type HTTPConfig struct {
 	Realm         string
 	Ratelimit     *RatelimitConfig            `mapstructure:",omitempty"`
 	Compat        []compat.MediaCompatibility `mapstructure:",omitempty"`
+	// cidrs of the reverse proxies whose X-Forwarded-For header gives the client ip
+	TrustedProxyCIDRs []string `mapstructure:",omitempty"`
 }
 
 type SchedulerConfig struct {
@@ -177,6 +327,45 @@ type LDAPConfig struct {
 	UserAttribute      string
 	UserFilter         string
 	CACert             string
+	// more ldap servers as host or host:port, Port being the default port, tried in order after Address
+	Addresses []string
+	// how long a server which could not be reached is tried after the others, defaults to 1 minute
+	ServerRetryInterval time.Duration
+	// searches the groups of the users, in addition to the UserGroupAttribute of their entry
+	GroupSearch *LDAPGroupSearchConfig
+	// how long the groups of a user are cached, 0 (default) disables the cache
+	GroupsCacheTTL time.Duration
+}
+
+type LDAPGroupSearchConfig struct {
+	BaseDN string
+	// filter of the groups of a user, {dn} and {username} being replaced by the user dn and name,
+	// defaults to "(member={dn})"
+	Filter string
+	// also finds the groups the user belongs to through nested groups, with the active directory
+	// LDAP_MATCHING_RULE_IN_CHAIN, if Filter is not set
+	Nested bool
+	// attribute of the group entries holding the group names, defaults to "cn"
+	NameAttribute string
+}
+
+// LDAPServers returns the addresses of the ldap servers, as host:port.
+func (ldapConf *LDAPConfig) LDAPServers() []string {
+	servers := []string{}
+
+	for _, address := range append([]string{ldapConf.Address}, ldapConf.Addresses...) {
+		if address == "" {
+			continue
+		}
+
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			address = net.JoinHostPort(address, strconv.Itoa(ldapConf.Port))
+		}
+
+		servers = append(servers, address)
+	}
+
+	return servers
 }
 
 func (ldapConf *LDAPConfig) BindDN() string {
@@ -203,6 +392,56 @@ type LogConfig struct {
 	Level  string
 	Output string
 	Audit  string
+	// rotation of the Audit file and the other sinks the audit records are delivered to
+	AuditLog *AuditLogConfig `mapstructure:",omitempty"`
+}
+
+// AuditLogConfig configures the audit records, which are chained by their hashes so that their edits and
+// deletions can be detected.
+type AuditLogConfig struct {
+	// the Audit file is rotated once it is larger than MaxSize megabytes, never by default
+	MaxSize int
+	// the rotated files are deleted once older than MaxAge or once there are more than MaxBackups of them,
+	// never by default
+	MaxAge     time.Duration
+	MaxBackups int
+	// file holding the key the records are chained with, using hmac-sha256 instead of sha256,
+	// so that only its owners can rewrite the chain
+	HashKeyFile string
+	Syslog      *AuditSyslogConfig
+	HTTP        *AuditHTTPConfig
+}
+
+type AuditSyslogConfig struct {
+	// udp, tcp or unixgram, defaults to udp
+	Network string
+	Address string
+	// defaults to zot
+	Tag string
+}
+
+// AuditHTTPConfig makes zot post the audit records to a collector, in batches of json lines.
+type AuditHTTPConfig struct {
+	URL string
+	// maximum duration of a request to the collector, defaults to 10 seconds
+	Timeout time.Duration
+}
+
+// TracingConfig makes zot export the traces of the requests to an OpenTelemetry collector, over OTLP.
+type TracingConfig struct {
+	// host:port of the collector
+	Endpoint string
+	// grpc or http, defaults to grpc
+	Protocol string
+	// disables tls when connecting to the collector
+	Insecure bool
+	// sent with the traces, like the credentials of the collector
+	Headers map[string]string
+	// defaults to zot
+	ServiceName string
+	// ratio of the traces sampled, all of them by default, the traces started by the clients
+	// follow their sampling decision
+	SampleRatio float64
 }
 
 type GlobalStorageConfig struct {
@@ -214,7 +453,43 @@ type AccessControlConfig struct {
 	Repositories Repositories `json:"repositories" mapstructure:"repositories"`
 	AdminPolicy  Policy
 	Groups       Groups
-	Metrics      Metrics
+	// json file holding the groups instead of Groups, which the mgmt api edits
+	GroupsFile string
+	Metrics    Metrics
+	// delegates the authorization decisions to an external policy engine
+	Webhook *AuthzWebhookConfig
+}
+
+// AuthzWebhookConfig configures the http endpoint the authorization decisions are delegated to.
+type AuthzWebhookConfig struct {
+	URL string
+	// maximum duration of the requests to the endpoint made while handling a request, the access being denied
+	// when it is exceeded
+	Timeout time.Duration
+	// duration the decisions are cached for
+	CacheTTL time.Duration
+}
+
+// Compile compiles the conditions and parses the networks of the policies once, instead of for each request,
+// and returns an error if one of them is invalid.
+func (config *AccessControlConfig) Compile() error {
+	for pattern, policyGroup := range config.Repositories {
+		for _, policies := range [][]Policy{policyGroup.Policies, policyGroup.DenyPolicies} {
+			for idx := range policies {
+				if err := policies[idx].compile(); err != nil {
+					return fmt.Errorf("repository %s: %w", pattern, err)
+				}
+			}
+		}
+
+		for idx := range policyGroup.NetworkPolicies {
+			if err := policyGroup.NetworkPolicies[idx].compile(); err != nil {
+				return fmt.Errorf("repository %s: %w", pattern, err)
+			}
+		}
+	}
+
+	return nil
 }
 
 func (config *AccessControlConfig) AnonymousPolicyExists() bool {
@@ -241,15 +516,124 @@ type Group struct {
 }
 
 type PolicyGroup struct {
-	Policies        []Policy
+	Policies []Policy
+	// deny the actions of their policies, taking precedence over all the allowing policies, including adminPolicy,
+	// of all the matching repository patterns
+	DenyPolicies    []Policy
 	DefaultPolicy   []string
 	AnonymousPolicy []string
+	// restrict the client ips the actions can be done from, whatever the identity, like the deny policies do
+	NetworkPolicies []NetworkPolicy
 }
 
 type Policy struct {
 	Users   []string
 	Actions []string
 	Groups  []string
+	// optional conditions on the reference of the create, update and delete actions:
+	// the tag must fully match TagRegex, or the reference must be a digest
+	TagRegex   string
+	DigestOnly bool
+	// TagRegex compiled by AccessControlConfig.Compile
+	tagRegexp *regexp.Regexp
+}
+
+// HasReferenceConditions returns true if the policy only applies to some references.
+func (p Policy) HasReferenceConditions() bool {
+	return p.TagRegex != "" || p.DigestOnly
+}
+
+// MatchesTag returns true if the tag fully matches TagRegex.
+func (p Policy) MatchesTag(tag string) bool {
+	tagRegexp := p.tagRegexp
+	if tagRegexp == nil {
+		// the policies built after the config was compiled
+		var err error
+
+		if tagRegexp, err = compileTagRegex(p.TagRegex); err != nil {
+			return false
+		}
+	}
+
+	return tagRegexp.MatchString(tag)
+}
+
+func (p *Policy) compile() error {
+	p.tagRegexp = nil
+
+	if p.TagRegex == "" {
+		return nil
+	}
+
+	tagRegexp, err := compileTagRegex(p.TagRegex)
+	if err != nil {
+		return fmt.Errorf("invalid tagRegex %s: %w", p.TagRegex, err)
+	}
+
+	p.tagRegexp = tagRegexp
+
+	return nil
+}
+
+func compileTagRegex(tagRegex string) (*regexp.Regexp, error) {
+	return regexp.Compile("^(?:" + tagRegex + ")$")
+}
+
+// NetworkPolicy denies its actions to the client ips in DenyCIDRs and, if AllowCIDRs is set,
+// to the client ips outside of AllowCIDRs.
+type NetworkPolicy struct {
+	Actions    []string
+	AllowCIDRs []string
+	DenyCIDRs  []string
+	// AllowCIDRs and DenyCIDRs parsed by AccessControlConfig.Compile
+	allowNetworks []*net.IPNet
+	denyNetworks  []*net.IPNet
+	compiled      bool
+}
+
+// Networks returns the networks of AllowCIDRs and DenyCIDRs, the invalid cidrs being skipped.
+func (p NetworkPolicy) Networks() ([]*net.IPNet, []*net.IPNet) {
+	if p.compiled {
+		return p.allowNetworks, p.denyNetworks
+	}
+
+	// the policies built after the config was compiled
+	allowNetworks, _ := ParseCIDRs(p.AllowCIDRs)
+	denyNetworks, _ := ParseCIDRs(p.DenyCIDRs)
+
+	return allowNetworks, denyNetworks
+}
+
+func (p *NetworkPolicy) compile() error {
+	allowNetworks, err := ParseCIDRs(p.AllowCIDRs)
+	if err != nil {
+		return err
+	}
+
+	denyNetworks, err := ParseCIDRs(p.DenyCIDRs)
+	if err != nil {
+		return err
+	}
+
+	p.allowNetworks, p.denyNetworks, p.compiled = allowNetworks, denyNetworks, true
+
+	return nil
+}
+
+// ParseCIDRs parses a list of cidrs, returning an error naming the first invalid one.
+func ParseCIDRs(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr %s: %w", cidr, err)
+		}
+
+		networks = append(networks, network)
+	}
+
+	return networks, nil
 }
 
 type Metrics struct {
@@ -268,6 +652,7 @@ type Config struct {
 	Extensions      *extconf.ExtensionConfig
 	Scheduler       *SchedulerConfig `json:"scheduler" mapstructure:",omitempty"`
 	Cluster         *ClusterConfig   `json:"cluster"   mapstructure:",omitempty"`
+	Tracing         *TracingConfig   `json:"tracing"   mapstructure:",omitempty"`
 }
 
 func New() *Config {
@@ -341,9 +726,27 @@ func (c *Config) Sanitize() *Config {
 		sanitizedConfig.HTTP.Auth.LDAP.bindPassword = "******"
 	}
 
+	if c.Tracing != nil {
+		for key := range sanitizedConfig.Tracing.Headers {
+			sanitizedConfig.Tracing.Headers[key] = "******"
+		}
+	}
+
+	if c.Extensions != nil && c.Extensions.Events != nil {
+		for idx := range sanitizedConfig.Extensions.Events.Webhooks {
+			if sanitizedConfig.Extensions.Events.Webhooks[idx].Secret != "" {
+				sanitizedConfig.Extensions.Events.Webhooks[idx].Secret = "******"
+			}
+		}
+	}
+
 	return sanitizedConfig
 }
 
+func (c *Config) IsTracingEnabled() bool {
+	return c.Tracing != nil && c.Tracing.Endpoint != ""
+}
+
 func (c *Config) IsLdapAuthEnabled() bool {
 	if c.HTTP.Auth != nil && c.HTTP.Auth.LDAP != nil {
 		return true
@@ -380,7 +783,8 @@ func (c *Config) IsHtpasswdAuthEnabled() bool {
 func (c *Config) IsBearerAuthEnabled() bool {
 	if c.HTTP.Auth != nil &&
 		c.HTTP.Auth.Bearer != nil &&
-		c.HTTP.Auth.Bearer.Cert != "" &&
+		(c.HTTP.Auth.Bearer.Cert != "" || len(c.HTTP.Auth.Bearer.Certs) > 0 || c.HTTP.Auth.Bearer.JWKS != "" ||
+			c.HTTP.Auth.Bearer.TokenServer != nil) &&
 		c.HTTP.Auth.Bearer.Realm != "" &&
 		c.HTTP.Auth.Bearer.Service != "" {
 		return true
@@ -389,6 +793,10 @@ func (c *Config) IsBearerAuthEnabled() bool {
 	return false
 }
 
+func (c *Config) IsTokenServerEnabled() bool {
+	return c.IsBearerAuthEnabled() && c.HTTP.Auth.Bearer.TokenServer != nil
+}
+
 func (c *Config) IsOpenIDAuthEnabled() bool {
 	if c.HTTP.Auth != nil &&
 		c.HTTP.Auth.OpenID != nil {
@@ -410,9 +818,39 @@ func (c *Config) IsAPIKeyEnabled() bool {
 	return false
 }
 
+func (c *Config) IsWorkloadIdentityEnabled() bool {
+	return c.HTTP.Auth != nil && c.HTTP.Auth.WorkloadIdentity != nil && len(c.HTTP.Auth.WorkloadIdentity.Issuers) > 0
+}
+
+func (c *Config) IsMTLSMappingEnabled() bool {
+	return c.HTTP.Auth != nil && c.HTTP.Auth.MTLS != nil
+}
+
+func (c *Config) IsLockoutEnabled() bool {
+	return c.HTTP.Auth != nil && c.HTTP.Auth.Lockout != nil
+}
+
+// IsClientRatelimitEnabled returns true if the requests are limited per client.
+func (c *Config) IsClientRatelimitEnabled() bool {
+	ratelimit := c.HTTP.Ratelimit
+
+	return ratelimit != nil &&
+		(ratelimit.Anonymous != nil || ratelimit.Authenticated != nil || len(ratelimit.Repositories) > 0)
+}
+
+// IsAuditEnabled returns true if the audit records are written to a file or delivered to a sink.
+func (c *Config) IsAuditEnabled() bool {
+	return c.Log != nil && (c.Log.Audit != "" ||
+		(c.Log.AuditLog != nil && (c.Log.AuditLog.Syslog != nil || c.Log.AuditLog.HTTP != nil)))
+}
+
+func (c *Config) IsProxyHeaderAuthEnabled() bool {
+	return c.HTTP.Auth != nil && c.HTTP.Auth.ProxyHeader != nil
+}
+
 func (c *Config) IsBasicAuthnEnabled() bool {
-	if c.IsHtpasswdAuthEnabled() || c.IsLdapAuthEnabled() ||
-		c.IsOpenIDAuthEnabled() || c.IsAPIKeyEnabled() {
+	if c.IsHtpasswdAuthEnabled() || c.IsLdapAuthEnabled() || c.IsOpenIDAuthEnabled() ||
+		c.IsAPIKeyEnabled() || c.IsWorkloadIdentityEnabled() || c.IsProxyHeaderAuthEnabled() {
 		return true
 	}
 
@@ -460,6 +898,10 @@ func (c *Config) IsMgmtEnabled() bool {
 	return c.IsSearchEnabled()
 }
 
+func (c *Config) IsEventsEnabled() bool {
+	return c.Extensions != nil && c.Extensions.Events != nil && *c.Extensions.Events.Enable
+}
+
 func (c *Config) IsImageTrustEnabled() bool {
 	return c.Extensions != nil && c.Extensions.Trust != nil && *c.Extensions.Trust.Enable
 }
@@ -489,6 +931,20 @@ func (c *Config) IsRetentionEnabled() bool {
 	return needsMetaDB
 }
 
+func (c *Config) IsQuotaEnabled() bool {
+	if len(c.Storage.Quotas) > 0 {
+		return true
+	}
+
+	for _, subpath := range c.Storage.SubPaths {
+		if len(subpath.Quotas) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (c *Config) isTagsRetentionEnabled(tagRetentionPolicy KeepTagsPolicy) bool {
 	if tagRetentionPolicy.MostRecentlyPulledCount != 0 ||
 		tagRetentionPolicy.MostRecentlyPushedCount != 0 ||
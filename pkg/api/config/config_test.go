@@ -1,6 +1,7 @@
 package config_test
 
 import (
+	"net"
 	"testing"
 	"time"
 
@@ -129,4 +130,62 @@ func TestConfig(t *testing.T) {
 
 		So(conf.IsRetentionEnabled(), ShouldBeTrue)
 	})
+
+	Convey("Test AccessControlConfig.Compile()", t, func() {
+		accessControl := &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				"**": config.PolicyGroup{
+					Policies:     []config.Policy{{Actions: []string{"create"}, TagRegex: "v[0-9]+|latest"}},
+					DenyPolicies: []config.Policy{{Actions: []string{"delete"}, DigestOnly: true}},
+				},
+			},
+		}
+
+		So(accessControl.Compile(), ShouldBeNil)
+
+		policy := accessControl.Repositories["**"].Policies[0]
+		So(policy.MatchesTag("v1"), ShouldBeTrue)
+		So(policy.MatchesTag("latest"), ShouldBeTrue)
+		// the tag must fully match
+		So(policy.MatchesTag("v1-rc"), ShouldBeFalse)
+		So(policy.MatchesTag("my-latest"), ShouldBeFalse)
+
+		// the policies which were not compiled match the same tags
+		policy = config.Policy{TagRegex: "v[0-9]+|latest"}
+		So(policy.MatchesTag("latest"), ShouldBeTrue)
+		So(policy.MatchesTag("v1-rc"), ShouldBeFalse)
+
+		accessControl.Repositories["**"] = config.PolicyGroup{
+			DenyPolicies: []config.Policy{{Actions: []string{"create"}, TagRegex: "v["}},
+		}
+
+		So(accessControl.Compile(), ShouldNotBeNil)
+		So(config.Policy{TagRegex: "v["}.MatchesTag("v["), ShouldBeFalse)
+
+		// the networks of the network policies are parsed too
+		accessControl.Repositories["**"] = config.PolicyGroup{
+			NetworkPolicies: []config.NetworkPolicy{
+				{Actions: []string{"read"}, AllowCIDRs: []string{"10.0.0.0/8"}, DenyCIDRs: []string{"10.1.0.0/16"}},
+			},
+		}
+
+		So(accessControl.Compile(), ShouldBeNil)
+
+		allowed, denied := accessControl.Repositories["**"].NetworkPolicies[0].Networks()
+		So(len(allowed), ShouldEqual, 1)
+		So(allowed[0].String(), ShouldEqual, "10.0.0.0/8")
+		So(len(denied), ShouldEqual, 1)
+		So(denied[0].Contains(net.ParseIP("10.1.2.3")), ShouldBeTrue)
+
+		accessControl.Repositories["**"] = config.PolicyGroup{
+			NetworkPolicies: []config.NetworkPolicy{{Actions: []string{"read"}, DenyCIDRs: []string{"10.0.0.1"}}},
+		}
+
+		err := accessControl.Compile()
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "10.0.0.1")
+
+		_, err = config.ParseCIDRs([]string{"10.0.0.0/33"})
+		So(err, ShouldNotBeNil)
+	})
 }
@@ -18,6 +18,10 @@ const (
 	LoginPath                    = AppNamespacePath + "/auth/login"
 	LogoutPath                   = AppNamespacePath + "/auth/logout"
 	APIKeyPath                   = AppNamespacePath + "/auth/apikey"
+	TokenPath                    = AppNamespacePath + "/auth/token"
+	LockoutPath                  = AppNamespacePath + "/auth/lockout"
+	ServiceAccountsPath          = AppNamespacePath + "/auth/serviceaccounts"
+	SessionsPath                 = AppNamespacePath + "/auth/sessions"
 	SessionClientHeaderName      = "X-ZOT-API-CLIENT"
 	SessionClientHeaderValue     = "zot-ui"
 	APIKeysPrefix                = "zak_"
@@ -33,6 +37,8 @@ const (
 	DetectManifestCollisionPermission = "detectManifestCollision"
 	// zot scale-out hop count header.
 	ScaleOutHopCountHeader = "X-Zot-Cluster-Hop-Count"
+	// id of a request, given by the client or generated, forwarded to the other members and upstream registries.
+	RequestIDHeader = "X-Request-ID"
 	// log string keys.
 	// these can be used together with the logger to add context to a log message.
 	RepositoryLogKey = "repository"
@@ -20,6 +20,17 @@ const (
 	Mgmt     = "/mgmt"
 	ExtMgmt  = ExtPrefix + Mgmt
 	FullMgmt = RoutePrefix + ExtMgmt
+	// storage quotas usage, served by the mgmt extension.
+	MgmtQuotas     = "/quotas"
+	FullMgmtQuotas = FullMgmt + MgmtQuotas
+	// tag rollback, served by the mgmt extension.
+	MgmtTagRollback     = "/rollback"
+	FullMgmtTagRollback = FullMgmt + MgmtTagRollback
+	// htpasswd users and access control groups, managed by the mgmt extension.
+	MgmtUsers      = "/users"
+	FullMgmtUsers  = FullMgmt + MgmtUsers
+	MgmtGroups     = "/groups"
+	FullMgmtGroups = FullMgmt + MgmtGroups
 
 	// signatures extension.
 	Notation     = "/notation"
@@ -10,24 +10,37 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/gorilla/securecookie"
+	"github.com/redis/go-redis/v9"
 	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"golang.org/x/sync/singleflight"
 
 	"zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/accounts"
 	"zotregistry.dev/zot/pkg/api/config"
+	rediscfg "zotregistry.dev/zot/pkg/api/config/redis"
+	"zotregistry.dev/zot/pkg/audit"
 	"zotregistry.dev/zot/pkg/common"
 	ext "zotregistry.dev/zot/pkg/extensions"
 	extconf "zotregistry.dev/zot/pkg/extensions/config"
+	"zotregistry.dev/zot/pkg/extensions/events"
 	"zotregistry.dev/zot/pkg/extensions/monitoring"
 	"zotregistry.dev/zot/pkg/log"
 	"zotregistry.dev/zot/pkg/meta"
 	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	"zotregistry.dev/zot/pkg/quota"
 	"zotregistry.dev/zot/pkg/scheduler"
 	"zotregistry.dev/zot/pkg/storage"
+	storageCommon "zotregistry.dev/zot/pkg/storage/common"
+	sconstants "zotregistry.dev/zot/pkg/storage/constants"
 	"zotregistry.dev/zot/pkg/storage/gc"
+	storageTypes "zotregistry.dev/zot/pkg/storage/types"
+	"zotregistry.dev/zot/pkg/tracing"
 )
 
 const (
@@ -36,22 +49,40 @@ const (
 )
 
 type Controller struct {
-	Config          *config.Config
-	Router          *mux.Router
-	MetaDB          mTypes.MetaDB
-	StoreController storage.StoreController
-	Log             log.Logger
-	Audit           *log.Logger
-	Server          *http.Server
-	Metrics         monitoring.MetricServer
-	CveScanner      ext.CveScanner
-	SyncOnDemand    SyncOnDemand
-	RelyingParties  map[string]rp.RelyingParty
-	CookieStore     *CookieStore
-	HTPasswd        *HTPasswd
-	HTPasswdWatcher *HTPasswdWatcher
-	LDAPClient      *LDAPClient
-	taskScheduler   *scheduler.Scheduler
+	Config           *config.Config
+	Router           *mux.Router
+	MetaDB           mTypes.MetaDB
+	StoreController  storage.StoreController
+	Log              log.Logger
+	Audit            *log.Logger
+	Server           *http.Server
+	Metrics          monitoring.MetricServer
+	CveScanner       ext.CveScanner
+	SyncOnDemand     SyncOnDemand
+	RelyingParties   map[string]rp.RelyingParty
+	OpenIDGroups     map[string]*OpenIDGroupsMapper
+	CookieStore      *CookieStore
+	HTPasswd         *HTPasswd
+	HTPasswdWatcher  *HTPasswdWatcher
+	LDAPClient       *LDAPClient
+	TokenIssuer      *TokenIssuer
+	BearerKeys       *BearerKeySet
+	WorkloadIdentity *WorkloadIdentityVerifier
+	AuthzWebhook     *AuthzWebhook
+	AuthLockout      *AuthLockout
+	ClientLimiter    *ClientRateLimiter
+	QuotaManager     *quota.Manager
+	Accounts         *accounts.Manager
+	EventsNotifier   *events.Notifier
+	TracerProvider   *sdktrace.TracerProvider
+	taskScheduler    *scheduler.Scheduler
+	// immutable tags policies compiled per store path, replaced when the config is reloaded
+	immutableTags     map[string]*storageCommon.ImmutableTags
+	immutableTagsLock sync.RWMutex
+	// chains the audit records and delivers them to the audit sinks
+	auditWriter *audit.Writer
+	// re-validations of the openid sessions in progress, shared by the concurrent requests of a session
+	openIDSessions singleflight.Group
 	// runtime params
 	chosenPort int // kernel-chosen port
 }
@@ -110,11 +141,21 @@ func NewController(appConfig *config.Config) *Controller {
 	controller.HTPasswd = htp
 	controller.HTPasswdWatcher = htw
 
-	if appConfig.Log.Audit != "" {
-		audit := log.NewAuditLogger(appConfig.Log.Level, appConfig.Log.Audit)
-		controller.Audit = audit
+	if appConfig.IsAuditEnabled() {
+		auditLogger, auditWriter, err := audit.NewLogger(appConfig.Log, logger)
+		if err != nil {
+			logger.Panic().Err(err).Msg("failed to create audit logger")
+		}
+
+		controller.Audit = auditLogger
+		controller.auditWriter = auditWriter
 	}
 
+	// the users changed through the mgmt api authenticate right away, without waiting for the file watcher
+	controller.Accounts = accounts.NewManager(appConfig, func() error {
+		return htp.Reload(controller.Config.HTTP.Auth.HTPasswd.Path)
+	}, controller.Audit, logger)
+
 	return &controller
 }
 
@@ -122,16 +163,126 @@ func (c *Controller) GetPort() int {
 	return c.chosenPort
 }
 
+func (c *Controller) initAuthzWebhook() {
+	if c.Config.HTTP.AccessControl == nil || c.Config.HTTP.AccessControl.Webhook == nil {
+		c.AuthzWebhook = nil
+
+		return
+	}
+
+	c.AuthzWebhook = NewAuthzWebhook(c.Config.HTTP.AccessControl.Webhook, c.Log)
+}
+
+// remoteCacheRedisClient returns a client of the remote cache if it is redis, with the prefix of its keys,
+// nil otherwise.
+func (c *Controller) remoteCacheRedisClient() (redis.UniversalClient, string, error) {
+	if !c.Config.Storage.RemoteCache || c.Config.Storage.CacheDriver["name"] != sconstants.RedisDriverName {
+		return nil, "", nil
+	}
+
+	client, err := rediscfg.GetRedisClient(c.Config.Storage.CacheDriver, c.Log)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyPrefix, _ := c.Config.Storage.CacheDriver["keyprefix"].(string)
+	if keyPrefix == "" {
+		keyPrefix = "zot"
+	}
+
+	return client, keyPrefix, nil
+}
+
+// initAuthLockout counts the authentication failures in redis if it is the remote cache driver,
+// so that the members of a cluster share them.
+func (c *Controller) initAuthLockout() error {
+	if !c.Config.IsLockoutEnabled() {
+		return nil
+	}
+
+	client, keyPrefix, err := c.remoteCacheRedisClient()
+	if err != nil {
+		c.Log.Error().Err(err).Msg("failed to create redis client for authentication lockout")
+
+		return err
+	}
+
+	// ldap matches the usernames case-insensitively, their failures are counted together
+	c.AuthLockout = NewAuthLockout(c.Config.HTTP.Auth.Lockout, c.Config.IsLdapAuthEnabled(), client, keyPrefix,
+		c.Audit, c.Log)
+
+	return nil
+}
+
+// initClientLimiter keeps the rate limits of the clients in redis if it is the remote cache driver,
+// so that the members of a cluster share them.
+func (c *Controller) initClientLimiter() error {
+	if !c.Config.IsClientRatelimitEnabled() {
+		return nil
+	}
+
+	client, keyPrefix, err := c.remoteCacheRedisClient()
+	if err != nil {
+		c.Log.Error().Err(err).Msg("failed to create redis client for rate limiting")
+
+		return err
+	}
+
+	c.ClientLimiter = NewClientRateLimiter(c.Config.HTTP.Ratelimit, client, keyPrefix, c.Log)
+
+	return nil
+}
+
 func (c *Controller) Run() error {
 	if err := c.initCookieStore(); err != nil {
 		return err
 	}
 
+	if c.Config.IsTokenServerEnabled() {
+		tokenIssuer, err := NewTokenIssuer(c.Config.HTTP.Auth.Bearer, c.Log)
+		if err != nil {
+			return err
+		}
+
+		c.TokenIssuer = tokenIssuer
+	}
+
+	if c.Config.HTTP.AccessControl != nil {
+		if err := c.Config.HTTP.AccessControl.Compile(); err != nil {
+			c.Log.Error().Err(err).Msg("failed to compile access control policies")
+
+			return err
+		}
+	}
+
+	c.initAuthzWebhook()
+
+	if err := c.initAuthLockout(); err != nil {
+		return err
+	}
+
+	if err := c.initClientLimiter(); err != nil {
+		return err
+	}
+
 	c.StartBackgroundTasks()
 
 	// setup HTTP API router
 	engine := mux.NewRouter()
 
+	// the id of the request is carried by the logs of all the other middlewares
+	engine.Use(RequestIDHandler())
+
+	// the client ips given by the trusted reverse proxies are used by all the other middlewares
+	if len(c.Config.HTTP.TrustedProxyCIDRs) > 0 {
+		engine.Use(ClientIPHandler(c))
+	}
+
+	// the spans of the requests are the parents of the spans of all the other middlewares
+	if c.Config.IsTracingEnabled() {
+		engine.Use(tracing.Handler())
+	}
+
 	// rate-limit HTTP requests if enabled
 	if c.Config.HTTP.Ratelimit != nil {
 		if c.Config.HTTP.Ratelimit.Rate != nil {
@@ -261,6 +412,10 @@ func (c *Controller) Init() error {
 
 	c.Metrics = monitoring.NewMetricsServer(enabled, c.Log)
 
+	if err := c.initTracing(); err != nil {
+		return err
+	}
+
 	if err := c.InitImageStore(); err != nil { //nolint:contextcheck
 		return err
 	}
@@ -269,6 +424,19 @@ func (c *Controller) Init() error {
 		return err
 	}
 
+	c.QuotaManager = quota.NewManager(c.Config, c.MetaDB, c.Metrics, c.Log)
+
+	if err := c.initWritePolicies(); err != nil {
+		return err
+	}
+
+	notifier, err := ext.GetEventsNotifier(c.Config, c.Log)
+	if err != nil {
+		return err
+	}
+
+	c.EventsNotifier = notifier
+
 	c.InitCVEInfo()
 
 	if c.Config.IsHtpasswdAuthEnabled() {
@@ -301,6 +469,66 @@ func (c *Controller) InitImageStore() error {
 	return nil
 }
 
+// initWritePolicies compiles the immutable tags policies of each storage and has its image store enforce them
+// along with the storage quotas, the writes of sync and of the extensions being checked as well as the ones
+// of the clients.
+func (c *Controller) initWritePolicies() error {
+	storageConfigs := map[string]config.StorageConfig{storage.DefaultStorePath: c.Config.Storage.StorageConfig}
+	for route, storageConfig := range c.Config.Storage.SubPaths {
+		storageConfigs[route] = storageConfig
+	}
+
+	immutableTags := make(map[string]*storageCommon.ImmutableTags, len(storageConfigs))
+
+	for storePath, storageConfig := range storageConfigs {
+		compiled, err := storageCommon.NewImmutableTags(storageConfig.ImmutableTags)
+		if err != nil {
+			c.Log.Error().Err(err).Str("storePath", storePath).Msg("failed to compile immutable tags policies")
+
+			return err
+		}
+
+		immutableTags[storePath] = compiled
+	}
+
+	c.immutableTagsLock.Lock()
+	c.immutableTags = immutableTags
+	c.immutableTagsLock.Unlock()
+
+	for storePath, compiled := range immutableTags {
+		imgStore := c.StoreController.DefaultStore
+		if storePath != storage.DefaultStorePath {
+			imgStore = c.StoreController.SubStore[storePath]
+		}
+
+		if imgStore == nil {
+			continue
+		}
+
+		policies := []storageTypes.WritePolicy{}
+		if compiled != nil {
+			policies = append(policies, compiled)
+		}
+
+		if c.QuotaManager != nil && len(storageConfigs[storePath].Quotas) > 0 {
+			policies = append(policies, c.QuotaManager)
+		}
+
+		imgStore.SetWritePolicies(policies...)
+	}
+
+	return nil
+}
+
+// GetImmutableTags returns the immutable tags policies currently configured for a store path,
+// nil if it has none.
+func (c *Controller) GetImmutableTags(storePath string) *storageCommon.ImmutableTags {
+	c.immutableTagsLock.RLock()
+	defer c.immutableTagsLock.RUnlock()
+
+	return c.immutableTags[storePath]
+}
+
 func (c *Controller) initCookieStore() error {
 	// setup sessions cookie store used to preserve logged in user in web sessions
 	if c.Config.IsBasicAuthnEnabled() {
@@ -323,9 +551,10 @@ func (c *Controller) initCookieStore() error {
 }
 
 func (c *Controller) InitMetaDB() error {
-	// init metaDB if search is enabled or we need to store user profiles, api keys or signatures
+	// init metaDB if search is enabled or we need to store user profiles, api keys, signatures
+	// or to compute storage quotas usage, events are also sent from the metaDB hooks
 	if c.Config.IsSearchEnabled() || c.Config.IsBasicAuthnEnabled() || c.Config.IsImageTrustEnabled() ||
-		c.Config.IsRetentionEnabled() {
+		c.Config.IsRetentionEnabled() || c.Config.IsQuotaEnabled() || c.Config.IsEventsEnabled() {
 		driver, err := meta.New(c.Config.Storage.StorageConfig, c.Log) //nolint:contextcheck
 		if err != nil {
 			return err
@@ -346,15 +575,43 @@ func (c *Controller) InitMetaDB() error {
 			return err
 		}
 
+		if c.Config.IsTracingEnabled() {
+			driver = tracing.MetaDB(driver)
+		}
+
 		c.MetaDB = driver
 	}
 
 	return nil
 }
 
+// initTracing registers the tracer provider exporting the traces to the collector, unless one was given.
+func (c *Controller) initTracing() error {
+	if !c.Config.IsTracingEnabled() {
+		return nil
+	}
+
+	if c.TracerProvider == nil {
+		provider, err := tracing.NewProvider(context.Background(), c.Config.Tracing)
+		if err != nil {
+			c.Log.Error().Err(err).Str("endpoint", c.Config.Tracing.Endpoint).Msg("failed to create tracer provider")
+
+			return err
+		}
+
+		c.TracerProvider = provider
+	}
+
+	tracing.Register(c.TracerProvider)
+
+	return nil
+}
+
 func (c *Controller) LoadNewConfig(newConfig *config.Config) {
 	// reload access control config
 	c.Config.HTTP.AccessControl = newConfig.HTTP.AccessControl
+	// the cached decisions may not be valid anymore
+	c.initAuthzWebhook()
 
 	if c.Config.HTTP.Auth != nil {
 		c.Config.HTTP.Auth.HTPasswd = newConfig.HTTP.Auth.HTPasswd
@@ -380,11 +637,16 @@ func (c *Controller) LoadNewConfig(newConfig *config.Config) {
 	c.Config.Storage.Dedupe = newConfig.Storage.Dedupe
 	c.Config.Storage.GCDelay = newConfig.Storage.GCDelay
 	c.Config.Storage.GCInterval = newConfig.Storage.GCInterval
+	c.Config.Storage.ImmutableTags = newConfig.Storage.ImmutableTags
 	// only if we have a metaDB already in place
 	if c.Config.IsRetentionEnabled() {
 		c.Config.Storage.Retention = newConfig.Storage.Retention
 	}
 
+	if c.MetaDB != nil {
+		c.Config.Storage.Quotas = newConfig.Storage.Quotas
+	}
+
 	for subPath, storageConfig := range newConfig.Storage.SubPaths {
 		subPathConfig, ok := c.Config.Storage.SubPaths[subPath]
 		if ok {
@@ -392,15 +654,25 @@ func (c *Controller) LoadNewConfig(newConfig *config.Config) {
 			subPathConfig.Dedupe = storageConfig.Dedupe
 			subPathConfig.GCDelay = storageConfig.GCDelay
 			subPathConfig.GCInterval = storageConfig.GCInterval
+			subPathConfig.ImmutableTags = storageConfig.ImmutableTags
 			// only if we have a metaDB already in place
 			if c.Config.IsRetentionEnabled() {
 				subPathConfig.Retention = storageConfig.Retention
 			}
 
+			if c.MetaDB != nil {
+				subPathConfig.Quotas = storageConfig.Quotas
+			}
+
 			c.Config.Storage.SubPaths[subPath] = subPathConfig
 		}
 	}
 
+	// the stores, the gc and the overrides apply the reloaded immutable tags policies
+	if err := c.initWritePolicies(); err != nil {
+		c.Log.Error().Err(err).Msg("failed to reload immutable tags policies")
+	}
+
 	// reload background tasks
 	if newConfig.Extensions != nil {
 		if c.Config.Extensions == nil {
@@ -423,6 +695,11 @@ func (c *Controller) LoadNewConfig(newConfig *config.Config) {
 		c.Config.Extensions = nil
 	}
 
+	// the new policies were validated along with the rest of the configuration
+	if err := c.initWritePolicies(); err != nil {
+		c.Log.Error().Err(err).Msg("failed to reload write policies")
+	}
+
 	c.InitCVEInfo()
 
 	c.Log.Info().Interface("reloaded params", c.Config.Sanitize()).
@@ -436,6 +713,43 @@ func (c *Controller) Shutdown() {
 		ctx := context.Background()
 		_ = c.Server.Shutdown(ctx)
 	}
+
+	if err := c.EventsNotifier.Close(); err != nil {
+		c.Log.Error().Err(err).Msg("failed to close events notifier")
+	}
+
+	if c.BearerKeys != nil {
+		c.BearerKeys.Close()
+	}
+
+	if c.WorkloadIdentity != nil {
+		c.WorkloadIdentity.Close()
+	}
+
+	if c.AuthLockout != nil {
+		if err := c.AuthLockout.Close(); err != nil {
+			c.Log.Error().Err(err).Msg("failed to close authentication lockout")
+		}
+	}
+
+	if c.ClientLimiter != nil {
+		if err := c.ClientLimiter.Close(); err != nil {
+			c.Log.Error().Err(err).Msg("failed to close rate limiter")
+		}
+	}
+
+	if c.auditWriter != nil {
+		if err := c.auditWriter.Close(); err != nil {
+			c.Log.Error().Err(err).Msg("failed to close audit sinks")
+		}
+	}
+
+	// exports the pending spans
+	if c.TracerProvider != nil {
+		if err := c.TracerProvider.Shutdown(context.Background()); err != nil {
+			c.Log.Error().Err(err).Msg("failed to shut down tracer provider")
+		}
+	}
 }
 
 // Will stop scheduler and wait for all tasks to finish their work.
@@ -445,6 +759,16 @@ func (c *Controller) StopBackgroundTasks() {
 	}
 }
 
+// getManifestNotifier returns the events notifier of the background tasks writing to the repositories,
+// or nil if events are disabled.
+func (c *Controller) getManifestNotifier() mTypes.ManifestNotifier {
+	if c.EventsNotifier == nil {
+		return nil
+	}
+
+	return c.EventsNotifier
+}
+
 func (c *Controller) StartBackgroundTasks() {
 	c.taskScheduler = scheduler.NewScheduler(c.Config, c.Metrics, c.Log)
 	c.taskScheduler.RunScheduler()
@@ -454,6 +778,10 @@ func (c *Controller) StartBackgroundTasks() {
 		gc := gc.NewGarbageCollect(c.StoreController.DefaultStore, c.MetaDB, gc.Options{
 			Delay:          c.Config.Storage.GCDelay,
 			ImageRetention: c.Config.Storage.Retention,
+			ImmutableTags: func() *storageCommon.ImmutableTags {
+				return c.GetImmutableTags(storage.DefaultStorePath)
+			},
+			Notifier: c.getManifestNotifier(),
 		}, c.Audit, c.Log)
 
 		gc.CleanImageStorePeriodically(c.Config.Storage.GCInterval, c.taskScheduler)
@@ -480,6 +808,10 @@ func (c *Controller) StartBackgroundTasks() {
 					gc.Options{
 						Delay:          storageConfig.GCDelay,
 						ImageRetention: storageConfig.Retention,
+						ImmutableTags: func() *storageCommon.ImmutableTags {
+							return c.GetImmutableTags(route)
+						},
+						Notifier: c.getManifestNotifier(),
 					}, c.Audit, c.Log)
 
 				gc.CleanImageStorePeriodically(storageConfig.GCInterval, c.taskScheduler)
@@ -505,7 +837,8 @@ func (c *Controller) StartBackgroundTasks() {
 	if c.Config.Extensions != nil {
 		ext.EnableScrubExtension(c.Config, c.Log, c.StoreController, c.taskScheduler)
 		//nolint: contextcheck
-		syncOnDemand, err := ext.EnableSyncExtension(c.Config, c.MetaDB, c.StoreController, c.taskScheduler, c.Log)
+		syncOnDemand, err := ext.EnableSyncExtension(c.Config, c.MetaDB, c.getManifestNotifier(), c.StoreController,
+			c.taskScheduler, c.Log)
 		if err != nil {
 			c.Log.Error().Err(err).Msg("failed to start sync extension")
 		}
@@ -517,6 +850,8 @@ func (c *Controller) StartBackgroundTasks() {
 		c.CookieStore.RunSessionCleaner(c.taskScheduler)
 	}
 
+	c.EventsNotifier.Start(c.taskScheduler)
+
 	// we can later move enabling the other scheduled tasks inside the call below
 	ext.EnableScheduledTasks(c.Config, c.taskScheduler, c.MetaDB, c.Log) //nolint: contextcheck
 }
@@ -7,8 +7,12 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"encoding/json"
 	goerrors "errors"
 	"fmt"
@@ -23,10 +27,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/alicebob/miniredis/v2"
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/go-github/v62/github"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/securecookie"
@@ -48,6 +56,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.etcd.io/bbolt"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"gopkg.in/resty.v1"
 
 	"zotregistry.dev/zot/errors"
@@ -60,6 +69,7 @@ import (
 	extconf "zotregistry.dev/zot/pkg/extensions/config"
 	"zotregistry.dev/zot/pkg/log"
 	"zotregistry.dev/zot/pkg/meta"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 	"zotregistry.dev/zot/pkg/storage"
 	storageConstants "zotregistry.dev/zot/pkg/storage/constants"
 	"zotregistry.dev/zot/pkg/storage/gc"
@@ -72,6 +82,7 @@ import (
 	ociutils "zotregistry.dev/zot/pkg/test/oci-utils"
 	"zotregistry.dev/zot/pkg/test/signature"
 	tskip "zotregistry.dev/zot/pkg/test/skip"
+	"zotregistry.dev/zot/pkg/tracing"
 )
 
 const (
@@ -754,7 +765,8 @@ func TestHtpasswdSingleCred(t *testing.T) {
 				resp, _ = resty.R().SetBasicAuth(user, password).Options(baseURL + "/v2/")
 				So(resp, ShouldNotBeNil)
 				So(resp.StatusCode(), ShouldEqual, http.StatusNoContent)
-				So(len(resp.Header()), ShouldEqual, 5)
+				So(len(resp.Header()), ShouldEqual, 6)
+				So(resp.Header().Get(constants.RequestIDHeader), ShouldNotBeEmpty)
 				So(resp.Header()["Access-Control-Allow-Headers"], ShouldResemble, header)
 				So(resp.Header().Get("Access-Control-Allow-Methods"), ShouldResemble, "GET,OPTIONS")
 
@@ -1061,6 +1073,78 @@ func TestRatelimit(t *testing.T) {
 	})
 }
 
+func TestTracing(t *testing.T) {
+	Convey("Make a new controller tracing the requests", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.Tracing = &config.TracingConfig{Endpoint: "127.0.0.1:4317"}
+
+		defaultVal := true
+		conf.Extensions = &extconf.ExtensionConfig{
+			Search: &extconf.SearchConfig{BaseConfig: extconf.BaseConfig{Enable: &defaultVal}},
+		}
+
+		exporter := tracetest.NewInMemoryExporter()
+
+		ctlr := makeController(conf, t.TempDir())
+		ctlr.TracerProvider = tracing.NewProviderWithExporter(conf.Tracing, exporter)
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		img := CreateRandomImage()
+
+		err := UploadImage(img, baseURL, "alpine", "1.0")
+		So(err, ShouldBeNil)
+
+		So(ctlr.TracerProvider.ForceFlush(context.Background()), ShouldBeNil)
+
+		spanNamed := func(name string) *tracetest.SpanStub {
+			spans := exporter.GetSpans()
+			for idx := range spans {
+				if spans[idx].Name == name {
+					return &spans[idx]
+				}
+			}
+
+			return nil
+		}
+
+		route := spanNamed("PUT /v2/{name}/manifests/{reference}")
+		So(route, ShouldNotBeNil)
+
+		putManifest := spanNamed("ImageStore.PutImageManifest")
+		So(putManifest, ShouldNotBeNil)
+		So(putManifest.Parent.TraceID(), ShouldEqual, route.SpanContext.TraceID())
+		So(putManifest.Attributes, ShouldContain, tracing.RepositoryKey.String("alpine"))
+
+		setRepoReference := spanNamed("MetaDB.SetRepoReference")
+		So(setRepoReference, ShouldNotBeNil)
+		So(setRepoReference.Parent.TraceID(), ShouldEqual, route.SpanContext.TraceID())
+
+		So(spanNamed("POST /v2/{name}/blobs/uploads/"), ShouldNotBeNil)
+
+		// the trace context of the clients is continued
+		_, client := tracing.Start(context.Background(), "client")
+		traceParent := fmt.Sprintf("00-%s-%s-01", client.SpanContext().TraceID(), client.SpanContext().SpanID())
+
+		resp, err := resty.R().SetHeader("Traceparent", traceParent).Get(baseURL + "/v2/alpine/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		So(ctlr.TracerProvider.ForceFlush(context.Background()), ShouldBeNil)
+
+		route = spanNamed("GET /v2/{name}/tags/list")
+		So(route, ShouldNotBeNil)
+		So(route.SpanContext.TraceID(), ShouldEqual, client.SpanContext().TraceID())
+		So(route.Parent.SpanID(), ShouldEqual, client.SpanContext().SpanID())
+	})
+}
+
 func TestBasicAuth(t *testing.T) {
 	Convey("Make a new controller", t, func() {
 		port := test.GetFreePort()
@@ -3153,6 +3237,51 @@ func TestBasicAuthWithLDAP(t *testing.T) {
 	})
 }
 
+func TestAuthnLockoutWithLDAP(t *testing.T) {
+	Convey("Make a new controller locking out the ldap users failing to authenticate", t, func() {
+		ldapServer := newTestLDAPServer()
+		port := test.GetFreePort()
+		ldapPort, err := strconv.Atoi(port)
+		So(err, ShouldBeNil)
+		ldapServer.Start(ldapPort)
+
+		defer ldapServer.Stop()
+
+		port = test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			LDAP: (&config.LDAPConfig{
+				Insecure:      true,
+				Address:       LDAPAddress,
+				Port:          ldapPort,
+				BaseDN:        LDAPBaseDN,
+				UserAttribute: "uid",
+			}).SetBindDN(LDAPBindDN).SetBindPassword(LDAPBindPassword),
+			Lockout: &config.LockoutConfig{UserThreshold: 2, IPThreshold: 10, Duration: time.Hour},
+		}
+		ctlr := makeController(conf, t.TempDir())
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		// ldap matches the usernames case-insensitively, changing their case does not dodge the lockout
+		for _, user := range []string{strings.ToUpper(username), strings.ToUpper(username[:1]) + username[1:]} {
+			resp, err := resty.R().SetBasicAuth(user, "wrong").Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		}
+
+		resp, err := resty.R().SetBasicAuth(username, password).Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+	})
+}
+
 func TestBasicAuthWithReloadedCredentials(t *testing.T) {
 	Convey("Start server with bad credentials", t, func() {
 		l := newTestLDAPServer()
@@ -4224,1324 +4353,2833 @@ func TestBearerAuthWrongAuthorizer(t *testing.T) {
 	})
 }
 
-func TestBearerAuthWithAllowReadAccess(t *testing.T) {
-	testCases := []struct {
-		name                    string
-		useLegacyAuthTestServer bool
-	}{
-		{
-			name:                    "new authentication test server",
-			useLegacyAuthTestServer: false,
-		},
-		{
-			name:                    "legacy authentication test server",
-			useLegacyAuthTestServer: true,
-		},
-	}
+func TestBearerAuthWithJWKS(t *testing.T) {
+	Convey("Make a new controller verifying bearer tokens with a json web key set", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
 
-	for _, testCase := range testCases {
-		Convey("Make a new controller with"+testCase.name, t, func() {
-			var authTestServer *httptest.Server
-			if testCase.useLegacyAuthTestServer {
-				authTestServer = authutils.MakeAuthTestServerLegacy(ServerKey, UnauthorizedNamespace)
-			} else {
-				authTestServer = authutils.MakeAuthTestServer(ServerKey, "RS256", UnauthorizedNamespace)
-			}
-			defer authTestServer.Close()
+		key1, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
 
-			port := test.GetFreePort()
-			baseURL := test.GetBaseURL(port)
+		key2, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
 
-			conf := config.New()
-			conf.HTTP.Port = port
+		jwksPath := path.Join(t.TempDir(), "jwks.json")
 
-			aurl, err := url.Parse(authTestServer.URL)
+		writeJWKS := func(keys ...jose.JSONWebKey) {
+			content, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
 			So(err, ShouldBeNil)
+			So(os.WriteFile(jwksPath, content, 0o600), ShouldBeNil)
+		}
 
-			conf.HTTP.Auth = &config.AuthConfig{
-				Bearer: &config.BearerConfig{
-					Cert:    ServerCert,
-					Realm:   authTestServer.URL + "/auth/token",
-					Service: aurl.Host,
+		signToken := func(key *ecdsa.PrivateKey, keyID string) string {
+			token := jwt.NewWithClaims(jwt.SigningMethodES256, api.ClaimsWithAccess{
+				RegisteredClaims: jwt.RegisteredClaims{
+					Issuer:    "issuer",
+					Audience:  jwt.ClaimStrings{"zot"},
+					IssuedAt:  jwt.NewNumericDate(time.Now()),
+					ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
 				},
-			}
-			ctlr := makeController(conf, t.TempDir())
+			})
 
-			conf.HTTP.AccessControl = &config.AccessControlConfig{
-				Repositories: config.Repositories{
-					test.AuthorizationAllRepos: config.PolicyGroup{
-						AnonymousPolicy: []string{"read"},
-					},
-				},
+			if keyID != "" {
+				token.Header["kid"] = keyID
 			}
 
-			cm := test.NewControllerManager(ctlr)
-			cm.StartAndWait(port)
+			signed, err := token.SignedString(key)
+			So(err, ShouldBeNil)
 
-			defer cm.StopServer()
+			return signed
+		}
 
-			blob := []byte("hello, blob!")
-			digest := godigest.FromBytes(blob).String()
+		writeJWKS(jose.JSONWebKey{Key: key1.Public(), KeyID: "key1"})
 
-			resp, err := resty.R().Get(baseURL + "/v2/")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			Bearer: &config.BearerConfig{
+				Realm:   "https://auth.example.com/token",
+				Service: "zot",
+				JWKS:    jwksPath,
+			},
+		}
 
-			authorizationHeader := authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
-			resp, err = resty.R().
-				SetQueryParam("service", authorizationHeader.Service).
-				SetQueryParam("scope", authorizationHeader.Scope).
-				Get(authorizationHeader.Realm)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		ctlr := makeController(conf, t.TempDir())
 
-			var goodToken authutils.AccessTokenResponse
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-			err = json.Unmarshal(resp.Body(), &goodToken)
-			So(err, ShouldBeNil)
+		defer cm.StopServer()
 
-			resp, err = resty.R().
-				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
-				Get(baseURL + "/v2/")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(ctlr.BearerKeys, ShouldNotBeNil)
 
-			s1, seed1 := test.GenerateRandomName()
-			s2, seed2 := test.GenerateRandomName()
-			repoName := s1 + "/" + s2
+		resp, err := resty.R().SetAuthToken(signToken(key1, "key1")).Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			ctlr.Log.Info().Int64("seed1", seed1).Int64("seed2", seed2).Msg("random seeds for repoName")
+		// tokens signed with unknown keys are rejected
+		resp, err = resty.R().SetAuthToken(signToken(key2, "key2")).Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-			resp, err = resty.R().Post(baseURL + "/v2/" + repoName + "/blobs/uploads/")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		// the issuer rotates its key, the previous key being kept for the tokens it already signed
+		writeJWKS(jose.JSONWebKey{Key: key2.Public(), KeyID: "key2"}, jose.JSONWebKey{Key: key1.Public(), KeyID: "key1"})
 
-			authorizationHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
-			resp, err = resty.R().
-				SetQueryParam("service", authorizationHeader.Service).
-				SetQueryParam("scope", authorizationHeader.Scope).
-				Get(authorizationHeader.Realm)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
-			err = json.Unmarshal(resp.Body(), &goodToken)
-			So(err, ShouldBeNil)
+		for range 50 {
+			if _, ok := ctlr.BearerKeys.Key("key2"); ok {
+				break
+			}
 
-			resp, err = resty.R().
-				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
-				Post(baseURL + "/v2/" + repoName + "/blobs/uploads/")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
-			loc := resp.Header().Get("Location")
+			time.Sleep(100 * time.Millisecond)
+		}
 
-			resp, err = resty.R().
-				SetHeader("Content-Length", strconv.Itoa(len(blob))).
-				SetHeader("Content-Type", "application/octet-stream").
-				SetQueryParam("digest", digest).
-				SetBody(blob).
-				Put(baseURL + loc)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		resp, err = resty.R().SetAuthToken(signToken(key2, "key2")).Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			authorizationHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
-			resp, err = resty.R().
-				SetQueryParam("service", authorizationHeader.Service).
-				SetQueryParam("scope", authorizationHeader.Scope).
-				Get(authorizationHeader.Realm)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
-			err = json.Unmarshal(resp.Body(), &goodToken)
-			So(err, ShouldBeNil)
+		resp, err = resty.R().SetAuthToken(signToken(key1, "key1")).Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			resp, err = resty.R().
-				SetHeader("Content-Length", strconv.Itoa(len(blob))).
-				SetHeader("Content-Type", "application/octet-stream").
-				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
-				SetQueryParam("digest", digest).
-				SetBody(blob).
-				Put(baseURL + loc)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+		// tokens without key id, or with a key id zot does not know, are verified with all the keys
+		resp, err = resty.R().SetAuthToken(signToken(key2, "")).Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			resp, err = resty.R().
-				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
-				Get(baseURL + "/v2/" + repoName + "/tags/list")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		resp, err = resty.R().SetAuthToken(signToken(key1, "other")).Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+	})
+}
 
-			authorizationHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
-			resp, err = resty.R().
-				SetQueryParam("service", authorizationHeader.Service).
-				SetQueryParam("scope", authorizationHeader.Scope).
-				Get(authorizationHeader.Realm)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
-			err = json.Unmarshal(resp.Body(), &goodToken)
-			So(err, ShouldBeNil)
+func TestBearerAuthUnknownKeysReload(t *testing.T) {
+	Convey("Make a new controller fetching its bearer keys from an unavailable json web key set", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
 
-			resp, err = resty.R().
-				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
-				Get(baseURL + "/v2/" + repoName + "/tags/list")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
-
-			resp, err = resty.R().
-				Post(baseURL + "/v2/" + UnauthorizedNamespace + "/blobs/uploads/")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-
-			authorizationHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
-			resp, err = resty.R().
-				SetQueryParam("service", authorizationHeader.Service).
-				SetQueryParam("scope", authorizationHeader.Scope).
-				Get(authorizationHeader.Realm)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
 
-			var badToken authutils.AccessTokenResponse
-			err = json.Unmarshal(resp.Body(), &badToken)
-			So(err, ShouldBeNil)
+		var requests atomic.Int32
 
-			resp, err = resty.R().
-				SetHeader("Authorization", "Bearer "+badToken.AccessToken).
-				Post(baseURL + "/v2/" + UnauthorizedNamespace + "/blobs/uploads/")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-		})
-	}
-}
+		issuer := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			requests.Add(1)
+			response.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer issuer.Close()
 
-func TestNewRelyingPartyOIDC(t *testing.T) {
-	Convey("Test NewRelyingPartyOIDC", t, func() {
 		conf := config.New()
-		ctx := context.Background()
-
-		mockOIDCServer, err := authutils.MockOIDCRun()
-		if err != nil {
-			panic(err)
-		}
-
-		defer func() {
-			err := mockOIDCServer.Shutdown()
-			if err != nil {
-				panic(err)
-			}
-		}()
-
-		mockOIDCConfig := mockOIDCServer.Config()
-
+		conf.HTTP.Port = port
 		conf.HTTP.Auth = &config.AuthConfig{
-			OpenID: &config.OpenIDConfig{
-				Providers: map[string]config.OpenIDProviderConfig{
-					"oidc": {
-						ClientID:     mockOIDCConfig.ClientID,
-						ClientSecret: mockOIDCConfig.ClientSecret,
-						KeyPath:      "",
-						Issuer:       mockOIDCConfig.Issuer,
-						Scopes:       []string{"openid", "email"},
-					},
-				},
+			Bearer: &config.BearerConfig{
+				Realm:   "https://auth.example.com/token",
+				Service: "zot",
+				JWKS:    issuer.URL,
 			},
 		}
 
-		Convey("provider not found in config", func() {
-			So(func() { _ = api.NewRelyingPartyOIDC(ctx, conf, "notDex", nil, nil, log.NewLogger("debug", "")) }, ShouldPanic)
-		})
+		ctlr := makeController(conf, t.TempDir())
 
-		Convey("key path not found on disk", func() {
-			oidcProviderCfg := conf.HTTP.Auth.OpenID.Providers["oidc"]
-			oidcProviderCfg.KeyPath = "path/to/file"
-			conf.HTTP.Auth.OpenID.Providers["oidc"] = oidcProviderCfg
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-			So(func() { _ = api.NewRelyingPartyOIDC(ctx, conf, "oidc", nil, nil, log.NewLogger("debug", "")) }, ShouldPanic)
-		})
+		defer cm.StopServer()
 
-		Convey("https callback", func() {
-			conf.HTTP.TLS = &config.TLSConfig{
-				Cert: ServerCert,
-				Key:  ServerKey,
-			}
+		loads := requests.Load()
 
-			rp := api.NewRelyingPartyOIDC(ctx, conf, "oidc", nil, nil, log.NewLogger("debug", ""))
-			So(rp, ShouldNotBeNil)
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, api.ClaimsWithAccess{
+			RegisteredClaims: jwt.RegisteredClaims{
+				IssuedAt:  jwt.NewNumericDate(time.Now()),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+			},
 		})
+		token.Header["kid"] = "unknown"
 
-		Convey("no client secret in config", func() {
-			oidcProvider := conf.HTTP.Auth.OpenID.Providers["oidc"]
-			oidcProvider.ClientSecret = ""
-			conf.HTTP.Auth.OpenID.Providers["oidc"] = oidcProvider
+		signed, err := token.SignedString(key)
+		So(err, ShouldBeNil)
 
-			rp := api.NewRelyingPartyOIDC(ctx, conf, "oidc", nil, nil, log.NewLogger("debug", ""))
-			So(rp, ShouldNotBeNil)
-		})
+		// the failed loads are rate limited too, whatever the number of concurrent requests
+		var wg sync.WaitGroup
 
-		Convey("provider issuer unreachable", func() {
-			oidcProvider := conf.HTTP.Auth.OpenID.Providers["oidc"]
-			oidcProvider.Issuer = ""
-			conf.HTTP.Auth.OpenID.Providers["oidc"] = oidcProvider
+		for range 10 {
+			wg.Add(1)
 
-			So(func() { _ = api.NewRelyingPartyOIDC(ctx, conf, "oidc", nil, nil, log.NewLogger("debug", "")) }, ShouldPanic)
-		})
-	})
-}
+			go func() {
+				defer wg.Done()
 
-func TestOpenIDMiddleware(t *testing.T) {
-	port := test.GetFreePort()
-	baseURL := test.GetBaseURL(port)
-	defaultVal := true
+				_, _ = resty.R().SetAuthToken(signed).Get(baseURL + "/v2/")
+			}()
+		}
 
-	conf := config.New()
-	conf.HTTP.Port = port
+		wg.Wait()
 
-	testCases := []struct {
-		testCaseName   string
-		address        string
-		externalURL    string
-		useSessionKeys bool
-	}{
-		{
-			address:      "0.0.0.0",
-			externalURL:  "http://" + net.JoinHostPort(conf.HTTP.Address, conf.HTTP.Port),
-			testCaseName: "with ExternalURL provided in config",
-		},
-		{
-			address:      "127.0.0.1",
-			externalURL:  "",
-			testCaseName: "without ExternalURL provided in config",
-		},
-		{
-			address:        "127.0.0.1",
-			externalURL:    "",
-			testCaseName:   "without ExternalURL provided in config and session keys for cookies",
-			useSessionKeys: true,
-		},
-	}
+		resp, err := resty.R().SetAuthToken(signed).Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		So(requests.Load()-loads, ShouldBeLessThanOrEqualTo, 1)
+	})
+}
 
-	// need a username different than ldap one, to test both logic
-	htpasswdUsername, seedUser := test.GenerateRandomString()
-	htpasswdPassword, seedPass := test.GenerateRandomString()
-	htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(htpasswdUsername, htpasswdPassword))
+func TestWorkloadIdentityAuthn(t *testing.T) {
+	Convey("Make a new controller authenticating CI jobs with their workload identity tokens", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
 
-	defer os.Remove(htpasswdPath)
+		issuerKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
 
-	ldapServer := newTestLDAPServer()
-	port = test.GetFreePort()
+		// a local issuer publishing its static keys
+		jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{{Key: issuerKey.Public(), KeyID: "ci"}}})
+		So(err, ShouldBeNil)
 
-	ldapPort, err := strconv.Atoi(port)
-	if err != nil {
-		panic(err)
-	}
+		issuer := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			_, _ = response.Write(jwks)
+		}))
+		defer issuer.Close()
 
-	ldapServer.Start(ldapPort)
-	defer ldapServer.Stop()
+		signToken := func(claims jwt.MapClaims) string {
+			claims["iss"] = issuer.URL
+			claims["aud"] = "zot"
+			claims["iat"] = time.Now().Unix()
+			claims["exp"] = time.Now().Add(5 * time.Minute).Unix()
 
-	mockOIDCServer, err := authutils.MockOIDCRun()
-	if err != nil {
-		panic(err)
-	}
+			token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+			token.Header["kid"] = "ci"
 
-	defer func() {
-		err := mockOIDCServer.Shutdown()
-		if err != nil {
-			panic(err)
+			signed, err := token.SignedString(issuerKey)
+			So(err, ShouldBeNil)
+
+			return signed
 		}
-	}()
 
-	mockOIDCConfig := mockOIDCServer.Config()
-	conf.HTTP.Auth = &config.AuthConfig{
-		HTPasswd: config.AuthHTPasswd{
-			Path: htpasswdPath,
-		},
-		LDAP: (&config.LDAPConfig{
-			Insecure:      true,
-			Address:       LDAPAddress,
-			Port:          ldapPort,
-			BaseDN:        LDAPBaseDN,
-			UserAttribute: "uid",
-		}).SetBindDN(LDAPBindDN).SetBindPassword(LDAPBindPassword),
-		OpenID: &config.OpenIDConfig{
-			Providers: map[string]config.OpenIDProviderConfig{
-				"oidc": {
-					ClientID:     mockOIDCConfig.ClientID,
-					ClientSecret: mockOIDCConfig.ClientSecret,
-					KeyPath:      "",
-					Issuer:       mockOIDCConfig.Issuer,
-					Scopes:       []string{"openid", "email"},
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			WorkloadIdentity: &config.WorkloadIdentityConfig{
+				Issuers: []config.WorkloadIdentityIssuerConfig{
+					{
+						Issuer:   issuer.URL,
+						Audience: "zot",
+						JWKS:     issuer.URL,
+						Rules: []config.WorkloadIdentityRule{
+							{
+								Claims:   map[string]string{"repository": "org/*", "ref": "refs/heads/main"},
+								Identity: "ci:{repository}",
+								Groups:   []string{"release"},
+							},
+							{
+								Claims:   map[string]string{"repository": "org/*"},
+								Identity: "ci:{repository}",
+							},
+						},
+					},
 				},
-				// just for the constructor coverage
-				"github": {
-					ClientID:     mockOIDCConfig.ClientID,
-					ClientSecret: mockOIDCConfig.ClientSecret,
-					KeyPath:      "",
-					Issuer:       mockOIDCConfig.Issuer,
-					Scopes:       []string{"openid", "email"},
+			},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Groups:  []string{"release"},
+							Actions: []string{constants.ReadPermission, constants.CreatePermission},
+						},
+						{
+							Users:   []string{api.WorkloadIdentityPrefix + "ci:org/app"},
+							Actions: []string{constants.ReadPermission},
+						},
+					},
 				},
 			},
-		},
-	}
+		}
 
-	searchConfig := &extconf.SearchConfig{
-		BaseConfig: extconf.BaseConfig{Enable: &defaultVal},
-	}
+		ctlr := makeController(conf, t.TempDir())
 
-	// UI is enabled because we also want to test access on the mgmt route
-	uiConfig := &extconf.UIConfig{
-		BaseConfig: extconf.BaseConfig{Enable: &defaultVal},
-	}
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-	conf.Extensions = &extconf.ExtensionConfig{
-		Search: searchConfig,
-		UI:     uiConfig,
-	}
+		defer cm.StopServer()
 
-	ctlr := api.NewController(conf)
-	ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
+		So(ctlr.WorkloadIdentity, ShouldNotBeNil)
 
-	for _, testcase := range testCases {
-		t.Run(testcase.testCaseName, func(t *testing.T) {
-			if testcase.useSessionKeys {
-				ctlr.Config.HTTP.Auth.SessionHashKey = []byte("3lrioGLGO2RfG9Y7HQGgWa3ayBjMLw2auMXqEWcSXjQKc9SoQ3fKTIbO+toPYa7e")
-				ctlr.Config.HTTP.Auth.SessionEncryptKey = []byte("KOzt01JrDz2uC//UBC5ZikxQw4owfmI8")
-			}
+		mainToken := signToken(jwt.MapClaims{"repository": "org/app", "ref": "refs/heads/main"})
+		branchToken := signToken(jwt.MapClaims{"repository": "org/app", "ref": "refs/heads/feature"})
+		otherToken := signToken(jwt.MapClaims{"repository": "other/app", "ref": "refs/heads/main"})
 
-			Convey("make controller", t, func() {
-				dir := t.TempDir()
+		// the token is given as password, the username being ignored
+		err = UploadImageWithBasicAuth(CreateRandomImage(), baseURL, "org/app", "1.0", "oidc", mainToken)
+		So(err, ShouldBeNil)
 
-				ctlr.Config.Storage.RootDirectory = dir
-				ctlr.Config.HTTP.ExternalURL = testcase.externalURL
-				ctlr.Config.HTTP.Address = testcase.address
-				cm := test.NewControllerManager(ctlr)
+		// the groups are stored in the profile of the prefixed identity, not of a user of the same name
+		workloadAc := reqCtx.NewUserAccessControl()
+		workloadAc.SetUsername("workload:ci:org/app")
 
-				cm.StartServer()
+		groups, err := ctlr.MetaDB.GetUserGroups(workloadAc.DeriveContext(context.Background()))
+		So(err, ShouldBeNil)
+		So(groups, ShouldResemble, []string{"release"})
 
-				defer cm.StopServer()
-				test.WaitTillServerReady(baseURL)
+		userAc := reqCtx.NewUserAccessControl()
+		userAc.SetUsername("ci:org/app")
 
-				Convey("browser client requests", func() {
-					Convey("login with no provider supplied", func() {
-						client := resty.New()
-						client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
-						// first login user
-						resp, err := client.R().
-							SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-							SetQueryParam("provider", "unknown").
-							Get(baseURL + constants.LoginPath)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
-					})
-
-					//nolint: dupl
-					Convey("make sure sessions are not used without UI header value", func() {
-						sessionsNo, err := getNumberOfSessions(conf.Storage.RootDirectory)
-						So(err, ShouldBeNil)
-						So(sessionsNo, ShouldEqual, 0)
+		groups, _ = ctlr.MetaDB.GetUserGroups(userAc.DeriveContext(context.Background()))
+		So(groups, ShouldBeEmpty)
 
-						client := resty.New()
+		// the identity of the jobs running on other branches is only allowed to read
+		resp, err := resty.R().SetBasicAuth("oidc", branchToken).Get(baseURL + "/v2/org/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-						// without header should not create session
-						resp, err := client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		err = UploadImageWithBasicAuth(CreateRandomImage(), baseURL, "org/app", "2.0", "oidc", branchToken)
+		So(err, ShouldNotBeNil)
 
-						sessionsNo, err = getNumberOfSessions(conf.Storage.RootDirectory)
-						So(err, ShouldBeNil)
-						So(sessionsNo, ShouldEqual, 0)
+		// the token can also be given as bearer token
+		resp, err = resty.R().SetAuthToken(branchToken).Get(baseURL + "/v2/org/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-						client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+		// tokens matching no rule are rejected
+		resp, err = resty.R().SetBasicAuth("oidc", otherToken).Get(baseURL + "/v2/org/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		resp, err = resty.R().SetAuthToken("invalid").Get(baseURL + "/v2/org/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+	})
+}
 
-						sessionsNo, err = getNumberOfSessions(conf.Storage.RootDirectory)
-						So(err, ShouldBeNil)
-						So(sessionsNo, ShouldEqual, 1)
+func TestBearerTokenServer(t *testing.T) {
+	Convey("Make a new controller issuing its own bearer tokens", t, func() {
+		user, password := "user", "password"
+		reader, readerPassword := "reader", "password"
 
-						// set cookies
-						client.SetCookies(resp.Cookies())
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(user, password) + "\n" +
+			test.GetCredString(reader, readerPassword))
+		defer os.Remove(htpasswdPath)
 
-						// should get same cookie
-						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
 
-						sessionsNo, err = getNumberOfSessions(conf.Storage.RootDirectory)
-						So(err, ShouldBeNil)
-						So(sessionsNo, ShouldEqual, 1)
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+			Bearer: &config.BearerConfig{
+				Realm:       baseURL + constants.TokenPath,
+				Service:     "zot",
+				TokenServer: &config.TokenServerConfig{Expiration: time.Minute},
+			},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users:   []string{user},
+							Actions: []string{constants.ReadPermission, constants.CreatePermission},
+						},
+						{
+							Users:   []string{reader},
+							Actions: []string{constants.ReadPermission},
+						},
+					},
+				},
+			},
+		}
 
-						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
-							Get(baseURL + constants.FullMgmt)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		ctlr := makeController(conf, t.TempDir())
 
-						client.SetCookies(resp.Cookies())
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-						// call endpoint with session, without credentials, (added to client after previous request)
-						resp, err = client.R().
-							Get(baseURL + "/v2/_catalog")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		defer cm.StopServer()
 
-						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		getToken := func(username, password string, authHeader *authutils.AuthHeader) string {
+			resp, err := resty.R().SetBasicAuth(username, password).
+				SetQueryParam("service", authHeader.Service).
+				SetQueryParam("scope", authHeader.Scope).
+				Get(authHeader.Realm)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-						sessionsNo, err = getNumberOfSessions(conf.Storage.RootDirectory)
-						So(err, ShouldBeNil)
-						So(sessionsNo, ShouldEqual, 1)
-					})
+			var token api.TokenResponse
 
-					Convey("login with openid and get catalog with session", func() {
-						client := resty.New()
-						client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
-						client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+			err = json.Unmarshal(resp.Body(), &token)
+			So(err, ShouldBeNil)
+			So(token.Token, ShouldEqual, token.AccessToken)
+			So(token.ExpiresIn, ShouldEqual, 60)
+			So(token.IssuedAt, ShouldNotBeEmpty)
 
-						Convey("with callback_ui value provided", func() {
-							// first login user
-							resp, err := client.R().
-								SetQueryParam("provider", "oidc").
-								SetQueryParam("callback_ui", baseURL+"/v2/").
-								Get(baseURL + constants.LoginPath)
-							So(err, ShouldBeNil)
-							So(resp, ShouldNotBeNil)
-							So(resp.StatusCode(), ShouldEqual, http.StatusOK)
-						})
+			return token.Token
+		}
 
-						// first login user
-						resp, err := client.R().
-							SetQueryParam("provider", "oidc").
-							Get(baseURL + constants.LoginPath)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+		resp, err := resty.R().Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-						client.SetCookies(resp.Cookies())
+		authHeader := authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
+		So(authHeader.Realm, ShouldEqual, baseURL+constants.TokenPath)
+		So(authHeader.Service, ShouldEqual, "zot")
 
-						// call endpoint with session (added to client after previous request)
-						resp, err = client.R().
-							Get(baseURL + "/v2/_catalog")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		// the token endpoint requires credentials
+		resp, err = resty.R().SetQueryParam("service", authHeader.Service).Get(authHeader.Realm)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-						// logout with options method for coverage
-						resp, err = client.R().
-							Options(baseURL + constants.LogoutPath)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
+		resp, err = resty.R().SetBasicAuth(user, "wrong").Get(authHeader.Realm)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-						// logout user
-						resp, err = client.R().
-							Post(baseURL + constants.LogoutPath)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		resp, err = resty.R().SetBasicAuth(user, password).SetQueryParam("service", "other").Get(authHeader.Realm)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
 
-						// calling endpoint should fail with unauthorized access
-						resp, err = client.R().
-							Get(baseURL + "/v2/_catalog")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-					})
+		resp, err = resty.R().SetBasicAuth(user, password).SetQueryParam("scope", "repository:pull").
+			Get(authHeader.Realm)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
 
-					//nolint: dupl
-					Convey("login with basic auth(htpasswd) and get catalog with session", func() {
-						client := resty.New()
-						client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+		token := getToken(user, password, authHeader)
 
-						// without creds, should get access error
-						resp, err := client.R().Get(baseURL + "/v2/")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		resp, err = resty.R().SetAuthToken(token).Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-						var e apiErr.Error
+		// the access claims only contain the actions allowed by the policies
+		resp, err = resty.R().Post(baseURL + "/v2/repo/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-						err = json.Unmarshal(resp.Body(), &e)
-						So(err, ShouldBeNil)
+		authHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
+		So(authHeader.Scope, ShouldEqual, "repository:repo:push")
 
-						// first login user
-						// with creds, should get expected status code
-						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		readerToken := getToken(reader, readerPassword, authHeader)
 
-						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		resp, err = resty.R().SetAuthToken(readerToken).Post(baseURL + "/v2/repo/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
-							Get(baseURL + constants.FullMgmt)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		authHeader.Scope = "repository:repo:pull,push,delete"
+		token = getToken(user, password, authHeader)
 
-						client.SetCookies(resp.Cookies())
+		claims := &api.ClaimsWithAccess{}
+		_, _, err = jwt.NewParser().ParseUnverified(token, claims)
+		So(err, ShouldBeNil)
+		So(claims.Subject, ShouldEqual, user)
+		So(claims.Issuer, ShouldEqual, "zot")
+		So(claims.Access, ShouldResemble, []api.ResourceAccess{
+			{Type: "repository", Name: "repo", Actions: []string{"pull", "push"}},
+		})
 
-						// call endpoint with session, without credentials, (added to client after previous request)
-						resp, err = client.R().
-							Get(baseURL + "/v2/_catalog")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		image := CreateRandomImage()
 
-						resp, err = client.R().
-							Get(baseURL + constants.FullMgmt)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		pushBlob := func(blob []byte) {
+			resp, err := resty.R().SetAuthToken(token).Post(baseURL + "/v2/repo/blobs/uploads/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
 
-						// logout user
-						resp, err = client.R().
-							Post(baseURL + constants.LogoutPath)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+			resp, err = resty.R().SetAuthToken(token).
+				SetHeader("Content-Type", "application/octet-stream").
+				SetQueryParam("digest", godigest.FromBytes(blob).String()).
+				SetBody(blob).
+				Put(baseURL + resp.Header().Get("Location"))
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+		}
 
-						// calling endpoint should fail with unauthorized access
-						resp, err = client.R().
-							Get(baseURL + "/v2/_catalog")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-					})
+		for _, layer := range image.Layers {
+			pushBlob(layer)
+		}
 
-					//nolint: dupl
-					Convey("login with ldap and get catalog", func() {
-						client := resty.New()
-						client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+		configBlob, err := json.Marshal(image.Config)
+		So(err, ShouldBeNil)
+		pushBlob(configBlob)
 
-						// without creds, should get access error
-						resp, err := client.R().Get(baseURL + "/v2/")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		resp, err = resty.R().SetAuthToken(token).
+			SetHeader("Content-Type", ispec.MediaTypeImageManifest).
+			SetBody(image.ManifestDescriptor.Data).
+			Put(baseURL + "/v2/repo/manifests/1.0")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
 
-						var e apiErr.Error
+		// the users authenticated by the token server are known to the rest of zot
+		history, err := ctlr.MetaDB.GetTagHistory(context.Background(), "repo", "1.0")
+		So(err, ShouldBeNil)
+		So(history, ShouldHaveLength, 1)
+		So(history[0].User, ShouldEqual, user)
 
-						err = json.Unmarshal(resp.Body(), &e)
-						So(err, ShouldBeNil)
+		// the tokens issued by zot are authorized like the other users: overwriting a tag needs 'update'
+		image2 := CreateRandomImage()
 
-						// first login user
-						// with creds, should get expected status code
-						resp, err = client.R().SetBasicAuth(username, password).Get(baseURL)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		for _, layer := range image2.Layers {
+			pushBlob(layer)
+		}
 
-						resp, err = client.R().SetBasicAuth(username, password).Get(baseURL + "/v2/")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		configBlob, err = json.Marshal(image2.Config)
+		So(err, ShouldBeNil)
+		pushBlob(configBlob)
 
-						resp, err = client.R().SetBasicAuth(username, password).
-							Get(baseURL + constants.FullMgmt)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		resp, err = resty.R().SetAuthToken(token).
+			SetHeader("Content-Type", ispec.MediaTypeImageManifest).
+			SetBody(image2.ManifestDescriptor.Data).
+			Put(baseURL + "/v2/repo/manifests/1.0")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
 
-						client.SetCookies(resp.Cookies())
+		// deleting needs a token with the 'delete' action, which is only granted with the 'delete' permission
+		resp, err = resty.R().SetAuthToken(token).Delete(baseURL + "/v2/repo/manifests/1.0")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-						// call endpoint with session, without credentials, (added to client after previous request)
-						resp, err = client.R().
-							Get(baseURL + "/v2/_catalog")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		authHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
+		So(authHeader.Scope, ShouldEqual, "repository:repo:delete")
 
-						resp, err = client.R().
-							Get(baseURL + constants.FullMgmt)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		resp, err = resty.R().SetAuthToken(getToken(user, password, authHeader)).
+			Delete(baseURL + "/v2/repo/manifests/1.0")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-						// logout user
-						resp, err = client.R().
-							Post(baseURL + constants.LogoutPath)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		resp, err = resty.R().SetAuthToken(readerToken).Get(baseURL + "/v2/repo/manifests/1.0")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-						// calling endpoint should fail with unauthorized access
-						resp, err = client.R().
-							Get(baseURL + "/v2/_catalog")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-					})
+		authHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
+		readerToken = getToken(reader, readerPassword, authHeader)
 
-					Convey("unauthenticated catalog request", func() {
-						client := resty.New()
+		resp, err = resty.R().SetAuthToken(readerToken).Get(baseURL + "/v2/repo/manifests/1.0")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+	})
+}
 
-						// mgmt should work both unauthenticated and authenticated
-						resp, err := client.R().
-							Get(baseURL + constants.FullMgmt)
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+func TestBearerTokenServerIdentities(t *testing.T) {
+	Convey("Make a new controller issuing its own bearer tokens and verifying external ones", t, func() {
+		user, password := "user", "password"
 
-						// call endpoint without session
-						resp, err = client.R().
-							Get(baseURL + "/v2/_catalog")
-						So(err, ShouldBeNil)
-						So(resp, ShouldNotBeNil)
-						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-					})
-				})
-			})
-		})
-	}
-}
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(user, password))
+		defer os.Remove(htpasswdPath)
+
+		externalKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
+
+		jwks, err := json.Marshal(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: externalKey.Public(), KeyID: "external", Use: "sig"},
+		}})
+		So(err, ShouldBeNil)
+
+		jwksPath := path.Join(t.TempDir(), "jwks.json")
+		So(os.WriteFile(jwksPath, jwks, 0o600), ShouldBeNil)
 
-func TestIsOpenIDEnabled(t *testing.T) {
-	Convey("make oidc server", t, func() {
 		port := test.GetFreePort()
 		baseURL := test.GetBaseURL(port)
 
 		conf := config.New()
 		conf.HTTP.Port = port
-
-		mockOIDCServer, err := authutils.MockOIDCRun()
-		if err != nil {
-			panic(err)
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+			APIKey:   true,
+			Bearer: &config.BearerConfig{
+				Realm:       baseURL + constants.TokenPath,
+				Service:     "zot",
+				JWKS:        jwksPath,
+				TokenServer: &config.TokenServerConfig{Expiration: time.Minute},
+			},
 		}
-
-		defer func() {
-			err := mockOIDCServer.Shutdown()
-			if err != nil {
-				panic(err)
-			}
-		}()
-
-		rootDir := t.TempDir()
-
-		Convey("Only OAuth2 provided", func() {
-			mockOIDCConfig := mockOIDCServer.Config()
-			conf.HTTP.Auth = &config.AuthConfig{
-				OpenID: &config.OpenIDConfig{
-					Providers: map[string]config.OpenIDProviderConfig{
-						"github": {
-							ClientID:     mockOIDCConfig.ClientID,
-							ClientSecret: mockOIDCConfig.ClientSecret,
-							KeyPath:      "",
-							Issuer:       mockOIDCConfig.Issuer,
-							Scopes:       []string{"email", "groups"},
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users: []string{user},
+							Actions: []string{
+								constants.ReadPermission, constants.CreatePermission, constants.UpdatePermission,
+							},
 						},
 					},
 				},
-			}
+			},
+		}
 
-			ctlr := api.NewController(conf)
+		ctlr := makeController(conf, t.TempDir())
 
-			ctlr.Config.Storage.RootDirectory = rootDir
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-			cm := test.NewControllerManager(ctlr)
+		defer cm.StopServer()
 
-			cm.StartServer()
+		pushImage := func(token, repo, tag string) *resty.Response {
+			image := CreateRandomImage()
 
-			defer cm.StopServer()
-			test.WaitTillServerReady(baseURL)
+			blobs := append([][]byte{}, image.Layers...)
 
-			resp, err := resty.R().
-				Get(baseURL + "/v2/")
+			configBlob, err := json.Marshal(image.Config)
 			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-		})
 
-		Convey("Unsupported provider", func() {
-			mockOIDCConfig := mockOIDCServer.Config()
-			conf.HTTP.Auth = &config.AuthConfig{
-				OpenID: &config.OpenIDConfig{
-					Providers: map[string]config.OpenIDProviderConfig{
-						"invalidProvider": {
-							ClientID:     mockOIDCConfig.ClientID,
-							ClientSecret: mockOIDCConfig.ClientSecret,
-							KeyPath:      "",
-							Issuer:       mockOIDCConfig.Issuer,
-							Scopes:       []string{"email", "groups"},
-						},
-					},
-				},
+			for _, blob := range append(blobs, configBlob) {
+				resp, err := resty.R().SetAuthToken(token).Post(baseURL + "/v2/" + repo + "/blobs/uploads/")
+				So(err, ShouldBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+
+				resp, err = resty.R().SetAuthToken(token).
+					SetHeader("Content-Type", "application/octet-stream").
+					SetQueryParam("digest", godigest.FromBytes(blob).String()).
+					SetBody(blob).
+					Put(baseURL + resp.Header().Get("Location"))
+				So(err, ShouldBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
 			}
 
-			ctlr := api.NewController(conf)
+			resp, err := resty.R().SetAuthToken(token).
+				SetHeader("Content-Type", ispec.MediaTypeImageManifest).
+				SetBody(image.ManifestDescriptor.Data).
+				Put(baseURL + "/v2/" + repo + "/manifests/" + tag)
+			So(err, ShouldBeNil)
 
-			ctlr.Config.Storage.RootDirectory = rootDir
+			return resp
+		}
 
-			cm := test.NewControllerManager(ctlr)
+		Convey("External tokens carrying the issuer of zot are not taken for its users", func() {
+			issuedAt := time.Now()
 
-			cm.StartServer()
+			token := jwt.NewWithClaims(jwt.SigningMethodES256, api.ClaimsWithAccess{
+				Access: []api.ResourceAccess{{Type: "repository", Name: "repo", Actions: []string{"pull", "push"}}},
+				RegisteredClaims: jwt.RegisteredClaims{
+					Issuer:    "zot",
+					Subject:   user,
+					ExpiresAt: jwt.NewNumericDate(issuedAt.Add(time.Minute)),
+					IssuedAt:  jwt.NewNumericDate(issuedAt),
+				},
+			})
+			token.Header["kid"] = "external"
 
-			defer cm.StopServer()
-			test.WaitTillServerReady(baseURL)
+			signedString, err := token.SignedString(externalKey)
+			So(err, ShouldBeNil)
 
-			// it will work because we have an invalid provider, and no other authn enabled, so no authn enabled
-			// normally an invalid provider will exit with error in cli validations
-			resp, err := resty.R().
-				Get(baseURL + "/v2/")
+			resp := pushImage(signedString, "repo", "1.0")
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			// the request was only authorized by the access claims, not as the user
+			history, err := ctlr.MetaDB.GetTagHistory(context.Background(), "repo", "1.0")
 			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+			So(history, ShouldHaveLength, 1)
+			So(history[0].User, ShouldBeEmpty)
 		})
-	})
-}
 
-func TestAuthnSessionErrors(t *testing.T) {
-	Convey("make controller", t, func() {
-		port := test.GetFreePort()
-		baseURL := test.GetBaseURL(port)
-		defaultVal := true
+		Convey("Tokens obtained with a scoped api key are restricted by its scopes", func() {
+			resp, err := resty.R().SetBasicAuth(user, password).
+				SetBody(api.APIKeyPayload{Label: "ci", Scopes: []string{"repository:repo:read,create"}}).
+				Post(baseURL + constants.APIKeyPath)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
 
-		conf := config.New()
-		conf.HTTP.Port = port
-		invalidSessionID := "sessionID"
+			var apiKey struct {
+				APIKey string `json:"apiKey"`
+			}
 
-		// need a username different than ldap one, to test both logic
-		htpasswdUsername, seedUser := test.GenerateRandomString()
-		htpasswdPassword, seedPass := test.GenerateRandomString()
+			err = json.Unmarshal(resp.Body(), &apiKey)
+			So(err, ShouldBeNil)
 
-		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(htpasswdUsername, htpasswdPassword))
+			resp, err = resty.R().SetBasicAuth(user, apiKey.APIKey).
+				SetQueryParams(map[string]string{"service": "zot", "scope": "repository:repo:pull,push"}).
+				Get(baseURL + constants.TokenPath)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		defer os.Remove(htpasswdPath)
+			var tokenResponse api.TokenResponse
 
-		ldapServer := newTestLDAPServer()
-		port = test.GetFreePort()
+			err = json.Unmarshal(resp.Body(), &tokenResponse)
+			So(err, ShouldBeNil)
 
-		ldapPort, err := strconv.Atoi(port)
-		if err != nil {
-			panic(err)
-		}
+			claims := &api.ClaimsWithAccess{}
+			_, _, err = jwt.NewParser().ParseUnverified(tokenResponse.Token, claims)
+			So(err, ShouldBeNil)
+			So(claims.APIKeyScopes, ShouldResemble, []string{"repository:repo:read,create"})
 
-		ldapServer.Start(ldapPort)
-		defer ldapServer.Stop()
+			resp = pushImage(tokenResponse.Token, "repo", "1.0")
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
 
-		mockOIDCServer, err := authutils.MockOIDCRun()
-		if err != nil {
-			panic(err)
-		}
+			// the user may update the tag, not the api key
+			resp = pushImage(tokenResponse.Token, "repo", "1.0")
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+		})
+	})
+}
 
-		defer func() {
-			err := mockOIDCServer.Shutdown()
-			if err != nil {
-				panic(err)
-			}
-		}()
+func TestBearerTokenServerWithLDAPGroups(t *testing.T) {
+	Convey("Make a new controller issuing bearer tokens to the users authenticated with ldap", t, func() {
+		ldapServer := newTestLDAPServer()
+		ldapPort, err := strconv.Atoi(test.GetFreePort())
+		So(err, ShouldBeNil)
+		ldapServer.Start(ldapPort)
 
-		rootDir := t.TempDir()
+		defer ldapServer.Stop()
 
-		mockOIDCConfig := mockOIDCServer.Config()
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		conf := config.New()
+		conf.HTTP.Port = port
 		conf.HTTP.Auth = &config.AuthConfig{
-			HTPasswd: config.AuthHTPasswd{
-				Path: htpasswdPath,
-			},
 			LDAP: (&config.LDAPConfig{
-				Insecure:      true,
-				Address:       LDAPAddress,
-				Port:          ldapPort,
-				BaseDN:        LDAPBaseDN,
-				UserAttribute: "uid",
+				Insecure:           true,
+				Address:            LDAPAddress,
+				Port:               ldapPort,
+				BaseDN:             LDAPBaseDN,
+				UserAttribute:      "uid",
+				UserGroupAttribute: "memberOf",
 			}).SetBindDN(LDAPBindDN).SetBindPassword(LDAPBindPassword),
-			OpenID: &config.OpenIDConfig{
-				Providers: map[string]config.OpenIDProviderConfig{
-					"oidc": {
-						ClientID:     mockOIDCConfig.ClientID,
-						ClientSecret: mockOIDCConfig.ClientSecret,
-						KeyPath:      "",
-						Issuer:       mockOIDCConfig.Issuer,
-						Scopes:       []string{"email", "groups"},
+			Bearer: &config.BearerConfig{
+				Realm:       baseURL + constants.TokenPath,
+				Service:     "zot",
+				TokenServer: &config.TokenServerConfig{Expiration: time.Minute},
+			},
+		}
+		// the users are only given access by their ldap group
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Groups:  []string{group},
+							Actions: []string{constants.ReadPermission, constants.CreatePermission},
+						},
 					},
 				},
 			},
+			AdminPolicy: config.Policy{
+				Groups:  []string{group},
+				Actions: []string{constants.ReadPermission},
+			},
 		}
 
-		uiConfig := &extconf.UIConfig{
-			BaseConfig: extconf.BaseConfig{Enable: &defaultVal},
-		}
+		ctlr := makeController(conf, t.TempDir())
 
-		searchConfig := &extconf.SearchConfig{
-			BaseConfig: extconf.BaseConfig{Enable: &defaultVal},
-		}
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-		conf.Extensions = &extconf.ExtensionConfig{
-			UI:     uiConfig,
-			Search: searchConfig,
-		}
+		defer cm.StopServer()
 
-		ctlr := api.NewController(conf)
-		ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
+		resp, err := resty.R().SetBasicAuth(username, password).
+			SetQueryParams(map[string]string{"service": "zot", "scope": "repository:repo:pull,push"}).
+			Get(baseURL + constants.TokenPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		ctlr.Config.Storage.RootDirectory = rootDir
+		var tokenResponse api.TokenResponse
 
-		cm := test.NewControllerManager(ctlr)
+		err = json.Unmarshal(resp.Body(), &tokenResponse)
+		So(err, ShouldBeNil)
 
-		cm.StartServer()
+		claims := &api.ClaimsWithAccess{}
+		_, _, err = jwt.NewParser().ParseUnverified(tokenResponse.Token, claims)
+		So(err, ShouldBeNil)
+		So(claims.Groups, ShouldContain, group)
+		So(claims.Access, ShouldResemble, []api.ResourceAccess{
+			{Type: "repository", Name: "repo", Actions: []string{"pull", "push"}},
+		})
 
-		defer cm.StopServer()
-		test.WaitTillServerReady(baseURL)
+		// the token is authorized with the ldap group of the user
+		resp, err = resty.R().SetAuthToken(tokenResponse.Token).Post(baseURL + "/v2/repo/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
 
-		Convey("trigger basic authn middle(htpasswd) error", func() {
-			client := resty.New()
+		resp, err = resty.R().SetAuthToken(tokenResponse.Token).Get(baseURL + "/v2/repo/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+	})
+}
 
-			ctlr.MetaDB = mocks.MetaDBMock{
-				SetUserGroupsFn: func(ctx context.Context, groups []string) error {
-					return ErrUnexpectedError
-				},
-			}
+func TestBearerAuthWithAllowReadAccess(t *testing.T) {
+	testCases := []struct {
+		name                    string
+		useLegacyAuthTestServer bool
+	}{
+		{
+			name:                    "new authentication test server",
+			useLegacyAuthTestServer: false,
+		},
+		{
+			name:                    "legacy authentication test server",
+			useLegacyAuthTestServer: true,
+		},
+	}
 
-			resp, err := client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
-				Get(baseURL + "/v2/_catalog")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
-		})
+	for _, testCase := range testCases {
+		Convey("Make a new controller with"+testCase.name, t, func() {
+			var authTestServer *httptest.Server
+			if testCase.useLegacyAuthTestServer {
+				authTestServer = authutils.MakeAuthTestServerLegacy(ServerKey, UnauthorizedNamespace)
+			} else {
+				authTestServer = authutils.MakeAuthTestServer(ServerKey, "RS256", UnauthorizedNamespace)
+			}
+			defer authTestServer.Close()
 
-		Convey("trigger basic authn middle(ldap) error", func() {
-			client := resty.New()
+			port := test.GetFreePort()
+			baseURL := test.GetBaseURL(port)
 
-			ctlr.MetaDB = mocks.MetaDBMock{
-				SetUserGroupsFn: func(ctx context.Context, groups []string) error {
-					return ErrUnexpectedError
-				},
-			}
+			conf := config.New()
+			conf.HTTP.Port = port
 
-			resp, err := client.R().SetBasicAuth(username, password).
-				Get(baseURL + "/v2/_catalog")
+			aurl, err := url.Parse(authTestServer.URL)
 			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
-		})
-
-		Convey("trigger updateUserData error", func() {
-			client := resty.New()
-			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
 
-			ctlr.MetaDB = mocks.MetaDBMock{
-				SetUserGroupsFn: func(ctx context.Context, groups []string) error {
-					return ErrUnexpectedError
+			conf.HTTP.Auth = &config.AuthConfig{
+				Bearer: &config.BearerConfig{
+					Cert:    ServerCert,
+					Realm:   authTestServer.URL + "/auth/token",
+					Service: aurl.Host,
 				},
 			}
+			ctlr := makeController(conf, t.TempDir())
 
-			resp, err := client.R().
-				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-				SetQueryParam("provider", "oidc").
-				Get(baseURL + constants.LoginPath)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
-		})
-
-		Convey("trigger session middle metaDB errors", func() {
-			client := resty.New()
-			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+			conf.HTTP.AccessControl = &config.AccessControlConfig{
+				Repositories: config.Repositories{
+					test.AuthorizationAllRepos: config.PolicyGroup{
+						AnonymousPolicy: []string{"read"},
+					},
+				},
+			}
 
-			user := mockoidc.DefaultUser()
-			user.Groups = []string{"group1", "group2"}
+			cm := test.NewControllerManager(ctlr)
+			cm.StartAndWait(port)
 
-			mockOIDCServer.QueueUser(user)
+			defer cm.StopServer()
 
-			ctlr.MetaDB = mocks.MetaDBMock{}
+			blob := []byte("hello, blob!")
+			digest := godigest.FromBytes(blob).String()
 
-			// first login user
-			resp, err := client.R().
-				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-				SetQueryParam("provider", "oidc").
-				Get(baseURL + constants.LoginPath)
+			resp, err := resty.R().Get(baseURL + "/v2/")
 			So(err, ShouldBeNil)
 			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
-
-			Convey("trigger session middle error internal server error", func() {
-				cookies := resp.Cookies()
-
-				client.SetCookies(cookies)
-
-				ctlr.MetaDB = mocks.MetaDBMock{
-					GetUserGroupsFn: func(ctx context.Context) ([]string, error) {
-						return []string{}, ErrUnexpectedError
-					},
-				}
-
-				// call endpoint with session (added to client after previous request)
-				resp, err = client.R().
-					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-					Get(baseURL + "/v2/_catalog")
-				So(err, ShouldBeNil)
-				So(resp, ShouldNotBeNil)
-				So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
-			})
-
-			Convey("trigger session middle error GetUserGroups not found", func() {
-				cookies := resp.Cookies()
-
-				client.SetCookies(cookies)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-				ctlr.MetaDB = mocks.MetaDBMock{
-					GetUserGroupsFn: func(ctx context.Context) ([]string, error) {
-						return []string{}, errors.ErrUserDataNotFound
-					},
-				}
+			authorizationHeader := authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
+			resp, err = resty.R().
+				SetQueryParam("service", authorizationHeader.Service).
+				SetQueryParam("scope", authorizationHeader.Scope).
+				Get(authorizationHeader.Realm)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-				// call endpoint with session (added to client after previous request)
-				resp, err = client.R().
-					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-					Get(baseURL + "/v2/_catalog")
-				So(err, ShouldBeNil)
-				So(resp, ShouldNotBeNil)
-				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-			})
-		})
+			var goodToken authutils.AccessTokenResponse
 
-		Convey("trigger no email error in routes(callback)", func() {
-			user := mockoidc.DefaultUser()
-			user.Email = ""
+			err = json.Unmarshal(resp.Body(), &goodToken)
+			So(err, ShouldBeNil)
 
-			mockOIDCServer.QueueUser(user)
+			resp, err = resty.R().
+				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
+				Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			client := resty.New()
-			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+			s1, seed1 := test.GenerateRandomName()
+			s2, seed2 := test.GenerateRandomName()
+			repoName := s1 + "/" + s2
 
-			client.SetCookie(&http.Cookie{Name: "session"})
+			ctlr.Log.Info().Int64("seed1", seed1).Int64("seed2", seed2).Msg("random seeds for repoName")
 
-			// call endpoint with session (added to client after previous request)
-			resp, err := client.R().
-				SetQueryParam("provider", "oidc").
-				Get(baseURL + constants.LoginPath)
+			resp, err = resty.R().Post(baseURL + "/v2/" + repoName + "/blobs/uploads/")
 			So(err, ShouldBeNil)
 			So(resp, ShouldNotBeNil)
 			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-		})
-
-		Convey("trigger session save error in routes(callback)", func() {
-			err := os.Chmod(rootDir, 0o000)
-			So(err, ShouldBeNil)
-
-			defer func() {
-				err := os.Chmod(rootDir, storageConstants.DefaultDirPerms)
-				So(err, ShouldBeNil)
-			}()
-
-			client := resty.New()
-			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
 
-			// first login user
-			resp, err := client.R().
-				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-				SetQueryParam("provider", "oidc").
-				Get(baseURL + constants.LoginPath)
+			authorizationHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
+			resp, err = resty.R().
+				SetQueryParam("service", authorizationHeader.Service).
+				SetQueryParam("scope", authorizationHeader.Scope).
+				Get(authorizationHeader.Realm)
 			So(err, ShouldBeNil)
 			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
-		})
-
-		Convey("trigger session save error in basicAuthn", func() {
-			err := os.Chmod(rootDir, 0o000)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+			err = json.Unmarshal(resp.Body(), &goodToken)
 			So(err, ShouldBeNil)
 
-			defer func() {
-				err := os.Chmod(rootDir, storageConstants.DefaultDirPerms)
-				So(err, ShouldBeNil)
-			}()
-
-			client := resty.New()
-			client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
-
-			// first htpasswd saveSessionLoggedUser() error
-			resp, err := client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
-				Get(baseURL + "/v2/")
+			resp, err = resty.R().
+				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
+				Post(baseURL + "/v2/" + repoName + "/blobs/uploads/")
 			So(err, ShouldBeNil)
 			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+			loc := resp.Header().Get("Location")
 
-			// second ldap saveSessionLoggedUser() error
-			resp, err = client.R().SetBasicAuth(username, password).
-				Get(baseURL + "/v2/")
+			resp, err = resty.R().
+				SetHeader("Content-Length", strconv.Itoa(len(blob))).
+				SetHeader("Content-Type", "application/octet-stream").
+				SetQueryParam("digest", digest).
+				SetBody(blob).
+				Put(baseURL + loc)
 			So(err, ShouldBeNil)
 			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
-		})
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			authorizationHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
+			resp, err = resty.R().
+				SetQueryParam("service", authorizationHeader.Service).
+				SetQueryParam("scope", authorizationHeader.Scope).
+				Get(authorizationHeader.Realm)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+			err = json.Unmarshal(resp.Body(), &goodToken)
+			So(err, ShouldBeNil)
+
+			resp, err = resty.R().
+				SetHeader("Content-Length", strconv.Itoa(len(blob))).
+				SetHeader("Content-Type", "application/octet-stream").
+				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
+				SetQueryParam("digest", digest).
+				SetBody(blob).
+				Put(baseURL + loc)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			resp, err = resty.R().
+				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
+				Get(baseURL + "/v2/" + repoName + "/tags/list")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			authorizationHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
+			resp, err = resty.R().
+				SetQueryParam("service", authorizationHeader.Service).
+				SetQueryParam("scope", authorizationHeader.Scope).
+				Get(authorizationHeader.Realm)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+			err = json.Unmarshal(resp.Body(), &goodToken)
+			So(err, ShouldBeNil)
+
+			resp, err = resty.R().
+				SetHeader("Authorization", "Bearer "+goodToken.AccessToken).
+				Get(baseURL + "/v2/" + repoName + "/tags/list")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			resp, err = resty.R().
+				Post(baseURL + "/v2/" + UnauthorizedNamespace + "/blobs/uploads/")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			authorizationHeader = authutils.ParseBearerAuthHeader(resp.Header().Get("WWW-Authenticate"))
+			resp, err = resty.R().
+				SetQueryParam("service", authorizationHeader.Service).
+				SetQueryParam("scope", authorizationHeader.Scope).
+				Get(authorizationHeader.Realm)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			var badToken authutils.AccessTokenResponse
+			err = json.Unmarshal(resp.Body(), &badToken)
+			So(err, ShouldBeNil)
+
+			resp, err = resty.R().
+				SetHeader("Authorization", "Bearer "+badToken.AccessToken).
+				Post(baseURL + "/v2/" + UnauthorizedNamespace + "/blobs/uploads/")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		})
+	}
+}
+
+func TestNewRelyingPartyOIDC(t *testing.T) {
+	Convey("Test NewRelyingPartyOIDC", t, func() {
+		conf := config.New()
+		ctx := context.Background()
+
+		mockOIDCServer, err := authutils.MockOIDCRun()
+		if err != nil {
+			panic(err)
+		}
+
+		defer func() {
+			err := mockOIDCServer.Shutdown()
+			if err != nil {
+				panic(err)
+			}
+		}()
+
+		mockOIDCConfig := mockOIDCServer.Config()
+
+		conf.HTTP.Auth = &config.AuthConfig{
+			OpenID: &config.OpenIDConfig{
+				Providers: map[string]config.OpenIDProviderConfig{
+					"oidc": {
+						ClientID:     mockOIDCConfig.ClientID,
+						ClientSecret: mockOIDCConfig.ClientSecret,
+						KeyPath:      "",
+						Issuer:       mockOIDCConfig.Issuer,
+						Scopes:       []string{"openid", "email"},
+					},
+				},
+			},
+		}
+
+		Convey("provider not found in config", func() {
+			So(func() { _ = api.NewRelyingPartyOIDC(ctx, conf, "notDex", nil, nil, log.NewLogger("debug", "")) }, ShouldPanic)
+		})
+
+		Convey("key path not found on disk", func() {
+			oidcProviderCfg := conf.HTTP.Auth.OpenID.Providers["oidc"]
+			oidcProviderCfg.KeyPath = "path/to/file"
+			conf.HTTP.Auth.OpenID.Providers["oidc"] = oidcProviderCfg
+
+			So(func() { _ = api.NewRelyingPartyOIDC(ctx, conf, "oidc", nil, nil, log.NewLogger("debug", "")) }, ShouldPanic)
+		})
+
+		Convey("https callback", func() {
+			conf.HTTP.TLS = &config.TLSConfig{
+				Cert: ServerCert,
+				Key:  ServerKey,
+			}
+
+			rp := api.NewRelyingPartyOIDC(ctx, conf, "oidc", nil, nil, log.NewLogger("debug", ""))
+			So(rp, ShouldNotBeNil)
+		})
+
+		Convey("no client secret in config", func() {
+			oidcProvider := conf.HTTP.Auth.OpenID.Providers["oidc"]
+			oidcProvider.ClientSecret = ""
+			conf.HTTP.Auth.OpenID.Providers["oidc"] = oidcProvider
+
+			rp := api.NewRelyingPartyOIDC(ctx, conf, "oidc", nil, nil, log.NewLogger("debug", ""))
+			So(rp, ShouldNotBeNil)
+		})
+
+		Convey("provider issuer unreachable", func() {
+			oidcProvider := conf.HTTP.Auth.OpenID.Providers["oidc"]
+			oidcProvider.Issuer = ""
+			conf.HTTP.Auth.OpenID.Providers["oidc"] = oidcProvider
+
+			So(func() { _ = api.NewRelyingPartyOIDC(ctx, conf, "oidc", nil, nil, log.NewLogger("debug", "")) }, ShouldPanic)
+		})
+	})
+}
+
+func TestOpenIDMiddleware(t *testing.T) {
+	port := test.GetFreePort()
+	baseURL := test.GetBaseURL(port)
+	defaultVal := true
+
+	conf := config.New()
+	conf.HTTP.Port = port
+
+	testCases := []struct {
+		testCaseName   string
+		address        string
+		externalURL    string
+		useSessionKeys bool
+	}{
+		{
+			address:      "0.0.0.0",
+			externalURL:  "http://" + net.JoinHostPort(conf.HTTP.Address, conf.HTTP.Port),
+			testCaseName: "with ExternalURL provided in config",
+		},
+		{
+			address:      "127.0.0.1",
+			externalURL:  "",
+			testCaseName: "without ExternalURL provided in config",
+		},
+		{
+			address:        "127.0.0.1",
+			externalURL:    "",
+			testCaseName:   "without ExternalURL provided in config and session keys for cookies",
+			useSessionKeys: true,
+		},
+	}
+
+	// need a username different than ldap one, to test both logic
+	htpasswdUsername, seedUser := test.GenerateRandomString()
+	htpasswdPassword, seedPass := test.GenerateRandomString()
+	htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(htpasswdUsername, htpasswdPassword))
+
+	defer os.Remove(htpasswdPath)
+
+	ldapServer := newTestLDAPServer()
+	port = test.GetFreePort()
+
+	ldapPort, err := strconv.Atoi(port)
+	if err != nil {
+		panic(err)
+	}
+
+	ldapServer.Start(ldapPort)
+	defer ldapServer.Stop()
+
+	mockOIDCServer, err := authutils.MockOIDCRun()
+	if err != nil {
+		panic(err)
+	}
+
+	defer func() {
+		err := mockOIDCServer.Shutdown()
+		if err != nil {
+			panic(err)
+		}
+	}()
+
+	mockOIDCConfig := mockOIDCServer.Config()
+	conf.HTTP.Auth = &config.AuthConfig{
+		HTPasswd: config.AuthHTPasswd{
+			Path: htpasswdPath,
+		},
+		LDAP: (&config.LDAPConfig{
+			Insecure:      true,
+			Address:       LDAPAddress,
+			Port:          ldapPort,
+			BaseDN:        LDAPBaseDN,
+			UserAttribute: "uid",
+		}).SetBindDN(LDAPBindDN).SetBindPassword(LDAPBindPassword),
+		OpenID: &config.OpenIDConfig{
+			Providers: map[string]config.OpenIDProviderConfig{
+				"oidc": {
+					ClientID:     mockOIDCConfig.ClientID,
+					ClientSecret: mockOIDCConfig.ClientSecret,
+					KeyPath:      "",
+					Issuer:       mockOIDCConfig.Issuer,
+					Scopes:       []string{"openid", "email"},
+				},
+				// just for the constructor coverage
+				"github": {
+					ClientID:     mockOIDCConfig.ClientID,
+					ClientSecret: mockOIDCConfig.ClientSecret,
+					KeyPath:      "",
+					Issuer:       mockOIDCConfig.Issuer,
+					Scopes:       []string{"openid", "email"},
+				},
+			},
+		},
+	}
+
+	searchConfig := &extconf.SearchConfig{
+		BaseConfig: extconf.BaseConfig{Enable: &defaultVal},
+	}
+
+	// UI is enabled because we also want to test access on the mgmt route
+	uiConfig := &extconf.UIConfig{
+		BaseConfig: extconf.BaseConfig{Enable: &defaultVal},
+	}
+
+	conf.Extensions = &extconf.ExtensionConfig{
+		Search: searchConfig,
+		UI:     uiConfig,
+	}
+
+	ctlr := api.NewController(conf)
+	ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
+
+	for _, testcase := range testCases {
+		t.Run(testcase.testCaseName, func(t *testing.T) {
+			if testcase.useSessionKeys {
+				ctlr.Config.HTTP.Auth.SessionHashKey = []byte("3lrioGLGO2RfG9Y7HQGgWa3ayBjMLw2auMXqEWcSXjQKc9SoQ3fKTIbO+toPYa7e")
+				ctlr.Config.HTTP.Auth.SessionEncryptKey = []byte("KOzt01JrDz2uC//UBC5ZikxQw4owfmI8")
+			}
+
+			Convey("make controller", t, func() {
+				dir := t.TempDir()
+
+				ctlr.Config.Storage.RootDirectory = dir
+				ctlr.Config.HTTP.ExternalURL = testcase.externalURL
+				ctlr.Config.HTTP.Address = testcase.address
+				cm := test.NewControllerManager(ctlr)
+
+				cm.StartServer()
+
+				defer cm.StopServer()
+				test.WaitTillServerReady(baseURL)
+
+				Convey("browser client requests", func() {
+					Convey("login with no provider supplied", func() {
+						client := resty.New()
+						client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+						// first login user
+						resp, err := client.R().
+							SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+							SetQueryParam("provider", "unknown").
+							Get(baseURL + constants.LoginPath)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+					})
+
+					//nolint: dupl
+					Convey("make sure sessions are not used without UI header value", func() {
+						sessionsNo, err := getNumberOfSessions(conf.Storage.RootDirectory)
+						So(err, ShouldBeNil)
+						So(sessionsNo, ShouldEqual, 0)
+
+						client := resty.New()
+
+						// without header should not create session
+						resp, err := client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						sessionsNo, err = getNumberOfSessions(conf.Storage.RootDirectory)
+						So(err, ShouldBeNil)
+						So(sessionsNo, ShouldEqual, 0)
+
+						client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+
+						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						sessionsNo, err = getNumberOfSessions(conf.Storage.RootDirectory)
+						So(err, ShouldBeNil)
+						So(sessionsNo, ShouldEqual, 1)
+
+						// set cookies
+						client.SetCookies(resp.Cookies())
+
+						// should get same cookie
+						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						sessionsNo, err = getNumberOfSessions(conf.Storage.RootDirectory)
+						So(err, ShouldBeNil)
+						So(sessionsNo, ShouldEqual, 1)
+
+						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
+							Get(baseURL + constants.FullMgmt)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						client.SetCookies(resp.Cookies())
+
+						// call endpoint with session, without credentials, (added to client after previous request)
+						resp, err = client.R().
+							Get(baseURL + "/v2/_catalog")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						sessionsNo, err = getNumberOfSessions(conf.Storage.RootDirectory)
+						So(err, ShouldBeNil)
+						So(sessionsNo, ShouldEqual, 1)
+					})
+
+					Convey("login with openid and get catalog with session", func() {
+						client := resty.New()
+						client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+						client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+
+						Convey("with callback_ui value provided", func() {
+							// first login user
+							resp, err := client.R().
+								SetQueryParam("provider", "oidc").
+								SetQueryParam("callback_ui", baseURL+"/v2/").
+								Get(baseURL + constants.LoginPath)
+							So(err, ShouldBeNil)
+							So(resp, ShouldNotBeNil)
+							So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+						})
+
+						// first login user
+						resp, err := client.R().
+							SetQueryParam("provider", "oidc").
+							Get(baseURL + constants.LoginPath)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+						client.SetCookies(resp.Cookies())
+
+						// call endpoint with session (added to client after previous request)
+						resp, err = client.R().
+							Get(baseURL + "/v2/_catalog")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						// logout with options method for coverage
+						resp, err = client.R().
+							Options(baseURL + constants.LogoutPath)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+
+						// logout user
+						resp, err = client.R().
+							Post(baseURL + constants.LogoutPath)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						// calling endpoint should fail with unauthorized access
+						resp, err = client.R().
+							Get(baseURL + "/v2/_catalog")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+					})
+
+					//nolint: dupl
+					Convey("login with basic auth(htpasswd) and get catalog with session", func() {
+						client := resty.New()
+						client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+
+						// without creds, should get access error
+						resp, err := client.R().Get(baseURL + "/v2/")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+						var e apiErr.Error
+
+						err = json.Unmarshal(resp.Body(), &e)
+						So(err, ShouldBeNil)
+
+						// first login user
+						// with creds, should get expected status code
+						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).Get(baseURL + "/v2/")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						resp, err = client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
+							Get(baseURL + constants.FullMgmt)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						client.SetCookies(resp.Cookies())
+
+						// call endpoint with session, without credentials, (added to client after previous request)
+						resp, err = client.R().
+							Get(baseURL + "/v2/_catalog")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						resp, err = client.R().
+							Get(baseURL + constants.FullMgmt)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						// logout user
+						resp, err = client.R().
+							Post(baseURL + constants.LogoutPath)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						// calling endpoint should fail with unauthorized access
+						resp, err = client.R().
+							Get(baseURL + "/v2/_catalog")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+					})
+
+					//nolint: dupl
+					Convey("login with ldap and get catalog", func() {
+						client := resty.New()
+						client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+
+						// without creds, should get access error
+						resp, err := client.R().Get(baseURL + "/v2/")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+						var e apiErr.Error
+
+						err = json.Unmarshal(resp.Body(), &e)
+						So(err, ShouldBeNil)
+
+						// first login user
+						// with creds, should get expected status code
+						resp, err = client.R().SetBasicAuth(username, password).Get(baseURL)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						resp, err = client.R().SetBasicAuth(username, password).Get(baseURL + "/v2/")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						resp, err = client.R().SetBasicAuth(username, password).
+							Get(baseURL + constants.FullMgmt)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						client.SetCookies(resp.Cookies())
+
+						// call endpoint with session, without credentials, (added to client after previous request)
+						resp, err = client.R().
+							Get(baseURL + "/v2/_catalog")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						resp, err = client.R().
+							Get(baseURL + constants.FullMgmt)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						// logout user
+						resp, err = client.R().
+							Post(baseURL + constants.LogoutPath)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						// calling endpoint should fail with unauthorized access
+						resp, err = client.R().
+							Get(baseURL + "/v2/_catalog")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+					})
+
+					Convey("unauthenticated catalog request", func() {
+						client := resty.New()
+
+						// mgmt should work both unauthenticated and authenticated
+						resp, err := client.R().
+							Get(baseURL + constants.FullMgmt)
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+						// call endpoint without session
+						resp, err = client.R().
+							Get(baseURL + "/v2/_catalog")
+						So(err, ShouldBeNil)
+						So(resp, ShouldNotBeNil)
+						So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+					})
+				})
+			})
+		})
+	}
+}
+
+func TestIsOpenIDEnabled(t *testing.T) {
+	Convey("make oidc server", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+
+		mockOIDCServer, err := authutils.MockOIDCRun()
+		if err != nil {
+			panic(err)
+		}
+
+		defer func() {
+			err := mockOIDCServer.Shutdown()
+			if err != nil {
+				panic(err)
+			}
+		}()
+
+		rootDir := t.TempDir()
+
+		Convey("Only OAuth2 provided", func() {
+			mockOIDCConfig := mockOIDCServer.Config()
+			conf.HTTP.Auth = &config.AuthConfig{
+				OpenID: &config.OpenIDConfig{
+					Providers: map[string]config.OpenIDProviderConfig{
+						"github": {
+							ClientID:     mockOIDCConfig.ClientID,
+							ClientSecret: mockOIDCConfig.ClientSecret,
+							KeyPath:      "",
+							Issuer:       mockOIDCConfig.Issuer,
+							Scopes:       []string{"email", "groups"},
+						},
+					},
+				},
+			}
+
+			ctlr := api.NewController(conf)
+
+			ctlr.Config.Storage.RootDirectory = rootDir
+
+			cm := test.NewControllerManager(ctlr)
+
+			cm.StartServer()
+
+			defer cm.StopServer()
+			test.WaitTillServerReady(baseURL)
+
+			resp, err := resty.R().
+				Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("Unsupported provider", func() {
+			mockOIDCConfig := mockOIDCServer.Config()
+			conf.HTTP.Auth = &config.AuthConfig{
+				OpenID: &config.OpenIDConfig{
+					Providers: map[string]config.OpenIDProviderConfig{
+						"invalidProvider": {
+							ClientID:     mockOIDCConfig.ClientID,
+							ClientSecret: mockOIDCConfig.ClientSecret,
+							KeyPath:      "",
+							Issuer:       mockOIDCConfig.Issuer,
+							Scopes:       []string{"email", "groups"},
+						},
+					},
+				},
+			}
+
+			ctlr := api.NewController(conf)
+
+			ctlr.Config.Storage.RootDirectory = rootDir
+
+			cm := test.NewControllerManager(ctlr)
+
+			cm.StartServer()
+
+			defer cm.StopServer()
+			test.WaitTillServerReady(baseURL)
+
+			// it will work because we have an invalid provider, and no other authn enabled, so no authn enabled
+			// normally an invalid provider will exit with error in cli validations
+			resp, err := resty.R().
+				Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		})
+	})
+}
+
+func TestAuthnSessionErrors(t *testing.T) {
+	Convey("make controller", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+		defaultVal := true
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		invalidSessionID := "sessionID"
+
+		// need a username different than ldap one, to test both logic
+		htpasswdUsername, seedUser := test.GenerateRandomString()
+		htpasswdPassword, seedPass := test.GenerateRandomString()
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(htpasswdUsername, htpasswdPassword))
+
+		defer os.Remove(htpasswdPath)
+
+		ldapServer := newTestLDAPServer()
+		port = test.GetFreePort()
+
+		ldapPort, err := strconv.Atoi(port)
+		if err != nil {
+			panic(err)
+		}
+
+		ldapServer.Start(ldapPort)
+		defer ldapServer.Stop()
+
+		mockOIDCServer, err := authutils.MockOIDCRun()
+		if err != nil {
+			panic(err)
+		}
+
+		defer func() {
+			err := mockOIDCServer.Shutdown()
+			if err != nil {
+				panic(err)
+			}
+		}()
+
+		rootDir := t.TempDir()
+
+		mockOIDCConfig := mockOIDCServer.Config()
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{
+				Path: htpasswdPath,
+			},
+			LDAP: (&config.LDAPConfig{
+				Insecure:      true,
+				Address:       LDAPAddress,
+				Port:          ldapPort,
+				BaseDN:        LDAPBaseDN,
+				UserAttribute: "uid",
+			}).SetBindDN(LDAPBindDN).SetBindPassword(LDAPBindPassword),
+			OpenID: &config.OpenIDConfig{
+				Providers: map[string]config.OpenIDProviderConfig{
+					"oidc": {
+						ClientID:     mockOIDCConfig.ClientID,
+						ClientSecret: mockOIDCConfig.ClientSecret,
+						KeyPath:      "",
+						Issuer:       mockOIDCConfig.Issuer,
+						Scopes:       []string{"email", "groups"},
+					},
+				},
+			},
+		}
+
+		uiConfig := &extconf.UIConfig{
+			BaseConfig: extconf.BaseConfig{Enable: &defaultVal},
+		}
+
+		searchConfig := &extconf.SearchConfig{
+			BaseConfig: extconf.BaseConfig{Enable: &defaultVal},
+		}
+
+		conf.Extensions = &extconf.ExtensionConfig{
+			UI:     uiConfig,
+			Search: searchConfig,
+		}
+
+		ctlr := api.NewController(conf)
+		ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
+
+		ctlr.Config.Storage.RootDirectory = rootDir
+
+		cm := test.NewControllerManager(ctlr)
+
+		cm.StartServer()
+
+		defer cm.StopServer()
+		test.WaitTillServerReady(baseURL)
+
+		Convey("trigger basic authn middle(htpasswd) error", func() {
+			client := resty.New()
+
+			ctlr.MetaDB = mocks.MetaDBMock{
+				SetUserGroupsFn: func(ctx context.Context, groups []string) error {
+					return ErrUnexpectedError
+				},
+			}
+
+			resp, err := client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
+				Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("trigger basic authn middle(ldap) error", func() {
+			client := resty.New()
+
+			ctlr.MetaDB = mocks.MetaDBMock{
+				SetUserGroupsFn: func(ctx context.Context, groups []string) error {
+					return ErrUnexpectedError
+				},
+			}
+
+			resp, err := client.R().SetBasicAuth(username, password).
+				Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("trigger updateUserData error", func() {
+			client := resty.New()
+			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+
+			ctlr.MetaDB = mocks.MetaDBMock{
+				SetUserGroupsFn: func(ctx context.Context, groups []string) error {
+					return ErrUnexpectedError
+				},
+			}
+
+			resp, err := client.R().
+				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+				SetQueryParam("provider", "oidc").
+				Get(baseURL + constants.LoginPath)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("trigger session middle metaDB errors", func() {
+			client := resty.New()
+			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+
+			user := mockoidc.DefaultUser()
+			user.Groups = []string{"group1", "group2"}
+
+			mockOIDCServer.QueueUser(user)
+
+			ctlr.MetaDB = mocks.MetaDBMock{}
+
+			// first login user
+			resp, err := client.R().
+				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+				SetQueryParam("provider", "oidc").
+				Get(baseURL + constants.LoginPath)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			Convey("trigger session middle error internal server error", func() {
+				cookies := resp.Cookies()
+
+				client.SetCookies(cookies)
+
+				ctlr.MetaDB = mocks.MetaDBMock{
+					GetUserGroupsFn: func(ctx context.Context) ([]string, error) {
+						return []string{}, ErrUnexpectedError
+					},
+				}
+
+				// call endpoint with session (added to client after previous request)
+				resp, err = client.R().
+					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+					Get(baseURL + "/v2/_catalog")
+				So(err, ShouldBeNil)
+				So(resp, ShouldNotBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+			})
+
+			Convey("trigger session middle error GetUserGroups not found", func() {
+				cookies := resp.Cookies()
+
+				client.SetCookies(cookies)
+
+				ctlr.MetaDB = mocks.MetaDBMock{
+					GetUserGroupsFn: func(ctx context.Context) ([]string, error) {
+						return []string{}, errors.ErrUserDataNotFound
+					},
+				}
+
+				// call endpoint with session (added to client after previous request)
+				resp, err = client.R().
+					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+					Get(baseURL + "/v2/_catalog")
+				So(err, ShouldBeNil)
+				So(resp, ShouldNotBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+
+		Convey("trigger no email error in routes(callback)", func() {
+			user := mockoidc.DefaultUser()
+			user.Email = ""
+
+			mockOIDCServer.QueueUser(user)
+
+			client := resty.New()
+			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+
+			client.SetCookie(&http.Cookie{Name: "session"})
+
+			// call endpoint with session (added to client after previous request)
+			resp, err := client.R().
+				SetQueryParam("provider", "oidc").
+				Get(baseURL + constants.LoginPath)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		})
+
+		Convey("trigger session save error in routes(callback)", func() {
+			err := os.Chmod(rootDir, 0o000)
+			So(err, ShouldBeNil)
+
+			defer func() {
+				err := os.Chmod(rootDir, storageConstants.DefaultDirPerms)
+				So(err, ShouldBeNil)
+			}()
+
+			client := resty.New()
+			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+
+			// first login user
+			resp, err := client.R().
+				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+				SetQueryParam("provider", "oidc").
+				Get(baseURL + constants.LoginPath)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("trigger session save error in basicAuthn", func() {
+			err := os.Chmod(rootDir, 0o000)
+			So(err, ShouldBeNil)
+
+			defer func() {
+				err := os.Chmod(rootDir, storageConstants.DefaultDirPerms)
+				So(err, ShouldBeNil)
+			}()
+
+			client := resty.New()
+			client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+
+			// first htpasswd saveSessionLoggedUser() error
+			resp, err := client.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
+				Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+
+			// second ldap saveSessionLoggedUser() error
+			resp, err = client.R().SetBasicAuth(username, password).
+				Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("trigger session middle errors", func() {
+			client := resty.New()
+			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+
+			user := mockoidc.DefaultUser()
+			user.Groups = []string{"group1", "group2"}
+
+			mockOIDCServer.QueueUser(user)
+
+			// first login user
+			resp, err := client.R().
+				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+				SetQueryParam("provider", "oidc").
+				Get(baseURL + constants.LoginPath)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			Convey("trigger bad session encoding error in authn", func() {
+				cookies := resp.Cookies()
+				for _, cookie := range cookies {
+					if cookie.Name == "session" {
+						cookie.Value = "badSessionValue"
+					}
+				}
+
+				client.SetCookies(cookies)
+
+				// call endpoint with session (added to client after previous request)
+				resp, err = client.R().
+					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+					Get(baseURL + "/v2/_catalog")
+				So(err, ShouldBeNil)
+				So(resp, ShouldNotBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			})
+
+			Convey("web request without cookies", func() {
+				client.SetCookie(&http.Cookie{})
+
+				// call endpoint with session (added to client after previous request)
+				resp, err = client.R().
+					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+					Get(baseURL + "/v2/_catalog")
+				So(err, ShouldBeNil)
+				So(resp, ShouldNotBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			})
+
+			Convey("web request with userless cookie", func() {
+				// first get session
+				session, err := ctlr.CookieStore.Get(resp.RawResponse.Request, "session")
+				So(err, ShouldBeNil)
+
+				session.ID = invalidSessionID
+				session.IsNew = false
+				session.Values["authStatus"] = true
+
+				cookieStore, ok := ctlr.CookieStore.Store.(*sessions.FilesystemStore)
+				So(ok, ShouldBeTrue)
+
+				// first encode sessionID
+				encoded, err := securecookie.EncodeMulti(session.Name(), session.ID,
+					cookieStore.Codecs...)
+				So(err, ShouldBeNil)
+
+				// save cookie
+				cookie := sessions.NewCookie(session.Name(), encoded, session.Options)
+				client.SetCookie(cookie)
+
+				// encode session values and save on disk
+				encoded, err = securecookie.EncodeMulti(session.Name(), session.Values,
+					cookieStore.Codecs...)
+				So(err, ShouldBeNil)
+
+				filename := filepath.Join(rootDir, "_sessions", "session_"+session.ID)
+
+				err = os.WriteFile(filename, []byte(encoded), 0o600)
+				So(err, ShouldBeNil)
+
+				// call endpoint with session (added to client after previous request)
+				resp, err = client.R().
+					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+					Get(baseURL + "/v2/_catalog")
+				So(err, ShouldBeNil)
+				So(resp, ShouldNotBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			})
+
+			Convey("web request with authStatus false cookie", func() {
+				// first get session
+				session, err := ctlr.CookieStore.Get(resp.RawResponse.Request, "session")
+				So(err, ShouldBeNil)
+
+				session.ID = invalidSessionID
+				session.IsNew = false
+				session.Values["authStatus"] = false
+				session.Values["test.Username"] = username
+
+				cookieStore, ok := ctlr.CookieStore.Store.(*sessions.FilesystemStore)
+				So(ok, ShouldBeTrue)
+
+				// first encode sessionID
+				encoded, err := securecookie.EncodeMulti(session.Name(), session.ID,
+					cookieStore.Codecs...)
+				So(err, ShouldBeNil)
+
+				// save cookie
+				cookie := sessions.NewCookie(session.Name(), encoded, session.Options)
+				client.SetCookie(cookie)
+
+				// encode session values and save on disk
+				encoded, err = securecookie.EncodeMulti(session.Name(), session.Values,
+					cookieStore.Codecs...)
+				So(err, ShouldBeNil)
+
+				filename := filepath.Join(rootDir, "_sessions", "session_"+session.ID)
+
+				err = os.WriteFile(filename, []byte(encoded), 0o600)
+				So(err, ShouldBeNil)
+
+				// call endpoint with session (added to client after previous request)
+				resp, err = client.R().
+					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+					Get(baseURL + "/v2/_catalog")
+				So(err, ShouldBeNil)
+				So(resp, ShouldNotBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			})
+		})
+	})
+}
+
+func TestAuthnMetaDBErrors(t *testing.T) {
+	Convey("make controller", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+		conf := config.New()
+		conf.HTTP.Port = port
+
+		username, seedUser := test.GenerateRandomString()
+		password, seedPass := test.GenerateRandomString()
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(username, password))
+
+		defer os.Remove(htpasswdPath)
+
+		mockOIDCServer, err := authutils.MockOIDCRun()
+		if err != nil {
+			panic(err)
+		}
+
+		defer func() {
+			err := mockOIDCServer.Shutdown()
+			if err != nil {
+				panic(err)
+			}
+		}()
+
+		rootDir := t.TempDir()
+
+		mockOIDCConfig := mockOIDCServer.Config()
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{
+				Path: htpasswdPath,
+			},
+			OpenID: &config.OpenIDConfig{
+				Providers: map[string]config.OpenIDProviderConfig{
+					"oidc": {
+						ClientID:     mockOIDCConfig.ClientID,
+						ClientSecret: mockOIDCConfig.ClientSecret,
+						KeyPath:      "",
+						Issuer:       mockOIDCConfig.Issuer,
+						Scopes:       []string{"openid", "email"},
+					},
+				},
+			},
+		}
+
+		ctlr := api.NewController(conf)
+		ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
+
+		ctlr.Config.Storage.RootDirectory = rootDir
+
+		cm := test.NewControllerManager(ctlr)
+
+		cm.StartServer()
+
+		defer cm.StopServer()
+		test.WaitTillServerReady(baseURL)
+
+		Convey("trigger basic authn middle(htpasswd) error", func() {
+			client := resty.New()
+
+			ctlr.MetaDB = mocks.MetaDBMock{
+				SetUserGroupsFn: func(ctx context.Context, groups []string) error {
+					return ErrUnexpectedError
+				},
+			}
+
+			resp, err := client.R().SetBasicAuth(username, password).
+				Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+		})
+
+		Convey("trigger session middle metaDB errors", func() {
+			client := resty.New()
+			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+
+			user := mockoidc.DefaultUser()
+			user.Groups = []string{"group1", "group2"}
+
+			mockOIDCServer.QueueUser(user)
+
+			// first login user
+			resp, err := client.R().
+				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+				SetQueryParam("provider", "oidc").
+				Get(baseURL + constants.LoginPath)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			Convey("trigger session middle error", func() {
+				cookies := resp.Cookies()
+
+				client.SetCookies(cookies)
+
+				ctlr.MetaDB = mocks.MetaDBMock{
+					GetUserGroupsFn: func(ctx context.Context) ([]string, error) {
+						return []string{}, ErrUnexpectedError
+					},
+				}
+
+				// call endpoint with session (added to client after previous request)
+				resp, err = client.R().
+					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+					Get(baseURL + "/v2/_catalog")
+				So(err, ShouldBeNil)
+				So(resp, ShouldNotBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
+			})
+		})
+	})
+}
+
+func TestAuthorization(t *testing.T) {
+	Convey("Make a new controller", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+		conf := config.New()
+		conf.HTTP.Port = port
+		username, seedUser := test.GenerateRandomString()
+		password, seedPass := test.GenerateRandomString()
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(username, password))
+
+		defer os.Remove(htpasswdPath)
+
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{
+				Path: htpasswdPath,
+			},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users:   []string{},
+							Actions: []string{},
+						},
+					},
+					DefaultPolicy: []string{},
+				},
+			},
+			AdminPolicy: config.Policy{
+				Users:   []string{},
+				Actions: []string{},
+			},
+		}
+
+		Convey("with openid", func() {
+			mockOIDCServer, err := authutils.MockOIDCRun()
+			if err != nil {
+				panic(err)
+			}
+
+			defer func() {
+				err := mockOIDCServer.Shutdown()
+				if err != nil {
+					panic(err)
+				}
+			}()
+
+			mockOIDCConfig := mockOIDCServer.Config()
+			conf.HTTP.Auth = &config.AuthConfig{
+				OpenID: &config.OpenIDConfig{
+					Providers: map[string]config.OpenIDProviderConfig{
+						"oidc": {
+							ClientID:     mockOIDCConfig.ClientID,
+							ClientSecret: mockOIDCConfig.ClientSecret,
+							KeyPath:      "",
+							Issuer:       mockOIDCConfig.Issuer,
+							Scopes:       []string{"openid", "email"},
+						},
+					},
+				},
+			}
+
+			ctlr := api.NewController(conf)
+			ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
+			ctlr.Config.Storage.RootDirectory = t.TempDir()
+
+			err = WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "0.0.1",
+				ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
+			So(err, ShouldBeNil)
+
+			cm := test.NewControllerManager(ctlr)
+			cm.StartAndWait(port)
+
+			defer cm.StopServer()
 
-		Convey("trigger session middle errors", func() {
 			client := resty.New()
+
 			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
 
-			user := mockoidc.DefaultUser()
-			user.Groups = []string{"group1", "group2"}
+			mockOIDCServer.QueueUser(&mockoidc.MockUser{
+				Email:   username,
+				Subject: "1234567890",
+			})
+
+			// first login user
+			resp, err := client.R().
+				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+				SetQueryParam("provider", "oidc").
+				Get(baseURL + constants.LoginPath)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			client.SetCookies(resp.Cookies())
+			client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+
+			RunAuthorizationTests(t, client, baseURL, username, conf)
+		})
+
+		Convey("with basic auth", func() {
+			ctlr := api.NewController(conf)
+			ctlr.Config.Storage.RootDirectory = t.TempDir()
+
+			err := WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "0.0.1",
+				ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
+			So(err, ShouldBeNil)
+
+			cm := test.NewControllerManager(ctlr)
+			cm.StartAndWait(port)
+
+			defer cm.StopServer()
+
+			client := resty.New()
+			client.SetBasicAuth(username, password)
+
+			RunAuthorizationTests(t, client, baseURL, username, conf)
+		})
+	})
+}
+
+func TestGetUsername(t *testing.T) {
+	Convey("Make a new controller", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		username, seedUser := test.GenerateRandomString()
+		password, seedPass := test.GenerateRandomString()
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(username, password))
+
+		defer os.Remove(htpasswdPath)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{
+				Path: htpasswdPath,
+			},
+		}
+
+		dir := t.TempDir()
+		ctlr := makeController(conf, dir)
+		ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		resp, err := resty.R().Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		// test base64 encode
+		resp, err = resty.R().SetHeader("Authorization", "Basic should fail").Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		// test "username:password" encoding
+		resp, err = resty.R().SetHeader("Authorization", "Basic dGVzdA==").Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		// failed parsing authorization header
+		resp, err = resty.R().SetHeader("Authorization", "Basic ").Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		var e apiErr.Error
+
+		err = json.Unmarshal(resp.Body(), &e)
+		So(err, ShouldBeNil)
+
+		resp, err = resty.R().SetBasicAuth(username, password).Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+	})
+}
+
+func TestAuthorizationMountBlob(t *testing.T) {
+	Convey("Make a new controller", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		// have two users: one for  user Policy, and another for default policy
+		username1, _ := test.GenerateRandomString()
+		password1, _ := test.GenerateRandomString()
+		username2, _ := test.GenerateRandomString()
+		password2, _ := test.GenerateRandomString()
+		username1 = strings.ToLower(username1)
+		username2 = strings.ToLower(username2)
+
+		content := test.GetCredString(username1, password1) + test.GetCredString(username2, password2)
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(content)
+
+		defer os.Remove(htpasswdPath)
+
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{
+				Path: htpasswdPath,
+			},
+		}
+
+		user1Repo := username1 + "/**"
+		user2Repo := username2 + "/**"
+
+		// config with all policy types, to test that the correct one is applied in each case
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				user1Repo: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users: []string{username1},
+							Actions: []string{
+								constants.ReadPermission,
+								constants.CreatePermission,
+							},
+						},
+					},
+				},
+				user2Repo: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users: []string{username2},
+							Actions: []string{
+								constants.ReadPermission,
+								constants.CreatePermission,
+							},
+						},
+					},
+				},
+			},
+		}
+
+		dir := t.TempDir()
+
+		ctlr := api.NewController(conf)
+		ctlr.Config.Storage.RootDirectory = dir
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		userClient1 := resty.New()
+		userClient1.SetBasicAuth(username1, password1)
+
+		userClient2 := resty.New()
+		userClient2.SetBasicAuth(username2, password2)
+
+		img := CreateImageWith().RandomLayers(1, 2).DefaultConfig().Build()
+
+		repoName1 := username1 + "/" + "myrepo"
+		tag := "1.0"
+
+		// upload image with user1 on repoName1
+		err := UploadImageWithBasicAuth(img, baseURL, repoName1, tag, username1, password1)
+		So(err, ShouldBeNil)
+
+		repoName2 := username2 + "/" + "myrepo"
+
+		blobDigest := img.Manifest.Layers[0].Digest
+
+		/* a HEAD request by user2 on blob digest (found in user1Repo) should return 404
+		because user2 doesn't have permissions to read user1Repo */
+		resp, err := userClient2.R().Head(baseURL + fmt.Sprintf("/v2/%s/blobs/%s", repoName2, blobDigest))
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+		params := make(map[string]string)
+		params["mount"] = blobDigest.String()
+
+		// trying to mount a blob which can be found in cache, but user doesn't have permission
+		// should return 202 instead of 201
+		resp, err = userClient2.R().SetQueryParams(params).Post(baseURL + "/v2/" + repoName2 + "/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+
+		// same when naming the source repository explicitly
+		resp, err = userClient2.R().SetQueryParams(params).SetQueryParam("from", repoName1).
+			Post(baseURL + "/v2/" + repoName2 + "/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+
+		// user1 can mount from its own repository
+		resp, err = userClient1.R().SetQueryParams(params).SetQueryParam("from", repoName1).
+			Post(baseURL + "/v2/" + username1 + "/mythirdrepo/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		/* a HEAD request by user1 on blob digest (found in user1Repo) should return 200
+		because user1 has permission to read user1Repo */
+		resp, err = userClient1.R().Head(baseURL + fmt.Sprintf("/v2/%s/blobs/%s", username1+"/"+"mysecondrepo", blobDigest))
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		// user2 can upload without dedupe
+		err = UploadImageWithBasicAuth(img, baseURL, repoName2, tag, username2, password2)
+		So(err, ShouldBeNil)
+
+		// trying to mount a blob which can be found in cache and user has permission should return 201 instead of 202
+		resp, err = userClient2.R().SetQueryParams(params).Post(baseURL + "/v2/" + repoName2 + "/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+	})
+}
+
+func TestAuthorizationWithOnlyAnonymousPolicy(t *testing.T) {
+	Convey("Make a new controller", t, func() {
+		const TestRepo = "my-repos/repo"
+
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				TestRepo: config.PolicyGroup{
+					AnonymousPolicy: []string{"read"},
+				},
+			},
+		}
+
+		dir := t.TempDir()
+		ctlr := makeController(conf, dir)
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		blob := []byte("hello, blob!")
+		digest := godigest.FromBytes(blob).String()
+
+		resp, err := resty.R().Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		var e apiErr.Error
+
+		err = json.Unmarshal(resp.Body(), &e)
+		So(err, ShouldBeNil)
+
+		// should get 401 without create
+		resp, err = resty.R().Post(baseURL + "/v2/" + TestRepo + "/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		if entry, ok := conf.HTTP.AccessControl.Repositories[TestRepo]; ok {
+			entry.AnonymousPolicy = []string{"create", "read"}
+			conf.HTTP.AccessControl.Repositories[TestRepo] = entry
+		}
+
+		// now it should get 202
+		resp, err = resty.R().Post(baseURL + "/v2/" + TestRepo + "/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+		loc := resp.Header().Get("Location")
+
+		// uploading blob should get 201
+		resp, err = resty.R().SetHeader("Content-Length", strconv.Itoa(len(blob))).
+			SetHeader("Content-Type", "application/octet-stream").
+			SetQueryParam("digest", digest).
+			SetBody(blob).
+			Put(baseURL + loc)
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		cblob, cdigest := GetRandomImageConfig()
+
+		resp, err = resty.R().Post(baseURL + "/v2/" + TestRepo + "/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+		loc = test.Location(baseURL, resp)
+
+		// uploading blob should get 201
+		resp, err = resty.R().SetHeader("Content-Length", strconv.Itoa(len(cblob))).
+			SetHeader("Content-Type", "application/octet-stream").
+			SetQueryParam("digest", cdigest.String()).
+			SetBody(cblob).
+			Put(loc)
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		manifest := ispec.Manifest{
+			Config: ispec.Descriptor{
+				MediaType: "application/vnd.oci.image.config.v1+json",
+				Digest:    cdigest,
+				Size:      int64(len(cblob)),
+			},
+			Layers: []ispec.Descriptor{
+				{
+					MediaType: "application/vnd.oci.image.layer.v1.tar",
+					Digest:    godigest.FromBytes(blob),
+					Size:      int64(len(blob)),
+				},
+			},
+		}
+		manifest.SchemaVersion = 2
+		manifestBlob, err := json.Marshal(manifest)
+		So(err, ShouldBeNil)
+
+		resp, err = resty.R().
+			SetHeader("Content-type", "application/vnd.oci.image.manifest.v1+json").
+			SetBody(manifestBlob).
+			Put(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		updateBlob := []byte("Hello, blob update!")
+
+		resp, err = resty.R().
+			Post(baseURL + "/v2/" + TestRepo + "/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+		loc = test.Location(baseURL, resp)
+		// uploading blob should get 201
+		resp, err = resty.R().
+			SetHeader("Content-Length", strconv.Itoa(len(updateBlob))).
+			SetHeader("Content-Type", "application/octet-stream").
+			SetQueryParam("digest", string(godigest.FromBytes(updateBlob))).
+			SetBody(updateBlob).
+			Put(loc)
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		updatedManifest := ispec.Manifest{
+			Config: ispec.Descriptor{
+				MediaType: "application/vnd.oci.image.config.v1+json",
+				Digest:    cdigest,
+				Size:      int64(len(cblob)),
+			},
+			Layers: []ispec.Descriptor{
+				{
+					MediaType: "application/vnd.oci.image.layer.v1.tar",
+					Digest:    godigest.FromBytes(updateBlob),
+					Size:      int64(len(updateBlob)),
+				},
+			},
+		}
+		updatedManifest.SchemaVersion = 2
+		updatedManifestBlob, err := json.Marshal(updatedManifest)
+		So(err, ShouldBeNil)
+
+		// update manifest should get 401 without update perm
+		resp, err = resty.R().SetBody(updatedManifestBlob).
+			Put(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		// get the manifest and check if it's the old one
+		resp, err = resty.R().
+			Get(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(resp.Body(), ShouldResemble, manifestBlob)
+
+		// add update perm on repo
+		if entry, ok := conf.HTTP.AccessControl.Repositories[TestRepo]; ok {
+			entry.AnonymousPolicy = []string{"create", "read", "update"}
+			conf.HTTP.AccessControl.Repositories[TestRepo] = entry
+		}
+
+		// update manifest should get 201 with update perm
+		resp, err = resty.R().
+			SetHeader("Content-type", "application/vnd.oci.image.manifest.v1+json").
+			SetBody(updatedManifestBlob).
+			Put(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+		// get the manifest and check if it's the new updated one
+		resp, err = resty.R().
+			Get(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(resp.Body(), ShouldResemble, updatedManifestBlob)
+
+		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		// make sure anonymous is correctly handled when using acCtx (requestcontext package)
+		catalog := struct {
+			Repositories []string `json:"repositories"`
+		}{}
 
-			mockOIDCServer.QueueUser(user)
+		err = json.Unmarshal(resp.Body(), &catalog)
+		So(err, ShouldBeNil)
+		So(len(catalog.Repositories), ShouldEqual, 1)
+		So(catalog.Repositories, ShouldContain, TestRepo)
 
-			// first login user
-			resp, err := client.R().
-				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-				SetQueryParam("provider", "oidc").
-				Get(baseURL + constants.LoginPath)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+		err = os.Mkdir(path.Join(dir, "zot-test"), storageConstants.DefaultDirPerms)
+		So(err, ShouldBeNil)
 
-			Convey("trigger bad session encoding error in authn", func() {
-				cookies := resp.Cookies()
-				for _, cookie := range cookies {
-					if cookie.Name == "session" {
-						cookie.Value = "badSessionValue"
-					}
-				}
+		err = WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "tag", ctlr.StoreController)
+		So(err, ShouldBeNil)
 
-				client.SetCookies(cookies)
+		// should not have read rights on zot-test
+		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-				// call endpoint with session (added to client after previous request)
-				resp, err = client.R().
-					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-					Get(baseURL + "/v2/_catalog")
-				So(err, ShouldBeNil)
-				So(resp, ShouldNotBeNil)
-				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-			})
+		err = json.Unmarshal(resp.Body(), &catalog)
+		So(err, ShouldBeNil)
+		So(len(catalog.Repositories), ShouldEqual, 1)
+		So(catalog.Repositories, ShouldContain, TestRepo)
 
-			Convey("web request without cookies", func() {
-				client.SetCookie(&http.Cookie{})
+		// add rights
+		conf.HTTP.AccessControl.Repositories["zot-test"] = config.PolicyGroup{
+			AnonymousPolicy: []string{"read"},
+		}
 
-				// call endpoint with session (added to client after previous request)
-				resp, err = client.R().
-					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-					Get(baseURL + "/v2/_catalog")
-				So(err, ShouldBeNil)
-				So(resp, ShouldNotBeNil)
-				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-			})
+		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
 
-			Convey("web request with userless cookie", func() {
-				// first get session
-				session, err := ctlr.CookieStore.Get(resp.RawResponse.Request, "session")
-				So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-				session.ID = invalidSessionID
-				session.IsNew = false
-				session.Values["authStatus"] = true
+		err = json.Unmarshal(resp.Body(), &catalog)
+		So(err, ShouldBeNil)
 
-				cookieStore, ok := ctlr.CookieStore.Store.(*sessions.FilesystemStore)
-				So(ok, ShouldBeTrue)
+		So(len(catalog.Repositories), ShouldEqual, 2)
+		So(catalog.Repositories, ShouldContain, TestRepo)
+		So(catalog.Repositories, ShouldContain, "zot-test")
+	})
+}
 
-				// first encode sessionID
-				encoded, err := securecookie.EncodeMulti(session.Name(), session.ID,
-					cookieStore.Codecs...)
-				So(err, ShouldBeNil)
+func TestAuthorizationWithAnonymousPolicyBasicAuthAndSessionHeader(t *testing.T) {
+	Convey("Make a new controller", t, func() {
+		const TestRepo = "my-repos/repo"
 
-				// save cookie
-				cookie := sessions.NewCookie(session.Name(), encoded, session.Options)
-				client.SetCookie(cookie)
+		const AllRepos = "**"
 
-				// encode session values and save on disk
-				encoded, err = securecookie.EncodeMulti(session.Name(), session.Values,
-					cookieStore.Codecs...)
-				So(err, ShouldBeNil)
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
 
-				filename := filepath.Join(rootDir, "_sessions", "session_"+session.ID)
+		badpassphrase := "bad"
+		htpasswdUsername, seedUser := test.GenerateRandomString()
+		htpasswdPassword, seedPass := test.GenerateRandomString()
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(htpasswdUsername, htpasswdPassword))
 
-				err = os.WriteFile(filename, []byte(encoded), 0o600)
-				So(err, ShouldBeNil)
+		defer os.Remove(htpasswdPath)
 
-				// call endpoint with session (added to client after previous request)
-				resp, err = client.R().
-					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-					Get(baseURL + "/v2/_catalog")
-				So(err, ShouldBeNil)
-				So(resp, ShouldNotBeNil)
-				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-			})
+		img := CreateRandomImage()
+		tagAnonymous := "1.0-anon"
+		tagAuth := "1.0-auth"
+		tagUnauth := "1.0-unauth"
 
-			Convey("web request with authStatus false cookie", func() {
-				// first get session
-				session, err := ctlr.CookieStore.Get(resp.RawResponse.Request, "session")
-				So(err, ShouldBeNil)
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{
+				Path: htpasswdPath,
+			},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				AllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users:   []string{htpasswdUsername},
+							Actions: []string{"read"},
+						},
+					},
+					AnonymousPolicy: []string{"read"},
+				},
+			},
+		}
 
-				session.ID = invalidSessionID
-				session.IsNew = false
-				session.Values["authStatus"] = false
-				session.Values["test.Username"] = username
+		dir := t.TempDir()
+		ctlr := makeController(conf, dir)
+		ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-				cookieStore, ok := ctlr.CookieStore.Store.(*sessions.FilesystemStore)
-				So(ok, ShouldBeTrue)
+		defer cm.StopServer()
 
-				// first encode sessionID
-				encoded, err := securecookie.EncodeMulti(session.Name(), session.ID,
-					cookieStore.Codecs...)
-				So(err, ShouldBeNil)
+		// /v2 access
+		// Can access /v2 without credentials
+		resp, err := resty.R().Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-				// save cookie
-				cookie := sessions.NewCookie(session.Name(), encoded, session.Options)
-				client.SetCookie(cookie)
+		// Can access /v2 without credentials and with X-Zot-Api-Client=zot-ui
+		resp, err = resty.R().
+			SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+			Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-				// encode session values and save on disk
-				encoded, err = securecookie.EncodeMulti(session.Name(), session.Values,
-					cookieStore.Codecs...)
-				So(err, ShouldBeNil)
+		// Can access /v2 with correct credentials
+		resp, err = resty.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
+			Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-				filename := filepath.Join(rootDir, "_sessions", "session_"+session.ID)
+		// Fail to access /v2 with incorrect credentials
+		resp, err = resty.R().SetBasicAuth(htpasswdUsername, badpassphrase).
+			Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-				err = os.WriteFile(filename, []byte(encoded), 0o600)
-				So(err, ShouldBeNil)
+		// Catalog access
+		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-				// call endpoint with session (added to client after previous request)
-				resp, err = client.R().
-					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-					Get(baseURL + "/v2/_catalog")
-				So(err, ShouldBeNil)
-				So(resp, ShouldNotBeNil)
-				So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
-			})
-		})
-	})
-}
+		var apiError apiErr.Error
 
-func TestAuthnMetaDBErrors(t *testing.T) {
-	Convey("make controller", t, func() {
-		port := test.GetFreePort()
-		baseURL := test.GetBaseURL(port)
-		conf := config.New()
-		conf.HTTP.Port = port
+		err = json.Unmarshal(resp.Body(), &apiError)
+		So(err, ShouldBeNil)
 
-		username, seedUser := test.GenerateRandomString()
-		password, seedPass := test.GenerateRandomString()
+		resp, err = resty.R().
+			SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+			Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(username, password))
+		apiError = apiErr.Error{}
+		err = json.Unmarshal(resp.Body(), &apiError)
+		So(err, ShouldBeNil)
 
-		defer os.Remove(htpasswdPath)
+		resp, err = resty.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
+			Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		mockOIDCServer, err := authutils.MockOIDCRun()
-		if err != nil {
-			panic(err)
-		}
+		apiError = apiErr.Error{}
+		err = json.Unmarshal(resp.Body(), &apiError)
+		So(err, ShouldBeNil)
 
-		defer func() {
-			err := mockOIDCServer.Shutdown()
-			if err != nil {
-				panic(err)
-			}
-		}()
+		resp, err = resty.R().
+			SetBasicAuth(htpasswdUsername, badpassphrase).
+			Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-		rootDir := t.TempDir()
+		apiError = apiErr.Error{}
+		err = json.Unmarshal(resp.Body(), &apiError)
+		So(err, ShouldBeNil)
 
-		mockOIDCConfig := mockOIDCServer.Config()
-		conf.HTTP.Auth = &config.AuthConfig{
-			HTPasswd: config.AuthHTPasswd{
-				Path: htpasswdPath,
-			},
-			OpenID: &config.OpenIDConfig{
-				Providers: map[string]config.OpenIDProviderConfig{
-					"oidc": {
-						ClientID:     mockOIDCConfig.ClientID,
-						ClientSecret: mockOIDCConfig.ClientSecret,
-						KeyPath:      "",
-						Issuer:       mockOIDCConfig.Issuer,
-						Scopes:       []string{"openid", "email"},
-					},
-				},
-			},
-		}
+		// upload capability
+		// should get 403 without create
+		err = UploadImage(img, baseURL, TestRepo, tagAnonymous)
+		So(err, ShouldNotBeNil)
 
-		ctlr := api.NewController(conf)
-		ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
+		err = UploadImageWithBasicAuth(img, baseURL,
+			TestRepo, tagAuth, htpasswdUsername, htpasswdPassword)
+		So(err, ShouldNotBeNil)
 
-		ctlr.Config.Storage.RootDirectory = rootDir
+		err = UploadImageWithBasicAuth(img, baseURL,
+			TestRepo, tagUnauth, htpasswdUsername, badpassphrase)
+		So(err, ShouldNotBeNil)
 
-		cm := test.NewControllerManager(ctlr)
+		if entry, ok := conf.HTTP.AccessControl.Repositories[AllRepos]; ok {
+			entry.AnonymousPolicy = []string{"create", "read"}
+			entry.Policies[0] = config.Policy{
+				Users:   []string{htpasswdUsername},
+				Actions: []string{"create", "read"},
+			}
+			conf.HTTP.AccessControl.Repositories[AllRepos] = entry
+		}
 
-		cm.StartServer()
+		// now it should succeed for valid users
+		err = UploadImage(img, baseURL, TestRepo, tagAnonymous)
+		So(err, ShouldBeNil)
 
-		defer cm.StopServer()
-		test.WaitTillServerReady(baseURL)
+		err = UploadImageWithBasicAuth(img, baseURL,
+			TestRepo, tagAuth, htpasswdUsername, htpasswdPassword)
+		So(err, ShouldBeNil)
 
-		Convey("trigger basic authn middle(htpasswd) error", func() {
-			client := resty.New()
+		err = UploadImageWithBasicAuth(img, baseURL,
+			TestRepo, tagUnauth, htpasswdUsername, badpassphrase)
+		So(err, ShouldNotBeNil)
 
-			ctlr.MetaDB = mocks.MetaDBMock{
-				SetUserGroupsFn: func(ctx context.Context, groups []string) error {
-					return ErrUnexpectedError
-				},
-			}
+		// read capability
+		catalog := struct {
+			Repositories []string `json:"repositories"`
+		}{}
 
-			resp, err := client.R().SetBasicAuth(username, password).
-				Get(baseURL + "/v2/_catalog")
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
-		})
+		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		Convey("trigger session middle metaDB errors", func() {
-			client := resty.New()
-			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+		err = json.Unmarshal(resp.Body(), &catalog)
+		So(err, ShouldBeNil)
+		So(len(catalog.Repositories), ShouldEqual, 1)
+		So(catalog.Repositories, ShouldContain, TestRepo)
 
-			user := mockoidc.DefaultUser()
-			user.Groups = []string{"group1", "group2"}
+		catalog = struct {
+			Repositories []string `json:"repositories"`
+		}{}
 
-			mockOIDCServer.QueueUser(user)
+		resp, err = resty.R().
+			SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+			Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			// first login user
-			resp, err := client.R().
-				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-				SetQueryParam("provider", "oidc").
-				Get(baseURL + constants.LoginPath)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+		err = json.Unmarshal(resp.Body(), &catalog)
+		So(err, ShouldBeNil)
+		So(len(catalog.Repositories), ShouldEqual, 1)
+		So(catalog.Repositories, ShouldContain, TestRepo)
 
-			Convey("trigger session middle error", func() {
-				cookies := resp.Cookies()
+		catalog = struct {
+			Repositories []string `json:"repositories"`
+		}{}
 
-				client.SetCookies(cookies)
+		resp, err = resty.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
+			Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-				ctlr.MetaDB = mocks.MetaDBMock{
-					GetUserGroupsFn: func(ctx context.Context) ([]string, error) {
-						return []string{}, ErrUnexpectedError
-					},
-				}
+		err = json.Unmarshal(resp.Body(), &catalog)
+		So(err, ShouldBeNil)
+		So(len(catalog.Repositories), ShouldEqual, 1)
+		So(catalog.Repositories, ShouldContain, TestRepo)
 
-				// call endpoint with session (added to client after previous request)
-				resp, err = client.R().
-					SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-					Get(baseURL + "/v2/_catalog")
-				So(err, ShouldBeNil)
-				So(resp, ShouldNotBeNil)
-				So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
-			})
-		})
+		resp, err = resty.R().SetBasicAuth(htpasswdUsername, badpassphrase).
+			Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp, ShouldNotBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 	})
 }
 
-func TestAuthorization(t *testing.T) {
+func TestAuthorizationWithMultiplePolicies(t *testing.T) {
 	Convey("Make a new controller", t, func() {
 		port := test.GetFreePort()
 		baseURL := test.GetBaseURL(port)
+
 		conf := config.New()
 		conf.HTTP.Port = port
-		username, seedUser := test.GenerateRandomString()
-		password, seedPass := test.GenerateRandomString()
-		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(username, password))
+		// have two users: one for  user Policy, and another for default policy
+		username1, seedUser1 := test.GenerateRandomString()
+		password1, seedPass1 := test.GenerateRandomString()
+		username2, seedUser2 := test.GenerateRandomString()
+		password2, seedPass2 := test.GenerateRandomString()
+		content := test.GetCredString(username1, password1) + test.GetCredString(username2, password2)
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(content)
 
 		defer os.Remove(htpasswdPath)
 
@@ -5550,6 +7188,7 @@ func TestAuthorization(t *testing.T) {
 				Path: htpasswdPath,
 			},
 		}
+		// config with all policy types, to test that the correct one is applied in each case
 		conf.HTTP.AccessControl = &config.AccessControlConfig{
 			Repositories: config.Repositories{
 				test.AuthorizationAllRepos: config.PolicyGroup{
@@ -5559,7 +7198,8 @@ func TestAuthorization(t *testing.T) {
 							Actions: []string{},
 						},
 					},
-					DefaultPolicy: []string{},
+					DefaultPolicy:   []string{},
+					AnonymousPolicy: []string{},
 				},
 			},
 			AdminPolicy: config.Policy{
@@ -5569,6 +7209,8 @@ func TestAuthorization(t *testing.T) {
 		}
 
 		Convey("with openid", func() {
+			dir := t.TempDir()
+
 			mockOIDCServer, err := authutils.MockOIDCRun()
 			if err != nil {
 				panic(err)
@@ -5597,8 +7239,12 @@ func TestAuthorization(t *testing.T) {
 			}
 
 			ctlr := api.NewController(conf)
-			ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
-			ctlr.Config.Storage.RootDirectory = t.TempDir()
+			ctlr.Log.Info().Int64("seedUser1", seedUser1).Int64("seedPass1", seedPass1).
+				Msg("random seed for username & password")
+			ctlr.Log.Info().Int64("seedUser2", seedUser2).Int64("seedPass2", seedPass2).
+				Msg("random seed for username & password")
+
+			ctlr.Config.Storage.RootDirectory = dir
 
 			err = WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "0.0.1",
 				ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
@@ -5609,17 +7255,17 @@ func TestAuthorization(t *testing.T) {
 
 			defer cm.StopServer()
 
-			client := resty.New()
+			testUserClient := resty.New()
 
-			client.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+			testUserClient.SetRedirectPolicy(test.CustomRedirectPolicy(20))
 
 			mockOIDCServer.QueueUser(&mockoidc.MockUser{
-				Email:   username,
+				Email:   username1,
 				Subject: "1234567890",
 			})
 
 			// first login user
-			resp, err := client.R().
+			resp, err := testUserClient.R().
 				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
 				SetQueryParam("provider", "oidc").
 				Get(baseURL + constants.LoginPath)
@@ -5627,825 +7273,863 @@ func TestAuthorization(t *testing.T) {
 			So(resp, ShouldNotBeNil)
 			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
 
-			client.SetCookies(resp.Cookies())
-			client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
-
-			RunAuthorizationTests(t, client, baseURL, username, conf)
-		})
+			testUserClient.SetCookies(resp.Cookies())
+			testUserClient.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
 
-		Convey("with basic auth", func() {
-			ctlr := api.NewController(conf)
-			ctlr.Config.Storage.RootDirectory = t.TempDir()
+			bobUserClient := resty.New()
 
-			err := WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "0.0.1",
-				ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
-			So(err, ShouldBeNil)
+			bobUserClient.SetRedirectPolicy(test.CustomRedirectPolicy(20))
 
-			cm := test.NewControllerManager(ctlr)
-			cm.StartAndWait(port)
+			mockOIDCServer.QueueUser(&mockoidc.MockUser{
+				Email:   username2,
+				Subject: "1234567890",
+			})
 
-			defer cm.StopServer()
+			// first login user
+			resp, err = bobUserClient.R().
+				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
+				SetQueryParam("provider", "oidc").
+				Get(baseURL + constants.LoginPath)
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
 
-			client := resty.New()
-			client.SetBasicAuth(username, password)
+			bobUserClient.SetCookies(resp.Cookies())
+			bobUserClient.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
 
-			RunAuthorizationTests(t, client, baseURL, username, conf)
+			RunAuthorizationWithMultiplePoliciesTests(t, testUserClient, bobUserClient, baseURL, username1, username2, conf)
 		})
-	})
-}
-
-func TestGetUsername(t *testing.T) {
-	Convey("Make a new controller", t, func() {
-		port := test.GetFreePort()
-		baseURL := test.GetBaseURL(port)
-
-		username, seedUser := test.GenerateRandomString()
-		password, seedPass := test.GenerateRandomString()
-		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(username, password))
-
-		defer os.Remove(htpasswdPath)
-
-		conf := config.New()
-		conf.HTTP.Port = port
-		conf.HTTP.Auth = &config.AuthConfig{
-			HTPasswd: config.AuthHTPasswd{
-				Path: htpasswdPath,
-			},
-		}
-
-		dir := t.TempDir()
-		ctlr := makeController(conf, dir)
-		ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
-
-		cm := test.NewControllerManager(ctlr)
-		cm.StartAndWait(port)
 
-		defer cm.StopServer()
+		Convey("with basic auth", func() {
+			dir := t.TempDir()
 
-		resp, err := resty.R().Get(baseURL + "/v2/")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			ctlr := api.NewController(conf)
+			ctlr.Config.Storage.RootDirectory = dir
 
-		// test base64 encode
-		resp, err = resty.R().SetHeader("Authorization", "Basic should fail").Get(baseURL + "/v2/_catalog")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			err := WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "0.0.1",
+				ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
+			So(err, ShouldBeNil)
 
-		// test "username:password" encoding
-		resp, err = resty.R().SetHeader("Authorization", "Basic dGVzdA==").Get(baseURL + "/v2/_catalog")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			cm := test.NewControllerManager(ctlr)
+			cm.StartAndWait(port)
 
-		// failed parsing authorization header
-		resp, err = resty.R().SetHeader("Authorization", "Basic ").Get(baseURL + "/v2/_catalog")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+			defer cm.StopServer()
 
-		var e apiErr.Error
+			userClient1 := resty.New()
+			userClient1.SetBasicAuth(username1, password1)
 
-		err = json.Unmarshal(resp.Body(), &e)
-		So(err, ShouldBeNil)
+			userClient2 := resty.New()
+			userClient2.SetBasicAuth(username2, password2)
 
-		resp, err = resty.R().SetBasicAuth(username, password).Get(baseURL + "/v2/_catalog")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+			RunAuthorizationWithMultiplePoliciesTests(t, userClient1, userClient2, baseURL, username1, username2, conf)
+		})
 	})
 }
 
-func TestAuthorizationMountBlob(t *testing.T) {
-	Convey("Make a new controller", t, func() {
+func TestAuthorizationWithDenyPoliciesAndConditions(t *testing.T) {
+	Convey("Make a new controller with deny policies and reference conditions", t, func() {
 		port := test.GetFreePort()
 		baseURL := test.GetBaseURL(port)
 
-		conf := config.New()
-		conf.HTTP.Port = port
-		// have two users: one for  user Policy, and another for default policy
-		username1, _ := test.GenerateRandomString()
-		password1, _ := test.GenerateRandomString()
-		username2, _ := test.GenerateRandomString()
-		password2, _ := test.GenerateRandomString()
-		username1 = strings.ToLower(username1)
-		username2 = strings.ToLower(username2)
-
-		content := test.GetCredString(username1, password1) + test.GetCredString(username2, password2)
-
-		htpasswdPath := test.MakeHtpasswdFileFromString(content)
+		admin, adminPassword := "admin", "admin"
+		releaser, releaserPassword := "releaser", "releaser"
+		developer, developerPassword := "developer", "developer"
 
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(admin, adminPassword) + "\n" +
+			test.GetCredString(releaser, releaserPassword) + "\n" + test.GetCredString(developer, developerPassword))
 		defer os.Remove(htpasswdPath)
 
+		conf := config.New()
+		conf.HTTP.Port = port
 		conf.HTTP.Auth = &config.AuthConfig{
-			HTPasswd: config.AuthHTPasswd{
-				Path: htpasswdPath,
-			},
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
 		}
-
-		user1Repo := username1 + "/**"
-		user2Repo := username2 + "/**"
-
-		// config with all policy types, to test that the correct one is applied in each case
 		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Groups: config.Groups{
+				"release": config.Group{Users: []string{releaser}},
+				"dev":     config.Group{Users: []string{developer}},
+			},
 			Repositories: config.Repositories{
-				user1Repo: config.PolicyGroup{
+				"teams/apps/**": config.PolicyGroup{
 					Policies: []config.Policy{
 						{
-							Users: []string{username1},
-							Actions: []string{
-								constants.ReadPermission,
-								constants.CreatePermission,
-							},
+							Groups:  []string{"dev"},
+							Actions: []string{constants.CreatePermission, constants.UpdatePermission},
+						},
+						{
+							Groups:   []string{"release"},
+							Actions:  []string{constants.CreatePermission, constants.UpdatePermission},
+							TagRegex: "v.*",
+						},
+						{
+							Groups:     []string{"dev"},
+							Actions:    []string{constants.DeletePermission},
+							DigestOnly: true,
 						},
 					},
+					DefaultPolicy: []string{constants.ReadPermission},
 				},
-				user2Repo: config.PolicyGroup{
-					Policies: []config.Policy{
+				"**/secret": config.PolicyGroup{
+					DenyPolicies: []config.Policy{
 						{
-							Users: []string{username2},
-							Actions: []string{
-								constants.ReadPermission,
-								constants.CreatePermission,
-							},
+							Users:   []string{api.Everyone},
+							Actions: []string{constants.ReadPermission},
 						},
 					},
 				},
 			},
+			AdminPolicy: config.Policy{
+				Users: []string{admin},
+				Actions: []string{
+					constants.ReadPermission, constants.CreatePermission,
+					constants.UpdatePermission, constants.DeletePermission,
+				},
+			},
 		}
 
-		dir := t.TempDir()
-
-		ctlr := api.NewController(conf)
-		ctlr.Config.Storage.RootDirectory = dir
+		ctlr := makeController(conf, t.TempDir())
 
 		cm := test.NewControllerManager(ctlr)
 		cm.StartAndWait(port)
 
 		defer cm.StopServer()
 
-		userClient1 := resty.New()
-		userClient1.SetBasicAuth(username1, password1)
-
-		userClient2 := resty.New()
-		userClient2.SetBasicAuth(username2, password2)
-
-		img := CreateImageWith().RandomLayers(1, 2).DefaultConfig().Build()
-
-		repoName1 := username1 + "/" + "myrepo"
-		tag := "1.0"
+		image := CreateRandomImage()
 
-		// upload image with user1 on repoName1
-		err := UploadImageWithBasicAuth(img, baseURL, repoName1, tag, username1, password1)
+		manifestBlob, err := json.Marshal(image.Manifest)
 		So(err, ShouldBeNil)
 
-		repoName2 := username2 + "/" + "myrepo"
+		pushManifest := func(username, password, repo, reference string) int {
+			resp, err := resty.R().SetBasicAuth(username, password).
+				SetHeader("Content-Type", ispec.MediaTypeImageManifest).SetBody(manifestBlob).
+				Put(baseURL + "/v2/" + repo + "/manifests/" + reference)
+			So(err, ShouldBeNil)
 
-		blobDigest := img.Manifest.Layers[0].Digest
+			return resp.StatusCode()
+		}
 
-		/* a HEAD request by user2 on blob digest (found in user1Repo) should return 404
-		because user2 doesn't have permissions to read user1Repo */
-		resp, err := userClient2.R().Head(baseURL + fmt.Sprintf("/v2/%s/blobs/%s", repoName2, blobDigest))
+		// the blobs can be pushed by the users only allowed to push some tags
+		err = UploadImageWithBasicAuth(image, baseURL, "teams/apps/web", "1.0", releaser, releaserPassword)
 		So(err, ShouldBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
-
-		params := make(map[string]string)
-		params["mount"] = blobDigest.String()
 
-		// trying to mount a blob which can be found in cache, but user doesn't have permission
-		// should return 202 instead of 201
-		resp, err = userClient2.R().SetQueryParams(params).Post(baseURL + "/v2/" + repoName2 + "/blobs/uploads/")
-		So(err, ShouldBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+		// only the release group may push the tags matching the tag condition
+		So(pushManifest(developer, developerPassword, "teams/apps/web", "1.0"), ShouldEqual, http.StatusCreated)
+		So(pushManifest(developer, developerPassword, "teams/apps/web", "v1.0"), ShouldEqual, http.StatusForbidden)
+		So(pushManifest(releaser, releaserPassword, "teams/apps/web", "v1.0"), ShouldEqual, http.StatusCreated)
+		So(pushManifest(releaser, releaserPassword, "teams/apps/web", "1.1"), ShouldEqual, http.StatusForbidden)
 
-		/* a HEAD request by user1 on blob digest (found in user1Repo) should return 200
-		because user1 has permission to read user1Repo */
-		resp, err = userClient1.R().Head(baseURL + fmt.Sprintf("/v2/%s/blobs/%s", username1+"/"+"mysecondrepo", blobDigest))
+		resp, err := resty.R().SetBasicAuth(developer, developerPassword).
+			Get(baseURL + "/v2/teams/apps/web/tags/list")
 		So(err, ShouldBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		// user2 can upload without dedupe
-		err = UploadImageWithBasicAuth(img, baseURL, repoName2, tag, username2, password2)
+		// deny policies take precedence over the longest matching pattern and the admin policy
+		err = UploadImageWithBasicAuth(image, baseURL, "teams/apps/secret", "1.0", admin, adminPassword)
 		So(err, ShouldBeNil)
+		So(pushManifest(admin, adminPassword, "teams/apps/secret", "1.0"), ShouldEqual, http.StatusCreated)
 
-		// trying to mount a blob which can be found in cache and user has permission should return 201 instead of 202
-		resp, err = userClient2.R().SetQueryParams(params).Post(baseURL + "/v2/" + repoName2 + "/blobs/uploads/")
-		So(err, ShouldBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
-	})
-}
+		for _, credentials := range [][]string{{developer, developerPassword}, {admin, adminPassword}} {
+			resp, err = resty.R().SetBasicAuth(credentials[0], credentials[1]).
+				Get(baseURL + "/v2/teams/apps/secret/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
 
-func TestAuthorizationWithOnlyAnonymousPolicy(t *testing.T) {
-	Convey("Make a new controller", t, func() {
-		const TestRepo = "my-repos/repo"
+			resp, err = resty.R().SetBasicAuth(credentials[0], credentials[1]).Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		port := test.GetFreePort()
-		baseURL := test.GetBaseURL(port)
+			var catalog struct {
+				Repositories []string `json:"repositories"`
+			}
 
-		conf := config.New()
-		conf.HTTP.Port = port
-		conf.HTTP.Auth = &config.AuthConfig{}
-		conf.HTTP.AccessControl = &config.AccessControlConfig{
-			Repositories: config.Repositories{
-				TestRepo: config.PolicyGroup{
-					AnonymousPolicy: []string{"read"},
-				},
-			},
+			err = json.Unmarshal(resp.Body(), &catalog)
+			So(err, ShouldBeNil)
+			So(catalog.Repositories, ShouldContain, "teams/apps/web")
+			So(catalog.Repositories, ShouldNotContain, "teams/apps/secret")
 		}
 
-		dir := t.TempDir()
-		ctlr := makeController(conf, dir)
-		cm := test.NewControllerManager(ctlr)
-		cm.StartAndWait(port)
-
-		defer cm.StopServer()
-
-		blob := []byte("hello, blob!")
-		digest := godigest.FromBytes(blob).String()
-
-		resp, err := resty.R().Get(baseURL + "/v2/")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
-
-		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
-
-		var e apiErr.Error
-
-		err = json.Unmarshal(resp.Body(), &e)
+		// the developers may only delete by digest
+		resp, err = resty.R().SetBasicAuth(developer, developerPassword).
+			Delete(baseURL + "/v2/teams/apps/web/manifests/1.0")
 		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
 
-		// should get 401 without create
-		resp, err = resty.R().Post(baseURL + "/v2/" + TestRepo + "/blobs/uploads/")
+		resp, err = resty.R().SetBasicAuth(developer, developerPassword).
+			Delete(baseURL + "/v2/teams/apps/web/manifests/" + image.DigestStr())
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+	})
+}
 
-		if entry, ok := conf.HTTP.AccessControl.Repositories[TestRepo]; ok {
-			entry.AnonymousPolicy = []string{"create", "read"}
-			conf.HTTP.AccessControl.Repositories[TestRepo] = entry
-		}
+func TestAuthorizationWithWebhook(t *testing.T) {
+	Convey("Make a new controller delegating the authorization to a webhook", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
 
-		// now it should get 202
-		resp, err = resty.R().Post(baseURL + "/v2/" + TestRepo + "/blobs/uploads/")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
-		loc := resp.Header().Get("Location")
+		writer, writerPassword := "writer", "writer"
+		reader, readerPassword := "reader", "reader"
 
-		// uploading blob should get 201
-		resp, err = resty.R().SetHeader("Content-Length", strconv.Itoa(len(blob))).
-			SetHeader("Content-Type", "application/octet-stream").
-			SetQueryParam("digest", digest).
-			SetBody(blob).
-			Put(baseURL + loc)
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(writer, writerPassword) + "\n" +
+			test.GetCredString(reader, readerPassword))
+		defer os.Remove(htpasswdPath)
 
-		cblob, cdigest := GetRandomImageConfig()
+		var (
+			lock     sync.Mutex
+			requests []api.AuthzWebhookRequest
+		)
 
-		resp, err = resty.R().Post(baseURL + "/v2/" + TestRepo + "/blobs/uploads/")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
-		loc = test.Location(baseURL, resp)
+		// the writer may do anything, the others may only read the public repositories
+		policyEngine := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			var authzRequest api.AuthzWebhookRequest
 
-		// uploading blob should get 201
-		resp, err = resty.R().SetHeader("Content-Length", strconv.Itoa(len(cblob))).
-			SetHeader("Content-Type", "application/octet-stream").
-			SetQueryParam("digest", cdigest.String()).
-			SetBody(cblob).
-			Put(loc)
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+			if err := json.NewDecoder(request.Body).Decode(&authzRequest); err != nil {
+				response.WriteHeader(http.StatusBadRequest)
 
-		manifest := ispec.Manifest{
-			Config: ispec.Descriptor{
-				MediaType: "application/vnd.oci.image.config.v1+json",
-				Digest:    cdigest,
-				Size:      int64(len(cblob)),
-			},
-			Layers: []ispec.Descriptor{
-				{
-					MediaType: "application/vnd.oci.image.layer.v1.tar",
-					Digest:    godigest.FromBytes(blob),
-					Size:      int64(len(blob)),
-				},
-			},
-		}
-		manifest.SchemaVersion = 2
-		manifestBlob, err := json.Marshal(manifest)
-		So(err, ShouldBeNil)
+				return
+			}
 
-		resp, err = resty.R().
-			SetHeader("Content-type", "application/vnd.oci.image.manifest.v1+json").
-			SetBody(manifestBlob).
-			Put(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+			lock.Lock()
+			requests = append(requests, authzRequest)
+			lock.Unlock()
 
-		updateBlob := []byte("Hello, blob update!")
+			allow := authzRequest.User == writer ||
+				(authzRequest.Action == constants.ReadPermission && strings.HasPrefix(authzRequest.Repository, "public/"))
 
-		resp, err = resty.R().
-			Post(baseURL + "/v2/" + TestRepo + "/blobs/uploads/")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
-		loc = test.Location(baseURL, resp)
-		// uploading blob should get 201
-		resp, err = resty.R().
-			SetHeader("Content-Length", strconv.Itoa(len(updateBlob))).
-			SetHeader("Content-Type", "application/octet-stream").
-			SetQueryParam("digest", string(godigest.FromBytes(updateBlob))).
-			SetBody(updateBlob).
-			Put(loc)
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+			_ = json.NewEncoder(response).Encode(api.AuthzWebhookResponse{Allow: allow})
+		}))
+		defer policyEngine.Close()
 
-		updatedManifest := ispec.Manifest{
-			Config: ispec.Descriptor{
-				MediaType: "application/vnd.oci.image.config.v1+json",
-				Digest:    cdigest,
-				Size:      int64(len(cblob)),
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Groups: config.Groups{
+				"dev": config.Group{Users: []string{writer}},
 			},
-			Layers: []ispec.Descriptor{
-				{
-					MediaType: "application/vnd.oci.image.layer.v1.tar",
-					Digest:    godigest.FromBytes(updateBlob),
-					Size:      int64(len(updateBlob)),
+			Repositories: config.Repositories{
+				// the deny policies are still enforced before asking the webhook
+				"public/secret": config.PolicyGroup{
+					DenyPolicies: []config.Policy{
+						{Users: []string{reader}, Actions: []string{constants.ReadPermission}},
+					},
 				},
 			},
+			Webhook: &config.AuthzWebhookConfig{URL: policyEngine.URL, CacheTTL: time.Hour},
 		}
-		updatedManifest.SchemaVersion = 2
-		updatedManifestBlob, err := json.Marshal(updatedManifest)
-		So(err, ShouldBeNil)
 
-		// update manifest should get 401 without update perm
-		resp, err = resty.R().SetBody(updatedManifestBlob).
-			Put(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		ctlr := makeController(conf, t.TempDir())
 
-		// get the manifest and check if it's the old one
-		resp, err = resty.R().
-			Get(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
-		So(resp.Body(), ShouldResemble, manifestBlob)
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-		// add update perm on repo
-		if entry, ok := conf.HTTP.AccessControl.Repositories[TestRepo]; ok {
-			entry.AnonymousPolicy = []string{"create", "read", "update"}
-			conf.HTTP.AccessControl.Repositories[TestRepo] = entry
+		defer cm.StopServer()
+
+		for _, repo := range []string{"public/app", "public/secret", "private/app"} {
+			err := UploadImageWithBasicAuth(CreateRandomImage(), baseURL, repo, "1.0", writer, writerPassword)
+			So(err, ShouldBeNil)
 		}
 
-		// update manifest should get 201 with update perm
-		resp, err = resty.R().
-			SetHeader("Content-type", "application/vnd.oci.image.manifest.v1+json").
-			SetBody(updatedManifestBlob).
-			Put(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+		lock.Lock()
+		So(requests, ShouldNotBeEmpty)
+		So(requests[0].Type, ShouldEqual, api.AuthzWebhookRepositoryResource)
+		So(requests[0].User, ShouldEqual, writer)
+		So(requests[0].Groups, ShouldResemble, []string{"dev"})
+		So(requests[0].ClientIP, ShouldEqual, "127.0.0.1")
+		So(requests, ShouldContain, api.AuthzWebhookRequest{
+			Type: api.AuthzWebhookRepositoryResource, User: writer, Groups: []string{"dev"},
+			Action: constants.CreatePermission, Repository: "private/app", Reference: "1.0", ClientIP: "127.0.0.1",
+		})
+		lock.Unlock()
 
-		// get the manifest and check if it's the new updated one
-		resp, err = resty.R().
-			Get(baseURL + "/v2/" + TestRepo + "/manifests/0.0.2")
+		resp, err := resty.R().SetBasicAuth(reader, readerPassword).Get(baseURL + "/v2/public/app/tags/list")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
-		So(resp.Body(), ShouldResemble, updatedManifestBlob)
 
-		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
+		resp, err = resty.R().SetBasicAuth(reader, readerPassword).Get(baseURL + "/v2/private/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		resp, err = resty.R().SetBasicAuth(reader, readerPassword).Get(baseURL + "/v2/public/secret/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		err = UploadImageWithBasicAuth(CreateRandomImage(), baseURL, "public/app", "2.0", reader, readerPassword)
+		So(err, ShouldNotBeNil)
+
+		// the catalog is filtered by the webhook too
+		resp, err = resty.R().SetBasicAuth(reader, readerPassword).Get(baseURL + "/v2/_catalog")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		// make sure anonymous is correctly handled when using acCtx (requestcontext package)
-		catalog := struct {
+		var catalog struct {
 			Repositories []string `json:"repositories"`
-		}{}
+		}
 
 		err = json.Unmarshal(resp.Body(), &catalog)
 		So(err, ShouldBeNil)
-		So(len(catalog.Repositories), ShouldEqual, 1)
-		So(catalog.Repositories, ShouldContain, TestRepo)
+		So(catalog.Repositories, ShouldResemble, []string{"public/app"})
 
-		err = os.Mkdir(path.Join(dir, "zot-test"), storageConstants.DefaultDirPerms)
-		So(err, ShouldBeNil)
+		// the decisions are cached
+		lock.Lock()
+		requestCount := len(requests)
+		lock.Unlock()
 
-		err = WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "tag", ctlr.StoreController)
+		resp, err = resty.R().SetBasicAuth(reader, readerPassword).Get(baseURL + "/v2/public/app/tags/list")
 		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		// should not have read rights on zot-test
-		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
+		lock.Lock()
+		So(requests, ShouldHaveLength, requestCount)
+		lock.Unlock()
+
+		// the access is denied when the webhook is down, except for the decisions still cached
+		policyEngine.Close()
+
+		resp, err = resty.R().SetBasicAuth(reader, readerPassword).Get(baseURL + "/v2/public/app/tags/list")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		err = json.Unmarshal(resp.Body(), &catalog)
+		resp, err = resty.R().SetBasicAuth(writer, writerPassword).Get(baseURL + "/v2/public/app/manifests/1.0")
 		So(err, ShouldBeNil)
-		So(len(catalog.Repositories), ShouldEqual, 1)
-		So(catalog.Repositories, ShouldContain, TestRepo)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+	})
+}
 
-		// add rights
-		conf.HTTP.AccessControl.Repositories["zot-test"] = config.PolicyGroup{
-			AnonymousPolicy: []string{"read"},
+func TestAuthorizationWithHangingWebhook(t *testing.T) {
+	Convey("Make a new controller delegating the authorization to a webhook which does not answer", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		reader, readerPassword := "reader", "reader"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(reader, readerPassword))
+		defer os.Remove(htpasswdPath)
+
+		var calls atomic.Int32
+
+		release := make(chan struct{})
+
+		policyEngine := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			calls.Add(1)
+
+			select {
+			case <-release:
+			case <-request.Context().Done():
+			}
+		}))
+		defer policyEngine.Close()
+		defer close(release)
+
+		timeout := 500 * time.Millisecond
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Webhook: &config.AuthzWebhookConfig{URL: policyEngine.URL, Timeout: timeout},
 		}
 
-		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
-		So(err, ShouldBeNil)
+		ctlr := makeController(conf, t.TempDir())
 
-		So(resp, ShouldNotBeNil)
+		for i := range 20 {
+			err := WriteImageToFileSystem(CreateRandomImage(), fmt.Sprintf("repo%d", i), "1.0",
+				ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
+			So(err, ShouldBeNil)
+		}
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		// the catalog checks every repository, but does not wait for the webhook for each of them
+		start := time.Now()
+
+		resp, err := resty.R().SetBasicAuth(reader, readerPassword).Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(time.Since(start), ShouldBeLessThan, 4*timeout)
+
+		var catalog struct {
+			Repositories []string `json:"repositories"`
+		}
 
 		err = json.Unmarshal(resp.Body(), &catalog)
 		So(err, ShouldBeNil)
+		So(catalog.Repositories, ShouldBeEmpty)
+		So(calls.Load(), ShouldEqual, 1)
 
-		So(len(catalog.Repositories), ShouldEqual, 2)
-		So(catalog.Repositories, ShouldContain, TestRepo)
-		So(catalog.Repositories, ShouldContain, "zot-test")
+		// the next requests ask the webhook again
+		resp, err = resty.R().SetBasicAuth(reader, readerPassword).Get(baseURL + "/v2/repo0/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+		So(calls.Load(), ShouldEqual, 2)
 	})
 }
 
-func TestAuthorizationWithAnonymousPolicyBasicAuthAndSessionHeader(t *testing.T) {
-	Convey("Make a new controller", t, func() {
-		const TestRepo = "my-repos/repo"
+func TestProxyHeaderAuthn(t *testing.T) {
+	Convey("Make a new controller trusting the headers of an authenticating proxy", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
 
-		const AllRepos = "**"
+		admin, adminPassword := "admin", "admin"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(admin, adminPassword))
+		defer os.Remove(htpasswdPath)
+
+		defaultVal := true
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd:    config.AuthHTPasswd{Path: htpasswdPath},
+			ProxyHeader: &config.ProxyHeaderConfig{TrustedCIDRs: []string{"127.0.0.1/32", "::1/128"}},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Groups: config.Groups{
+				"qa": config.Group{Users: []string{"carol"}},
+			},
+			Repositories: config.Repositories{
+				"**": config.PolicyGroup{
+					Policies: []config.Policy{
+						{Groups: []string{"dev", "qa"}, Actions: []string{constants.ReadPermission}},
+					},
+				},
+			},
+			AdminPolicy: config.Policy{
+				Users:   []string{admin},
+				Actions: []string{constants.ReadPermission, constants.CreatePermission},
+			},
+		}
+		conf.Extensions = &extconf.ExtensionConfig{
+			Search: &extconf.SearchConfig{BaseConfig: extconf.BaseConfig{Enable: &defaultVal}},
+			UI:     &extconf.UIConfig{BaseConfig: extconf.BaseConfig{Enable: &defaultVal}},
+		}
+
+		proxyRequest := func(user, groups string) *resty.Request {
+			request := resty.R()
+
+			if user != "" {
+				request.SetHeader("X-Forwarded-User", user)
+			}
+
+			if groups != "" {
+				request.SetHeader("X-Forwarded-Groups", groups)
+			}
+
+			return request
+		}
+
+		Convey("The headers of the trusted proxies authenticate the users", func() {
+			ctlr := makeController(conf, t.TempDir())
+
+			cm := test.NewControllerManager(ctlr)
+			cm.StartAndWait(port)
+
+			defer cm.StopServer()
+
+			err := UploadImageWithBasicAuth(CreateRandomImage(), baseURL, "apps/web", "1.0", admin, adminPassword)
+			So(err, ShouldBeNil)
+
+			resp, err := proxyRequest("alice", "ops, dev").Get(baseURL + "/v2/apps/web/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			// the groups of the access control config are added to the groups of the proxy
+			resp, err = proxyRequest("carol", "").Get(baseURL + "/v2/apps/web/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			resp, err = proxyRequest("bob", "ops").Get(baseURL + "/v2/apps/web/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			// the groups alone do not authenticate anybody
+			resp, err = proxyRequest("", "dev").Get(baseURL + "/v2/apps/web/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			// the users have a profile for their preferences
+			resp, err = proxyRequest("alice", "dev").
+				Put(baseURL + constants.FullUserPrefs + "?repo=apps/web&action=toggleStar")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			// the other authentication methods still work without the headers
+			resp, err = resty.R().SetBasicAuth(admin, adminPassword).Get(baseURL + "/v2/apps/web/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		})
 
+		Convey("The headers sent from untrusted sources are rejected", func() {
+			conf.HTTP.Auth.ProxyHeader = &config.ProxyHeaderConfig{
+				UserHeader:       "X-Auth-User",
+				TrustedCIDRs:     []string{"10.0.0.0/8"},
+				TrustedCertNames: []string{"sso-proxy"},
+			}
+
+			ctlr := makeController(conf, t.TempDir())
+
+			cm := test.NewControllerManager(ctlr)
+			cm.StartAndWait(port)
+
+			defer cm.StopServer()
+
+			resp, err := resty.R().SetHeader("X-Auth-User", admin).Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			resp, err = proxyRequest("", "dev").Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			// even with valid credentials
+			resp, err = resty.R().SetHeader("X-Auth-User", admin).SetBasicAuth(admin, adminPassword).
+				Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			resp, err = resty.R().SetBasicAuth(admin, adminPassword).Get(baseURL + "/v2/_catalog")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		})
+	})
+}
+
+func TestMTLSMappingAuthn(t *testing.T) {
+	Convey("Make a new controller mapping the client certificates along with basic authentication", t, func() {
 		port := test.GetFreePort()
-		baseURL := test.GetBaseURL(port)
+		secureBaseURL := test.GetSecureBaseURL(port)
 
-		badpassphrase := "bad"
-		htpasswdUsername, seedUser := test.GenerateRandomString()
-		htpasswdPassword, seedPass := test.GenerateRandomString()
-		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(htpasswdUsername, htpasswdPassword))
+		admin, adminPassword := "admin", "admin"
 
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(admin, adminPassword))
 		defer os.Remove(htpasswdPath)
 
-		img := CreateRandomImage()
-		tagAnonymous := "1.0-anon"
-		tagAuth := "1.0-auth"
-		tagUnauth := "1.0-unauth"
+		pki := newTestPKI(t.TempDir())
 
 		conf := config.New()
 		conf.HTTP.Port = port
+		conf.HTTP.TLS = &config.TLSConfig{
+			Cert:   pki.serverCertPath,
+			Key:    pki.serverKeyPath,
+			CACert: pki.caPath,
+		}
 		conf.HTTP.Auth = &config.AuthConfig{
-			HTPasswd: config.AuthHTPasswd{
-				Path: htpasswdPath,
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+			MTLS: &config.MTLSConfig{
+				IdentityRules: []config.CertificateMappingRule{
+					{Attribute: api.CertURI, Pattern: "spiffe://myorg.io/.*"},
+				},
+				GroupRules: []config.CertificateMappingRule{
+					{Attribute: api.CertOrganizationalUnit},
+					{Attribute: api.CertURI, Pattern: "spiffe://myorg.io/team/([^/]+)/.*", Value: "team-$1"},
+				},
 			},
 		}
 		conf.HTTP.AccessControl = &config.AccessControlConfig{
 			Repositories: config.Repositories{
-				AllRepos: config.PolicyGroup{
+				"build/**": config.PolicyGroup{
 					Policies: []config.Policy{
-						{
-							Users:   []string{htpasswdUsername},
-							Actions: []string{"read"},
-						},
+						{Groups: []string{"team-build"}, Actions: []string{constants.ReadPermission, constants.CreatePermission}},
+					},
+				},
+				"platform/**": config.PolicyGroup{
+					Policies: []config.Policy{
+						{Groups: []string{"platform"}, Actions: []string{constants.ReadPermission}},
 					},
-					AnonymousPolicy: []string{"read"},
 				},
 			},
+			AdminPolicy: config.Policy{
+				Users:   []string{admin},
+				Actions: []string{constants.ReadPermission},
+			},
+		}
+
+		ctlr := makeController(conf, t.TempDir())
+
+		image := CreateRandomImage()
+		storeController := ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log)
+
+		for _, repo := range []string{"build/app", "platform/app", "secret/app"} {
+			So(WriteImageToFileSystem(image, repo, "1.0", storeController), ShouldBeNil)
 		}
 
-		dir := t.TempDir()
-		ctlr := makeController(conf, dir)
-		ctlr.Log.Info().Int64("seedUser", seedUser).Int64("seedPass", seedPass).Msg("random seed for username & password")
 		cm := test.NewControllerManager(ctlr)
 		cm.StartAndWait(port)
 
-		defer cm.StopServer()
+		defer cm.StopServer()
+
+		newClient := func(certificates ...tls.Certificate) *resty.Client {
+			return resty.New().SetTLSClientConfig(&tls.Config{
+				RootCAs: pki.caPool, Certificates: certificates, MinVersion: tls.VersionTLS12,
+			})
+		}
+
+		spiffeID, err := url.Parse("spiffe://myorg.io/team/build/sa/ci")
+		So(err, ShouldBeNil)
 
-		// /v2 access
-		// Can access /v2 without credentials
-		resp, err := resty.R().Get(baseURL + "/v2/")
+		ciClient := newClient(pki.issue(&x509.Certificate{
+			Subject: pkix.Name{CommonName: "ci", OrganizationalUnit: []string{"platform"}},
+			URIs:    []*url.URL{spiffeID},
+		}))
+
+		// the certificate gives the identity and groups without any password
+		resp, err := ciClient.R().Get(secureBaseURL + "/v2/build/app/tags/list")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		// Can access /v2 without credentials and with X-Zot-Api-Client=zot-ui
-		resp, err = resty.R().
-			SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-			Get(baseURL + "/v2/")
+		resp, err = ciClient.R().Get(secureBaseURL + "/v2/platform/app/tags/list")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		// Can access /v2 with correct credentials
-		resp, err = resty.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
-			Get(baseURL + "/v2/")
+		resp, err = ciClient.R().Get(secureBaseURL + "/v2/secret/app/tags/list")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
 
-		// Fail to access /v2 with incorrect credentials
-		resp, err = resty.R().SetBasicAuth(htpasswdUsername, badpassphrase).
-			Get(baseURL + "/v2/")
+		manifestBlob, err := json.Marshal(image.Manifest)
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-		// Catalog access
-		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
+		resp, err = ciClient.R().SetHeader("Content-Type", ispec.MediaTypeImageManifest).SetBody(manifestBlob).
+			Put(secureBaseURL + "/v2/build/app/manifests/2.0")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
 
-		var apiError apiErr.Error
+		userAc := reqCtx.NewUserAccessControl()
+		userAc.SetUsername(spiffeID.String())
 
-		err = json.Unmarshal(resp.Body(), &apiError)
+		groups, err := ctlr.MetaDB.GetUserGroups(userAc.DeriveContext(context.Background()))
 		So(err, ShouldBeNil)
+		So(groups, ShouldResemble, []string{"platform", "team-build"})
 
-		resp, err = resty.R().
-			SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-			Get(baseURL + "/v2/_catalog")
+		// the user profile is only written when the groups of the certificate changed
+		metaDB := ctlr.MetaDB
+		ctlr.MetaDB = mocks.MetaDBMock{
+			GetUserGroupsFn: func(ctx context.Context) ([]string, error) {
+				return groups, nil
+			},
+			SetUserGroupsFn: func(ctx context.Context, groups []string) error {
+				return ErrUnexpectedError
+			},
+		}
+
+		resp, err = ciClient.R().Get(secureBaseURL + "/v2/build/app/tags/list")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		apiError = apiErr.Error{}
-		err = json.Unmarshal(resp.Body(), &apiError)
-		So(err, ShouldBeNil)
+		ctlr.MetaDB = mocks.MetaDBMock{
+			GetUserGroupsFn: func(ctx context.Context) ([]string, error) {
+				return []string{"platform"}, nil
+			},
+			SetUserGroupsFn: func(ctx context.Context, groups []string) error {
+				return ErrUnexpectedError
+			},
+		}
 
-		resp, err = resty.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
-			Get(baseURL + "/v2/_catalog")
+		resp, err = ciClient.R().Get(secureBaseURL + "/v2/build/app/tags/list")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(resp.StatusCode(), ShouldEqual, http.StatusInternalServerError)
 
-		apiError = apiErr.Error{}
-		err = json.Unmarshal(resp.Body(), &apiError)
-		So(err, ShouldBeNil)
+		ctlr.MetaDB = metaDB
 
-		resp, err = resty.R().
-			SetBasicAuth(htpasswdUsername, badpassphrase).
-			Get(baseURL + "/v2/_catalog")
+		// the credentials take precedence over the certificate
+		resp, err = ciClient.R().SetBasicAuth(admin, "wrong").Get(secureBaseURL + "/v2/build/app/tags/list")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-		apiError = apiErr.Error{}
-		err = json.Unmarshal(resp.Body(), &apiError)
+		// the certificates not mapped to any identity do not authenticate
+		otherClient := newClient(pki.issue(&x509.Certificate{
+			Subject: pkix.Name{CommonName: "other", OrganizationalUnit: []string{"platform"}},
+		}))
+
+		resp, err = otherClient.R().Get(secureBaseURL + "/v2/platform/app/tags/list")
 		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-		// upload capability
-		// should get 403 without create
-		err = UploadImage(img, baseURL, TestRepo, tagAnonymous)
-		So(err, ShouldNotBeNil)
+		// basic authentication keeps working for the clients without certificate
+		resp, err = newClient().R().Get(secureBaseURL + "/v2/secret/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-		err = UploadImageWithBasicAuth(img, baseURL,
-			TestRepo, tagAuth, htpasswdUsername, htpasswdPassword)
-		So(err, ShouldNotBeNil)
+		resp, err = newClient().R().SetBasicAuth(admin, adminPassword).Get(secureBaseURL + "/v2/secret/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+	})
+}
 
-		err = UploadImageWithBasicAuth(img, baseURL,
-			TestRepo, tagUnauth, htpasswdUsername, badpassphrase)
-		So(err, ShouldNotBeNil)
+func TestSessionAuditSubject(t *testing.T) {
+	Convey("Make a new controller auditing the requests of the users", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
 
-		if entry, ok := conf.HTTP.AccessControl.Repositories[AllRepos]; ok {
-			entry.AnonymousPolicy = []string{"create", "read"}
-			entry.Policies[0] = config.Policy{
-				Users:   []string{htpasswdUsername},
-				Actions: []string{"create", "read"},
-			}
-			conf.HTTP.AccessControl.Repositories[AllRepos] = entry
-		}
+		user, password := "alice", "alice"
 
-		// now it should succeed for valid users
-		err = UploadImage(img, baseURL, TestRepo, tagAnonymous)
-		So(err, ShouldBeNil)
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(user, password))
+		defer os.Remove(htpasswdPath)
 
-		err = UploadImageWithBasicAuth(img, baseURL,
-			TestRepo, tagAuth, htpasswdUsername, htpasswdPassword)
-		So(err, ShouldBeNil)
+		auditPath := path.Join(t.TempDir(), "audit.log")
 
-		err = UploadImageWithBasicAuth(img, baseURL,
-			TestRepo, tagUnauth, htpasswdUsername, badpassphrase)
-		So(err, ShouldNotBeNil)
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.Log.Audit = auditPath
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+		}
 
-		// read capability
-		catalog := struct {
-			Repositories []string `json:"repositories"`
-		}{}
+		ctlr := makeController(conf, t.TempDir())
 
-		resp, err = resty.R().Get(baseURL + "/v2/_catalog")
-		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-		err = json.Unmarshal(resp.Body(), &catalog)
-		So(err, ShouldBeNil)
-		So(len(catalog.Repositories), ShouldEqual, 1)
-		So(catalog.Repositories, ShouldContain, TestRepo)
+		defer cm.StopServer()
 
-		catalog = struct {
-			Repositories []string `json:"repositories"`
-		}{}
+		client := resty.New()
+		client.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
 
-		resp, err = resty.R().
-			SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-			Get(baseURL + "/v2/_catalog")
+		resp, err := client.R().SetBasicAuth(user, password).Get(baseURL + "/v2/")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
 		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-		err = json.Unmarshal(resp.Body(), &catalog)
-		So(err, ShouldBeNil)
-		So(len(catalog.Repositories), ShouldEqual, 1)
-		So(catalog.Repositories, ShouldContain, TestRepo)
-
-		catalog = struct {
-			Repositories []string `json:"repositories"`
-		}{}
+		client.SetCookies(resp.Cookies())
 
-		resp, err = resty.R().SetBasicAuth(htpasswdUsername, htpasswdPassword).
-			Get(baseURL + "/v2/_catalog")
+		// the requests authenticated without basic auth credentials are attributed to their user too
+		resp, err = client.R().Post(baseURL + "/v2/session-repo/blobs/uploads/")
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
 
-		err = json.Unmarshal(resp.Body(), &catalog)
+		// the rejected requests are attributed to the user they claimed to be
+		resp, err = resty.R().SetBasicAuth("mallory", "wrong").Post(baseURL + "/v2/basic-repo/blobs/uploads/")
 		So(err, ShouldBeNil)
-		So(len(catalog.Repositories), ShouldEqual, 1)
-		So(catalog.Repositories, ShouldContain, TestRepo)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-		resp, err = resty.R().SetBasicAuth(htpasswdUsername, badpassphrase).
-			Get(baseURL + "/v2/_catalog")
+		auditLog, err := os.ReadFile(auditPath)
 		So(err, ShouldBeNil)
-		So(resp, ShouldNotBeNil)
-		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		So(string(auditLog), ShouldContainSubstring, `"subject":"alice","action":"POST","object":"/v2/session-repo/`)
+		So(string(auditLog), ShouldContainSubstring, `"subject":"mallory","action":"POST","object":"/v2/basic-repo/`)
 	})
 }
 
-func TestAuthorizationWithMultiplePolicies(t *testing.T) {
-	Convey("Make a new controller", t, func() {
+func TestAuthnLockout(t *testing.T) {
+	Convey("Make a new controller locking out the users failing to authenticate", t, func() {
 		port := test.GetFreePort()
 		baseURL := test.GetBaseURL(port)
 
-		conf := config.New()
-		conf.HTTP.Port = port
-		// have two users: one for  user Policy, and another for default policy
-		username1, seedUser1 := test.GenerateRandomString()
-		password1, seedPass1 := test.GenerateRandomString()
-		username2, seedUser2 := test.GenerateRandomString()
-		password2, seedPass2 := test.GenerateRandomString()
-		content := test.GetCredString(username1, password1) + test.GetCredString(username2, password2)
-
-		htpasswdPath := test.MakeHtpasswdFileFromString(content)
+		admin, adminPassword := "admin", "admin"
+		user, password := "alice", "alice"
 
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(admin, adminPassword) + "\n" +
+			test.GetCredString(user, password))
 		defer os.Remove(htpasswdPath)
 
+		auditPath := path.Join(t.TempDir(), "audit.log")
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.Log.Audit = auditPath
 		conf.HTTP.Auth = &config.AuthConfig{
-			HTPasswd: config.AuthHTPasswd{
-				Path: htpasswdPath,
-			},
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+			Lockout:  &config.LockoutConfig{UserThreshold: 2, IPThreshold: 10, Duration: time.Hour},
+			APIKey:   true,
 		}
-		// config with all policy types, to test that the correct one is applied in each case
 		conf.HTTP.AccessControl = &config.AccessControlConfig{
 			Repositories: config.Repositories{
-				test.AuthorizationAllRepos: config.PolicyGroup{
+				"**": config.PolicyGroup{
 					Policies: []config.Policy{
-						{
-							Users:   []string{},
-							Actions: []string{},
-						},
+						{Users: []string{user}, Actions: []string{constants.ReadPermission}},
 					},
-					DefaultPolicy:   []string{},
-					AnonymousPolicy: []string{},
 				},
 			},
 			AdminPolicy: config.Policy{
-				Users:   []string{},
-				Actions: []string{},
+				Users:   []string{admin},
+				Actions: []string{constants.ReadPermission, constants.CreatePermission},
 			},
 		}
 
-		Convey("with openid", func() {
-			dir := t.TempDir()
-
-			mockOIDCServer, err := authutils.MockOIDCRun()
-			if err != nil {
-				panic(err)
-			}
-
-			defer func() {
-				err := mockOIDCServer.Shutdown()
-				if err != nil {
-					panic(err)
-				}
-			}()
-
-			mockOIDCConfig := mockOIDCServer.Config()
-			conf.HTTP.Auth = &config.AuthConfig{
-				OpenID: &config.OpenIDConfig{
-					Providers: map[string]config.OpenIDProviderConfig{
-						"oidc": {
-							ClientID:     mockOIDCConfig.ClientID,
-							ClientSecret: mockOIDCConfig.ClientSecret,
-							KeyPath:      "",
-							Issuer:       mockOIDCConfig.Issuer,
-							Scopes:       []string{"openid", "email"},
-						},
-					},
-				},
-			}
-
-			ctlr := api.NewController(conf)
-			ctlr.Log.Info().Int64("seedUser1", seedUser1).Int64("seedPass1", seedPass1).
-				Msg("random seed for username & password")
-			ctlr.Log.Info().Int64("seedUser2", seedUser2).Int64("seedPass2", seedPass2).
-				Msg("random seed for username & password")
-
-			ctlr.Config.Storage.RootDirectory = dir
-
-			err = WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "0.0.1",
-				ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
-			So(err, ShouldBeNil)
-
-			cm := test.NewControllerManager(ctlr)
-			cm.StartAndWait(port)
+		ctlr := makeController(conf, t.TempDir())
 
-			defer cm.StopServer()
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
 
-			testUserClient := resty.New()
+		defer cm.StopServer()
 
-			testUserClient.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+		resp, err := resty.R().SetBasicAuth(user, "wrong").Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-			mockOIDCServer.QueueUser(&mockoidc.MockUser{
-				Email:   username1,
-				Subject: "1234567890",
-			})
+		// a success forgets the previous failures
+		resp, err = resty.R().SetBasicAuth(user, password).Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			// first login user
-			resp, err := testUserClient.R().
-				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-				SetQueryParam("provider", "oidc").
-				Get(baseURL + constants.LoginPath)
+		for range 2 {
+			resp, err = resty.R().SetBasicAuth(user, "wrong").Get(baseURL + "/v2/_catalog")
 			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
-
-			testUserClient.SetCookies(resp.Cookies())
-			testUserClient.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
-
-			bobUserClient := resty.New()
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+		}
 
-			bobUserClient.SetRedirectPolicy(test.CustomRedirectPolicy(20))
+		// the valid credentials are rejected during the lockout
+		resp, err = resty.R().SetBasicAuth(user, password).Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-			mockOIDCServer.QueueUser(&mockoidc.MockUser{
-				Email:   username2,
-				Subject: "1234567890",
-			})
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			// first login user
-			resp, err = bobUserClient.R().
-				SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue).
-				SetQueryParam("provider", "oidc").
-				Get(baseURL + constants.LoginPath)
-			So(err, ShouldBeNil)
-			So(resp, ShouldNotBeNil)
-			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+		auditLog, err := os.ReadFile(auditPath)
+		So(err, ShouldBeNil)
+		So(string(auditLog), ShouldContainSubstring, `"action":"lockout","object":"user:alice"`)
 
-			bobUserClient.SetCookies(resp.Cookies())
-			bobUserClient.SetHeader(constants.SessionClientHeaderName, constants.SessionClientHeaderValue)
+		// only the admins lift the lockouts
+		resp, err = resty.R().SetBasicAuth(user, password).SetQueryParam("user", user).
+			Delete(baseURL + constants.LockoutPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
 
-			RunAuthorizationWithMultiplePoliciesTests(t, testUserClient, bobUserClient, baseURL, username1, username2, conf)
-		})
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).Delete(baseURL + constants.LockoutPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
 
-		Convey("with basic auth", func() {
-			dir := t.TempDir()
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).SetQueryParam("user", user).
+			Delete(baseURL + constants.LockoutPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			ctlr := api.NewController(conf)
-			ctlr.Config.Storage.RootDirectory = dir
+		resp, err = resty.R().SetBasicAuth(user, password).Get(baseURL + "/v2/_catalog")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 
-			err := WriteImageToFileSystem(CreateDefaultImage(), "zot-test", "0.0.1",
-				ociutils.GetDefaultStoreController(ctlr.Config.Storage.RootDirectory, ctlr.Log))
-			So(err, ShouldBeNil)
+		resp, err = resty.R().SetBasicAuth(user, password).SetQueryParam("user", admin).
+			Delete(baseURL + constants.LockoutPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
 
-			cm := test.NewControllerManager(ctlr)
-			cm.StartAndWait(port)
+		// the scoped api keys of admins need the admin scope
+		resp, err = resty.R().SetBasicAuth(admin, adminPassword).
+			SetBody(api.APIKeyPayload{Label: "admin", Scopes: []string{"repository:**:read"}}).
+			Post(baseURL + constants.APIKeyPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
 
-			defer cm.StopServer()
+		var apiKeyResponse struct {
+			APIKey string `json:"apiKey"`
+		}
 
-			userClient1 := resty.New()
-			userClient1.SetBasicAuth(username1, password1)
+		err = json.Unmarshal(resp.Body(), &apiKeyResponse)
+		So(err, ShouldBeNil)
 
-			userClient2 := resty.New()
-			userClient2.SetBasicAuth(username2, password2)
+		resp, err = resty.R().SetBasicAuth(admin, apiKeyResponse.APIKey).SetQueryParam("user", user).
+			Delete(baseURL + constants.LockoutPath)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
 
-			RunAuthorizationWithMultiplePoliciesTests(t, userClient1, userClient2, baseURL, username1, username2, conf)
-		})
+		auditLog, err = os.ReadFile(auditPath)
+		So(err, ShouldBeNil)
+		So(string(auditLog), ShouldContainSubstring, `"subject":"admin","action":"clearLockout","object":"user:alice"`)
 	})
 }
 
@@ -6658,40 +8342,69 @@ func TestCrossRepoMount(t *testing.T) {
 			baseURL, constants.RoutePrefix, constants.Blobs, constants.Uploads))
 
 		// Use correct request
-		// This is correct request but it will return 202 because blob is not present in cache.
+		// The blob is not present in cache, but it is mounted from the repository given by "from".
 		params["mount"] = string(manifestDigest)
 		postResponse, err = client.R().
 			SetBasicAuth(username, password).SetQueryParams(params).
 			Post(baseURL + "/v2/zot-c-test/blobs/uploads/")
 		So(err, ShouldBeNil)
-		So(postResponse.StatusCode(), ShouldEqual, http.StatusAccepted)
-		So(test.Location(baseURL, postResponse), ShouldStartWith, fmt.Sprintf("%s%s/zot-c-test/%s/%s",
-			baseURL, constants.RoutePrefix, constants.Blobs, constants.Uploads))
+		So(postResponse.StatusCode(), ShouldEqual, http.StatusCreated)
+		So(test.Location(baseURL, postResponse), ShouldEqual, fmt.Sprintf("%s%s/zot-c-test/%s/%s",
+			baseURL, constants.RoutePrefix, constants.Blobs, manifestDigest))
+		So(postResponse.Header().Get(constants.DistContentDigestKey), ShouldEqual, manifestDigest.String())
+
+		srcFi, err := os.Stat(path.Join(dir, "zot-cve-test", "blobs/sha256", dgst.Encoded()))
+		So(err, ShouldBeNil)
+
+		mountFi, err := os.Stat(path.Join(dir, "zot-c-test", "blobs/sha256", dgst.Encoded()))
+		So(err, ShouldBeNil)
+		So(os.SameFile(srcFi, mountFi), ShouldBeTrue)
 
 		// Send same request again
 		postResponse, err = client.R().
 			SetBasicAuth(username, password).SetQueryParams(params).
 			Post(baseURL + "/v2/zot-c-test/blobs/uploads/")
 		So(err, ShouldBeNil)
-		So(postResponse.StatusCode(), ShouldEqual, http.StatusAccepted)
+		So(postResponse.StatusCode(), ShouldEqual, http.StatusCreated)
 
 		// Valid requests
 		postResponse, err = client.R().
 			SetBasicAuth(username, password).SetQueryParams(params).
 			Post(baseURL + "/v2/zot-d-test/blobs/uploads/")
 		So(err, ShouldBeNil)
-		So(postResponse.StatusCode(), ShouldEqual, http.StatusAccepted)
+		So(postResponse.StatusCode(), ShouldEqual, http.StatusCreated)
 
 		headResponse, err = client.R().SetBasicAuth(username, password).
 			Head(fmt.Sprintf("%s/v2/zot-cv-test/blobs/%s", baseURL, manifestDigest))
 		So(err, ShouldBeNil)
 		So(headResponse.StatusCode(), ShouldEqual, http.StatusNotFound)
 
+		headResponse, err = client.R().SetBasicAuth(username, password).
+			Head(fmt.Sprintf("%s/v2/zot-d-test/blobs/%s", baseURL, manifestDigest))
+		So(err, ShouldBeNil)
+		So(headResponse.StatusCode(), ShouldEqual, http.StatusOK)
+
+		// Source repository without the blob
 		postResponse, err = client.R().
-			SetBasicAuth(username, password).SetQueryParams(params).Post(baseURL + "/v2/zot-c-test/blobs/uploads/")
+			SetBasicAuth(username, password).
+			SetQueryParams(map[string]string{"mount": godigest.FromString("dummy").String(), "from": "zot-c-test"}).
+			Post(baseURL + "/v2/zot-e-test/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(postResponse.StatusCode(), ShouldEqual, http.StatusAccepted)
+
+		// Invalid source repository
+		postResponse, err = client.R().
+			SetBasicAuth(username, password).
+			SetQueryParams(map[string]string{"mount": manifestDigest.String(), "from": "../zot-cve-test"}).
+			Post(baseURL + "/v2/zot-e-test/blobs/uploads/")
 		So(err, ShouldBeNil)
 		So(postResponse.StatusCode(), ShouldEqual, http.StatusAccepted)
 
+		postResponse, err = client.R().
+			SetBasicAuth(username, password).SetQueryParams(params).Post(baseURL + "/v2/zot-c-test/blobs/uploads/")
+		So(err, ShouldBeNil)
+		So(postResponse.StatusCode(), ShouldEqual, http.StatusCreated)
+
 		postResponse, err = client.R().
 			SetBasicAuth(username, password).SetQueryParams(params).
 			Post(baseURL + "/v2/ /blobs/uploads/")
@@ -10920,6 +12633,332 @@ func TestManifestCollision(t *testing.T) {
 	})
 }
 
+func TestStorageQuotas(t *testing.T) {
+	Convey("Make a new controller with storage quotas", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.Storage.Quotas = []config.StorageQuota{
+			{Repositories: []string{"images/*"}, MaxImages: 2},
+			{Repositories: []string{"size"}, MaxSize: 2048},
+		}
+
+		dir := t.TempDir()
+		ctlr := makeController(conf, dir)
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		So(ctlr.MetaDB, ShouldNotBeNil)
+
+		Convey("Images quota is shared by the matching repositories", func() {
+			img := CreateImageWith().RandomLayers(1, 10).DefaultConfig().Build()
+
+			err := UploadImage(img, baseURL, "images/a", "1.0")
+			So(err, ShouldBeNil)
+
+			err = UploadImage(img, baseURL, "images/b", "1.0")
+			So(err, ShouldBeNil)
+
+			resp, err := resty.R().SetHeader("Content-Type", ispec.MediaTypeImageManifest).
+				SetBody(img.ManifestDescriptor.Data).Put(baseURL + "/v2/images/a/manifests/2.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			var apiErrList apiErr.ErrorList
+			err = json.Unmarshal(resp.Body(), &apiErrList)
+			So(err, ShouldBeNil)
+			So(apiErrList.Errors, ShouldHaveLength, 1)
+			So(apiErrList.Errors[0].Code, ShouldEqual, "DENIED")
+			So(apiErrList.Errors[0].Detail["quota"], ShouldEqual, "images/*")
+			So(apiErrList.Errors[0].Detail["reason"], ShouldContainSubstring, "2 images out of 2")
+
+			// existing tags and digests can still be pushed
+			err = UploadImage(img, baseURL, "images/a", "1.0")
+			So(err, ShouldBeNil)
+
+			err = UploadImage(img, baseURL, "images/a", img.DigestStr())
+			So(err, ShouldBeNil)
+
+			// other repositories are not limited
+			err = UploadImage(img, baseURL, "other", "2.0")
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Rejected manifests are not counted", func() {
+			img := CreateImageWith().RandomLayers(1, 10).DefaultConfig().Build()
+
+			// the blobs of the image were not uploaded, the manifest is rejected after the quota check
+			for _, tag := range []string{"1.0", "2.0", "3.0"} {
+				resp, err := resty.R().SetHeader("Content-Type", ispec.MediaTypeImageManifest).
+					SetBody(img.ManifestDescriptor.Data).Put(baseURL + "/v2/images/a/manifests/" + tag)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+			}
+
+			err := UploadImage(img, baseURL, "images/a", "1.0")
+			So(err, ShouldBeNil)
+
+			err = UploadImage(img, baseURL, "images/b", "1.0")
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Size quota is checked on blob uploads", func() {
+			img := CreateImageWith().RandomLayers(1, 100).DefaultConfig().Build()
+
+			err := UploadImage(img, baseURL, "size", "1.0")
+			So(err, ShouldBeNil)
+
+			blob := make([]byte, 2048)
+			digest := godigest.FromBytes(blob)
+
+			// monolithic upload
+			resp, err := resty.R().SetHeader("Content-Length", strconv.Itoa(len(blob))).
+				SetHeader("Content-Type", "application/octet-stream").
+				SetQueryParam("digest", digest.String()).SetBody(blob).
+				Post(baseURL + "/v2/size/blobs/uploads/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+			So(string(resp.Body()), ShouldContainSubstring, "DENIED")
+
+			// chunked upload
+			resp, err = resty.R().Post(baseURL + "/v2/size/blobs/uploads/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+			loc := test.Location(baseURL, resp)
+
+			resp, err = resty.R().SetHeader("Content-Length", strconv.Itoa(len(blob))).
+				SetHeader("Content-Range", fmt.Sprintf("0-%d", len(blob)-1)).
+				SetHeader("Content-Type", "application/octet-stream").SetBody(blob).Patch(loc)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			// streamed upload, the upload is removed once over quota
+			resp, err = resty.R().SetBody(blob).Patch(loc)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().Get(loc)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+			// finishing an upload
+			resp, err = resty.R().Post(baseURL + "/v2/size/blobs/uploads/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+			loc = test.Location(baseURL, resp)
+
+			resp, err = resty.R().SetHeader("Content-Length", strconv.Itoa(len(blob))).
+				SetHeader("Content-Type", "application/octet-stream").
+				SetQueryParam("digest", digest.String()).SetBody(blob).Put(loc)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().Head(baseURL + "/v2/size/blobs/" + digest.String())
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+			// small blobs still fit
+			smallBlob := []byte("small blob")
+
+			resp, err = resty.R().SetHeader("Content-Length", strconv.Itoa(len(smallBlob))).
+				SetHeader("Content-Type", "application/octet-stream").
+				SetQueryParam("digest", godigest.FromBytes(smallBlob).String()).SetBody(smallBlob).
+				Post(baseURL + "/v2/size/blobs/uploads/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+		})
+
+		Convey("Size quota counts the layers of the images and the mounted blobs", func() {
+			img := CreateImageWith().RandomLayers(1, 1500).DefaultConfig().Build()
+
+			err := UploadImage(img, baseURL, "other", "1.0")
+			So(err, ShouldBeNil)
+
+			// the layer is mounted, then counted with the image once tagged
+			layerDigest := img.Manifest.Layers[0].Digest
+
+			resp, err := resty.R().SetQueryParam("mount", layerDigest.String()).SetQueryParam("from", "other").
+				Post(baseURL + "/v2/size/blobs/uploads/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			err = UploadImage(img, baseURL, "size", "1.0")
+			So(err, ShouldBeNil)
+
+			otherImg := CreateImageWith().RandomLayers(1, 1000).DefaultConfig().Build()
+
+			err = UploadImage(otherImg, baseURL, "other", "2.0")
+			So(err, ShouldBeNil)
+
+			// a mount over quota falls back to an upload
+			otherLayerDigest := otherImg.Manifest.Layers[0].Digest
+
+			resp, err = resty.R().SetQueryParam("mount", otherLayerDigest.String()).SetQueryParam("from", "other").
+				Post(baseURL + "/v2/size/blobs/uploads/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+
+			resp, err = resty.R().Head(baseURL + "/v2/size/blobs/" + otherLayerDigest.String())
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+		})
+	})
+}
+
+func TestImmutableTags(t *testing.T) {
+	Convey("Make a new controller with immutable tags", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.Storage.ImmutableTags = []config.ImmutableTagsPolicy{
+			{Repositories: []string{"releases/**"}, Patterns: []string{`^v\d+\.\d+\.\d+$`}},
+		}
+
+		adminUser, adminPassword := "admin", "admin"
+		user, password := "user", "user"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(adminUser, adminPassword) +
+			test.GetCredString(user, password))
+
+		defer os.Remove(htpasswdPath)
+
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{
+				Path: htpasswdPath,
+			},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users:   []string{user},
+							Actions: []string{"read", "create", "update", "delete"},
+						},
+					},
+				},
+			},
+			AdminPolicy: config.Policy{
+				Users:   []string{adminUser},
+				Actions: []string{"read", "create", "update", "delete"},
+			},
+		}
+
+		dir := t.TempDir()
+		ctlr := makeController(conf, dir)
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		img1 := CreateRandomImage()
+		img2 := CreateRandomImage()
+
+		err := UploadImageWithBasicAuth(img1, baseURL, "releases/app", "v1.0.0", user, password)
+		So(err, ShouldBeNil)
+
+		err = UploadImageWithBasicAuth(img1, baseURL, "releases/app", "latest", user, password)
+		So(err, ShouldBeNil)
+
+		Convey("Immutable tags can not be re-pointed", func() {
+			resp, err := resty.R().SetBasicAuth(user, password).
+				SetHeader("Content-Type", ispec.MediaTypeImageManifest).
+				SetBody(img2.ManifestDescriptor.Data).Put(baseURL + "/v2/releases/app/manifests/v1.0.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			var apiErrList apiErr.ErrorList
+			err = json.Unmarshal(resp.Body(), &apiErrList)
+			So(err, ShouldBeNil)
+			So(apiErrList.Errors, ShouldHaveLength, 1)
+			So(apiErrList.Errors[0].Code, ShouldEqual, "DENIED")
+			So(apiErrList.Errors[0].Detail["tag"], ShouldEqual, "v1.0.0")
+
+			// pushing the same manifest again is allowed
+			err = UploadImageWithBasicAuth(img1, baseURL, "releases/app", "v1.0.0", user, password)
+			So(err, ShouldBeNil)
+
+			// mutable and new tags can be pushed
+			err = UploadImageWithBasicAuth(img2, baseURL, "releases/app", "latest", user, password)
+			So(err, ShouldBeNil)
+
+			err = UploadImageWithBasicAuth(img2, baseURL, "releases/app", "v2.0.0", user, password)
+			So(err, ShouldBeNil)
+
+			// only users with admin rights can override the policy
+			resp, err = resty.R().SetBasicAuth(user, password).
+				SetHeader("Content-Type", ispec.MediaTypeImageManifest).SetQueryParam("force", "true").
+				SetBody(img2.ManifestDescriptor.Data).Put(baseURL + "/v2/releases/app/manifests/v1.0.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				SetHeader("Content-Type", ispec.MediaTypeImageManifest).SetQueryParam("force", "true").
+				SetBody(img2.ManifestDescriptor.Data).Put(baseURL + "/v2/releases/app/manifests/v1.0.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+			So(resp.Header().Get(constants.DistContentDigestKey), ShouldEqual, img2.DigestStr())
+		})
+
+		Convey("Immutable tags can not be deleted", func() {
+			resp, err := resty.R().SetBasicAuth(user, password).
+				Delete(baseURL + "/v2/releases/app/manifests/v1.0.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			// deleting by digest would remove the immutable tag as well
+			resp, err = resty.R().SetBasicAuth(user, password).
+				Delete(baseURL + "/v2/releases/app/manifests/" + img1.DigestStr())
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(user, password).
+				Delete(baseURL + "/v2/releases/app/manifests/latest")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+
+			resp, err = resty.R().SetBasicAuth(user, password).SetQueryParam("force", "true").
+				Delete(baseURL + "/v2/releases/app/manifests/v1.0.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).SetQueryParam("force", "true").
+				Delete(baseURL + "/v2/releases/app/manifests/v1.0.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+		})
+
+		Convey("Reloaded immutable tags policies apply", func() {
+			newConf := *ctlr.Config
+			newConf.Storage.ImmutableTags = nil
+
+			ctlr.LoadNewConfig(&newConf)
+			So(ctlr.GetImmutableTags(storage.DefaultStorePath), ShouldBeNil)
+
+			err := UploadImageWithBasicAuth(img2, baseURL, "releases/app", "v1.0.0", user, password)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Other repositories are not affected", func() {
+			err := UploadImageWithBasicAuth(img1, baseURL, "app", "v1.0.0", user, password)
+			So(err, ShouldBeNil)
+
+			err = UploadImageWithBasicAuth(img2, baseURL, "app", "v1.0.0", user, password)
+			So(err, ShouldBeNil)
+
+			resp, err := resty.R().SetBasicAuth(user, password).Delete(baseURL + "/v2/app/manifests/v1.0.0")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+		})
+	})
+}
+
 func TestPullRange(t *testing.T) {
 	Convey("Make a new controller", t, func() {
 		port := test.GetFreePort()
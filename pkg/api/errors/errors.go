@@ -9,10 +9,14 @@ type Error struct {
 	Message     string            `json:"message"`
 	Description string            `json:"-"`
 	Detail      map[string]string `json:"detail"`
+	// id of the request which failed, to find its logs
+	RequestID string `json:"requestID,omitempty"`
 }
 
 type ErrorList struct {
 	Errors []*Error `json:"errors"`
+	// id of the request which failed, to find its logs
+	RequestID string `json:"requestID,omitempty"`
 }
 
 type ErrorCode int
@@ -167,5 +171,5 @@ func NewErrorList(errors ...*Error) ErrorList {
 	var errList []*Error
 	errList = append(errList, errors...)
 
-	return ErrorList{errList}
+	return ErrorList{Errors: errList}
 }
@@ -11,14 +11,15 @@ import (
 	"syscall"
 
 	"github.com/fsnotify/fsnotify"
-	"golang.org/x/crypto/bcrypt"
 
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/accounts"
 	"zotregistry.dev/zot/pkg/log"
 )
 
 // HTPasswd user auth store
 //
-// Currently supports only bcrypt hashes.
+// Currently supports bcrypt and argon2id hashes.
 type HTPasswd struct {
 	mu      sync.RWMutex
 	credMap map[string]string
@@ -87,12 +88,12 @@ func (s *HTPasswd) Authenticate(username, passphrase string) (ok, present bool)
 		return false, false
 	}
 
-	err := bcrypt.CompareHashAndPassword([]byte(passphraseHash), []byte(passphrase))
+	err := accounts.ComparePassword(passphraseHash, passphrase)
 	ok = err == nil
 
-	if err != nil && !errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+	if err != nil && !errors.Is(err, zerr.ErrPasswordMismatch) {
 		// Log that user's hash has unsupported format. Better than silently return 401.
-		s.log.Warn().Err(err).Str("username", username).Msg("htpasswd bcrypt compare failed")
+		s.log.Warn().Err(err).Str("username", username).Msg("htpasswd hash compare failed")
 	}
 
 	return
@@ -140,7 +141,15 @@ func NewHTPasswdWatcher(htp *HTPasswd, filePath string) (*HTPasswdWatcher, error
 		for {
 			select {
 			case ev := <-ret.watcher.Events:
-				if ev.Op != fsnotify.Write {
+				// the file replaced by renaming another one over it, as done by the mgmt api and many editors,
+				// is no longer watched
+				if ev.Has(fsnotify.Remove) || ev.Has(fsnotify.Rename) {
+					if err := ret.watcher.Add(ev.Name); err != nil {
+						ret.log.Warn().Err(err).Str("htpasswd-file", ev.Name).Msg("failed to watch replaced file")
+
+						continue
+					}
+				} else if ev.Op != fsnotify.Write {
 					continue
 				}
 
@@ -169,7 +178,7 @@ func NewHTPasswdWatcher(htp *HTPasswd, filePath string) (*HTPasswdWatcher, error
 func (s *HTPasswdWatcher) ChangeFile(filePath string) error {
 	if s.filePath != "" {
 		err := s.watcher.Remove(s.filePath)
-		if err != nil {
+		if err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
 			return err
 		}
 	}
@@ -7,6 +7,7 @@ import (
 
 	. "github.com/smartystreets/goconvey/convey"
 
+	"zotregistry.dev/zot/pkg/accounts"
 	"zotregistry.dev/zot/pkg/api"
 	"zotregistry.dev/zot/pkg/log"
 	test "zotregistry.dev/zot/pkg/test/common"
@@ -60,5 +61,27 @@ func TestHTPasswdWatcher(t *testing.T) {
 		ok, present = htp.Authenticate(username, password2)
 		So(ok, ShouldBeTrue)
 		So(present, ShouldBeTrue)
+
+		// 5. Replace the file atomically, it is still watched afterwards
+		argon2idHash, err := accounts.HashPassword(accounts.Argon2idAlgorithm, password1)
+		So(err, ShouldBeNil)
+
+		err = accounts.WriteFileAtomically(htpasswdPath, []byte(username+":"+argon2idHash+"\n"))
+		So(err, ShouldBeNil)
+
+		time.Sleep(10 * time.Millisecond)
+
+		ok, present = htp.Authenticate(username, password1)
+		So(ok, ShouldBeTrue)
+		So(present, ShouldBeTrue)
+
+		err = os.WriteFile(htpasswdPath, []byte(test.GetCredString(username, password2)), 0o600)
+		So(err, ShouldBeNil)
+
+		time.Sleep(10 * time.Millisecond)
+
+		ok, present = htp.Authenticate(username, password2)
+		So(ok, ShouldBeTrue)
+		So(present, ShouldBeTrue)
 	})
 }
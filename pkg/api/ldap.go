@@ -6,15 +6,34 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/go-ldap/ldap/v3"
 
 	"zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/extensions/monitoring"
 	"zotregistry.dev/zot/pkg/log"
 )
 
+const (
+	defaultLDAPServerRetryInterval = time.Minute
+	defaultLDAPGroupFilter         = "(member={dn})"
+	// LDAP_MATCHING_RULE_IN_CHAIN of active directory, which also matches the groups of the nested groups.
+	nestedLDAPGroupFilter     = "(member:1.2.840.113556.1.4.1941:={dn})"
+	defaultLDAPGroupAttribute = "cn"
+	ldapGroupsCacheHit        = "hit"
+	ldapGroupsCacheMiss       = "miss"
+	ldapOperationConnect      = "connect"
+	ldapOperationBind         = "bind"
+	ldapOperationSearch       = "search"
+	ldapOperationGroupSearch  = "groupsearch"
+)
+
 type LDAPClient struct {
 	InsecureSkipVerify bool
 	UseSSL             bool
@@ -25,75 +44,156 @@ type LDAPClient struct {
 	Base               string
 	BindDN             string
 	BindPassword       string
-	GroupFilter        string // e.g. "(memberUid=%s)"
+	GroupFilter        string // e.g. "(member={dn})", {dn} and {username} being replaced by the user dn and name
+	GroupBase          string // the group search is done only if set
+	GroupAttribute     string // e.g. "cn"
+	NestedGroups       bool   // also search the nested groups, if GroupFilter is not set
+	GroupsCacheTTL     time.Duration
 	UserGroupAttribute string // e.g. "memberOf"
 	Host               string
+	Servers            []string      // e.g. "ldap1.example.com:636", Host:Port if not set
+	ServerRetry        time.Duration // how long a server which could not be reached is tried after the others
 	ServerName         string
 	UserFilter         string // e.g. "(!(nsaccountlock=TRUE))"
 	UserAttribute      string // e.g. "uid"
 	Conn               *ldap.Conn
 	ClientCertificates []tls.Certificate // Adding client certificates
 	ClientCAs          *x509.CertPool
+	Metrics            monitoring.MetricServer
 	Log                log.Logger
 	lock               sync.Mutex
+	server             string               // the server Conn is connected to
+	serversDownUntil   map[string]time.Time // servers which could not be reached and are tried last
+	groupsCache        map[string]ldapCachedGroups
+}
+
+type ldapCachedGroups struct {
+	groups    []string
+	expiresAt time.Time
+}
+
+// servers returns the ldap servers in the order they should be tried,
+// the ones which could not be reached recently being tried last.
+func (lc *LDAPClient) servers() []string {
+	servers := lc.Servers
+	if len(servers) == 0 {
+		servers = []string{net.JoinHostPort(lc.Host, strconv.Itoa(lc.Port))}
+	}
+
+	upServers := make([]string, 0, len(servers))
+	downServers := []string{}
+
+	for _, server := range servers {
+		if time.Now().Before(lc.serversDownUntil[server]) {
+			downServers = append(downServers, server)
+		} else {
+			upServers = append(upServers, server)
+		}
+	}
+
+	return append(upServers, downServers...)
 }
 
-// Connect connects to the ldap backend.
+// markServerDown makes the server be tried after the others during the server retry interval.
+func (lc *LDAPClient) markServerDown(server string) {
+	if lc.serversDownUntil == nil {
+		lc.serversDownUntil = map[string]time.Time{}
+	}
+
+	retryInterval := lc.ServerRetry
+	if retryInterval == 0 {
+		retryInterval = defaultLDAPServerRetryInterval
+	}
+
+	lc.serversDownUntil[server] = time.Now().Add(retryInterval)
+}
+
+func (lc *LDAPClient) observeLatency(operation string, start time.Time) {
+	if lc.Metrics != nil {
+		monitoring.ObserveLDAPRequestLatency(lc.Metrics, time.Since(start), operation)
+	}
+}
+
+// Connect connects to the first ldap backend which can be reached.
 func (lc *LDAPClient) Connect() error {
-	if lc.Conn == nil {
-		var l *ldap.Conn
+	if lc.Conn != nil {
+		return nil
+	}
 
-		var err error
+	var err error
 
-		address := fmt.Sprintf("%s:%d", lc.Host, lc.Port)
+	for _, server := range lc.servers() {
+		err = lc.connect(server)
+		if err == nil {
+			return nil
+		}
 
-		if !lc.UseSSL {
-			l, err = ldap.Dial("tcp", address) //nolint:staticcheck
-			if err != nil {
-				lc.Log.Error().Err(err).Str("address", address).Msg("failed to establish a TCP connection")
+		lc.markServerDown(server)
+	}
 
-				return err
-			}
+	return err
+}
 
-			// Reconnect with TLS
-			if !lc.SkipTLS {
-				config := &tls.Config{
-					InsecureSkipVerify: lc.InsecureSkipVerify, //nolint: gosec // InsecureSkipVerify is not true by default
-					RootCAs:            lc.ClientCAs,
-				}
+func (lc *LDAPClient) connect(address string) error {
+	var l *ldap.Conn
 
-				if len(lc.ClientCertificates) > 0 {
-					config.Certificates = lc.ClientCertificates
-				}
+	var err error
 
-				err = l.StartTLS(config)
-				if err != nil {
-					lc.Log.Error().Err(err).Str("address", address).Msg("failed to establish a TLS connection")
+	defer lc.observeLatency(ldapOperationConnect, time.Now())
 
-					return err
-				}
-			}
-		} else {
+	serverName := lc.ServerName
+	if len(lc.Servers) > 0 {
+		serverName, _, _ = net.SplitHostPort(address)
+	}
+
+	if !lc.UseSSL {
+		l, err = ldap.Dial("tcp", address) //nolint:staticcheck
+		if err != nil {
+			lc.Log.Error().Err(err).Str("address", address).Msg("failed to establish a TCP connection")
+
+			return err
+		}
+
+		// Reconnect with TLS
+		if !lc.SkipTLS {
 			config := &tls.Config{
 				InsecureSkipVerify: lc.InsecureSkipVerify, //nolint: gosec // InsecureSkipVerify is not true by default
-				ServerName:         lc.ServerName,
 				RootCAs:            lc.ClientCAs,
 			}
+
 			if len(lc.ClientCertificates) > 0 {
 				config.Certificates = lc.ClientCertificates
 			}
 
-			l, err = ldap.DialTLS("tcp", address, config) //nolint:staticcheck
+			err = l.StartTLS(config)
 			if err != nil {
 				lc.Log.Error().Err(err).Str("address", address).Msg("failed to establish a TLS connection")
+				l.Close()
 
 				return err
 			}
 		}
+	} else {
+		config := &tls.Config{
+			InsecureSkipVerify: lc.InsecureSkipVerify, //nolint: gosec // InsecureSkipVerify is not true by default
+			ServerName:         serverName,
+			RootCAs:            lc.ClientCAs,
+		}
+		if len(lc.ClientCertificates) > 0 {
+			config.Certificates = lc.ClientCertificates
+		}
+
+		l, err = ldap.DialTLS("tcp", address, config) //nolint:staticcheck
+		if err != nil {
+			lc.Log.Error().Err(err).Str("address", address).Msg("failed to establish a TLS connection")
 
-		lc.Conn = l
+			return err
+		}
 	}
 
+	lc.Conn = l
+	lc.server = address
+
 	return nil
 }
 
@@ -102,6 +202,7 @@ func (lc *LDAPClient) Close() {
 	if lc.Conn != nil {
 		lc.Conn.Close()
 		lc.Conn = nil
+		lc.server = ""
 	}
 }
 
@@ -140,26 +241,18 @@ func (lc *LDAPClient) Authenticate(username, password string) (bool, map[string]
 		}
 
 		// First bind with a read only user
-		if lc.BindPassword != "" {
-			err := lc.Conn.Bind(lc.BindDN, lc.BindPassword)
-			if err != nil {
-				lc.Log.Error().Err(err).Str("bindDN", lc.BindDN).Msg("failed to bind")
-				// clean up the cached conn, so we can retry
-				lc.Conn.Close()
-				lc.Conn = nil
+		err = lc.bindServiceAccount()
+		if err != nil {
+			lc.Log.Error().Err(err).Str("bindDN", lc.BindDN).Str("address", lc.server).Msg("failed to bind")
 
-				continue
+			if ldap.IsErrorWithCode(err, ldap.ErrorNetwork) {
+				lc.markServerDown(lc.server)
 			}
-		} else {
-			err := lc.Conn.UnauthenticatedBind(lc.BindDN)
-			if err != nil {
-				lc.Log.Error().Err(err).Str("bindDN", lc.BindDN).Msg("failed to bind")
-				// clean up the cached conn, so we can retry
-				lc.Conn.Close()
-				lc.Conn = nil
 
-				continue
-			}
+			// clean up the cached conn, so we can retry
+			lc.Close()
+
+			continue
 		}
 
 		connected = true
@@ -193,7 +286,11 @@ func (lc *LDAPClient) Authenticate(username, password string) (bool, map[string]
 		nil,
 	)
 
+	start := time.Now()
 	search, err := lc.Conn.Search(searchRequest)
+
+	lc.observeLatency(ldapOperationSearch, start)
+
 	if err != nil {
 		fmt.Printf("%v\n", err)
 		lc.Log.Error().Err(err).Str("bindDN", lc.BindDN).Str("username", username).
@@ -220,31 +317,164 @@ func (lc *LDAPClient) Authenticate(username, password string) (bool, map[string]
 
 	userDN := search.Entries[0].DN
 
-	var userGroups []string
-
-	if lc.UserGroupAttribute != "" && len(search.Entries[0].Attributes) > 0 {
-		for _, attr := range search.Entries[0].Attributes {
-			userGroups = append(userGroups, attr.Values...)
-		}
-	}
-
 	user := map[string]string{}
 
 	for _, attr := range lc.Attributes {
 		user[attr] = search.Entries[0].GetAttributeValue(attr)
 	}
 
+	userGroups, cached := lc.cachedGroups(username)
+	if !cached {
+		if lc.UserGroupAttribute != "" && len(search.Entries[0].Attributes) > 0 {
+			for _, attr := range search.Entries[0].Attributes {
+				userGroups = append(userGroups, attr.Values...)
+			}
+		}
+
+		searchedGroups, err := lc.searchGroups(username, userDN)
+		if err != nil {
+			lc.Log.Error().Err(err).Str("bindDN", lc.BindDN).Str("username", username).
+				Str("groupBaseDN", lc.GroupBase).Msg("failed to search the groups of the user")
+
+			return false, user, nil, err
+		}
+
+		for _, group := range searchedGroups {
+			if !slices.Contains(userGroups, group) {
+				userGroups = append(userGroups, group)
+			}
+		}
+	}
+
 	// Bind as the user to verify their password
+	start = time.Now()
 	err = lc.Conn.Bind(userDN, password)
+
+	lc.observeLatency(ldapOperationBind, start)
+
 	if err != nil {
 		lc.Log.Error().Err(err).Str("bindDN", userDN).Msg("failed to bind user")
 
 		return false, user, userGroups, err
 	}
 
+	if !cached {
+		lc.cacheGroups(username, userGroups)
+	}
+
 	return true, user, userGroups, nil
 }
 
+func (lc *LDAPClient) bindServiceAccount() error {
+	defer lc.observeLatency(ldapOperationBind, time.Now())
+
+	if lc.BindPassword != "" {
+		return lc.Conn.Bind(lc.BindDN, lc.BindPassword)
+	}
+
+	return lc.Conn.UnauthenticatedBind(lc.BindDN)
+}
+
+// searchGroups returns the names of the groups matching the group filter under the group base dn.
+func (lc *LDAPClient) searchGroups(username, userDN string) ([]string, error) {
+	if lc.GroupBase == "" {
+		return []string{}, nil
+	}
+
+	groupAttribute := lc.GroupAttribute
+	if groupAttribute == "" {
+		groupAttribute = defaultLDAPGroupAttribute
+	}
+
+	searchRequest := ldap.NewSearchRequest(
+		lc.GroupBase,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		lc.groupFilter(username, userDN),
+		[]string{groupAttribute},
+		nil,
+	)
+
+	defer lc.observeLatency(ldapOperationGroupSearch, time.Now())
+
+	search, err := lc.Conn.Search(searchRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := []string{}
+
+	for _, entry := range search.Entries {
+		group := entry.GetAttributeValue(groupAttribute)
+		if group != "" && !slices.Contains(groups, group) {
+			groups = append(groups, group)
+		}
+	}
+
+	return groups, nil
+}
+
+func (lc *LDAPClient) groupFilter(username, userDN string) string {
+	filter := lc.GroupFilter
+	if filter == "" {
+		filter = defaultLDAPGroupFilter
+
+		if lc.NestedGroups {
+			filter = nestedLDAPGroupFilter
+		}
+	}
+
+	return strings.NewReplacer(
+		"{dn}", ldap.EscapeFilter(userDN),
+		"{username}", ldap.EscapeFilter(username),
+	).Replace(filter)
+}
+
+// cachedGroups returns the groups of the user if they were cached less than GroupsCacheTTL ago.
+func (lc *LDAPClient) cachedGroups(username string) ([]string, bool) {
+	if lc.GroupsCacheTTL <= 0 {
+		return nil, false
+	}
+
+	cachedGroups, ok := lc.groupsCache[username]
+	if !ok || time.Now().After(cachedGroups.expiresAt) {
+		lc.incGroupsCacheRequests(ldapGroupsCacheMiss)
+
+		return nil, false
+	}
+
+	lc.incGroupsCacheRequests(ldapGroupsCacheHit)
+
+	return slices.Clone(cachedGroups.groups), true
+}
+
+func (lc *LDAPClient) cacheGroups(username string, groups []string) {
+	if lc.GroupsCacheTTL <= 0 {
+		return
+	}
+
+	if lc.groupsCache == nil {
+		lc.groupsCache = map[string]ldapCachedGroups{}
+	}
+
+	// drop the expired entries, so that the cache does not grow with the users who stopped logging in
+	for cachedUsername, cachedGroups := range lc.groupsCache {
+		if time.Now().After(cachedGroups.expiresAt) {
+			delete(lc.groupsCache, cachedUsername)
+		}
+	}
+
+	lc.groupsCache[username] = ldapCachedGroups{
+		groups:    slices.Clone(groups),
+		expiresAt: time.Now().Add(lc.GroupsCacheTTL),
+	}
+}
+
+func (lc *LDAPClient) incGroupsCacheRequests(result string) {
+	if lc.Metrics != nil {
+		monitoring.IncLDAPGroupsCacheRequests(lc.Metrics, result)
+	}
+}
+
 func (lc *LDAPClient) userFilter(username string) string {
 	filter := fmt.Sprintf("(%s=%s)", lc.UserAttribute, ldap.EscapeFilter(username))
 
@@ -0,0 +1,213 @@
+//go:build sync && scrub && metrics && search && lint && userprefs && mgmt && imagetrust && ui
+// +build sync,scrub,metrics,search,lint,userprefs,mgmt,imagetrust,ui
+
+package api_test
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	vldap "github.com/nmcclain/ldap"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/resty.v1"
+
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/extensions/monitoring"
+	"zotregistry.dev/zot/pkg/log"
+	test "zotregistry.dev/zot/pkg/test/common"
+)
+
+const LDAPGroupsBaseDN = "ou=groups"
+
+var searchedGroup = "searched-" + group //nolint: gochecknoglobals
+
+// groupsLDAPServer serves the test user and the groups of its group search.
+type groupsLDAPServer struct {
+	*testLDAPServer
+	groupSearches atomic.Int32
+	groupFilters  chan string
+}
+
+func newGroupsLDAPServer() *groupsLDAPServer {
+	ldaps := &groupsLDAPServer{testLDAPServer: newTestLDAPServer(), groupFilters: make(chan string, 10)}
+	ldaps.server.SearchFunc("", ldaps)
+
+	return ldaps
+}
+
+func (l *groupsLDAPServer) Search(boundDN string, req vldap.SearchRequest,
+	conn net.Conn,
+) (vldap.ServerSearchResult, error) {
+	if req.BaseDN != LDAPGroupsBaseDN {
+		return l.testLDAPServer.Search(boundDN, req, conn)
+	}
+
+	l.groupSearches.Add(1)
+
+	select {
+	case l.groupFilters <- req.Filter:
+	default:
+	}
+
+	groups := []string{group, searchedGroup}
+	entries := []*vldap.Entry{}
+
+	for _, group := range groups {
+		entries = append(entries, &vldap.Entry{
+			DN:         fmt.Sprintf("cn=%s,%s", group, LDAPGroupsBaseDN),
+			Attributes: []*vldap.EntryAttribute{{Name: "cn", Values: []string{group}}},
+		})
+	}
+
+	return vldap.ServerSearchResult{Entries: entries, ResultCode: vldap.LDAPResultSuccess}, nil
+}
+
+func TestLDAPClientGroupSearch(t *testing.T) {
+	Convey("Make a new ldap server", t, func() {
+		ldapServer := newGroupsLDAPServer()
+		ldapPort, err := strconv.Atoi(test.GetFreePort())
+		So(err, ShouldBeNil)
+		ldapServer.Start(ldapPort)
+
+		defer ldapServer.Stop()
+
+		ldapClient := &api.LDAPClient{
+			SkipTLS:       true,
+			Servers:       []string{net.JoinHostPort(LDAPAddress, strconv.Itoa(ldapPort))},
+			Base:          LDAPBaseDN,
+			BindDN:        LDAPBindDN,
+			BindPassword:  LDAPBindPassword,
+			UserAttribute: "uid",
+			GroupBase:     LDAPGroupsBaseDN,
+			Metrics:       monitoring.NewMetricsServer(true, log.NewLogger("debug", "")),
+			Log:           log.NewLogger("debug", ""),
+		}
+
+		defer ldapClient.Close()
+
+		Convey("Test the default group filter", func() {
+			ok, _, groups, err := ldapClient.Authenticate(username, password)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			So(groups, ShouldResemble, []string{group, searchedGroup})
+			So(<-ldapServer.groupFilters, ShouldEqual, fmt.Sprintf("(member=cn=%s,%s)", username, LDAPBaseDN))
+		})
+
+		Convey("Test a custom group filter", func() {
+			ldapClient.GroupFilter = "(memberUid={username})"
+
+			ok, _, groups, err := ldapClient.Authenticate(username, password)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			So(groups, ShouldResemble, []string{group, searchedGroup})
+			So(<-ldapServer.groupFilters, ShouldEqual, fmt.Sprintf("(memberUid=%s)", username))
+		})
+
+		Convey("Test the groups of the user entry are kept", func() {
+			ldapClient.UserGroupAttribute = "memberOf"
+
+			ok, _, groups, err := ldapClient.Authenticate(username, password)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			So(groups, ShouldResemble, []string{group, searchedGroup})
+		})
+
+		Convey("Test the groups cache", func() {
+			ldapClient.GroupsCacheTTL = time.Second
+
+			for range 3 {
+				ok, _, groups, err := ldapClient.Authenticate(username, password)
+				So(err, ShouldBeNil)
+				So(ok, ShouldBeTrue)
+				So(groups, ShouldResemble, []string{group, searchedGroup})
+			}
+
+			So(ldapServer.groupSearches.Load(), ShouldEqual, 1)
+
+			// the password is still checked when the groups are cached
+			ok, _, _, err := ldapClient.Authenticate(username, "wrong")
+			So(err, ShouldNotBeNil)
+			So(ok, ShouldBeFalse)
+
+			// the groups are searched again once they expired
+			time.Sleep(ldapClient.GroupsCacheTTL)
+
+			ok, _, _, err = ldapClient.Authenticate(username, password)
+			So(err, ShouldBeNil)
+			So(ok, ShouldBeTrue)
+			So(ldapServer.groupSearches.Load(), ShouldEqual, 2)
+		})
+	})
+}
+
+func TestLDAPServerFailover(t *testing.T) {
+	Convey("Make a new controller with a down ldap server", t, func() {
+		ldapServer := newGroupsLDAPServer()
+		ldapPort, err := strconv.Atoi(test.GetFreePort())
+		So(err, ShouldBeNil)
+		ldapServer.Start(ldapPort)
+
+		defer ldapServer.Stop()
+
+		// nothing listens on the first server
+		downAddress := net.JoinHostPort(LDAPAddress, test.GetFreePort())
+
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			LDAP: (&config.LDAPConfig{
+				Insecure:      true,
+				Addresses:     []string{downAddress, LDAPAddress},
+				Port:          ldapPort,
+				BaseDN:        LDAPBaseDN,
+				UserAttribute: "uid",
+				GroupSearch: &config.LDAPGroupSearchConfig{
+					BaseDN: LDAPGroupsBaseDN,
+				},
+				GroupsCacheTTL: time.Hour,
+			}).SetBindDN(LDAPBindDN).SetBindPassword(LDAPBindPassword),
+		}
+		// the repository can only be read through the searched group
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{Groups: []string{searchedGroup}, Actions: []string{"read"}},
+					},
+				},
+			},
+		}
+
+		So(conf.HTTP.Auth.LDAP.LDAPServers(), ShouldResemble, []string{
+			downAddress, net.JoinHostPort(LDAPAddress, strconv.Itoa(ldapPort)),
+		})
+
+		ctlr := makeController(conf, t.TempDir())
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		for range 2 {
+			resp, err := resty.R().SetBasicAuth(username, password).Get(baseURL + "/v2/zot-test/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+		}
+
+		So(ldapServer.groupSearches.Load(), ShouldEqual, 1)
+
+		resp, err := resty.R().SetBasicAuth(username, "wrong").Get(baseURL + "/v2/zot-test/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,418 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+const (
+	defaultLockoutUserThreshold = 5
+	defaultLockoutIPThreshold   = 20
+	defaultLockoutWindow        = 15 * time.Minute
+	defaultLockoutDuration      = time.Minute
+	defaultLockoutMaxDuration   = time.Hour
+	// the lockouts doubling the next one are forgotten after this period
+	lockoutBackoffPeriod = 24 * time.Hour
+	// the in memory entries are pruned when there are more of them
+	maxLockoutEntries = 10000
+)
+
+// AuthLockout counts the authentication failures per user and client ip, locking them out once they
+// reach their threshold within the window, for a duration doubling with each repeated lockout.
+type AuthLockout struct {
+	userThreshold int
+	ipThreshold   int
+	window        time.Duration
+	duration      time.Duration
+	maxDuration   time.Duration
+	// foldUsernames counts the failures of the usernames differing only by case together, for the
+	// authentication backends matching them case-insensitively, such as ldap
+	foldUsernames bool
+	store         lockoutStore
+	audit         *log.Logger
+	log           log.Logger
+}
+
+// lockoutStore keeps the failures and lockouts of the keys identifying users and client ips.
+type lockoutStore interface {
+	// addFailure counts a failure of key, returning the number of failures within the window
+	addFailure(ctx context.Context, key string, window time.Duration) (int, error)
+	// lockOut locks key out for the duration given its number of lockouts, returning this duration
+	lockOut(ctx context.Context, key string, duration func(lockouts int) time.Duration) (time.Duration, error)
+	// lockedFor returns how long key is still locked out
+	lockedFor(ctx context.Context, key string) (time.Duration, error)
+	// reset forgets the failures and lockouts of key
+	reset(ctx context.Context, key string) error
+	close() error
+}
+
+// NewAuthLockout returns a lockout counting the failures in redis if client is set, in memory otherwise.
+// The usernames differing only by case are the same user if foldUsernames is set.
+func NewAuthLockout(lockoutConfig *config.LockoutConfig, foldUsernames bool, client redis.UniversalClient,
+	keyPrefix string, audit *log.Logger, log log.Logger,
+) *AuthLockout {
+	lockout := &AuthLockout{
+		userThreshold: lockoutConfig.UserThreshold,
+		ipThreshold:   lockoutConfig.IPThreshold,
+		window:        lockoutConfig.Window,
+		duration:      lockoutConfig.Duration,
+		maxDuration:   lockoutConfig.MaxDuration,
+		foldUsernames: foldUsernames,
+		audit:         audit,
+		log:           log,
+	}
+
+	if lockout.userThreshold == 0 {
+		lockout.userThreshold = defaultLockoutUserThreshold
+	}
+
+	if lockout.ipThreshold == 0 {
+		lockout.ipThreshold = defaultLockoutIPThreshold
+	}
+
+	if lockout.window == 0 {
+		lockout.window = defaultLockoutWindow
+	}
+
+	if lockout.duration == 0 {
+		lockout.duration = defaultLockoutDuration
+	}
+
+	if lockout.maxDuration == 0 {
+		lockout.maxDuration = max(defaultLockoutMaxDuration, lockout.duration)
+	}
+
+	if client != nil {
+		lockout.store = &redisLockoutStore{client: client, keyPrefix: keyPrefix}
+	} else {
+		lockout.store = &memoryLockoutStore{entries: map[string]*lockoutEntry{}}
+	}
+
+	return lockout
+}
+
+// LockedFor returns how long the user or the client ip are still locked out, zero if neither is.
+func (al *AuthLockout) LockedFor(ctx context.Context, username, clientIP string) time.Duration {
+	var remaining time.Duration
+
+	for _, key := range al.lockoutKeys(username, clientIP) {
+		keyRemaining, err := al.store.lockedFor(ctx, key)
+		if err != nil {
+			// brute-force protection failing should not prevent every user from logging in
+			al.log.Error().Err(err).Str("key", key).Msg("failed to get authentication lockout")
+
+			continue
+		}
+
+		remaining = max(remaining, keyRemaining)
+	}
+
+	return remaining
+}
+
+// RecordFailure counts an authentication failure of the user from the client ip.
+func (al *AuthLockout) RecordFailure(ctx context.Context, username, clientIP string) {
+	for _, key := range al.lockoutKeys(username, clientIP) {
+		threshold := al.userThreshold
+		if key == ipLockoutKey(clientIP) {
+			threshold = al.ipThreshold
+		}
+
+		failures, err := al.store.addFailure(ctx, key, al.window)
+		if err != nil {
+			al.log.Error().Err(err).Str("key", key).Msg("failed to record authentication failure")
+
+			continue
+		}
+
+		if failures < threshold {
+			continue
+		}
+
+		duration, err := al.store.lockOut(ctx, key, al.lockoutDuration)
+		if err != nil {
+			al.log.Error().Err(err).Str("key", key).Msg("failed to lock out after authentication failures")
+
+			continue
+		}
+
+		al.log.Warn().Str("key", key).Str("clientIP", clientIP).Str("username", username).
+			Int("failures", failures).Str("duration", duration.String()).
+			Msg("too many authentication failures, locked out")
+
+		if al.audit != nil {
+			al.audit.Info().
+				Str("component", "authn").
+				Str("clientIP", clientIP).
+				Str("subject", username).
+				Str("action", "lockout").
+				Str("object", key).
+				Str("duration", duration.String()).
+				Msg("HTTP API Audit")
+		}
+	}
+}
+
+// RecordSuccess forgets the failures of a user who authenticated.
+func (al *AuthLockout) RecordSuccess(ctx context.Context, username string) {
+	if username == "" {
+		return
+	}
+
+	if err := al.store.reset(ctx, al.userLockoutKey(username)); err != nil {
+		al.log.Error().Err(err).Str("username", username).Msg("failed to reset authentication failures")
+	}
+}
+
+// Clear lifts the lockouts of the user and the client ip, on behalf of admin.
+func (al *AuthLockout) Clear(ctx context.Context, username, clientIP, admin string) error {
+	for _, key := range al.lockoutKeys(username, clientIP) {
+		if err := al.store.reset(ctx, key); err != nil {
+			return err
+		}
+
+		al.log.Info().Str("key", key).Str("admin", admin).Msg("cleared authentication lockout")
+
+		if al.audit != nil {
+			al.audit.Info().
+				Str("component", "authn").
+				Str("subject", admin).
+				Str("action", "clearLockout").
+				Str("object", key).
+				Msg("HTTP API Audit")
+		}
+	}
+
+	return nil
+}
+
+func (al *AuthLockout) Close() error {
+	return al.store.close()
+}
+
+// lockoutDuration doubles the duration of the first lockout for each previous one, up to the longest lockout.
+func (al *AuthLockout) lockoutDuration(lockouts int) time.Duration {
+	duration := al.duration
+
+	for range lockouts - 1 {
+		if duration >= al.maxDuration {
+			break
+		}
+
+		duration *= 2
+	}
+
+	return min(duration, al.maxDuration)
+}
+
+func (al *AuthLockout) lockoutKeys(username, clientIP string) []string {
+	keys := []string{}
+
+	if username != "" {
+		keys = append(keys, al.userLockoutKey(username))
+	}
+
+	if clientIP != "" {
+		keys = append(keys, ipLockoutKey(clientIP))
+	}
+
+	return keys
+}
+
+func (al *AuthLockout) userLockoutKey(username string) string {
+	if al.foldUsernames {
+		username = strings.ToLower(username)
+	}
+
+	return "user:" + username
+}
+
+func ipLockoutKey(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+type lockoutEntry struct {
+	failures    int
+	windowEnd   time.Time
+	lockouts    int
+	backoffEnd  time.Time
+	lockedUntil time.Time
+}
+
+// expired returns true if the entry no longer holds any failure or lockout.
+func (entry *lockoutEntry) expired(now time.Time) bool {
+	return now.After(entry.windowEnd) && now.After(entry.backoffEnd) && now.After(entry.lockedUntil)
+}
+
+type memoryLockoutStore struct {
+	entries map[string]*lockoutEntry
+	lock    sync.Mutex
+}
+
+func (store *memoryLockoutStore) addFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	now := time.Now()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		if len(store.entries) >= maxLockoutEntries {
+			store.prune(now)
+		}
+
+		entry = &lockoutEntry{}
+		store.entries[key] = entry
+	}
+
+	if now.After(entry.windowEnd) {
+		entry.failures = 0
+		entry.windowEnd = now.Add(window)
+	}
+
+	entry.failures++
+
+	return entry.failures, nil
+}
+
+func (store *memoryLockoutStore) lockOut(ctx context.Context, key string, duration func(lockouts int) time.Duration,
+) (time.Duration, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	now := time.Now()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		entry = &lockoutEntry{}
+		store.entries[key] = entry
+	}
+
+	if now.After(entry.backoffEnd) {
+		entry.lockouts = 0
+	}
+
+	entry.lockouts++
+	entry.backoffEnd = now.Add(lockoutBackoffPeriod)
+	entry.failures = 0
+	entry.windowEnd = time.Time{}
+
+	lockDuration := duration(entry.lockouts)
+	entry.lockedUntil = now.Add(lockDuration)
+
+	return lockDuration, nil
+}
+
+func (store *memoryLockoutStore) lockedFor(ctx context.Context, key string) (time.Duration, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	entry, ok := store.entries[key]
+	if !ok {
+		return 0, nil
+	}
+
+	return max(time.Until(entry.lockedUntil), 0), nil
+}
+
+func (store *memoryLockoutStore) reset(ctx context.Context, key string) error {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	delete(store.entries, key)
+
+	return nil
+}
+
+func (store *memoryLockoutStore) close() error {
+	return nil
+}
+
+func (store *memoryLockoutStore) prune(now time.Time) {
+	for key, entry := range store.entries {
+		if entry.expired(now) {
+			delete(store.entries, key)
+		}
+	}
+}
+
+// redisLockoutStore shares the failures and lockouts between the members of a cluster.
+type redisLockoutStore struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+func (store *redisLockoutStore) key(kind, key string) string {
+	return fmt.Sprintf("%s:lockout:%s:%s", store.keyPrefix, kind, key)
+}
+
+func (store *redisLockoutStore) addFailure(ctx context.Context, key string, window time.Duration) (int, error) {
+	failuresKey := store.key("failures", key)
+
+	failures, err := store.client.Incr(ctx, failuresKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	// the window starts with the first failure
+	if failures == 1 {
+		if err := store.client.PExpire(ctx, failuresKey, window).Err(); err != nil {
+			return 0, err
+		}
+	}
+
+	return int(failures), nil
+}
+
+func (store *redisLockoutStore) lockOut(ctx context.Context, key string, duration func(lockouts int) time.Duration,
+) (time.Duration, error) {
+	lockoutsKey := store.key("lockouts", key)
+
+	lockouts, err := store.client.Incr(ctx, lockoutsKey).Result()
+	if err != nil {
+		return 0, err
+	}
+
+	lockDuration := duration(int(lockouts))
+
+	_, err = store.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Expire(ctx, lockoutsKey, lockoutBackoffPeriod)
+		pipe.Del(ctx, store.key("failures", key))
+		pipe.Set(ctx, store.key("locked", key), "1", lockDuration)
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return lockDuration, nil
+}
+
+func (store *redisLockoutStore) lockedFor(ctx context.Context, key string) (time.Duration, error) {
+	remaining, err := store.client.PTTL(ctx, store.key("locked", key)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return 0, err
+	}
+
+	// negative if the key does not exist or does not expire
+	return max(remaining, 0), nil
+}
+
+func (store *redisLockoutStore) reset(ctx context.Context, key string) error {
+	return store.client.Del(ctx, store.key("failures", key), store.key("lockouts", key),
+		store.key("locked", key)).Err()
+}
+
+func (store *redisLockoutStore) close() error {
+	return store.client.Close()
+}
@@ -0,0 +1,154 @@
+package api_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+func TestAuthLockout(t *testing.T) {
+	lockoutConfig := &config.LockoutConfig{
+		UserThreshold: 3,
+		IPThreshold:   5,
+		Window:        time.Minute,
+		Duration:      time.Second,
+		MaxDuration:   3 * time.Second,
+	}
+
+	testLockout := func(lockout *api.AuthLockout, wait func(time.Duration)) {
+		ctx := context.Background()
+
+		Convey("The users are locked out after too many failures", func() {
+			lockout.RecordFailure(ctx, "alice", "10.0.0.1")
+			lockout.RecordFailure(ctx, "alice", "10.0.0.2")
+			So(lockout.LockedFor(ctx, "alice", "10.0.0.1"), ShouldEqual, 0)
+
+			lockout.RecordFailure(ctx, "alice", "10.0.0.3")
+			So(lockout.LockedFor(ctx, "alice", "10.0.0.4"), ShouldBeBetweenOrEqual, time.Millisecond, time.Second)
+			So(lockout.LockedFor(ctx, "bob", "10.0.0.1"), ShouldEqual, 0)
+
+			// each repeated lockout doubles the previous one, up to the longest lockout
+			wait(time.Second + 100*time.Millisecond)
+			So(lockout.LockedFor(ctx, "alice", "10.0.0.1"), ShouldEqual, 0)
+
+			for range 3 {
+				lockout.RecordFailure(ctx, "alice", "10.0.0.5")
+			}
+
+			So(lockout.LockedFor(ctx, "alice", ""), ShouldBeGreaterThan, time.Second)
+
+			wait(2*time.Second + 100*time.Millisecond)
+
+			for range 3 {
+				lockout.RecordFailure(ctx, "alice", "10.0.0.6")
+			}
+
+			So(lockout.LockedFor(ctx, "alice", ""), ShouldBeBetweenOrEqual, 2*time.Second, 3*time.Second)
+
+			So(lockout.Clear(ctx, "alice", "", "admin"), ShouldBeNil)
+			So(lockout.LockedFor(ctx, "alice", ""), ShouldEqual, 0)
+		})
+
+		Convey("The failures are forgotten after a success", func() {
+			lockout.RecordFailure(ctx, "alice", "10.0.0.1")
+			lockout.RecordFailure(ctx, "alice", "10.0.0.1")
+			lockout.RecordSuccess(ctx, "alice")
+			lockout.RecordFailure(ctx, "alice", "10.0.0.1")
+			lockout.RecordFailure(ctx, "alice", "10.0.0.1")
+			So(lockout.LockedFor(ctx, "alice", "10.0.0.1"), ShouldEqual, 0)
+		})
+
+		Convey("The client ips are locked out after too many failures", func() {
+			for _, username := range []string{"alice", "bob", "carol", "dave", ""} {
+				lockout.RecordFailure(ctx, username, "10.0.0.1")
+			}
+
+			So(lockout.LockedFor(ctx, "erin", "10.0.0.1"), ShouldBeGreaterThan, 0)
+			So(lockout.LockedFor(ctx, "", "10.0.0.1"), ShouldBeGreaterThan, 0)
+			So(lockout.LockedFor(ctx, "erin", "10.0.0.2"), ShouldEqual, 0)
+
+			So(lockout.Clear(ctx, "", "10.0.0.1", "admin"), ShouldBeNil)
+			So(lockout.LockedFor(ctx, "erin", "10.0.0.1"), ShouldEqual, 0)
+		})
+	}
+
+	Convey("Test counting the authentication failures in memory", t, func() {
+		lockout := api.NewAuthLockout(lockoutConfig, false, nil, "", nil, log.NewLogger("debug", ""))
+		defer lockout.Close()
+
+		testLockout(lockout, time.Sleep)
+	})
+
+	Convey("Test counting the authentication failures of case-insensitive usernames", t, func() {
+		ctx := context.Background()
+
+		lockout := api.NewAuthLockout(lockoutConfig, true, nil, "", nil, log.NewLogger("debug", ""))
+		defer lockout.Close()
+
+		lockout.RecordFailure(ctx, "alice", "10.0.0.1")
+		lockout.RecordFailure(ctx, "Alice", "10.0.0.2")
+		lockout.RecordFailure(ctx, "ALICE", "10.0.0.3")
+		So(lockout.LockedFor(ctx, "alice", ""), ShouldBeGreaterThan, 0)
+		So(lockout.LockedFor(ctx, "aLiCe", ""), ShouldBeGreaterThan, 0)
+
+		So(lockout.Clear(ctx, "Alice", "", "admin"), ShouldBeNil)
+		So(lockout.LockedFor(ctx, "alice", ""), ShouldEqual, 0)
+
+		// the usernames are still case-sensitive otherwise
+		other := api.NewAuthLockout(lockoutConfig, false, nil, "", nil, log.NewLogger("debug", ""))
+		defer other.Close()
+
+		other.RecordFailure(ctx, "alice", "10.0.0.1")
+		other.RecordFailure(ctx, "Alice", "10.0.0.2")
+		other.RecordFailure(ctx, "ALICE", "10.0.0.3")
+		So(other.LockedFor(ctx, "alice", ""), ShouldEqual, 0)
+	})
+
+	Convey("Test counting the authentication failures in redis", t, func() {
+		miniRedis := miniredis.RunT(t)
+
+		client := redis.NewClient(&redis.Options{Addr: miniRedis.Addr()})
+
+		auditPath := path.Join(t.TempDir(), "audit.log")
+		audit := log.NewAuditLogger("debug", auditPath)
+		lockout := api.NewAuthLockout(lockoutConfig, false, client, "zot", audit, log.NewLogger("debug", ""))
+		defer lockout.Close()
+
+		testLockout(lockout, miniRedis.FastForward)
+
+		Convey("The members of a cluster share the lockouts", func() {
+			for range 3 {
+				lockout.RecordFailure(context.Background(), "mallory", "")
+			}
+
+			other := api.NewAuthLockout(lockoutConfig, false, redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}),
+				"zot", nil, log.NewLogger("debug", ""))
+			defer other.Close()
+
+			So(other.LockedFor(context.Background(), "mallory", ""), ShouldBeGreaterThan, 0)
+			So(miniRedis.Exists("zot:lockout:locked:user:mallory"), ShouldBeTrue)
+
+			auditLog, err := os.ReadFile(auditPath)
+			So(err, ShouldBeNil)
+			So(string(auditLog), ShouldContainSubstring, `"action":"lockout","object":"user:mallory"`)
+		})
+
+		Convey("The users are not locked out when redis fails", func() {
+			miniRedis.Close()
+
+			lockout.RecordFailure(context.Background(), "mallory", "")
+			So(lockout.LockedFor(context.Background(), "mallory", ""), ShouldEqual, 0)
+			So(lockout.Clear(context.Background(), "mallory", "", "admin"), ShouldNotBeNil)
+		})
+	})
+}
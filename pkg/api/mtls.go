@@ -0,0 +1,181 @@
+package api
+
+import (
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+)
+
+// client certificate attributes the mapping rules apply to.
+const (
+	CertCommonName         = "commonName"
+	CertOrganization       = "organization"
+	CertOrganizationalUnit = "organizationalUnit"
+	CertEmail              = "email"
+	CertDNSName            = "dnsName"
+	CertURI                = "uri"
+)
+
+// CertificateMapper maps the attributes of the client certificates, like their organizational units or
+// SPIFFE ids, to identities and groups.
+type CertificateMapper struct {
+	identityRules []certificateMappingRule
+	groupRules    []certificateMappingRule
+}
+
+type certificateMappingRule struct {
+	attribute string
+	pattern   *regexp.Regexp
+	value     string
+}
+
+// NewCertificateMapper compiles the mapping rules, the certificates being identified by their common name
+// if no identity rule is configured.
+func NewCertificateMapper(mtlsConfig *config.MTLSConfig) (*CertificateMapper, error) {
+	if mtlsConfig == nil {
+		mtlsConfig = &config.MTLSConfig{}
+	}
+
+	identityRules := mtlsConfig.IdentityRules
+	if len(identityRules) == 0 {
+		identityRules = []config.CertificateMappingRule{{Attribute: CertCommonName}}
+	}
+
+	mapper := &CertificateMapper{}
+
+	for _, rule := range identityRules {
+		compiledRule, err := newCertificateMappingRule(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		mapper.identityRules = append(mapper.identityRules, compiledRule)
+	}
+
+	for _, rule := range mtlsConfig.GroupRules {
+		compiledRule, err := newCertificateMappingRule(rule)
+		if err != nil {
+			return nil, err
+		}
+
+		mapper.groupRules = append(mapper.groupRules, compiledRule)
+	}
+
+	return mapper, nil
+}
+
+func newCertificateMappingRule(rule config.CertificateMappingRule) (certificateMappingRule, error) {
+	if !IsCertificateAttribute(rule.Attribute) {
+		return certificateMappingRule{}, fmt.Errorf("%w: %s", zerr.ErrUnknownCertificateAttribute, rule.Attribute)
+	}
+
+	pattern := rule.Pattern
+	if pattern == "" {
+		pattern = ".*"
+	}
+
+	compiledPattern, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return certificateMappingRule{}, fmt.Errorf("%w: invalid pattern %s: %w", zerr.ErrBadConfig, rule.Pattern, err)
+	}
+
+	value := rule.Value
+	if value == "" {
+		value = "$0"
+	}
+
+	return certificateMappingRule{attribute: rule.Attribute, pattern: compiledPattern, value: value}, nil
+}
+
+// Map returns the identity and groups of a certificate, the identity being empty if no identity rule matches.
+func (cm *CertificateMapper) Map(cert *x509.Certificate) (string, []string) {
+	var identity string
+
+	for _, rule := range cm.identityRules {
+		if values := rule.apply(cert); len(values) > 0 {
+			identity = values[0]
+
+			break
+		}
+	}
+
+	groups := []string{}
+
+	for _, rule := range cm.groupRules {
+		for _, group := range rule.apply(cert) {
+			if !slices.Contains(groups, group) {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	return identity, groups
+}
+
+// apply returns the non empty values mapped from the values of the attribute matching the pattern.
+func (rule certificateMappingRule) apply(cert *x509.Certificate) []string {
+	mapped := []string{}
+
+	for _, value := range certificateAttribute(cert, rule.attribute) {
+		match := rule.pattern.FindStringSubmatchIndex(value)
+		if match == nil {
+			continue
+		}
+
+		if result := string(rule.pattern.ExpandString(nil, rule.value, value, match)); result != "" {
+			mapped = append(mapped, result)
+		}
+	}
+
+	return mapped
+}
+
+func certificateAttribute(cert *x509.Certificate, attribute string) []string {
+	switch attribute {
+	case CertCommonName:
+		if cert.Subject.CommonName == "" {
+			return nil
+		}
+
+		return []string{cert.Subject.CommonName}
+	case CertOrganization:
+		return cert.Subject.Organization
+	case CertOrganizationalUnit:
+		return cert.Subject.OrganizationalUnit
+	case CertEmail:
+		return cert.EmailAddresses
+	case CertDNSName:
+		return cert.DNSNames
+	case CertURI:
+		uris := make([]string, 0, len(cert.URIs))
+		for _, uri := range cert.URIs {
+			uris = append(uris, uri.String())
+		}
+
+		return uris
+	default:
+		return nil
+	}
+}
+
+// IsCertificateAttribute returns true if the mapping rules can apply to attribute.
+func IsCertificateAttribute(attribute string) bool {
+	return slices.Contains([]string{
+		CertCommonName, CertOrganization, CertOrganizationalUnit, CertEmail, CertDNSName, CertURI,
+	}, attribute)
+}
+
+// verifiedClientCertificate returns the client certificate of a request if it was verified against
+// the ca of the server.
+func verifiedClientCertificate(request *http.Request) *x509.Certificate {
+	if request.TLS == nil || len(request.TLS.VerifiedChains) == 0 || len(request.TLS.VerifiedChains[0]) == 0 {
+		return nil
+	}
+
+	return request.TLS.VerifiedChains[0][0]
+}
@@ -0,0 +1,200 @@
+package api_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+)
+
+// testPKI is a certificate authority issuing the certificates of a test server and its clients.
+type testPKI struct {
+	caCert         *x509.Certificate
+	caKey          *ecdsa.PrivateKey
+	caPool         *x509.CertPool
+	caPath         string
+	serverCertPath string
+	serverKeyPath  string
+}
+
+func newTestPKI(dir string) *testPKI {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Minute),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		panic(err)
+	}
+
+	caCert, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		panic(err)
+	}
+
+	pki := &testPKI{
+		caCert:         caCert,
+		caKey:          caKey,
+		caPool:         x509.NewCertPool(),
+		caPath:         path.Join(dir, "ca.crt"),
+		serverCertPath: path.Join(dir, "server.crt"),
+		serverKeyPath:  path.Join(dir, "server.key"),
+	}
+
+	pki.caPool.AddCert(caCert)
+
+	if err := os.WriteFile(pki.caPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		0o600); err != nil {
+		panic(err)
+	}
+
+	serverCert := pki.issue(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+
+	serverKey, err := x509.MarshalECPrivateKey(serverCert.PrivateKey.(*ecdsa.PrivateKey)) //nolint: forcetypeassert
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(pki.serverCertPath,
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: serverCert.Certificate[0]}), 0o600); err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(pki.serverKeyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: serverKey}),
+		0o600); err != nil {
+		panic(err)
+	}
+
+	return pki
+}
+
+// issue signs a certificate with the attributes of template.
+func (pki *testPKI) issue(template *x509.Certificate) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+
+	serialNumber, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		panic(err)
+	}
+
+	template.SerialNumber = serialNumber
+	template.NotBefore = time.Now().Add(-time.Minute)
+	template.NotAfter = time.Now().Add(time.Hour)
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+
+	if template.ExtKeyUsage == nil {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, pki.caCert, key.Public(), pki.caKey)
+	if err != nil {
+		panic(err)
+	}
+
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestCertificateMapper(t *testing.T) {
+	Convey("Test mapping client certificates to identities and groups", t, func() {
+		spiffeID, err := url.Parse("spiffe://myorg.io/team/build/sa/ci")
+		So(err, ShouldBeNil)
+
+		otherURI, err := url.Parse("https://myorg.io/ci")
+		So(err, ShouldBeNil)
+
+		cert := &x509.Certificate{
+			Subject: pkix.Name{
+				CommonName:         "ci",
+				Organization:       []string{"myorg"},
+				OrganizationalUnit: []string{"platform", "release", "platform"},
+			},
+			EmailAddresses: []string{"ci@myorg.io"},
+			DNSNames:       []string{"ci.myorg.io"},
+			URIs:           []*url.URL{otherURI, spiffeID},
+		}
+
+		Convey("The common name is the default identity", func() {
+			mapper, err := api.NewCertificateMapper(nil)
+			So(err, ShouldBeNil)
+
+			identity, groups := mapper.Map(cert)
+			So(identity, ShouldEqual, "ci")
+			So(groups, ShouldBeEmpty)
+
+			identity, _ = mapper.Map(&x509.Certificate{})
+			So(identity, ShouldBeEmpty)
+		})
+
+		Convey("The rules map the attributes", func() {
+			mapper, err := api.NewCertificateMapper(&config.MTLSConfig{
+				IdentityRules: []config.CertificateMappingRule{
+					{Attribute: api.CertEmail, Pattern: `(?P<user>[^@]+)@example\.com`, Value: "${user}"},
+					{Attribute: api.CertURI, Pattern: "spiffe://myorg.io/.*"},
+					{Attribute: api.CertCommonName},
+				},
+				GroupRules: []config.CertificateMappingRule{
+					{Attribute: api.CertOrganizationalUnit},
+					{Attribute: api.CertURI, Pattern: "spiffe://myorg.io/team/([^/]+)/.*", Value: "team-$1"},
+					{Attribute: api.CertOrganization, Pattern: "other"},
+					{Attribute: api.CertDNSName, Pattern: `(.*)\.myorg\.io`, Value: "$2"},
+				},
+			})
+			So(err, ShouldBeNil)
+
+			identity, groups := mapper.Map(cert)
+			So(identity, ShouldEqual, "spiffe://myorg.io/team/build/sa/ci")
+			So(groups, ShouldResemble, []string{"platform", "release", "team-build"})
+
+			cert.EmailAddresses = []string{"ci@example.com"}
+
+			identity, _ = mapper.Map(cert)
+			So(identity, ShouldEqual, "ci")
+		})
+
+		Convey("Invalid rules are rejected", func() {
+			_, err := api.NewCertificateMapper(&config.MTLSConfig{
+				IdentityRules: []config.CertificateMappingRule{{Attribute: "serialNumber"}},
+			})
+			So(err, ShouldWrap, zerr.ErrUnknownCertificateAttribute)
+
+			_, err = api.NewCertificateMapper(&config.MTLSConfig{
+				GroupRules: []config.CertificateMappingRule{{Attribute: api.CertURI, Pattern: "(.*"}},
+			})
+			So(err, ShouldWrap, zerr.ErrBadConfig)
+		})
+	})
+}
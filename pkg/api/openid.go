@@ -0,0 +1,381 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+	"github.com/zitadel/oidc/v3/pkg/client/rp"
+	"github.com/zitadel/oidc/v3/pkg/oidc"
+	"golang.org/x/oauth2"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	mTypes "zotregistry.dev/zot/pkg/meta/types"
+)
+
+const defaultOpenIDGroupsClaim = "groups"
+
+// the oauth2 error codes of the tokens rejected by a provider.
+var openIDRejectionErrors = []string{"invalid_grant", "invalid_token", "access_denied"} //nolint: gochecknoglobals
+
+// OpenIDGroupsMapper gets the groups of the users logging in with an openid/oauth2 provider from the claims
+// of their tokens, removing the configured prefix and keeping the groups matching the filters.
+type OpenIDGroupsMapper struct {
+	claim   []string
+	prefix  string
+	filters []*regexp.Regexp
+}
+
+// NewOpenIDGroupsMapper compiles the groups mapping of a provider.
+func NewOpenIDGroupsMapper(providerConfig config.OpenIDProviderConfig) (*OpenIDGroupsMapper, error) {
+	claim := providerConfig.GroupsClaim
+	if claim == "" {
+		claim = defaultOpenIDGroupsClaim
+	}
+
+	mapper := &OpenIDGroupsMapper{
+		claim:  strings.Split(claim, "."),
+		prefix: providerConfig.GroupsPrefix,
+	}
+
+	for _, filter := range providerConfig.GroupsFilter {
+		compiledFilter, err := regexp.Compile("^(?:" + filter + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid groups filter %s: %w", zerr.ErrBadConfig, filter, err)
+		}
+
+		mapper.filters = append(mapper.filters, compiledFilter)
+	}
+
+	return mapper, nil
+}
+
+// ClaimGroups returns the mapped groups of the first claims holding the groups claim,
+// which is either a list or a single group.
+func (mapper *OpenIDGroupsMapper) ClaimGroups(claimsList ...map[string]any) ([]string, bool) {
+	for _, claims := range claimsList {
+		value, ok := lookupClaim(claims, mapper.claim)
+		if !ok {
+			continue
+		}
+
+		var groups []string
+
+		switch typedValue := value.(type) {
+		case []any:
+			for _, group := range typedValue {
+				groups = append(groups, fmt.Sprint(group))
+			}
+		case string:
+			groups = append(groups, typedValue)
+		default:
+			continue
+		}
+
+		return mapper.Map(groups), true
+	}
+
+	return []string{}, false
+}
+
+// Map removes the prefix from the groups and keeps the ones matching the filters.
+func (mapper *OpenIDGroupsMapper) Map(groups []string) []string {
+	mappedGroups := []string{}
+
+	for _, group := range groups {
+		group = strings.TrimPrefix(group, mapper.prefix)
+		if group == "" || slices.Contains(mappedGroups, group) {
+			continue
+		}
+
+		if len(mapper.filters) > 0 && !slices.ContainsFunc(mapper.filters, func(filter *regexp.Regexp) bool {
+			return filter.MatchString(group)
+		}) {
+			continue
+		}
+
+		mappedGroups = append(mappedGroups, group)
+	}
+
+	return mappedGroups
+}
+
+func lookupClaim(claims map[string]any, path []string) (any, bool) {
+	var value any = claims
+
+	for _, key := range path {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		value, ok = object[key]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+// openIDGroupsMapper returns the groups mapping of a provider, the default one if it is not configured.
+func openIDGroupsMapper(ctlr *Controller, provider string) *OpenIDGroupsMapper {
+	if mapper, ok := ctlr.OpenIDGroups[provider]; ok {
+		return mapper
+	}
+
+	return &OpenIDGroupsMapper{claim: []string{defaultOpenIDGroupsClaim}}
+}
+
+// newOpenIDSessionToken returns the tokens to keep with a session opened with provider,
+// nil if the sessions of the provider are not re-validated.
+func newOpenIDSessionToken(ctlr *Controller, provider string, token *oauth2.Token, subject string,
+) *mTypes.UserSessionToken {
+	if token == nil || openIDRevalidateInterval(ctlr, provider) == 0 {
+		return nil
+	}
+
+	return &mTypes.UserSessionToken{
+		Subject:      subject,
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+		ValidatedAt:  time.Now(),
+	}
+}
+
+func openIDRevalidateInterval(ctlr *Controller, provider string) time.Duration {
+	if ctlr.Config.HTTP.Auth == nil || ctlr.Config.HTTP.Auth.OpenID == nil {
+		return 0
+	}
+
+	return ctlr.Config.HTTP.Auth.OpenID.Providers[provider].SessionRevalidateInterval
+}
+
+// openIDRevalidateRetryInterval is how long a session is trusted again when its provider failed to answer,
+// before asking it again, unless the revalidation interval of the provider is shorter.
+const openIDRevalidateRetryInterval = time.Minute
+
+// revalidateOpenIDSession re-validates a session with its provider once its revalidation interval elapsed,
+// refreshing its tokens and the groups of the user. It returns ErrOpenIDSessionInvalid if the provider
+// rejects the tokens of the session, while the session is kept as is if the provider fails to answer.
+// The concurrent requests of a session share its re-validation, some providers rotating the refresh tokens.
+func revalidateOpenIDSession(ctx context.Context, ctlr *Controller, identity string,
+	session mTypes.UserSession,
+) error {
+	interval := openIDRevalidateInterval(ctlr, session.Provider)
+
+	relyingParty, ok := ctlr.RelyingParties[session.Provider]
+	if session.Token == nil || !ok || interval == 0 || time.Since(session.Token.ValidatedAt) < interval {
+		return nil
+	}
+
+	_, err, _ := ctlr.openIDSessions.Do(identity+"/"+session.ID, func() (any, error) {
+		// the request starting the re-validation may be canceled before the others sharing it
+		return nil, refreshOpenIDSession(context.WithoutCancel(ctx), ctlr, identity, session.ID, relyingParty,
+			interval)
+	})
+
+	return err
+}
+
+func refreshOpenIDSession(ctx context.Context, ctlr *Controller, identity, sessionID string,
+	relyingParty rp.RelyingParty, interval time.Duration,
+) error {
+	// the session may have been re-validated meanwhile by a request which read it earlier, or by another replica
+	session, err := getUserSession(ctx, ctlr, sessionID)
+	if err != nil {
+		if errors.Is(err, zerr.ErrUserSessionNotFound) {
+			return fmt.Errorf("%w: %w", zerr.ErrOpenIDSessionInvalid, err)
+		}
+
+		return err
+	}
+
+	if session.Token == nil || time.Since(session.Token.ValidatedAt) < interval {
+		return nil
+	}
+
+	token := *session.Token
+	mapper := openIDGroupsMapper(ctlr, session.Provider)
+
+	var groups []string
+
+	if config.IsOauth2Supported(session.Provider) {
+		groups, err = revalidateGithubToken(ctx, ctlr, identity, relyingParty, mapper, &token)
+	} else {
+		groups, err = revalidateOIDCToken(ctx, relyingParty, mapper, &token)
+	}
+
+	if err != nil {
+		if !isOpenIDRejection(err) {
+			ctlr.Log.Warn().Err(err).Str("identity", identity).Str("provider", session.Provider).
+				Msg("failed to re-validate openid session, keeping it")
+
+			// the provider is asked again after a while rather than on every request,
+			// the tokens being kept in case they were refreshed before the failure
+			token.ValidatedAt = time.Now().Add(min(openIDRevalidateRetryInterval, interval) - interval)
+			session.Token = &token
+
+			if err := ctlr.MetaDB.AddUserSession(ctx, session); err != nil {
+				ctlr.Log.Error().Err(err).Str("identity", identity).Msg("failed to store user session in DB")
+			}
+
+			return nil
+		}
+
+		ctlr.Log.Info().Err(err).Str("identity", identity).Str("provider", session.Provider).
+			Msg("openid session was rejected by its provider")
+
+		return fmt.Errorf("%w: %w", zerr.ErrOpenIDSessionInvalid, err)
+	}
+
+	token.ValidatedAt = time.Now()
+	session.Token = &token
+
+	if err := ctlr.MetaDB.SetUserGroups(ctx, groups); err != nil {
+		ctlr.Log.Error().Err(err).Str("identity", identity).Msg("failed to update the user profile")
+
+		return err
+	}
+
+	if err := ctlr.MetaDB.AddUserSession(ctx, session); err != nil {
+		ctlr.Log.Error().Err(err).Str("identity", identity).Msg("failed to store user session in DB")
+
+		return err
+	}
+
+	return nil
+}
+
+// getUserSession returns the session of the current user with the given id.
+func getUserSession(ctx context.Context, ctlr *Controller, sessionID string) (mTypes.UserSession, error) {
+	sessions, err := ctlr.MetaDB.GetUserSessions(ctx)
+	if err != nil {
+		return mTypes.UserSession{}, err
+	}
+
+	for _, session := range sessions {
+		if session.ID == sessionID {
+			return session, nil
+		}
+	}
+
+	return mTypes.UserSession{}, zerr.ErrUserSessionNotFound
+}
+
+// isOpenIDRejection returns true if the provider definitively rejected the tokens of a session: an invalid grant
+// or token, or a 401 or 403 answer. Other errors, like an unreachable provider or a server error, are transient.
+func isOpenIDRejection(err error) bool {
+	if errors.Is(err, zerr.ErrOpenIDSessionInvalid) || errors.Is(err, rp.ErrUserInfoSubNotMatching) {
+		return true
+	}
+
+	var oidcErr *oidc.Error
+	if errors.As(err, &oidcErr) {
+		return slices.Contains(openIDRejectionErrors, string(oidcErr.ErrorType))
+	}
+
+	var retrieveErr *oauth2.RetrieveError
+	if errors.As(err, &retrieveErr) {
+		return slices.Contains(openIDRejectionErrors, retrieveErr.ErrorCode) ||
+			(retrieveErr.Response != nil && isRejectionStatus(retrieveErr.Response.StatusCode))
+	}
+
+	var githubErr *github.ErrorResponse
+	if errors.As(err, &githubErr) {
+		return githubErr.Response != nil && isRejectionStatus(githubErr.Response.StatusCode)
+	}
+
+	// the oidc client reports the error answers without an oauth2 error as text only
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		if strings.HasPrefix(err.Error(), fmt.Sprintf("http status not ok: %d ", status)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isRejectionStatus(status int) bool {
+	return status == http.StatusUnauthorized || status == http.StatusForbidden
+}
+
+// revalidateOIDCToken refreshes the tokens if the provider gave a refresh token and gets the groups
+// from the userinfo, or from the refreshed id token.
+func revalidateOIDCToken(ctx context.Context, relyingParty rp.RelyingParty, mapper *OpenIDGroupsMapper,
+	token *mTypes.UserSessionToken,
+) ([]string, error) {
+	var idTokenClaims map[string]any
+
+	if token.RefreshToken != "" {
+		tokens, err := rp.RefreshTokens[*oidc.IDTokenClaims](ctx, relyingParty, token.RefreshToken, "", "")
+		if err != nil {
+			return nil, err
+		}
+
+		token.AccessToken = tokens.AccessToken
+		token.TokenType = tokens.TokenType
+		token.Expiry = tokens.Expiry
+
+		if tokens.RefreshToken != "" {
+			token.RefreshToken = tokens.RefreshToken
+		}
+
+		if tokens.IDTokenClaims != nil {
+			idTokenClaims = tokens.IDTokenClaims.Claims
+		}
+	}
+
+	info, err := rp.Userinfo[*oidc.UserInfo](ctx, token.AccessToken, token.TokenType, token.Subject, relyingParty)
+	if err != nil {
+		return nil, err
+	}
+
+	groups, _ := mapper.ClaimGroups(info.Claims, idTokenClaims)
+
+	return groups, nil
+}
+
+// revalidateGithubToken gets the organizations of the user again, the token being refreshed if it expired.
+func revalidateGithubToken(ctx context.Context, ctlr *Controller, identity string, relyingParty rp.RelyingParty,
+	mapper *OpenIDGroupsMapper, token *mTypes.UserSessionToken,
+) ([]string, error) {
+	tokenSource := relyingParty.OAuthConfig().TokenSource(ctx, &oauth2.Token{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		TokenType:    token.TokenType,
+		Expiry:       token.Expiry,
+	})
+
+	refreshedToken, err := tokenSource.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	client := github.NewClient(oauth2.NewClient(ctx, oauth2.StaticTokenSource(refreshedToken)))
+
+	email, groups, err := GetGithubUserInfo(ctx, client, ctlr.Log)
+	if err != nil {
+		return nil, err
+	}
+
+	if email != identity {
+		return nil, fmt.Errorf("%w: github user changed its primary email", zerr.ErrOpenIDSessionInvalid)
+	}
+
+	token.AccessToken = refreshedToken.AccessToken
+	token.RefreshToken = refreshedToken.RefreshToken
+	token.TokenType = refreshedToken.TokenType
+	token.Expiry = refreshedToken.Expiry
+
+	return mapper.Map(groups), nil
+}
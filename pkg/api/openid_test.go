@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+)
+
+func TestOpenIDGroupsMapper(t *testing.T) {
+	Convey("Test the default groups claim", t, func() {
+		mapper, err := api.NewOpenIDGroupsMapper(config.OpenIDProviderConfig{})
+		So(err, ShouldBeNil)
+
+		groups, ok := mapper.ClaimGroups(map[string]any{"groups": []any{"dev", "ops", "dev"}})
+		So(ok, ShouldBeTrue)
+		So(groups, ShouldResemble, []string{"dev", "ops"})
+
+		// a single group given as a string
+		groups, ok = mapper.ClaimGroups(map[string]any{"groups": "dev"})
+		So(ok, ShouldBeTrue)
+		So(groups, ShouldResemble, []string{"dev"})
+
+		groups, ok = mapper.ClaimGroups(map[string]any{"roles": []any{"dev"}}, nil)
+		So(ok, ShouldBeFalse)
+		So(groups, ShouldBeEmpty)
+	})
+
+	Convey("Test a nested claim with prefix and filters", t, func() {
+		mapper, err := api.NewOpenIDGroupsMapper(config.OpenIDProviderConfig{
+			GroupsClaim:  "realm_access.roles",
+			GroupsPrefix: "/",
+			GroupsFilter: []string{"zot-.*", "admins"},
+		})
+		So(err, ShouldBeNil)
+
+		userinfo := map[string]any{"email": "alice@example.com"}
+		idToken := map[string]any{
+			"realm_access": map[string]any{
+				"roles": []any{"/zot-devs", "/zot-ops", "/admins", "/admins-backup", "offline_access"},
+			},
+		}
+
+		// the claim is not in the userinfo, it is taken from the id token
+		groups, ok := mapper.ClaimGroups(userinfo, idToken)
+		So(ok, ShouldBeTrue)
+		So(groups, ShouldResemble, []string{"zot-devs", "zot-ops", "admins"})
+
+		So(mapper.Map([]string{"/zot-devs", "zot-devs", "/", "other"}), ShouldResemble, []string{"zot-devs"})
+	})
+
+	Convey("Test an invalid filter", t, func() {
+		_, err := api.NewOpenIDGroupsMapper(config.OpenIDProviderConfig{GroupsFilter: []string{"zot-("}})
+		So(errors.Is(err, zerr.ErrBadConfig), ShouldBeTrue)
+	})
+}
@@ -9,10 +9,13 @@ import (
 	"net/http"
 
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/trace"
 
 	"zotregistry.dev/zot/pkg/api/constants"
 	"zotregistry.dev/zot/pkg/cluster"
 	"zotregistry.dev/zot/pkg/common"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+	"zotregistry.dev/zot/pkg/tracing"
 )
 
 // ClusterProxy wraps an http.HandlerFunc which requires proxying between zot instances to ensure
@@ -116,7 +119,15 @@ func getTargetMemberServerSockets(targetMemberSocket string) ([]string, error) {
 // proxy the request to the target member and return a pointer to the response or an error.
 func proxyHTTPRequest(ctx context.Context, req *http.Request,
 	targetMember string, ctrlr *Controller,
-) (*http.Response, error) {
+) (resp *http.Response, err error) {
+	if ctrlr.Config.IsTracingEnabled() {
+		var span trace.Span
+
+		ctx, span = tracing.Start(ctx, "ClusterProxy", tracing.MemberKey.String(targetMember),
+			tracing.RepositoryKey.String(mux.Vars(req)["name"]))
+		defer func() { tracing.End(span, err) }()
+	}
+
 	cloneURL := *req.URL
 
 	proxyQueryScheme := "http"
@@ -141,6 +152,11 @@ func proxyHTTPRequest(ctx context.Context, req *http.Request,
 	// already has a hop count but is due for proxying.
 	fwdRequest.Header.Set(constants.ScaleOutHopCountHeader, "1")
 
+	// the member logs the request with the same id
+	if requestID := reqCtx.RequestIDFromContext(ctx); requestID != "" {
+		fwdRequest.Header.Set(constants.RequestIDHeader, requestID)
+	}
+
 	clientOpts := common.HTTPClientOptions{
 		TLSEnabled: ctrlr.Config.HTTP.TLS != nil,
 		VerifyTLS:  ctrlr.Config.HTTP.TLS != nil, // for now, always verify TLS when TLS mode is enabled
@@ -159,7 +175,12 @@ func proxyHTTPRequest(ctx context.Context, req *http.Request,
 		return nil, err
 	}
 
-	resp, err := httpClient.Do(fwdRequest)
+	// propagates the trace context of the request to the member
+	if ctrlr.Config.IsTracingEnabled() {
+		httpClient.Transport = tracing.Transport(httpClient.Transport)
+	}
+
+	resp, err = httpClient.Do(fwdRequest)
 	if err != nil {
 		return nil, err
 	}
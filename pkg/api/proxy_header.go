@@ -0,0 +1,113 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+)
+
+const (
+	defaultProxyUserHeader      = "X-Forwarded-User"
+	defaultProxyGroupsHeader    = "X-Forwarded-Groups"
+	defaultProxyGroupsSeparator = ","
+)
+
+// ProxyHeaderAuthenticator authenticates the users from the headers set by a trusted reverse proxy
+// which has already authenticated them.
+type ProxyHeaderAuthenticator struct {
+	userHeader       string
+	groupsHeader     string
+	groupsSeparator  string
+	trustedNetworks  []*net.IPNet
+	trustedCertNames []string
+}
+
+func NewProxyHeaderAuthenticator(proxyHeaderConfig *config.ProxyHeaderConfig) (*ProxyHeaderAuthenticator, error) {
+	authenticator := &ProxyHeaderAuthenticator{
+		userHeader:       proxyHeaderConfig.UserHeader,
+		groupsHeader:     proxyHeaderConfig.GroupsHeader,
+		groupsSeparator:  proxyHeaderConfig.GroupsSeparator,
+		trustedCertNames: proxyHeaderConfig.TrustedCertNames,
+	}
+
+	if authenticator.userHeader == "" {
+		authenticator.userHeader = defaultProxyUserHeader
+	}
+
+	if authenticator.groupsHeader == "" {
+		authenticator.groupsHeader = defaultProxyGroupsHeader
+	}
+
+	if authenticator.groupsSeparator == "" {
+		authenticator.groupsSeparator = defaultProxyGroupsSeparator
+	}
+
+	for _, cidr := range proxyHeaderConfig.TrustedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid trusted cidr %s: %w", zerr.ErrBadConfig, cidr, err)
+		}
+
+		authenticator.trustedNetworks = append(authenticator.trustedNetworks, network)
+	}
+
+	return authenticator, nil
+}
+
+// HasHeaders returns true if the request carries any of the proxy authentication headers.
+func (pha *ProxyHeaderAuthenticator) HasHeaders(request *http.Request) bool {
+	return request.Header.Get(pha.userHeader) != "" || request.Header.Get(pha.groupsHeader) != ""
+}
+
+// Authenticate returns the user and groups given by the headers of a request sent by a trusted proxy.
+func (pha *ProxyHeaderAuthenticator) Authenticate(request *http.Request) (string, []string, error) {
+	if !pha.isTrusted(request) {
+		return "", nil, fmt.Errorf("%w: %s", zerr.ErrUntrustedProxy, remoteIP(request))
+	}
+
+	identity := strings.TrimSpace(request.Header.Get(pha.userHeader))
+	if identity == "" {
+		return "", nil, fmt.Errorf("%w: %s", zerr.ErrMissingProxyUserHeader, pha.userHeader)
+	}
+
+	groups := []string{}
+
+	for _, header := range request.Header.Values(pha.groupsHeader) {
+		for _, group := range strings.Split(header, pha.groupsSeparator) {
+			if group = strings.TrimSpace(group); group != "" && !slices.Contains(groups, group) {
+				groups = append(groups, group)
+			}
+		}
+	}
+
+	return identity, groups, nil
+}
+
+// isTrusted returns true if the request comes from a trusted network or with a trusted client certificate.
+func (pha *ProxyHeaderAuthenticator) isTrusted(request *http.Request) bool {
+	if ip := net.ParseIP(remoteIP(request)); ip != nil {
+		for _, network := range pha.trustedNetworks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+	}
+
+	cert := verifiedClientCertificate(request)
+	if cert == nil {
+		return false
+	}
+
+	for _, name := range pha.trustedCertNames {
+		if cert.Subject.CommonName == name || slices.Contains(cert.DNSNames, name) {
+			return true
+		}
+	}
+
+	return false
+}
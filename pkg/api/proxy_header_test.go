@@ -0,0 +1,96 @@
+package api_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+)
+
+func TestProxyHeaderAuthenticator(t *testing.T) {
+	Convey("Test authenticating the users from the headers of a reverse proxy", t, func() {
+		authenticator, err := api.NewProxyHeaderAuthenticator(&config.ProxyHeaderConfig{
+			GroupsSeparator:  ";",
+			TrustedCIDRs:     []string{"10.0.0.0/8", "fd00::/8"},
+			TrustedCertNames: []string{"sso-proxy", "proxy.myorg.io"},
+		})
+		So(err, ShouldBeNil)
+
+		request := httptest.NewRequest(http.MethodGet, "/v2/", nil)
+		So(authenticator.HasHeaders(request), ShouldBeFalse)
+
+		request.Header.Set("X-Forwarded-User", "alice")
+		request.Header.Set("X-Forwarded-Groups", "dev; ops;;dev")
+		request.Header.Add("X-Forwarded-Groups", "qa")
+		So(authenticator.HasHeaders(request), ShouldBeTrue)
+
+		Convey("From the trusted networks", func() {
+			for _, remoteAddr := range []string{"10.1.2.3:4567", "[fd00::1]:4567"} {
+				request.RemoteAddr = remoteAddr
+
+				identity, groups, err := authenticator.Authenticate(request)
+				So(err, ShouldBeNil)
+				So(identity, ShouldEqual, "alice")
+				So(groups, ShouldResemble, []string{"dev", "ops", "qa"})
+			}
+
+			request.Header.Del("X-Forwarded-User")
+
+			_, _, err := authenticator.Authenticate(request)
+			So(err, ShouldWrap, zerr.ErrMissingProxyUserHeader)
+		})
+
+		Convey("With the trusted client certificates", func() {
+			request.RemoteAddr = "192.168.1.2:4567"
+
+			_, _, err := authenticator.Authenticate(request)
+			So(err, ShouldWrap, zerr.ErrUntrustedProxy)
+
+			verifiedState := func(cert *x509.Certificate) *tls.ConnectionState {
+				return &tls.ConnectionState{
+					PeerCertificates: []*x509.Certificate{cert},
+					VerifiedChains:   [][]*x509.Certificate{{cert}},
+				}
+			}
+
+			request.TLS = verifiedState(&x509.Certificate{Subject: pkix.Name{CommonName: "sso-proxy"}})
+
+			identity, _, err := authenticator.Authenticate(request)
+			So(err, ShouldBeNil)
+			So(identity, ShouldEqual, "alice")
+
+			request.TLS = verifiedState(&x509.Certificate{
+				Subject: pkix.Name{CommonName: "other"}, DNSNames: []string{"proxy.myorg.io"},
+			})
+
+			_, _, err = authenticator.Authenticate(request)
+			So(err, ShouldBeNil)
+
+			request.TLS = verifiedState(&x509.Certificate{Subject: pkix.Name{CommonName: "other"}})
+
+			_, _, err = authenticator.Authenticate(request)
+			So(err, ShouldWrap, zerr.ErrUntrustedProxy)
+
+			// the certificate was not verified
+			request.TLS = &tls.ConnectionState{
+				PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "sso-proxy"}}},
+			}
+
+			_, _, err = authenticator.Authenticate(request)
+			So(err, ShouldWrap, zerr.ErrUntrustedProxy)
+		})
+
+		Convey("Invalid networks are rejected", func() {
+			_, err := api.NewProxyHeaderAuthenticator(&config.ProxyHeaderConfig{TrustedCIDRs: []string{"10.0.0.1"}})
+			So(err, ShouldWrap, zerr.ErrBadConfig)
+		})
+	})
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	glob "github.com/bmatcuk/doublestar/v4"
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+
+	"zotregistry.dev/zot/pkg/api/config"
+	apiErr "zotregistry.dev/zot/pkg/api/errors"
+	zcommon "zotregistry.dev/zot/pkg/common"
+	"zotregistry.dev/zot/pkg/log"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+)
+
+// the in memory buckets are pruned when there are more of them.
+const maxRatelimitBuckets = 10000
+
+// ClientRateLimiter limits the requests of each anonymous client by source ip, of each authenticated client
+// by api key or identity, and of each client on the repositories matching globs.
+type ClientRateLimiter struct {
+	anonymous     *rateLimit
+	authenticated *rateLimit
+	repositories  []repositoryRateLimit
+	store         ratelimitStore
+	log           log.Logger
+}
+
+// rateLimit is a token bucket, refilled with a token every interval and holding up to burst tokens.
+type rateLimit struct {
+	interval time.Duration
+	burst    int
+}
+
+type repositoryRateLimit struct {
+	globs         []string
+	anonymous     *rateLimit
+	authenticated *rateLimit
+}
+
+// ratelimitStore keeps the buckets of the keys identifying the clients, with the generic cell rate algorithm:
+// a bucket is the time it would be full again, its theoretical arrival time.
+type ratelimitStore interface {
+	// take takes a token from the bucket of key, returning how long to wait for one if it is empty
+	take(ctx context.Context, key string, limit rateLimit) (time.Duration, error)
+	close() error
+}
+
+// NewClientRateLimiter returns a rate limiter keeping the buckets in redis if client is set, in memory otherwise.
+func NewClientRateLimiter(ratelimitConfig *config.RatelimitConfig, client redis.UniversalClient, keyPrefix string,
+	log log.Logger,
+) *ClientRateLimiter {
+	limiter := &ClientRateLimiter{
+		anonymous:     newRateLimit(ratelimitConfig.Anonymous),
+		authenticated: newRateLimit(ratelimitConfig.Authenticated),
+		log:           log,
+	}
+
+	for _, repoConfig := range ratelimitConfig.Repositories {
+		limiter.repositories = append(limiter.repositories, repositoryRateLimit{
+			globs:         repoConfig.Repositories,
+			anonymous:     newRateLimit(repoConfig.Anonymous),
+			authenticated: newRateLimit(repoConfig.Authenticated),
+		})
+	}
+
+	if client != nil {
+		limiter.store = &redisRatelimitStore{client: client, keyPrefix: keyPrefix}
+	} else {
+		limiter.store = &memoryRatelimitStore{buckets: map[string]time.Time{}}
+	}
+
+	return limiter
+}
+
+func newRateLimit(clientConfig *config.ClientRatelimitConfig) *rateLimit {
+	if clientConfig == nil || clientConfig.Rate <= 0 {
+		return nil
+	}
+
+	burst := clientConfig.Burst
+	if burst <= 0 {
+		burst = int(math.Ceil(clientConfig.Rate))
+	}
+
+	return &rateLimit{
+		interval: time.Duration(float64(time.Second) / clientConfig.Rate),
+		burst:    burst,
+	}
+}
+
+// Allow takes a token from the buckets of the client making the request, returning how long it has to wait
+// before retrying if one of them is empty.
+func (limiter *ClientRateLimiter) Allow(request *http.Request) time.Duration {
+	clientKey, anonymous := ratelimitClientKey(request)
+
+	limits := map[string]*rateLimit{}
+
+	if anonymous {
+		limits[clientKey] = limiter.anonymous
+	} else {
+		limits[clientKey] = limiter.authenticated
+	}
+
+	if repository, ok := mux.Vars(request)["name"]; ok {
+		for index, repoLimit := range limiter.repositories {
+			if !matchesAnyGlob(repoLimit.globs, repository) {
+				continue
+			}
+
+			key := fmt.Sprintf("repo%d:%s", index, clientKey)
+
+			if anonymous {
+				limits[key] = repoLimit.anonymous
+			} else {
+				limits[key] = repoLimit.authenticated
+			}
+		}
+	}
+
+	var retryAfter time.Duration
+
+	for key, limit := range limits {
+		if limit == nil {
+			continue
+		}
+
+		wait, err := limiter.store.take(request.Context(), key, *limit)
+		if err != nil {
+			// the rate limiter failing should not prevent every client from using the registry
+			limiter.log.Error().Err(err).Str("key", key).Msg("failed to take from rate limit bucket")
+
+			continue
+		}
+
+		retryAfter = max(retryAfter, wait)
+	}
+
+	return retryAfter
+}
+
+func (limiter *ClientRateLimiter) Close() error {
+	return limiter.store.close()
+}
+
+// ratelimitClientKey returns the key identifying the client which made the request and whether it is anonymous.
+func ratelimitClientKey(request *http.Request) (string, bool) {
+	userAc, err := reqCtx.UserAcFromContext(request.Context())
+	if err != nil || userAc == nil || userAc.IsAnonymous() {
+		return "ip:" + clientIP(request), true
+	}
+
+	if apiKey := userAc.GetAPIKey(); apiKey != "" {
+		return "apikey:" + apiKey, false
+	}
+
+	return "user:" + userAc.GetUsername(), false
+}
+
+func matchesAnyGlob(globs []string, repository string) bool {
+	for _, pattern := range globs {
+		if matched, err := glob.Match(pattern, repository); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientRateLimitHandler rejects the requests of the clients over their rate limits with 429 Too Many Requests.
+func ClientRateLimitHandler(ctlr *Controller) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		if ctlr.ClientLimiter == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if retryAfter := ctlr.ClientLimiter.Allow(request); retryAfter > 0 {
+				clientKey, _ := ratelimitClientKey(request)
+
+				ctlr.Log.Info().Str("client", clientKey).Str("path", request.URL.Path).
+					Str("retryAfter", retryAfter.String()).Msg("rate limit exceeded")
+
+				response.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				zcommon.WriteJSON(response, http.StatusTooManyRequests, apiErr.NewError(apiErr.TOOMANYREQUESTS))
+
+				return
+			}
+
+			next.ServeHTTP(response, request)
+		})
+	}
+}
+
+type memoryRatelimitStore struct {
+	buckets map[string]time.Time
+	lock    sync.Mutex
+}
+
+func (store *memoryRatelimitStore) take(ctx context.Context, key string, limit rateLimit) (time.Duration, error) {
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	now := time.Now()
+
+	arrival, ok := store.buckets[key]
+	if !ok && len(store.buckets) >= maxRatelimitBuckets {
+		store.prune(now)
+	}
+
+	if arrival.Before(now) {
+		arrival = now
+	}
+
+	newArrival := arrival.Add(limit.interval)
+
+	if allowedAt := newArrival.Add(-time.Duration(limit.burst) * limit.interval); now.Before(allowedAt) {
+		return allowedAt.Sub(now), nil
+	}
+
+	store.buckets[key] = newArrival
+
+	return 0, nil
+}
+
+func (store *memoryRatelimitStore) close() error {
+	return nil
+}
+
+// prune drops the full buckets, which are the same as no bucket.
+func (store *memoryRatelimitStore) prune(now time.Time) {
+	for key, arrival := range store.buckets {
+		if arrival.Before(now) {
+			delete(store.buckets, key)
+		}
+	}
+}
+
+// redisRatelimitTake is the take of the generic cell rate algorithm, using the clock of redis
+// so that the members of a cluster agree on the time. The times are in microseconds.
+var redisRatelimitTake = redis.NewScript(`
+local time = redis.call("TIME")
+local now = tonumber(time[1]) * 1000000 + tonumber(time[2])
+local interval = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+
+local arrival = tonumber(redis.call("GET", KEYS[1])) or now
+if arrival < now then
+	arrival = now
+end
+
+local newArrival = arrival + interval
+local allowedAt = newArrival - burst * interval
+if now < allowedAt then
+	return allowedAt - now
+end
+
+redis.call("SET", KEYS[1], newArrival, "PX", math.ceil((newArrival - now) / 1000))
+
+return 0
+`) //nolint: gochecknoglobals
+
+// redisRatelimitStore shares the buckets between the members of a cluster.
+type redisRatelimitStore struct {
+	client    redis.UniversalClient
+	keyPrefix string
+}
+
+func (store *redisRatelimitStore) take(ctx context.Context, key string, limit rateLimit) (time.Duration, error) {
+	wait, err := redisRatelimitTake.Run(ctx, store.client, []string{store.keyPrefix + ":ratelimit:" + key},
+		limit.interval.Microseconds(), limit.burst).Int64()
+	if err != nil {
+		return 0, err
+	}
+
+	return time.Duration(wait) * time.Microsecond, nil
+}
+
+func (store *redisRatelimitStore) close() error {
+	return store.client.Close()
+}
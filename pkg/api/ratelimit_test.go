@@ -0,0 +1,203 @@
+//go:build sync && scrub && metrics && search && lint && userprefs && mgmt && imagetrust && ui
+// +build sync,scrub,metrics,search,lint,userprefs,mgmt,imagetrust,ui
+
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/resty.v1"
+
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/api/constants"
+	"zotregistry.dev/zot/pkg/log"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+	test "zotregistry.dev/zot/pkg/test/common"
+)
+
+func TestClientRateLimiter(t *testing.T) {
+	ratelimitConfig := &config.RatelimitConfig{
+		Anonymous:     &config.ClientRatelimitConfig{Rate: 1, Burst: 2},
+		Authenticated: &config.ClientRatelimitConfig{Rate: 10},
+		Repositories: []config.RepositoryRatelimitConfig{
+			{
+				Repositories:  []string{"private/**"},
+				Authenticated: &config.ClientRatelimitConfig{Rate: 1},
+			},
+		},
+	}
+
+	newRequest := func(remoteAddr, username, apiKey, repository string) *http.Request {
+		request := httptest.NewRequest(http.MethodGet, "/v2/", nil)
+		request.RemoteAddr = remoteAddr
+
+		if username != "" {
+			userAc := reqCtx.NewUserAccessControl()
+			userAc.SetUsername(username)
+			userAc.SetAPIKey(apiKey)
+			userAc.SaveOnRequest(request)
+		}
+
+		if repository != "" {
+			request = mux.SetURLVars(request, map[string]string{"name": repository})
+		}
+
+		return request
+	}
+
+	testLimiter := func(limiter *api.ClientRateLimiter) {
+		Convey("The anonymous clients are limited by source ip", func() {
+			So(limiter.Allow(newRequest("10.0.0.1:1234", "", "", "")), ShouldEqual, 0)
+			So(limiter.Allow(newRequest("10.0.0.1:1235", "", "", "")), ShouldEqual, 0)
+			So(limiter.Allow(newRequest("10.0.0.1:1236", "", "", "")), ShouldBeBetweenOrEqual,
+				time.Millisecond, time.Second)
+			So(limiter.Allow(newRequest("10.0.0.2:1234", "", "", "")), ShouldEqual, 0)
+
+			// the authenticated clients have their own limits, whatever their ip
+			So(limiter.Allow(newRequest("10.0.0.1:1237", "alice", "", "")), ShouldEqual, 0)
+		})
+
+		Convey("The authenticated clients are limited by api key or identity", func() {
+			for range 10 {
+				So(limiter.Allow(newRequest("10.0.0.1:1234", "alice", "", "")), ShouldEqual, 0)
+			}
+
+			So(limiter.Allow(newRequest("10.0.0.2:1234", "alice", "", "")), ShouldBeGreaterThan, 0)
+			So(limiter.Allow(newRequest("10.0.0.1:1234", "bob", "", "")), ShouldEqual, 0)
+			So(limiter.Allow(newRequest("10.0.0.1:1234", "alice", "hashed-key", "")), ShouldEqual, 0)
+		})
+
+		Convey("The clients are limited on the repositories matching the globs", func() {
+			So(limiter.Allow(newRequest("10.0.0.1:1234", "alice", "", "private/app")), ShouldEqual, 0)
+			So(limiter.Allow(newRequest("10.0.0.1:1234", "alice", "", "private/other")), ShouldBeGreaterThan, 0)
+			So(limiter.Allow(newRequest("10.0.0.1:1234", "alice", "", "public/app")), ShouldEqual, 0)
+			So(limiter.Allow(newRequest("10.0.0.1:1234", "bob", "", "private/app")), ShouldEqual, 0)
+
+			// no repository limit for the anonymous clients
+			So(limiter.Allow(newRequest("10.0.0.1:1234", "", "", "private/app")), ShouldEqual, 0)
+			So(limiter.Allow(newRequest("10.0.0.1:1234", "", "", "private/app")), ShouldEqual, 0)
+		})
+	}
+
+	Convey("Test limiting the clients in memory", t, func() {
+		limiter := api.NewClientRateLimiter(ratelimitConfig, nil, "", log.NewLogger("debug", ""))
+		defer limiter.Close()
+
+		testLimiter(limiter)
+
+		Convey("The buckets are refilled at the rate", func() {
+			for range 2 {
+				So(limiter.Allow(newRequest("10.0.0.3:1234", "", "", "")), ShouldEqual, 0)
+			}
+
+			retryAfter := limiter.Allow(newRequest("10.0.0.3:1234", "", "", ""))
+			So(retryAfter, ShouldBeGreaterThan, 0)
+
+			time.Sleep(retryAfter)
+			So(limiter.Allow(newRequest("10.0.0.3:1234", "", "", "")), ShouldEqual, 0)
+		})
+	})
+
+	Convey("Test limiting the clients in redis", t, func() {
+		miniRedis := miniredis.RunT(t)
+
+		limiter := api.NewClientRateLimiter(ratelimitConfig, redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}),
+			"zot", log.NewLogger("debug", ""))
+		defer limiter.Close()
+
+		testLimiter(limiter)
+
+		Convey("The members of a cluster share the limits", func() {
+			other := api.NewClientRateLimiter(ratelimitConfig, redis.NewClient(&redis.Options{Addr: miniRedis.Addr()}),
+				"zot", log.NewLogger("debug", ""))
+			defer other.Close()
+
+			So(limiter.Allow(newRequest("10.0.0.4:1234", "", "", "")), ShouldEqual, 0)
+			So(other.Allow(newRequest("10.0.0.4:1234", "", "", "")), ShouldEqual, 0)
+			So(other.Allow(newRequest("10.0.0.4:1234", "", "", "")), ShouldBeGreaterThan, 0)
+			So(miniRedis.Exists("zot:ratelimit:ip:10.0.0.4"), ShouldBeTrue)
+		})
+
+		Convey("The clients are not limited when redis fails", func() {
+			miniRedis.Close()
+
+			for range 3 {
+				So(limiter.Allow(newRequest("10.0.0.5:1234", "", "", "")), ShouldEqual, 0)
+			}
+		})
+	})
+}
+
+func TestClientRateLimitHandler(t *testing.T) {
+	Convey("Make a new controller with per client rate limits", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+
+		username, password := "alice", "alice"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(username, password))
+		defer os.Remove(htpasswdPath)
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth = &config.AuthConfig{
+			HTPasswd: config.AuthHTPasswd{Path: htpasswdPath},
+		}
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					AnonymousPolicy: []string{constants.ReadPermission},
+					Policies: []config.Policy{
+						{Users: []string{username}, Actions: []string{constants.ReadPermission}},
+					},
+				},
+			},
+		}
+		conf.HTTP.Ratelimit = &config.RatelimitConfig{
+			Anonymous:     &config.ClientRatelimitConfig{Rate: 0.1, Burst: 1},
+			Authenticated: &config.ClientRatelimitConfig{Rate: 0.1, Burst: 2},
+		}
+
+		ctlr := makeController(conf, t.TempDir())
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		resp, err := resty.R().Get(baseURL + "/v2/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+		resp, err = resty.R().Get(baseURL + "/v2/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusTooManyRequests)
+		So(resp.Header().Get("Retry-After"), ShouldEqual, "10")
+		So(string(resp.Body()), ShouldContainSubstring, "TOOMANYREQUESTS")
+
+		// the authenticated users are not limited by the anonymous clients sharing their ip
+		for range 2 {
+			resp, err = resty.R().SetBasicAuth(username, password).Get(baseURL + "/v2/app/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+		}
+
+		resp, err = resty.R().SetBasicAuth(username, password).Get(baseURL + "/v2/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusTooManyRequests)
+
+		// the requests failing authentication are not counted
+		resp, err = resty.R().SetBasicAuth(username, "wrong").Get(baseURL + "/v2/app/tags/list")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+	})
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+
+	"zotregistry.dev/zot/pkg/api/constants"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+)
+
+const maxRequestIDLength = 128
+
+// RequestIDHandler gives each request the id sent by its client in the X-Request-ID header, or a generated one,
+// saves it on the request and returns it in the response. The events logged with the request context carry it,
+// as well as the audit records, the error responses and the requests forwarded to the other members of a cluster.
+func RequestIDHandler() mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			requestID := request.Header.Get(constants.RequestIDHeader)
+			if !isValidRequestID(requestID) {
+				requestID = uuid.NewString()
+			}
+
+			response.Header().Set(constants.RequestIDHeader, requestID)
+
+			next.ServeHTTP(response, request.WithContext(reqCtx.WithRequestID(request.Context(), requestID)))
+		})
+	}
+}
+
+// isValidRequestID accepts the ids of up to 128 visible ascii characters, the other ones are replaced.
+func isValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for idx := range len(requestID) {
+		if requestID[idx] <= ' ' || requestID[idx] > '~' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,184 @@
+//go:build sync && scrub && metrics && search && lint && userprefs && mgmt && imagetrust && ui
+// +build sync,scrub,metrics,search,lint,userprefs,mgmt,imagetrust,ui
+
+package api_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	godigest "github.com/opencontainers/go-digest"
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/resty.v1"
+
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/api/constants"
+	apiErr "zotregistry.dev/zot/pkg/api/errors"
+	test "zotregistry.dev/zot/pkg/test/common"
+)
+
+// readLogEvents returns the json events of a log file.
+func readLogEvents(logPath string) []map[string]any {
+	file, err := os.Open(logPath)
+	So(err, ShouldBeNil)
+
+	defer file.Close()
+
+	events := []map[string]any{}
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		var event map[string]any
+
+		if err := json.Unmarshal(scanner.Bytes(), &event); err == nil {
+			events = append(events, event)
+		}
+	}
+
+	return events
+}
+
+func TestRequestID(t *testing.T) {
+	Convey("Make a new controller", t, func() {
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+		dir := t.TempDir()
+
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.Log = &config.LogConfig{
+			Level:  "debug",
+			Output: path.Join(dir, "zot.log"),
+			Audit:  path.Join(dir, "zot-audit.log"),
+		}
+
+		ctlr := makeController(conf, path.Join(dir, "storage"))
+
+		cm := test.NewControllerManager(ctlr)
+		cm.StartAndWait(port)
+
+		defer cm.StopServer()
+
+		Convey("The requests without an id get a generated one", func() {
+			resp, err := resty.R().Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			_, err = uuid.Parse(resp.Header().Get(constants.RequestIDHeader))
+			So(err, ShouldBeNil)
+
+			// invalid ids are replaced
+			for _, requestID := range []string{"push 1", strings.Repeat("a", 129), "pushé1"} {
+				resp, err = resty.R().SetHeader(constants.RequestIDHeader, requestID).Get(baseURL + "/v2/")
+				So(err, ShouldBeNil)
+
+				_, err = uuid.Parse(resp.Header().Get(constants.RequestIDHeader))
+				So(err, ShouldBeNil)
+			}
+		})
+
+		Convey("The ids of the clients are returned in the responses and the errors", func() {
+			resp, err := resty.R().SetHeader(constants.RequestIDHeader, "pull-1").
+				Get(baseURL + "/v2/alpine/manifests/latest")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+			So(resp.Header().Get(constants.RequestIDHeader), ShouldEqual, "pull-1")
+
+			var errorList apiErr.ErrorList
+
+			So(json.Unmarshal(resp.Body(), &errorList), ShouldBeNil)
+			So(errorList.RequestID, ShouldEqual, "pull-1")
+			So(errorList.Errors, ShouldNotBeEmpty)
+			So(errorList.Errors[0].RequestID, ShouldBeEmpty)
+		})
+
+		Convey("The logs and the audit records of a request carry its id", func() {
+			resp, err := resty.R().SetHeader(constants.RequestIDHeader, "push-1").
+				SetHeader("Content-Type", "application/vnd.oci.image.manifest.v1+json").
+				SetBody([]byte("{}")).Put(baseURL + "/v2/alpine/manifests/latest")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+			// the session and the audit records are written once the response is sent
+			var requestEvents []map[string]any
+
+			for range 50 {
+				requestEvents = requestEvents[:0]
+
+				for _, event := range readLogEvents(conf.Log.Output) {
+					if event["requestID"] == "push-1" {
+						requestEvents = append(requestEvents, event)
+					}
+				}
+
+				if len(requestEvents) > 0 && requestEvents[len(requestEvents)-1]["message"] == "HTTP API" {
+					break
+				}
+
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			So(requestEvents, ShouldNotBeEmpty)
+
+			// the events logged by the image store carry the id too
+			requestMessages := []any{}
+
+			for _, event := range requestEvents {
+				requestMessages = append(requestMessages, event["message"])
+			}
+
+			So(requestMessages, ShouldContain, "failed to validate OCIv1 image manifest schema")
+
+			session := requestEvents[len(requestEvents)-1]
+			So(session["message"], ShouldEqual, "HTTP API")
+			So(session["path"], ShouldEqual, "/v2/alpine/manifests/latest")
+
+			// the events logged by the handlers carry the id too
+			resp, err = resty.R().SetHeader(constants.RequestIDHeader, "referrers-1").
+				Get(baseURL + "/v2/alpine/referrers/" + godigest.FromString("missing").String())
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			handlerMessages := map[string]string{}
+
+			for range 50 {
+				for _, event := range readLogEvents(conf.Log.Output) {
+					if requestID, _ := event["requestID"].(string); requestID != "" {
+						message, _ := event["message"].(string)
+						handlerMessages[message] = requestID
+					}
+				}
+
+				if handlerMessages["getting manifest"] != "" {
+					break
+				}
+
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			So(handlerMessages["getting manifest"], ShouldEqual, "referrers-1")
+
+			var auditRecords []map[string]any
+
+			for range 50 {
+				if auditRecords = readLogEvents(conf.Log.Audit); len(auditRecords) > 0 {
+					break
+				}
+
+				time.Sleep(100 * time.Millisecond)
+			}
+
+			So(len(auditRecords), ShouldEqual, 1)
+			So(auditRecords[0]["requestID"], ShouldEqual, "push-1")
+		})
+	})
+}
@@ -33,6 +33,7 @@ import (
 	"github.com/zitadel/oidc/v3/pkg/oidc"
 
 	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/accounts"
 	"zotregistry.dev/zot/pkg/api/config"
 	"zotregistry.dev/zot/pkg/api/constants"
 	apiErr "zotregistry.dev/zot/pkg/api/errors"
@@ -51,6 +52,7 @@ import (
 	storageCommon "zotregistry.dev/zot/pkg/storage/common"
 	storageTypes "zotregistry.dev/zot/pkg/storage/types"
 	"zotregistry.dev/zot/pkg/test/inject"
+	"zotregistry.dev/zot/pkg/tracing"
 )
 
 type RouteHandler struct {
@@ -68,6 +70,12 @@ func (rh *RouteHandler) SetupRoutes() {
 	// first get Auth middleware in order to first setup openid/ldap/htpasswd, before oidc provider routes are setup
 	authHandler := AuthHandler(rh.c)
 
+	// with the built-in token server, the users authenticate with their credentials to get bearer tokens
+	credentialsAuthHandler := authHandler
+	if rh.c.Config.IsTokenServerEnabled() {
+		credentialsAuthHandler = CredentialsAuthHandler(rh.c)
+	}
+
 	applyCORSHeaders := getCORSHeadersHandler(rh.c.Config.HTTP.AllowOrigin)
 
 	if rh.c.Config.IsOpenIDAuthEnabled() {
@@ -78,10 +86,10 @@ func (rh *RouteHandler) SetupRoutes() {
 		for provider, relyingParty := range rh.c.RelyingParties {
 			if config.IsOauth2Supported(provider) {
 				rh.c.Router.HandleFunc(constants.CallbackBasePath+"/"+provider,
-					rp.CodeExchangeHandler(rh.GithubCodeExchangeCallback(), relyingParty))
+					rp.CodeExchangeHandler(rh.GithubCodeExchangeCallback(provider), relyingParty))
 			} else if config.IsOpenIDSupported(provider) {
 				rh.c.Router.HandleFunc(constants.CallbackBasePath+"/"+provider,
-					rp.CodeExchangeHandler(rp.UserinfoCallback(rh.OpenIDCodeExchangeCallback()), relyingParty))
+					rp.CodeExchangeHandler(rp.UserinfoCallback(rh.OpenIDCodeExchangeCallback(provider)), relyingParty))
 			}
 		}
 	}
@@ -89,8 +97,9 @@ func (rh *RouteHandler) SetupRoutes() {
 	if rh.c.Config.IsAPIKeyEnabled() {
 		// enable api key management urls
 		apiKeyRouter := rh.c.Router.PathPrefix(constants.APIKeyPath).Subrouter()
-		apiKeyRouter.Use(authHandler)
+		apiKeyRouter.Use(credentialsAuthHandler)
 		apiKeyRouter.Use(BaseAuthzHandler(rh.c))
+		apiKeyRouter.Use(zcommon.AuthzUnscopedMiddleware(rh.c.Config))
 
 		// Always use CORSHeadersMiddleware before ACHeadersMiddleware
 		apiKeyRouter.Use(zcommon.CORSHeadersMiddleware(rh.c.Config.HTTP.AllowOrigin))
@@ -100,6 +109,49 @@ func (rh *RouteHandler) SetupRoutes() {
 		apiKeyRouter.Methods(http.MethodPost, http.MethodOptions).HandlerFunc(rh.CreateAPIKey)
 		apiKeyRouter.Methods(http.MethodGet).HandlerFunc(rh.GetAPIKeys)
 		apiKeyRouter.Methods(http.MethodDelete).HandlerFunc(rh.RevokeAPIKey)
+
+		// admins manage the service accounts and their api keys
+		serviceAccountsRouter := rh.c.Router.PathPrefix(constants.ServiceAccountsPath).Subrouter()
+		serviceAccountsRouter.Use(credentialsAuthHandler)
+		serviceAccountsRouter.Use(BaseAuthzHandler(rh.c))
+
+		serviceAccountsRouter.HandleFunc("", rh.GetServiceAccounts).Methods(http.MethodGet)
+		serviceAccountsRouter.HandleFunc("", rh.CreateServiceAccount).Methods(http.MethodPost)
+		serviceAccountsRouter.HandleFunc("/{name}", rh.GetServiceAccount).Methods(http.MethodGet)
+		serviceAccountsRouter.HandleFunc("/{name}", rh.UpdateServiceAccount).Methods(http.MethodPatch)
+		serviceAccountsRouter.HandleFunc("/{name}", rh.DeleteServiceAccount).Methods(http.MethodDelete)
+		serviceAccountsRouter.HandleFunc("/{name}/apikey", rh.CreateServiceAccountAPIKey).Methods(http.MethodPost)
+		serviceAccountsRouter.HandleFunc("/{name}/apikey", rh.RevokeServiceAccountAPIKey).Methods(http.MethodDelete)
+	}
+
+	if rh.c.Config.IsBasicAuthnEnabled() {
+		// the users list and revoke their web sessions, the admins those of any user
+		sessionsRouter := rh.c.Router.PathPrefix(constants.SessionsPath).Subrouter()
+		sessionsRouter.Use(credentialsAuthHandler)
+		sessionsRouter.Use(BaseAuthzHandler(rh.c))
+		sessionsRouter.Use(zcommon.AuthzUnscopedMiddleware(rh.c.Config))
+
+		// Always use CORSHeadersMiddleware before ACHeadersMiddleware
+		sessionsRouter.Use(zcommon.CORSHeadersMiddleware(rh.c.Config.HTTP.AllowOrigin))
+		sessionsRouter.Use(zcommon.ACHeadersMiddleware(rh.c.Config,
+			http.MethodGet, http.MethodDelete, http.MethodOptions))
+
+		sessionsRouter.Methods(http.MethodGet).HandlerFunc(rh.GetUserSessions)
+		sessionsRouter.Methods(http.MethodDelete, http.MethodOptions).HandlerFunc(rh.RevokeUserSession)
+	}
+
+	if rh.c.Config.IsTokenServerEnabled() {
+		tokenRouter := rh.c.Router.PathPrefix(constants.TokenPath).Subrouter()
+		tokenRouter.Use(credentialsAuthHandler)
+		tokenRouter.Methods(http.MethodGet).HandlerFunc(rh.GetBearerToken)
+	}
+
+	if rh.c.Config.IsLockoutEnabled() {
+		// admins lift the lockouts of the users and client ips
+		lockoutRouter := rh.c.Router.PathPrefix(constants.LockoutPath).Subrouter()
+		lockoutRouter.Use(credentialsAuthHandler)
+		lockoutRouter.Use(BaseAuthzHandler(rh.c))
+		lockoutRouter.Methods(http.MethodDelete).HandlerFunc(rh.ClearAuthLockout)
 	}
 
 	/* on every route which may be used by UI we set OPTIONS as allowed METHOD
@@ -113,6 +165,8 @@ func (rh *RouteHandler) SetupRoutes() {
 
 	prefixedRouter := rh.c.Router.PathPrefix(constants.RoutePrefix).Subrouter()
 	prefixedRouter.Use(authHandler)
+	// the clients are identified once authenticated
+	prefixedRouter.Use(ClientRateLimitHandler(rh.c))
 
 	prefixedDistSpecRouter := prefixedRouter.NewRoute().Subrouter()
 	// authz is being enabled if AccessControl is specified
@@ -126,6 +180,9 @@ func (rh *RouteHandler) SetupRoutes() {
 
 		prefixedRouter.Use(BaseAuthzHandler(rh.c))
 		prefixedDistSpecRouter.Use(DistSpecAuthzHandler(rh.c))
+	} else if rh.c.Config.IsAPIKeyEnabled() {
+		// api key scopes need to be enforced even if access control is not configured
+		prefixedDistSpecRouter.Use(DistSpecAuthzHandler(rh.c))
 	}
 
 	clusterRouteProxy := ClusterProxy(rh.c)
@@ -211,7 +268,8 @@ func (rh *RouteHandler) SetupRoutes() {
 	ext.SetupSearchRoutes(rh.c.Config, prefixedRouter, rh.c.StoreController, rh.c.MetaDB, rh.c.CveScanner,
 		rh.c.Log)
 	ext.SetupImageTrustRoutes(rh.c.Config, prefixedRouter, rh.c.MetaDB, rh.c.Log)
-	ext.SetupMgmtRoutes(rh.c.Config, prefixedRouter, rh.c.Log)
+	ext.SetupMgmtRoutes(rh.c.Config, rh.c.QuotaManager, rh.c.Accounts, rh.c.StoreController, rh.c.MetaDB,
+		rh.c.GetImmutableTags, rh.c.EventsNotifier, prefixedRouter, rh.c.Log)
 	ext.SetupUserPreferencesRoutes(rh.c.Config, prefixedRouter, rh.c.MetaDB, rh.c.Log)
 	// last should always be UI because it will setup a http.FileServer and paths will be resolved by this FileServer.
 	ext.SetupUIRoutes(rh.c.Config, rh.c.Router, rh.c.Log)
@@ -348,7 +406,7 @@ func (rh *RouteHandler) ListTags(response http.ResponseWriter, request *http.Req
 		last = lastQuery[0]
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	tags, err := imgStore.GetImageTags(name)
 	if err != nil {
@@ -423,6 +481,8 @@ func (rh *RouteHandler) ListTags(response http.ResponseWriter, request *http.Req
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error".
 func (rh *RouteHandler) CheckManifest(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	if request.Method == http.MethodOptions {
 		return
 	}
@@ -436,7 +496,7 @@ func (rh *RouteHandler) CheckManifest(response http.ResponseWriter, request *htt
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	reference, ok := vars["reference"]
 	if !ok || reference == "" {
@@ -458,7 +518,7 @@ func (rh *RouteHandler) CheckManifest(response http.ResponseWriter, request *htt
 			e := apiErr.NewError(apiErr.MANIFEST_UNKNOWN).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 
 			e := apiErr.NewError(apiErr.MANIFEST_INVALID).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusInternalServerError, apiErr.NewErrorList(e))
@@ -494,6 +554,8 @@ type ExtensionList struct {
 // @Failure 500 {string} string "internal server error"
 // @Router /v2/{name}/manifests/{reference} [get].
 func (rh *RouteHandler) GetManifest(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	if rh.c.Config.IsBasicAuthnEnabled() {
 		response.Header().Set("Access-Control-Allow-Credentials", "true")
 	}
@@ -507,7 +569,7 @@ func (rh *RouteHandler) GetManifest(response http.ResponseWriter, request *http.
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	reference, ok := vars["reference"]
 	if !ok || reference == "" {
@@ -533,7 +595,7 @@ func (rh *RouteHandler) GetManifest(response http.ResponseWriter, request *http.
 			e := apiErr.NewError(apiErr.MANIFEST_UNKNOWN).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
 
@@ -541,7 +603,7 @@ func (rh *RouteHandler) GetManifest(response http.ResponseWriter, request *http.
 	}
 
 	if rh.c.MetaDB != nil {
-		err := meta.OnGetManifest(name, reference, mediaType, content, rh.c.StoreController, rh.c.MetaDB, rh.c.Log)
+		err := meta.OnGetManifest(name, reference, mediaType, content, rh.c.StoreController, rh.c.MetaDB, logger)
 		if err != nil && !errors.Is(err, zerr.ErrImageMetaNotFound) && !errors.Is(err, zerr.ErrRepoMetaNotFound) {
 			response.WriteHeader(http.StatusInternalServerError)
 
@@ -565,7 +627,7 @@ func getReferrers(ctx context.Context, routeHandler *RouteHandler,
 ) (ispec.Index, error) {
 	refs, err := imgStore.GetReferrers(name, digest, artifactTypes)
 	if err != nil || len(refs.Manifests) == 0 {
-		if isSyncOnDemandEnabled(*routeHandler.c) {
+		if isSyncOnDemandEnabled(routeHandler.c) {
 			routeHandler.c.Log.Info().Str("repository", name).Str("reference", digest.String()).
 				Msg("referrers not found, trying to get reference by syncing on demand")
 
@@ -595,6 +657,8 @@ func getReferrers(ctx context.Context, routeHandler *RouteHandler,
 // @Failure 500 {string} string "internal server error"
 // @Router /v2/{name}/referrers/{digest} [get].
 func (rh *RouteHandler) GetReferrers(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	if request.Method == http.MethodOptions {
 		return
 	}
@@ -620,18 +684,18 @@ func (rh *RouteHandler) GetReferrers(response http.ResponseWriter, request *http
 	// filter by artifact type (more than one can be specified)
 	artifactTypes := request.URL.Query()["artifactType"]
 
-	rh.c.Log.Info().Str("digest", digest.String()).Interface("artifactType", artifactTypes).Msg("getting manifest")
+	logger.Info().Str("digest", digest.String()).Interface("artifactType", artifactTypes).Msg("getting manifest")
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	referrers, err := getReferrers(request.Context(), rh, imgStore, name, digest, artifactTypes)
 	if err != nil {
 		if errors.Is(err, zerr.ErrManifestNotFound) || errors.Is(err, zerr.ErrRepoNotFound) {
-			rh.c.Log.Error().Err(err).Str("name", name).Str("digest", digest.String()).
+			logger.Error().Err(err).Str("name", name).Str("digest", digest.String()).
 				Msg("failed to get manifest")
 			response.WriteHeader(http.StatusNotFound)
 		} else {
-			rh.c.Log.Error().Err(err).Str("name", name).Str("digest", digest.String()).
+			logger.Error().Err(err).Str("name", name).Str("digest", digest.String()).
 				Msg("failed to get references")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
@@ -641,7 +705,7 @@ func (rh *RouteHandler) GetReferrers(response http.ResponseWriter, request *http
 
 	out, err := json.Marshal(referrers)
 	if err != nil {
-		rh.c.Log.Error().Err(err).Str("name", name).Str("digest", digest.String()).Msg("failed to marshal json")
+		logger.Error().Err(err).Str("name", name).Str("digest", digest.String()).Msg("failed to marshal json")
 		response.WriteHeader(http.StatusInternalServerError)
 
 		return
@@ -662,13 +726,17 @@ func (rh *RouteHandler) GetReferrers(response http.ResponseWriter, request *http
 // @Produce json
 // @Param   name         path    string     true        "repository name"
 // @Param   reference    path    string     true        "image reference or digest"
+// @Param   force        query   boolean    false       "admin override of the immutable tags policies"
 // @Header  201 {object} constants.DistContentDigestKey
 // @Success 201 {string} string "created"
 // @Failure 400 {string} string "bad request"
+// @Failure 403 {string} string "denied"
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /v2/{name}/manifests/{reference} [put].
 func (rh *RouteHandler) UpdateManifest(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -678,7 +746,7 @@ func (rh *RouteHandler) UpdateManifest(response http.ResponseWriter, request *ht
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	reference, ok := vars["reference"]
 	if !ok || reference == "" {
@@ -700,12 +768,14 @@ func (rh *RouteHandler) UpdateManifest(response http.ResponseWriter, request *ht
 	// hard to reach test case, injected error (simulates an interrupted image manifest upload)
 	// err could be io.ErrUnexpectedEOF
 	if err := inject.Error(err); err != nil {
-		rh.c.Log.Error().Err(err).Msg("unexpected error")
+		logger.Error().Err(err).Msg("unexpected error")
 		response.WriteHeader(http.StatusInternalServerError)
 
 		return
 	}
 
+	imgStore = rh.overrideImmutableTags(request, name, imgStore)
+
 	digest, subjectDigest, err := imgStore.PutImageManifest(name, reference, mediaType, body)
 	if err != nil {
 		details := zerr.GetDetails(err)
@@ -729,15 +799,19 @@ func (rh *RouteHandler) UpdateManifest(response http.ResponseWriter, request *ht
 			details["reference"] = reference
 			e := apiErr.NewError(apiErr.MANIFEST_INVALID).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusBadRequest, apiErr.NewErrorList(e))
+		} else if errors.Is(err, zerr.ErrImmutableTag) || errors.Is(err, zerr.ErrStorageQuotaExceeded) {
+			e := apiErr.NewError(apiErr.DENIED).AddDetail(details)
+			zcommon.WriteJSON(response, http.StatusForbidden, apiErr.NewErrorList(e))
 		} else {
 			// could be syscall.EMFILE (Err:0x18 too many opened files), etc
-			rh.c.Log.Error().Err(err).Msg("unexpected error, performing cleanup")
+			logger.Error().Err(err).Msg("unexpected error, performing cleanup")
 
-			if err = imgStore.DeleteImageManifest(name, reference, false); err != nil {
+			// the cleanup removes what this request wrote, whatever the write policies
+			if err = imgStore.WithoutWritePolicies().DeleteImageManifest(name, reference, false); err != nil {
 				// deletion of image manifest is important, but not critical for image repo consistency
 				// in the worst scenario a partial manifest file written to disk will not affect the repo because
 				// the new manifest was not added to "index.json" file (it is possible that GC will take care of it)
-				rh.c.Log.Error().Err(err).Str("repository", name).Str("reference", reference).
+				logger.Error().Err(err).Str("repository", name).Str("reference", reference).
 					Msg("couldn't remove image manifest in repo")
 			}
 
@@ -748,8 +822,10 @@ func (rh *RouteHandler) UpdateManifest(response http.ResponseWriter, request *ht
 	}
 
 	if rh.c.MetaDB != nil {
-		err := meta.OnUpdateManifest(request.Context(), name, reference, mediaType,
-			digest, body, rh.c.StoreController, rh.c.MetaDB, rh.c.Log)
+		ctx := reqCtx.WithClient(request.Context(), request.UserAgent())
+
+		err := meta.OnUpdateManifest(ctx, name, reference, mediaType,
+			digest, body, rh.c.StoreController, rh.c.MetaDB, rh.c.EventsNotifier, logger)
 		if err != nil {
 			response.WriteHeader(http.StatusInternalServerError)
 
@@ -773,9 +849,13 @@ func (rh *RouteHandler) UpdateManifest(response http.ResponseWriter, request *ht
 // @Produce json
 // @Param   name          path    string     true        "repository name"
 // @Param   reference     path    string     true        "image reference or digest"
+// @Param   force         query   boolean    false       "admin override of the immutable tags policies"
 // @Success 200 {string} string "ok"
+// @Failure 403 {string} string "denied"
 // @Router /v2/{name}/manifests/{reference} [delete].
 func (rh *RouteHandler) DeleteManifest(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -785,7 +865,7 @@ func (rh *RouteHandler) DeleteManifest(response http.ResponseWriter, request *ht
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	reference, ok := vars["reference"]
 	if !ok || reference == "" {
@@ -823,13 +903,15 @@ func (rh *RouteHandler) DeleteManifest(response http.ResponseWriter, request *ht
 			e := apiErr.NewError(apiErr.UNSUPPORTED).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusBadRequest, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
 
 		return
 	}
 
+	imgStore = rh.overrideImmutableTags(request, name, imgStore)
+
 	err = imgStore.DeleteImageManifest(name, reference, detectCollision)
 	if err != nil { //nolint: dupl
 		details := zerr.GetDetails(err)
@@ -854,8 +936,11 @@ func (rh *RouteHandler) DeleteManifest(response http.ResponseWriter, request *ht
 			details["reference"] = reference
 			e := apiErr.NewError(apiErr.DENIED).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusMethodNotAllowed, apiErr.NewErrorList(e))
+		} else if errors.Is(err, zerr.ErrImmutableTag) || errors.Is(err, zerr.ErrStorageQuotaExceeded) {
+			e := apiErr.NewError(apiErr.DENIED).AddDetail(details)
+			zcommon.WriteJSON(response, http.StatusForbidden, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
 
@@ -863,8 +948,10 @@ func (rh *RouteHandler) DeleteManifest(response http.ResponseWriter, request *ht
 	}
 
 	if rh.c.MetaDB != nil {
-		err := meta.OnDeleteManifest(name, reference, mediaType, manifestDigest, manifestBlob,
-			rh.c.StoreController, rh.c.MetaDB, rh.c.Log)
+		ctx := reqCtx.WithClient(request.Context(), request.UserAgent())
+
+		err := meta.OnDeleteManifest(ctx, name, reference, mediaType, manifestDigest, manifestBlob,
+			rh.c.StoreController, rh.c.MetaDB, rh.c.EventsNotifier, logger)
 		if err != nil {
 			response.WriteHeader(http.StatusInternalServerError)
 
@@ -904,6 +991,91 @@ func canMount(userAc *reqCtx.UserAccessControl, imgStore storageTypes.ImageStore
 	return canMount, nil
 }
 
+// mountBlob links a blob of the "from" repository into the "name" repository.
+// It returns false if the user can not read the source repository or the blob can not be mounted.
+func (rh *RouteHandler) mountBlob(userAc *reqCtx.UserAccessControl, imgStore storageTypes.ImageStore,
+	name, from string, digest godigest.Digest,
+) bool {
+	if !userAc.Can(constants.ReadPermission, from) {
+		return false
+	}
+
+	// blobs can only be linked inside the same storage
+	if rh.c.StoreController.GetImageStore(from).RootDir() != imgStore.RootDir() {
+		return false
+	}
+
+	if _, err := imgStore.MountBlob(name, from, digest); err != nil {
+		rh.c.Log.Info().Err(err).Str("repository", name).Str("from", from).Str("digest", digest.String()).
+			Msg("failed to mount blob")
+
+		return false
+	}
+
+	return true
+}
+
+func (rh *RouteHandler) isQuotaEnabled() bool {
+	return rh.c.QuotaManager != nil && rh.c.Config.IsQuotaEnabled()
+}
+
+// checkBlobQuota responds with a DENIED error and returns false
+// if a blob of the given size would exceed the storage quotas of the repository.
+// It rejects the uploads early, the quotas being enforced by the image stores when the blobs are written.
+func (rh *RouteHandler) checkBlobQuota(response http.ResponseWriter, name string, size int64) bool {
+	if !rh.isQuotaEnabled() {
+		return true
+	}
+
+	return rh.handleQuotaError(response, rh.c.QuotaManager.CheckPutBlob(name, size))
+}
+
+func (rh *RouteHandler) handleQuotaError(response http.ResponseWriter, err error) bool {
+	if err == nil {
+		return true
+	}
+
+	if errors.Is(err, zerr.ErrStorageQuotaExceeded) {
+		e := apiErr.NewError(apiErr.DENIED).AddDetail(zerr.GetDetails(err))
+		zcommon.WriteJSON(response, http.StatusForbidden, apiErr.NewErrorList(e))
+	} else {
+		response.WriteHeader(http.StatusInternalServerError)
+	}
+
+	return false
+}
+
+// overrideImmutableTags returns a view of imgStore which does not enforce the immutable tags policies of the
+// repository, if an authenticated admin explicitly asks for it with the force query parameter, or imgStore otherwise.
+func (rh *RouteHandler) overrideImmutableTags(request *http.Request, name string,
+	imgStore storageTypes.ImageStore,
+) storageTypes.ImageStore {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
+	if request.URL.Query().Get("force") != "true" {
+		return imgStore
+	}
+
+	immutableTags := rh.c.GetImmutableTags(rh.c.StoreController.GetStorePath(name))
+	if immutableTags == nil {
+		return imgStore
+	}
+
+	// without authentication nobody is identified as an admin, whatever the authn method otherwise
+	userAc, err := reqCtx.UserAcFromContext(request.Context())
+	if err != nil || request.Context().Value(reqCtx.GetContextKey()) == nil ||
+		userAc.IsAnonymous() || !userAc.IsAdmin() {
+		logger.Warn().Str("repository", name).Msg("immutable tags policies can only be overridden by admins")
+
+		return imgStore
+	}
+
+	logger.Info().Str("repository", name).Str("username", userAc.GetUsername()).
+		Msg("immutable tags policies overridden")
+
+	return imgStore.WithoutWritePolicies(immutableTags)
+}
+
 // CheckBlob godoc
 // @Summary Check image blob/layer
 // @Description Check an image's blob/layer given a digest
@@ -915,6 +1087,8 @@ func canMount(userAc *reqCtx.UserAccessControl, imgStore storageTypes.ImageStore
 // @Header  200 {object} constants.DistContentDigestKey
 // @Router /v2/{name}/blobs/{digest} [head].
 func (rh *RouteHandler) CheckBlob(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -924,7 +1098,7 @@ func (rh *RouteHandler) CheckBlob(response http.ResponseWriter, request *http.Re
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	digestStr, ok := vars["digest"]
 
@@ -947,7 +1121,7 @@ func (rh *RouteHandler) CheckBlob(response http.ResponseWriter, request *http.Re
 	if rh.c.Config.IsAuthzEnabled() {
 		userCanMount, err = canMount(userAc, imgStore, digest)
 		if err != nil {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 		}
 	}
 
@@ -958,8 +1132,8 @@ func (rh *RouteHandler) CheckBlob(response http.ResponseWriter, request *http.Re
 	} else {
 		var lockLatency time.Time
 
-		imgStore.RLock(&lockLatency)
-		defer imgStore.RUnlock(&lockLatency)
+		imgStore.RLockRepo(name, &lockLatency)
+		defer imgStore.RUnlockRepo(name, &lockLatency)
 
 		ok, blen, _, err = imgStore.StatBlob(name, digest)
 	}
@@ -979,7 +1153,7 @@ func (rh *RouteHandler) CheckBlob(response http.ResponseWriter, request *http.Re
 			e := apiErr.NewError(apiErr.BLOB_UNKNOWN).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
 
@@ -1057,6 +1231,8 @@ func parseRangeHeader(contentRange string) (int64, int64, error) {
 // @Success 200 {object} api.ImageManifest
 // @Router /v2/{name}/blobs/{digest} [get].
 func (rh *RouteHandler) GetBlob(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -1066,7 +1242,7 @@ func (rh *RouteHandler) GetBlob(response http.ResponseWriter, request *http.Requ
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	digestStr, ok := vars["digest"]
 
@@ -1132,7 +1308,7 @@ func (rh *RouteHandler) GetBlob(response http.ResponseWriter, request *http.Requ
 			e := apiErr.NewError(apiErr.BLOB_UNKNOWN).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
 
@@ -1154,7 +1330,7 @@ func (rh *RouteHandler) GetBlob(response http.ResponseWriter, request *http.Requ
 	}
 
 	// return the blob data
-	WriteDataFromReader(response, status, blen, mediaType, repo, rh.c.Log)
+	WriteDataFromReader(response, status, blen, mediaType, repo, logger)
 }
 
 // DeleteBlob godoc
@@ -1167,6 +1343,8 @@ func (rh *RouteHandler) GetBlob(response http.ResponseWriter, request *http.Requ
 // @Success 202 {string} string "accepted"
 // @Router /v2/{name}/blobs/{digest} [delete].
 func (rh *RouteHandler) DeleteBlob(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -1185,7 +1363,7 @@ func (rh *RouteHandler) DeleteBlob(response http.ResponseWriter, request *http.R
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	err = imgStore.DeleteBlob(name, digest)
 	if err != nil {
@@ -1207,7 +1385,7 @@ func (rh *RouteHandler) DeleteBlob(response http.ResponseWriter, request *http.R
 			e := apiErr.NewError(apiErr.DENIED).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusMethodNotAllowed, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
 
@@ -1223,14 +1401,22 @@ func (rh *RouteHandler) DeleteBlob(response http.ResponseWriter, request *http.R
 // @Accept  json
 // @Produce json
 // @Param   name    path    string     true        "repository name"
+// @Param   mount   query   string     false       "digest of the blob to mount"
+// @Param   from    query   string     false       "repository to mount the blob from"
+// @Success 201 {string} string "created"
+// @Header  201 {string} Location "/v2/{name}/blobs/{digest}"
+// @Header  201 {object} constants.DistContentDigestKey
 // @Success 202 {string} string "accepted"
 // @Header  202 {string} Location "/v2/{name}/blobs/uploads/{session_id}"
 // @Header  202 {string} Range "0-0"
 // @Failure 401 {string} string "unauthorized"
+// @Failure 403 {string} string "denied"
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /v2/{name}/blobs/uploads [post].
 func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -1240,9 +1426,8 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
-	// currently zot does not support cross-repository mounting, following dist-spec and returning 202
 	if mountDigests, ok := request.URL.Query()["mount"]; ok {
 		if len(mountDigests) != 1 {
 			response.WriteHeader(http.StatusBadRequest)
@@ -1259,22 +1444,30 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 			return
 		}
 
-		userCanMount := true
-		if rh.c.Config.IsAuthzEnabled() {
-			userCanMount, err = canMount(userAc, imgStore, mountDigest)
-			if err != nil {
-				rh.c.Log.Error().Err(err).Msg("unexpected error")
-			}
+		var mounted bool
+
+		if from := request.URL.Query().Get("from"); from != "" {
+			mounted = rh.mountBlob(userAc, imgStore, name, from, mountDigest)
 		}
 
-		// zot does not support cross mounting directly and do a workaround creating using hard link.
-		// check blob looks for actual path (name+mountDigests[0]) first then look for cache and
-		// if found in cache, will do hard link and if fails we will start new upload.
-		if userCanMount {
-			_, _, err = imgStore.CheckBlob(name, mountDigest)
+		// otherwise look for the blob in the dedupe cache, following dist-spec and
+		// starting a new upload (202) if it can not be mounted
+		if !mounted {
+			userCanMount := true
+			if rh.c.Config.IsAuthzEnabled() {
+				userCanMount, err = canMount(userAc, imgStore, mountDigest)
+				if err != nil {
+					logger.Error().Err(err).Msg("unexpected error")
+				}
+			}
+
+			if userCanMount {
+				mounted, _, err = imgStore.CheckBlob(name, mountDigest)
+				mounted = mounted && err == nil
+			}
 		}
 
-		if err != nil || !userCanMount {
+		if !mounted {
 			upload, err := imgStore.NewBlobUpload(name)
 			if err != nil {
 				details := zerr.GetDetails(err)
@@ -1283,7 +1476,7 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 					e := apiErr.NewError(apiErr.NAME_UNKNOWN).AddDetail(details)
 					zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
 				} else {
-					rh.c.Log.Error().Err(err).Msg("unexpected error")
+					logger.Error().Err(err).Msg("unexpected error")
 					response.WriteHeader(http.StatusInternalServerError)
 				}
 
@@ -1298,6 +1491,7 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 		}
 
 		response.Header().Set("Location", getBlobUploadLocation(request.URL, name, mountDigest))
+		response.Header().Set(constants.DistContentDigestKey, mountDigest.String())
 		response.WriteHeader(http.StatusCreated)
 
 		return
@@ -1319,7 +1513,7 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 		}
 
 		if contentType := request.Header.Get("Content-Type"); contentType != constants.BinaryMediaType {
-			rh.c.Log.Warn().Str("actual", contentType).Str("expected", constants.BinaryMediaType).Msg("invalid media type")
+			logger.Warn().Str("actual", contentType).Str("expected", constants.BinaryMediaType).Msg("invalid media type")
 			response.WriteHeader(http.StatusUnsupportedMediaType)
 
 			return
@@ -1333,7 +1527,7 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 
 		contentLength, err := strconv.ParseInt(request.Header.Get("Content-Length"), 10, 64)
 		if err != nil || contentLength <= 0 {
-			rh.c.Log.Warn().Str("actual", request.Header.Get("Content-Length")).Msg("invalid content length")
+			logger.Warn().Str("actual", request.Header.Get("Content-Length")).Msg("invalid content length")
 
 			details := map[string]string{"digest": digest.String()}
 
@@ -1349,9 +1543,19 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 			return
 		}
 
+		if !rh.checkBlobQuota(response, name, contentLength) {
+			return
+		}
+
 		sessionID, size, err := imgStore.FullBlobUpload(name, request.Body, digest)
+		if errors.Is(err, zerr.ErrStorageQuotaExceeded) {
+			rh.handleQuotaError(response, err)
+
+			return
+		}
+
 		if err != nil {
-			rh.c.Log.Error().Err(err).Int64("actual", size).Int64("expected", contentLength).
+			logger.Error().Err(err).Int64("actual", size).Int64("expected", contentLength).
 				Msg("failed to full blob upload")
 			response.WriteHeader(http.StatusInternalServerError)
 
@@ -1359,7 +1563,7 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 		}
 
 		if size != contentLength {
-			rh.c.Log.Warn().Int64("actual", size).Int64("expected", contentLength).Msg("invalid content length")
+			logger.Warn().Int64("actual", size).Int64("expected", contentLength).Msg("invalid content length")
 			response.WriteHeader(http.StatusInternalServerError)
 
 			return
@@ -1380,7 +1584,7 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 			e := apiErr.NewError(apiErr.NAME_UNKNOWN).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
 
@@ -1406,6 +1610,8 @@ func (rh *RouteHandler) CreateBlobUpload(response http.ResponseWriter, request *
 // @Failure 500 {string} string "internal server error"
 // @Router /v2/{name}/blobs/uploads/{session_id} [get].
 func (rh *RouteHandler) GetBlobUpload(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -1415,7 +1621,7 @@ func (rh *RouteHandler) GetBlobUpload(response http.ResponseWriter, request *htt
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	sessionID, ok := vars["session_id"]
 	if !ok || sessionID == "" {
@@ -1441,7 +1647,7 @@ func (rh *RouteHandler) GetBlobUpload(response http.ResponseWriter, request *htt
 			e := apiErr.NewError(apiErr.BLOB_UPLOAD_UNKNOWN).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
 
@@ -1465,11 +1671,14 @@ func (rh *RouteHandler) GetBlobUpload(response http.ResponseWriter, request *htt
 // @Header  202 {string} Range "0-128"
 // @Header  200 {object} api.BlobUploadUUID
 // @Failure 400 {string} string "bad request"
+// @Failure 403 {string} string "denied"
 // @Failure 404 {string} string "not found"
 // @Failure 416 {string} string "range not satisfiable"
 // @Failure 500 {string} string "internal server error"
 // @Router /v2/{name}/blobs/uploads/{session_id} [patch].
 func (rh *RouteHandler) PatchBlobUpload(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -1479,7 +1688,7 @@ func (rh *RouteHandler) PatchBlobUpload(response http.ResponseWriter, request *h
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	sessionID, ok := vars["session_id"]
 	if !ok || sessionID == "" {
@@ -1500,7 +1709,7 @@ func (rh *RouteHandler) PatchBlobUpload(response http.ResponseWriter, request *h
 		var contentLength int64
 
 		if contentLength, err = strconv.ParseInt(request.Header.Get("Content-Length"), 10, 64); err != nil {
-			rh.c.Log.Warn().Str("actual", request.Header.Get("Content-Length")).Msg("invalid content length")
+			logger.Warn().Str("actual", request.Header.Get("Content-Length")).Msg("invalid content length")
 			response.WriteHeader(http.StatusBadRequest)
 
 			return
@@ -1513,6 +1722,11 @@ func (rh *RouteHandler) PatchBlobUpload(response http.ResponseWriter, request *h
 			return
 		}
 
+		// the upload will hold to+1 bytes once this chunk is written
+		if !rh.checkBlobQuota(response, name, to+1) {
+			return
+		}
+
 		clen, err = imgStore.PutBlobChunk(name, sessionID, from, to, request.Body)
 	}
 
@@ -1530,12 +1744,20 @@ func (rh *RouteHandler) PatchBlobUpload(response http.ResponseWriter, request *h
 			details["session_id"] = sessionID
 			e := apiErr.NewError(apiErr.BLOB_UPLOAD_UNKNOWN).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
+		} else if errors.Is(err, zerr.ErrStorageQuotaExceeded) {
+			if err = imgStore.DeleteBlobUpload(name, sessionID); err != nil {
+				logger.Error().Err(err).Str("blobUpload", sessionID).Str("repository", name).
+					Msg("failed to remove blobUpload in repo")
+			}
+
+			e := apiErr.NewError(apiErr.DENIED).AddDetail(details)
+			zcommon.WriteJSON(response, http.StatusForbidden, apiErr.NewErrorList(e))
 		} else {
 			// could be io.ErrUnexpectedEOF, syscall.EMFILE (Err:0x18 too many opened files), etc
-			rh.c.Log.Error().Err(err).Msg("unexpected error, removing .uploads/ files")
+			logger.Error().Err(err).Msg("unexpected error, removing .uploads/ files")
 
 			if err = imgStore.DeleteBlobUpload(name, sessionID); err != nil {
-				rh.c.Log.Error().Err(err).Str("blobUpload", sessionID).Str("repository", name).
+				logger.Error().Err(err).Str("blobUpload", sessionID).Str("repository", name).
 					Msg("couldn't remove blobUpload in repo")
 			}
 
@@ -1545,6 +1767,16 @@ func (rh *RouteHandler) PatchBlobUpload(response http.ResponseWriter, request *h
 		return
 	}
 
+	// streamed uploads are only checked once the chunk is written, their size not being known in advance
+	if !rh.checkBlobQuota(response, name, clen) {
+		if err = imgStore.DeleteBlobUpload(name, sessionID); err != nil {
+			logger.Error().Err(err).Str("blobUpload", sessionID).Str("repository", name).
+				Msg("failed to remove blobUpload in repo")
+		}
+
+		return
+	}
+
 	response.Header().Set("Location", getBlobUploadSessionLocation(request.URL, sessionID))
 	response.Header().Set("Range", fmt.Sprintf("0-%d", clen-1))
 	response.Header().Set("Content-Length", "0")
@@ -1563,10 +1795,13 @@ func (rh *RouteHandler) PatchBlobUpload(response http.ResponseWriter, request *h
 // @Success 201 {string} string "created"
 // @Header  202 {string} Location "/v2/{name}/blobs/uploads/{digest}"
 // @Header  200 {object} constants.DistContentDigestKey
+// @Failure 403 {string} string "denied"
 // @Failure 404 {string} string "not found"
 // @Failure 500 {string} string "internal server error"
 // @Router /v2/{name}/blobs/uploads/{session_id} [put].
 func (rh *RouteHandler) UpdateBlobUpload(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -1576,7 +1811,7 @@ func (rh *RouteHandler) UpdateBlobUpload(response http.ResponseWriter, request *
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	sessionID, ok := vars["session_id"]
 	if !ok || sessionID == "" {
@@ -1655,10 +1890,10 @@ func (rh *RouteHandler) UpdateBlobUpload(response http.ResponseWriter, request *
 				zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
 			} else {
 				// could be io.ErrUnexpectedEOF, syscall.EMFILE (Err:0x18 too many opened files), etc
-				rh.c.Log.Error().Err(err).Msg("unexpected error, removing .uploads/ files")
+				logger.Error().Err(err).Msg("unexpected error, removing .uploads/ files")
 
 				if err = imgStore.DeleteBlobUpload(name, sessionID); err != nil {
-					rh.c.Log.Error().Err(err).Str("blobUpload", sessionID).Str("repository", name).
+					logger.Error().Err(err).Str("blobUpload", sessionID).Str("repository", name).
 						Msg("failed to remove blobUpload in repo")
 				}
 
@@ -1689,12 +1924,20 @@ finish:
 			details["session_id"] = sessionID
 			e := apiErr.NewError(apiErr.BLOB_UPLOAD_UNKNOWN).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
+		} else if errors.Is(err, zerr.ErrStorageQuotaExceeded) {
+			if err = imgStore.DeleteBlobUpload(name, sessionID); err != nil {
+				logger.Error().Err(err).Str("blobUpload", sessionID).Str("repository", name).
+					Msg("failed to remove blobUpload in repo")
+			}
+
+			e := apiErr.NewError(apiErr.DENIED).AddDetail(details)
+			zcommon.WriteJSON(response, http.StatusForbidden, apiErr.NewErrorList(e))
 		} else {
 			// could be io.ErrUnexpectedEOF, syscall.EMFILE (Err:0x18 too many opened files), etc
-			rh.c.Log.Error().Err(err).Msg("unexpected error, removing .uploads/ files")
+			logger.Error().Err(err).Msg("unexpected error, removing .uploads/ files")
 
 			if err = imgStore.DeleteBlobUpload(name, sessionID); err != nil {
-				rh.c.Log.Error().Err(err).Str("blobUpload", sessionID).Str("repository", name).
+				logger.Error().Err(err).Str("blobUpload", sessionID).Str("repository", name).
 					Msg("failed to remove blobUpload in repo")
 			}
 			response.WriteHeader(http.StatusInternalServerError)
@@ -1721,6 +1964,8 @@ finish:
 // @Failure 500 {string} string "internal server error"
 // @Router /v2/{name}/blobs/uploads/{session_id} [delete].
 func (rh *RouteHandler) DeleteBlobUpload(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	vars := mux.Vars(request)
 	name, ok := vars["name"]
 
@@ -1730,7 +1975,7 @@ func (rh *RouteHandler) DeleteBlobUpload(response http.ResponseWriter, request *
 		return
 	}
 
-	imgStore := rh.getImageStore(name)
+	imgStore := rh.getImageStore(request.Context(), name)
 
 	sessionID, ok := vars["session_id"]
 	if !ok || sessionID == "" {
@@ -1750,7 +1995,7 @@ func (rh *RouteHandler) DeleteBlobUpload(response http.ResponseWriter, request *
 			e := apiErr.NewError(apiErr.BLOB_UPLOAD_UNKNOWN).AddDetail(details)
 			zcommon.WriteJSON(response, http.StatusNotFound, apiErr.NewErrorList(e))
 		} else {
-			rh.c.Log.Error().Err(err).Msg("unexpected error")
+			logger.Error().Err(err).Msg("unexpected error")
 			response.WriteHeader(http.StatusInternalServerError)
 		}
 
@@ -1921,10 +2166,26 @@ func (rh *RouteHandler) ListExtensions(w http.ResponseWriter, r *http.Request) {
 // @Success 200 {string} string "ok".
 // @Failure 500 {string} string "internal server error".
 func (rh *RouteHandler) Logout(response http.ResponseWriter, request *http.Request) {
+	logger := log.Ctx(request.Context(), rh.c.Log)
+
 	if request.Method == http.MethodOptions {
 		return
 	}
 
+	// the tracked session is revoked too, in case its cookie was copied
+	if identity, sessionID, ok := GetAuthUserFromRequestSession(rh.c.CookieStore, request, logger); ok {
+		userAc := reqCtx.NewUserAccessControl()
+		userAc.SetUsername(identity)
+
+		err := rh.c.MetaDB.DeleteUserSession(userAc.DeriveContext(request.Context()), sessionID)
+		if err != nil && !errors.Is(err, zerr.ErrUserSessionNotFound) {
+			logger.Error().Err(err).Str("identity", identity).Msg("failed to delete user session")
+			response.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+	}
+
 	session, _ := rh.c.CookieStore.Get(request, "session")
 	session.Options.MaxAge = -1
 
@@ -1939,7 +2200,7 @@ func (rh *RouteHandler) Logout(response http.ResponseWriter, request *http.Reque
 }
 
 // github Oauth2 CodeExchange callback.
-func (rh *RouteHandler) GithubCodeExchangeCallback() rp.CodeExchangeCallback[*oidc.IDTokenClaims] {
+func (rh *RouteHandler) GithubCodeExchangeCallback(provider string) rp.CodeExchangeCallback[*oidc.IDTokenClaims] {
 	return func(w http.ResponseWriter, r *http.Request,
 		tokens *oidc.Tokens[*oidc.IDTokenClaims], state string, relyingParty rp.RelyingParty,
 	) {
@@ -1954,9 +2215,12 @@ func (rh *RouteHandler) GithubCodeExchangeCallback() rp.CodeExchangeCallback[*oi
 			return
 		}
 
-		callbackUI, err := OAuth2Callback(rh.c, w, r, state, email, groups) //nolint: contextcheck
+		groups = openIDGroupsMapper(rh.c, provider).Map(groups)
+		token := newOpenIDSessionToken(rh.c, provider, tokens.Token, "")
+
+		callbackUI, err := OAuth2Callback(rh.c, w, r, state, email, groups, provider, token) //nolint: contextcheck
 		if err != nil {
-			if errors.Is(err, zerr.ErrInvalidStateCookie) {
+			if errors.Is(err, zerr.ErrInvalidStateCookie) || errors.Is(err, zerr.ErrServiceAccountLogin) {
 				w.WriteHeader(http.StatusUnauthorized)
 			}
 
@@ -1974,7 +2238,7 @@ func (rh *RouteHandler) GithubCodeExchangeCallback() rp.CodeExchangeCallback[*oi
 }
 
 // Openid CodeExchange callback.
-func (rh *RouteHandler) OpenIDCodeExchangeCallback() rp.CodeExchangeUserinfoCallback[
+func (rh *RouteHandler) OpenIDCodeExchangeCallback(provider string) rp.CodeExchangeUserinfoCallback[
 	*oidc.IDTokenClaims,
 	*oidc.UserInfo,
 ] {
@@ -1989,20 +2253,22 @@ func (rh *RouteHandler) OpenIDCodeExchangeCallback() rp.CodeExchangeUserinfoCall
 			return
 		}
 
-		var groups []string
+		// the groups claim is looked up in the userinfo first, then in the id token
+		var idTokenClaims map[string]any
+		if tokens.IDTokenClaims != nil {
+			idTokenClaims = tokens.IDTokenClaims.Claims
+		}
 
-		val, ok := info.Claims["groups"].([]interface{})
+		groups, ok := openIDGroupsMapper(rh.c, provider).ClaimGroups(info.Claims, idTokenClaims)
 		if !ok {
-			rh.c.Log.Info().Msgf("failed to find any 'groups' claim for user %s", email)
+			rh.c.Log.Info().Msgf("failed to find any groups claim for user %s", email)
 		}
 
-		for _, group := range val {
-			groups = append(groups, fmt.Sprint(group))
-		}
+		token := newOpenIDSessionToken(rh.c, provider, tokens.Token, info.Subject)
 
-		callbackUI, err := OAuth2Callback(rh.c, w, r, state, email, groups)
+		callbackUI, err := OAuth2Callback(rh.c, w, r, state, email, groups, provider, token)
 		if err != nil {
-			if errors.Is(err, zerr.ErrInvalidStateCookie) {
+			if errors.Is(err, zerr.ErrInvalidStateCookie) || errors.Is(err, zerr.ErrServiceAccountLogin) {
 				w.WriteHeader(http.StatusUnauthorized)
 			}
 
@@ -2064,16 +2330,23 @@ func WriteDataFromReader(response http.ResponseWriter, status int, length int64,
 	}
 }
 
-// will return image storage corresponding to subpath provided in config.
-func (rh *RouteHandler) getImageStore(name string) storageTypes.ImageStore {
-	return rh.c.StoreController.GetImageStore(name)
+// will return image storage corresponding to subpath provided in config, logging the id of the request,
+// and tracing its operations as children of the span of the request if tracing is enabled.
+func (rh *RouteHandler) getImageStore(ctx context.Context, name string) storageTypes.ImageStore {
+	imgStore := rh.c.StoreController.GetImageStore(name).WithRequestLogger(ctx)
+
+	if rh.c.Config.IsTracingEnabled() {
+		return tracing.ImageStore(ctx, imgStore)
+	}
+
+	return imgStore
 }
 
 // will sync on demand if an image is not found, in case sync extensions is enabled.
 func getImageManifest(ctx context.Context, routeHandler *RouteHandler, imgStore storageTypes.ImageStore, name,
 	reference string,
 ) ([]byte, godigest.Digest, string, error) {
-	syncEnabled := isSyncOnDemandEnabled(*routeHandler.c)
+	syncEnabled := isSyncOnDemandEnabled(routeHandler.c)
 
 	_, digestErr := godigest.Parse(reference)
 	if digestErr == nil {
@@ -2113,9 +2386,11 @@ type APIKeyPayload struct { //nolint:revive
 // @Failure 500 {string} string "internal server error"
 // @Router  /zot/auth/apikey  [get].
 func (rh *RouteHandler) GetAPIKeys(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
 	apiKeys, err := rh.c.MetaDB.GetUserAPIKeys(req.Context())
 	if err != nil {
-		rh.c.Log.Error().Err(err).Msg("failed to get list of api keys for user")
+		logger.Error().Err(err).Msg("failed to get list of api keys for user")
 		resp.WriteHeader(http.StatusInternalServerError)
 
 		return
@@ -2131,7 +2406,7 @@ func (rh *RouteHandler) GetAPIKeys(resp http.ResponseWriter, req *http.Request)
 
 	data, err := json.Marshal(apiKeyResponse)
 	if err != nil {
-		rh.c.Log.Error().Err(err).Msg("failed to marshal api key response")
+		logger.Error().Err(err).Msg("failed to marshal api key response")
 
 		resp.WriteHeader(http.StatusInternalServerError)
 
@@ -2155,24 +2430,79 @@ func (rh *RouteHandler) GetAPIKeys(resp http.ResponseWriter, req *http.Request)
 // @Failure 500 {string} string "internal server error"
 // @Router  /zot/auth/apikey  [post].
 func (rh *RouteHandler) CreateAPIKey(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	payload, ok := rh.readAPIKeyPayload(resp, req)
+	if !ok {
+		return
+	}
+
+	userAc, err := reqCtx.UserAcFromContext(req.Context())
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	// the api keys of service accounts are created by the admins only
+	userData, err := rh.c.MetaDB.GetUserData(req.Context())
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		logger.Error().Err(err).Msg("failed to get user profile in DB")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	if userData.ServiceAccount != nil {
+		logger.Info().Err(zerr.ErrServiceAccountAPIKey).Str("identity", userAc.GetUsername()).
+			Msg("failed to create api key")
+		resp.WriteHeader(http.StatusForbidden)
+
+		return
+	}
+
+	rh.addAPIKey(req.Context(), resp, req, payload)
+}
+
+// readAPIKeyPayload decodes and validates the api key request, writing the error response if it is invalid.
+func (rh *RouteHandler) readAPIKeyPayload(resp http.ResponseWriter, req *http.Request) (APIKeyPayload, bool) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
 	var payload APIKeyPayload
 
 	body, err := io.ReadAll(req.Body)
 	if err != nil {
-		rh.c.Log.Error().Msg("failed to read request body")
+		logger.Error().Msg("failed to read request body")
 		resp.WriteHeader(http.StatusInternalServerError)
 
-		return
+		return payload, false
 	}
 
 	err = json.Unmarshal(body, &payload)
 	if err != nil {
 		resp.WriteHeader(http.StatusBadRequest)
 
-		return
+		return payload, false
+	}
+
+	if _, err := reqCtx.ParseScopes(payload.Scopes); err != nil {
+		logger.Error().Err(err).Strs("scopes", payload.Scopes).Msg("failed to parse api key scopes")
+		zcommon.WriteJSON(resp, http.StatusBadRequest, apiErr.NewErrorList(apiErr.NewError(apiErr.UNSUPPORTED).
+			AddDetail(map[string]string{"scopes": err.Error()})))
+
+		return payload, false
 	}
 
-	apiKey, apiKeyID, err := GenerateAPIKey(guuid.DefaultGenerator, rh.c.Log)
+	return payload, true
+}
+
+// addAPIKey generates an api key for the user on the context and writes it in the response.
+func (rh *RouteHandler) addAPIKey(ctx context.Context, resp http.ResponseWriter, req *http.Request,
+	payload APIKeyPayload,
+) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	apiKey, apiKeyID, err := GenerateAPIKey(guuid.DefaultGenerator, logger)
 	if err != nil {
 		resp.WriteHeader(http.StatusInternalServerError)
 
@@ -2213,9 +2543,9 @@ func (rh *RouteHandler) CreateAPIKey(resp http.ResponseWriter, req *http.Request
 		UUID:           apiKeyID,
 	}
 
-	err = rh.c.MetaDB.AddUserAPIKey(req.Context(), hashedAPIKey, apiKeyDetails)
+	err = rh.c.MetaDB.AddUserAPIKey(ctx, hashedAPIKey, apiKeyDetails)
 	if err != nil {
-		rh.c.Log.Error().Err(err).Msg("failed to store api key")
+		logger.Error().Err(err).Msg("failed to store api key")
 		resp.WriteHeader(http.StatusInternalServerError)
 
 		return
@@ -2233,7 +2563,7 @@ func (rh *RouteHandler) CreateAPIKey(resp http.ResponseWriter, req *http.Request
 
 	data, err := json.Marshal(apiKeyResponse)
 	if err != nil {
-		rh.c.Log.Error().Err(err).Msg("failed to marshal api key response")
+		logger.Error().Err(err).Msg("failed to marshal api key response")
 
 		resp.WriteHeader(http.StatusInternalServerError)
 
@@ -2257,6 +2587,8 @@ func (rh *RouteHandler) CreateAPIKey(resp http.ResponseWriter, req *http.Request
 // @Failure 400 {string} string "bad request"
 // @Router  /zot/auth/apikey [delete].
 func (rh *RouteHandler) RevokeAPIKey(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
 	ids, ok := req.URL.Query()["id"]
 	if !ok || len(ids) != 1 {
 		resp.WriteHeader(http.StatusBadRequest)
@@ -2268,7 +2600,604 @@ func (rh *RouteHandler) RevokeAPIKey(resp http.ResponseWriter, req *http.Request
 
 	err := rh.c.MetaDB.DeleteUserAPIKey(req.Context(), keyID)
 	if err != nil {
-		rh.c.Log.Error().Err(err).Str("keyID", keyID).Msg("failed to delete api key")
+		logger.Error().Err(err).Str("keyID", keyID).Msg("failed to delete api key")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+}
+
+type ServiceAccountPayload struct { //nolint:revive
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Groups      []string `json:"groups"`
+}
+
+// ServiceAccountUpdatePayload only changes the fields which are set.
+type ServiceAccountUpdatePayload struct { //nolint:revive
+	Description *string   `json:"description,omitempty"`
+	Groups      *[]string `json:"groups,omitempty"`
+	Disabled    *bool     `json:"disabled,omitempty"`
+}
+
+type ServiceAccountInfo struct { //nolint:revive
+	Name string `json:"name"`
+	mTypes.ServiceAccount
+	LastUsed time.Time              `json:"lastUsed"`
+	APIKeys  []mTypes.APIKeyDetails `json:"apiKeys"`
+}
+
+type ServiceAccountsResponse struct { //nolint:revive
+	ServiceAccounts []ServiceAccountInfo `json:"serviceAccounts"`
+}
+
+// GetServiceAccounts godoc
+// @Summary List the service accounts
+// @Description List the service accounts with their api keys and when they were last used, admins only
+// @Produce json
+// @Success 200 {object} api.ServiceAccountsResponse
+// @Failure 500 {string} string "internal server error"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Router  /zot/auth/serviceaccounts [get].
+func (rh *RouteHandler) GetServiceAccounts(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	if _, ok := rh.checkAdmin(resp, req); !ok {
+		return
+	}
+
+	serviceAccounts, err := rh.c.MetaDB.GetServiceAccounts(req.Context())
+	if err != nil {
+		logger.Error().Err(err).Msg("failed to get service accounts")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	response := ServiceAccountsResponse{ServiceAccounts: make([]ServiceAccountInfo, 0, len(serviceAccounts))}
+
+	for name, userData := range serviceAccounts {
+		response.ServiceAccounts = append(response.ServiceAccounts, getServiceAccountInfo(name, userData))
+	}
+
+	sort.Slice(response.ServiceAccounts, func(i, j int) bool {
+		return response.ServiceAccounts[i].Name < response.ServiceAccounts[j].Name
+	})
+
+	zcommon.WriteJSON(resp, http.StatusOK, response)
+}
+
+// CreateServiceAccount godoc
+// @Summary Create a service account
+// @Description Create an identity which can not log in and only authenticates with its api keys, admins only
+// @Accept  json
+// @Produce json
+// @Param   account  body  api.ServiceAccountPayload  true  "service account"
+// @Success 201 {object} api.ServiceAccountInfo
+// @Failure 500 {string} string "internal server error"
+// @Failure 409 {string} string "conflict"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 400 {string} string "bad request"
+// @Router  /zot/auth/serviceaccounts [post].
+func (rh *RouteHandler) CreateServiceAccount(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	admin, ok := rh.checkAdmin(resp, req)
+	if !ok {
+		return
+	}
+
+	var payload ServiceAccountPayload
+
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if err := accounts.ValidateUsername(payload.Name); err != nil {
+		logger.Info().Err(err).Msg("failed to create service account")
+		resp.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	// the name of a service account can not be taken by a user, which could then log in with it
+	if _, present := rh.c.HTPasswd.Get(payload.Name); present {
+		resp.WriteHeader(http.StatusConflict)
+
+		return
+	}
+
+	ctx := serviceAccountContext(req, payload.Name)
+
+	_, err := rh.c.MetaDB.GetUserData(ctx)
+	if err == nil {
+		resp.WriteHeader(http.StatusConflict)
+
+		return
+	}
+
+	if !errors.Is(err, zerr.ErrUserDataNotFound) {
+		logger.Error().Err(err).Str("name", payload.Name).Msg("failed to get user profile in DB")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	serviceAccount := mTypes.ServiceAccount{
+		Description: payload.Description,
+		Groups:      payload.Groups,
+		CreatedBy:   admin,
+		CreatedAt:   time.Now(),
+	}
+
+	if err := rh.c.MetaDB.SetServiceAccount(ctx, serviceAccount); err != nil {
+		logger.Error().Err(err).Str("name", payload.Name).Msg("failed to store service account")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	zcommon.WriteJSON(resp, http.StatusCreated, getServiceAccountInfo(payload.Name,
+		mTypes.UserData{ServiceAccount: &serviceAccount}))
+}
+
+// GetServiceAccount godoc
+// @Summary Get a service account
+// @Description Get a service account with its api keys and when they were last used, admins only
+// @Produce json
+// @Param   name  path  string  true  "service account name"
+// @Success 200 {object} api.ServiceAccountInfo
+// @Failure 500 {string} string "internal server error"
+// @Failure 404 {string} string "not found"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Router  /zot/auth/serviceaccounts/{name} [get].
+func (rh *RouteHandler) GetServiceAccount(resp http.ResponseWriter, req *http.Request) {
+	if _, ok := rh.checkAdmin(resp, req); !ok {
+		return
+	}
+
+	name := mux.Vars(req)["name"]
+
+	userData, ok := rh.getServiceAccount(resp, serviceAccountContext(req, name))
+	if !ok {
+		return
+	}
+
+	zcommon.WriteJSON(resp, http.StatusOK, getServiceAccountInfo(name, userData))
+}
+
+// UpdateServiceAccount godoc
+// @Summary Update a service account
+// @Description Update the description and the groups of a service account, or disable it, admins only
+// @Accept  json
+// @Produce json
+// @Param   name     path  string                           true  "service account name"
+// @Param   account  body  api.ServiceAccountUpdatePayload  true  "changed fields"
+// @Success 200 {object} api.ServiceAccountInfo
+// @Failure 500 {string} string "internal server error"
+// @Failure 404 {string} string "not found"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 400 {string} string "bad request"
+// @Router  /zot/auth/serviceaccounts/{name} [patch].
+func (rh *RouteHandler) UpdateServiceAccount(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	if _, ok := rh.checkAdmin(resp, req); !ok {
+		return
+	}
+
+	name := mux.Vars(req)["name"]
+	ctx := serviceAccountContext(req, name)
+
+	userData, ok := rh.getServiceAccount(resp, ctx)
+	if !ok {
+		return
+	}
+
+	var payload ServiceAccountUpdatePayload
+
+	if err := json.NewDecoder(req.Body).Decode(&payload); err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	serviceAccount := *userData.ServiceAccount
+
+	if payload.Description != nil {
+		serviceAccount.Description = *payload.Description
+	}
+
+	if payload.Groups != nil {
+		serviceAccount.Groups = *payload.Groups
+	}
+
+	if payload.Disabled != nil {
+		serviceAccount.Disabled = *payload.Disabled
+	}
+
+	if err := rh.c.MetaDB.SetServiceAccount(ctx, serviceAccount); err != nil {
+		logger.Error().Err(err).Str("name", name).Msg("failed to store service account")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	userData.ServiceAccount = &serviceAccount
+
+	zcommon.WriteJSON(resp, http.StatusOK, getServiceAccountInfo(name, userData))
+}
+
+// DeleteServiceAccount godoc
+// @Summary Delete a service account
+// @Description Delete a service account and revoke all its api keys, admins only
+// @Produce json
+// @Param   name  path  string  true  "service account name"
+// @Success 200 {string} string "ok"
+// @Failure 500 {string} string "internal server error"
+// @Failure 404 {string} string "not found"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Router  /zot/auth/serviceaccounts/{name} [delete].
+func (rh *RouteHandler) DeleteServiceAccount(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	if _, ok := rh.checkAdmin(resp, req); !ok {
+		return
+	}
+
+	name := mux.Vars(req)["name"]
+	ctx := serviceAccountContext(req, name)
+
+	userData, ok := rh.getServiceAccount(resp, ctx)
+	if !ok {
+		return
+	}
+
+	for _, apiKeyDetails := range userData.APIKeys {
+		if err := rh.c.MetaDB.DeleteUserAPIKey(ctx, apiKeyDetails.UUID); err != nil {
+			logger.Error().Err(err).Str("name", name).Str("keyID", apiKeyDetails.UUID).
+				Msg("failed to delete api key")
+			resp.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	if err := rh.c.MetaDB.DeleteUserData(ctx); err != nil {
+		logger.Error().Err(err).Str("name", name).Msg("failed to delete service account")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+}
+
+// CreateServiceAccountAPIKey godoc
+// @Summary Create an API key for a service account
+// @Description Create an api key owned by a service account, based on the provided label and scopes, admins only
+// @Accept  json
+// @Produce json
+// @Param   name  path  string         true  "service account name"
+// @Param   id    body  APIKeyPayload  true  "api token id (UUID)"
+// @Success 201 {string} string "created"
+// @Failure 500 {string} string "internal server error"
+// @Failure 404 {string} string "not found"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 400 {string} string "bad request"
+// @Router  /zot/auth/serviceaccounts/{name}/apikey [post].
+func (rh *RouteHandler) CreateServiceAccountAPIKey(resp http.ResponseWriter, req *http.Request) {
+	if _, ok := rh.checkAdmin(resp, req); !ok {
+		return
+	}
+
+	ctx := serviceAccountContext(req, mux.Vars(req)["name"])
+
+	if _, ok := rh.getServiceAccount(resp, ctx); !ok {
+		return
+	}
+
+	payload, ok := rh.readAPIKeyPayload(resp, req)
+	if !ok {
+		return
+	}
+
+	rh.addAPIKey(ctx, resp, req, payload)
+}
+
+// RevokeServiceAccountAPIKey godoc
+// @Summary Revokes one API key of a service account
+// @Description Revokes one API key of a service account based on given key ID, admins only
+// @Produce json
+// @Param   name  path   string  true  "service account name"
+// @Param   id    query  string  true  "api token id (UUID)"
+// @Success 200 {string} string "ok"
+// @Failure 500 {string} string "internal server error"
+// @Failure 404 {string} string "not found"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 400 {string} string "bad request"
+// @Router  /zot/auth/serviceaccounts/{name}/apikey [delete].
+func (rh *RouteHandler) RevokeServiceAccountAPIKey(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	if _, ok := rh.checkAdmin(resp, req); !ok {
+		return
+	}
+
+	ctx := serviceAccountContext(req, mux.Vars(req)["name"])
+
+	if _, ok := rh.getServiceAccount(resp, ctx); !ok {
+		return
+	}
+
+	ids, ok := req.URL.Query()["id"]
+	if !ok || len(ids) != 1 {
+		resp.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if err := rh.c.MetaDB.DeleteUserAPIKey(ctx, ids[0]); err != nil {
+		logger.Error().Err(err).Str("keyID", ids[0]).Msg("failed to delete api key")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+}
+
+// checkAdmin writes the error response and returns false if the user of the request is not an admin.
+func (rh *RouteHandler) checkAdmin(resp http.ResponseWriter, req *http.Request) (string, bool) {
+	userAc, err := reqCtx.UserAcFromContext(req.Context())
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return "", false
+	}
+
+	if !userAc.IsAdmin() {
+		resp.WriteHeader(http.StatusForbidden)
+
+		return "", false
+	}
+
+	return userAc.GetUsername(), true
+}
+
+// getServiceAccount returns the data of the service account on the context, writing the error response if missing.
+func (rh *RouteHandler) getServiceAccount(resp http.ResponseWriter, ctx context.Context) (mTypes.UserData, bool) {
+	userData, err := rh.c.MetaDB.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		rh.c.Log.Error().Err(err).Msg("failed to get user profile in DB")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return userData, false
+	}
+
+	// the users which logged in are not service accounts
+	if err != nil || userData.ServiceAccount == nil {
+		rh.c.Log.Info().Err(zerr.ErrServiceAccountNotFound).Msg("failed to get service account")
+		resp.WriteHeader(http.StatusNotFound)
+
+		return userData, false
+	}
+
+	return userData, true
+}
+
+// serviceAccountContext returns a context on which the per user metadb methods act on the service account.
+func serviceAccountContext(req *http.Request, name string) context.Context {
+	userAc := reqCtx.NewUserAccessControl()
+	userAc.SetUsername(name)
+
+	return userAc.DeriveContext(req.Context())
+}
+
+func getServiceAccountInfo(name string, userData mTypes.UserData) ServiceAccountInfo {
+	info := ServiceAccountInfo{
+		Name:           name,
+		ServiceAccount: *userData.ServiceAccount,
+		APIKeys:        make([]mTypes.APIKeyDetails, 0, len(userData.APIKeys)),
+	}
+
+	for _, apiKeyDetails := range userData.APIKeys {
+		if !apiKeyDetails.ExpirationDate.IsZero() && time.Now().After(apiKeyDetails.ExpirationDate) {
+			apiKeyDetails.IsExpired = true
+		}
+
+		if apiKeyDetails.LastUsed.After(info.LastUsed) {
+			info.LastUsed = apiKeyDetails.LastUsed
+		}
+
+		info.APIKeys = append(info.APIKeys, apiKeyDetails)
+	}
+
+	sort.Slice(info.APIKeys, func(i, j int) bool {
+		return info.APIKeys[i].CreatedAt.Before(info.APIKeys[j].CreatedAt)
+	})
+
+	return info
+}
+
+// UserSessionInfo describes a web session, without the provider tokens it may hold.
+type UserSessionInfo struct { //nolint:revive
+	ID        string    `json:"id"`
+	UserAgent string    `json:"userAgent"`
+	ClientIP  string    `json:"clientIP"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Provider  string    `json:"provider,omitempty"`
+	Current   bool      `json:"current"`
+}
+
+type UserSessionsResponse struct { //nolint:revive
+	Sessions []UserSessionInfo `json:"sessions"`
+}
+
+// GetUserSessions godoc
+// @Summary Get the active web sessions
+// @Description Get the active web sessions of the current user, or of any user for admins, with their device, ip and last seen time
+// @Produce json
+// @Param   user  query  string  false  "username, admins only"
+// @Success 200 {object} api.UserSessionsResponse
+// @Failure 500 {string} string "internal server error"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Router  /zot/auth/sessions [get].
+func (rh *RouteHandler) GetUserSessions(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	ctx, username, ok := rh.getSessionsUserContext(resp, req)
+	if !ok {
+		return
+	}
+
+	sessions, err := rh.c.MetaDB.GetUserSessions(ctx)
+	if err != nil {
+		logger.Error().Err(err).Str("identity", username).Msg("failed to get user sessions")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	// the session which sent the request, if any, is flagged
+	identity, currentSessionID, _ := GetAuthUserFromRequestSession(rh.c.CookieStore, req, logger)
+
+	response := UserSessionsResponse{Sessions: make([]UserSessionInfo, 0, len(sessions))}
+
+	for _, session := range sessions {
+		response.Sessions = append(response.Sessions, UserSessionInfo{
+			ID:        session.ID,
+			UserAgent: session.UserAgent,
+			ClientIP:  session.ClientIP,
+			CreatedAt: session.CreatedAt,
+			LastSeen:  session.LastSeen,
+			ExpiresAt: session.ExpiresAt,
+			Provider:  session.Provider,
+			Current:   identity == username && session.ID == currentSessionID,
+		})
+	}
+
+	zcommon.WriteJSON(resp, http.StatusOK, response)
+}
+
+// RevokeUserSession godoc
+// @Summary Revoke a web session
+// @Description Revoke a web session of the current user, or of any user for admins, logging that session out
+// @Produce json
+// @Param   id    query  string  true   "session id"
+// @Param   user  query  string  false  "username, admins only"
+// @Success 200 {string} string "ok"
+// @Failure 500 {string} string "internal server error"
+// @Failure 404 {string} string "not found"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 400 {string} string "bad request"
+// @Router  /zot/auth/sessions [delete].
+func (rh *RouteHandler) RevokeUserSession(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	ids, ok := req.URL.Query()["id"]
+	if !ok || len(ids) != 1 {
+		resp.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	ctx, username, ok := rh.getSessionsUserContext(resp, req)
+	if !ok {
+		return
+	}
+
+	err := rh.c.MetaDB.DeleteUserSession(ctx, ids[0])
+	if err != nil {
+		if errors.Is(err, zerr.ErrUserSessionNotFound) {
+			resp.WriteHeader(http.StatusNotFound)
+
+			return
+		}
+
+		logger.Error().Err(err).Str("identity", username).Str("sessionID", ids[0]).
+			Msg("failed to delete user session")
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	resp.WriteHeader(http.StatusOK)
+}
+
+// getSessionsUserContext returns the context of the user whose sessions are managed, the current user
+// or the one given by an admin, writing the error response if it is not allowed.
+func (rh *RouteHandler) getSessionsUserContext(resp http.ResponseWriter, req *http.Request,
+) (context.Context, string, bool) {
+	userAc, err := reqCtx.UserAcFromContext(req.Context())
+	if err != nil {
+		resp.WriteHeader(http.StatusInternalServerError)
+
+		return nil, "", false
+	}
+
+	username := req.URL.Query().Get("user")
+	if username == "" || username == userAc.GetUsername() {
+		return req.Context(), userAc.GetUsername(), true
+	}
+
+	if _, ok := rh.checkAdmin(resp, req); !ok {
+		return nil, "", false
+	}
+
+	otherUserAc := reqCtx.NewUserAccessControl()
+	otherUserAc.SetUsername(username)
+
+	return otherUserAc.DeriveContext(req.Context()), username, true
+}
+
+// ClearAuthLockout godoc
+// @Summary Clears an authentication lockout
+// @Description Lifts the lockout of a user or a client ip after too many authentication failures, admins only
+// @Produce json
+// @Param   user  query  string   false  "locked out username"
+// @Param   ip    query  string   false  "locked out client ip"
+// @Success 200 {string} string "ok"
+// @Failure 500 {string} string "internal server error"
+// @Failure 403 {string} string "forbidden"
+// @Failure 401 {string} string "unauthorized"
+// @Failure 400 {string} string "bad request"
+// @Router  /zot/auth/lockout [delete].
+func (rh *RouteHandler) ClearAuthLockout(resp http.ResponseWriter, req *http.Request) {
+	logger := log.Ctx(req.Context(), rh.c.Log)
+
+	admin, ok := rh.checkAdmin(resp, req)
+	if !ok {
+		return
+	}
+
+	username := req.URL.Query().Get("user")
+	clientIP := req.URL.Query().Get("ip")
+
+	if username == "" && clientIP == "" {
+		resp.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if err := rh.c.AuthLockout.Clear(req.Context(), username, clientIP, admin); err != nil {
+		logger.Error().Err(err).Str("username", username).Str("clientIP", clientIP).
+			Msg("failed to clear authentication lockout")
 		resp.WriteHeader(http.StatusInternalServerError)
 
 		return
@@ -2305,7 +3234,7 @@ func getBlobUploadLocation(url *url.URL, name string, digest godigest.Digest) st
 	return url.String()
 }
 
-func isSyncOnDemandEnabled(ctlr Controller) bool {
+func isSyncOnDemandEnabled(ctlr *Controller) bool {
 	if ctlr.Config.IsSyncEnabled() &&
 		fmt.Sprintf("%v", ctlr.SyncOnDemand) != fmt.Sprintf("%v", nil) {
 		return true
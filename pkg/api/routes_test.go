@@ -99,7 +99,7 @@ func TestRoutes(t *testing.T) {
 		// so path routing is bypassed
 
 		Convey("Test GithubCodeExchangeCallback", func() {
-			callback := rthdlr.GithubCodeExchangeCallback()
+			callback := rthdlr.GithubCodeExchangeCallback("github")
 			ctx := context.TODO()
 
 			request, _ := http.NewRequestWithContext(ctx, http.MethodGet, baseURL, nil)
@@ -123,7 +123,7 @@ func TestRoutes(t *testing.T) {
 			request, _ := http.NewRequestWithContext(ctx, http.MethodGet, baseURL, nil)
 			response := httptest.NewRecorder()
 
-			_, err := api.OAuth2Callback(ctlr, response, request, "state", "email", []string{"group"})
+			_, err := api.OAuth2Callback(ctlr, response, request, "state", "email", []string{"group"}, "github", nil)
 			So(err, ShouldEqual, zerr.ErrInvalidStateCookie)
 
 			session, _ := ctlr.CookieStore.Get(request, "statecookie")
@@ -140,7 +140,7 @@ func TestRoutes(t *testing.T) {
 			err = session.Save(request, response)
 			So(err, ShouldBeNil)
 
-			_, err = api.OAuth2Callback(ctlr, response, request, "state", "email", []string{"group"})
+			_, err = api.OAuth2Callback(ctlr, response, request, "state", "email", []string{"group"}, "github", nil)
 			So(err, ShouldEqual, zerr.ErrInvalidStateCookie)
 		})
 
@@ -1478,7 +1478,7 @@ func TestRoutes(t *testing.T) {
 
 				payload := api.APIKeyPayload{
 					Label:  "test",
-					Scopes: []string{"test"},
+					Scopes: []string{"repository:**:read"},
 				}
 				reqBody, err := json.Marshal(payload)
 				So(err, ShouldBeNil)
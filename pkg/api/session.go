@@ -9,9 +9,12 @@ import (
 
 	"github.com/didip/tollbooth/v7"
 	"github.com/gorilla/mux"
+	godigest "github.com/opencontainers/go-digest"
 
+	"zotregistry.dev/zot/pkg/api/constants"
 	"zotregistry.dev/zot/pkg/extensions/monitoring"
 	"zotregistry.dev/zot/pkg/log"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 )
 
 type statusWriter struct {
@@ -43,7 +46,7 @@ func RateLimiter(ctlr *Controller, rate int) mux.MiddlewareFunc {
 	limiter := tollbooth.NewLimiter(float64(rate), nil)
 	limiter.SetMessage(http.StatusText(http.StatusTooManyRequests)).
 		SetStatusCode(http.StatusTooManyRequests).
-		SetOnLimitReached(nil)
+		SetOnLimitReached(setRetryAfter)
 
 	return func(next http.Handler) http.Handler {
 		return tollbooth.LimitHandler(limiter, next)
@@ -58,16 +61,21 @@ func MethodRateLimiter(ctlr *Controller, method string, rate int) mux.Middleware
 	limiter.SetMethods([]string{method}).
 		SetMessage(http.StatusText(http.StatusTooManyRequests)).
 		SetStatusCode(http.StatusTooManyRequests).
-		SetOnLimitReached(nil)
+		SetOnLimitReached(setRetryAfter)
 
 	return func(next http.Handler) http.Handler {
 		return tollbooth.LimitHandler(limiter, next)
 	}
 }
 
+// setRetryAfter tells the clients over the rates, which are in requests per second, to retry in a second.
+func setRetryAfter(response http.ResponseWriter, request *http.Request) {
+	response.Header().Set("Retry-After", "1")
+}
+
 // SessionLogger logs session details.
 func SessionLogger(ctlr *Controller) mux.MiddlewareFunc {
-	logger := ctlr.Log.With().Str("module", "http").Logger()
+	logger := log.Logger{Logger: ctlr.Log.With().Str("module", "http").Logger()}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
@@ -89,7 +97,8 @@ func SessionLogger(ctlr *Controller) mux.MiddlewareFunc {
 			clientIP := request.RemoteAddr
 			method := request.Method
 			headers := map[string][]string{}
-			log := logger.Info() //nolint: zerologlint // false positive, the Msg call is below
+			requestLogger := log.Ctx(request.Context(), logger)
+			log := requestLogger.Info() //nolint: zerologlint // false positive, the Msg call is below
 
 			for key, value := range request.Header {
 				if key == "Authorization" { // anonymize from logs
@@ -150,43 +159,101 @@ func SessionAuditLogger(audit *log.Logger) mux.MiddlewareFunc {
 			// Process request
 			next.ServeHTTP(&statusWr, request)
 
-			clientIP := request.RemoteAddr
 			method := request.Method
+			if method != http.MethodPost && method != http.MethodPut &&
+				method != http.MethodPatch && method != http.MethodDelete {
+				return
+			}
+
+			// the authn middleware saves the user it identified on the request, whichever the credentials were:
+			// sessions, api keys, bearer tokens...
 			username := ""
 
-			for key, value := range request.Header {
-				if key == "Authorization" { // anonymize from logs
-					s := strings.SplitN(value[0], " ", 2) //nolint:mnd
-					if len(s) == 2 && strings.EqualFold(s[0], "basic") {
-						b, err := base64.StdEncoding.DecodeString(s[1])
-						if err == nil {
-							pair := strings.SplitN(string(b), ":", 2) //nolint:mnd
-							if len(pair) == 2 {                       //nolint:mnd
-								username = pair[0]
-							}
-						}
-					}
-				}
+			if userAc, err := reqCtx.UserAcFromContext(request.Context()); err == nil {
+				username = userAc.GetUsername()
+			}
+
+			// the requests rejected before being authenticated are attributed to the user they claimed to be
+			if username == "" {
+				username = basicAuthUsername(request)
 			}
 
 			statusCode := statusWr.status
+			if statusCode == 0 {
+				// nothing was written, net/http replies with 200
+				statusCode = http.StatusOK
+			}
 
 			if raw != "" {
 				path = path + "?" + raw
 			}
 
-			if (method == http.MethodPost || method == http.MethodPut ||
-				method == http.MethodPatch || method == http.MethodDelete) &&
-				(statusCode == http.StatusOK || statusCode == http.StatusCreated || statusCode == http.StatusAccepted) {
-				audit.Info().
-					Str("component", "session").
-					Str("clientIP", clientIP).
-					Str("subject", username).
-					Str("action", method).
-					Str("object", path).
-					Int("status", statusCode).
-					Msg("HTTP API Audit")
-			}
+			vars := mux.Vars(request)
+
+			audit.Info().
+				Str("component", "session").
+				Str("clientIP", clientIP(request)).
+				Str("subject", username).
+				Str("action", method).
+				Str("object", path).
+				Str("repo", vars["name"]).
+				Str("reference", vars["reference"]).
+				Str("digest", auditDigest(response, request, vars["reference"])).
+				Int("status", statusCode).
+				Str("outcome", auditOutcome(statusCode)).
+				Str("requestID", reqCtx.RequestIDFromContext(request.Context())).
+				Msg("HTTP API Audit")
 		})
 	}
 }
+
+// auditDigest returns the digest of the content a request changed: the one returned to the client,
+// else the one given by the client.
+func auditDigest(response http.ResponseWriter, request *http.Request, reference string) string {
+	if digest := response.Header().Get(constants.DistContentDigestKey); digest != "" {
+		return digest
+	}
+
+	if digest := request.URL.Query().Get("digest"); digest != "" {
+		return digest
+	}
+
+	if _, err := godigest.Parse(reference); err == nil {
+		return reference
+	}
+
+	return ""
+}
+
+func auditOutcome(statusCode int) string {
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return "denied"
+	case statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices:
+		return "success"
+	default:
+		return "failure"
+	}
+}
+
+// basicAuthUsername returns the username of the basic auth credentials of the request, if any.
+func basicAuthUsername(request *http.Request) string {
+	value := request.Header.Get("Authorization")
+
+	s := strings.SplitN(value, " ", 2)                    //nolint:mnd
+	if len(s) != 2 || !strings.EqualFold(s[0], "basic") { //nolint:mnd
+		return ""
+	}
+
+	b, err := base64.StdEncoding.DecodeString(s[1])
+	if err != nil {
+		return ""
+	}
+
+	pair := strings.SplitN(string(b), ":", 2) //nolint:mnd
+	if len(pair) != 2 {                       //nolint:mnd
+		return ""
+	}
+
+	return pair[0]
+}
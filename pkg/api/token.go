@@ -0,0 +1,458 @@
+package api
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/api/constants"
+	apiErr "zotregistry.dev/zot/pkg/api/errors"
+	zcommon "zotregistry.dev/zot/pkg/common"
+	"zotregistry.dev/zot/pkg/log"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+)
+
+const (
+	defaultTokenExpiration          = 5 * time.Minute
+	defaultTokenKeyRotationInterval = 24 * time.Hour
+	tokenKeyIDLength                = 16
+)
+
+// token scope actions, as specified by the distribution token authentication specification.
+const (
+	pullAction   = "pull"
+	pushAction   = "push"
+	deleteAction = "delete"
+	allActions   = "*"
+)
+
+// TokenResponse is the body of a successful token request.
+// https://distribution.github.io/distribution/spec/auth/token/#token-response-fields
+type TokenResponse struct {
+	Token       string `json:"token"`
+	AccessToken string `json:"access_token"` //nolint:tagliatelle // required by the specification
+	ExpiresIn   int    `json:"expires_in"`   //nolint:tagliatelle // required by the specification
+	IssuedAt    string `json:"issued_at"`    //nolint:tagliatelle // required by the specification
+}
+
+// TokenIssuer signs the bearer tokens served by the built-in token server. Unless one is configured, its signing
+// key is replaced periodically, the previous keys being kept for verification as long as the tokens they signed
+// are valid.
+type TokenIssuer struct {
+	issuer           string
+	service          string
+	expiration       time.Duration
+	rotationInterval time.Duration
+	keys             []*tokenSigningKey // newest first
+	sharedKey        bool               // the configured signing key is never rotated
+	lock             sync.RWMutex
+	log              log.Logger
+}
+
+type tokenSigningKey struct {
+	id        string
+	key       *ecdsa.PrivateKey
+	createdAt time.Time
+}
+
+func NewTokenIssuer(bearerConfig *config.BearerConfig, log log.Logger) (*TokenIssuer, error) {
+	tokenIssuer := &TokenIssuer{
+		issuer:           bearerConfig.TokenServer.Issuer,
+		service:          bearerConfig.Service,
+		expiration:       bearerConfig.TokenServer.Expiration,
+		rotationInterval: bearerConfig.TokenServer.KeyRotationInterval,
+		log:              log,
+	}
+
+	if tokenIssuer.issuer == "" {
+		tokenIssuer.issuer = bearerConfig.Service
+	}
+
+	if tokenIssuer.expiration == 0 {
+		tokenIssuer.expiration = defaultTokenExpiration
+	}
+
+	if tokenIssuer.rotationInterval == 0 {
+		tokenIssuer.rotationInterval = defaultTokenKeyRotationInterval
+	}
+
+	if keyPath := bearerConfig.TokenServer.SigningKey; keyPath != "" {
+		signingKey, err := loadTokenSigningKey(keyPath)
+		if err != nil {
+			log.Error().Err(err).Str("path", keyPath).Msg("failed to load token signing key")
+
+			return nil, err
+		}
+
+		tokenIssuer.keys = []*tokenSigningKey{signingKey}
+		tokenIssuer.sharedKey = true
+	}
+
+	return tokenIssuer, nil
+}
+
+// Issuer returns the 'iss' claim of the tokens signed by this issuer.
+func (ti *TokenIssuer) Issuer() string {
+	return ti.issuer
+}
+
+// Issue returns a token granting access to subject, signed with the current key, and its expiration time.
+// The groups of subject and the scopes of the api key it authenticated with, if any, are kept in the token
+// so it is authorized like the request it was issued for.
+func (ti *TokenIssuer) Issue(subject string, groups []string, access []ResourceAccess, apiKeyScopes []string,
+) (string, time.Time, error) {
+	signingKey, err := ti.signingKey()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	issuedAt := time.Now()
+	expiresAt := issuedAt.Add(ti.expiration)
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, ClaimsWithAccess{
+		Access:       access,
+		APIKeyScopes: apiKeyScopes,
+		Groups:       groups,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    ti.issuer,
+			Subject:   subject,
+			Audience:  jwt.ClaimStrings{ti.service},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			NotBefore: jwt.NewNumericDate(issuedAt),
+			IssuedAt:  jwt.NewNumericDate(issuedAt),
+			ID:        uuid.NewString(),
+		},
+	})
+	token.Header["kid"] = signingKey.id
+
+	signedString, err := token.SignedString(signingKey.key)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return signedString, expiresAt, nil
+}
+
+// PublicKey returns the key verifying the tokens signed by this issuer with keyID, if it is still in use.
+func (ti *TokenIssuer) PublicKey(keyID string) (crypto.PublicKey, bool) {
+	ti.lock.RLock()
+	defer ti.lock.RUnlock()
+
+	for _, signingKey := range ti.keys {
+		if signingKey.id == keyID {
+			return signingKey.key.Public(), true
+		}
+	}
+
+	return nil, false
+}
+
+// signingKey returns the current signing key, replacing it if it is due for rotation.
+func (ti *TokenIssuer) signingKey() (*tokenSigningKey, error) {
+	ti.lock.Lock()
+	defer ti.lock.Unlock()
+
+	now := time.Now()
+
+	if len(ti.keys) > 0 && (ti.sharedKey || now.Sub(ti.keys[0].createdAt) < ti.rotationInterval) {
+		return ti.keys[0], nil
+	}
+
+	newKey, err := newTokenSigningKey(now)
+	if err != nil {
+		ti.log.Error().Err(err).Msg("failed to generate token signing key")
+
+		return nil, err
+	}
+
+	keys := []*tokenSigningKey{newKey}
+
+	// keep the keys retired recently enough to have signed tokens which did not expire yet
+	retiredAt := now
+
+	for _, signingKey := range ti.keys {
+		if now.Sub(retiredAt) < ti.expiration {
+			keys = append(keys, signingKey)
+		}
+
+		retiredAt = signingKey.createdAt
+	}
+
+	ti.keys = keys
+
+	ti.log.Info().Str("kid", newKey.id).Msg("rotated token signing key")
+
+	return newKey, nil
+}
+
+func newTokenSigningKey(createdAt time.Time) (*tokenSigningKey, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return makeTokenSigningKey(key, createdAt)
+}
+
+// loadTokenSigningKey reads the PKCS #8 or SEC 1 encoded ECDSA P-256 private key of a PEM file,
+// the tokens being signed with ES256.
+func loadTokenSigningKey(keyPath string) (*tokenSigningKey, error) {
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("%w: no PEM data found", zerr.ErrInvalidTokenSigningKey)
+	}
+
+	var key any
+
+	switch block.Type {
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", zerr.ErrInvalidTokenSigningKey, err)
+	}
+
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok || ecKey.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%w: not an ECDSA P-256 key", zerr.ErrInvalidTokenSigningKey)
+	}
+
+	return makeTokenSigningKey(ecKey, time.Now())
+}
+
+func makeTokenSigningKey(key *ecdsa.PrivateKey, createdAt time.Time) (*tokenSigningKey, error) {
+	publicKey, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	keyID := sha256.Sum256(publicKey)
+
+	return &tokenSigningKey{
+		id:        hex.EncodeToString(keyID[:tokenKeyIDLength]),
+		key:       key,
+		createdAt: createdAt,
+	}, nil
+}
+
+// bearerKeyFunc looks up the keys verifying a bearer token: the tokens signed by the built-in token server
+// or by a key of the configured key set are identified by their key id, all the others being verified with
+// every key of the set, as the key ids of the token issuer and of zot may differ for the same certificate.
+// If issuedByZot is given, it records whether or not the key of the token is one of the token issuer.
+func bearerKeyFunc(tokenIssuer *TokenIssuer, keySet *BearerKeySet, issuedByZot *bool) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		keyID, _ := token.Header["kid"].(string)
+
+		if keyID != "" {
+			if tokenIssuer != nil {
+				if key, ok := tokenIssuer.PublicKey(keyID); ok {
+					if issuedByZot != nil {
+						*issuedByZot = true
+					}
+
+					return key, nil
+				}
+			}
+
+			if key, ok := keySet.Key(keyID); ok {
+				return key, nil
+			}
+
+			// the issuer may have rotated its keys since they were loaded
+			keySet.reloadForUnknownKey()
+
+			if key, ok := keySet.Key(keyID); ok {
+				return key, nil
+			}
+		}
+
+		keys := keySet.Keys()
+
+		switch len(keys) {
+		case 0:
+			return nil, zerr.ErrBearerKeyNotFound
+		case 1:
+			return keys[0], nil
+		}
+
+		verificationKeys := jwt.VerificationKeySet{}
+		for _, key := range keys {
+			verificationKeys.Keys = append(verificationKeys.Keys, key)
+		}
+
+		return verificationKeys, nil
+	}
+}
+
+// bearerTokenHeader returns the key id and the signing algorithm of a bearer token, without verifying it,
+// for logging purposes.
+func bearerTokenHeader(header string) (string, string) {
+	token, _, err := jwt.NewParser().ParseUnverified(bearerTokenMatch.ReplaceAllString(header, "$1"), jwt.MapClaims{})
+	if err != nil {
+		return "", ""
+	}
+
+	keyID, _ := token.Header["kid"].(string)
+	algorithm, _ := token.Header["alg"].(string)
+
+	return keyID, algorithm
+}
+
+// parseTokenScope parses a scope of a token request, formatted as 'type:name:action[,action...]',
+// the repository name possibly including a registry host and port.
+// https://distribution.github.io/distribution/spec/auth/scope/
+func parseTokenScope(scope string) (ResourceAccess, error) {
+	resourceType, rest, found := strings.Cut(scope, ":")
+
+	sepIdx := strings.LastIndex(rest, ":")
+	if !found || sepIdx <= 0 || sepIdx == len(rest)-1 {
+		return ResourceAccess{}, fmt.Errorf("%w: %s", zerr.ErrInvalidTokenScope, scope)
+	}
+
+	return ResourceAccess{
+		Type:    resourceType,
+		Name:    rest[:sepIdx],
+		Actions: strings.Split(rest[sepIdx+1:], ","),
+	}, nil
+}
+
+// tokenActionPermissions maps the token scope actions to the access control permissions they require.
+func tokenActionPermissions() map[string]string {
+	return map[string]string{
+		pullAction:   constants.ReadPermission,
+		pushAction:   constants.CreatePermission,
+		deleteAction: constants.DeletePermission,
+	}
+}
+
+// grantedAccess returns the part of the requested access the user is allowed, checking the same
+// access control policies and api key scopes as DistSpecAuthzHandler.
+func (rh *RouteHandler) grantedAccess(request *http.Request, userAc *reqCtx.UserAccessControl,
+	requested ResourceAccess,
+) ResourceAccess {
+	granted := ResourceAccess{Type: requested.Type, Name: requested.Name, Actions: []string{}}
+
+	// only repositories are protected by the access control policies
+	if requested.Type != "repository" {
+		return granted
+	}
+
+	acCtrlr := NewAccessController(rh.c.Config).from(request).delegateTo(rh.c.AuthzWebhook, request)
+	permissions := tokenActionPermissions()
+
+	for _, action := range []string{pullAction, pushAction, deleteAction} {
+		if !zcommon.Contains(requested.Actions, action) && !zcommon.Contains(requested.Actions, allActions) {
+			continue
+		}
+
+		permission := permissions[action]
+
+		can := true
+		if rh.c.Config.IsAuthzEnabled() {
+			can = acCtrlr.can(userAc, permission, requested.Name)
+		}
+
+		if can && userAc.ScopesAllow(permission, requested.Name) {
+			granted.Actions = append(granted.Actions, action)
+		}
+	}
+
+	return granted
+}
+
+// GetBearerToken godoc
+// @Summary Get a bearer token
+// @Description Issue a bearer token granting the authenticated user the requested access allowed by the policies.
+// @Router  /zot/auth/token [get]
+// @Accept  json
+// @Produce json
+// @Param   service  query     string   false   "service the token is requested for"
+// @Param   scope    query     []string false   "requested access, formatted as type:name:actions" collectionFormat(multi)
+// @Success 200 {object}   api.TokenResponse
+// @Failure 400 {object}   apiErr.Error      "bad request"
+// @Failure 401 {string}   string            "unauthorized"
+// @Failure 500 {string}   string            "internal server error".
+func (rh *RouteHandler) GetBearerToken(response http.ResponseWriter, request *http.Request) {
+	query := request.URL.Query()
+
+	if service := query.Get("service"); service != "" && service != rh.c.Config.HTTP.Auth.Bearer.Service {
+		rh.c.Log.Info().Err(zerr.ErrUnknownTokenService).Str("service", service).Msg("failed to issue token")
+		zcommon.WriteJSON(response, http.StatusBadRequest, apiErr.NewErrorList(apiErr.NewError(apiErr.UNSUPPORTED).
+			AddDetail(map[string]string{"service": service})))
+
+		return
+	}
+
+	userAc, err := reqCtx.UserAcFromContext(request.Context())
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	access := []ResourceAccess{}
+
+	for _, scope := range query["scope"] {
+		// several scopes may also be requested in a single space separated parameter
+		for _, scope := range strings.Fields(scope) {
+			requested, err := parseTokenScope(scope)
+			if err != nil {
+				rh.c.Log.Info().Err(err).Msg("failed to issue token")
+				zcommon.WriteJSON(response, http.StatusBadRequest, apiErr.NewErrorList(apiErr.NewError(apiErr.UNSUPPORTED).
+					AddDetail(map[string]string{"scope": scope})))
+
+				return
+			}
+
+			access = append(access, rh.grantedAccess(request, userAc, requested))
+		}
+	}
+
+	// a token obtained with a scoped api key is restricted by the same scopes
+	apiKeyScopes := []string{}
+	for _, scope := range userAc.GetScopes() {
+		apiKeyScopes = append(apiKeyScopes, scope.String())
+	}
+
+	token, expiresAt, err := rh.c.TokenIssuer.Issue(userAc.GetUsername(), userAc.GetGroups(), access, apiKeyScopes)
+	if err != nil {
+		response.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	rh.c.Log.Debug().Str("identity", userAc.GetUsername()).Interface("access", access).Msg("issued token")
+
+	issuedAt := expiresAt.Add(-rh.c.TokenIssuer.expiration)
+
+	zcommon.WriteJSON(response, http.StatusOK, TokenResponse{
+		Token:       token,
+		AccessToken: token,
+		ExpiresIn:   int(rh.c.TokenIssuer.expiration.Seconds()),
+		IssuedAt:    issuedAt.UTC().Format(time.RFC3339),
+	})
+}
@@ -0,0 +1,184 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	glob "github.com/bmatcuk/doublestar/v4"
+	"github.com/golang-jwt/jwt/v5"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+const (
+	// workload identity tokens are short lived, only a small clock skew is tolerated.
+	workloadIdentityLeeway = 30 * time.Second
+	// WorkloadIdentityPrefix prefixes the workload identities, so that they can't take the name,
+	// and the groups, of the users authenticated otherwise.
+	WorkloadIdentityPrefix = "workload:"
+)
+
+var identityClaimMatch = regexp.MustCompile(`\{([^{}]+)\}`)
+
+// WorkloadIdentityVerifier authenticates the CI jobs with the OIDC ID tokens issued by their platform,
+// mapping their claims to a zot identity and groups, so the access control policies apply to them.
+type WorkloadIdentityVerifier struct {
+	issuers map[string]*workloadIdentityIssuer
+}
+
+type workloadIdentityIssuer struct {
+	config config.WorkloadIdentityIssuerConfig
+	keySet *BearerKeySet
+}
+
+func NewWorkloadIdentityVerifier(workloadIdentityConfig *config.WorkloadIdentityConfig, log log.Logger,
+) (*WorkloadIdentityVerifier, error) {
+	verifier := &WorkloadIdentityVerifier{
+		issuers: map[string]*workloadIdentityIssuer{},
+	}
+
+	for _, issuerConfig := range workloadIdentityConfig.Issuers {
+		keySet, err := newBearerKeySet(nil, issuerConfig.JWKS, issuerConfig.KeysRefreshInterval, log)
+		if err != nil {
+			verifier.Close()
+
+			return nil, err
+		}
+
+		verifier.issuers[issuerConfig.Issuer] = &workloadIdentityIssuer{
+			config: issuerConfig,
+			keySet: keySet,
+		}
+	}
+
+	return verifier, nil
+}
+
+// Verify checks the token was signed by one of the configured issuers for the expected audience,
+// and returns the identity, prefixed with WorkloadIdentityPrefix, and groups given by the first rule
+// matching its claims.
+func (v *WorkloadIdentityVerifier) Verify(signedString string) (string, []string, error) {
+	unverified, _, err := jwt.NewParser().ParseUnverified(signedString, jwt.MapClaims{})
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %w", zerr.ErrInvalidBearerToken, err)
+	}
+
+	issuerName, err := unverified.Claims.GetIssuer()
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %w", zerr.ErrInvalidBearerToken, err)
+	}
+
+	issuer, ok := v.issuers[issuerName]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: %s", zerr.ErrUnknownTokenIssuer, issuerName)
+	}
+
+	claims := jwt.MapClaims{}
+
+	_, err = jwt.ParseWithClaims(signedString, claims, bearerKeyFunc(nil, issuer.keySet, nil),
+		jwt.WithValidMethods(asymmetricSigningAlgorithms()),
+		jwt.WithIssuer(issuer.config.Issuer),
+		jwt.WithAudience(issuer.config.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(workloadIdentityLeeway),
+	)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: %w", zerr.ErrInvalidBearerToken, err)
+	}
+
+	for _, rule := range issuer.config.Rules {
+		if !workloadIdentityRuleMatches(rule, claims) {
+			continue
+		}
+
+		identity, err := workloadIdentityName(rule, claims)
+		if err != nil {
+			return "", nil, err
+		}
+
+		return WorkloadIdentityPrefix + identity, rule.Groups, nil
+	}
+
+	return "", nil, fmt.Errorf("%w: issuer %s", zerr.ErrNoWorkloadIdentityRuleMatched, issuerName)
+}
+
+// Close stops reloading the keys of the issuers.
+func (v *WorkloadIdentityVerifier) Close() {
+	for _, issuer := range v.issuers {
+		issuer.keySet.Close()
+	}
+}
+
+// workloadIdentityRuleMatches returns true if every claim of the rule matches its pattern.
+func workloadIdentityRuleMatches(rule config.WorkloadIdentityRule, claims jwt.MapClaims) bool {
+	for claim, pattern := range rule.Claims {
+		value, ok := workloadIdentityClaim(claims, claim)
+		if !ok {
+			return false
+		}
+
+		matched, err := glob.Match(pattern, value)
+		if err != nil || !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// workloadIdentityName returns the identity given by a rule, its {claim} references being replaced by
+// the values of the claims of the token.
+func workloadIdentityName(rule config.WorkloadIdentityRule, claims jwt.MapClaims) (string, error) {
+	if rule.Identity == "" {
+		subject, err := claims.GetSubject()
+		if err != nil || subject == "" {
+			return "", fmt.Errorf("%w: missing 'sub' claim", zerr.ErrInvalidBearerToken)
+		}
+
+		return subject, nil
+	}
+
+	var missingClaim string
+
+	identity := identityClaimMatch.ReplaceAllStringFunc(rule.Identity, func(reference string) string {
+		claim := strings.Trim(reference, "{}")
+
+		value, ok := workloadIdentityClaim(claims, claim)
+		if !ok {
+			missingClaim = claim
+		}
+
+		return value
+	})
+
+	if missingClaim != "" {
+		return "", fmt.Errorf("%w: missing '%s' claim", zerr.ErrInvalidBearerToken, missingClaim)
+	}
+
+	return identity, nil
+}
+
+// workloadIdentityClaim returns the value of a string, boolean or numeric claim.
+func workloadIdentityClaim(claims jwt.MapClaims, claim string) (string, bool) {
+	switch value := claims[claim].(type) {
+	case string:
+		return value, true
+	case bool:
+		return strconv.FormatBool(value), true
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), true
+	default:
+		return "", false
+	}
+}
+
+// isWorkloadIdentityToken returns true if a password looks like a signed JWT rather than a secret.
+func isWorkloadIdentityToken(password string) bool {
+	return strings.Count(password, ".") == 2 && strings.HasPrefix(password, "eyJ")
+}
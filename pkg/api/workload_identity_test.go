@@ -0,0 +1,188 @@
+package api_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+	"github.com/golang-jwt/jwt/v5"
+	. "github.com/smartystreets/goconvey/convey"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+const workloadIdentityIssuer = "https://ci.example.com"
+
+func signWorkloadIdentityToken(key *ecdsa.PrivateKey, keyID string, claims jwt.MapClaims) string {
+	defaultClaims := jwt.MapClaims{
+		"iss": workloadIdentityIssuer,
+		"aud": "zot",
+		"sub": "repo:org/app:ref:refs/heads/main",
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+
+	for claim, value := range claims {
+		defaultClaims[claim] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, defaultClaims)
+	token.Header["kid"] = keyID
+
+	signedString, err := token.SignedString(key)
+	if err != nil {
+		panic(err)
+	}
+
+	return signedString
+}
+
+func writeWorkloadIdentityJWKS(jwksPath string, keys ...jose.JSONWebKey) {
+	content, err := json.Marshal(jose.JSONWebKeySet{Keys: keys})
+	if err != nil {
+		panic(err)
+	}
+
+	if err := os.WriteFile(jwksPath, content, 0o600); err != nil {
+		panic(err)
+	}
+}
+
+func TestWorkloadIdentityVerifier(t *testing.T) {
+	Convey("Test mapping workload identity tokens to zot identities", t, func() {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
+
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		So(err, ShouldBeNil)
+
+		jwksPath := path.Join(t.TempDir(), "jwks.json")
+		writeWorkloadIdentityJWKS(jwksPath, jose.JSONWebKey{Key: key.Public(), KeyID: "ci", Use: "sig"})
+
+		verifier, err := api.NewWorkloadIdentityVerifier(&config.WorkloadIdentityConfig{
+			Issuers: []config.WorkloadIdentityIssuerConfig{
+				{
+					Issuer:   workloadIdentityIssuer,
+					Audience: "zot",
+					JWKS:     jwksPath,
+					Rules: []config.WorkloadIdentityRule{
+						{
+							Claims:   map[string]string{"repository": "org/*", "ref": "refs/heads/main"},
+							Identity: "ci:{repository}",
+							Groups:   []string{"release"},
+						},
+						{
+							Claims: map[string]string{"repository": "org/*", "run_attempt": "1"},
+						},
+					},
+				},
+			},
+		}, log.NewLogger("debug", ""))
+		So(err, ShouldBeNil)
+
+		defer verifier.Close()
+
+		Convey("The first matching rule gives the identity", func() {
+			identity, groups, err := verifier.Verify(signWorkloadIdentityToken(key, "ci", jwt.MapClaims{
+				"repository": "org/app", "ref": "refs/heads/main",
+			}))
+			So(err, ShouldBeNil)
+			So(identity, ShouldEqual, "workload:ci:org/app")
+			So(groups, ShouldResemble, []string{"release"})
+
+			// numeric claims are matched too, the identity defaults to the subject
+			identity, groups, err = verifier.Verify(signWorkloadIdentityToken(key, "ci", jwt.MapClaims{
+				"repository": "org/app", "ref": "refs/heads/feature", "run_attempt": 1,
+			}))
+			So(err, ShouldBeNil)
+			So(identity, ShouldEqual, "workload:repo:org/app:ref:refs/heads/main")
+			So(groups, ShouldBeEmpty)
+		})
+
+		Convey("Tokens matching no rule are rejected", func() {
+			_, _, err := verifier.Verify(signWorkloadIdentityToken(key, "ci", jwt.MapClaims{
+				"repository": "other/app", "ref": "refs/heads/main",
+			}))
+			So(err, ShouldWrap, zerr.ErrNoWorkloadIdentityRuleMatched)
+
+			// pattern wildcards do not match across path separators
+			_, _, err = verifier.Verify(signWorkloadIdentityToken(key, "ci", jwt.MapClaims{
+				"repository": "org/team/app", "ref": "refs/heads/main",
+			}))
+			So(err, ShouldWrap, zerr.ErrNoWorkloadIdentityRuleMatched)
+		})
+
+		Convey("Invalid tokens are rejected", func() {
+			claims := jwt.MapClaims{"repository": "org/app", "ref": "refs/heads/main"}
+
+			_, _, err := verifier.Verify("invalid")
+			So(err, ShouldWrap, zerr.ErrInvalidBearerToken)
+
+			// unknown issuer
+			_, _, err = verifier.Verify(signWorkloadIdentityToken(key, "ci", jwt.MapClaims{
+				"iss": "https://other.example.com", "repository": "org/app", "ref": "refs/heads/main",
+			}))
+			So(err, ShouldWrap, zerr.ErrUnknownTokenIssuer)
+
+			// wrong audience
+			_, _, err = verifier.Verify(signWorkloadIdentityToken(key, "ci", jwt.MapClaims{
+				"aud": "other", "repository": "org/app", "ref": "refs/heads/main",
+			}))
+			So(err, ShouldWrap, zerr.ErrInvalidBearerToken)
+
+			// expired
+			_, _, err = verifier.Verify(signWorkloadIdentityToken(key, "ci", jwt.MapClaims{
+				"exp": time.Now().Add(-time.Hour).Unix(), "repository": "org/app", "ref": "refs/heads/main",
+			}))
+			So(err, ShouldWrap, zerr.ErrInvalidBearerToken)
+
+			// signed with a key which is not published by the issuer
+			_, _, err = verifier.Verify(signWorkloadIdentityToken(otherKey, "ci", claims))
+			So(err, ShouldWrap, zerr.ErrInvalidBearerToken)
+
+			_, _, err = verifier.Verify(signWorkloadIdentityToken(otherKey, "other", claims))
+			So(err, ShouldWrap, zerr.ErrInvalidBearerToken)
+		})
+
+		Convey("Identities referencing missing claims are rejected", func() {
+			verifier, err := api.NewWorkloadIdentityVerifier(&config.WorkloadIdentityConfig{
+				Issuers: []config.WorkloadIdentityIssuerConfig{
+					{
+						Issuer:   workloadIdentityIssuer,
+						Audience: "zot",
+						JWKS:     jwksPath,
+						Rules: []config.WorkloadIdentityRule{
+							{Claims: map[string]string{"sub": "**"}, Identity: "ci:{project_path}"},
+						},
+					},
+				},
+			}, log.NewLogger("debug", ""))
+			So(err, ShouldBeNil)
+
+			defer verifier.Close()
+
+			_, _, err = verifier.Verify(signWorkloadIdentityToken(key, "ci", nil))
+			So(err, ShouldWrap, zerr.ErrInvalidBearerToken)
+		})
+
+		Convey("Invalid key sets are rejected", func() {
+			So(os.WriteFile(jwksPath, []byte("invalid"), 0o600), ShouldBeNil)
+
+			_, err := api.NewWorkloadIdentityVerifier(&config.WorkloadIdentityConfig{
+				Issuers: []config.WorkloadIdentityIssuerConfig{
+					{Issuer: workloadIdentityIssuer, Audience: "zot", JWKS: jwksPath},
+				},
+			}, log.NewLogger("debug", ""))
+			So(err, ShouldWrap, zerr.ErrInvalidJWKS)
+		})
+	})
+}
@@ -0,0 +1,172 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	zlog "zotregistry.dev/zot/pkg/log"
+)
+
+type sink interface {
+	write(record []byte) error
+	close() error
+}
+
+// Writer chains the audit records written by the audit logger, one per write, and delivers them to its sinks.
+// Each record carries its sequence number, the hash of the previous record and its own hash, so that
+// the edits and deletions of the records can be detected by verifying the chain.
+type Writer struct {
+	key   []byte
+	sinks []sink
+	seq   uint64
+	hash  string
+	lock  sync.Mutex
+	log   zlog.Logger
+}
+
+// NewLogger returns the audit logger of a log config, whose records are chained and delivered by the returned
+// writer, which must be closed to deliver the pending records.
+func NewLogger(logConfig *config.LogConfig, log zlog.Logger) (*zlog.Logger, *Writer, error) {
+	writer, err := NewWriter(logConfig, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return zlog.NewAuditLoggerWithWriter(logConfig.Level, writer), writer, nil
+}
+
+// NewWriter returns a writer delivering the records to the audit file and to the sinks of a log config.
+// The chain goes on from the last record of the audit file, if any.
+func NewWriter(logConfig *config.LogConfig, log zlog.Logger) (*Writer, error) {
+	auditLogConfig := logConfig.AuditLog
+	if auditLogConfig == nil {
+		auditLogConfig = &config.AuditLogConfig{}
+	}
+
+	writer := &Writer{log: log}
+
+	if auditLogConfig.HashKeyFile != "" {
+		key, err := ReadHashKey(auditLogConfig.HashKeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		writer.key = key
+	}
+
+	if logConfig.Audit != "" {
+		fileSink, err := newFileSink(logConfig.Audit, auditLogConfig, log)
+		if err != nil {
+			return nil, err
+		}
+
+		writer.sinks = append(writer.sinks, fileSink)
+
+		if err := writer.resume(fileSink); err != nil {
+			writer.Close() //nolint: errcheck
+
+			return nil, err
+		}
+	}
+
+	if auditLogConfig.Syslog != nil {
+		writer.sinks = append(writer.sinks, newSyslogSink(auditLogConfig.Syslog, log))
+	}
+
+	if auditLogConfig.HTTP != nil {
+		writer.sinks = append(writer.sinks, newHTTPSink(auditLogConfig.HTTP, log))
+	}
+
+	return writer, nil
+}
+
+// ReadHashKey reads the key the records are chained with from a file.
+func ReadHashKey(path string) ([]byte, error) {
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key = bytes.TrimSpace(key)
+	if len(key) == 0 {
+		return nil, fmt.Errorf("%w: empty audit hash key file %s", zerr.ErrBadConfig, path)
+	}
+
+	return key, nil
+}
+
+// resume makes the chain go on from the last complete record of the audit file.
+func (w *Writer) resume(fileSink *fileSink) error {
+	record, err := fileSink.lastRecord()
+	if err != nil {
+		return err
+	}
+
+	if len(record) == 0 {
+		// like the audit files written before the records were chained
+		if fileSink.size > 0 {
+			w.log.Warn().Str("file", fileSink.path).
+				Msg("audit file does not contain a chained record, starting a new chain")
+		}
+
+		return nil
+	}
+
+	_, fields, recordHash, err := unlink(record)
+	if err != nil {
+		return err
+	}
+
+	w.seq = fields.Seq
+	w.hash = recordHash
+
+	return nil
+}
+
+// Write chains a record and delivers it to the sinks.
+func (w *Writer) Write(record []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	chained, recordHash, err := link(record, w.seq+1, w.hash, w.key)
+	if err != nil {
+		return 0, err
+	}
+
+	// the chain goes on even if a sink fails, the missing record being detected when verifying it
+	w.seq++
+	w.hash = recordHash
+
+	var errs error
+
+	for _, sink := range w.sinks {
+		errs = errors.Join(errs, sink.write(chained))
+	}
+
+	if errs != nil {
+		return 0, errs
+	}
+
+	return len(record), nil
+}
+
+// Close delivers the pending records and closes the sinks.
+func (w *Writer) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var errs error
+
+	for _, sink := range w.sinks {
+		errs = errors.Join(errs, sink.close())
+	}
+
+	w.sinks = nil
+
+	return errs
+}
@@ -0,0 +1,388 @@
+package audit_test
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/audit"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+func verifyFiles(key []byte, paths ...string) (*audit.Verifier, error) {
+	verifier := audit.NewVerifier(key)
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := verifier.Verify(bytes.NewReader(content)); err != nil {
+			return verifier, err
+		}
+	}
+
+	return verifier, nil
+}
+
+// verifyRotatedFiles verifies files whose oldest records have been pruned, from the record the first one follows.
+func verifyRotatedFiles(paths ...string) (*audit.Verifier, error) {
+	content, err := os.ReadFile(paths[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var first struct {
+		Seq uint64 `json:"seq"`
+	}
+
+	firstLine, _, _ := bytes.Cut(content, []byte("\n"))
+	if err := json.Unmarshal(firstLine, &first); err != nil {
+		return nil, err
+	}
+
+	verifier := audit.NewVerifier(nil)
+	verifier.From(first.Seq-1, "")
+
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := verifier.Verify(bytes.NewReader(content)); err != nil {
+			return verifier, err
+		}
+	}
+
+	return verifier, nil
+}
+
+func TestAuditChain(t *testing.T) {
+	logger := log.NewLogger("debug", "")
+
+	Convey("Audit records are chained", t, func() {
+		auditPath := path.Join(t.TempDir(), "audit.log")
+		logConfig := &config.LogConfig{Level: "debug", Audit: auditPath}
+
+		auditLogger, writer, err := audit.NewLogger(logConfig, logger)
+		So(err, ShouldBeNil)
+
+		for _, action := range []string{"createUser", "updatePassword", "deleteUser"} {
+			auditLogger.Info().Str("subject", "admin").Str("action", action).Str("object", "alice").
+				Msg("HTTP API Audit")
+		}
+
+		So(writer.Close(), ShouldBeNil)
+
+		content, err := os.ReadFile(auditPath)
+		So(err, ShouldBeNil)
+
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		So(len(lines), ShouldEqual, 3)
+		So(lines[0], ShouldContainSubstring, `"action":"createUser","object":"alice"`)
+		So(lines[0], ShouldContainSubstring, `"seq":1,"prevHash":""`)
+		So(lines[1], ShouldContainSubstring, `"seq":2,"prevHash":"`)
+
+		verifier, err := verifyFiles(nil, auditPath)
+		So(err, ShouldBeNil)
+		So(verifier.Records, ShouldEqual, 3)
+		So(verifier.FirstSeq, ShouldEqual, 1)
+		So(verifier.LastSeq, ShouldEqual, 3)
+
+		Convey("Edits are detected", func() {
+			edited := strings.Replace(string(content), `"subject":"admin"`, `"subject":"bob"`, 1)
+			So(os.WriteFile(auditPath, []byte(edited), 0o600), ShouldBeNil)
+
+			_, err := verifyFiles(nil, auditPath)
+			So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+			So(err.Error(), ShouldContainSubstring, "line 1")
+		})
+
+		Convey("Deletions are detected", func() {
+			So(os.WriteFile(auditPath, []byte(lines[0]+"\n"+lines[2]+"\n"), 0o600), ShouldBeNil)
+
+			_, err := verifyFiles(nil, auditPath)
+			So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+			So(err.Error(), ShouldContainSubstring, "line 2")
+		})
+
+		Convey("A truncated chain is only verified from the record it follows", func() {
+			So(os.WriteFile(auditPath, []byte(lines[1]+"\n"+lines[2]+"\n"), 0o600), ShouldBeNil)
+
+			_, err := verifyFiles(nil, auditPath)
+			So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+			So(err.Error(), ShouldContainSubstring, "does not start the chain")
+
+			first := audit.NewVerifier(nil)
+			So(first.Verify(strings.NewReader(lines[0])), ShouldBeNil)
+
+			verifier := audit.NewVerifier(nil)
+			verifier.From(1, first.LastHash)
+			So(verifier.Verify(strings.NewReader(lines[1]+"\n"+lines[2])), ShouldBeNil)
+			So(verifier.FirstSeq, ShouldEqual, 2)
+
+			// without the hash, only the sequence numbers are checked
+			verifier = audit.NewVerifier(nil)
+			verifier.From(1, "")
+			So(verifier.Verify(strings.NewReader(lines[1]+"\n"+lines[2])), ShouldBeNil)
+
+			verifier = audit.NewVerifier(nil)
+			verifier.From(1, "0000")
+			So(verifier.Verify(strings.NewReader(lines[1])), ShouldWrap, zerr.ErrAuditChainBroken)
+
+			verifier = audit.NewVerifier(nil)
+			verifier.From(2, "")
+			So(verifier.Verify(strings.NewReader(lines[1])), ShouldWrap, zerr.ErrAuditChainBroken)
+		})
+
+		Convey("The last record is checked against the expected one", func() {
+			So(verifier.Expect(3, verifier.LastHash), ShouldBeNil)
+			So(verifier.Expect(0, ""), ShouldBeNil)
+			So(verifier.Expect(4, ""), ShouldWrap, zerr.ErrAuditChainBroken)
+			So(verifier.Expect(0, "0000"), ShouldWrap, zerr.ErrAuditChainBroken)
+		})
+
+		Convey("Unchained records are rejected", func() {
+			So(os.WriteFile(auditPath, []byte(`{"level":"info"}`+"\n"), 0o600), ShouldBeNil)
+
+			_, err := verifyFiles(nil, auditPath)
+			So(err, ShouldWrap, zerr.ErrInvalidAuditRecord)
+		})
+
+		Convey("The chain goes on from the last complete record of the file", func() {
+			// an interrupted write leaves a partial record
+			So(os.WriteFile(auditPath, []byte(string(content)+lines[2][:20]), 0o600), ShouldBeNil)
+
+			auditLogger, writer, err := audit.NewLogger(logConfig, logger)
+			So(err, ShouldBeNil)
+
+			auditLogger.Info().Str("action", "createUser").Msg("HTTP API Audit")
+			So(writer.Close(), ShouldBeNil)
+
+			content, err := os.ReadFile(auditPath)
+			So(err, ShouldBeNil)
+
+			lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+			So(len(lines), ShouldEqual, 5)
+			So(lines[4], ShouldContainSubstring, `"seq":4,"prevHash":"`+verifier.LastHash+`"`)
+
+			// the partial record is reported
+			_, err = verifyFiles(nil, auditPath)
+			So(err, ShouldWrap, zerr.ErrInvalidAuditRecord)
+			So(err.Error(), ShouldContainSubstring, "line 4")
+		})
+
+		Convey("The chain goes on from the last record of the file", func() {
+			auditLogger, writer, err := audit.NewLogger(logConfig, logger)
+			So(err, ShouldBeNil)
+
+			auditLogger.Info().Str("action", "createUser").Msg("HTTP API Audit")
+			So(writer.Close(), ShouldBeNil)
+
+			verifier, err := verifyFiles(nil, auditPath)
+			So(err, ShouldBeNil)
+			So(verifier.LastSeq, ShouldEqual, 4)
+		})
+	})
+
+	Convey("Audit records are chained with a key", t, func() {
+		dir := t.TempDir()
+		auditPath := path.Join(dir, "audit.log")
+		keyPath := path.Join(dir, "audit.key")
+		So(os.WriteFile(keyPath, []byte("secret\n"), 0o600), ShouldBeNil)
+
+		logConfig := &config.LogConfig{
+			Level:    "debug",
+			Audit:    auditPath,
+			AuditLog: &config.AuditLogConfig{HashKeyFile: keyPath},
+		}
+
+		auditLogger, writer, err := audit.NewLogger(logConfig, logger)
+		So(err, ShouldBeNil)
+
+		auditLogger.Info().Str("action", "createUser").Msg("HTTP API Audit")
+		So(writer.Close(), ShouldBeNil)
+
+		_, err = verifyFiles([]byte("secret"), auditPath)
+		So(err, ShouldBeNil)
+
+		_, err = verifyFiles(nil, auditPath)
+		So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+
+		So(os.WriteFile(keyPath, []byte(" \n"), 0o600), ShouldBeNil)
+
+		_, _, err = audit.NewLogger(logConfig, logger)
+		So(err, ShouldWrap, zerr.ErrBadConfig)
+	})
+
+	Convey("A chain starts after unchained records", t, func() {
+		auditPath := path.Join(t.TempDir(), "audit.log")
+		So(os.WriteFile(auditPath, []byte(`{"level":"info"}`+"\n"), 0o600), ShouldBeNil)
+
+		auditLogger, writer, err := audit.NewLogger(&config.LogConfig{Level: "debug", Audit: auditPath}, logger)
+		So(err, ShouldBeNil)
+
+		auditLogger.Info().Str("action", "createUser").Msg("HTTP API Audit")
+		So(writer.Close(), ShouldBeNil)
+
+		content, err := os.ReadFile(auditPath)
+		So(err, ShouldBeNil)
+		So(string(content), ShouldContainSubstring, `"seq":1,"prevHash":""`)
+	})
+}
+
+func TestAuditFileRotation(t *testing.T) {
+	logger := log.NewLogger("debug", "")
+
+	Convey("Audit files are rotated and pruned", t, func() {
+		auditPath := path.Join(t.TempDir(), "audit.log")
+		logConfig := &config.LogConfig{
+			Level: "debug",
+			Audit: auditPath,
+			AuditLog: &config.AuditLogConfig{
+				MaxSize:    1,
+				MaxBackups: 2,
+			},
+		}
+
+		auditLogger, writer, err := audit.NewLogger(logConfig, logger)
+		So(err, ShouldBeNil)
+
+		// each record is about a third of the maximum size
+		payload := strings.Repeat("a", 350*1024)
+
+		for range 10 {
+			auditLogger.Info().Str("payload", payload).Msg("HTTP API Audit")
+		}
+
+		So(writer.Close(), ShouldBeNil)
+
+		backups, err := filepath.Glob(auditPath + ".*")
+		So(err, ShouldBeNil)
+		So(len(backups), ShouldEqual, 2)
+
+		// the remaining files are still chained, from the oldest to the newest
+		_, err = verifyFiles(nil, append(backups, auditPath)...)
+		So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+
+		verifier, err := verifyRotatedFiles(append(backups, auditPath)...)
+		So(err, ShouldBeNil)
+		So(verifier.LastSeq, ShouldEqual, 10)
+		So(verifier.FirstSeq, ShouldBeGreaterThan, 1)
+
+		Convey("The chain goes on from the newest rotated file", func() {
+			So(os.Truncate(auditPath, 0), ShouldBeNil)
+
+			rotated, err := verifyRotatedFiles(backups...)
+			So(err, ShouldBeNil)
+
+			auditLogger, writer, err := audit.NewLogger(logConfig, logger)
+			So(err, ShouldBeNil)
+
+			auditLogger.Info().Msg("HTTP API Audit")
+			So(writer.Close(), ShouldBeNil)
+
+			verifier, err := verifyRotatedFiles(append(backups, auditPath)...)
+			So(err, ShouldBeNil)
+			So(verifier.LastSeq, ShouldEqual, rotated.LastSeq+1)
+		})
+	})
+}
+
+func TestAuditRemoteSinks(t *testing.T) {
+	logger := log.NewLogger("debug", "")
+
+	Convey("Audit records are posted to an http collector", t, func() {
+		var (
+			lock         sync.Mutex
+			records      []string
+			contentTypes []string
+		)
+
+		server := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			body, _ := io.ReadAll(request.Body)
+
+			lock.Lock()
+			records = append(records, strings.Split(strings.TrimSpace(string(body)), "\n")...)
+			contentTypes = append(contentTypes, request.Header.Get("Content-Type"))
+			lock.Unlock()
+		}))
+		defer server.Close()
+
+		logConfig := &config.LogConfig{
+			Level:    "debug",
+			AuditLog: &config.AuditLogConfig{HTTP: &config.AuditHTTPConfig{URL: server.URL}},
+		}
+
+		auditLogger, writer, err := audit.NewLogger(logConfig, logger)
+		So(err, ShouldBeNil)
+
+		for range 5 {
+			auditLogger.Info().Str("action", "createUser").Msg("HTTP API Audit")
+		}
+
+		// the pending records are delivered when closing
+		So(writer.Close(), ShouldBeNil)
+
+		lock.Lock()
+		defer lock.Unlock()
+
+		So(len(records), ShouldEqual, 5)
+		So(contentTypes, ShouldContain, "application/x-ndjson")
+
+		verifier := audit.NewVerifier(nil)
+		So(verifier.Verify(strings.NewReader(strings.Join(records, "\n"))), ShouldBeNil)
+		So(verifier.LastSeq, ShouldEqual, 5)
+	})
+
+	Convey("Audit records are sent to syslog", t, func() {
+		conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		So(err, ShouldBeNil)
+		defer conn.Close()
+
+		logConfig := &config.LogConfig{
+			Level: "debug",
+			AuditLog: &config.AuditLogConfig{
+				Syslog: &config.AuditSyslogConfig{Address: conn.LocalAddr().String(), Tag: "registry"},
+			},
+		}
+
+		auditLogger, writer, err := audit.NewLogger(logConfig, logger)
+		So(err, ShouldBeNil)
+
+		auditLogger.Info().Str("action", "createUser").Msg("HTTP API Audit")
+		So(writer.Close(), ShouldBeNil)
+
+		So(conn.SetReadDeadline(time.Now().Add(5*time.Second)), ShouldBeNil)
+
+		buf := make([]byte, 4096)
+		count, _, err := conn.ReadFrom(buf)
+		So(err, ShouldBeNil)
+
+		message := string(buf[:count])
+		So(message, ShouldStartWith, "<110>1 ")
+		So(message, ShouldContainSubstring, " registry - - - {")
+
+		record := message[strings.Index(message, "{"):]
+		verifier := audit.NewVerifier(nil)
+		So(verifier.Verify(bufio.NewReader(strings.NewReader(record))), ShouldBeNil)
+		So(verifier.LastSeq, ShouldEqual, 1)
+	})
+}
@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+
+	zerr "zotregistry.dev/zot/errors"
+)
+
+// the records are read by lines of up to 1MB.
+const maxRecordSize = 1 << 20
+
+var hashFieldPrefix = []byte(`,"hash":"`) //nolint: gochecknoglobals
+
+// chainFields are the fields chaining a record to the previous one, covered by its hash.
+type chainFields struct {
+	Seq      uint64 `json:"seq"`
+	PrevHash string `json:"prevHash"`
+}
+
+// link chains a record, a json object, to the previous one: the sequence number and the hash of the previous
+// record are added to it, then its hash, computed on everything before it. The chained record ends with
+// a new line.
+func link(record []byte, seq uint64, prevHash string, key []byte) ([]byte, string, error) {
+	record = bytes.TrimRight(record, "\n")
+	if len(record) < 2 || record[0] != '{' || record[len(record)-1] != '}' {
+		return nil, "", fmt.Errorf("%w: not a json object", zerr.ErrInvalidAuditRecord)
+	}
+
+	separator := ","
+	if len(record) == 2 { //nolint: mnd // empty object
+		separator = ""
+	}
+
+	// the capacity makes append copy the record, which zerolog reuses
+	body := record[: len(record)-1 : len(record)-1]
+	body = fmt.Appendf(body, `%s"seq":%d,"prevHash":"%s"}`, separator, seq, prevHash)
+
+	recordHash := hashRecord(body, key)
+
+	chained := append(body[:len(body)-1], hashFieldPrefix...)
+	chained = append(chained, recordHash...)
+	chained = append(chained, "\"}\n"...)
+
+	return chained, recordHash, nil
+}
+
+// unlink splits a chained record into the part covered by its hash, its chain fields and its hash.
+func unlink(record []byte) ([]byte, chainFields, string, error) {
+	var fields chainFields
+
+	record = bytes.TrimRight(record, "\r\n")
+
+	index := bytes.LastIndex(record, hashFieldPrefix)
+	if index < 0 || !bytes.HasSuffix(record, []byte(`"}`)) {
+		return nil, fields, "", fmt.Errorf("%w: not chained", zerr.ErrInvalidAuditRecord)
+	}
+
+	recordHash := string(record[index+len(hashFieldPrefix) : len(record)-2])
+	body := append(record[:index:index], '}')
+
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, fields, "", fmt.Errorf("%w: %w", zerr.ErrInvalidAuditRecord, err)
+	}
+
+	return body, fields, recordHash, nil
+}
+
+// hashRecord returns the sha256 hash of a record, or its hmac-sha256 if a key is given.
+func hashRecord(record, key []byte) string {
+	var hasher hash.Hash
+
+	if len(key) > 0 {
+		hasher = hmac.New(sha256.New, key)
+	} else {
+		hasher = sha256.New()
+	}
+
+	hasher.Write(record)
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
+
+// Verifier checks the hash chain of audit records, read in order from one or more files.
+type Verifier struct {
+	key []byte
+	// whether the chain continues a record given by From instead of starting at the first record
+	anchored bool
+	// number of verified records
+	Records int
+	// sequence numbers of the first and the last verified records, and hash of the last one
+	FirstSeq uint64
+	LastSeq  uint64
+	LastHash string
+}
+
+// NewVerifier returns a verifier of the records chained with key, or with sha256 if key is empty.
+func NewVerifier(key []byte) *Verifier {
+	return &Verifier{key: key}
+}
+
+// From makes the records verified next continue the record seq of hash recordHash, as reported by
+// a previous verification, instead of starting the chain. This is needed once the oldest rotated files
+// have been deleted. An empty hash only checks the sequence numbers.
+func (v *Verifier) From(seq uint64, recordHash string) {
+	v.anchored = true
+	v.LastSeq = seq
+	v.LastHash = recordHash
+}
+
+// Verify checks the records read from reader and that they continue the chain of the records verified before.
+// The first record verified must start the chain, unless the record it continues was given with From.
+func (v *Verifier) Verify(reader io.Reader) error {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRecordSize)
+
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		body, fields, recordHash, err := unlink(scanner.Bytes())
+		if err != nil {
+			return fmt.Errorf("line %d: %w", line, err)
+		}
+
+		if hashRecord(body, v.key) != recordHash {
+			return fmt.Errorf("%w: line %d: record %d does not match its hash", zerr.ErrAuditChainBroken,
+				line, fields.Seq)
+		}
+
+		switch {
+		case v.Records == 0 && !v.anchored && (fields.Seq != 1 || fields.PrevHash != ""):
+			return fmt.Errorf("%w: line %d: record %d does not start the chain", zerr.ErrAuditChainBroken,
+				line, fields.Seq)
+		case fields.Seq != v.LastSeq+1:
+			return fmt.Errorf("%w: line %d: record %d follows record %d", zerr.ErrAuditChainBroken, line,
+				fields.Seq, v.LastSeq)
+		case fields.PrevHash != v.LastHash && (v.Records > 0 || v.LastHash != ""):
+			return fmt.Errorf("%w: line %d: record %d does not follow the hash of record %d",
+				zerr.ErrAuditChainBroken, line, fields.Seq, v.LastSeq)
+		}
+
+		if v.Records == 0 {
+			v.FirstSeq = fields.Seq
+		}
+
+		v.Records++
+		v.LastSeq = fields.Seq
+		v.LastHash = recordHash
+	}
+
+	return scanner.Err()
+}
+
+// Expect checks that the last verified record is the record seq of hash recordHash, so that the deletion
+// of the newest records is detected. A zero seq or an empty hash is not checked.
+func (v *Verifier) Expect(seq uint64, recordHash string) error {
+	if seq != 0 && v.LastSeq != seq {
+		return fmt.Errorf("%w: last record is %d instead of %d", zerr.ErrAuditChainBroken, v.LastSeq, seq)
+	}
+
+	if recordHash != "" && v.LastHash != recordHash {
+		return fmt.Errorf("%w: last record %d has hash %s instead of %s", zerr.ErrAuditChainBroken,
+			v.LastSeq, v.LastHash, recordHash)
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package audit
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"zotregistry.dev/zot/pkg/api/config"
+	zlog "zotregistry.dev/zot/pkg/log"
+)
+
+const (
+	defaultFilePerms = 0o0600
+	megabyte         = 1 << 20
+	// suffix of the rotated files, sorting them by rotation time
+	backupTimeFormat = "20060102T150405.000000000"
+)
+
+// fileSink appends the records to a file, rotated once it is larger than maxSize bytes.
+type fileSink struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	file       *os.File
+	size       int64
+	log        zlog.Logger
+}
+
+func newFileSink(path string, auditLogConfig *config.AuditLogConfig, log zlog.Logger) (*fileSink, error) {
+	sink := &fileSink{
+		path:       path,
+		maxSize:    int64(auditLogConfig.MaxSize) * megabyte,
+		maxAge:     auditLogConfig.MaxAge,
+		maxBackups: auditLogConfig.MaxBackups,
+		log:        log,
+	}
+
+	if err := sink.open(); err != nil {
+		return nil, err
+	}
+
+	if err := sink.terminate(); err != nil {
+		sink.close() //nolint: errcheck
+
+		return nil, err
+	}
+
+	return sink, nil
+}
+
+func (sink *fileSink) open() error {
+	file, err := os.OpenFile(sink.path, os.O_APPEND|os.O_RDWR|os.O_CREATE, defaultFilePerms)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+
+		return err
+	}
+
+	sink.file = file
+	sink.size = info.Size()
+
+	return nil
+}
+
+func (sink *fileSink) write(record []byte) error {
+	if sink.maxSize > 0 && sink.size > 0 && sink.size+int64(len(record)) > sink.maxSize {
+		// the records are still written to the current file if it can not be rotated
+		if err := sink.rotate(); err != nil {
+			sink.log.Error().Err(err).Str("file", sink.path).Msg("failed to rotate audit file")
+		}
+	}
+
+	written, err := sink.file.Write(record)
+	sink.size += int64(written)
+
+	return err
+}
+
+func (sink *fileSink) rotate() error {
+	if err := sink.file.Close(); err != nil {
+		return err
+	}
+
+	backup := sink.path + "." + time.Now().UTC().Format(backupTimeFormat)
+
+	renameErr := os.Rename(sink.path, backup)
+
+	if err := sink.open(); err != nil {
+		return errors.Join(renameErr, err)
+	}
+
+	if renameErr != nil {
+		return renameErr
+	}
+
+	sink.prune()
+
+	return nil
+}
+
+// prune deletes the rotated files older than maxAge and the oldest ones beyond maxBackups.
+func (sink *fileSink) prune() {
+	backups, err := sink.backups()
+	if err != nil {
+		sink.log.Error().Err(err).Str("file", sink.path).Msg("failed to list rotated audit files")
+
+		return
+	}
+
+	for index, backup := range backups {
+		expired := sink.maxBackups > 0 && index < len(backups)-sink.maxBackups
+
+		if !expired && sink.maxAge > 0 {
+			info, err := os.Stat(backup)
+			expired = err == nil && time.Since(info.ModTime()) > sink.maxAge
+		}
+
+		if !expired {
+			continue
+		}
+
+		if err := os.Remove(backup); err != nil {
+			sink.log.Error().Err(err).Str("file", backup).Msg("failed to delete rotated audit file")
+		}
+	}
+}
+
+// backups returns the rotated files, from the oldest to the newest.
+func (sink *fileSink) backups() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(sink.path))
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := filepath.Base(sink.path) + "."
+	backups := []string{}
+
+	for _, entry := range entries {
+		suffix, ok := strings.CutPrefix(entry.Name(), prefix)
+		if !ok || entry.IsDir() {
+			continue
+		}
+
+		if _, err := time.Parse(backupTimeFormat, suffix); err == nil {
+			backups = append(backups, filepath.Join(filepath.Dir(sink.path), entry.Name()))
+		}
+	}
+
+	slices.Sort(backups)
+
+	return backups, nil
+}
+
+// lastRecord returns the last complete chained record, in the file or else in the newest rotated file, if any.
+// A partial record left by an interrupted write is skipped.
+func (sink *fileSink) lastRecord() ([]byte, error) {
+	record, err := lastChainedLine(sink.path)
+	if err != nil || len(record) > 0 {
+		return record, err
+	}
+
+	backups, err := sink.backups()
+	if err != nil || len(backups) == 0 {
+		return nil, err
+	}
+
+	return lastChainedLine(backups[len(backups)-1])
+}
+
+// terminate ends the file with a new line if its last record is partial, so that the next records
+// are not appended to it.
+func (sink *fileSink) terminate() error {
+	if sink.size == 0 {
+		return nil
+	}
+
+	last := make([]byte, 1)
+	if _, err := sink.file.ReadAt(last, sink.size-1); err != nil {
+		return err
+	}
+
+	if last[0] == '\n' {
+		return nil
+	}
+
+	sink.log.Warn().Str("file", sink.path).Msg("audit file ends with a partial record")
+
+	written, err := sink.file.Write([]byte("\n"))
+	sink.size += int64(written)
+
+	return err
+}
+
+func (sink *fileSink) close() error {
+	return sink.file.Close()
+}
+
+// lastChainedLine returns the last complete line of a file which is a chained record,
+// reading at most maxRecordSize bytes of it.
+func lastChainedLine(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	offset := max(0, info.Size()-maxRecordSize)
+
+	tail := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(tail, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	// the line after the last new line is partial
+	tail = tail[:bytes.LastIndexByte(tail, '\n')+1]
+
+	for len(tail) > 0 {
+		tail = bytes.TrimRight(tail, "\r\n")
+		index := bytes.LastIndexByte(tail, '\n')
+		line := tail[index+1:]
+
+		// the first line of the tail may be cut, unless it starts the file
+		if index < 0 && offset > 0 {
+			break
+		}
+
+		if _, _, _, err := unlink(line); err == nil {
+			return line, nil
+		}
+
+		tail = tail[:index+1]
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,238 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"time"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	zlog "zotregistry.dev/zot/pkg/log"
+)
+
+const (
+	remoteSinkBufferSize = 1000
+	remoteSinkRetries    = 3
+	remoteSinkRetryDelay = time.Second
+	httpSinkBatchSize    = 100
+	defaultHTTPTimeout   = 10 * time.Second
+	syslogDialTimeout    = 10 * time.Second
+	defaultSyslogNetwork = "udp"
+	defaultSyslogTag     = "zot"
+	// facility log audit (13) and severity informational (6)
+	syslogPriority = 13*8 + 6
+)
+
+// remoteSink delivers the records in the background, so that the requests are not slowed down by the remote
+// collectors. The records are dropped when they can not be delivered or when the collectors can not keep up.
+type remoteSink struct {
+	name      string
+	batchSize int
+	deliver   func(records [][]byte) error
+	closer    func() error
+	records   chan []byte
+	done      chan struct{}
+	log       zlog.Logger
+}
+
+func newRemoteSink(name string, batchSize int, deliver func(records [][]byte) error, closer func() error,
+	log zlog.Logger,
+) *remoteSink {
+	sink := &remoteSink{
+		name:      name,
+		batchSize: batchSize,
+		deliver:   deliver,
+		closer:    closer,
+		records:   make(chan []byte, remoteSinkBufferSize),
+		done:      make(chan struct{}),
+		log:       log,
+	}
+
+	go sink.run()
+
+	return sink
+}
+
+func (sink *remoteSink) write(record []byte) error {
+	select {
+	case sink.records <- record:
+	default:
+		sink.log.Error().Str("sink", sink.name).Msg("dropped audit record, the sink can not keep up")
+	}
+
+	return nil
+}
+
+func (sink *remoteSink) run() {
+	defer close(sink.done)
+
+	for record := range sink.records {
+		batch := [][]byte{record}
+
+	batching:
+		for len(batch) < sink.batchSize {
+			select {
+			case record, ok := <-sink.records:
+				if !ok {
+					break batching
+				}
+
+				batch = append(batch, record)
+			default:
+				break batching
+			}
+		}
+
+		sink.deliverBatch(batch)
+	}
+}
+
+func (sink *remoteSink) deliverBatch(batch [][]byte) {
+	var err error
+
+	for attempt := 1; attempt <= remoteSinkRetries; attempt++ {
+		if err = sink.deliver(batch); err == nil {
+			return
+		}
+
+		if attempt < remoteSinkRetries {
+			time.Sleep(time.Duration(attempt) * remoteSinkRetryDelay)
+		}
+	}
+
+	sink.log.Error().Err(err).Str("sink", sink.name).Int("records", len(batch)).
+		Msg("failed to deliver audit records")
+}
+
+// close delivers the pending records before closing the sink.
+func (sink *remoteSink) close() error {
+	close(sink.records)
+	<-sink.done
+
+	if sink.closer != nil {
+		return sink.closer()
+	}
+
+	return nil
+}
+
+// syslogClient sends the records as RFC 5424 messages, one per udp or unixgram datagram
+// or per line over tcp.
+type syslogClient struct {
+	network  string
+	address  string
+	tag      string
+	hostname string
+	conn     net.Conn
+}
+
+func newSyslogSink(syslogConfig *config.AuditSyslogConfig, log zlog.Logger) *remoteSink {
+	client := &syslogClient{
+		network: syslogConfig.Network,
+		address: syslogConfig.Address,
+		tag:     syslogConfig.Tag,
+	}
+
+	if client.network == "" {
+		client.network = defaultSyslogNetwork
+	}
+
+	if client.tag == "" {
+		client.tag = defaultSyslogTag
+	}
+
+	client.hostname, _ = os.Hostname()
+	if client.hostname == "" {
+		client.hostname = "-"
+	}
+
+	// the records are sent one by one, so that the ones already sent are not sent again on retries
+	return newRemoteSink("syslog", 1, client.send, client.close, log)
+}
+
+func (client *syslogClient) send(records [][]byte) error {
+	if client.conn == nil {
+		conn, err := net.DialTimeout(client.network, client.address, syslogDialTimeout)
+		if err != nil {
+			return err
+		}
+
+		client.conn = conn
+	}
+
+	for _, record := range records {
+		message := fmt.Appendf(nil, "<%d>1 %s %s %s - - - %s", syslogPriority,
+			time.Now().UTC().Format("2006-01-02T15:04:05.000000Z07:00"), client.hostname, client.tag,
+			bytes.TrimRight(record, "\n"))
+
+		if client.network == "tcp" {
+			message = append(message, '\n')
+		}
+
+		if _, err := client.conn.Write(message); err != nil {
+			// reconnects on the next attempt
+			client.close() //nolint: errcheck
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (client *syslogClient) close() error {
+	if client.conn == nil {
+		return nil
+	}
+
+	err := client.conn.Close()
+	client.conn = nil
+
+	return err
+}
+
+// httpCollector posts the records to an http collector, in batches of json lines.
+type httpCollector struct {
+	url        string
+	httpClient *http.Client
+}
+
+func newHTTPSink(httpConfig *config.AuditHTTPConfig, log zlog.Logger) *remoteSink {
+	timeout := httpConfig.Timeout
+	if timeout <= 0 {
+		timeout = defaultHTTPTimeout
+	}
+
+	collector := &httpCollector{
+		url:        httpConfig.URL,
+		httpClient: &http.Client{Timeout: timeout},
+	}
+
+	return newRemoteSink("http", httpSinkBatchSize, collector.post, nil, log)
+}
+
+func (collector *httpCollector) post(records [][]byte) error {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodPost, collector.url,
+		bytes.NewReader(bytes.Join(records, nil)))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/x-ndjson")
+
+	response, err := collector.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < http.StatusOK || response.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", zerr.ErrUnexpectedAuditSinkStatus, response.StatusCode)
+	}
+
+	return nil
+}
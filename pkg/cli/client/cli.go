@@ -12,4 +12,6 @@ func enableCli(rootCmd *cobra.Command) {
 	rootCmd.AddCommand(NewRepoCommand(NewSearchService()))
 	rootCmd.AddCommand(NewSearchCommand(NewSearchService()))
 	rootCmd.AddCommand(NewServerStatusCommand())
+	rootCmd.AddCommand(NewUserCommand())
+	rootCmd.AddCommand(NewGroupCommand())
 }
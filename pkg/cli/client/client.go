@@ -54,6 +54,34 @@ func makeHEADRequest(ctx context.Context, url, username, password string, verify
 	return doHTTPRequest(req, verifyTLS, debug, nil, io.Discard)
 }
 
+func makeJSONRequest(ctx context.Context, method, url, username, password string,
+	verifyTLS bool, debug bool, body interface{}, resultsPtr interface{}, configWriter io.Writer,
+) (http.Header, error) {
+	var reqBody io.Reader
+
+	if body != nil {
+		bodyBytes, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+
+		reqBody = bytes.NewReader(bodyBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	req.SetBasicAuth(username, password)
+
+	if body != nil {
+		req.Header.Add("Content-Type", "application/json")
+	}
+
+	return doHTTPRequest(req, verifyTLS, debug, resultsPtr, configWriter)
+}
+
 func makeGraphQLRequest(ctx context.Context, url, query, username,
 	password string, verifyTLS bool, debug bool, resultsPtr interface{}, configWriter io.Writer,
 ) error {
@@ -124,7 +152,7 @@ func doHTTPRequest(req *http.Request, verifyTLS bool, debug bool,
 
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		var err error
 
 		switch resp.StatusCode {
@@ -12,17 +12,19 @@ import (
 )
 
 const (
-	URLFlag          = "url"
-	ConfigFlag       = "config"
-	UserFlag         = "user"
-	OutputFormatFlag = "format"
-	FixedFlag        = "fixed"
-	VerboseFlag      = "verbose"
-	VersionFlag      = "version"
-	DebugFlag        = "debug"
-	SearchedCVEID    = "cve-id"
-	SortByFlag       = "sort-by"
-	PlatformFlag     = "platform"
+	URLFlag           = "url"
+	ConfigFlag        = "config"
+	UserFlag          = "user"
+	OutputFormatFlag  = "format"
+	FixedFlag         = "fixed"
+	VerboseFlag       = "verbose"
+	VersionFlag       = "version"
+	DebugFlag         = "debug"
+	SearchedCVEID     = "cve-id"
+	SortByFlag        = "sort-by"
+	PlatformFlag      = "platform"
+	PasswordFlag      = "password"
+	PasswordStdinFlag = "password-stdin"
 )
 
 const (
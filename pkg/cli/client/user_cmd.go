@@ -0,0 +1,330 @@
+//go:build search
+// +build search
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/constants"
+)
+
+type userPayload struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password"`
+}
+
+type groupPayload struct {
+	Users []string `json:"users"`
+}
+
+func NewUserCommand() *cobra.Command {
+	userCmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage the htpasswd users of the server",
+		Long:  `Manage the htpasswd users of the server, available to admins only`,
+		RunE:  ShowSuggestionsIfUnknownCommand,
+	}
+
+	userCmd.SetUsageTemplate(userCmd.UsageTemplate() + usageFooter)
+
+	addAccountsFlags(userCmd)
+
+	userCmd.AddCommand(NewListUsersCommand())
+	userCmd.AddCommand(NewAddUserCommand())
+	userCmd.AddCommand(NewUpdateUserPasswordCommand())
+	userCmd.AddCommand(NewRemoveUserCommand())
+
+	return userCmd
+}
+
+func NewGroupCommand() *cobra.Command {
+	groupCmd := &cobra.Command{
+		Use:   "group",
+		Short: "Manage the access control groups of the server",
+		Long:  `Manage the access control groups of the server, available to admins only`,
+		RunE:  ShowSuggestionsIfUnknownCommand,
+	}
+
+	groupCmd.SetUsageTemplate(groupCmd.UsageTemplate() + usageFooter)
+
+	addAccountsFlags(groupCmd)
+
+	groupCmd.AddCommand(NewListGroupsCommand())
+	groupCmd.AddCommand(NewSetGroupCommand())
+	groupCmd.AddCommand(NewRemoveGroupCommand())
+
+	return groupCmd
+}
+
+func addAccountsFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().String(URLFlag, "",
+		"Specify zot server URL if config-name is not mentioned")
+	cmd.PersistentFlags().String(ConfigFlag, "",
+		"Specify the registry configuration to use for connection")
+	cmd.PersistentFlags().StringP(UserFlag, "u", "",
+		`User Credentials of zot server in "username:password" format`)
+	cmd.PersistentFlags().Bool(DebugFlag, false, "Show debug output")
+}
+
+func addPasswordFlags(cmd *cobra.Command) {
+	cmd.Flags().String(PasswordFlag, "", "Password of the user, visible in the shell history")
+	cmd.Flags().Bool(PasswordStdinFlag, false, "Read the password of the user from stdin")
+}
+
+func NewListUsersCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the htpasswd users",
+		Long:  "List the htpasswd users",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			searchConfig, err := GetSearchConfigFromFlags(cmd, NewSearchService())
+			if err != nil {
+				return err
+			}
+
+			users := []string{}
+
+			if err := makeAccountsRequest(searchConfig, http.MethodGet, constants.FullMgmtUsers, nil,
+				&users); err != nil {
+				return err
+			}
+
+			return printAccounts(searchConfig, users, strings.Join(users, "\n"))
+		},
+	}
+
+	cmd.Flags().StringP(OutputFormatFlag, "f", "text", "Specify the output format [text|json|yaml]")
+
+	return cmd
+}
+
+func NewAddUserCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "add [username]",
+		Short: "Add a htpasswd user",
+		Long:  "Add a htpasswd user, reading its password from --password or --password-stdin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			searchConfig, err := GetSearchConfigFromFlags(cmd, NewSearchService())
+			if err != nil {
+				return err
+			}
+
+			password, err := getPasswordFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			return makeAccountsRequest(searchConfig, http.MethodPost, constants.FullMgmtUsers,
+				userPayload{Username: args[0], Password: password}, nil)
+		},
+	}
+
+	addPasswordFlags(cmd)
+
+	return cmd
+}
+
+func NewUpdateUserPasswordCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "passwd [username]",
+		Short: "Update the password of a htpasswd user",
+		Long:  "Update the password of a htpasswd user, reading it from --password or --password-stdin",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			searchConfig, err := GetSearchConfigFromFlags(cmd, NewSearchService())
+			if err != nil {
+				return err
+			}
+
+			password, err := getPasswordFromFlags(cmd)
+			if err != nil {
+				return err
+			}
+
+			return makeAccountsRequest(searchConfig, http.MethodPut,
+				constants.FullMgmtUsers+"/"+url.PathEscape(args[0]), userPayload{Password: password}, nil)
+		},
+	}
+
+	addPasswordFlags(cmd)
+
+	return cmd
+}
+
+func NewRemoveUserCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove [username]",
+		Short: "Remove a htpasswd user",
+		Long:  "Remove a htpasswd user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			searchConfig, err := GetSearchConfigFromFlags(cmd, NewSearchService())
+			if err != nil {
+				return err
+			}
+
+			return makeAccountsRequest(searchConfig, http.MethodDelete,
+				constants.FullMgmtUsers+"/"+url.PathEscape(args[0]), nil, nil)
+		},
+	}
+}
+
+func NewListGroupsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the access control groups",
+		Long:  "List the access control groups and their users",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			searchConfig, err := GetSearchConfigFromFlags(cmd, NewSearchService())
+			if err != nil {
+				return err
+			}
+
+			groups := map[string]groupPayload{}
+
+			if err := makeAccountsRequest(searchConfig, http.MethodGet, constants.FullMgmtGroups, nil,
+				&groups); err != nil {
+				return err
+			}
+
+			names := slices.Sorted(maps.Keys(groups))
+
+			lines := make([]string, 0, len(names))
+			for _, name := range names {
+				lines = append(lines, name+": "+strings.Join(groups[name].Users, ", "))
+			}
+
+			return printAccounts(searchConfig, groups, strings.Join(lines, "\n"))
+		},
+	}
+
+	cmd.Flags().StringP(OutputFormatFlag, "f", "text", "Specify the output format [text|json|yaml]")
+
+	return cmd
+}
+
+func NewSetGroupCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set [group] [users...]",
+		Short: "Set the users of an access control group",
+		Long:  "Create an access control group or replace its users",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			searchConfig, err := GetSearchConfigFromFlags(cmd, NewSearchService())
+			if err != nil {
+				return err
+			}
+
+			return makeAccountsRequest(searchConfig, http.MethodPut,
+				constants.FullMgmtGroups+"/"+url.PathEscape(args[0]), groupPayload{Users: args[1:]}, nil)
+		},
+	}
+}
+
+func NewRemoveGroupCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove [group]",
+		Short: "Remove an access control group",
+		Long:  "Remove an access control group",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			searchConfig, err := GetSearchConfigFromFlags(cmd, NewSearchService())
+			if err != nil {
+				return err
+			}
+
+			return makeAccountsRequest(searchConfig, http.MethodDelete,
+				constants.FullMgmtGroups+"/"+url.PathEscape(args[0]), nil, nil)
+		},
+	}
+}
+
+// getPasswordFromFlags returns the password given with --password, or read from stdin with --password-stdin.
+func getPasswordFromFlags(cmd *cobra.Command) (string, error) {
+	password := defaultIfError(cmd.Flags().GetString(PasswordFlag))
+	passwordStdin := defaultIfError(cmd.Flags().GetBool(PasswordStdinFlag))
+
+	if password != "" && passwordStdin {
+		return "", fmt.Errorf("%w: --%s and --%s are mutually exclusive", zerr.ErrInvalidFlagsCombination,
+			PasswordFlag, PasswordStdinFlag)
+	}
+
+	if passwordStdin {
+		line, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+
+		password = strings.TrimRight(line, "\r\n")
+	}
+
+	if password == "" {
+		return "", fmt.Errorf("%w: the password is empty, use --%s or --%s", zerr.ErrInvalidArgs,
+			PasswordFlag, PasswordStdinFlag)
+	}
+
+	return password, nil
+}
+
+func makeAccountsRequest(config SearchConfig, method, endpoint string, body, resultsPtr interface{}) error {
+	username, password := getUsernameAndPassword(config.User)
+
+	endpointURL, err := combineServerAndEndpointURL(config.ServURL, endpoint)
+	if err != nil {
+		return err
+	}
+
+	_, err = makeJSONRequest(context.Background(), method, endpointURL, username, password, config.VerifyTLS,
+		config.Debug, body, resultsPtr, config.ResultWriter)
+
+	return err
+}
+
+func printAccounts(config SearchConfig, accounts interface{}, text string) error {
+	var output string
+
+	switch config.OutputFormat {
+	case "text", "":
+		output = text
+	case "json":
+		body, err := json.MarshalIndent(accounts, "", "    ")
+		if err != nil {
+			return err
+		}
+
+		output = string(body)
+	case "yaml", "yml":
+		body, err := yaml.Marshal(accounts)
+		if err != nil {
+			return err
+		}
+
+		output = strings.TrimSuffix(string(body), "\n")
+	default:
+		return zerr.ErrFormatNotSupported
+	}
+
+	if output != "" {
+		fmt.Fprintln(config.ResultWriter, output)
+	}
+
+	return nil
+}
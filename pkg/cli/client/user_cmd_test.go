@@ -0,0 +1,151 @@
+//go:build search && mgmt
+// +build search,mgmt
+
+package client //nolint:testpackage
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/resty.v1"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/api/constants"
+	extconf "zotregistry.dev/zot/pkg/extensions/config"
+	test "zotregistry.dev/zot/pkg/test/common"
+)
+
+func TestUserAndGroupCommands(t *testing.T) {
+	Convey("Test managing the users and groups with zli", t, func() {
+		adminCredentials := "admin:admin"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString("admin", "admin"))
+		defer os.Remove(htpasswdPath)
+
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+		conf := config.New()
+		conf.HTTP.Port = port
+		conf.HTTP.Auth.HTPasswd.Path = htpasswdPath
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			GroupsFile: path.Join(t.TempDir(), "groups.json"),
+			AdminPolicy: config.Policy{
+				Users:   []string{"admin"},
+				Actions: []string{constants.ReadPermission},
+			},
+		}
+		defaultVal := true
+		conf.Extensions = &extconf.ExtensionConfig{
+			Search: &extconf.SearchConfig{BaseConfig: extconf.BaseConfig{Enable: &defaultVal}},
+			UI:     &extconf.UIConfig{BaseConfig: extconf.BaseConfig{Enable: &defaultVal}},
+		}
+		conf.Extensions.Search.CVE = nil
+
+		ctlr := api.NewController(conf)
+		ctlr.Config.Storage.RootDirectory = t.TempDir()
+		cm := test.NewControllerManager(ctlr)
+
+		cm.StartAndWait(conf.HTTP.Port)
+		defer cm.StopServer()
+
+		configPath := makeConfigFile(fmt.Sprintf(`{"configs":[{"_name":"accounts-test","url":"%s","showspinner":false}]}`,
+			baseURL))
+		defer os.Remove(configPath)
+
+		execute := func(stdin string, args ...string) (string, error) {
+			cmd := NewCliRootCmd()
+			buff := bytes.NewBufferString("")
+			cmd.SetOut(buff)
+			cmd.SetErr(buff)
+			cmd.SetIn(strings.NewReader(stdin))
+			cmd.SetArgs(args)
+			err := cmd.Execute()
+
+			return buff.String(), err
+		}
+
+		_, err := execute("", "user", "add", "alice", "--password", "alice", "--config", "accounts-test",
+			"-u", adminCredentials)
+		So(err, ShouldBeNil)
+
+		_, err = execute("bob\n", "user", "add", "bob", "--password-stdin", "--config", "accounts-test",
+			"-u", adminCredentials)
+		So(err, ShouldBeNil)
+
+		output, err := execute("", "user", "list", "--config", "accounts-test", "-u", adminCredentials)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "admin\nalice\nbob\n")
+
+		output, err = execute("", "user", "list", "--config", "accounts-test", "-u", adminCredentials,
+			"--format", "json")
+		So(err, ShouldBeNil)
+		So(output, ShouldContainSubstring, `"alice"`)
+
+		_, err = execute("", "user", "list", "--config", "accounts-test", "-u", adminCredentials,
+			"--format", "badType")
+		So(err, ShouldEqual, zerr.ErrFormatNotSupported)
+
+		_, err = execute("", "user", "passwd", "alice", "--password", "alice2", "--config", "accounts-test",
+			"-u", adminCredentials)
+		So(err, ShouldBeNil)
+
+		resp, err := resty.R().SetBasicAuth("alice", "alice2").Get(baseURL + "/v2/")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		_, err = execute("", "user", "passwd", "alice", "--config", "accounts-test", "-u", adminCredentials)
+		So(err, ShouldWrap, zerr.ErrInvalidArgs)
+
+		_, err = execute("alice\n", "user", "passwd", "alice", "--password", "alice", "--password-stdin",
+			"--config", "accounts-test", "-u", adminCredentials)
+		So(err, ShouldWrap, zerr.ErrInvalidFlagsCombination)
+
+		_, err = execute("", "user", "add", "alice", "--password", "alice", "--config", "accounts-test",
+			"-u", adminCredentials)
+		So(err, ShouldWrap, zerr.ErrBadHTTPStatusCode)
+
+		_, err = execute("", "user", "remove", "bob", "--config", "accounts-test", "-u", adminCredentials)
+		So(err, ShouldBeNil)
+
+		_, err = execute("", "user", "remove", "bob", "--config", "accounts-test", "-u", adminCredentials)
+		So(err, ShouldWrap, zerr.ErrURLNotFound)
+
+		// only admins manage the users
+		_, err = execute("", "user", "list", "--config", "accounts-test", "-u", "alice:alice2")
+		So(err, ShouldWrap, zerr.ErrBadHTTPStatusCode)
+
+		_, err = execute("", "group", "set", "devs", "alice", "admin", "--config", "accounts-test",
+			"-u", adminCredentials)
+		So(err, ShouldBeNil)
+
+		_, err = execute("", "group", "set", "ops", "--config", "accounts-test", "-u", adminCredentials)
+		So(err, ShouldBeNil)
+
+		output, err = execute("", "group", "list", "--config", "accounts-test", "-u", adminCredentials)
+		So(err, ShouldBeNil)
+		So(output, ShouldEqual, "devs: alice, admin\nops: \n")
+
+		output, err = execute("", "group", "list", "--config", "accounts-test", "-u", adminCredentials,
+			"--format", "yaml")
+		So(err, ShouldBeNil)
+		So(output, ShouldContainSubstring, "devs:")
+
+		_, err = execute("", "group", "remove", "ops", "--config", "accounts-test", "-u", adminCredentials)
+		So(err, ShouldBeNil)
+
+		_, err = execute("", "group", "remove", "ops", "--config", "accounts-test", "-u", adminCredentials)
+		So(err, ShouldWrap, zerr.ErrURLNotFound)
+
+		So(ctlr.Config.HTTP.AccessControl.Groups, ShouldResemble, config.Groups{
+			"devs": config.Group{Users: []string{"alice", "admin"}},
+		})
+	})
+}
@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -22,11 +24,14 @@ import (
 	"github.com/spf13/viper"
 
 	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/accounts"
 	"zotregistry.dev/zot/pkg/api"
 	"zotregistry.dev/zot/pkg/api/config"
 	"zotregistry.dev/zot/pkg/api/constants"
+	"zotregistry.dev/zot/pkg/audit"
 	"zotregistry.dev/zot/pkg/common"
 	extconf "zotregistry.dev/zot/pkg/extensions/config"
+	"zotregistry.dev/zot/pkg/extensions/events"
 	"zotregistry.dev/zot/pkg/extensions/monitoring"
 	zlog "zotregistry.dev/zot/pkg/log"
 	storageConstants "zotregistry.dev/zot/pkg/storage/constants"
@@ -180,6 +185,97 @@ func newVerifyCmd(conf *config.Config) *cobra.Command {
 	return verifyCmd
 }
 
+func newAuditCmd() *cobra.Command {
+	// "audit"
+	auditCmd := &cobra.Command{
+		Use:   "audit",
+		Short: "`audit` inspects the audit logs",
+		Long:  "`audit` inspects the audit logs",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Usage()
+		},
+	}
+
+	var (
+		keyFile  string
+		fromSeq  uint64
+		fromHash string
+		lastSeq  uint64
+		lastHash string
+	)
+
+	// "audit verify"
+	verifyCmd := &cobra.Command{
+		Use:   "verify <file>...",
+		Short: "`verify` checks the hash chain of audit log files",
+		Long: "`verify` checks the hash chain of audit log files, given from the oldest to the newest, " +
+			"so that the edited, deleted or reordered records are detected. The first record must start the chain, " +
+			"unless the record it follows is given, and the last record can be checked against a previous report",
+		Args: cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
+
+			var key []byte
+
+			if keyFile != "" {
+				var err error
+
+				if key, err = audit.ReadHashKey(keyFile); err != nil {
+					log.Error().Err(err).Str("file", keyFile).Msg("failed to read audit hash key")
+
+					return err
+				}
+			}
+
+			verifier := audit.NewVerifier(key)
+
+			if fromSeq != 0 || fromHash != "" {
+				verifier.From(fromSeq, fromHash)
+			}
+
+			for _, path := range args {
+				if err := verifyAuditFile(verifier, path); err != nil {
+					log.Error().Err(err).Str("file", path).Msg("audit log verification failed")
+
+					return err
+				}
+			}
+
+			if err := verifier.Expect(lastSeq, lastHash); err != nil {
+				log.Error().Err(err).Msg("audit log verification failed")
+
+				return err
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "verified %d records, from %d to %d, last hash %s\n",
+				verifier.Records, verifier.FirstSeq, verifier.LastSeq, verifier.LastHash)
+
+			return nil
+		},
+	}
+
+	verifyCmd.Flags().StringVar(&keyFile, "key-file", "", "file of the key the records are chained with")
+	verifyCmd.Flags().Uint64Var(&fromSeq, "from-seq", 0,
+		"sequence number of the record the first record follows, when the oldest files have been deleted")
+	verifyCmd.Flags().StringVar(&fromHash, "from-hash", "", "hash of the record the first record follows")
+	verifyCmd.Flags().Uint64Var(&lastSeq, "last-seq", 0, "expected sequence number of the last record")
+	verifyCmd.Flags().StringVar(&lastHash, "last-hash", "", "expected hash of the last record")
+
+	auditCmd.AddCommand(verifyCmd)
+
+	return auditCmd
+}
+
+func verifyAuditFile(verifier *audit.Verifier, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return verifier.Verify(file)
+}
+
 // "zot" - registry server.
 func NewServerRootCmd() *cobra.Command {
 	showVersion := false
@@ -206,6 +302,7 @@ func NewServerRootCmd() *cobra.Command {
 	rootCmd.AddCommand(newServeCmd(conf))
 	// "verify"
 	rootCmd.AddCommand(newVerifyCmd(conf))
+	rootCmd.AddCommand(newAuditCmd())
 	// "scrub"
 	rootCmd.AddCommand(newScrubCmd(conf))
 	// "version"
@@ -374,6 +471,14 @@ func validateConfiguration(config *config.Config, log zlog.Logger) error {
 		return err
 	}
 
+	if err := validateQuotas(config, log); err != nil {
+		return err
+	}
+
+	if err := validateImmutableTags(config, log); err != nil {
+		return err
+	}
+
 	if err := validateLDAP(config, log); err != nil {
 		return err
 	}
@@ -382,10 +487,54 @@ func validateConfiguration(config *config.Config, log zlog.Logger) error {
 		return err
 	}
 
+	if err := validateTokenServer(config, log); err != nil {
+		return err
+	}
+
+	if err := validateBearerKeys(config, log); err != nil {
+		return err
+	}
+
+	if err := validateWorkloadIdentity(config, log); err != nil {
+		return err
+	}
+
+	if err := validateProxyHeader(config, log); err != nil {
+		return err
+	}
+
+	if err := validateMTLSMapping(config, log); err != nil {
+		return err
+	}
+
+	if err := validateLockout(config, log); err != nil {
+		return err
+	}
+
+	if err := validateRatelimit(config, log); err != nil {
+		return err
+	}
+
+	if err := validateAuditLog(config, log); err != nil {
+		return err
+	}
+
+	if err := validateTracing(config, log); err != nil {
+		return err
+	}
+
+	if err := validateHTPasswdHashAlgorithm(config, log); err != nil {
+		return err
+	}
+
 	if err := validateSync(config, log); err != nil {
 		return err
 	}
 
+	if err := validateEvents(config, log); err != nil {
+		return err
+	}
+
 	if err := validateStorageConfig(config, log); err != nil {
 		return err
 	}
@@ -404,6 +553,28 @@ func validateConfiguration(config *config.Config, log zlog.Logger) error {
 		if err := validateAuthzPolicies(config, log); err != nil {
 			return err
 		}
+
+		if err := validatePolicyConditions(config, log); err != nil {
+			return err
+		}
+
+		if err := validateNetworkPolicies(config, log); err != nil {
+			return err
+		}
+
+		// the tag regexes and the cidrs of the policies are compiled once, when loading the config
+		if err := config.HTTP.AccessControl.Compile(); err != nil {
+			msg := "invalid access control policy"
+			log.Error().Err(err).Msg(msg)
+
+			return fmt.Errorf("%w: %s: %w", zerr.ErrBadConfig, msg, err)
+		}
+
+		if err := validateAuthzWebhook(config, log); err != nil {
+			return err
+		}
+
+		reportPolicyConflicts(config, log)
 	}
 
 	if len(config.Storage.StorageDriver) != 0 {
@@ -451,53 +622,429 @@ func validateConfiguration(config *config.Config, log zlog.Logger) error {
 		}
 	}
 
-	// check glob patterns in authz config are compilable
-	if config.HTTP.AccessControl != nil {
-		for pattern := range config.HTTP.AccessControl.Repositories {
-			ok := glob.ValidatePattern(pattern)
-			if !ok {
-				msg := "failed to compile authorization pattern"
-				log.Error().Err(glob.ErrBadPattern).Str("pattern", pattern).Msg(msg)
+	// check glob patterns in authz config are compilable
+	if config.HTTP.AccessControl != nil {
+		for pattern := range config.HTTP.AccessControl.Repositories {
+			ok := glob.ValidatePattern(pattern)
+			if !ok {
+				msg := "failed to compile authorization pattern"
+				log.Error().Err(glob.ErrBadPattern).Str("pattern", pattern).Msg(msg)
+
+				return fmt.Errorf("%w: %s", glob.ErrBadPattern, msg)
+			}
+		}
+	}
+
+	// check validity of scale out cluster config
+	if err := validateClusterConfig(config, log); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func validateOpenIDConfig(cfg *config.Config, log zlog.Logger) error {
+	if cfg.HTTP.Auth != nil && cfg.HTTP.Auth.OpenID != nil {
+		for provider, providerConfig := range cfg.HTTP.Auth.OpenID.Providers {
+			//nolint: gocritic
+			if config.IsOpenIDSupported(provider) {
+				if providerConfig.ClientID == "" || providerConfig.Issuer == "" ||
+					len(providerConfig.Scopes) == 0 {
+					msg := "OpenID provider config requires clientid, issuer and scopes parameters"
+					log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+					return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+				}
+			} else if config.IsOauth2Supported(provider) {
+				if providerConfig.ClientID == "" || len(providerConfig.Scopes) == 0 {
+					msg := "OAuth2 provider config requires clientid and scopes parameters"
+					log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+					return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+				}
+			} else {
+				msg := "unsupported openid/oauth2 provider"
+				log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+				return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+			}
+
+			if _, err := api.NewOpenIDGroupsMapper(providerConfig); err != nil {
+				msg := "invalid openid groups filter"
+				log.Error().Err(err).Str("provider", provider).Msg(msg)
+
+				return fmt.Errorf("%w: %s: %w", zerr.ErrBadConfig, msg, err)
+			}
+
+			if providerConfig.SessionRevalidateInterval < 0 {
+				msg := "openid session revalidate interval can not be negative"
+				log.Error().Err(zerr.ErrBadConfig).Str("provider", provider).Msg(msg)
+
+				return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateTokenServer(config *config.Config, log zlog.Logger) error {
+	if config.HTTP.Auth == nil || config.HTTP.Auth.Bearer == nil || config.HTTP.Auth.Bearer.TokenServer == nil {
+		return nil
+	}
+
+	if config.HTTP.Auth.Bearer.Realm == "" || config.HTTP.Auth.Bearer.Service == "" {
+		msg := "bearer token server requires realm and service parameters"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	if !config.IsBasicAuthnEnabled() && !config.HTTP.AccessControl.AnonymousPolicyExists() {
+		msg := "bearer token server requires one of htpasswd, ldap, openid or api key authentication " +
+			"or an 'anonymousPolicy' policy"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	tokenServer := config.HTTP.Auth.Bearer.TokenServer
+
+	if tokenServer.Expiration < 0 || tokenServer.KeyRotationInterval < 0 {
+		msg := "bearer token server expiration and key rotation interval can not be negative"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	return nil
+}
+
+func validateBearerKeys(config *config.Config, log zlog.Logger) error {
+	if config.HTTP.Auth == nil || config.HTTP.Auth.Bearer == nil {
+		return nil
+	}
+
+	bearer := config.HTTP.Auth.Bearer
+
+	if bearer.KeysRefreshInterval < 0 {
+		msg := "bearer keys refresh interval can not be negative"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	if bearer.JWKS != "" && strings.Contains(bearer.JWKS, "://") &&
+		!strings.HasPrefix(bearer.JWKS, "http://") && !strings.HasPrefix(bearer.JWKS, "https://") {
+		msg := "bearer jwks must be a file path or an http(s) url"
+		log.Error().Err(zerr.ErrBadConfig).Str("jwks", bearer.JWKS).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	return nil
+}
+
+func validateWorkloadIdentity(config *config.Config, log zlog.Logger) error {
+	if config.HTTP.Auth == nil || config.HTTP.Auth.WorkloadIdentity == nil {
+		return nil
+	}
+
+	issuers := map[string]bool{}
+
+	for _, issuer := range config.HTTP.Auth.WorkloadIdentity.Issuers {
+		if issuer.Issuer == "" || issuer.Audience == "" || issuer.JWKS == "" {
+			msg := "workload identity issuers require issuer, audience and jwks parameters"
+			log.Error().Err(zerr.ErrBadConfig).Str("issuer", issuer.Issuer).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+
+		if issuers[issuer.Issuer] {
+			msg := "workload identity issuers must be unique"
+			log.Error().Err(zerr.ErrBadConfig).Str("issuer", issuer.Issuer).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+
+		issuers[issuer.Issuer] = true
+
+		if issuer.KeysRefreshInterval < 0 {
+			msg := "workload identity keys refresh interval can not be negative"
+			log.Error().Err(zerr.ErrBadConfig).Str("issuer", issuer.Issuer).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+
+		// any job of a public CI platform can get a token for any audience, so the claims must be restricted
+		if len(issuer.Rules) == 0 {
+			msg := "workload identity issuers require at least one rule"
+			log.Error().Err(zerr.ErrBadConfig).Str("issuer", issuer.Issuer).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+
+		for _, rule := range issuer.Rules {
+			if len(rule.Claims) == 0 {
+				msg := "workload identity rules require at least one claim pattern"
+				log.Error().Err(zerr.ErrBadConfig).Str("issuer", issuer.Issuer).Msg(msg)
+
+				return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+			}
+
+			for claim, pattern := range rule.Claims {
+				if !glob.ValidatePattern(pattern) {
+					msg := "invalid workload identity claim pattern"
+					log.Error().Err(glob.ErrBadPattern).Str("issuer", issuer.Issuer).Str("claim", claim).
+						Str("pattern", pattern).Msg(msg)
+
+					return fmt.Errorf("%w: %s: %s", zerr.ErrBadConfig, msg, pattern)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateProxyHeader(config *config.Config, log zlog.Logger) error {
+	if !config.IsProxyHeaderAuthEnabled() {
+		return nil
+	}
+
+	proxyHeader := config.HTTP.Auth.ProxyHeader
+
+	// trusting the headers from anywhere would let anyone impersonate any user
+	if len(proxyHeader.TrustedCIDRs) == 0 && len(proxyHeader.TrustedCertNames) == 0 {
+		msg := "proxy header authentication requires trustedCIDRs or trustedCertNames"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	for _, cidr := range proxyHeader.TrustedCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			msg := "invalid proxy header trusted cidr"
+			log.Error().Err(err).Str("cidr", cidr).Msg(msg)
+
+			return fmt.Errorf("%w: %s: %s", zerr.ErrBadConfig, msg, cidr)
+		}
+	}
+
+	if len(proxyHeader.TrustedCertNames) > 0 && (config.HTTP.TLS == nil || config.HTTP.TLS.CACert == "") {
+		msg := "proxy header trustedCertNames require tls with a cacert verifying the client certificates"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	if config.IsBearerAuthEnabled() {
+		msg := "proxy header authentication can not be used with bearer authentication"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	return nil
+}
+
+func validateMTLSMapping(config *config.Config, log zlog.Logger) error {
+	if !config.IsMTLSMappingEnabled() {
+		return nil
+	}
+
+	if config.HTTP.TLS == nil || config.HTTP.TLS.CACert == "" {
+		msg := "mtls authentication requires tls with a cacert verifying the client certificates"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	if _, err := api.NewCertificateMapper(config.HTTP.Auth.MTLS); err != nil {
+		msg := "invalid mtls certificate mapping rule"
+		log.Error().Err(err).Msg(msg)
+
+		return fmt.Errorf("%w: %s: %w", zerr.ErrBadConfig, msg, err)
+	}
+
+	return nil
+}
+
+func validateHTPasswdHashAlgorithm(config *config.Config, log zlog.Logger) error {
+	if config.HTTP.Auth == nil || config.HTTP.Auth.HTPasswd.HashAlgorithm == "" {
+		return nil
+	}
+
+	if !accounts.IsHashAlgorithm(config.HTTP.Auth.HTPasswd.HashAlgorithm) {
+		msg := "htpasswd hashAlgorithm must be bcrypt or argon2id"
+		log.Error().Err(zerr.ErrBadConfig).Str("hashAlgorithm", config.HTTP.Auth.HTPasswd.HashAlgorithm).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	return nil
+}
+
+func validateLockout(config *config.Config, log zlog.Logger) error {
+	if !config.IsLockoutEnabled() {
+		return nil
+	}
+
+	lockout := config.HTTP.Auth.Lockout
+
+	if lockout.UserThreshold < 0 || lockout.IPThreshold < 0 || lockout.Window < 0 ||
+		lockout.Duration < 0 || lockout.MaxDuration < 0 {
+		msg := "lockout thresholds and durations can not be negative"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	if lockout.MaxDuration != 0 && lockout.MaxDuration < lockout.Duration {
+		msg := "lockout maxDuration can not be shorter than its duration"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	return nil
+}
+
+func validateRatelimit(cfg *config.Config, log zlog.Logger) error {
+	if !cfg.IsClientRatelimitEnabled() {
+		return nil
+	}
+
+	ratelimit := cfg.HTTP.Ratelimit
+	clientLimits := []*config.ClientRatelimitConfig{ratelimit.Anonymous, ratelimit.Authenticated}
+
+	for _, repoLimit := range ratelimit.Repositories {
+		if len(repoLimit.Repositories) == 0 || (repoLimit.Anonymous == nil && repoLimit.Authenticated == nil) {
+			msg := "repositories ratelimit must apply to at least one repository and have a limit"
+			log.Error().Err(zerr.ErrBadConfig).Interface("ratelimit", repoLimit).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+
+		for _, pattern := range repoLimit.Repositories {
+			if ok := glob.ValidatePattern(pattern); !ok {
+				log.Error().Err(glob.ErrBadPattern).Str("pattern", pattern).
+					Msg("ratelimit repo glob pattern could not be compiled")
+
+				return fmt.Errorf("%w: ratelimit repo glob pattern could not be compiled: %s",
+					zerr.ErrBadConfig, pattern)
+			}
+		}
+
+		clientLimits = append(clientLimits, repoLimit.Anonymous, repoLimit.Authenticated)
+	}
+
+	for _, clientLimit := range clientLimits {
+		if clientLimit != nil && (clientLimit.Rate <= 0 || clientLimit.Burst < 0) {
+			msg := "ratelimit rate must be positive and its burst can not be negative"
+			log.Error().Err(zerr.ErrBadConfig).Interface("ratelimit", clientLimit).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+	}
+
+	return nil
+}
+
+func validateAuditLog(cfg *config.Config, log zlog.Logger) error {
+	auditLog := cfg.Log.AuditLog
+	if auditLog == nil {
+		return nil
+	}
+
+	if auditLog.MaxSize < 0 || auditLog.MaxAge < 0 || auditLog.MaxBackups < 0 {
+		msg := "audit log maxSize, maxAge and maxBackups can not be negative"
+		log.Error().Err(zerr.ErrBadConfig).Interface("auditLog", auditLog).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	if (auditLog.MaxSize > 0 || auditLog.MaxAge > 0 || auditLog.MaxBackups > 0) && cfg.Log.Audit == "" {
+		msg := "audit log rotation requires an audit file"
+		log.Error().Err(zerr.ErrBadConfig).Interface("auditLog", auditLog).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	if auditLog.HashKeyFile != "" {
+		if _, err := audit.ReadHashKey(auditLog.HashKeyFile); err != nil {
+			log.Error().Err(err).Str("hashKeyFile", auditLog.HashKeyFile).Msg("failed to read audit hash key")
+
+			return fmt.Errorf("%w: failed to read audit hash key %s: %w", zerr.ErrBadConfig,
+				auditLog.HashKeyFile, err)
+		}
+	}
+
+	if syslog := auditLog.Syslog; syslog != nil {
+		if syslog.Address == "" || !slices.Contains([]string{"", "udp", "tcp", "unixgram"}, syslog.Network) {
+			msg := "audit syslog requires an address and a udp, tcp or unixgram network"
+			log.Error().Err(zerr.ErrBadConfig).Interface("syslog", syslog).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+	}
+
+	if collector := auditLog.HTTP; collector != nil {
+		collectorURL, err := url.Parse(collector.URL)
+		if err != nil || (collectorURL.Scheme != "http" && collectorURL.Scheme != "https") ||
+			collectorURL.Host == "" {
+			msg := "audit http collector requires an http or https url"
+			log.Error().Err(zerr.ErrBadConfig).Str("url", collector.URL).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+	}
+
+	return nil
+}
 
-				return fmt.Errorf("%w: %s", glob.ErrBadPattern, msg)
-			}
-		}
+func validateTracing(cfg *config.Config, log zlog.Logger) error {
+	tracing := cfg.Tracing
+	if tracing == nil {
+		return nil
 	}
 
-	// check validity of scale out cluster config
-	if err := validateClusterConfig(config, log); err != nil {
-		return err
+	if tracing.Endpoint == "" || !slices.Contains([]string{"", "grpc", "http"}, tracing.Protocol) {
+		msg := "tracing requires an endpoint and a grpc or http protocol"
+		log.Error().Err(zerr.ErrBadConfig).Interface("tracing", cfg.Sanitize().Tracing).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
+
+	if tracing.SampleRatio < 0 || tracing.SampleRatio > 1 {
+		msg := "tracing sampleRatio must be between 0 and 1"
+		log.Error().Err(zerr.ErrBadConfig).Float64("sampleRatio", tracing.SampleRatio).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
 	}
 
 	return nil
 }
 
-func validateOpenIDConfig(cfg *config.Config, log zlog.Logger) error {
-	if cfg.HTTP.Auth != nil && cfg.HTTP.Auth.OpenID != nil {
-		for provider, providerConfig := range cfg.HTTP.Auth.OpenID.Providers {
-			//nolint: gocritic
-			if config.IsOpenIDSupported(provider) {
-				if providerConfig.ClientID == "" || providerConfig.Issuer == "" ||
-					len(providerConfig.Scopes) == 0 {
-					msg := "OpenID provider config requires clientid, issuer and scopes parameters"
-					log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+func validateAuthzWebhook(config *config.Config, log zlog.Logger) error {
+	webhook := config.HTTP.AccessControl.Webhook
+	if webhook == nil {
+		return nil
+	}
 
-					return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
-				}
-			} else if config.IsOauth2Supported(provider) {
-				if providerConfig.ClientID == "" || len(providerConfig.Scopes) == 0 {
-					msg := "OAuth2 provider config requires clientid and scopes parameters"
-					log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+	webhookURL, err := url.Parse(webhook.URL)
+	if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+		msg := "authorization webhook requires an http or https url"
+		log.Error().Err(zerr.ErrBadConfig).Str("url", webhook.URL).Msg(msg)
 
-					return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
-				}
-			} else {
-				msg := "unsupported openid/oauth2 provider"
-				log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+	}
 
-				return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
-			}
-		}
+	if webhook.Timeout < 0 || webhook.CacheTTL < 0 {
+		msg := "authorization webhook timeout and cache ttl can not be negative"
+		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
+
+		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
 	}
 
 	return nil
@@ -505,9 +1052,11 @@ func validateOpenIDConfig(cfg *config.Config, log zlog.Logger) error {
 
 func validateAuthzPolicies(config *config.Config, log zlog.Logger) error {
 	if (config.HTTP.Auth == nil || (config.HTTP.Auth.HTPasswd.Path == "" && config.HTTP.Auth.LDAP == nil &&
-		config.HTTP.Auth.OpenID == nil)) && !authzContainsOnlyAnonymousPolicy(config) {
-		msg := "access control config requires one of httpasswd, ldap or openid authentication " +
-			"or using only 'anonymousPolicy' policies"
+		config.HTTP.Auth.OpenID == nil && !config.IsWorkloadIdentityEnabled() &&
+		!config.IsProxyHeaderAuthEnabled() && !config.IsMTLSMappingEnabled())) &&
+		!authzContainsOnlyAnonymousPolicy(config) {
+		msg := "access control config requires one of httpasswd, ldap, openid, workload identity, proxy header " +
+			"or mtls authentication or using only 'anonymousPolicy' policies"
 		log.Error().Err(zerr.ErrBadConfig).Msg(msg)
 
 		return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
@@ -516,6 +1065,67 @@ func validateAuthzPolicies(config *config.Config, log zlog.Logger) error {
 	return nil
 }
 
+func applyTokenServerDefaults(conf *config.Config, viperInstance *viper.Viper) {
+	if conf.HTTP.Auth != nil && conf.HTTP.Auth.Bearer != nil && conf.HTTP.Auth.Bearer.TokenServer == nil &&
+		viperInstance.Get("http::auth::bearer::tokenserver") != nil {
+		// we found a config like `"bearer": {"tokenServer": {}}`
+		conf.HTTP.Auth.Bearer.TokenServer = &config.TokenServerConfig{}
+	}
+}
+
+func applyProxyHeaderDefaults(conf *config.Config, viperInstance *viper.Viper) {
+	if conf.HTTP.Auth != nil && conf.HTTP.Auth.ProxyHeader == nil &&
+		viperInstance.Get("http::auth::proxyheader") != nil {
+		// we found a config like `"proxyHeader": {}`, rejected as it trusts no source
+		conf.HTTP.Auth.ProxyHeader = &config.ProxyHeaderConfig{}
+	}
+}
+
+func applyMTLSDefaults(conf *config.Config, viperInstance *viper.Viper) {
+	if viperInstance.Get("http::auth::mtls") == nil {
+		return
+	}
+
+	// we found a config like `"mtls": {}`, the certificates being identified by their common name
+	if conf.HTTP.Auth == nil {
+		conf.HTTP.Auth = &config.AuthConfig{}
+	}
+
+	if conf.HTTP.Auth.MTLS == nil {
+		conf.HTTP.Auth.MTLS = &config.MTLSConfig{}
+	}
+}
+
+func applyLockoutDefaults(conf *config.Config, viperInstance *viper.Viper) {
+	if viperInstance.Get("http::auth::lockout") == nil {
+		return
+	}
+
+	// we found a config like `"lockout": {}`, the default thresholds and durations applying
+	if conf.HTTP.Auth == nil {
+		conf.HTTP.Auth = &config.AuthConfig{}
+	}
+
+	if conf.HTTP.Auth.Lockout == nil {
+		conf.HTTP.Auth.Lockout = &config.LockoutConfig{}
+	}
+}
+
+func applyAuthzWebhookDefaults(conf *config.Config, viperInstance *viper.Viper) {
+	if viperInstance.Get("http::accesscontrol::webhook") == nil {
+		return
+	}
+
+	// we found a config like `"accessControl": {"webhook": {}}`, rejected as it has no url
+	if conf.HTTP.AccessControl == nil {
+		conf.HTTP.AccessControl = &config.AccessControlConfig{}
+	}
+
+	if conf.HTTP.AccessControl.Webhook == nil {
+		conf.HTTP.AccessControl.Webhook = &config.AuthzWebhookConfig{}
+	}
+}
+
 //nolint:gocyclo,cyclop,nestif
 func applyDefaultValues(config *config.Config, viperInstance *viper.Viper, log zlog.Logger) {
 	defaultVal := true
@@ -559,6 +1169,13 @@ func applyDefaultValues(config *config.Config, viperInstance *viper.Viper, log z
 			// Note: In case UI is not empty the config.Extensions will not be nil and we will not reach here
 			config.Extensions.UI = &extconf.UIConfig{}
 		}
+
+		_, ok = extMap["events"]
+		if ok {
+			// we found a config like `"extensions": {"events:": {}}`
+			// Note: In case events is not empty the config.Extensions will not be nil and we will not reach here
+			config.Extensions.Events = &extconf.EventsConfig{}
+		}
 	}
 
 	if config.Extensions != nil {
@@ -642,8 +1259,20 @@ func applyDefaultValues(config *config.Config, viperInstance *viper.Viper, log z
 				config.Extensions.Trust.Enable = &defaultVal
 			}
 		}
+
+		if config.Extensions.Events != nil {
+			if config.Extensions.Events.Enable == nil {
+				config.Extensions.Events.Enable = &defaultVal
+			}
+		}
 	}
 
+	applyTokenServerDefaults(config, viperInstance)
+	applyAuthzWebhookDefaults(config, viperInstance)
+	applyProxyHeaderDefaults(config, viperInstance)
+	applyMTLSDefaults(config, viperInstance)
+	applyLockoutDefaults(config, viperInstance)
+
 	if !config.Storage.GC {
 		if viperInstance.Get("storage::gcdelay") == nil {
 			config.Storage.GCDelay = 0
@@ -854,6 +1483,12 @@ func LoadConfiguration(config *config.Config, configPath string) error {
 		return err
 	}
 
+	if err := loadGroupsFile(config); err != nil {
+		log.Error().Err(err).Msg("failed to read access control groupsFile")
+
+		return err
+	}
+
 	// defaults
 	applyDefaultValues(config, viperInstance, log)
 
@@ -868,6 +1503,26 @@ func LoadConfiguration(config *config.Config, configPath string) error {
 	return nil
 }
 
+// loadGroupsFile sets the access control groups to the groups of the groups file, which the mgmt api edits.
+func loadGroupsFile(conf *config.Config) error {
+	if conf.HTTP.AccessControl == nil || conf.HTTP.AccessControl.GroupsFile == "" {
+		return nil
+	}
+
+	if len(conf.HTTP.AccessControl.Groups) > 0 {
+		return fmt.Errorf("%w: access control groups and groupsFile can not be both set", zerr.ErrBadConfig)
+	}
+
+	groups, err := accounts.LoadGroupsFile(conf.HTTP.AccessControl.GroupsFile)
+	if err != nil {
+		return err
+	}
+
+	conf.HTTP.AccessControl.Groups = groups
+
+	return nil
+}
+
 func loadSessionKeys(conf *config.Config) error {
 	if conf.HTTP.Auth != nil && conf.HTTP.Auth.SessionKeysFile != "" {
 		var sessionKeys config.SessionKeys
@@ -953,6 +1608,107 @@ func readSecretFile(path string, v any, checkUnsetFields bool) error { //nolint:
 	return nil
 }
 
+func validatePolicyConditions(config *config.Config, log zlog.Logger) error {
+	for pattern, policyGroup := range config.HTTP.AccessControl.Repositories {
+		policies := slices.Concat(policyGroup.Policies, policyGroup.DenyPolicies)
+
+		for _, policy := range policies {
+			if !policy.HasReferenceConditions() {
+				continue
+			}
+
+			if policy.TagRegex != "" && policy.DigestOnly {
+				msg := "access control policies can not have both tagRegex and digestOnly conditions"
+				log.Error().Err(zerr.ErrBadConfig).Str("repository", pattern).Msg(msg)
+
+				return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+			}
+
+			for _, action := range policy.Actions {
+				if action != constants.CreatePermission && action != constants.UpdatePermission &&
+					action != constants.DeletePermission {
+					msg := "access control policies with reference conditions can only have create, update and " +
+						"delete actions"
+					log.Error().Err(zerr.ErrBadConfig).Str("repository", pattern).Str("action", action).Msg(msg)
+
+					return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// reportPolicyConflicts logs the allowing policies which are always overridden by a deny policy, the deny
+// policy naming some of their users or groups for some of their actions on all their repositories.
+func reportPolicyConflicts(config *config.Config, log zlog.Logger) {
+	repositories := config.HTTP.AccessControl.Repositories
+
+	for denyPattern, denyPolicyGroup := range repositories {
+		for _, denyPolicy := range denyPolicyGroup.DenyPolicies {
+			if denyPolicy.HasReferenceConditions() {
+				continue
+			}
+
+			everyone := common.Contains(denyPolicy.Users, api.Everyone)
+
+			for pattern, policyGroup := range repositories {
+				// the deny policy covers all the repositories of the pattern
+				if matched, err := glob.Match(denyPattern, pattern); err != nil || !matched {
+					continue
+				}
+
+				for _, policy := range policyGroup.Policies {
+					actions := common.Intersection(policy.Actions, denyPolicy.Actions)
+					users := policy.Users
+					groups := policy.Groups
+
+					if !everyone {
+						users = common.Intersection(policy.Users, denyPolicy.Users)
+						groups = common.Intersection(policy.Groups, denyPolicy.Groups)
+					}
+
+					if len(actions) > 0 && len(users)+len(groups) > 0 {
+						log.Warn().Str("repository", pattern).Str("denyRepository", denyPattern).
+							Strs("actions", actions).Strs("users", users).Strs("groups", groups).
+							Msg("access control policy conflicts with a deny policy which overrides it")
+					}
+				}
+
+				if !everyone {
+					continue
+				}
+
+				if actions := common.Intersection(policyGroup.DefaultPolicy, denyPolicy.Actions); len(actions) > 0 {
+					log.Warn().Str("repository", pattern).Str("denyRepository", denyPattern).Strs("actions", actions).
+						Msg("access control default policy conflicts with a deny policy which overrides it")
+				}
+
+				if actions := common.Intersection(policyGroup.AnonymousPolicy, denyPolicy.Actions); len(actions) > 0 {
+					log.Warn().Str("repository", pattern).Str("denyRepository", denyPattern).Strs("actions", actions).
+						Msg("access control anonymous policy conflicts with a deny policy which overrides it")
+				}
+			}
+		}
+	}
+}
+
+func validateNetworkPolicies(config *config.Config, log zlog.Logger) error {
+	for pattern, policyGroup := range config.HTTP.AccessControl.Repositories {
+		for _, policy := range policyGroup.NetworkPolicies {
+			if len(policy.Actions) == 0 || len(policy.AllowCIDRs)+len(policy.DenyCIDRs) == 0 {
+				msg := "access control network policies require actions and allowCIDRs or denyCIDRs"
+				log.Error().Err(zerr.ErrBadConfig).Str("repository", pattern).Msg(msg)
+
+				return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+			}
+		}
+	}
+
+	return nil
+}
+
 func authzContainsOnlyAnonymousPolicy(cfg *config.Config) bool {
 	adminPolicy := cfg.HTTP.AccessControl.AdminPolicy
 	anonymousPolicyPresent := false
@@ -1004,8 +1760,8 @@ func validateLDAP(config *config.Config, log zlog.Logger) error {
 			return fmt.Errorf("%w: %s", zerr.ErrLDAPConfig, msg)
 		}
 
-		if ldap.Address == "" {
-			msg := "invalid LDAP configuration, missing mandatory key: address"
+		if ldap.Address == "" && len(ldap.Addresses) == 0 {
+			msg := "invalid LDAP configuration, missing mandatory key: address or addresses"
 			log.Error().Str("address", ldap.Address).Msg(msg)
 
 			return fmt.Errorf("%w: %s", zerr.ErrLDAPConfig, msg)
@@ -1017,6 +1773,21 @@ func validateLDAP(config *config.Config, log zlog.Logger) error {
 
 			return fmt.Errorf("%w: %s", zerr.ErrLDAPConfig, msg)
 		}
+
+		if ldap.ServerRetryInterval < 0 || ldap.GroupsCacheTTL < 0 {
+			msg := "invalid LDAP configuration, serverRetryInterval and groupsCacheTTL can not be negative"
+			log.Error().Dur("serverRetryInterval", ldap.ServerRetryInterval).
+				Dur("groupsCacheTTL", ldap.GroupsCacheTTL).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrLDAPConfig, msg)
+		}
+
+		if ldap.GroupSearch != nil && ldap.GroupSearch.BaseDN == "" {
+			msg := "invalid LDAP configuration, missing mandatory key: groupSearch.basedn"
+			log.Error().Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrLDAPConfig, msg)
+		}
 	}
 
 	return nil
@@ -1032,6 +1803,15 @@ func validateHTTP(config *config.Config, log zlog.Logger) error {
 		}
 	}
 
+	for _, cidr := range config.HTTP.TrustedProxyCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			msg := "invalid trusted proxy cidr"
+			log.Error().Err(err).Str("cidr", cidr).Msg(msg)
+
+			return fmt.Errorf("%w: %s: %s", zerr.ErrBadConfig, msg, cidr)
+		}
+	}
+
 	return nil
 }
 
@@ -1118,6 +1898,153 @@ func validateGCRules(retention config.ImageRetention, log zlog.Logger) error {
 	return nil
 }
 
+func validateQuotas(config *config.Config, log zlog.Logger) error {
+	if err := validateStorageQuotas(config.Storage.Quotas, log); err != nil {
+		return err
+	}
+
+	for _, subPath := range config.Storage.SubPaths {
+		if err := validateStorageQuotas(subPath.Quotas, log); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateStorageQuotas(quotas []config.StorageQuota, log zlog.Logger) error {
+	for _, quota := range quotas {
+		if len(quota.Repositories) == 0 {
+			msg := "storage quota must apply to at least one repository"
+			log.Error().Err(zerr.ErrBadConfig).Interface("quota", quota).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+
+		for _, pattern := range quota.Repositories {
+			if ok := glob.ValidatePattern(pattern); !ok {
+				log.Error().Err(glob.ErrBadPattern).Str("pattern", pattern).
+					Msg("storage quota repo glob pattern could not be compiled")
+
+				return fmt.Errorf("%w: storage quota repo glob pattern could not be compiled: %s",
+					zerr.ErrBadConfig, pattern)
+			}
+		}
+
+		if quota.MaxSize < 0 || quota.MaxImages < 0 {
+			msg := "storage quota limits can not be negative"
+			log.Error().Err(zerr.ErrBadConfig).Interface("quota", quota).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+	}
+
+	return nil
+}
+
+func validateImmutableTags(config *config.Config, log zlog.Logger) error {
+	if err := validateImmutableTagsPolicies(config.Storage.ImmutableTags, log); err != nil {
+		return err
+	}
+
+	for _, subPath := range config.Storage.SubPaths {
+		if err := validateImmutableTagsPolicies(subPath.ImmutableTags, log); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateImmutableTagsPolicies(policies []config.ImmutableTagsPolicy, log zlog.Logger) error {
+	for _, policy := range policies {
+		if len(policy.Repositories) == 0 {
+			msg := "immutable tags policy must apply to at least one repository"
+			log.Error().Err(zerr.ErrBadConfig).Interface("policy", policy).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+
+		for _, pattern := range policy.Repositories {
+			if ok := glob.ValidatePattern(pattern); !ok {
+				log.Error().Err(glob.ErrBadPattern).Str("pattern", pattern).
+					Msg("immutable tags repo glob pattern could not be compiled")
+
+				return fmt.Errorf("%w: immutable tags repo glob pattern could not be compiled: %s",
+					zerr.ErrBadConfig, pattern)
+			}
+		}
+
+		for _, regex := range policy.Patterns {
+			if _, err := regexp.Compile(regex); err != nil {
+				log.Error().Err(err).Str("regex", regex).
+					Msg("immutable tags regex could not be compiled")
+
+				return fmt.Errorf("%w: immutable tags regex could not be compiled: %s",
+					zerr.ErrBadConfig, regex)
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateEvents(config *config.Config, log zlog.Logger) error {
+	if config.Extensions == nil || config.Extensions.Events == nil {
+		return nil
+	}
+
+	urls := map[string]bool{}
+
+	for _, webhook := range config.Extensions.Events.Webhooks {
+		webhookURL, err := url.Parse(webhook.URL)
+		if err != nil || (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+			msg := "events webhook url must be an absolute http or https url"
+			log.Error().Err(zerr.ErrBadConfig).Str("url", webhook.URL).Msg(msg)
+
+			return fmt.Errorf("%w: %s: %s", zerr.ErrBadConfig, msg, webhook.URL)
+		}
+
+		if urls[webhook.URL] {
+			msg := "events webhook url must be unique"
+			log.Error().Err(zerr.ErrBadConfig).Str("url", webhook.URL).Msg(msg)
+
+			return fmt.Errorf("%w: %s: %s", zerr.ErrBadConfig, msg, webhook.URL)
+		}
+
+		urls[webhook.URL] = true
+
+		for _, pattern := range webhook.Repositories {
+			if ok := glob.ValidatePattern(pattern); !ok {
+				log.Error().Err(glob.ErrBadPattern).Str("pattern", pattern).
+					Msg("events webhook repo glob pattern could not be compiled")
+
+				return fmt.Errorf("%w: events webhook repo glob pattern could not be compiled: %s",
+					zerr.ErrBadConfig, pattern)
+			}
+		}
+
+		for _, eventType := range webhook.EventTypes {
+			if !common.Contains(events.EventTypes(), eventType) {
+				msg := "unknown events webhook event type"
+				log.Error().Err(zerr.ErrBadConfig).Str("eventType", eventType).Msg(msg)
+
+				return fmt.Errorf("%w: %s: %s", zerr.ErrBadConfig, msg, eventType)
+			}
+		}
+
+		if webhook.Timeout < 0 || (webhook.MaxRetries != nil && *webhook.MaxRetries < 0) ||
+			(webhook.RetryDelay != nil && *webhook.RetryDelay <= 0) {
+			msg := "events webhook timeout and maxRetries can not be negative, retryDelay must be positive"
+			log.Error().Err(zerr.ErrBadConfig).Str("url", webhook.URL).Msg(msg)
+
+			return fmt.Errorf("%w: %s", zerr.ErrBadConfig, msg)
+		}
+	}
+
+	return nil
+}
+
 func validateSync(config *config.Config, log zlog.Logger) error {
 	// check glob patterns in sync config are compilable
 	if config.Extensions != nil && config.Extensions.Sync != nil {
@@ -1,6 +1,7 @@
 package server_test
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -11,9 +12,12 @@ import (
 
 	. "github.com/smartystreets/goconvey/convey"
 
+	zerr "zotregistry.dev/zot/errors"
 	"zotregistry.dev/zot/pkg/api"
 	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/audit"
 	cli "zotregistry.dev/zot/pkg/cli/server"
+	"zotregistry.dev/zot/pkg/log"
 	storageConstants "zotregistry.dev/zot/pkg/storage/constants"
 	. "zotregistry.dev/zot/pkg/test/common"
 )
@@ -668,6 +672,804 @@ storage:
 		So(cli.NewServerRootCmd().Execute(), ShouldNotBeNil)
 	})
 
+	Convey("Test verify storage quotas", t, func(c C) {
+		verifyQuotas := func(quotas string) error {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot",
+					"subPaths": {
+						"/a": {
+							"rootDirectory": "/zot-a",
+							"quotas": ` + quotas + `
+						}
+					}
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080"
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			_, err = tmpfile.Write(content)
+			So(err, ShouldBeNil)
+			err = tmpfile.Close()
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+
+			return cli.NewServerRootCmd().Execute()
+		}
+
+		So(verifyQuotas(`[{"repositories": ["a/**"], "maxSize": 1024, "maxImages": 10}]`), ShouldBeNil)
+		So(verifyQuotas(`[{"repositories": ["["], "maxSize": 1024}]`), ShouldNotBeNil)
+		So(verifyQuotas(`[{"repositories": [], "maxSize": 1024}]`), ShouldNotBeNil)
+		So(verifyQuotas(`[{"repositories": ["a/**"], "maxSize": -1}]`), ShouldNotBeNil)
+		So(verifyQuotas(`[{"repositories": ["a/**"], "maxImages": -1}]`), ShouldNotBeNil)
+	})
+
+	Convey("Test verify immutable tags", t, func(c C) {
+		verifyImmutableTags := func(immutableTags string) error {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot",
+					"subPaths": {
+						"/a": {
+							"rootDirectory": "/zot-a",
+							"immutableTags": ` + immutableTags + `
+						}
+					}
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080"
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			_, err = tmpfile.Write(content)
+			So(err, ShouldBeNil)
+			err = tmpfile.Close()
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+
+			return cli.NewServerRootCmd().Execute()
+		}
+
+		So(verifyImmutableTags(`[{"repositories": ["a/**"], "patterns": ["^v[0-9]+$"]}]`), ShouldBeNil)
+		So(verifyImmutableTags(`[{"repositories": ["a/**"]}]`), ShouldBeNil)
+		So(verifyImmutableTags(`[{"repositories": ["["]}]`), ShouldNotBeNil)
+		So(verifyImmutableTags(`[{"repositories": [], "patterns": ["^v[0-9]+$"]}]`), ShouldNotBeNil)
+		So(verifyImmutableTags(`[{"repositories": ["a/**"], "patterns": ["["]}]`), ShouldNotBeNil)
+	})
+
+	Convey("Test verify events", t, func(c C) {
+		verifyWebhooks := func(webhooks string) error {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080"
+				},
+				"log": {
+					"level": "debug"
+				},
+				"extensions": {
+					"events": {
+						"webhooks": ` + webhooks + `
+					}
+				}
+			}`)
+
+			_, err = tmpfile.Write(content)
+			So(err, ShouldBeNil)
+			err = tmpfile.Close()
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+
+			return cli.NewServerRootCmd().Execute()
+		}
+
+		So(verifyWebhooks(`[{"url": "https://hooks.example.com/zot", "secret": "s3cr3t", "repositories": ["a/**"],
+			"eventTypes": ["dev.zotregistry.image.updated"], "maxRetries": 3, "retryDelay": "10s"}]`), ShouldBeNil)
+		So(verifyWebhooks(`[{"url": "http://localhost:8000"}]`), ShouldBeNil)
+		So(verifyWebhooks(`[{"url": "localhost:8000"}]`), ShouldNotBeNil)
+		So(verifyWebhooks(`[{"url": "ftp://hooks.example.com"}]`), ShouldNotBeNil)
+		So(verifyWebhooks(`[{"url": "http://localhost:8000"}, {"url": "http://localhost:8000"}]`), ShouldNotBeNil)
+		So(verifyWebhooks(`[{"url": "http://localhost:8000", "repositories": ["["]}]`), ShouldNotBeNil)
+		So(verifyWebhooks(`[{"url": "http://localhost:8000", "eventTypes": ["image.pushed"]}]`), ShouldNotBeNil)
+		So(verifyWebhooks(`[{"url": "http://localhost:8000", "maxRetries": -1}]`), ShouldNotBeNil)
+		So(verifyWebhooks(`[{"url": "http://localhost:8000", "retryDelay": "0s"}]`), ShouldNotBeNil)
+		So(verifyWebhooks(`[{"url": "http://localhost:8000", "timeout": "-1s"}]`), ShouldNotBeNil)
+	})
+
+	Convey("Test verify bearer token server", t, func(c C) {
+		verifyBearer := func(auth string) error {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"auth": ` + auth + `
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			_, err = tmpfile.Write(content)
+			So(err, ShouldBeNil)
+			err = tmpfile.Close()
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+
+			return cli.NewServerRootCmd().Execute()
+		}
+
+		htpasswdPath := MakeHtpasswdFileFromString(GetCredString("user", "pass"))
+		defer os.Remove(htpasswdPath)
+
+		So(verifyBearer(`{"htpasswd": {"path": "`+htpasswdPath+`"}, "bearer": {"realm": "https://zot/zot/auth/token",
+			"service": "zot", "tokenServer": {"expiration": "10m", "keyRotationInterval": "12h"}}}`), ShouldBeNil)
+		So(verifyBearer(`{"htpasswd": {"path": "`+htpasswdPath+`"}, "bearer": {"service": "zot",
+			"tokenServer": {}}}`), ShouldNotBeNil)
+		So(verifyBearer(`{"bearer": {"realm": "https://zot/zot/auth/token", "service": "zot",
+			"tokenServer": {}}}`), ShouldNotBeNil)
+		So(verifyBearer(`{"htpasswd": {"path": "`+htpasswdPath+`"}, "bearer": {"realm": "https://zot/zot/auth/token",
+			"service": "zot", "tokenServer": {"expiration": "-1s"}}}`), ShouldNotBeNil)
+		So(verifyBearer(`{"bearer": {"realm": "https://auth/token", "service": "zot",
+			"certs": ["/etc/zot/auth1.crt", "/etc/zot/auth2.crt"], "jwks": "https://auth/jwks.json",
+			"keysRefreshInterval": "10m"}}`), ShouldBeNil)
+		So(verifyBearer(`{"bearer": {"realm": "https://auth/token", "service": "zot",
+			"jwks": "ftp://auth/jwks.json"}}`), ShouldNotBeNil)
+		So(verifyBearer(`{"bearer": {"realm": "https://auth/token", "service": "zot",
+			"jwks": "/etc/zot/jwks.json", "keysRefreshInterval": "-1m"}}`), ShouldNotBeNil)
+	})
+
+	Convey("Test verify workload identity", t, func(c C) {
+		verifyWorkloadIdentity := func(workloadIdentity string) error {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"auth": {"workloadIdentity": ` + workloadIdentity + `},
+					"accessControl": {"repositories": {"**": {"policies": [
+						{"users": ["github:org/app"], "actions": ["read", "create"]}
+					]}}}
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			_, err = tmpfile.Write(content)
+			So(err, ShouldBeNil)
+			err = tmpfile.Close()
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+
+			return cli.NewServerRootCmd().Execute()
+		}
+
+		So(verifyWorkloadIdentity(`{"issuers": [{"issuer": "https://token.actions.githubusercontent.com",
+			"audience": "zot", "jwks": "https://token.actions.githubusercontent.com/.well-known/jwks",
+			"rules": [{"claims": {"repository": "org/*", "ref": "refs/heads/main"},
+			"identity": "github:{repository}", "groups": ["ci"]}]}]}`), ShouldBeNil)
+		// missing audience
+		So(verifyWorkloadIdentity(`{"issuers": [{"issuer": "https://issuer", "jwks": "https://issuer/jwks",
+			"rules": [{"claims": {"repository": "org/*"}}]}]}`), ShouldNotBeNil)
+		// missing rules
+		So(verifyWorkloadIdentity(`{"issuers": [{"issuer": "https://issuer", "audience": "zot",
+			"jwks": "https://issuer/jwks"}]}`), ShouldNotBeNil)
+		// rule without claims
+		So(verifyWorkloadIdentity(`{"issuers": [{"issuer": "https://issuer", "audience": "zot",
+			"jwks": "https://issuer/jwks", "rules": [{"identity": "ci"}]}]}`), ShouldNotBeNil)
+		// invalid pattern
+		So(verifyWorkloadIdentity(`{"issuers": [{"issuer": "https://issuer", "audience": "zot",
+			"jwks": "https://issuer/jwks", "rules": [{"claims": {"repository": "org/["}}]}]}`), ShouldNotBeNil)
+		// duplicate issuer
+		So(verifyWorkloadIdentity(`{"issuers": [{"issuer": "https://issuer", "audience": "zot",
+			"jwks": "https://issuer/jwks", "rules": [{"claims": {"sub": "*"}}]}, {"issuer": "https://issuer",
+			"audience": "zot", "jwks": "https://issuer/jwks", "rules": [{"claims": {"sub": "*"}}]}]}`), ShouldNotBeNil)
+		// negative refresh interval
+		So(verifyWorkloadIdentity(`{"issuers": [{"issuer": "https://issuer", "audience": "zot",
+			"jwks": "https://issuer/jwks", "keysRefreshInterval": "-1h",
+			"rules": [{"claims": {"sub": "*"}}]}]}`), ShouldNotBeNil)
+	})
+
+	Convey("Test verify proxy header authentication", t, func(c C) {
+		verifyProxyHeader := func(auth string) error {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"auth": ` + auth + `,
+					"accessControl": {"repositories": {"**": {"policies": [
+						{"groups": ["dev"], "actions": ["read", "create"]}
+					]}}}
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			_, err = tmpfile.Write(content)
+			So(err, ShouldBeNil)
+			err = tmpfile.Close()
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+
+			return cli.NewServerRootCmd().Execute()
+		}
+
+		So(verifyProxyHeader(`{"proxyHeader": {"trustedCIDRs": ["10.0.0.0/8", "fd00::/8"]}}`), ShouldBeNil)
+		So(verifyProxyHeader(`{"proxyHeader": {"userHeader": "X-Auth-Request-User",
+			"groupsHeader": "X-Auth-Request-Groups", "groupsSeparator": ";", "trustedCIDRs": ["127.0.0.1/32"]}}`),
+			ShouldBeNil)
+		// no trusted source
+		So(verifyProxyHeader(`{"proxyHeader": {}}`), ShouldNotBeNil)
+		So(verifyProxyHeader(`{"proxyHeader": {"userHeader": "X-User"}}`), ShouldNotBeNil)
+		// invalid cidr
+		So(verifyProxyHeader(`{"proxyHeader": {"trustedCIDRs": ["10.0.0.1"]}}`), ShouldNotBeNil)
+		// the client certificates can not be verified without tls
+		So(verifyProxyHeader(`{"proxyHeader": {"trustedCertNames": ["sso-proxy"]}}`), ShouldNotBeNil)
+		// bearer authentication replaces the other methods
+		So(verifyProxyHeader(`{"proxyHeader": {"trustedCIDRs": ["10.0.0.0/8"]},
+			"bearer": {"realm": "https://auth.myreg.io/auth/token", "service": "myauth", "cert": "/etc/zot/auth.crt"}}`),
+			ShouldNotBeNil)
+	})
+
+	Convey("Test verify mtls certificate mapping", t, func(c C) {
+		htpasswdPath := MakeHtpasswdFileFromString(GetCredString("user", "pass"))
+		defer os.Remove(htpasswdPath)
+
+		verifyMTLS := func(tlsConfig, mtls string) error {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"tls": ` + tlsConfig + `,
+					"auth": {"htpasswd": {"path": "` + htpasswdPath + `"}, "mtls": ` + mtls + `}
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			_, err = tmpfile.Write(content)
+			So(err, ShouldBeNil)
+			err = tmpfile.Close()
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+
+			return cli.NewServerRootCmd().Execute()
+		}
+
+		tlsConfig := `{"cert": "/etc/zot/server.cert", "key": "/etc/zot/server.key", "cacert": "/etc/zot/ca.crt"}`
+
+		So(verifyMTLS(tlsConfig, `{}`), ShouldBeNil)
+		So(verifyMTLS(tlsConfig, `{"identityRules": [{"attribute": "uri", "pattern": "spiffe://myorg.io/.*"}],
+			"groupRules": [{"attribute": "organizationalUnit"},
+			{"attribute": "uri", "pattern": "spiffe://myorg.io/team/([^/]+)/.*", "value": "$1"}]}`), ShouldBeNil)
+		// the client certificates can not be verified without a ca
+		So(verifyMTLS(`{"cert": "/etc/zot/server.cert", "key": "/etc/zot/server.key"}`, `{}`), ShouldNotBeNil)
+		// unknown attribute
+		So(verifyMTLS(tlsConfig, `{"identityRules": [{"attribute": "serialNumber"}]}`), ShouldNotBeNil)
+		So(verifyMTLS(tlsConfig, `{"groupRules": [{"attribute": "ou"}]}`), ShouldNotBeNil)
+		// invalid pattern
+		So(verifyMTLS(tlsConfig, `{"groupRules": [{"attribute": "uri", "pattern": "spiffe://(.*"}]}`),
+			ShouldNotBeNil)
+	})
+
+	Convey("Test verify authentication lockout", t, func(c C) {
+		htpasswdPath := MakeHtpasswdFileFromString(GetCredString("user", "pass"))
+		defer os.Remove(htpasswdPath)
+
+		loadLockout := func(lockout string) (*config.Config, error) {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"auth": {"htpasswd": {"path": "` + htpasswdPath + `"}, "lockout": ` + lockout + `}
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			err = os.WriteFile(tmpfile.Name(), content, 0o0600)
+			So(err, ShouldBeNil)
+
+			conf := config.New()
+
+			return conf, cli.LoadConfiguration(conf, tmpfile.Name())
+		}
+
+		conf, err := loadLockout(`{}`)
+		So(err, ShouldBeNil)
+		So(conf.IsLockoutEnabled(), ShouldBeTrue)
+
+		conf, err = loadLockout(`{"userThreshold": 3, "ipThreshold": 50, "window": "10m", "duration": "30s",
+			"maxDuration": "2h"}`)
+		So(err, ShouldBeNil)
+		So(conf.HTTP.Auth.Lockout.UserThreshold, ShouldEqual, 3)
+		So(conf.HTTP.Auth.Lockout.MaxDuration, ShouldEqual, 2*time.Hour)
+
+		_, err = loadLockout(`{"userThreshold": -1}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadLockout(`{"window": "-1m"}`)
+		So(err, ShouldNotBeNil)
+		// the longest lockout is shorter than the first one
+		_, err = loadLockout(`{"duration": "1h", "maxDuration": "10m"}`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Test verify per client rate limits", t, func(c C) {
+		loadRatelimit := func(ratelimit string) (*config.Config, error) {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"ratelimit": ` + ratelimit + `
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			err = os.WriteFile(tmpfile.Name(), content, 0o0600)
+			So(err, ShouldBeNil)
+
+			conf := config.New()
+
+			return conf, cli.LoadConfiguration(conf, tmpfile.Name())
+		}
+
+		conf, err := loadRatelimit(`{"anonymous": {"rate": 0.5}, "authenticated": {"rate": 10, "burst": 20},
+			"repositories": [{"repositories": ["private/**"], "authenticated": {"rate": 1}}]}`)
+		So(err, ShouldBeNil)
+		So(conf.IsClientRatelimitEnabled(), ShouldBeTrue)
+		So(conf.HTTP.Ratelimit.Anonymous.Rate, ShouldEqual, 0.5)
+		So(conf.HTTP.Ratelimit.Authenticated.Burst, ShouldEqual, 20)
+
+		conf, err = loadRatelimit(`{"rate": 10}`)
+		So(err, ShouldBeNil)
+		So(conf.IsClientRatelimitEnabled(), ShouldBeFalse)
+
+		_, err = loadRatelimit(`{"anonymous": {"rate": 0}}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadRatelimit(`{"authenticated": {"rate": 1, "burst": -1}}`)
+		So(err, ShouldNotBeNil)
+		// the repositories rate limits need globs and limits
+		_, err = loadRatelimit(`{"repositories": [{"anonymous": {"rate": 1}}]}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadRatelimit(`{"repositories": [{"repositories": ["**"]}]}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadRatelimit(`{"repositories": [{"repositories": ["[a-"], "anonymous": {"rate": 1}}]}`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Test verify audit log", t, func(c C) {
+		loadAuditLog := func(audit, auditLog string) (*config.Config, error) {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080"
+				},
+				"log": {
+					"level": "debug",
+					"audit": "` + audit + `",
+					"auditLog": ` + auditLog + `
+				}
+			}`)
+
+			err = os.WriteFile(tmpfile.Name(), content, 0o0600)
+			So(err, ShouldBeNil)
+
+			conf := config.New()
+
+			return conf, cli.LoadConfiguration(conf, tmpfile.Name())
+		}
+
+		keyFile := path.Join(t.TempDir(), "audit.key")
+		err := os.WriteFile(keyFile, []byte("secret"), 0o0600)
+		So(err, ShouldBeNil)
+
+		conf, err := loadAuditLog("/tmp/zot-audit.log", `{"maxSize": 100, "maxAge": "720h", "maxBackups": 10,
+			"hashKeyFile": "`+keyFile+`", "syslog": {"network": "tcp", "address": "127.0.0.1:514"},
+			"http": {"url": "https://collector.example.com/audit", "timeout": "5s"}}`)
+		So(err, ShouldBeNil)
+		So(conf.IsAuditEnabled(), ShouldBeTrue)
+		So(conf.Log.AuditLog.MaxAge, ShouldEqual, 720*time.Hour)
+		So(conf.Log.AuditLog.Syslog.Network, ShouldEqual, "tcp")
+		So(conf.Log.AuditLog.HTTP.Timeout, ShouldEqual, 5*time.Second)
+
+		// the records can be delivered to the sinks only
+		conf, err = loadAuditLog("", `{"syslog": {"address": "127.0.0.1:514"}}`)
+		So(err, ShouldBeNil)
+		So(conf.IsAuditEnabled(), ShouldBeTrue)
+
+		_, err = loadAuditLog("/tmp/zot-audit.log", `{"maxSize": -1}`)
+		So(err, ShouldNotBeNil)
+		// there is no file to rotate
+		_, err = loadAuditLog("", `{"maxBackups": 1}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadAuditLog("/tmp/zot-audit.log", `{"hashKeyFile": "/inexistent/audit.key"}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadAuditLog("", `{"syslog": {"network": "tcp"}}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadAuditLog("", `{"syslog": {"network": "unix", "address": "/dev/log"}}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadAuditLog("", `{"http": {"url": "collector.example.com"}}`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Test verify tracing", t, func(c C) {
+		loadTracing := func(tracing string) (*config.Config, error) {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080"
+				},
+				"log": {
+					"level": "debug"
+				},
+				"tracing": ` + tracing + `
+			}`)
+
+			err = os.WriteFile(tmpfile.Name(), content, 0o0600)
+			So(err, ShouldBeNil)
+
+			conf := config.New()
+
+			return conf, cli.LoadConfiguration(conf, tmpfile.Name())
+		}
+
+		conf, err := loadTracing(`{"endpoint": "127.0.0.1:4318", "protocol": "http", "insecure": true,
+			"headers": {"Authorization": "Bearer secret"}, "serviceName": "zot-eu", "sampleRatio": 0.25}`)
+		So(err, ShouldBeNil)
+		So(conf.IsTracingEnabled(), ShouldBeTrue)
+		So(conf.Tracing.Protocol, ShouldEqual, "http")
+		So(conf.Tracing.SampleRatio, ShouldEqual, 0.25)
+		So(conf.Sanitize().Tracing.Headers["authorization"], ShouldEqual, "******")
+		So(conf.Tracing.Headers["authorization"], ShouldEqual, "Bearer secret")
+
+		_, err = loadTracing(`{"protocol": "grpc"}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadTracing(`{"endpoint": "127.0.0.1:4317", "protocol": "zipkin"}`)
+		So(err, ShouldNotBeNil)
+		_, err = loadTracing(`{"endpoint": "127.0.0.1:4317", "sampleRatio": 2}`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Test verify network policies", t, func(c C) {
+		loadNetworkPolicies := func(trustedProxyCIDRs, networkPolicies string) (*config.Config, error) {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"trustedProxyCIDRs": ` + trustedProxyCIDRs + `,
+					"accessControl": {
+						"repositories": {
+							"**": {
+								"anonymousPolicy": ["read"],
+								"networkPolicies": ` + networkPolicies + `
+							}
+						}
+					}
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			err = os.WriteFile(tmpfile.Name(), content, 0o0600)
+			So(err, ShouldBeNil)
+
+			conf := config.New()
+
+			return conf, cli.LoadConfiguration(conf, tmpfile.Name())
+		}
+
+		conf, err := loadNetworkPolicies(`["10.0.0.0/24"]`,
+			`[{"actions": ["read"], "allowCIDRs": ["10.0.0.0/8"], "denyCIDRs": ["10.1.0.0/16"]}]`)
+		So(err, ShouldBeNil)
+		So(conf.HTTP.TrustedProxyCIDRs, ShouldResemble, []string{"10.0.0.0/24"})
+		So(conf.HTTP.AccessControl.Repositories["**"].NetworkPolicies[0].DenyCIDRs, ShouldResemble,
+			[]string{"10.1.0.0/16"})
+
+		_, err = loadNetworkPolicies(`["10.0.0.1"]`, `[]`)
+		So(err, ShouldNotBeNil)
+		_, err = loadNetworkPolicies(`[]`, `[{"actions": ["read"], "allowCIDRs": ["10.0.0.0/33"]}]`)
+		So(err, ShouldNotBeNil)
+		// the policies need actions and networks
+		_, err = loadNetworkPolicies(`[]`, `[{"allowCIDRs": ["10.0.0.0/8"]}]`)
+		So(err, ShouldNotBeNil)
+		_, err = loadNetworkPolicies(`[]`, `[{"actions": ["read"]}]`)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Test verify htpasswd users and groups management", t, func(c C) {
+		htpasswdPath := MakeHtpasswdFileFromString(GetCredString("user", "pass"))
+		defer os.Remove(htpasswdPath)
+
+		groupsPath := path.Join(t.TempDir(), "groups.json")
+
+		loadAccounts := func(htpasswd, accessControl string) (*config.Config, error) {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"auth": {"htpasswd": ` + htpasswd + `},
+					"accessControl": ` + accessControl + `
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			err = os.WriteFile(tmpfile.Name(), content, 0o0600)
+			So(err, ShouldBeNil)
+
+			conf := config.New()
+
+			return conf, cli.LoadConfiguration(conf, tmpfile.Name())
+		}
+
+		htpasswd := `{"path": "` + htpasswdPath + `", "hashAlgorithm": "argon2id"}`
+		accessControl := `{"groupsFile": "` + groupsPath + `", "adminPolicy": {"users": ["user"], "actions": ["read"]}}`
+
+		// the groups file is created by the first change
+		conf, err := loadAccounts(htpasswd, accessControl)
+		So(err, ShouldBeNil)
+		So(conf.HTTP.Auth.HTPasswd.HashAlgorithm, ShouldEqual, "argon2id")
+		So(conf.HTTP.AccessControl.Groups, ShouldBeEmpty)
+
+		err = os.WriteFile(groupsPath, []byte(`{"devs": {"users": ["user"]}}`), 0o600)
+		So(err, ShouldBeNil)
+
+		conf, err = loadAccounts(htpasswd, accessControl)
+		So(err, ShouldBeNil)
+		So(conf.HTTP.AccessControl.Groups, ShouldResemble, config.Groups{"devs": config.Group{Users: []string{"user"}}})
+
+		_, err = loadAccounts(`{"path": "`+htpasswdPath+`", "hashAlgorithm": "md5"}`, accessControl)
+		So(err, ShouldNotBeNil)
+
+		// the groups are either inline or in the groups file
+		_, err = loadAccounts(htpasswd, `{"groupsFile": "`+groupsPath+`", "groups": {"ops": {"users": ["user"]}}}`)
+		So(err, ShouldNotBeNil)
+
+		err = os.WriteFile(groupsPath, []byte(`{`), 0o600)
+		So(err, ShouldBeNil)
+
+		_, err = loadAccounts(htpasswd, accessControl)
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Test verify deny policies and reference conditions", t, func(c C) {
+		htpasswdPath := MakeHtpasswdFileFromString(GetCredString("user", "pass"))
+		defer os.Remove(htpasswdPath)
+
+		verifyPolicies := func(repositories string) error {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"auth": {"htpasswd": {"path": "` + htpasswdPath + `"}},
+					"accessControl": {"repositories": ` + repositories + `}
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			_, err = tmpfile.Write(content)
+			So(err, ShouldBeNil)
+			err = tmpfile.Close()
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+
+			return cli.NewServerRootCmd().Execute()
+		}
+
+		So(verifyPolicies(`{"team/**": {"policies": [
+			{"groups": ["release"], "actions": ["create", "update"], "tagRegex": "v[0-9]+.*"},
+			{"users": ["ci"], "actions": ["delete"], "digestOnly": true}
+		], "denyPolicies": [{"users": ["*"], "actions": ["create"], "tagRegex": "latest"}]}}`), ShouldBeNil)
+		// conflicting policies are only reported
+		So(verifyPolicies(`{"team/**": {"policies": [{"users": ["user"], "actions": ["read"]}],
+			"defaultPolicy": ["read"]}, "**": {"denyPolicies": [{"users": ["*"], "actions": ["read"]}]}}`),
+			ShouldBeNil)
+		// invalid tag regex
+		So(verifyPolicies(`{"**": {"policies": [{"users": ["user"], "actions": ["create"],
+			"tagRegex": "v["}]}}`), ShouldNotBeNil)
+		// conditions only apply to create, update and delete
+		So(verifyPolicies(`{"**": {"policies": [{"users": ["user"], "actions": ["read"],
+			"digestOnly": true}]}}`), ShouldNotBeNil)
+		So(verifyPolicies(`{"**": {"denyPolicies": [{"users": ["user"], "actions": ["read"],
+			"tagRegex": "v.*"}]}}`), ShouldNotBeNil)
+		// both conditions
+		So(verifyPolicies(`{"**": {"policies": [{"users": ["user"], "actions": ["delete"],
+			"tagRegex": "v.*", "digestOnly": true}]}}`), ShouldNotBeNil)
+	})
+
+	Convey("Test verify authorization webhook", t, func(c C) {
+		htpasswdPath := MakeHtpasswdFileFromString(GetCredString("user", "pass"))
+		defer os.Remove(htpasswdPath)
+
+		verifyWebhook := func(webhook string) error {
+			tmpfile, err := os.CreateTemp("", "zot-test*.json")
+			So(err, ShouldBeNil)
+
+			defer os.Remove(tmpfile.Name()) // clean up
+
+			content := []byte(`{
+				"distSpecVersion": "1.1.1",
+				"storage": {
+					"rootDirectory": "/tmp/zot"
+				},
+				"http": {
+					"address": "127.0.0.1",
+					"port": "8080",
+					"auth": {"htpasswd": {"path": "` + htpasswdPath + `"}},
+					"accessControl": {"webhook": ` + webhook + `}
+				},
+				"log": {
+					"level": "debug"
+				}
+			}`)
+
+			_, err = tmpfile.Write(content)
+			So(err, ShouldBeNil)
+			err = tmpfile.Close()
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+
+			return cli.NewServerRootCmd().Execute()
+		}
+
+		So(verifyWebhook(`{"url": "https://policy.example.com/v1/authorize", "timeout": "2s", "cacheTTL": "30s"}`),
+			ShouldBeNil)
+		So(verifyWebhook(`{"url": "http://127.0.0.1:8181/authorize"}`), ShouldBeNil)
+		// missing or invalid url
+		So(verifyWebhook(`{}`), ShouldNotBeNil)
+		So(verifyWebhook(`{"url": "policy.example.com/authorize"}`), ShouldNotBeNil)
+		So(verifyWebhook(`{"url": "ftp://policy.example.com/authorize"}`), ShouldNotBeNil)
+		// negative durations
+		So(verifyWebhook(`{"url": "https://policy.example.com", "timeout": "-1s"}`), ShouldNotBeNil)
+		So(verifyWebhook(`{"url": "https://policy.example.com", "cacheTTL": "-1s"}`), ShouldNotBeNil)
+	})
+
 	Convey("Test apply defaults cache db", t, func(c C) {
 		tmpfile, err := os.CreateTemp("", "zot-test*.json")
 		So(err, ShouldBeNil)
@@ -1271,6 +2073,54 @@ storage:
 		So(err, ShouldBeNil)
 	})
 
+	Convey("Test verify openid config with groups mapping", t, func(c C) {
+		tmpfile, err := os.CreateTemp("", "zot-test*.json")
+		So(err, ShouldBeNil)
+
+		defer os.Remove(tmpfile.Name()) // clean up
+
+		content := []byte(`{"distSpecVersion":"1.1.1","storage":{"rootDirectory":"/tmp/zot"},
+							"http":{"address":"127.0.0.1","port":"8080","realm":"zot",
+							"auth":{"openid":{"providers":{"oidc":{"issuer":"http://127.0.0.1:5556/dex",
+							"clientid":"client_id","scopes":["openid"],"groupsclaim":"realm_access.roles",
+							"groupsprefix":"/","groupsfilter":["zot-.*"],"sessionrevalidateinterval":"5m"}}}}},
+							"log":{"level":"debug"}}`)
+		_, err = tmpfile.Write(content)
+		So(err, ShouldBeNil)
+		err = tmpfile.Close()
+		So(err, ShouldBeNil)
+
+		os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldBeNil)
+
+		// invalid groups filter
+		content = []byte(`{"distSpecVersion":"1.1.1","storage":{"rootDirectory":"/tmp/zot"},
+							"http":{"address":"127.0.0.1","port":"8080","realm":"zot",
+							"auth":{"openid":{"providers":{"oidc":{"issuer":"http://127.0.0.1:5556/dex",
+							"clientid":"client_id","scopes":["openid"],"groupsfilter":["zot-("]}}}}},
+							"log":{"level":"debug"}}`)
+		err = os.WriteFile(tmpfile.Name(), content, 0o0600)
+		So(err, ShouldBeNil)
+
+		os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldNotBeNil)
+
+		// negative revalidate interval
+		content = []byte(`{"distSpecVersion":"1.1.1","storage":{"rootDirectory":"/tmp/zot"},
+							"http":{"address":"127.0.0.1","port":"8080","realm":"zot",
+							"auth":{"openid":{"providers":{"github":{"clientid":"client_id","scopes":["read:org"],
+							"sessionrevalidateinterval":"-5m"}}}}},
+							"log":{"level":"debug"}}`)
+		err = os.WriteFile(tmpfile.Name(), content, 0o0600)
+		So(err, ShouldBeNil)
+
+		os.Args = []string{"cli_test", "verify", tmpfile.Name()}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldNotBeNil)
+	})
+
 	Convey("Test verify config with missing basedn key", t, func(c C) {
 		tmpfile, err := os.CreateTemp("", "zot-test*.json")
 		So(err, ShouldBeNil)
@@ -1459,6 +2309,53 @@ storage:
 		So(err, ShouldBeNil)
 	})
 
+	Convey("Test verify ldap config with failover servers and group search", t, func(c C) {
+		tmpFile, err := os.CreateTemp("", "zot-test*.json")
+		So(err, ShouldBeNil)
+		defer os.Remove(tmpFile.Name())
+
+		tmpCredsFile, err := os.CreateTemp("", "zot-cred*.json")
+		So(err, ShouldBeNil)
+		defer os.Remove(tmpCredsFile.Name())
+
+		content := []byte(`{
+			"bindDN":"cn=ldap-searcher,ou=Users,dc=example,dc=org",
+			"bindPassword":"ldap-searcher-password"
+		}`)
+
+		_, err = tmpCredsFile.Write(content)
+		So(err, ShouldBeNil)
+		err = tmpCredsFile.Close()
+		So(err, ShouldBeNil)
+
+		ldapConfig := `"credentialsFile": "%v", "addresses": ["ldap1.example.org", "ldap2.example.org:1389"],
+			"port": 389, "baseDN": "ou=Users,dc=example,dc=org", "userAttribute": "uid",
+			"serverRetryInterval": "30s", "groupsCacheTTL": "%s", "groupSearch": %s`
+
+		for _, testCase := range []struct {
+			groupsCacheTTL string
+			groupSearch    string
+			valid          bool
+		}{
+			{"5m", `{"baseDN": "ou=Groups,dc=example,dc=org", "nested": true}`, true},
+			{"5m", `{"filter": "(memberUid={username})"}`, false},
+			{"-5m", `{"baseDN": "ou=Groups,dc=example,dc=org"}`, false},
+		} {
+			content = []byte(fmt.Sprintf(`{ "distSpecVersion": "1.1.1",
+				"storage": { "rootDirectory": "/tmp/zot" }, "http": { "address": "127.0.0.1", "port": "8080",
+				"auth": { "ldap": { `+ldapConfig+` } } }, "log": { "level": "debug" } }`,
+				tmpCredsFile.Name(), testCase.groupsCacheTTL, testCase.groupSearch),
+			)
+
+			err = os.WriteFile(tmpFile.Name(), content, 0o0600)
+			So(err, ShouldBeNil)
+
+			os.Args = []string{"cli_test", "verify", tmpFile.Name()}
+			err = cli.NewServerRootCmd().Execute()
+			So(err == nil, ShouldEqual, testCase.valid)
+		}
+	})
+
 	Convey("Test verify bad ldap config: key is missing", t, func(c C) {
 		tmpFile, err := os.CreateTemp("", "zot-test*.json")
 		So(err, ShouldBeNil)
@@ -1613,6 +2510,103 @@ storage:
 	})
 }
 
+func TestAuditVerify(t *testing.T) {
+	oldArgs := os.Args
+
+	defer func() { os.Args = oldArgs }()
+
+	Convey("Test audit verify", t, func(c C) {
+		dir := t.TempDir()
+		auditPath := path.Join(dir, "audit.log")
+		keyFile := path.Join(dir, "audit.key")
+
+		err := os.WriteFile(keyFile, []byte("secret"), 0o0600)
+		So(err, ShouldBeNil)
+
+		logConfig := &config.LogConfig{
+			Level:    "debug",
+			Audit:    auditPath,
+			AuditLog: &config.AuditLogConfig{HashKeyFile: keyFile},
+		}
+
+		auditLogger, writer, err := audit.NewLogger(logConfig, log.NewLogger("debug", ""))
+		So(err, ShouldBeNil)
+
+		auditLogger.Info().Str("subject", "admin").Str("action", "createUser").Msg("HTTP API Audit")
+		auditLogger.Info().Str("subject", "admin").Str("action", "deleteUser").Msg("HTTP API Audit")
+		So(writer.Close(), ShouldBeNil)
+
+		var out bytes.Buffer
+
+		rootCmd := cli.NewServerRootCmd()
+		rootCmd.SetOut(&out)
+
+		os.Args = []string{"cli_test", "audit", "verify", "--key-file", keyFile, auditPath}
+		err = rootCmd.Execute()
+		So(err, ShouldBeNil)
+		So(out.String(), ShouldContainSubstring, "verified 2 records, from 1 to 2")
+
+		// the records are chained with the key
+		os.Args = []string{"cli_test", "audit", "verify", auditPath}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+
+		// the last record is checked against the expected one
+		os.Args = []string{"cli_test", "audit", "verify", "--key-file", keyFile, "--last-seq", "2", auditPath}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldBeNil)
+
+		os.Args = []string{"cli_test", "audit", "verify", "--key-file", keyFile, "--last-seq", "3", auditPath}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+
+		content, err := os.ReadFile(auditPath)
+		So(err, ShouldBeNil)
+
+		lines := bytes.SplitAfter(content, []byte("\n"))
+		_, firstHash, _ := bytes.Cut(bytes.TrimSpace(lines[0]), []byte(`"hash":"`))
+		firstHash = bytes.TrimSuffix(firstHash, []byte(`"}`))
+
+		// a chain without its first records is only verified from the record it follows
+		truncatedPath := path.Join(dir, "truncated.log")
+		err = os.WriteFile(truncatedPath, lines[1], 0o0600)
+		So(err, ShouldBeNil)
+
+		os.Args = []string{"cli_test", "audit", "verify", "--key-file", keyFile, truncatedPath}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+
+		os.Args = []string{
+			"cli_test", "audit", "verify", "--key-file", keyFile, "--from-seq", "1", "--from-hash", string(firstHash),
+			truncatedPath,
+		}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldBeNil)
+
+		os.Args = []string{
+			"cli_test", "audit", "verify", "--key-file", keyFile, "--from-seq", "1", "--from-hash", "0000",
+			truncatedPath,
+		}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+
+		err = os.WriteFile(auditPath, bytes.Replace(content, []byte("deleteUser"), []byte("updateUser"), 1), 0o0600)
+		So(err, ShouldBeNil)
+
+		os.Args = []string{"cli_test", "audit", "verify", "--key-file", keyFile, auditPath}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldWrap, zerr.ErrAuditChainBroken)
+
+		os.Args = []string{"cli_test", "audit", "verify", "--key-file", keyFile, path.Join(dir, "inexistent")}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldNotBeNil)
+
+		os.Args = []string{"cli_test", "audit", "verify"}
+		err = cli.NewServerRootCmd().Execute()
+		So(err, ShouldNotBeNil)
+	})
+}
+
 func TestApiKeyConfig(t *testing.T) {
 	Convey("Test API Keys are enabled if OpenID is enabled", t, func(c C) {
 		config := config.New()
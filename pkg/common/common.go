@@ -48,6 +48,19 @@ func Contains[T comparable](elems []T, v T) bool {
 	return false
 }
 
+// elements of first which are also in second.
+func Intersection[T comparable](first, second []T) []T {
+	intersection := []T{}
+
+	for _, v := range first {
+		if Contains(second, v) && !Contains(intersection, v) {
+			intersection = append(intersection, v)
+		}
+	}
+
+	return intersection
+}
+
 // first match of item in [].
 func Index(slice []string, item string) int {
 	for k, v := range slice {
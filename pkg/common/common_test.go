@@ -21,6 +21,12 @@ func TestCommon(t *testing.T) {
 		So(common.Contains([]string{}, "apple"), ShouldBeFalse)
 	})
 
+	Convey("test Intersection()", t, func() {
+		So(common.Intersection([]string{"apple", "peach", "apple"}, []string{"apple", "pear"}),
+			ShouldResemble, []string{"apple"})
+		So(common.Intersection([]string{"apple"}, []string{}), ShouldBeEmpty)
+	})
+
 	Convey("test MarshalThroughStruct()", t, func() {
 		cfg := config.New()
 
@@ -99,6 +99,58 @@ func AuthzOnlyAdminsMiddleware(conf *config.Config) mux.MiddlewareFunc {
 	}
 }
 
+// AuthzScopeMiddleware rejects requests made with api keys which were not granted the given scope.
+func AuthzScopeMiddleware(conf *config.Config, scope string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			userAc, err := reqCtx.UserAcFromContext(request.Context())
+			if err != nil {
+				AuthzFail(response, request, "", conf.HTTP.Realm, conf.HTTP.Auth.FailDelay)
+
+				return
+			}
+
+			if !userAc.HasScope(scope) {
+				AuthzFail(response, request, userAc.GetUsername(), conf.HTTP.Realm, conf.HTTP.Auth.FailDelay)
+
+				return
+			}
+
+			next.ServeHTTP(response, request)
+		})
+	}
+}
+
+// AuthzUnscopedMiddleware rejects the requests changing the credentials of their user made with scoped api keys,
+// otherwise these could be used to obtain wider permissions, listing them being allowed.
+func AuthzUnscopedMiddleware(conf *config.Config) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+			if request.Method == http.MethodGet || request.Method == http.MethodHead ||
+				request.Method == http.MethodOptions {
+				next.ServeHTTP(response, request)
+
+				return
+			}
+
+			userAc, err := reqCtx.UserAcFromContext(request.Context())
+			if err != nil {
+				AuthzFail(response, request, "", conf.HTTP.Realm, conf.HTTP.Auth.FailDelay)
+
+				return
+			}
+
+			if userAc.IsScoped() {
+				AuthzFail(response, request, userAc.GetUsername(), conf.HTTP.Realm, conf.HTTP.Auth.FailDelay)
+
+				return
+			}
+
+			next.ServeHTTP(response, request)
+		})
+	}
+}
+
 func AuthzFail(w http.ResponseWriter, r *http.Request, identity, realm string, delay int) {
 	time.Sleep(time.Duration(delay) * time.Second)
 
@@ -125,6 +177,17 @@ func AuthzFail(w http.ResponseWriter, r *http.Request, identity, realm string, d
 func WriteJSON(response http.ResponseWriter, status int, data interface{}) {
 	json := jsoniter.ConfigCompatibleWithStandardLibrary
 
+	// the errors carry the id of the request given in the response, to find the logs of the request
+	if requestID := response.Header().Get(constants.RequestIDHeader); requestID != "" {
+		switch body := data.(type) {
+		case apiErr.ErrorList:
+			body.RequestID = requestID
+			data = body
+		case *apiErr.Error:
+			body.RequestID = requestID
+		}
+	}
+
 	body, err := json.Marshal(data)
 	if err != nil {
 		panic(err)
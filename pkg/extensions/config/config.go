@@ -21,6 +21,26 @@ type ExtensionConfig struct {
 	Mgmt    *MgmtConfig
 	APIKey  *APIKeyConfig
 	Trust   *ImageTrustConfig
+	Events  *EventsConfig
+}
+
+type EventsConfig struct {
+	BaseConfig `mapstructure:",squash"`
+	Source     string // CloudEvents source attribute, default is "zot"
+	Webhooks   []WebhookConfig
+}
+
+// WebhookConfig is an endpoint receiving the registry events as JSON CloudEvents,
+// empty filters match all repositories, event types and media types.
+type WebhookConfig struct {
+	URL          string
+	Secret       string // payloads are signed with HMAC-SHA256 if set
+	Timeout      time.Duration
+	MaxRetries   *int
+	RetryDelay   *time.Duration // doubled after each failed attempt
+	Repositories []string
+	EventTypes   []string
+	MediaTypes   []string
 }
 
 type ImageTrustConfig struct {
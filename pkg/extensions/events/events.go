@@ -0,0 +1,156 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	glob "github.com/bmatcuk/doublestar/v4"
+	"github.com/google/uuid"
+	godigest "github.com/opencontainers/go-digest"
+	ispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	zcommon "zotregistry.dev/zot/pkg/common"
+	extconf "zotregistry.dev/zot/pkg/extensions/config"
+	"zotregistry.dev/zot/pkg/storage"
+)
+
+const (
+	ImageUpdatedEvent     = "dev.zotregistry.image.updated"
+	ImageDeletedEvent     = "dev.zotregistry.image.deleted"
+	SignatureAddedEvent   = "dev.zotregistry.signature.added"
+	SignatureDeletedEvent = "dev.zotregistry.signature.deleted"
+	ReferrerAddedEvent    = "dev.zotregistry.referrer.added"
+	ReferrerDeletedEvent  = "dev.zotregistry.referrer.deleted"
+
+	DefaultSource       = "zot"
+	cloudEventsVersion  = "1.0"
+	eventDataMediaType  = "application/json"
+	cloudEventMediaType = "application/cloudevents+json"
+)
+
+// EventTypes lists all the types of events sent by the registry.
+func EventTypes() []string {
+	return []string{
+		ImageUpdatedEvent, ImageDeletedEvent,
+		SignatureAddedEvent, SignatureDeletedEvent,
+		ReferrerAddedEvent, ReferrerDeletedEvent,
+	}
+}
+
+// Event is a CloudEvents 1.0 event in structured JSON format.
+type Event struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	Data            EventData `json:"data"`
+}
+
+type EventData struct {
+	Repository    string `json:"repository"`
+	Reference     string `json:"reference"`
+	Digest        string `json:"digest"`
+	MediaType     string `json:"mediaType"`
+	ArtifactType  string `json:"artifactType,omitempty"`
+	Subject       string `json:"subject,omitempty"` // digest of the signed or referred manifest
+	SignatureType string `json:"signatureType,omitempty"`
+}
+
+// newManifestEvent classifies a manifest write or delete as an image, signature or referrer event.
+func newManifestEvent(source, repo, reference, mediaType string, digest godigest.Digest, body []byte,
+	deleted bool,
+) (Event, error) {
+	data := EventData{
+		Repository: repo,
+		Reference:  reference,
+		Digest:     digest.String(),
+		MediaType:  mediaType,
+	}
+
+	isSignature, signatureType, signedManifestDigest, err := storage.CheckIsImageSignature(repo, body, reference)
+	if err != nil {
+		return Event{}, err
+	}
+
+	var manifest struct {
+		ArtifactType string            `json:"artifactType"`
+		Subject      *ispec.Descriptor `json:"subject"`
+	}
+
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return Event{}, err
+	}
+
+	data.ArtifactType = manifest.ArtifactType
+
+	var eventType string
+
+	switch {
+	case isSignature:
+		data.Subject = signedManifestDigest.String()
+		data.SignatureType = signatureType
+		eventType = pick(deleted, SignatureDeletedEvent, SignatureAddedEvent)
+	case manifest.Subject != nil:
+		data.Subject = manifest.Subject.Digest.String()
+		eventType = pick(deleted, ReferrerDeletedEvent, ReferrerAddedEvent)
+	default:
+		eventType = pick(deleted, ImageDeletedEvent, ImageUpdatedEvent)
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		SpecVersion:     cloudEventsVersion,
+		ID:              id.String(),
+		Source:          source,
+		Type:            eventType,
+		Subject:         fmt.Sprintf("%s@%s", repo, digest),
+		Time:            time.Now().UTC(),
+		DataContentType: eventDataMediaType,
+		Data:            data,
+	}, nil
+}
+
+// matches returns true if the event passes all the filters of the webhook.
+func matches(webhook extconf.WebhookConfig, event Event) bool {
+	if len(webhook.Repositories) > 0 {
+		var matched bool
+
+		for _, pattern := range webhook.Repositories {
+			if ok, err := glob.Match(pattern, event.Data.Repository); err == nil && ok {
+				matched = true
+
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(webhook.EventTypes) > 0 && !zcommon.Contains(webhook.EventTypes, event.Type) {
+		return false
+	}
+
+	if len(webhook.MediaTypes) > 0 && !zcommon.Contains(webhook.MediaTypes, event.Data.MediaType) {
+		return false
+	}
+
+	return true
+}
+
+func pick(deleted bool, deletedEvent, addedEvent string) string {
+	if deleted {
+		return deletedEvent
+	}
+
+	return addedEvent
+}
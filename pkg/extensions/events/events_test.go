@@ -0,0 +1,286 @@
+package events_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	ispec "github.com/opencontainers/image-spec/specs-go/v1"
+	. "github.com/smartystreets/goconvey/convey"
+
+	"zotregistry.dev/zot/pkg/api/config"
+	extconf "zotregistry.dev/zot/pkg/extensions/config"
+	"zotregistry.dev/zot/pkg/extensions/events"
+	"zotregistry.dev/zot/pkg/extensions/monitoring"
+	"zotregistry.dev/zot/pkg/log"
+	"zotregistry.dev/zot/pkg/scheduler"
+	. "zotregistry.dev/zot/pkg/test/image-utils"
+)
+
+type webhookServer struct {
+	*httptest.Server
+	events          chan events.Event
+	validSignatures chan bool
+}
+
+func newWebhookServer(status int) *webhookServer {
+	server := &webhookServer{
+		events:          make(chan events.Event, 10),
+		validSignatures: make(chan bool, 10),
+	}
+
+	server.Server = httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		body, _ := io.ReadAll(req.Body)
+
+		var event events.Event
+
+		if err := json.Unmarshal(body, &event); err == nil {
+			server.events <- event
+			server.validSignatures <- req.Header.Get(events.SignatureHeader) == "sha256="+events.Sign("secret", body)
+		}
+
+		resp.WriteHeader(status)
+	}))
+
+	return server
+}
+
+func receive(received chan events.Event) (events.Event, bool) {
+	select {
+	case event := <-received:
+		return event, true
+	case <-time.After(5 * time.Second):
+		return events.Event{}, false
+	}
+}
+
+func startScheduler(logger log.Logger) *scheduler.Scheduler {
+	sch := scheduler.NewScheduler(config.New(), monitoring.NewMetricsServer(false, logger), logger)
+	sch.RunScheduler()
+
+	return sch
+}
+
+func TestNotifier(t *testing.T) {
+	logger := log.NewLogger("debug", "")
+
+	Convey("Events are sent to the matching webhooks", t, func() {
+		all := newWebhookServer(http.StatusOK)
+		defer all.Close()
+
+		filtered := newWebhookServer(http.StatusOK)
+		defer filtered.Close()
+
+		notifier, err := events.NewNotifier(&extconf.EventsConfig{
+			Webhooks: []extconf.WebhookConfig{
+				{URL: all.URL, Secret: "secret"},
+				{
+					URL:          filtered.URL,
+					Repositories: []string{"infra/**"},
+					EventTypes:   []string{events.ImageDeletedEvent},
+					MediaTypes:   []string{ispec.MediaTypeImageManifest},
+				},
+			},
+		}, t.TempDir(), logger)
+		So(err, ShouldBeNil)
+
+		defer notifier.Close()
+
+		sch := startScheduler(logger)
+		defer sch.Shutdown()
+
+		notifier.Start(sch)
+
+		image := CreateRandomImage()
+
+		notifier.OnManifestUpdated("infra/a", "1.0", ispec.MediaTypeImageManifest, image.Digest(),
+			image.ManifestDescriptor.Data)
+
+		event, ok := receive(all.events)
+		So(ok, ShouldBeTrue)
+		So(event.SpecVersion, ShouldEqual, "1.0")
+		So(event.ID, ShouldNotBeEmpty)
+		So(event.Source, ShouldEqual, events.DefaultSource)
+		So(event.Type, ShouldEqual, events.ImageUpdatedEvent)
+		So(event.Subject, ShouldEqual, "infra/a@"+image.DigestStr())
+		So(event.Data.Repository, ShouldEqual, "infra/a")
+		So(event.Data.Reference, ShouldEqual, "1.0")
+		So(event.Data.Digest, ShouldEqual, image.DigestStr())
+		So(event.Data.MediaType, ShouldEqual, ispec.MediaTypeImageManifest)
+
+		// the payload is signed with the webhook secret
+		So(<-all.validSignatures, ShouldBeTrue)
+
+		notifier.OnManifestDeleted("other", "1.0", ispec.MediaTypeImageManifest, image.Digest(),
+			image.ManifestDescriptor.Data)
+		notifier.OnManifestDeleted("infra/a", "1.0", ispec.MediaTypeImageManifest, image.Digest(),
+			image.ManifestDescriptor.Data)
+
+		event, ok = receive(filtered.events)
+		So(ok, ShouldBeTrue)
+		So(event.Type, ShouldEqual, events.ImageDeletedEvent)
+		So(event.Data.Repository, ShouldEqual, "infra/a")
+
+		// the filtered webhook received neither the update nor the delete in the other repository
+		So(filtered.events, ShouldBeEmpty)
+
+		for range 2 {
+			event, ok = receive(all.events)
+			So(ok, ShouldBeTrue)
+			So(event.Type, ShouldEqual, events.ImageDeletedEvent)
+		}
+
+		Convey("Signatures and referrers are classified", func() {
+			signature := CreateMockNotationSignature(image.DescriptorRef())
+
+			notifier.OnManifestUpdated("repo", signature.DigestStr(), ispec.MediaTypeImageManifest,
+				signature.Digest(), signature.ManifestDescriptor.Data)
+
+			referrer := CreateRandomImageWith().Subject(image.DescriptorRef()).ArtifactType("application/sbom").Build()
+
+			notifier.OnManifestUpdated("repo", referrer.DigestStr(), ispec.MediaTypeImageManifest,
+				referrer.Digest(), referrer.ManifestDescriptor.Data)
+
+			received := map[string]events.Event{}
+
+			for range 2 {
+				event, ok := receive(all.events)
+				So(ok, ShouldBeTrue)
+
+				received[event.Type] = event
+			}
+
+			So(received, ShouldContainKey, events.SignatureAddedEvent)
+			So(received[events.SignatureAddedEvent].Data.Subject, ShouldEqual, image.DigestStr())
+			So(received[events.SignatureAddedEvent].Data.SignatureType, ShouldEqual, "notation")
+
+			So(received, ShouldContainKey, events.ReferrerAddedEvent)
+			So(received[events.ReferrerAddedEvent].Data.Subject, ShouldEqual, image.DigestStr())
+			So(received[events.ReferrerAddedEvent].Data.ArtifactType, ShouldEqual, "application/sbom")
+		})
+	})
+
+	Convey("Failed deliveries are retried and moved to the dead-letter queue", t, func() {
+		failing := newWebhookServer(http.StatusInternalServerError)
+		defer failing.Close()
+
+		maxRetries := 2
+		retryDelay := 10 * time.Millisecond
+
+		notifier, err := events.NewNotifier(&extconf.EventsConfig{
+			Webhooks: []extconf.WebhookConfig{
+				{URL: failing.URL, MaxRetries: &maxRetries, RetryDelay: &retryDelay},
+			},
+		}, t.TempDir(), logger)
+		So(err, ShouldBeNil)
+
+		defer notifier.Close()
+
+		sch := startScheduler(logger)
+		defer sch.Shutdown()
+
+		notifier.Start(sch)
+
+		image := CreateRandomImage()
+
+		notifier.OnManifestUpdated("repo", "1.0", ispec.MediaTypeImageManifest, image.Digest(),
+			image.ManifestDescriptor.Data)
+
+		for range maxRetries + 1 {
+			_, ok := receive(failing.events)
+			So(ok, ShouldBeTrue)
+		}
+
+		var deadLetters []events.Delivery
+
+		for range 50 {
+			deadLetters, err = notifier.GetDeadLetters()
+			So(err, ShouldBeNil)
+
+			if len(deadLetters) > 0 {
+				break
+			}
+
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		So(deadLetters, ShouldHaveLength, 1)
+		So(deadLetters[0].URL, ShouldEqual, failing.URL)
+		So(deadLetters[0].Attempts, ShouldEqual, maxRetries+1)
+		So(deadLetters[0].LastError, ShouldContainSubstring, "500")
+		So(deadLetters[0].Event.Data.Repository, ShouldEqual, "repo")
+	})
+
+	Convey("Pending deliveries survive restarts", t, func() {
+		webhook := newWebhookServer(http.StatusOK)
+		defer webhook.Close()
+
+		rootDir := t.TempDir()
+		eventsConfig := &extconf.EventsConfig{
+			Source:   "registry.example.com",
+			Webhooks: []extconf.WebhookConfig{{URL: webhook.URL}},
+		}
+
+		notifier, err := events.NewNotifier(eventsConfig, rootDir, logger)
+		So(err, ShouldBeNil)
+
+		image := CreateRandomImage()
+
+		// not started yet, the delivery is only persisted
+		notifier.OnManifestUpdated("repo", "1.0", ispec.MediaTypeImageManifest, image.Digest(),
+			image.ManifestDescriptor.Data)
+
+		So(notifier.Close(), ShouldBeNil)
+
+		notifier, err = events.NewNotifier(eventsConfig, rootDir, logger)
+		So(err, ShouldBeNil)
+
+		defer notifier.Close()
+
+		sch := startScheduler(logger)
+		defer sch.Shutdown()
+
+		notifier.Start(sch)
+
+		event, ok := receive(webhook.events)
+		So(ok, ShouldBeTrue)
+		So(event.Source, ShouldEqual, "registry.example.com")
+		So(event.Data.Repository, ShouldEqual, "repo")
+	})
+
+	Convey("Errors", t, func() {
+		var notifier *events.Notifier
+
+		// a nil notifier does nothing
+		notifier.OnManifestUpdated("repo", "1.0", ispec.MediaTypeImageManifest, "", nil)
+		notifier.Start(nil)
+		So(notifier.Close(), ShouldBeNil)
+
+		// the events db can not be created
+		rootDir := t.TempDir()
+		err := os.Mkdir(path.Join(rootDir, "events.db"), 0o755)
+		So(err, ShouldBeNil)
+
+		_, err = events.NewNotifier(&extconf.EventsConfig{}, rootDir, logger)
+		So(err, ShouldNotBeNil)
+
+		notifier, err = events.NewNotifier(&extconf.EventsConfig{
+			Webhooks: []extconf.WebhookConfig{{URL: "http://127.0.0.1:0"}},
+		}, t.TempDir(), logger)
+		So(err, ShouldBeNil)
+
+		defer notifier.Close()
+
+		// invalid manifests are not published
+		notifier.OnManifestUpdated("repo", "1.0", ispec.MediaTypeImageManifest, "", []byte("invalid"))
+
+		deadLetters, err := notifier.GetDeadLetters()
+		So(err, ShouldBeNil)
+		So(deadLetters, ShouldBeEmpty)
+	})
+}
@@ -0,0 +1,357 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	godigest "github.com/opencontainers/go-digest"
+
+	zerr "zotregistry.dev/zot/errors"
+	extconf "zotregistry.dev/zot/pkg/extensions/config"
+	zlog "zotregistry.dev/zot/pkg/log"
+	"zotregistry.dev/zot/pkg/scheduler"
+)
+
+const (
+	SignatureHeader = "X-Zot-Signature" //nolint:gosec // not a credential
+
+	DefaultTimeout    = 10 * time.Second
+	DefaultMaxRetries = 5
+	DefaultRetryDelay = 5 * time.Second
+	maxRetryDelay     = time.Hour
+	leaseDuration     = time.Minute
+)
+
+// Notifier sends the registry events to the configured webhooks.
+// Deliveries are persisted first, so events are sent at least once even across restarts,
+// and failed deliveries are retried with an exponential backoff by a scheduler generator
+// until they are moved to the dead-letter queue.
+type Notifier struct {
+	source   string
+	webhooks map[string]extconf.WebhookConfig
+	store    *deliveryStore
+	client   *http.Client
+	sch      *scheduler.Scheduler
+	schLock  sync.RWMutex
+	log      zlog.Logger
+}
+
+func NewNotifier(config *extconf.EventsConfig, rootDir string, log zlog.Logger) (*Notifier, error) {
+	store, err := newDeliveryStore(rootDir, log)
+	if err != nil {
+		return nil, err
+	}
+
+	source := config.Source
+	if source == "" {
+		source = DefaultSource
+	}
+
+	webhooks := make(map[string]extconf.WebhookConfig, len(config.Webhooks))
+	for _, webhook := range config.Webhooks {
+		webhooks[webhook.URL] = webhook
+	}
+
+	return &Notifier{
+		source:   source,
+		webhooks: webhooks,
+		store:    store,
+		client:   &http.Client{},
+		log:      log,
+	}, nil
+}
+
+// Start delivers the pending events using the scheduler and periodically retries the failed ones.
+func (n *Notifier) Start(sch *scheduler.Scheduler) {
+	if n == nil {
+		return
+	}
+
+	n.schLock.Lock()
+	n.sch = sch
+	n.schLock.Unlock()
+
+	retryInterval := DefaultRetryDelay
+
+	for _, webhook := range n.webhooks {
+		if webhook.RetryDelay != nil && *webhook.RetryDelay < retryInterval {
+			retryInterval = *webhook.RetryDelay
+		}
+	}
+
+	sch.SubmitGenerator(&retryTaskGenerator{notifier: n}, retryInterval, scheduler.MediumPriority)
+}
+
+func (n *Notifier) Close() error {
+	if n == nil {
+		return nil
+	}
+
+	return n.store.close()
+}
+
+// OnManifestUpdated publishes the event matching a manifest push.
+func (n *Notifier) OnManifestUpdated(repo, reference, mediaType string, digest godigest.Digest, body []byte) {
+	n.publishManifestEvent(repo, reference, mediaType, digest, body, false)
+}
+
+// OnManifestDeleted publishes the event matching a manifest deletion.
+func (n *Notifier) OnManifestDeleted(repo, reference, mediaType string, digest godigest.Digest, body []byte) {
+	n.publishManifestEvent(repo, reference, mediaType, digest, body, true)
+}
+
+// GetDeadLetters returns the deliveries which failed after all their attempts.
+func (n *Notifier) GetDeadLetters() ([]Delivery, error) {
+	return n.store.getDeadLetters()
+}
+
+func (n *Notifier) publishManifestEvent(repo, reference, mediaType string, digest godigest.Digest, body []byte,
+	deleted bool,
+) {
+	if n == nil {
+		return
+	}
+
+	event, err := newManifestEvent(n.source, repo, reference, mediaType, digest, body, deleted)
+	if err != nil {
+		n.log.Error().Err(err).Str("repository", repo).Str("reference", reference).
+			Msg("failed to create event")
+
+		return
+	}
+
+	n.publish(event)
+}
+
+func (n *Notifier) publish(event Event) {
+	n.schLock.RLock()
+	defer n.schLock.RUnlock()
+
+	for url, webhook := range n.webhooks {
+		if !matches(webhook, event) {
+			continue
+		}
+
+		delivery := Delivery{
+			ID:          event.ID + "/" + url,
+			URL:         url,
+			Event:       event,
+			NextAttempt: time.Now(),
+		}
+
+		if n.sch != nil {
+			// in case the task submitted below is dropped, the delivery is retried once the lease expires
+			delivery.NextAttempt = delivery.NextAttempt.Add(leaseDuration)
+		}
+
+		if err := n.store.put(delivery); err != nil {
+			n.log.Error().Err(err).Str("event", event.ID).Str("url", url).Msg("failed to persist event delivery")
+
+			continue
+		}
+
+		if n.sch != nil {
+			n.sch.SubmitTask(newDeliveryTask(n, delivery.ID, delivery.Attempts), scheduler.MediumPriority)
+		}
+	}
+}
+
+// attempt sends a delivery and updates its state, it does nothing if the delivery
+// was already handled, for example by a duplicated task.
+func (n *Notifier) attempt(ctx context.Context, id string, attempts int) error {
+	delivery, found, err := n.store.get(id)
+	if err != nil {
+		return err
+	}
+
+	if !found || delivery.Attempts != attempts {
+		return nil
+	}
+
+	webhook, ok := n.webhooks[delivery.URL]
+	if !ok {
+		n.log.Warn().Str("event", delivery.Event.ID).Str("url", delivery.URL).
+			Msg("webhook is no longer configured, dropping event delivery")
+
+		return n.store.delete(id)
+	}
+
+	err = n.send(ctx, webhook, delivery.Event)
+	if err == nil {
+		return n.store.delete(id)
+	}
+
+	delivery.Attempts++
+	delivery.LastError = err.Error()
+
+	if delivery.Attempts > getMaxRetries(webhook) {
+		n.log.Error().Err(err).Str("event", delivery.Event.ID).Str("url", delivery.URL).
+			Int("attempts", delivery.Attempts).Msg("failed to deliver event, moving it to the dead-letter queue")
+
+		if storeErr := n.store.deadLetter(delivery); storeErr != nil {
+			return storeErr
+		}
+
+		return err
+	}
+
+	delivery.NextAttempt = time.Now().Add(getBackoff(webhook, delivery.Attempts))
+
+	n.log.Warn().Err(err).Str("event", delivery.Event.ID).Str("url", delivery.URL).
+		Int("attempts", delivery.Attempts).Time("nextAttempt", delivery.NextAttempt).
+		Msg("failed to deliver event, will retry")
+
+	if storeErr := n.store.put(delivery); storeErr != nil {
+		return storeErr
+	}
+
+	return err
+}
+
+func (n *Notifier) send(ctx context.Context, webhook extconf.WebhookConfig, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	timeout := webhook.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", cloudEventMediaType)
+
+	if webhook.Secret != "" {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(webhook.Secret, payload))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("%w: %d", zerr.ErrUnexpectedWebhookStatus, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of the payload, receivers compare it
+// with the value of the X-Zot-Signature header to authenticate the events.
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func getMaxRetries(webhook extconf.WebhookConfig) int {
+	if webhook.MaxRetries != nil {
+		return *webhook.MaxRetries
+	}
+
+	return DefaultMaxRetries
+}
+
+func getBackoff(webhook extconf.WebhookConfig, attempts int) time.Duration {
+	delay := DefaultRetryDelay
+	if webhook.RetryDelay != nil {
+		delay = *webhook.RetryDelay
+	}
+
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxRetryDelay)
+}
+
+// retryTaskGenerator generates the delivery tasks of the pending events which are due.
+type retryTaskGenerator struct {
+	notifier   *Notifier
+	deliveries []Delivery
+	loaded     bool
+	done       bool
+}
+
+func (gen *retryTaskGenerator) Name() string {
+	return "EventsRetryGenerator"
+}
+
+func (gen *retryTaskGenerator) Next() (scheduler.Task, error) {
+	if !gen.loaded {
+		deliveries, err := gen.notifier.store.lease(time.Now(), leaseDuration)
+		if err != nil {
+			return nil, err
+		}
+
+		gen.deliveries = deliveries
+		gen.loaded = true
+	}
+
+	if len(gen.deliveries) == 0 {
+		gen.done = true
+
+		return nil, nil //nolint:nilnil
+	}
+
+	delivery := gen.deliveries[0]
+	gen.deliveries = gen.deliveries[1:]
+
+	return newDeliveryTask(gen.notifier, delivery.ID, delivery.Attempts), nil
+}
+
+func (gen *retryTaskGenerator) IsDone() bool {
+	return gen.done
+}
+
+func (gen *retryTaskGenerator) IsReady() bool {
+	return true
+}
+
+func (gen *retryTaskGenerator) Reset() {
+	gen.deliveries = nil
+	gen.loaded = false
+	gen.done = false
+}
+
+type deliveryTask struct {
+	notifier *Notifier
+	id       string
+	attempts int
+}
+
+func newDeliveryTask(notifier *Notifier, id string, attempts int) *deliveryTask {
+	return &deliveryTask{notifier: notifier, id: id, attempts: attempts}
+}
+
+func (dt *deliveryTask) DoWork(ctx context.Context) error {
+	return dt.notifier.attempt(ctx, dt.id, dt.attempts)
+}
+
+func (dt *deliveryTask) String() string {
+	return fmt.Sprintf("{Name: %s, delivery: %s, attempts: %d}", dt.Name(), dt.id, dt.attempts)
+}
+
+func (dt *deliveryTask) Name() string {
+	return "EventDeliveryTask"
+}
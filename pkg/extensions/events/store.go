@@ -0,0 +1,183 @@
+package events
+
+import (
+	"encoding/json"
+	"path"
+	"time"
+
+	"go.etcd.io/bbolt"
+
+	zlog "zotregistry.dev/zot/pkg/log"
+	"zotregistry.dev/zot/pkg/storage/constants"
+)
+
+const (
+	dbName             = "events"
+	pendingBucket      = "pending"
+	deadLetterBucket   = "deadletter"
+	dbLockCheckTimeout = 10 * time.Second
+)
+
+// Delivery is an event to be sent to a webhook, persisted until it is delivered
+// or moved to the dead-letter queue once all its attempts failed.
+type Delivery struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Event       Event     `json:"event"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"nextAttempt"`
+	LastError   string    `json:"lastError,omitempty"`
+}
+
+type deliveryStore struct {
+	db  *bbolt.DB
+	log zlog.Logger
+}
+
+func newDeliveryStore(rootDir string, log zlog.Logger) (*deliveryStore, error) {
+	dbPath := path.Join(rootDir, dbName+constants.DBExtensionName)
+
+	boltDB, err := bbolt.Open(dbPath, 0o600, &bbolt.Options{Timeout: dbLockCheckTimeout}) //nolint:mnd
+	if err != nil {
+		log.Error().Err(err).Str("dbPath", dbPath).Msg("failed to open events db")
+
+		return nil, err
+	}
+
+	err = boltDB.Update(func(tx *bbolt.Tx) error {
+		for _, bucket := range []string{pendingBucket, deadLetterBucket} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(bucket)); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		log.Error().Err(err).Str("dbPath", dbPath).Msg("failed to create events db buckets")
+
+		_ = boltDB.Close()
+
+		return nil, err
+	}
+
+	return &deliveryStore{db: boltDB, log: log}, nil
+}
+
+func (s *deliveryStore) put(delivery Delivery) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return putDelivery(tx.Bucket([]byte(pendingBucket)), delivery)
+	})
+}
+
+// get returns the pending delivery with the given id, if any.
+func (s *deliveryStore) get(id string) (Delivery, bool, error) {
+	var (
+		delivery Delivery
+		found    bool
+	)
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		blob := tx.Bucket([]byte(pendingBucket)).Get([]byte(id))
+		if blob == nil {
+			return nil
+		}
+
+		found = true
+
+		return json.Unmarshal(blob, &delivery)
+	})
+
+	return delivery, found, err
+}
+
+func (s *deliveryStore) delete(id string) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(pendingBucket)).Delete([]byte(id))
+	})
+}
+
+// lease returns the pending deliveries due before now, postponing their next attempt
+// until the lease expires so they are not handed out twice.
+func (s *deliveryStore) lease(now time.Time, leaseDuration time.Duration) ([]Delivery, error) {
+	deliveries := []Delivery{}
+
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket([]byte(pendingBucket))
+
+		err := bucket.ForEach(func(_, blob []byte) error {
+			var delivery Delivery
+
+			if err := json.Unmarshal(blob, &delivery); err != nil {
+				return err
+			}
+
+			if delivery.NextAttempt.After(now) {
+				return nil
+			}
+
+			deliveries = append(deliveries, delivery)
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for idx := range deliveries {
+			deliveries[idx].NextAttempt = now.Add(leaseDuration)
+
+			if err := putDelivery(bucket, deliveries[idx]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return deliveries, err
+}
+
+// deadLetter moves a delivery from the pending queue to the dead-letter queue.
+func (s *deliveryStore) deadLetter(delivery Delivery) error {
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket([]byte(pendingBucket)).Delete([]byte(delivery.ID)); err != nil {
+			return err
+		}
+
+		return putDelivery(tx.Bucket([]byte(deadLetterBucket)), delivery)
+	})
+}
+
+func (s *deliveryStore) getDeadLetters() ([]Delivery, error) {
+	deliveries := []Delivery{}
+
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		return tx.Bucket([]byte(deadLetterBucket)).ForEach(func(_, blob []byte) error {
+			var delivery Delivery
+
+			if err := json.Unmarshal(blob, &delivery); err != nil {
+				return err
+			}
+
+			deliveries = append(deliveries, delivery)
+
+			return nil
+		})
+	})
+
+	return deliveries, err
+}
+
+func (s *deliveryStore) close() error {
+	return s.db.Close()
+}
+
+func putDelivery(bucket *bbolt.Bucket, delivery Delivery) error {
+	blob, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	return bucket.Put([]byte(delivery.ID), blob)
+}
@@ -0,0 +1,21 @@
+//go:build events
+// +build events
+
+package extensions
+
+import (
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/extensions/events"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+// GetEventsNotifier returns the notifier sending the registry events to the configured webhooks.
+func GetEventsNotifier(config *config.Config, log log.Logger) (*events.Notifier, error) {
+	if !config.IsEventsEnabled() {
+		log.Info().Msg("events config not provided, skipping events")
+
+		return nil, nil //nolint:nilnil
+	}
+
+	return events.NewNotifier(config.Extensions.Events, config.Storage.RootDirectory, log)
+}
@@ -0,0 +1,20 @@
+//go:build !events
+// +build !events
+
+package extensions
+
+import (
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/extensions/events"
+	"zotregistry.dev/zot/pkg/log"
+)
+
+// GetEventsNotifier ...
+func GetEventsNotifier(config *config.Config, log log.Logger) (*events.Notifier, error) {
+	if config.IsEventsEnabled() {
+		log.Warn().Msg("skipping enabling events extension because given zot binary doesn't include this feature," +
+			"please build a binary that does so")
+	}
+
+	return nil, nil //nolint:nilnil
+}
@@ -0,0 +1,105 @@
+//go:build events
+// +build events
+
+package extensions_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+	"gopkg.in/resty.v1"
+
+	"zotregistry.dev/zot/pkg/api"
+	"zotregistry.dev/zot/pkg/api/config"
+	extconf "zotregistry.dev/zot/pkg/extensions/config"
+	"zotregistry.dev/zot/pkg/extensions/events"
+	test "zotregistry.dev/zot/pkg/test/common"
+	. "zotregistry.dev/zot/pkg/test/image-utils"
+)
+
+func TestEventsExtension(t *testing.T) {
+	Convey("Verify zot sends events for manifest pushes and deletes", t, func() {
+		received := make(chan events.Event, 10)
+
+		webhook := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+			var event events.Event
+
+			if err := json.NewDecoder(req.Body).Decode(&event); err == nil {
+				received <- event
+			}
+		}))
+		defer webhook.Close()
+
+		conf := config.New()
+		port := test.GetFreePort()
+		baseURL := test.GetBaseURL(port)
+		conf.HTTP.Port = port
+		conf.Storage.RootDirectory = t.TempDir()
+
+		defaultValue := true
+
+		conf.Extensions = &extconf.ExtensionConfig{}
+		conf.Extensions.Events = &extconf.EventsConfig{
+			BaseConfig: extconf.BaseConfig{Enable: &defaultValue},
+			Webhooks: []extconf.WebhookConfig{
+				{URL: webhook.URL, Repositories: []string{"events/**"}},
+			},
+		}
+
+		ctlr := api.NewController(conf)
+
+		ctlrManager := test.NewControllerManager(ctlr)
+		ctlrManager.StartAndWait(port)
+
+		defer ctlrManager.StopServer()
+
+		So(ctlr.MetaDB, ShouldNotBeNil)
+		So(ctlr.EventsNotifier, ShouldNotBeNil)
+
+		receive := func() events.Event {
+			select {
+			case event := <-received:
+				return event
+			case <-time.After(10 * time.Second):
+				return events.Event{}
+			}
+		}
+
+		image := CreateRandomImage()
+
+		err := UploadImage(image, baseURL, "events/repo", "1.0")
+		So(err, ShouldBeNil)
+
+		event := receive()
+		So(event.Type, ShouldEqual, events.ImageUpdatedEvent)
+		So(event.Data.Repository, ShouldEqual, "events/repo")
+		So(event.Data.Reference, ShouldEqual, "1.0")
+		So(event.Data.Digest, ShouldEqual, image.DigestStr())
+
+		signature := CreateMockNotationSignature(image.DescriptorRef())
+
+		err = UploadImage(signature, baseURL, "events/repo", signature.DigestStr())
+		So(err, ShouldBeNil)
+
+		event = receive()
+		So(event.Type, ShouldEqual, events.SignatureAddedEvent)
+		So(event.Data.Subject, ShouldEqual, image.DigestStr())
+
+		// repositories not matching the webhook filters do not send events
+		err = UploadImage(image, baseURL, "other", "1.0")
+		So(err, ShouldBeNil)
+
+		resp, err := resty.R().Delete(baseURL + "/v2/events/repo/manifests/1.0")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusAccepted)
+
+		event = receive()
+		So(event.Type, ShouldEqual, events.ImageDeletedEvent)
+		So(event.Data.Repository, ShouldEqual, "events/repo")
+		So(event.Data.Reference, ShouldEqual, "1.0")
+	})
+}
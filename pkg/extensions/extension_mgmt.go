@@ -5,14 +5,26 @@ package extensions
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/gorilla/mux"
+	godigest "github.com/opencontainers/go-digest"
 
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/accounts"
 	"zotregistry.dev/zot/pkg/api/config"
 	"zotregistry.dev/zot/pkg/api/constants"
 	zcommon "zotregistry.dev/zot/pkg/common"
+	"zotregistry.dev/zot/pkg/extensions/events"
 	"zotregistry.dev/zot/pkg/log"
+	"zotregistry.dev/zot/pkg/meta"
+	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	"zotregistry.dev/zot/pkg/quota"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+	"zotregistry.dev/zot/pkg/storage"
+	storageCommon "zotregistry.dev/zot/pkg/storage/common"
+	storageTypes "zotregistry.dev/zot/pkg/storage/types"
 )
 
 type HTPasswd struct {
@@ -42,6 +54,17 @@ type Auth struct {
 	APIKey bool          `json:"apikey,omitempty" mapstructure:"apikey"`
 }
 
+// UserPayload is the body of the requests creating a htpasswd user or updating its password.
+type UserPayload struct {
+	Username string `json:"username,omitempty"`
+	Password string `json:"password"`
+}
+
+// GroupPayload is an access control group, the body of the requests setting its users.
+type GroupPayload struct {
+	Users []string `json:"users"`
+}
+
 type StrippedConfig struct {
 	DistSpecVersion string `json:"distSpecVersion" mapstructure:"distSpecVersion"`
 	Commit          string `json:"commit"          mapstructure:"commit"`
@@ -84,7 +107,11 @@ func (auth Auth) MarshalJSON() ([]byte, error) {
 	return json.Marshal((localAuth)(auth))
 }
 
-func SetupMgmtRoutes(conf *config.Config, router *mux.Router, log log.Logger) {
+func SetupMgmtRoutes(conf *config.Config, quotaManager *quota.Manager, accountsManager *accounts.Manager,
+	storeController storage.StoreController, metaDB mTypes.MetaDB,
+	immutableTags func(storePath string) *storageCommon.ImmutableTags, notifier *events.Notifier, router *mux.Router,
+	log log.Logger,
+) {
 	if !conf.IsMgmtEnabled() {
 		log.Info().Msg("skip enabling the mgmt route as the config prerequisites are not met")
 
@@ -93,7 +120,16 @@ func SetupMgmtRoutes(conf *config.Config, router *mux.Router, log log.Logger) {
 
 	log.Info().Msg("setting up mgmt routes")
 
-	mgmt := Mgmt{Conf: conf, Log: log}
+	mgmt := Mgmt{
+		Conf:            conf,
+		QuotaManager:    quotaManager,
+		Accounts:        accountsManager,
+		StoreController: storeController,
+		MetaDB:          metaDB,
+		ImmutableTags:   immutableTags,
+		EventsNotifier:  notifier,
+		Log:             log,
+	}
 
 	// The endpoint for reading configuration should be available to all users
 	allowedMethods := zcommon.AllowedMethods(http.MethodGet)
@@ -102,14 +138,50 @@ func SetupMgmtRoutes(conf *config.Config, router *mux.Router, log log.Logger) {
 	mgmtRouter.Use(zcommon.CORSHeadersMiddleware(conf.HTTP.AllowOrigin))
 	mgmtRouter.Use(zcommon.AddExtensionSecurityHeaders())
 	mgmtRouter.Use(zcommon.ACHeadersMiddleware(conf, allowedMethods...))
+
+	// quotas usage exposes repositories sizes, so it is only available to admins
+	quotasRouter := mgmtRouter.PathPrefix(constants.MgmtQuotas).Subrouter()
+	quotasRouter.Use(zcommon.AuthzOnlyAdminsMiddleware(conf))
+	quotasRouter.Methods(allowedMethods...).HandlerFunc(mgmt.HandleGetQuotas)
+
+	// rolling back a tag is a manifest push, permissions are checked against the repository
+	rollbackRouter := mgmtRouter.PathPrefix(constants.MgmtTagRollback).Subrouter()
+	rollbackRouter.Use(zcommon.ACHeadersMiddleware(conf, http.MethodPost, http.MethodOptions))
+	rollbackRouter.Methods(http.MethodPost, http.MethodOptions).HandlerFunc(mgmt.HandleTagRollback)
+
+	// the htpasswd users and the access control groups are only managed by admins
+	if conf.IsBasicAuthnEnabled() {
+		accountsRouter := mgmtRouter.NewRoute().Subrouter()
+		accountsRouter.Use(zcommon.AuthzOnlyAdminsMiddleware(conf))
+		accountsRouter.Use(zcommon.ACHeadersMiddleware(conf,
+			http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions))
+
+		accountsRouter.HandleFunc(constants.MgmtUsers, mgmt.HandleGetUsers).Methods(http.MethodGet)
+		accountsRouter.HandleFunc(constants.MgmtUsers, mgmt.HandleCreateUser).Methods(http.MethodPost)
+		accountsRouter.HandleFunc(constants.MgmtUsers+"/{username}", mgmt.HandleUpdateUser).Methods(http.MethodPut)
+		accountsRouter.HandleFunc(constants.MgmtUsers+"/{username}", mgmt.HandleDeleteUser).
+			Methods(http.MethodDelete)
+		accountsRouter.HandleFunc(constants.MgmtGroups, mgmt.HandleGetGroups).Methods(http.MethodGet)
+		accountsRouter.HandleFunc(constants.MgmtGroups+"/{group}", mgmt.HandleSetGroup).Methods(http.MethodPut)
+		accountsRouter.HandleFunc(constants.MgmtGroups+"/{group}", mgmt.HandleDeleteGroup).
+			Methods(http.MethodDelete)
+	}
+
 	mgmtRouter.Methods(allowedMethods...).HandlerFunc(mgmt.HandleGetConfig)
 
 	log.Info().Msg("finished setting up mgmt routes")
 }
 
 type Mgmt struct {
-	Conf *config.Config
-	Log  log.Logger
+	Conf            *config.Config
+	QuotaManager    *quota.Manager
+	Accounts        *accounts.Manager
+	StoreController storage.StoreController
+	MetaDB          mTypes.MetaDB
+	// returns the immutable tags policies of a store path, which admins can override
+	ImmutableTags  func(storePath string) *storageCommon.ImmutableTags
+	EventsNotifier *events.Notifier
+	Log            log.Logger
 }
 
 // mgmtHandler godoc
@@ -132,3 +204,375 @@ func (mgmt *Mgmt) HandleGetConfig(w http.ResponseWriter, r *http.Request) {
 
 	_, _ = w.Write(buf)
 }
+
+// mgmtQuotasHandler godoc
+// @Summary Get storage quotas usage
+// @Description Get the live usage of the configured storage quotas, available to admins only
+// @Router  /v2/_zot/ext/mgmt/quotas [get]
+// @Accept  json
+// @Produce json
+// @Success 200 {array}    quota.Usage
+// @Failure 500 {string}   string   "internal server error".
+func (mgmt *Mgmt) HandleGetQuotas(w http.ResponseWriter, r *http.Request) {
+	usages := []quota.Usage{}
+
+	if mgmt.QuotaManager != nil {
+		var err error
+
+		usages, err = mgmt.QuotaManager.GetUsage()
+		if err != nil {
+			mgmt.Log.Error().Err(err).Str("component", "mgmt").Msg("failed to get storage quotas usage")
+			w.WriteHeader(http.StatusInternalServerError)
+
+			return
+		}
+	}
+
+	zcommon.WriteJSON(w, http.StatusOK, usages)
+}
+
+// mgmtTagRollbackHandler godoc
+// @Summary Re-point a tag to a manifest it pointed to before
+// @Description Re-point a tag to an earlier digest from its history, as long as the manifest is still in storage
+// @Router  /v2/_zot/ext/mgmt/rollback [post]
+// @Accept  json
+// @Produce json
+// @Param   repo      query     string   true    "repository name"
+// @Param   tag       query     string   true    "tag to re-point"
+// @Param   digest    query     string   true    "digest of a manifest the tag pointed to before"
+// @Param   force     query     boolean  false   "override the immutable tags policies, admins only"
+// @Success 200 {array}    types.TagHistoryEntry
+// @Failure 400 {string}   string   "bad request"
+// @Failure 403 {string}   string   "forbidden"
+// @Failure 404 {string}   string   "not found"
+// @Failure 500 {string}   string   "internal server error".
+func (mgmt *Mgmt) HandleTagRollback(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	query := r.URL.Query()
+	repo, tag := query.Get("repo"), query.Get("tag")
+
+	digest, err := godigest.Parse(query.Get("digest"))
+	if err != nil || repo == "" || tag == "" || !zcommon.IsTag(tag) {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	userAc, err := reqCtx.UserAcFromContext(r.Context())
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	// the policies may only allow updating some of the tags of the repository
+	if !userAc.CanOnReference(constants.UpdatePermission, repo, tag) {
+		zcommon.AuthzFail(w, r, userAc.GetUsername(), mgmt.Conf.HTTP.Realm, mgmt.Conf.HTTP.Auth.FailDelay)
+
+		return
+	}
+
+	history, err := mgmt.MetaDB.GetTagHistory(r.Context(), repo, tag)
+	if err != nil {
+		if errors.Is(err, zerr.ErrTagHistoryNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			mgmt.Log.Error().Err(err).Str("component", "mgmt").Msg("failed to get tag history")
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	var mediaType string
+
+	for _, entry := range history {
+		if entry.Digest == digest.String() {
+			mediaType = entry.MediaType
+		}
+	}
+
+	if mediaType == "" {
+		mgmt.Log.Info().Err(zerr.ErrDigestNotInTagHistory).Str("repository", repo).Str("tag", tag).
+			Str("digest", digest.String()).Msg("can not roll back tag")
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	imgStore := mgmt.StoreController.GetImageStore(repo)
+
+	body, err := imgStore.GetBlobContent(repo, digest)
+	if err != nil {
+		if errors.Is(err, zerr.ErrBlobNotFound) || errors.Is(err, zerr.ErrRepoNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else {
+			mgmt.Log.Error().Err(err).Str("component", "mgmt").Msg("failed to get manifest")
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	_, _, err = mgmt.overrideImmutableTags(r, userAc, imgStore, repo).PutImageManifest(repo, tag, mediaType, body)
+	if err != nil {
+		// the blobs referenced by the manifest may have been garbage collected
+		if errors.Is(err, zerr.ErrBlobNotFound) || errors.Is(err, zerr.ErrManifestNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+		} else if errors.Is(err, zerr.ErrImmutableTag) {
+			w.WriteHeader(http.StatusForbidden)
+		} else {
+			mgmt.Log.Error().Err(err).Str("component", "mgmt").Msg("failed to re-point tag")
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		return
+	}
+
+	ctx := reqCtx.WithClient(r.Context(), r.UserAgent())
+
+	err = meta.OnUpdateManifest(ctx, repo, tag, mediaType, digest, body, mgmt.StoreController, mgmt.MetaDB,
+		mgmt.EventsNotifier, mgmt.Log)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	mgmt.Log.Info().Str("repository", repo).Str("tag", tag).Str("digest", digest.String()).
+		Str("username", userAc.GetUsername()).Msg("tag rolled back")
+
+	history, err = mgmt.MetaDB.GetTagHistory(r.Context(), repo, tag)
+	if err != nil {
+		mgmt.Log.Error().Err(err).Str("component", "mgmt").Msg("failed to get tag history")
+		w.WriteHeader(http.StatusInternalServerError)
+
+		return
+	}
+
+	zcommon.WriteJSON(w, http.StatusOK, history)
+}
+
+// mgmtUsersHandler godoc
+// @Summary Get the htpasswd users
+// @Description Get the names of the users of the htpasswd file, available to admins only
+// @Router  /v2/_zot/ext/mgmt/users [get]
+// @Produce json
+// @Success 200 {array}    string
+// @Failure 404 {string}   string   "not found"
+// @Failure 500 {string}   string   "internal server error".
+func (mgmt *Mgmt) HandleGetUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := mgmt.Accounts.Users()
+	if err != nil {
+		mgmt.writeAccountsError(w, err, "failed to get htpasswd users")
+
+		return
+	}
+
+	zcommon.WriteJSON(w, http.StatusOK, users)
+}
+
+// mgmtCreateUserHandler godoc
+// @Summary Create a htpasswd user
+// @Description Add a user to the htpasswd file, available to admins only
+// @Router  /v2/_zot/ext/mgmt/users [post]
+// @Accept  json
+// @Produce json
+// @Param   user   body   extensions.UserPayload   true   "username and password"
+// @Success 201 {string}   string   "created"
+// @Failure 400 {string}   string   "bad request"
+// @Failure 404 {string}   string   "not found"
+// @Failure 409 {string}   string   "conflict"
+// @Failure 500 {string}   string   "internal server error".
+func (mgmt *Mgmt) HandleCreateUser(w http.ResponseWriter, r *http.Request) {
+	var payload UserPayload
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Password == "" {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if err := mgmt.Accounts.CreateUser(actor(r), payload.Username, payload.Password); err != nil {
+		mgmt.writeAccountsError(w, err, "failed to create htpasswd user")
+
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+}
+
+// mgmtUpdateUserHandler godoc
+// @Summary Update the password of a htpasswd user
+// @Description Replace the password of a user of the htpasswd file, available to admins only
+// @Router  /v2/_zot/ext/mgmt/users/{username} [put]
+// @Accept  json
+// @Produce json
+// @Param   username   path   string                   true   "username"
+// @Param   user       body   extensions.UserPayload   true   "new password"
+// @Success 200 {string}   string   "ok"
+// @Failure 400 {string}   string   "bad request"
+// @Failure 404 {string}   string   "not found"
+// @Failure 500 {string}   string   "internal server error".
+func (mgmt *Mgmt) HandleUpdateUser(w http.ResponseWriter, r *http.Request) {
+	var payload UserPayload
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.Password == "" {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if err := mgmt.Accounts.UpdatePassword(actor(r), mux.Vars(r)["username"], payload.Password); err != nil {
+		mgmt.writeAccountsError(w, err, "failed to update htpasswd user password")
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// mgmtDeleteUserHandler godoc
+// @Summary Delete a htpasswd user
+// @Description Remove a user from the htpasswd file, available to admins only
+// @Router  /v2/_zot/ext/mgmt/users/{username} [delete]
+// @Param   username   path   string   true   "username"
+// @Success 200 {string}   string   "ok"
+// @Failure 404 {string}   string   "not found"
+// @Failure 500 {string}   string   "internal server error".
+func (mgmt *Mgmt) HandleDeleteUser(w http.ResponseWriter, r *http.Request) {
+	if err := mgmt.Accounts.DeleteUser(actor(r), mux.Vars(r)["username"]); err != nil {
+		mgmt.writeAccountsError(w, err, "failed to delete htpasswd user")
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// mgmtGroupsHandler godoc
+// @Summary Get the access control groups
+// @Description Get the access control groups of the groups file, available to admins only
+// @Router  /v2/_zot/ext/mgmt/groups [get]
+// @Produce json
+// @Success 200 {object}   map[string]extensions.GroupPayload
+// @Failure 404 {string}   string   "not found"
+// @Failure 500 {string}   string   "internal server error".
+func (mgmt *Mgmt) HandleGetGroups(w http.ResponseWriter, r *http.Request) {
+	groups, err := mgmt.Accounts.Groups()
+	if err != nil {
+		mgmt.writeAccountsError(w, err, "failed to get access control groups")
+
+		return
+	}
+
+	response := map[string]GroupPayload{}
+	for name, group := range groups {
+		response[name] = GroupPayload{Users: group.Users}
+	}
+
+	zcommon.WriteJSON(w, http.StatusOK, response)
+}
+
+// mgmtSetGroupHandler godoc
+// @Summary Set the users of an access control group
+// @Description Create or replace a group of the groups file, available to admins only
+// @Router  /v2/_zot/ext/mgmt/groups/{group} [put]
+// @Accept  json
+// @Produce json
+// @Param   group   path   string                    true   "group name"
+// @Param   users   body   extensions.GroupPayload   true   "users of the group"
+// @Success 200 {string}   string   "ok"
+// @Failure 400 {string}   string   "bad request"
+// @Failure 404 {string}   string   "not found"
+// @Failure 500 {string}   string   "internal server error".
+func (mgmt *Mgmt) HandleSetGroup(w http.ResponseWriter, r *http.Request) {
+	var payload GroupPayload
+
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+
+		return
+	}
+
+	if err := mgmt.Accounts.SetGroup(actor(r), mux.Vars(r)["group"], payload.Users); err != nil {
+		mgmt.writeAccountsError(w, err, "failed to set access control group")
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// mgmtDeleteGroupHandler godoc
+// @Summary Delete an access control group
+// @Description Remove a group from the groups file, available to admins only
+// @Router  /v2/_zot/ext/mgmt/groups/{group} [delete]
+// @Param   group   path   string   true   "group name"
+// @Success 200 {string}   string   "ok"
+// @Failure 404 {string}   string   "not found"
+// @Failure 500 {string}   string   "internal server error".
+func (mgmt *Mgmt) HandleDeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if err := mgmt.Accounts.DeleteGroup(actor(r), mux.Vars(r)["group"]); err != nil {
+		mgmt.writeAccountsError(w, err, "failed to delete access control group")
+
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// writeAccountsError writes the status matching an error of the accounts manager.
+func (mgmt *Mgmt) writeAccountsError(w http.ResponseWriter, err error, msg string) {
+	switch {
+	case errors.Is(err, zerr.ErrHTPasswdNotConfigured), errors.Is(err, zerr.ErrGroupsFileNotConfigured),
+		errors.Is(err, zerr.ErrBadUser), errors.Is(err, zerr.ErrGroupNotFound):
+		w.WriteHeader(http.StatusNotFound)
+	case errors.Is(err, zerr.ErrUserAlreadyExists):
+		w.WriteHeader(http.StatusConflict)
+	case errors.Is(err, zerr.ErrInvalidUsername):
+		w.WriteHeader(http.StatusBadRequest)
+	default:
+		mgmt.Log.Error().Err(err).Str("component", "mgmt").Msg(msg)
+		w.WriteHeader(http.StatusInternalServerError)
+	}
+}
+
+// actor returns the name of the user sending a request.
+func actor(r *http.Request) string {
+	userAc, err := reqCtx.UserAcFromContext(r.Context())
+	if err != nil || userAc == nil {
+		return ""
+	}
+
+	return userAc.GetUsername()
+}
+
+// overrideImmutableTags returns a view of imgStore which does not enforce its write policies, the immutable tags
+// policies, if an authenticated admin explicitly asks for it with the force query parameter, or imgStore otherwise.
+func (mgmt *Mgmt) overrideImmutableTags(r *http.Request, userAc *reqCtx.UserAccessControl,
+	imgStore storageTypes.ImageStore, repo string,
+) storageTypes.ImageStore {
+	if r.URL.Query().Get("force") != "true" {
+		return imgStore
+	}
+
+	immutableTags := mgmt.ImmutableTags(mgmt.StoreController.GetStorePath(repo))
+	if immutableTags == nil {
+		return imgStore
+	}
+
+	if userAc.IsAnonymous() || !userAc.IsAdmin() {
+		mgmt.Log.Warn().Str("repository", repo).Msg("immutable tags policies can only be overridden by admins")
+
+		return imgStore
+	}
+
+	mgmt.Log.Info().Str("repository", repo).Str("username", userAc.GetUsername()).
+		Msg("immutable tags policies overridden")
+
+	return imgStore.WithoutWritePolicies(immutableTags)
+}
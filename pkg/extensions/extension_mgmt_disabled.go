@@ -6,15 +6,25 @@ package extensions
 import (
 	"github.com/gorilla/mux"
 
+	"zotregistry.dev/zot/pkg/accounts"
 	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/extensions/events"
 	"zotregistry.dev/zot/pkg/log"
+	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	"zotregistry.dev/zot/pkg/quota"
+	"zotregistry.dev/zot/pkg/storage"
+	storageCommon "zotregistry.dev/zot/pkg/storage/common"
 )
 
 func IsBuiltWithMGMTExtension() bool {
 	return false
 }
 
-func SetupMgmtRoutes(config *config.Config, router *mux.Router, log log.Logger) {
+func SetupMgmtRoutes(config *config.Config, quotaManager *quota.Manager, accountsManager *accounts.Manager,
+	storeController storage.StoreController, metaDB mTypes.MetaDB,
+	immutableTags func(storePath string) *storageCommon.ImmutableTags, notifier *events.Notifier, router *mux.Router,
+	log log.Logger,
+) {
 	log.Warn().Msg("skipping setting up mgmt routes because given zot binary doesn't include this feature," +
 		"please build a binary that does so")
 }
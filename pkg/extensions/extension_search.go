@@ -18,6 +18,7 @@ import (
 	"zotregistry.dev/zot/pkg/extensions/search/gql_generated"
 	"zotregistry.dev/zot/pkg/log"
 	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 	"zotregistry.dev/zot/pkg/scheduler"
 	"zotregistry.dev/zot/pkg/storage"
 )
@@ -98,6 +99,7 @@ func SetupSearchRoutes(conf *config.Config, router *mux.Router, storeController
 	extRouter.Use(zcommon.CORSHeadersMiddleware(conf.HTTP.AllowOrigin))
 	extRouter.Use(zcommon.ACHeadersMiddleware(conf, allowedMethods...))
 	extRouter.Use(zcommon.AddExtensionSecurityHeaders())
+	extRouter.Use(zcommon.AuthzScopeMiddleware(conf, reqCtx.SearchScope))
 	extRouter.Methods(allowedMethods...).
 		Handler(gqlHandler.NewDefaultServer(gql_generated.NewExecutableSchema(resConfig))) //nolint: staticcheck
 
@@ -18,7 +18,7 @@ import (
 	"zotregistry.dev/zot/pkg/storage"
 )
 
-func EnableSyncExtension(config *config.Config, metaDB mTypes.MetaDB,
+func EnableSyncExtension(config *config.Config, metaDB mTypes.MetaDB, notifier mTypes.ManifestNotifier,
 	storeController storage.StoreController, sch *scheduler.Scheduler, log log.Logger,
 ) (*sync.BaseOnDemand, error) {
 	if config.Extensions.Sync != nil && *config.Extensions.Sync.Enable {
@@ -49,7 +49,7 @@ func EnableSyncExtension(config *config.Config, metaDB mTypes.MetaDB,
 			credsPath := config.Extensions.Sync.CredentialsFile
 			clusterCfg := config.Cluster
 
-			service, err := sync.New(registryConfig, credsPath, clusterCfg, tmpDir, storeController, metaDB, log)
+			service, err := sync.New(registryConfig, credsPath, clusterCfg, tmpDir, storeController, metaDB, notifier, log)
 			if err != nil {
 				log.Error().Err(err).Msg("failed to initialize sync extension")
 
@@ -15,6 +15,7 @@ import (
 	zcommon "zotregistry.dev/zot/pkg/common"
 	"zotregistry.dev/zot/pkg/log"
 	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 )
 
 const (
@@ -43,6 +44,7 @@ func SetupUserPreferencesRoutes(conf *config.Config, router *mux.Router,
 	userPrefsRouter.Use(zcommon.CORSHeadersMiddleware(conf.HTTP.AllowOrigin))
 	userPrefsRouter.Use(zcommon.AddExtensionSecurityHeaders())
 	userPrefsRouter.Use(zcommon.ACHeadersMiddleware(conf, allowedMethods...))
+	userPrefsRouter.Use(zcommon.AuthzScopeMiddleware(conf, reqCtx.UserPrefsScope))
 	userPrefsRouter.Methods(allowedMethods...).Handler(HandleUserPrefs(metaDB, log))
 
 	log.Info().Msg("finished setting up user preferences routes")
@@ -9,6 +9,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path"
 	"testing"
 	"time"
 
@@ -21,8 +22,11 @@ import (
 	"zotregistry.dev/zot/pkg/extensions"
 	extconf "zotregistry.dev/zot/pkg/extensions/config"
 	syncconf "zotregistry.dev/zot/pkg/extensions/config/sync"
+	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	"zotregistry.dev/zot/pkg/quota"
 	authutils "zotregistry.dev/zot/pkg/test/auth"
 	test "zotregistry.dev/zot/pkg/test/common"
+	. "zotregistry.dev/zot/pkg/test/image-utils"
 )
 
 const (
@@ -1046,6 +1050,605 @@ func TestMgmtWithBearer(t *testing.T) {
 	}
 }
 
+func TestMgmtQuotas(t *testing.T) {
+	Convey("Verify mgmt quotas route is only available to admins", t, func() {
+		adminUser, adminPassword := "admin", "admin"
+		user, password := "user", "user"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(adminUser, adminPassword) + "\n" +
+			test.GetCredString(user, password))
+		defer os.Remove(htpasswdPath)
+
+		defaultValue := true
+
+		conf := config.New()
+		port := test.GetFreePort()
+		conf.HTTP.Port = port
+		baseURL := test.GetBaseURL(port)
+
+		conf.HTTP.Auth.HTPasswd.Path = htpasswdPath
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users:   []string{user},
+							Actions: []string{constants.ReadPermission},
+						},
+					},
+				},
+			},
+			AdminPolicy: config.Policy{
+				Users:   []string{adminUser},
+				Actions: []string{constants.ReadPermission, constants.CreatePermission},
+			},
+		}
+		conf.Extensions = &extconf.ExtensionConfig{}
+		conf.Extensions.Search = &extconf.SearchConfig{}
+		conf.Extensions.Search.Enable = &defaultValue
+		conf.Extensions.Search.CVE = nil
+		conf.Extensions.UI = &extconf.UIConfig{}
+		conf.Extensions.UI.Enable = &defaultValue
+
+		conf.Storage.RootDirectory = t.TempDir()
+		conf.Storage.Quotas = []config.StorageQuota{
+			{Repositories: []string{"infra/*"}, MaxSize: 1024 * 1024, MaxImages: 10},
+		}
+
+		ctlr := api.NewController(conf)
+
+		ctlrManager := test.NewControllerManager(ctlr)
+		ctlrManager.StartAndWait(port)
+		defer ctlrManager.StopServer()
+
+		img := CreateRandomImage()
+
+		err := UploadImageWithBasicAuth(img, baseURL, "infra/a", "1.0", adminUser, adminPassword)
+		So(err, ShouldBeNil)
+
+		resp, err := resty.R().Get(baseURL + constants.FullMgmtQuotas)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		resp, err = resty.R().SetBasicAuth(user, password).Get(baseURL + constants.FullMgmtQuotas)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).Get(baseURL + constants.FullMgmtQuotas)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		usages := []quota.Usage{}
+		err = json.Unmarshal(resp.Body(), &usages)
+		So(err, ShouldBeNil)
+		So(usages, ShouldHaveLength, 1)
+		So(usages[0].Repositories, ShouldResemble, []string{"infra/*"})
+		So(usages[0].MaxSize, ShouldEqual, 1024*1024)
+		So(usages[0].MaxImages, ShouldEqual, 10)
+		So(usages[0].Size, ShouldEqual, img.ManifestDescriptor.Size+img.ConfigDescriptor.Size+img.Manifest.Layers[0].Size)
+		So(usages[0].Images, ShouldEqual, 1)
+
+		// the configuration is still served to everyone
+		resp, err = resty.R().SetBasicAuth(user, password).Get(baseURL + constants.FullMgmt)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+	})
+}
+
+func TestMgmtTagRollback(t *testing.T) {
+	Convey("Verify mgmt tag rollback re-points tags to digests from their history", t, func() {
+		adminUser, adminPassword := "admin", "admin"
+		user, password := "user", "user"
+		reader, readerPassword := "reader", "reader"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(adminUser, adminPassword) + "\n" +
+			test.GetCredString(user, password) + "\n" + test.GetCredString(reader, readerPassword))
+		defer os.Remove(htpasswdPath)
+
+		defaultValue := true
+
+		conf := config.New()
+		port := test.GetFreePort()
+		conf.HTTP.Port = port
+		baseURL := test.GetBaseURL(port)
+
+		conf.HTTP.Auth.HTPasswd.Path = htpasswdPath
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users: []string{user},
+							Actions: []string{
+								constants.ReadPermission, constants.CreatePermission, constants.UpdatePermission,
+							},
+						},
+						{
+							Users:   []string{reader},
+							Actions: []string{constants.ReadPermission},
+						},
+					},
+				},
+				"dev/**": config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users:   []string{user},
+							Actions: []string{constants.ReadPermission, constants.CreatePermission},
+						},
+						{
+							Users:    []string{user},
+							Actions:  []string{constants.UpdatePermission},
+							TagRegex: "dev-.*",
+						},
+					},
+				},
+				"release/**": config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Users: []string{user},
+							Actions: []string{
+								constants.ReadPermission, constants.CreatePermission, constants.UpdatePermission,
+							},
+						},
+					},
+					DenyPolicies: []config.Policy{
+						{
+							Users:    []string{user},
+							Actions:  []string{constants.UpdatePermission},
+							TagRegex: "v.*",
+						},
+					},
+				},
+			},
+			AdminPolicy: config.Policy{
+				Users: []string{adminUser},
+				Actions: []string{
+					constants.ReadPermission, constants.CreatePermission, constants.UpdatePermission,
+				},
+			},
+		}
+		conf.Extensions = &extconf.ExtensionConfig{}
+		conf.Extensions.Search = &extconf.SearchConfig{}
+		conf.Extensions.Search.Enable = &defaultValue
+		conf.Extensions.Search.CVE = nil
+		conf.Extensions.UI = &extconf.UIConfig{}
+		conf.Extensions.UI.Enable = &defaultValue
+
+		conf.Storage.RootDirectory = t.TempDir()
+
+		ctlr := api.NewController(conf)
+
+		ctlrManager := test.NewControllerManager(ctlr)
+		ctlrManager.StartAndWait(port)
+		defer ctlrManager.StopServer()
+
+		repo, tag := "repo", "latest"
+		image1 := CreateRandomImage()
+		image2 := CreateRandomImage()
+
+		err := UploadImageWithBasicAuth(image1, baseURL, repo, tag, user, password)
+		So(err, ShouldBeNil)
+
+		err = UploadImageWithBasicAuth(image2, baseURL, repo, tag, user, password)
+		So(err, ShouldBeNil)
+
+		rollback := func(username, password string, params map[string]string) *resty.Response {
+			resp, err := resty.R().SetBasicAuth(username, password).SetQueryParams(params).
+				Post(baseURL + constants.FullMgmtTagRollback)
+			So(err, ShouldBeNil)
+
+			return resp
+		}
+
+		params := map[string]string{"repo": repo, "tag": tag, "digest": image1.DigestStr()}
+
+		resp, err := resty.R().SetQueryParams(params).Post(baseURL + constants.FullMgmtTagRollback)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+		resp = rollback(reader, readerPassword, params)
+		So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+		resp = rollback(user, password, map[string]string{"repo": repo, "tag": tag})
+		So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+		resp = rollback(user, password, map[string]string{"repo": repo, "tag": image2.DigestStr(),
+			"digest": image1.DigestStr()})
+		So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+		resp = rollback(user, password, map[string]string{"repo": repo, "tag": "missing",
+			"digest": image1.DigestStr()})
+		So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+		// the tag never pointed to this digest
+		image3 := CreateRandomImage()
+
+		resp = rollback(user, password, map[string]string{"repo": repo, "tag": tag,
+			"digest": image3.DigestStr()})
+		So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+		resp = rollback(user, password, params)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+		history := []mTypes.TagHistoryEntry{}
+		err = json.Unmarshal(resp.Body(), &history)
+		So(err, ShouldBeNil)
+		So(history, ShouldHaveLength, 3)
+		So(history[0].Digest, ShouldEqual, image1.DigestStr())
+		So(history[1].Digest, ShouldEqual, image2.DigestStr())
+		So(history[2].Digest, ShouldEqual, image1.DigestStr())
+		So(history[2].Action, ShouldEqual, mTypes.TagUpdated)
+		So(history[2].User, ShouldEqual, user)
+
+		resp, err = resty.R().SetBasicAuth(reader, readerPassword).Head(baseURL + "/v2/" + repo + "/manifests/" + tag)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		So(resp.Header().Get(constants.DistContentDigestKey), ShouldEqual, image1.DigestStr())
+
+		Convey("Immutable tags can only be rolled back by admins", func() {
+			newConf := *ctlr.Config
+			newConf.Storage.ImmutableTags = []config.ImmutableTagsPolicy{{Repositories: []string{repo}}}
+
+			ctlr.LoadNewConfig(&newConf)
+
+			params["digest"] = image2.DigestStr()
+
+			resp = rollback(user, password, params)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			params["force"] = "true"
+
+			resp = rollback(user, password, params)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp = rollback(adminUser, adminPassword, params)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			// rolling back to the current digest is a noop
+			resp = rollback(user, password, map[string]string{"repo": repo, "tag": tag, "digest": image2.DigestStr()})
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			err = json.Unmarshal(resp.Body(), &history)
+			So(err, ShouldBeNil)
+			So(history, ShouldHaveLength, 4)
+			So(history[3].Digest, ShouldEqual, image2.DigestStr())
+			So(history[3].User, ShouldEqual, adminUser)
+		})
+
+		Convey("The tag conditions of the policies apply", func() {
+			for _, repo := range []string{"dev/app", "release/app"} {
+				for _, tag := range []string{"v1.0", "dev-1"} {
+					err := UploadImageWithBasicAuth(image1, baseURL, repo, tag, adminUser, adminPassword)
+					So(err, ShouldBeNil)
+
+					err = UploadImageWithBasicAuth(image2, baseURL, repo, tag, adminUser, adminPassword)
+					So(err, ShouldBeNil)
+				}
+			}
+
+			// only the dev tags can be updated
+			resp = rollback(user, password, map[string]string{"repo": "dev/app", "tag": "v1.0",
+				"digest": image1.DigestStr()})
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp = rollback(user, password, map[string]string{"repo": "dev/app", "tag": "dev-1",
+				"digest": image1.DigestStr()})
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			// updating the version tags is denied
+			resp = rollback(user, password, map[string]string{"repo": "release/app", "tag": "v1.0",
+				"digest": image1.DigestStr()})
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp = rollback(user, password, map[string]string{"repo": "release/app", "tag": "dev-1",
+				"digest": image1.DigestStr()})
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		})
+	})
+}
+
+func TestMgmtAccounts(t *testing.T) {
+	Convey("Verify mgmt routes manage the htpasswd users and the access control groups", t, func() {
+		adminUser, adminPassword := "admin", "admin"
+		user, password := "user", "user"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(adminUser, adminPassword) + "\n" +
+			test.GetCredString(user, password))
+		defer os.Remove(htpasswdPath)
+
+		auditPath := path.Join(t.TempDir(), "audit.log")
+		groupsPath := path.Join(t.TempDir(), "groups.json")
+
+		defaultValue := true
+
+		conf := config.New()
+		port := test.GetFreePort()
+		conf.HTTP.Port = port
+		baseURL := test.GetBaseURL(port)
+
+		conf.HTTP.Auth.HTPasswd.Path = htpasswdPath
+		conf.HTTP.Auth.HTPasswd.HashAlgorithm = "argon2id"
+		conf.HTTP.Auth.APIKey = true
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			GroupsFile: groupsPath,
+			Repositories: config.Repositories{
+				test.AuthorizationAllRepos: config.PolicyGroup{
+					Policies: []config.Policy{
+						{
+							Groups:  []string{"readers"},
+							Actions: []string{constants.ReadPermission},
+						},
+					},
+				},
+			},
+			AdminPolicy: config.Policy{
+				Users:   []string{adminUser},
+				Actions: []string{constants.ReadPermission},
+			},
+		}
+		conf.Log.Audit = auditPath
+		conf.Extensions = &extconf.ExtensionConfig{}
+		conf.Extensions.Search = &extconf.SearchConfig{}
+		conf.Extensions.Search.Enable = &defaultValue
+		conf.Extensions.Search.CVE = nil
+		conf.Extensions.UI = &extconf.UIConfig{}
+		conf.Extensions.UI.Enable = &defaultValue
+
+		conf.Storage.RootDirectory = t.TempDir()
+
+		ctlr := api.NewController(conf)
+
+		ctlrManager := test.NewControllerManager(ctlr)
+		ctlrManager.StartAndWait(port)
+		defer ctlrManager.StopServer()
+
+		Convey("Only admins manage the users and groups", func() {
+			resp, err := resty.R().Get(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			resp, err = resty.R().SetBasicAuth(user, password).Get(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(user, password).
+				SetBody(extensions.UserPayload{Username: "mallory", Password: "mallory"}).
+				Post(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(user, password).SetBody(extensions.GroupPayload{Users: []string{user}}).
+				Put(baseURL + constants.FullMgmtGroups + "/readers")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+		})
+
+		Convey("The scoped api keys of admins need the admin scope to manage the users and groups", func() {
+			createAPIKey := func(scopes []string) string {
+				resp, err := resty.R().SetBasicAuth(adminUser, adminPassword).
+					SetBody(api.APIKeyPayload{Label: "test", Scopes: scopes}).
+					Post(baseURL + constants.APIKeyPath)
+				So(err, ShouldBeNil)
+				So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+				var apiKeyResponse struct {
+					APIKey string `json:"apiKey"`
+				}
+
+				err = json.Unmarshal(resp.Body(), &apiKeyResponse)
+				So(err, ShouldBeNil)
+
+				return apiKeyResponse.APIKey
+			}
+
+			apiKey := createAPIKey([]string{"repository:**:read"})
+
+			resp, err := resty.R().SetBasicAuth(adminUser, apiKey).Get(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, apiKey).
+				SetBody(extensions.UserPayload{Username: "mallory", Password: "mallory"}).
+				Post(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, apiKey).SetBody(extensions.GroupPayload{Users: []string{user}}).
+				Put(baseURL + constants.FullMgmtGroups + "/readers")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, apiKey).Get(baseURL + constants.FullMgmtQuotas)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			apiKey = createAPIKey([]string{"admin"})
+
+			resp, err = resty.R().SetBasicAuth(adminUser, apiKey).Get(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		})
+
+		Convey("Admins create, update and delete users", func() {
+			resp, err := resty.R().SetBasicAuth(adminUser, adminPassword).Get(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			users := []string{}
+			err = json.Unmarshal(resp.Body(), &users)
+			So(err, ShouldBeNil)
+			So(users, ShouldResemble, []string{adminUser, user})
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				SetBody(extensions.UserPayload{Username: "alice", Password: "alice1"}).
+				Post(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusCreated)
+
+			// the new user logs in right away
+			resp, err = resty.R().SetBasicAuth("alice", "alice1").Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			htpasswd, err := os.ReadFile(htpasswdPath)
+			So(err, ShouldBeNil)
+			So(string(htpasswd), ShouldContainSubstring, "alice:$argon2id$")
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				SetBody(extensions.UserPayload{Username: "alice", Password: "alice2"}).
+				Post(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusConflict)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				SetBody(extensions.UserPayload{Username: "bad:name", Password: "password"}).
+				Post(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				SetBody(extensions.UserPayload{Username: "bob"}).
+				Post(baseURL + constants.FullMgmtUsers)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				SetBody(extensions.UserPayload{Password: "alice2"}).
+				Put(baseURL + constants.FullMgmtUsers + "/alice")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			resp, err = resty.R().SetBasicAuth("alice", "alice1").Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			resp, err = resty.R().SetBasicAuth("alice", "alice2").Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				SetBody(extensions.UserPayload{Password: "bob"}).
+				Put(baseURL + constants.FullMgmtUsers + "/bob")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				Delete(baseURL + constants.FullMgmtUsers + "/alice")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			resp, err = resty.R().SetBasicAuth("alice", "alice2").Get(baseURL + "/v2/")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusUnauthorized)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				Delete(baseURL + constants.FullMgmtUsers + "/alice")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+			auditLog, err := os.ReadFile(auditPath)
+			So(err, ShouldBeNil)
+			So(string(auditLog), ShouldContainSubstring, `"subject":"admin","action":"createUser","object":"alice"`)
+			So(string(auditLog), ShouldContainSubstring, `"action":"updatePassword","object":"alice"`)
+			So(string(auditLog), ShouldContainSubstring, `"action":"deleteUser","object":"alice"`)
+		})
+
+		Convey("Admins set and delete groups", func() {
+			resp, err := resty.R().SetBasicAuth(user, password).Get(baseURL + "/v2/alpine/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				SetBody(extensions.GroupPayload{Users: []string{user, user}}).
+				Put(baseURL + constants.FullMgmtGroups + "/readers")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			// the members of the group are authorized right away
+			resp, err = resty.R().SetBasicAuth(user, password).Get(baseURL + "/v2/alpine/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).Get(baseURL + constants.FullMgmtGroups)
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			groups := map[string]extensions.GroupPayload{}
+			err = json.Unmarshal(resp.Body(), &groups)
+			So(err, ShouldBeNil)
+			So(groups, ShouldResemble, map[string]extensions.GroupPayload{"readers": {Users: []string{user}}})
+
+			groupsFile, err := os.ReadFile(groupsPath)
+			So(err, ShouldBeNil)
+			So(string(groupsFile), ShouldContainSubstring, `"readers"`)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				Delete(baseURL + constants.FullMgmtGroups + "/readers")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+
+			resp, err = resty.R().SetBasicAuth(user, password).Get(baseURL + "/v2/alpine/tags/list")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+				Delete(baseURL + constants.FullMgmtGroups + "/readers")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+			resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).SetBody("{").
+				Put(baseURL + constants.FullMgmtGroups + "/readers")
+			So(err, ShouldBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusBadRequest)
+		})
+	})
+
+	Convey("Verify the groups are not managed without a groups file", t, func() {
+		adminUser, adminPassword := "admin", "admin"
+
+		htpasswdPath := test.MakeHtpasswdFileFromString(test.GetCredString(adminUser, adminPassword))
+		defer os.Remove(htpasswdPath)
+
+		conf := config.New()
+		port := test.GetFreePort()
+		conf.HTTP.Port = port
+		baseURL := test.GetBaseURL(port)
+
+		conf.HTTP.Auth.HTPasswd.Path = htpasswdPath
+		conf.HTTP.AccessControl = &config.AccessControlConfig{
+			AdminPolicy: config.Policy{
+				Users:   []string{adminUser},
+				Actions: []string{constants.ReadPermission},
+			},
+		}
+		conf.Extensions = &extconf.ExtensionConfig{}
+		conf.Extensions.UI = &extconf.UIConfig{}
+		defaultValue := true
+		conf.Extensions.UI.Enable = &defaultValue
+		conf.Extensions.Search = &extconf.SearchConfig{}
+		conf.Extensions.Search.Enable = &defaultValue
+		conf.Extensions.Search.CVE = nil
+
+		conf.Storage.RootDirectory = t.TempDir()
+
+		ctlr := api.NewController(conf)
+
+		ctlrManager := test.NewControllerManager(ctlr)
+		ctlrManager.StartAndWait(port)
+		defer ctlrManager.StopServer()
+
+		resp, err := resty.R().SetBasicAuth(adminUser, adminPassword).Get(baseURL + constants.FullMgmtGroups)
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+
+		resp, err = resty.R().SetBasicAuth(adminUser, adminPassword).
+			SetBody(extensions.GroupPayload{Users: []string{adminUser}}).
+			Put(baseURL + constants.FullMgmtGroups + "/readers")
+		So(err, ShouldBeNil)
+		So(resp.StatusCode(), ShouldEqual, http.StatusNotFound)
+	})
+}
+
 func TestAllowedMethodsHeaderMgmt(t *testing.T) {
 	defaultVal := true
 
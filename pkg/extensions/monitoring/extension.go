@@ -50,6 +50,22 @@ var (
 		},
 		[]string{"repo"},
 	)
+	storageQuotaUsage = promauto.NewGaugeVec( //nolint: gochecknoglobals
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "storage_quota_usage",
+			Help:      "Usage of each storage quota, in bytes or images",
+		},
+		[]string{"quota", "resource"},
+	)
+	storageQuotaLimit = promauto.NewGaugeVec( //nolint: gochecknoglobals
+		prometheus.GaugeOpts{
+			Namespace: metricsNamespace,
+			Name:      "storage_quota_limit",
+			Help:      "Limit of each storage quota, in bytes or images",
+		},
+		[]string{"quota", "resource"},
+	)
 	uploadCounter = promauto.NewCounterVec( //nolint: gochecknoglobals
 		prometheus.CounterOpts{
 			Namespace: metricsNamespace,
@@ -83,6 +99,23 @@ var (
 		},
 		[]string{"storageName", "lockType"},
 	)
+	ldapGroupsCacheRequests = promauto.NewCounterVec( //nolint: gochecknoglobals
+		prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "ldap_groups_cache_requests_total",
+			Help:      "Total number of lookups of the ldap groups cache, by result (hit or miss)",
+		},
+		[]string{"result"},
+	)
+	ldapRequestLatency = promauto.NewHistogramVec( //nolint: gochecknoglobals
+		prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "ldap_request_latency_seconds",
+			Help:      "Latency of the requests to the ldap servers",
+			Buckets:   GetStorageLatencyBuckets(),
+		},
+		[]string{"operation"},
+	)
 	schedulerGenerators = promauto.NewCounter( //nolint: gochecknoglobals
 		prometheus.CounterOpts{
 			Namespace: metricsNamespace,
@@ -228,6 +261,13 @@ func SetStorageUsage(ms MetricServer, rootDir, repo string) {
 	})
 }
 
+func SetStorageQuota(ms MetricServer, quota, resource string, usage, limit int64) {
+	ms.ForceSendMetric(func() {
+		storageQuotaUsage.WithLabelValues(quota, resource).Set(float64(usage))
+		storageQuotaLimit.WithLabelValues(quota, resource).Set(float64(limit))
+	})
+}
+
 func IncUploadCounter(ms MetricServer, repo string) {
 	ms.SendMetric(func() {
 		uploadCounter.WithLabelValues(repo).Inc()
@@ -246,6 +286,18 @@ func ObserveStorageLockLatency(ms MetricServer, latency time.Duration, storageNa
 	})
 }
 
+func IncLDAPGroupsCacheRequests(ms MetricServer, result string) {
+	ms.SendMetric(func() {
+		ldapGroupsCacheRequests.WithLabelValues(result).Inc()
+	})
+}
+
+func ObserveLDAPRequestLatency(ms MetricServer, latency time.Duration, operation string) {
+	ms.SendMetric(func() {
+		ldapRequestLatency.WithLabelValues(operation).Observe(latency.Seconds())
+	})
+}
+
 func IncSchedulerGenerators(ms MetricServer) {
 	ms.ForceSendMetric(func() {
 		schedulerGenerators.Inc()
@@ -22,8 +22,11 @@ const (
 	repoDownloads       = metricsNamespace + ".repo.downloads"
 	repoUploads         = metricsNamespace + ".repo.uploads"
 	schedulerGenerators = metricsNamespace + ".scheduler.generators"
+	ldapGroupsCache     = metricsNamespace + ".ldap.groups.cache.requests"
 	// Gauge.
 	repoStorageBytes          = metricsNamespace + ".repo.storage.bytes"
+	storageQuotaUsage         = metricsNamespace + ".storage.quota.usage"
+	storageQuotaLimit         = metricsNamespace + ".storage.quota.limit"
 	serverInfo                = metricsNamespace + ".info"
 	schedulerNumWorkers       = metricsNamespace + ".scheduler.workers.total"
 	schedulerWorkers          = metricsNamespace + ".scheduler.workers"
@@ -35,6 +38,7 @@ const (
 	httpMethodLatencySeconds  = metricsNamespace + ".http.method.latency.seconds"
 	storageLockLatencySeconds = metricsNamespace + ".storage.lock.latency.seconds"
 	workersTasksDuration      = metricsNamespace + ".scheduler.workers.tasks.duration.seconds"
+	ldapRequestLatencySeconds = metricsNamespace + ".ldap.request.latency.seconds"
 
 	metricsScrapeTimeout       = 2 * time.Minute
 	metricsScrapeCheckInterval = 30 * time.Second
@@ -253,12 +257,15 @@ func GetCounters() map[string][]string {
 		repoDownloads:       {"repo"},
 		repoUploads:         {"repo"},
 		schedulerGenerators: {},
+		ldapGroupsCache:     {"result"},
 	}
 }
 
 func GetGauges() map[string][]string {
 	return map[string][]string{
 		repoStorageBytes:          {"repo"},
+		storageQuotaUsage:         {"quota", "resource"},
+		storageQuotaLimit:         {"quota", "resource"},
 		serverInfo:                {"commit", "binaryType", "goVersion", "version"},
 		schedulerNumWorkers:       {},
 		schedulerGeneratorsStatus: {"priority", "state"},
@@ -278,6 +285,7 @@ func GetHistograms() map[string][]string {
 		httpMethodLatencySeconds:  {"method"},
 		storageLockLatencySeconds: {"storageName", "lockType"},
 		workersTasksDuration:      {"name"},
+		ldapRequestLatencySeconds: {"operation"},
 	}
 }
 
@@ -542,6 +550,24 @@ func SetStorageUsage(ms MetricServer, rootDir, repo string) {
 	ms.ForceSendMetric(storage)
 }
 
+func SetStorageQuota(ms MetricServer, quota, resource string, usage, limit int64) {
+	quotaUsage := GaugeValue{
+		Name:        storageQuotaUsage,
+		Value:       float64(usage),
+		LabelNames:  []string{"quota", "resource"},
+		LabelValues: []string{quota, resource},
+	}
+	ms.ForceSendMetric(quotaUsage)
+
+	quotaLimit := GaugeValue{
+		Name:        storageQuotaLimit,
+		Value:       float64(limit),
+		LabelNames:  []string{"quota", "resource"},
+		LabelValues: []string{quota, resource},
+	}
+	ms.ForceSendMetric(quotaLimit)
+}
+
 func SetServerInfo(ms MetricServer, lvs ...string) {
 	info := GaugeValue{
 		Name:        serverInfo,
@@ -563,13 +589,32 @@ func ObserveStorageLockLatency(ms MetricServer, latency time.Duration, storageNa
 	ms.SendMetric(h)
 }
 
+func IncLDAPGroupsCacheRequests(ms MetricServer, result string) {
+	cacheCounter := CounterValue{
+		Name:        ldapGroupsCache,
+		LabelNames:  []string{"result"},
+		LabelValues: []string{result},
+	}
+	ms.SendMetric(cacheCounter)
+}
+
+func ObserveLDAPRequestLatency(ms MetricServer, latency time.Duration, operation string) {
+	h := HistogramValue{
+		Name:        ldapRequestLatencySeconds,
+		Sum:         latency.Seconds(), // convenient temporary store for Histogram latency value
+		LabelNames:  []string{"operation"},
+		LabelValues: []string{operation},
+	}
+	ms.SendMetric(h)
+}
+
 func GetMaxIdleScrapeInterval() time.Duration {
 	return metricsScrapeTimeout + metricsScrapeCheckInterval
 }
 
 func GetBuckets(metricName string) []float64 {
 	switch metricName {
-	case storageLockLatencySeconds:
+	case storageLockLatencySeconds, ldapRequestLatencySeconds:
 		return GetStorageLatencyBuckets()
 	default:
 		return GetDefaultBuckets()
@@ -4,10 +4,12 @@
 package monitoring_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path"
 	"testing"
@@ -310,6 +312,45 @@ func TestMetricsAuthorization(t *testing.T) {
 			So(resp, ShouldNotBeNil)
 			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
 		})
+		Convey("with basic auth: authorization webhook in accessControl", func() {
+			policyEngine := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+				var authzRequest api.AuthzWebhookRequest
+
+				_ = json.NewDecoder(request.Body).Decode(&authzRequest)
+
+				allow := authzRequest.Type == api.AuthzWebhookMetricsResource && authzRequest.User == metricsuser
+
+				_ = json.NewEncoder(response).Encode(api.AuthzWebhookResponse{Allow: allow})
+			}))
+			defer policyEngine.Close()
+
+			// the webhook decides instead of the metrics users
+			conf.HTTP.AccessControl = &config.AccessControlConfig{
+				Metrics: config.Metrics{
+					Users: []string{username},
+				},
+				Webhook: &config.AuthzWebhookConfig{URL: policyEngine.URL},
+			}
+			ctlr := api.NewController(conf)
+			ctlr.Config.Storage.RootDirectory = t.TempDir()
+
+			cm := test.NewControllerManager(ctlr)
+			cm.StartAndWait(port)
+			defer cm.StopServer()
+
+			client := resty.New()
+			client.SetBasicAuth(username, password)
+			resp, err := client.R().Get(baseURL + "/metrics")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusForbidden)
+
+			client.SetBasicAuth(metricsuser, metricspass)
+			resp, err = client.R().Get(baseURL + "/metrics")
+			So(err, ShouldBeNil)
+			So(resp, ShouldNotBeNil)
+			So(resp.StatusCode(), ShouldEqual, http.StatusOK)
+		})
 		Convey("with basic auth: with anonymousPolicy in accessControl", func() {
 			conf.HTTP.AccessControl = &config.AccessControlConfig{
 				Metrics: config.Metrics{
@@ -201,6 +201,7 @@ type ComplexityRoot struct {
 		Referrers               func(childComplexity int, repo string, digest string, typeArg []string) int
 		RepoListWithNewestImage func(childComplexity int, requestedPage *PageInput) int
 		StarredRepos            func(childComplexity int, requestedPage *PageInput) int
+		TagHistory              func(childComplexity int, repo string, tag string) int
 	}
 
 	Referrer struct {
@@ -235,6 +236,15 @@ type ComplexityRoot struct {
 		IsTrusted func(childComplexity int) int
 		Tool      func(childComplexity int) int
 	}
+
+	TagHistoryEntry struct {
+		Action    func(childComplexity int) int
+		Client    func(childComplexity int) int
+		Digest    func(childComplexity int) int
+		MediaType func(childComplexity int) int
+		Timestamp func(childComplexity int) int
+		User      func(childComplexity int) int
+	}
 }
 
 type QueryResolver interface {
@@ -251,6 +261,7 @@ type QueryResolver interface {
 	BaseImageList(ctx context.Context, image string, digest *string, requestedPage *PageInput) (*PaginatedImagesResult, error)
 	Image(ctx context.Context, image string) (*ImageSummary, error)
 	Referrers(ctx context.Context, repo string, digest string, typeArg []string) ([]*Referrer, error)
+	TagHistory(ctx context.Context, repo string, tag string) ([]*TagHistoryEntry, error)
 	StarredRepos(ctx context.Context, requestedPage *PageInput) (*PaginatedReposResult, error)
 	BookmarkedRepos(ctx context.Context, requestedPage *PageInput) (*PaginatedReposResult, error)
 }
@@ -1077,6 +1088,18 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.Query.StarredRepos(childComplexity, args["requestedPage"].(*PageInput)), true
 
+	case "Query.TagHistory":
+		if e.complexity.Query.TagHistory == nil {
+			break
+		}
+
+		args, err := ec.field_Query_TagHistory_args(context.TODO(), rawArgs)
+		if err != nil {
+			return 0, false
+		}
+
+		return e.complexity.Query.TagHistory(childComplexity, args["repo"].(string), args["tag"].(string)), true
+
 	case "Referrer.Annotations":
 		if e.complexity.Referrer.Annotations == nil {
 			break
@@ -1224,6 +1247,48 @@ func (e *executableSchema) Complexity(typeName, field string, childComplexity in
 
 		return e.complexity.SignatureSummary.Tool(childComplexity), true
 
+	case "TagHistoryEntry.Action":
+		if e.complexity.TagHistoryEntry.Action == nil {
+			break
+		}
+
+		return e.complexity.TagHistoryEntry.Action(childComplexity), true
+
+	case "TagHistoryEntry.Client":
+		if e.complexity.TagHistoryEntry.Client == nil {
+			break
+		}
+
+		return e.complexity.TagHistoryEntry.Client(childComplexity), true
+
+	case "TagHistoryEntry.Digest":
+		if e.complexity.TagHistoryEntry.Digest == nil {
+			break
+		}
+
+		return e.complexity.TagHistoryEntry.Digest(childComplexity), true
+
+	case "TagHistoryEntry.MediaType":
+		if e.complexity.TagHistoryEntry.MediaType == nil {
+			break
+		}
+
+		return e.complexity.TagHistoryEntry.MediaType(childComplexity), true
+
+	case "TagHistoryEntry.Timestamp":
+		if e.complexity.TagHistoryEntry.Timestamp == nil {
+			break
+		}
+
+		return e.complexity.TagHistoryEntry.Timestamp(childComplexity), true
+
+	case "TagHistoryEntry.User":
+		if e.complexity.TagHistoryEntry.User == nil {
+			break
+		}
+
+		return e.complexity.TagHistoryEntry.User(childComplexity), true
+
 	}
 	return 0, false
 }
@@ -1831,6 +1896,36 @@ type Referrer {
     Annotations:  [Annotation]!
 }
 
+"""
+A change of the manifest a tag points to
+"""
+type TagHistoryEntry {
+    """
+    Either ` + "`" + `updated` + "`" + ` when the tag was pushed or ` + "`" + `deleted` + "`" + ` when it was removed
+    """
+    Action: String
+    """
+    Digest of the manifest the tag pointed to
+    """
+    Digest: String
+    """
+    Media type of the manifest the tag pointed to
+    """
+    MediaType: String
+    """
+    Timestamp of the change
+    """
+    Timestamp: Time
+    """
+    User who made the change
+    """
+    User: String
+    """
+    Client (user agent) used to make the change
+    """
+    Client: String
+}
+
 """
 Contains details about the OS and architecture of the image
 """
@@ -2196,6 +2291,16 @@ type Query {
         type: [String!]
     ): [Referrer]!
 
+    """
+    Returns the changes of the manifest a tag points to, from the oldest to the newest
+    """
+    TagHistory(
+        "Repository name"
+        repo: String!,
+        "Tag name"
+        tag: String!
+    ): [TagHistoryEntry!]!
+
     """
     Receive RepoSummaries of repos starred by current user
     """
@@ -3169,6 +3274,57 @@ func (ec *executionContext) field_Query_StarredRepos_argsRequestedPage(
 	return zeroVal, nil
 }
 
+func (ec *executionContext) field_Query_TagHistory_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
+	var err error
+	args := map[string]any{}
+	arg0, err := ec.field_Query_TagHistory_argsRepo(ctx, rawArgs)
+	if err != nil {
+		return nil, err
+	}
+	args["repo"] = arg0
+	arg1, err := ec.field_Query_TagHistory_argsTag(ctx, rawArgs)
+	if err != nil {
+		return nil, err
+	}
+	args["tag"] = arg1
+	return args, nil
+}
+func (ec *executionContext) field_Query_TagHistory_argsRepo(
+	ctx context.Context,
+	rawArgs map[string]any,
+) (string, error) {
+	if _, ok := rawArgs["repo"]; !ok {
+		var zeroVal string
+		return zeroVal, nil
+	}
+
+	ctx = graphql.WithPathContext(ctx, graphql.NewPathWithField("repo"))
+	if tmp, ok := rawArgs["repo"]; ok {
+		return ec.unmarshalNString2string(ctx, tmp)
+	}
+
+	var zeroVal string
+	return zeroVal, nil
+}
+
+func (ec *executionContext) field_Query_TagHistory_argsTag(
+	ctx context.Context,
+	rawArgs map[string]any,
+) (string, error) {
+	if _, ok := rawArgs["tag"]; !ok {
+		var zeroVal string
+		return zeroVal, nil
+	}
+
+	ctx = graphql.WithPathContext(ctx, graphql.NewPathWithField("tag"))
+	if tmp, ok := rawArgs["tag"]; ok {
+		return ec.unmarshalNString2string(ctx, tmp)
+	}
+
+	var zeroVal string
+	return zeroVal, nil
+}
+
 func (ec *executionContext) field_Query___type_args(ctx context.Context, rawArgs map[string]any) (map[string]any, error) {
 	var err error
 	args := map[string]any{}
@@ -8257,6 +8413,75 @@ func (ec *executionContext) fieldContext_Query_Referrers(ctx context.Context, fi
 	return fc, nil
 }
 
+func (ec *executionContext) _Query_TagHistory(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_Query_TagHistory(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (any, error) {
+		ctx = rctx // use context from middleware stack in children
+		return ec.resolvers.Query().TagHistory(rctx, fc.Args["repo"].(string), fc.Args["tag"].(string))
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		if !graphql.HasFieldError(ctx, fc) {
+			ec.Errorf(ctx, "must not be null")
+		}
+		return graphql.Null
+	}
+	res := resTmp.([]*TagHistoryEntry)
+	fc.Result = res
+	return ec.marshalNTagHistoryEntry2ᚕᚖzotregistryᚗdevᚋzotᚋpkgᚋextensionsᚋsearchᚋgql_generatedᚐTagHistoryEntryᚄ(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_Query_TagHistory(ctx context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "Query",
+		Field:      field,
+		IsMethod:   true,
+		IsResolver: true,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			switch field.Name {
+			case "Action":
+				return ec.fieldContext_TagHistoryEntry_Action(ctx, field)
+			case "Digest":
+				return ec.fieldContext_TagHistoryEntry_Digest(ctx, field)
+			case "MediaType":
+				return ec.fieldContext_TagHistoryEntry_MediaType(ctx, field)
+			case "Timestamp":
+				return ec.fieldContext_TagHistoryEntry_Timestamp(ctx, field)
+			case "User":
+				return ec.fieldContext_TagHistoryEntry_User(ctx, field)
+			case "Client":
+				return ec.fieldContext_TagHistoryEntry_Client(ctx, field)
+			}
+			return nil, fmt.Errorf("no field named %q was found under type TagHistoryEntry", field.Name)
+		},
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = ec.Recover(ctx, r)
+			ec.Error(ctx, err)
+		}
+	}()
+	ctx = graphql.WithFieldContext(ctx, fc)
+	if fc.Args, err = ec.field_Query_TagHistory_args(ctx, field.ArgumentMap(ec.Variables)); err != nil {
+		ec.Error(ctx, err)
+		return fc, err
+	}
+	return fc, nil
+}
+
 func (ec *executionContext) _Query_StarredRepos(ctx context.Context, field graphql.CollectedField) (ret graphql.Marshaler) {
 	fc, err := ec.fieldContext_Query_StarredRepos(ctx, field)
 	if err != nil {
@@ -9506,6 +9731,252 @@ func (ec *executionContext) fieldContext_SignatureSummary_Author(_ context.Conte
 	return fc, nil
 }
 
+func (ec *executionContext) _TagHistoryEntry_Action(ctx context.Context, field graphql.CollectedField, obj *TagHistoryEntry) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_TagHistoryEntry_Action(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (any, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Action, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_TagHistoryEntry_Action(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TagHistoryEntry",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TagHistoryEntry_Digest(ctx context.Context, field graphql.CollectedField, obj *TagHistoryEntry) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_TagHistoryEntry_Digest(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (any, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Digest, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_TagHistoryEntry_Digest(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TagHistoryEntry",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TagHistoryEntry_MediaType(ctx context.Context, field graphql.CollectedField, obj *TagHistoryEntry) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_TagHistoryEntry_MediaType(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (any, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.MediaType, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_TagHistoryEntry_MediaType(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TagHistoryEntry",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TagHistoryEntry_Timestamp(ctx context.Context, field graphql.CollectedField, obj *TagHistoryEntry) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_TagHistoryEntry_Timestamp(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (any, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Timestamp, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*time.Time)
+	fc.Result = res
+	return ec.marshalOTime2ᚖtimeᚐTime(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_TagHistoryEntry_Timestamp(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TagHistoryEntry",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type Time does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TagHistoryEntry_User(ctx context.Context, field graphql.CollectedField, obj *TagHistoryEntry) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_TagHistoryEntry_User(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (any, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.User, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_TagHistoryEntry_User(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TagHistoryEntry",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
+func (ec *executionContext) _TagHistoryEntry_Client(ctx context.Context, field graphql.CollectedField, obj *TagHistoryEntry) (ret graphql.Marshaler) {
+	fc, err := ec.fieldContext_TagHistoryEntry_Client(ctx, field)
+	if err != nil {
+		return graphql.Null
+	}
+	ctx = graphql.WithFieldContext(ctx, fc)
+	defer func() {
+		if r := recover(); r != nil {
+			ec.Error(ctx, ec.Recover(ctx, r))
+			ret = graphql.Null
+		}
+	}()
+	resTmp, err := ec.ResolverMiddleware(ctx, func(rctx context.Context) (any, error) {
+		ctx = rctx // use context from middleware stack in children
+		return obj.Client, nil
+	})
+	if err != nil {
+		ec.Error(ctx, err)
+		return graphql.Null
+	}
+	if resTmp == nil {
+		return graphql.Null
+	}
+	res := resTmp.(*string)
+	fc.Result = res
+	return ec.marshalOString2ᚖstring(ctx, field.Selections, res)
+}
+
+func (ec *executionContext) fieldContext_TagHistoryEntry_Client(_ context.Context, field graphql.CollectedField) (fc *graphql.FieldContext, err error) {
+	fc = &graphql.FieldContext{
+		Object:     "TagHistoryEntry",
+		Field:      field,
+		IsMethod:   false,
+		IsResolver: false,
+		Child: func(ctx context.Context, field graphql.CollectedField) (*graphql.FieldContext, error) {
+			return nil, errors.New("field of type String does not have child fields")
+		},
+	}
+	return fc, nil
+}
+
 func (ec *executionContext) ___Directive_name(ctx context.Context, field graphql.CollectedField, obj *introspection.Directive) (ret graphql.Marshaler) {
 	fc, err := ec.fieldContext___Directive_name(ctx, field)
 	if err != nil {
@@ -12727,6 +13198,28 @@ func (ec *executionContext) _Query(ctx context.Context, sel ast.SelectionSet) gr
 					func(ctx context.Context) graphql.Marshaler { return innerFunc(ctx, out) })
 			}
 
+			out.Concurrently(i, func(ctx context.Context) graphql.Marshaler { return rrm(innerCtx) })
+		case "TagHistory":
+			field := field
+
+			innerFunc := func(ctx context.Context, fs *graphql.FieldSet) (res graphql.Marshaler) {
+				defer func() {
+					if r := recover(); r != nil {
+						ec.Error(ctx, ec.Recover(ctx, r))
+					}
+				}()
+				res = ec._Query_TagHistory(ctx, field)
+				if res == graphql.Null {
+					atomic.AddUint32(&fs.Invalids, 1)
+				}
+				return res
+			}
+
+			rrm := func(ctx context.Context) graphql.Marshaler {
+				return ec.OperationContext.RootResolverMiddleware(ctx,
+					func(ctx context.Context) graphql.Marshaler { return innerFunc(ctx, out) })
+			}
+
 			out.Concurrently(i, func(ctx context.Context) graphql.Marshaler { return rrm(innerCtx) })
 		case "StarredRepos":
 			field := field
@@ -12984,6 +13477,52 @@ func (ec *executionContext) _SignatureSummary(ctx context.Context, sel ast.Selec
 	return out
 }
 
+var tagHistoryEntryImplementors = []string{"TagHistoryEntry"}
+
+func (ec *executionContext) _TagHistoryEntry(ctx context.Context, sel ast.SelectionSet, obj *TagHistoryEntry) graphql.Marshaler {
+	fields := graphql.CollectFields(ec.OperationContext, sel, tagHistoryEntryImplementors)
+
+	out := graphql.NewFieldSet(fields)
+	deferred := make(map[string]*graphql.FieldSet)
+	for i, field := range fields {
+		switch field.Name {
+		case "__typename":
+			out.Values[i] = graphql.MarshalString("TagHistoryEntry")
+		case "Action":
+			out.Values[i] = ec._TagHistoryEntry_Action(ctx, field, obj)
+		case "Digest":
+			out.Values[i] = ec._TagHistoryEntry_Digest(ctx, field, obj)
+		case "MediaType":
+			out.Values[i] = ec._TagHistoryEntry_MediaType(ctx, field, obj)
+		case "Timestamp":
+			out.Values[i] = ec._TagHistoryEntry_Timestamp(ctx, field, obj)
+		case "User":
+			out.Values[i] = ec._TagHistoryEntry_User(ctx, field, obj)
+		case "Client":
+			out.Values[i] = ec._TagHistoryEntry_Client(ctx, field, obj)
+		default:
+			panic("unknown field " + strconv.Quote(field.Name))
+		}
+	}
+	out.Dispatch(ctx)
+	if out.Invalids > 0 {
+		return graphql.Null
+	}
+
+	atomic.AddInt32(&ec.deferred, int32(len(deferred)))
+
+	for label, dfs := range deferred {
+		ec.processDeferredGroup(graphql.DeferredGroup{
+			Label:    label,
+			Path:     graphql.GetPath(ctx),
+			FieldSet: dfs,
+			Context:  ctx,
+		})
+	}
+
+	return out
+}
+
 var __DirectiveImplementors = []string{"__Directive"}
 
 func (ec *executionContext) ___Directive(ctx context.Context, sel ast.SelectionSet, obj *introspection.Directive) graphql.Marshaler {
@@ -13651,6 +14190,60 @@ func (ec *executionContext) marshalNString2string(ctx context.Context, sel ast.S
 	return res
 }
 
+func (ec *executionContext) marshalNTagHistoryEntry2ᚕᚖzotregistryᚗdevᚋzotᚋpkgᚋextensionsᚋsearchᚋgql_generatedᚐTagHistoryEntryᚄ(ctx context.Context, sel ast.SelectionSet, v []*TagHistoryEntry) graphql.Marshaler {
+	ret := make(graphql.Array, len(v))
+	var wg sync.WaitGroup
+	isLen1 := len(v) == 1
+	if !isLen1 {
+		wg.Add(len(v))
+	}
+	for i := range v {
+		i := i
+		fc := &graphql.FieldContext{
+			Index:  &i,
+			Result: &v[i],
+		}
+		ctx := graphql.WithFieldContext(ctx, fc)
+		f := func(i int) {
+			defer func() {
+				if r := recover(); r != nil {
+					ec.Error(ctx, ec.Recover(ctx, r))
+					ret = nil
+				}
+			}()
+			if !isLen1 {
+				defer wg.Done()
+			}
+			ret[i] = ec.marshalNTagHistoryEntry2ᚖzotregistryᚗdevᚋzotᚋpkgᚋextensionsᚋsearchᚋgql_generatedᚐTagHistoryEntry(ctx, sel, v[i])
+		}
+		if isLen1 {
+			f(i)
+		} else {
+			go f(i)
+		}
+
+	}
+	wg.Wait()
+
+	for _, e := range ret {
+		if e == graphql.Null {
+			return graphql.Null
+		}
+	}
+
+	return ret
+}
+
+func (ec *executionContext) marshalNTagHistoryEntry2ᚖzotregistryᚗdevᚋzotᚋpkgᚋextensionsᚋsearchᚋgql_generatedᚐTagHistoryEntry(ctx context.Context, sel ast.SelectionSet, v *TagHistoryEntry) graphql.Marshaler {
+	if v == nil {
+		if !graphql.HasFieldError(ctx, graphql.GetFieldContext(ctx)) {
+			ec.Errorf(ctx, "the requested element is null which the schema does not allow")
+		}
+		return graphql.Null
+	}
+	return ec._TagHistoryEntry(ctx, sel, v)
+}
+
 func (ec *executionContext) marshalN__Directive2githubᚗcomᚋ99designsᚋgqlgenᚋgraphqlᚋintrospectionᚐDirective(ctx context.Context, sel ast.SelectionSet, v introspection.Directive) graphql.Marshaler {
 	return ec.___Directive(ctx, sel, &v)
 }
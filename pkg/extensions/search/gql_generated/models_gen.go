@@ -379,6 +379,22 @@ type SignatureSummary struct {
 	Author *string `json:"Author,omitempty"`
 }
 
+// A change of the manifest a tag points to
+type TagHistoryEntry struct {
+	// Either `updated` when the tag was pushed or `deleted` when it was removed
+	Action *string `json:"Action,omitempty"`
+	// Digest of the manifest the tag pointed to
+	Digest *string `json:"Digest,omitempty"`
+	// Media type of the manifest the tag pointed to
+	MediaType *string `json:"MediaType,omitempty"`
+	// Timestamp of the change
+	Timestamp *time.Time `json:"Timestamp,omitempty"`
+	// User who made the change
+	User *string `json:"User,omitempty"`
+	// Client (user agent) used to make the change
+	Client *string `json:"Client,omitempty"`
+}
+
 // All sort criteria usable with pagination, some of these criteria applies only
 // to certain queries. For example sort by severity is available for CVEs but not
 // for repositories
@@ -1562,6 +1562,43 @@ func getImageList(ctx context.Context, repo string, metaDB mTypes.MetaDB, cveInf
 	}, nil
 }
 
+func getTagHistory(ctx context.Context, metaDB mTypes.MetaDB, repo, tag string, log log.Logger,
+) ([]*gql_generated.TagHistoryEntry, error) {
+	if ok, err := reqCtx.RepoIsUserAvailable(ctx, repo); !ok || err != nil {
+		log.Info().Err(err).Str("repository", repo).Bool("availability", ok).Str("component", "graphql").
+			Msg("repo user availability")
+
+		return []*gql_generated.TagHistoryEntry{}, nil //nolint:nilerr // don't give details to a potential attacker
+	}
+
+	entries, err := metaDB.GetTagHistory(ctx, repo, tag)
+	if err != nil {
+		if errors.Is(err, zerr.ErrTagHistoryNotFound) {
+			return []*gql_generated.TagHistoryEntry{}, nil
+		}
+
+		log.Error().Err(err).Str("repository", repo).Str("tag", tag).Str("component", "graphql").
+			Msg("failed to get tag history")
+
+		return []*gql_generated.TagHistoryEntry{}, err
+	}
+
+	results := make([]*gql_generated.TagHistoryEntry, 0, len(entries))
+
+	for _, entry := range entries {
+		results = append(results, &gql_generated.TagHistoryEntry{
+			Action:    ref(entry.Action),
+			Digest:    ref(entry.Digest),
+			MediaType: ref(entry.MediaType),
+			Timestamp: ref(entry.Timestamp),
+			User:      ref(entry.User),
+			Client:    ref(entry.Client),
+		})
+	}
+
+	return results, nil
+}
+
 func getReferrers(metaDB mTypes.MetaDB, repo string, referredDigest string, artifactTypes []string,
 	log log.Logger,
 ) ([]*gql_generated.Referrer, error) {
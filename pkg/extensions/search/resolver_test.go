@@ -15,6 +15,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 
 	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/constants"
 	"zotregistry.dev/zot/pkg/common"
 	"zotregistry.dev/zot/pkg/extensions/search/convert"
 	cveinfo "zotregistry.dev/zot/pkg/extensions/search/cve"
@@ -398,6 +399,53 @@ func TestGetStarredRepos(t *testing.T) {
 	})
 }
 
+func TestGetTagHistory(t *testing.T) {
+	Convey("getTagHistory", t, func() {
+		userAc := reqCtx.NewUserAccessControl()
+		userAc.SetUsername("user")
+		userAc.SetGlobPatterns(constants.ReadPermission, map[string]bool{"repo": true})
+
+		ctx := userAc.DeriveContext(context.Background())
+		timestamp := time.Now()
+
+		mockMetaDB := mocks.MetaDBMock{
+			GetTagHistoryFn: func(ctx context.Context, repo, tag string) ([]mTypes.TagHistoryEntry, error) {
+				switch tag {
+				case "missing":
+					return nil, zerr.ErrTagHistoryNotFound
+				case "error":
+					return nil, ErrTestError
+				default:
+					return []mTypes.TagHistoryEntry{
+						{Action: mTypes.TagUpdated, Digest: "digest", Timestamp: timestamp, User: "user", Client: "client"},
+					}, nil
+				}
+			},
+		}
+
+		history, err := getTagHistory(ctx, mockMetaDB, "repo", "tag", log.NewLogger("debug", ""))
+		So(err, ShouldBeNil)
+		So(history, ShouldHaveLength, 1)
+		So(*history[0].Action, ShouldEqual, mTypes.TagUpdated)
+		So(*history[0].Digest, ShouldEqual, "digest")
+		So(*history[0].Timestamp, ShouldEqual, timestamp)
+		So(*history[0].User, ShouldEqual, "user")
+		So(*history[0].Client, ShouldEqual, "client")
+
+		history, err = getTagHistory(ctx, mockMetaDB, "repo", "missing", log.NewLogger("debug", ""))
+		So(err, ShouldBeNil)
+		So(history, ShouldBeEmpty)
+
+		_, err = getTagHistory(ctx, mockMetaDB, "repo", "error", log.NewLogger("debug", ""))
+		So(err, ShouldNotBeNil)
+
+		// the history of repos the user can not read is not disclosed
+		history, err = getTagHistory(ctx, mockMetaDB, "other", "tag", log.NewLogger("debug", ""))
+		So(err, ShouldBeNil)
+		So(history, ShouldBeEmpty)
+	})
+}
+
 func getTestRepoMetaWithImages(repo string, images []Image) mTypes.RepoMeta {
 	tags := map[mTypes.Tag]mTypes.Descriptor{"": {}}
 	statistics := map[mTypes.Tag]mTypes.DescriptorStatistics{"": {}}
@@ -159,6 +159,11 @@ func (r *queryResolver) Referrers(ctx context.Context, repo string, digest strin
 	return referrers, nil
 }
 
+// TagHistory is the resolver for the TagHistory field.
+func (r *queryResolver) TagHistory(ctx context.Context, repo string, tag string) ([]*gql_generated.TagHistoryEntry, error) {
+	return getTagHistory(ctx, r.metaDB, repo, tag, r.log)
+}
+
 // StarredRepos is the resolver for the StarredRepos field.
 func (r *queryResolver) StarredRepos(ctx context.Context, requestedPage *gql_generated.PageInput) (*gql_generated.PaginatedReposResult, error) {
 	return getStarredRepos(ctx, r.cveInfo, r.log, requestedPage, r.metaDB)
@@ -6348,7 +6348,7 @@ func TestMetaDBWhenDeletingImages(t *testing.T) {
 				}
 
 				ctlr.MetaDB = mocks.MetaDBMock{
-					RemoveRepoReferenceFn: func(repo, reference string, manifestDigest godigest.Digest,
+					RemoveRepoReferenceFn: func(ctx context.Context, repo, reference string, manifestDigest godigest.Digest,
 					) error {
 						return ErrTestError
 					},
@@ -33,6 +33,7 @@ type DestinationRegistry struct {
 	storeController storage.StoreController
 	tempStorage     OciLayoutStorage
 	metaDB          mTypes.MetaDB
+	notifier        mTypes.ManifestNotifier
 	log             log.Logger
 }
 
@@ -40,12 +41,14 @@ func NewDestinationRegistry(
 	storeController storage.StoreController, // local store controller
 	tempStoreController storage.StoreController, // temp store controller
 	metaDB mTypes.MetaDB,
+	notifier mTypes.ManifestNotifier, // notified of the synced manifests
 	log log.Logger,
 ) Destination {
 	return &DestinationRegistry{
 		storeController: storeController,
 		tempStorage:     NewOciLayoutStorage(tempStoreController),
 		metaDB:          metaDB,
+		notifier:        notifier,
 		// first we sync from remote (using containers/image copy from docker:// to oci:) to a temp imageStore
 		// then we copy the image from tempStorage to zot's storage using ImageStore APIs
 		log: log,
@@ -136,9 +139,9 @@ func (registry *DestinationRegistry) CommitImage(imageReference types.ImageRefer
 		}
 
 		for _, manifest := range indexManifest.Manifests {
-			tempImageStore.RLock(&lockLatency)
+			tempImageStore.RLockRepo(repo, &lockLatency)
 			manifestBuf, err := tempImageStore.GetBlobContent(repo, manifest.Digest)
-			tempImageStore.RUnlock(&lockLatency)
+			tempImageStore.RUnlockRepo(repo, &lockLatency)
 
 			if err != nil {
 				registry.log.Error().Str("errorType", common.TypeOf(err)).
@@ -178,6 +181,8 @@ func (registry *DestinationRegistry) CommitImage(imageReference types.ImageRefer
 
 			registry.log.Debug().Str("repo", repo).Str("reference", reference).Str("component", "metadb").
 				Msg("successfully set metadata for image")
+
+			registry.notifyManifestUpdated(repo, reference, mediaType, manifestDigest, manifestBlob)
 		}
 	}
 
@@ -186,6 +191,15 @@ func (registry *DestinationRegistry) CommitImage(imageReference types.ImageRefer
 	return nil
 }
 
+// notifyManifestUpdated notifies the synced manifests like the pushed ones, once MetaDB is updated.
+func (registry *DestinationRegistry) notifyManifestUpdated(repo, reference, mediaType string,
+	manifestDigest digest.Digest, body []byte,
+) {
+	if registry.notifier != nil {
+		registry.notifier.OnManifestUpdated(repo, reference, mediaType, manifestDigest, body)
+	}
+}
+
 func (registry *DestinationRegistry) CleanupImage(imageReference types.ImageReference, repo, reference string) error {
 	tmpDir := getTempRootDirFromImageReference(imageReference, repo, reference)
 
@@ -245,6 +259,8 @@ func (registry *DestinationRegistry) copyManifest(repo string, manifestContent [
 		}
 
 		registry.log.Debug().Str("repo", repo).Str("reference", reference).Msg("successfully set metadata for image")
+
+		registry.notifyManifestUpdated(repo, reference, ispec.MediaTypeImageManifest, digest, manifestContent)
 	}
 
 	return nil
@@ -13,8 +13,11 @@ import (
 	"time"
 
 	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/constants"
 	"zotregistry.dev/zot/pkg/common"
 	"zotregistry.dev/zot/pkg/log"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+	"zotregistry.dev/zot/pkg/tracing"
 )
 
 const (
@@ -141,6 +144,9 @@ func (httpClient *Client) SetConfig(config Config) error {
 		return err
 	}
 
+	// propagates the trace context of the syncs to the upstream registry, if tracing is registered
+	client.Transport = tracing.Transport(client.Transport)
+
 	httpClient.client = client
 	httpClient.config = &config
 
@@ -198,8 +204,8 @@ func (httpClient *Client) MakeGetRequest(ctx context.Context, resultPtr interfac
 
 	url.RawQuery = rawQuery
 
-	//nolint: bodyclose,contextcheck
-	resp, body, err := httpClient.makeAndDoRequest(http.MethodGet, mediaType, namespace, url.String())
+	//nolint: bodyclose
+	resp, body, err := httpClient.makeAndDoRequest(ctx, http.MethodGet, mediaType, namespace, url.String())
 	if err != nil {
 		httpClient.log.Error().Err(err).Str("url", url.String()).Str("component", "sync").
 			Str("errorType", common.TypeOf(err)).
@@ -222,7 +228,7 @@ func (httpClient *Client) MakeGetRequest(ctx context.Context, resultPtr interfac
 
 func (httpClient *Client) setupAuth(req *http.Request, namespace string) error {
 	if httpClient.authType == tokenAuth {
-		token, err := httpClient.getToken(req.URL.String(), namespace)
+		token, err := httpClient.getToken(req.Context(), req.URL.String(), namespace)
 		if err != nil {
 			httpClient.log.Error().Err(err).Str("url", req.URL.String()).Str("component", "sync").
 				Str("errorType", common.TypeOf(err)).
@@ -247,6 +253,8 @@ func (httpClient *Client) get(ctx context.Context, url string, mediaType string,
 		return nil, nil, err
 	}
 
+	setRequestID(req)
+
 	if mediaType != "" {
 		req.Header.Set("Accept", mediaType)
 	}
@@ -282,13 +290,15 @@ func (httpClient *Client) doRequest(req *http.Request) (*http.Response, []byte,
 	return resp, body, nil
 }
 
-func (httpClient *Client) makeAndDoRequest(method, mediaType, namespace, urlStr string,
+func (httpClient *Client) makeAndDoRequest(ctx context.Context, method, mediaType, namespace, urlStr string,
 ) (*http.Response, []byte, error) {
-	req, err := http.NewRequest(method, urlStr, nil) //nolint
+	req, err := http.NewRequestWithContext(ctx, method, urlStr, nil) //nolint
 	if err != nil {
 		return nil, nil, err
 	}
 
+	setRequestID(req)
+
 	err = httpClient.setupAuth(req, namespace)
 	if err != nil {
 		// harbor catalog requests return basicAuth by default, even if bearer is used on the rest of endpoints.
@@ -296,7 +306,7 @@ func (httpClient *Client) makeAndDoRequest(method, mediaType, namespace, urlStr
 			httpClient.log.Err(err).Msg("expected bearer auth header, received basic, retrying with basic auth...")
 
 			// try with basic auth
-			return httpClient.get(context.Background(), urlStr, mediaType, true)
+			return httpClient.get(ctx, urlStr, mediaType, true)
 		}
 
 		return nil, nil, err
@@ -323,7 +333,7 @@ func (httpClient *Client) makeAndDoRequest(method, mediaType, namespace, urlStr
 				return nil, nil, err
 			}
 
-			token, err = httpClient.getTokenFromURL(tokenURL.String(), namespace)
+			token, err = httpClient.getTokenFromURL(ctx, tokenURL.String(), namespace)
 			if err != nil {
 				return nil, nil, err
 			}
@@ -337,9 +347,16 @@ func (httpClient *Client) makeAndDoRequest(method, mediaType, namespace, urlStr
 	return resp, body, err
 }
 
-func (httpClient *Client) getTokenFromURL(urlStr, namespace string) (*bearerToken, error) {
+// setRequestID forwards the id of the request which triggered the sync, if any, to the upstream registry.
+func setRequestID(req *http.Request) {
+	if requestID := reqCtx.RequestIDFromContext(req.Context()); requestID != "" {
+		req.Header.Set(constants.RequestIDHeader, requestID)
+	}
+}
+
+func (httpClient *Client) getTokenFromURL(ctx context.Context, urlStr, namespace string) (*bearerToken, error) {
 	//nolint: bodyclose
-	resp, body, err := httpClient.get(context.Background(), urlStr, "", true)
+	resp, body, err := httpClient.get(ctx, urlStr, "", true)
 	if err != nil {
 		return nil, err
 	}
@@ -360,7 +377,7 @@ func (httpClient *Client) getTokenFromURL(urlStr, namespace string) (*bearerToke
 }
 
 // Gets bearer token from Authorization realm.
-func (httpClient *Client) getToken(urlStr, namespace string) (*bearerToken, error) {
+func (httpClient *Client) getToken(ctx context.Context, urlStr, namespace string) (*bearerToken, error) {
 	// first check cache
 	token := httpClient.cache.Get(namespace)
 	if token != nil && !token.isExpired() {
@@ -368,7 +385,7 @@ func (httpClient *Client) getToken(urlStr, namespace string) (*bearerToken, erro
 	}
 
 	//nolint: bodyclose
-	resp, _, err := httpClient.get(context.Background(), urlStr, "", false)
+	resp, _, err := httpClient.get(ctx, urlStr, "", false)
 	if err != nil {
 		return nil, err
 	}
@@ -383,7 +400,7 @@ func (httpClient *Client) getToken(urlStr, namespace string) (*bearerToken, erro
 		return nil, err
 	}
 
-	return httpClient.getTokenFromURL(tokenURL.String(), namespace)
+	return httpClient.getTokenFromURL(ctx, tokenURL.String(), namespace)
 }
 
 func getAuthType(resp *http.Response) authType {
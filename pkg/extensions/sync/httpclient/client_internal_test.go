@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,7 +9,9 @@ import (
 
 	. "github.com/smartystreets/goconvey/convey"
 
+	"zotregistry.dev/zot/pkg/api/constants"
 	"zotregistry.dev/zot/pkg/log"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 )
 
 func TestTokenCache(t *testing.T) {
@@ -151,7 +154,7 @@ func TestClient(t *testing.T) {
 		Convey("Test makeAndDoRequest() fails", func() {
 			client.authType = tokenAuth
 			//nolint: bodyclose
-			_, _, err := client.makeAndDoRequest(http.MethodGet, "application/json", "catalog", server.URL)
+			_, _, err := client.makeAndDoRequest(context.Background(), http.MethodGet, "application/json", "catalog", server.URL)
 			So(err, ShouldNotBeNil)
 		})
 
@@ -165,3 +168,34 @@ func TestClient(t *testing.T) {
 		})
 	})
 }
+
+func TestClientRequestID(t *testing.T) {
+	Convey("Forward the id of the request which triggered the sync", t, func() {
+		requestIDs := make(chan string, 1)
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestIDs <- r.Header.Get(constants.RequestIDHeader)
+
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		client, err := New(Config{
+			URL:       server.URL,
+			TLSVerify: false,
+		}, log.NewLogger("", ""))
+		So(err, ShouldBeNil)
+
+		ctx := reqCtx.WithRequestID(context.Background(), "pull-1")
+
+		_, _, statusCode, err := client.MakeGetRequest(ctx, nil, "application/json", "", "v2", "alpine", "tags", "list")
+		So(err, ShouldBeNil)
+		So(statusCode, ShouldEqual, http.StatusOK)
+		So(<-requestIDs, ShouldEqual, "pull-1")
+
+		// nothing to forward
+		_, _, _, err = client.MakeGetRequest(context.Background(), nil, "application/json", "", "v2", "alpine")
+		So(err, ShouldBeNil)
+		So(<-requestIDs, ShouldBeEmpty)
+	})
+}
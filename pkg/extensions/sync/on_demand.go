@@ -14,6 +14,8 @@ import (
 	zerr "zotregistry.dev/zot/errors"
 	"zotregistry.dev/zot/pkg/common"
 	"zotregistry.dev/zot/pkg/log"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+	"zotregistry.dev/zot/pkg/tracing"
 )
 
 type request struct {
@@ -45,7 +47,13 @@ func (onDemand *BaseOnDemand) Add(service Service) {
 	onDemand.services = append(onDemand.services, service)
 }
 
-func (onDemand *BaseOnDemand) SyncImage(ctx context.Context, repo, reference string) error {
+func (onDemand *BaseOnDemand) SyncImage(ctx context.Context, repo, reference string) (err error) {
+	ctx, span := tracing.Start(ctx, "Sync.SyncImage", tracing.RepositoryKey.String(repo),
+		tracing.ReferenceKey.String(reference))
+	defer func() { tracing.End(span, err) }()
+
+	logger := log.Ctx(ctx, onDemand.log)
+
 	req := request{
 		repo:      repo,
 		reference: reference,
@@ -53,7 +61,7 @@ func (onDemand *BaseOnDemand) SyncImage(ctx context.Context, repo, reference str
 
 	val, found := onDemand.requestStore.Load(req)
 	if found {
-		onDemand.log.Info().Str("repo", repo).Str("reference", reference).
+		logger.Info().Str("repo", repo).Str("reference", reference).
 			Msg("image already demanded, waiting on channel")
 
 		syncResult, _ := val.(chan error)
@@ -106,6 +114,10 @@ func (onDemand *BaseOnDemand) SyncReference(ctx context.Context, repo string,
 }
 
 func (onDemand *BaseOnDemand) syncImage(ctx context.Context, repo, reference string, syncResult chan error) {
+	// the logs of the sync carry the id of the request which demanded the image
+	logger := log.Ctx(ctx, onDemand.log)
+	requestID := reqCtx.RequestIDFromContext(ctx)
+
 	var err error
 	for serviceID, service := range onDemand.services {
 		err = service.SetNextAvailableURL()
@@ -149,22 +161,24 @@ func (onDemand *BaseOnDemand) syncImage(ctx context.Context, repo, reference str
 					// remove image after syncing
 					defer func() {
 						onDemand.requestStore.Delete(req)
-						onDemand.log.Info().Str("repo", repo).Str("reference", reference).
+						logger.Info().Str("repo", repo).Str("reference", reference).
 							Msg("sync routine for image exited")
 					}()
 
-					onDemand.log.Info().Str("repo", repo).Str(reference, "reference").Str("err", err.Error()).
+					logger.Info().Str("repo", repo).Str(reference, "reference").Str("err", err.Error()).
 						Str("component", "sync").Msg("starting routine to copy image, because of error")
 
 					time.Sleep(retryOptions.Delay)
 
 					// retrying in background, can't use the same context which should be cancelled by now.
-					if err = retry.RetryIfNecessary(context.Background(), func() error {
-						err := service.SyncImage(context.Background(), repo, reference)
+					retryCtx := reqCtx.WithRequestID(context.Background(), requestID)
+
+					if err = retry.RetryIfNecessary(retryCtx, func() error {
+						err := service.SyncImage(retryCtx, repo, reference)
 
 						return err
 					}, retryOptions); err != nil {
-						onDemand.log.Error().Str("errorType", common.TypeOf(err)).Str("repo", repo).Str("reference", reference).
+						logger.Error().Str("errorType", common.TypeOf(err)).Str("repo", repo).Str("reference", reference).
 							Err(err).Str("component", "sync").Msg("failed to copy image")
 					}
 				}(service)
@@ -50,6 +50,7 @@ func New(
 	tmpDir string,
 	storeController storage.StoreController,
 	metadb mTypes.MetaDB,
+	notifier mTypes.ManifestNotifier,
 	log log.Logger,
 ) (*BaseService, error) {
 	service := &BaseService{}
@@ -103,7 +104,7 @@ func New(
 
 	if len(tmpDir) == 0 {
 		// first it will sync in tmpDir then it will move everything into local ImageStore
-		service.destination = NewDestinationRegistry(storeController, storeController, metadb, log)
+		service.destination = NewDestinationRegistry(storeController, storeController, metadb, notifier, log)
 	} else {
 		// first it will sync under /rootDir/reponame/.sync/ then it will move everything into local ImageStore
 		service.destination = NewDestinationRegistry(
@@ -112,6 +113,7 @@ func New(
 				DefaultStore: getImageStore(tmpDir, log),
 			},
 			metadb,
+			notifier,
 			log,
 		)
 	}
@@ -332,6 +334,8 @@ func (service *BaseService) GetNextRepo(lastRepo string) (string, error) {
 func (service *BaseService) SyncReference(ctx context.Context, repo string,
 	subjectDigestStr string, referenceType string,
 ) error {
+	logger := log.Ctx(ctx, service.log)
+
 	remoteRepo := repo
 
 	remoteURL := service.client.GetConfig().URL
@@ -339,7 +343,7 @@ func (service *BaseService) SyncReference(ctx context.Context, repo string,
 	if len(service.config.Content) > 0 {
 		remoteRepo = service.contentManager.GetRepoSource(repo)
 		if remoteRepo == "" {
-			service.log.Info().Str("remote", remoteURL).Str("repository", repo).Str("subject", subjectDigestStr).
+			logger.Info().Str("remote", remoteURL).Str("repository", repo).Str("subject", subjectDigestStr).
 				Str("reference type", referenceType).Msg("will not sync reference for image, filtered out by content")
 
 			return zerr.ErrSyncImageFilteredOut
@@ -348,7 +352,7 @@ func (service *BaseService) SyncReference(ctx context.Context, repo string,
 
 	remoteRepo = service.remote.GetDockerRemoteRepo(remoteRepo)
 
-	service.log.Info().Str("remote", remoteURL).Str("repository", repo).Str("subject", subjectDigestStr).
+	logger.Info().Str("remote", remoteURL).Str("repository", repo).Str("subject", subjectDigestStr).
 		Str("reference type", referenceType).Msg("syncing reference for image")
 
 	return service.references.SyncReference(ctx, repo, remoteRepo, subjectDigestStr, referenceType)
@@ -356,6 +360,8 @@ func (service *BaseService) SyncReference(ctx context.Context, repo string,
 
 // SyncImage on demand.
 func (service *BaseService) SyncImage(ctx context.Context, repo, reference string) error {
+	logger := log.Ctx(ctx, service.log)
+
 	remoteRepo := repo
 
 	remoteURL := service.client.GetConfig().URL
@@ -363,7 +369,7 @@ func (service *BaseService) SyncImage(ctx context.Context, repo, reference strin
 	if len(service.config.Content) > 0 {
 		remoteRepo = service.contentManager.GetRepoSource(repo)
 		if remoteRepo == "" {
-			service.log.Info().Str("remote", remoteURL).Str("repository", repo).Str("reference", reference).
+			logger.Info().Str("remote", remoteURL).Str("repository", repo).Str("reference", reference).
 				Msg("will not sync image, filtered out by content")
 
 			return zerr.ErrSyncImageFilteredOut
@@ -372,7 +378,7 @@ func (service *BaseService) SyncImage(ctx context.Context, repo, reference strin
 
 	remoteRepo = service.remote.GetDockerRemoteRepo(remoteRepo)
 
-	service.log.Info().Str("remote", remoteURL).Str("repository", repo).Str("reference", reference).
+	logger.Info().Str("remote", remoteURL).Str("repository", repo).Str("reference", reference).
 		Msg("syncing image")
 
 	manifestDigest, err := service.syncTag(ctx, repo, remoteRepo, reference)
@@ -390,7 +396,9 @@ func (service *BaseService) SyncImage(ctx context.Context, repo, reference strin
 
 // sync repo periodically.
 func (service *BaseService) SyncRepo(ctx context.Context, repo string) error {
-	service.log.Info().Str("repository", repo).Str("registry", service.client.GetConfig().URL).
+	logger := log.Ctx(ctx, service.log)
+
+	logger.Info().Str("repository", repo).Str("registry", service.client.GetConfig().URL).
 		Msg("syncing repo")
 
 	var err error
@@ -402,7 +410,7 @@ func (service *BaseService) SyncRepo(ctx context.Context, repo string) error {
 
 		return err
 	}, service.retryOptions); err != nil {
-		service.log.Error().Str("errorType", common.TypeOf(err)).Str("repository", repo).
+		logger.Error().Str("errorType", common.TypeOf(err)).Str("repository", repo).
 			Err(err).Msg("failed to get tags for repository")
 
 		return err
@@ -414,7 +422,7 @@ func (service *BaseService) SyncRepo(ctx context.Context, repo string) error {
 		return err
 	}
 
-	service.log.Info().Str("repository", repo).Msgf("syncing tags %v", tags)
+	logger.Info().Str("repository", repo).Msgf("syncing tags %v", tags)
 
 	// apply content.destination rule
 	destinationRepo := service.contentManager.GetRepoDestination(repo)
@@ -440,7 +448,7 @@ func (service *BaseService) SyncRepo(ctx context.Context, repo string) error {
 				continue
 			}
 
-			service.log.Error().Str("errorType", common.TypeOf(err)).Str("repository", repo).
+			logger.Error().Str("errorType", common.TypeOf(err)).Str("repository", repo).
 				Err(err).Msg("failed to sync tags for repository")
 
 			return err
@@ -455,19 +463,21 @@ func (service *BaseService) SyncRepo(ctx context.Context, repo string) error {
 
 				return err
 			}, service.retryOptions); err != nil {
-				service.log.Error().Str("errorType", common.TypeOf(err)).Str("repository", repo).
+				logger.Error().Str("errorType", common.TypeOf(err)).Str("repository", repo).
 					Err(err).Msg("failed to sync tags for repository")
 			}
 		}
 	}
 
-	service.log.Info().Str("component", "sync").Str("repository", repo).Msg("finished syncing repository")
+	logger.Info().Str("component", "sync").Str("repository", repo).Msg("finished syncing repository")
 
 	return nil
 }
 
 func (service *BaseService) syncTag(ctx context.Context, destinationRepo, remoteRepo, tag string,
 ) (digest.Digest, error) {
+	logger := log.Ctx(ctx, service.log)
+
 	copyOptions := getCopyOptions(service.remote.GetContext(), service.destination.GetContext())
 
 	policyContext, err := getPolicyContext(service.log)
@@ -481,7 +491,7 @@ func (service *BaseService) syncTag(ctx context.Context, destinationRepo, remote
 
 	remoteImageRef, err := service.remote.GetImageReference(remoteRepo, tag)
 	if err != nil {
-		service.log.Error().Err(err).Str("errortype", common.TypeOf(err)).
+		logger.Error().Err(err).Str("errortype", common.TypeOf(err)).
 			Str("repository", remoteRepo).Str("reference", tag).Msg("couldn't get a remote image reference")
 
 		return "", err
@@ -489,7 +499,7 @@ func (service *BaseService) syncTag(ctx context.Context, destinationRepo, remote
 
 	_, mediaType, manifestDigest, err := service.remote.GetManifestContent(remoteImageRef)
 	if err != nil {
-		service.log.Error().Err(err).Str("repository", remoteRepo).Str("reference", tag).
+		logger.Error().Err(err).Str("repository", remoteRepo).Str("reference", tag).
 			Msg("couldn't get upstream image manifest details")
 
 		return "", err
@@ -504,7 +514,7 @@ func (service *BaseService) syncTag(ctx context.Context, destinationRepo, remote
 		signed := service.references.IsSigned(ctx, remoteRepo, manifestDigest.String())
 		if !signed {
 			// skip unsigned images
-			service.log.Info().Str("image", remoteImageRef.DockerReference().String()).
+			logger.Info().Str("image", remoteImageRef.DockerReference().String()).
 				Msg("skipping image without mandatory signature")
 
 			return "", zerr.ErrSyncImageNotSigned
@@ -513,7 +523,7 @@ func (service *BaseService) syncTag(ctx context.Context, destinationRepo, remote
 
 	skipImage, err := service.destination.CanSkipImage(destinationRepo, tag, manifestDigest)
 	if err != nil {
-		service.log.Error().Err(err).Str("errortype", common.TypeOf(err)).
+		logger.Error().Err(err).Str("errortype", common.TypeOf(err)).
 			Str("repository", destinationRepo).Str("reference", tag).
 			Msg("couldn't check if the local image can be skipped")
 	}
@@ -521,25 +531,25 @@ func (service *BaseService) syncTag(ctx context.Context, destinationRepo, remote
 	if !skipImage {
 		localImageRef, err := service.destination.GetImageReference(destinationRepo, tag)
 		if err != nil {
-			service.log.Error().Err(err).Str("errortype", common.TypeOf(err)).
+			logger.Error().Err(err).Str("errortype", common.TypeOf(err)).
 				Str("repository", destinationRepo).Str("reference", tag).Msg("couldn't get a local image reference")
 
 			return "", err
 		}
 
-		service.log.Info().Str("remote image", remoteImageRef.DockerReference().String()).
+		logger.Info().Str("remote image", remoteImageRef.DockerReference().String()).
 			Str("local image", fmt.Sprintf("%s:%s", destinationRepo, tag)).Msg("syncing image")
 
 		_, err = copy.Image(ctx, policyContext, localImageRef, remoteImageRef, &copyOptions)
 		if err != nil {
 			// cleanup in cases of copy.Image errors while copying.
 			if cErr := service.destination.CleanupImage(localImageRef, destinationRepo, tag); cErr != nil {
-				service.log.Error().Err(err).Str("errortype", common.TypeOf(err)).
+				logger.Error().Err(err).Str("errortype", common.TypeOf(err)).
 					Str("local image", fmt.Sprintf("%s:%s", destinationRepo, tag)).
 					Msg("couldn't cleanup temp local image")
 			}
 
-			service.log.Error().Err(err).Str("errortype", common.TypeOf(err)).
+			logger.Error().Err(err).Str("errortype", common.TypeOf(err)).
 				Str("remote image", remoteImageRef.DockerReference().String()).
 				Str("local image", fmt.Sprintf("%s:%s", destinationRepo, tag)).Msg("coulnd't sync image")
 
@@ -548,17 +558,17 @@ func (service *BaseService) syncTag(ctx context.Context, destinationRepo, remote
 
 		err = service.destination.CommitImage(localImageRef, destinationRepo, tag)
 		if err != nil {
-			service.log.Error().Err(err).Str("errortype", common.TypeOf(err)).
+			logger.Error().Err(err).Str("errortype", common.TypeOf(err)).
 				Str("repository", destinationRepo).Str("reference", tag).Msg("couldn't commit image to local image store")
 
 			return "", err
 		}
 	} else {
-		service.log.Info().Str("image", remoteImageRef.DockerReference().String()).
+		logger.Info().Str("image", remoteImageRef.DockerReference().String()).
 			Msg("skipping image because it's already synced")
 	}
 
-	service.log.Info().Str("component", "sync").
+	logger.Info().Str("component", "sync").
 		Str("image", remoteImageRef.DockerReference().String()).Msg("finished syncing image")
 
 	return manifestDigest, nil
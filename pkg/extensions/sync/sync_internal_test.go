@@ -173,7 +173,7 @@ func TestService(t *testing.T) {
 			URLs: []string{"http://localhost"},
 		}
 
-		service, err := New(conf, "", nil, os.TempDir(), storage.StoreController{}, mocks.MetaDBMock{}, log.Logger{})
+		service, err := New(conf, "", nil, os.TempDir(), storage.StoreController{}, mocks.MetaDBMock{}, nil, log.Logger{})
 		So(err, ShouldBeNil)
 
 		err = service.SyncRepo(context.Background(), "repo")
@@ -187,7 +187,7 @@ func TestSyncRepo(t *testing.T) {
 			URLs: []string{"http://localhost"},
 		}
 
-		service, err := New(conf, "", nil, os.TempDir(), storage.StoreController{}, mocks.MetaDBMock{}, log.Logger{})
+		service, err := New(conf, "", nil, os.TempDir(), storage.StoreController{}, mocks.MetaDBMock{}, nil, log.Logger{})
 		So(err, ShouldBeNil)
 
 		service.remote = mocks.SyncRemote{
@@ -204,6 +204,23 @@ func TestSyncRepo(t *testing.T) {
 	})
 }
 
+type recordingNotifier struct {
+	references []string
+	digests    []godigest.Digest
+}
+
+func (rn *recordingNotifier) OnManifestUpdated(repo, reference, mediaType string, digest godigest.Digest,
+	body []byte,
+) {
+	rn.references = append(rn.references, reference)
+	rn.digests = append(rn.digests, digest)
+}
+
+func (rn *recordingNotifier) OnManifestDeleted(repo, reference, mediaType string, digest godigest.Digest,
+	body []byte,
+) {
+}
+
 func TestDestinationRegistry(t *testing.T) {
 	Convey("make StoreController", t, func() {
 		dir := t.TempDir()
@@ -220,7 +237,7 @@ func TestDestinationRegistry(t *testing.T) {
 		repoName := "repo"
 
 		storeController := storage.StoreController{DefaultStore: syncImgStore}
-		registry := NewDestinationRegistry(storeController, storeController, nil, log)
+		registry := NewDestinationRegistry(storeController, storeController, nil, nil, log)
 		imageReference, err := registry.GetImageReference(repoName, "1.0")
 		So(err, ShouldBeNil)
 		So(imageReference, ShouldNotBeNil)
@@ -317,6 +334,19 @@ func TestDestinationRegistry(t *testing.T) {
 			So(err, ShouldBeNil)
 		})
 
+		Convey("synced manifests are notified once metaDB is updated", func() {
+			notifier := &recordingNotifier{}
+			registry := NewDestinationRegistry(storeController, storeController, mocks.MetaDBMock{}, notifier, log)
+
+			err = registry.CommitImage(imageReference, repoName, "1.0")
+			So(err, ShouldBeNil)
+
+			// the manifests of the index, then the index
+			So(notifier.references, ShouldHaveLength, 5)
+			So(notifier.references[4], ShouldEqual, "1.0")
+			So(notifier.digests[4], ShouldEqual, indexDigest)
+		})
+
 		Convey("trigger GetImageManifest error in CommitImage()", func() {
 			err = os.Chmod(imgStore.BlobPath(repoName, indexDigest), 0o000)
 			So(err, ShouldBeNil)
@@ -338,7 +368,7 @@ func TestDestinationRegistry(t *testing.T) {
 			repoName := "repo"
 
 			storeController := storage.StoreController{DefaultStore: syncImgStore}
-			registry := NewDestinationRegistry(storeController, storeController, nil, log)
+			registry := NewDestinationRegistry(storeController, storeController, nil, nil, log)
 
 			err = registry.CommitImage(imageReference, repoName, "1.0")
 			So(err, ShouldBeNil)
@@ -381,7 +411,7 @@ func TestDestinationRegistry(t *testing.T) {
 
 					return nil
 				},
-			}, log)
+			}, nil, log)
 
 			err = registry.CommitImage(imageReference, repoName, "1.0")
 			So(err, ShouldNotBeNil)
@@ -393,7 +423,7 @@ func TestDestinationRegistry(t *testing.T) {
 				SetRepoReferenceFn: func(ctx context.Context, repo, reference string, imageMeta mTypes.ImageMeta) error {
 					return zerr.ErrRepoMetaNotFound
 				},
-			}, log)
+			}, nil, log)
 
 			err = registry.CommitImage(imageReference, repoName, "1.0")
 			So(err, ShouldNotBeNil)
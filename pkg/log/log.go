@@ -1,6 +1,8 @@
 package log
 
 import (
+	"context"
+	"io"
 	"os"
 	"runtime"
 	"strconv"
@@ -9,6 +11,8 @@ import (
 	"time"
 
 	"github.com/rs/zerolog"
+
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 )
 
 const defaultPerms = 0o0600
@@ -54,6 +58,25 @@ func NewLogger(level, output string) Logger {
 }
 
 func NewAuditLogger(level, output string) *Logger {
+	// the file is not created for an invalid level
+	if _, err := zerolog.ParseLevel(level); err != nil {
+		panic(err)
+	}
+
+	if output == "" {
+		return NewAuditLoggerWithWriter(level, os.Stdout)
+	}
+
+	auditFile, err := os.OpenFile(output, os.O_APPEND|os.O_WRONLY|os.O_CREATE, defaultPerms)
+	if err != nil {
+		panic(err)
+	}
+
+	return NewAuditLoggerWithWriter(level, auditFile)
+}
+
+// NewAuditLoggerWithWriter returns an audit logger writing each record to writer, in a single write.
+func NewAuditLoggerWithWriter(level string, writer io.Writer) *Logger {
 	loggerSetTimeFormat.Do(func() {
 		zerolog.TimeFieldFormat = time.RFC3339Nano
 	})
@@ -65,20 +88,7 @@ func NewAuditLogger(level, output string) *Logger {
 
 	zerolog.SetGlobalLevel(lvl)
 
-	var auditLog zerolog.Logger
-
-	if output == "" {
-		auditLog = zerolog.New(os.Stdout)
-	} else {
-		auditFile, err := os.OpenFile(output, os.O_APPEND|os.O_WRONLY|os.O_CREATE, defaultPerms)
-		if err != nil {
-			panic(err)
-		}
-
-		auditLog = zerolog.New(auditFile)
-	}
-
-	return &Logger{Logger: auditLog.With().Timestamp().Logger()}
+	return &Logger{Logger: zerolog.New(writer).With().Timestamp().Logger()}
 }
 
 // GoroutineID adds goroutine-id to logs to help debug concurrency issues.
@@ -95,6 +105,17 @@ func GoroutineID() int {
 	return id
 }
 
+// Ctx returns logger adding the id of the request handled with ctx, if any, to the events it logs,
+// so the events logged by all the layers handling a request can be correlated.
+func Ctx(ctx context.Context, logger Logger) Logger {
+	requestID := reqCtx.RequestIDFromContext(ctx)
+	if requestID == "" {
+		return logger
+	}
+
+	return Logger{Logger: logger.With().Str("requestID", requestID).Logger()}
+}
+
 type goroutineHook struct{}
 
 func (h goroutineHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
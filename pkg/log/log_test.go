@@ -5,6 +5,7 @@ package log_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -24,6 +25,7 @@ import (
 	"zotregistry.dev/zot/pkg/api/config"
 	"zotregistry.dev/zot/pkg/api/constants"
 	"zotregistry.dev/zot/pkg/log"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 	test "zotregistry.dev/zot/pkg/test/common"
 )
 
@@ -341,3 +343,52 @@ func TestNewAuditLogger(t *testing.T) {
 		}, ShouldPanic)
 	})
 }
+
+func TestCtx(t *testing.T) {
+	Convey("Log the events of a request with its id", t, func() {
+		logPath := path.Join(t.TempDir(), "zot.log")
+		logger := log.NewLogger(zerolog.DebugLevel.String(), logPath)
+
+		ctx := reqCtx.WithRequestID(context.Background(), "push-1")
+
+		requestLogger := log.Ctx(ctx, logger)
+		requestLogger.Info().Msg("handling request")
+
+		done := make(chan struct{})
+
+		// the id follows the context, not the goroutine
+		go func() {
+			defer close(done)
+
+			logger.Info().Msg("another request")
+
+			requestLogger := log.Ctx(ctx, logger)
+			requestLogger.Info().Msg("same request")
+		}()
+
+		<-done
+
+		// no id, nothing to add
+		noIDLogger := log.Ctx(context.Background(), logger)
+		noIDLogger.Info().Msg("no request")
+
+		content, err := os.ReadFile(logPath)
+		So(err, ShouldBeNil)
+
+		lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+		So(len(lines), ShouldEqual, 4)
+
+		requestIDs := []string{}
+
+		for _, line := range lines {
+			var event map[string]any
+
+			So(json.Unmarshal([]byte(line), &event), ShouldBeNil)
+
+			requestID, _ := event["requestID"].(string)
+			requestIDs = append(requestIDs, requestID)
+		}
+
+		So(requestIDs, ShouldResemble, []string{"push-1", "", "push-1", ""})
+	})
+}
@@ -76,6 +76,11 @@ func New(boltDB *bbolt.DB, log log.Logger) (*BoltDB, error) {
 			return err
 		}
 
+		_, err = repoBlobsBuck.CreateBucketIfNotExists([]byte(TagHistoryBuck))
+		if err != nil {
+			return err
+		}
+
 		return nil
 	})
 	if err != nil {
@@ -186,6 +191,7 @@ func (bdw *BoltDB) SetRepoReference(ctx context.Context, repo string, reference
 		repoBuck := tx.Bucket([]byte(RepoMetaBuck))
 		repoBlobsBuck := tx.Bucket([]byte(RepoBlobsBuck))
 		repoLastUpdatedBuck := repoBlobsBuck.Bucket([]byte(RepoLastUpdatedBuck))
+		tagHistoryBuck := repoBlobsBuck.Bucket([]byte(TagHistoryBuck))
 		imageBuck := tx.Bucket([]byte(ImageMetaBuck))
 
 		// 1. Add image data to db if needed
@@ -245,6 +251,13 @@ func (bdw *BoltDB) SetRepoReference(ctx context.Context, repo string, reference
 				Digest:    imageMeta.Digest.String(),
 				MediaType: imageMeta.MediaType,
 			}
+
+			entry := common.NewTagHistoryEntry(ctx, mTypes.TagUpdated, imageMeta.Digest.String(), imageMeta.MediaType)
+
+			err = updateTagHistory(repo, reference, entry, tagHistoryBuck)
+			if err != nil {
+				return err
+			}
 		}
 
 		if _, ok := protoRepoMeta.Statistics[imageMeta.Digest.String()]; !ok {
@@ -296,6 +309,22 @@ func (bdw *BoltDB) SetRepoReference(ctx context.Context, repo string, reference
 	return err
 }
 
+// updateTagHistory appends entry to the history of tag, the histories of the tags of a repo being
+// stored in a bucket of their own, one key per tag.
+func updateTagHistory(repo, tag string, entry mTypes.TagHistoryEntry, tagHistoryBuck *bbolt.Bucket) error {
+	repoTagHistoryBuck, err := tagHistoryBuck.CreateBucketIfNotExists([]byte(repo))
+	if err != nil {
+		return err
+	}
+
+	tagHistoryBlob, err := common.UpdateTagHistory(repoTagHistoryBuck.Get([]byte(tag)), entry)
+	if err != nil || tagHistoryBlob == nil {
+		return err
+	}
+
+	return repoTagHistoryBuck.Put([]byte(tag), tagHistoryBlob)
+}
+
 func setRepoLastUpdated(repo string, lastUpdated time.Time, repoLastUpdatedBuck *bbolt.Bucket) error {
 	protoTime := timestamppb.New(lastUpdated)
 
@@ -1126,6 +1155,7 @@ func (bdw *BoltDB) DeleteRepoMeta(repo string) error {
 		repoBuck := tx.Bucket([]byte(RepoMetaBuck))
 		repoBlobsBuck := tx.Bucket([]byte(RepoBlobsBuck))
 		repoLastUpdatedBuck := repoBlobsBuck.Bucket([]byte(RepoLastUpdatedBuck))
+		tagHistoryBuck := repoBlobsBuck.Bucket([]byte(TagHistoryBuck))
 
 		err := repoBuck.Delete([]byte(repo))
 		if err != nil {
@@ -1137,6 +1167,13 @@ func (bdw *BoltDB) DeleteRepoMeta(repo string) error {
 			return err
 		}
 
+		if tagHistoryBuck.Bucket([]byte(repo)) != nil {
+			err = tagHistoryBuck.DeleteBucket([]byte(repo))
+			if err != nil {
+				return err
+			}
+		}
+
 		return repoLastUpdatedBuck.Delete([]byte(repo))
 	})
 
@@ -1172,6 +1209,32 @@ func (bdw *BoltDB) ResetRepoReferences(repo string) error {
 	return err
 }
 
+func (bdw *BoltDB) GetTagHistory(ctx context.Context, repo, tag string) ([]mTypes.TagHistoryEntry, error) {
+	var entries []mTypes.TagHistoryEntry
+
+	err := bdw.DB.View(func(tx *bbolt.Tx) error {
+		tagHistoryBuck := tx.Bucket([]byte(RepoBlobsBuck)).Bucket([]byte(TagHistoryBuck))
+
+		repoTagHistoryBuck := tagHistoryBuck.Bucket([]byte(repo))
+		if repoTagHistoryBuck == nil {
+			return zerr.ErrTagHistoryNotFound
+		}
+
+		tagHistoryBlob := repoTagHistoryBuck.Get([]byte(tag))
+		if tagHistoryBlob == nil {
+			return zerr.ErrTagHistoryNotFound
+		}
+
+		var err error
+
+		entries, err = common.UnmarshalTagHistory(tagHistoryBlob)
+
+		return err
+	})
+
+	return entries, err
+}
+
 func (bdw *BoltDB) GetReferrersInfo(repo string, referredDigest godigest.Digest, artifactTypes []string,
 ) ([]mTypes.ReferrerInfo, error) {
 	referrersInfoResult := []mTypes.ReferrerInfo{}
@@ -1331,12 +1394,15 @@ func (bdw *BoltDB) UpdateSignaturesValidity(ctx context.Context, repo string, ma
 	return err
 }
 
-func (bdw *BoltDB) RemoveRepoReference(repo, reference string, manifestDigest godigest.Digest) error {
+func (bdw *BoltDB) RemoveRepoReference(ctx context.Context, repo, reference string,
+	manifestDigest godigest.Digest,
+) error {
 	err := bdw.DB.Update(func(tx *bbolt.Tx) error {
 		repoMetaBuck := tx.Bucket([]byte(RepoMetaBuck))
 		imageMetaBuck := tx.Bucket([]byte(ImageMetaBuck))
 		repoBlobsBuck := tx.Bucket([]byte(RepoBlobsBuck))
 		repoLastUpdatedBuck := repoBlobsBuck.Bucket([]byte(RepoLastUpdatedBuck))
+		tagHistoryBuck := repoBlobsBuck.Bucket([]byte(TagHistoryBuck))
 
 		protoRepoMeta, err := getProtoRepoMeta(repo, repoMetaBuck)
 		if err != nil {
@@ -1384,6 +1450,8 @@ func (bdw *BoltDB) RemoveRepoReference(repo, reference string, manifestDigest go
 			protoRepoMeta.Referrers[referredDigest] = refInfo
 		}
 
+		removedTags := common.GetRemovedTags(protoRepoMeta, reference)
+
 		if !common.ReferenceIsDigest(reference) {
 			delete(protoRepoMeta.Tags, reference)
 		} else {
@@ -1395,6 +1463,15 @@ func (bdw *BoltDB) RemoveRepoReference(repo, reference string, manifestDigest go
 			}
 		}
 
+		for tag, desc := range removedTags {
+			entry := common.NewTagHistoryEntry(ctx, mTypes.TagDeleted, desc.Digest, desc.MediaType)
+
+			err = updateTagHistory(repo, tag, entry, tagHistoryBuck)
+			if err != nil {
+				return err
+			}
+		}
+
 		/* try to find at least one tag pointing to manifestDigest
 		if not found then we can also remove everything related to this digest */
 		var foundTag bool
@@ -1688,6 +1765,137 @@ func (bdw *BoltDB) SetUserGroups(ctx context.Context, groups []string) error {
 	return err
 }
 
+func (bdw *BoltDB) GetServiceAccounts(ctx context.Context) (map[string]mTypes.UserData, error) {
+	serviceAccounts := map[string]mTypes.UserData{}
+
+	err := bdw.DB.View(func(tx *bbolt.Tx) error {
+		buck := tx.Bucket([]byte(UserDataBucket))
+		if buck == nil {
+			return zerr.ErrBucketDoesNotExist
+		}
+
+		return buck.ForEach(func(userid, upBlob []byte) error {
+			var userData mTypes.UserData
+
+			if err := json.Unmarshal(upBlob, &userData); err != nil {
+				return err
+			}
+
+			if userData.ServiceAccount != nil {
+				serviceAccounts[string(userid)] = userData
+			}
+
+			return nil
+		})
+	})
+
+	return serviceAccounts, err
+}
+
+func (bdw *BoltDB) SetServiceAccount(ctx context.Context, serviceAccount mTypes.ServiceAccount) error {
+	userAc, err := reqCtx.UserAcFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if userAc.IsAnonymous() {
+		return zerr.ErrUserDataNotAllowed
+	}
+
+	userid := userAc.GetUsername()
+
+	err = bdw.DB.Update(func(tx *bbolt.Tx) error { //nolint:varnamelen
+		var userData mTypes.UserData
+
+		err := bdw.getUserData(userid, tx, &userData)
+		if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+			return err
+		}
+
+		userData.ServiceAccount = &serviceAccount
+
+		return bdw.setUserData(userid, tx, userData)
+	})
+
+	return err
+}
+
+func (bdw *BoltDB) AddUserSession(ctx context.Context, session mTypes.UserSession) error {
+	return bdw.updateUserData(ctx, func(userData *mTypes.UserData) error {
+		common.AddUserSession(userData, session)
+
+		return nil
+	})
+}
+
+func (bdw *BoltDB) GetUserSessions(ctx context.Context) ([]mTypes.UserSession, error) {
+	userData, err := bdw.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		return nil, err
+	}
+
+	return common.GetUserSessions(userData), nil
+}
+
+func (bdw *BoltDB) UpdateUserSessionLastSeen(ctx context.Context, sessionID, clientIP string,
+) (mTypes.UserSession, error) {
+	userData, err := bdw.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		return mTypes.UserSession{}, err
+	}
+
+	// most requests are not recorded, these only read the user data
+	session, err := common.GetUserSession(userData, sessionID)
+	if err != nil || !common.IsUserSessionLastSeenStale(session, clientIP) {
+		return session, err
+	}
+
+	err = bdw.updateUserData(ctx, func(userData *mTypes.UserData) error {
+		var err error
+
+		session, err = common.UpdateUserSessionLastSeen(userData, sessionID, clientIP)
+
+		return err
+	})
+
+	return session, err
+}
+
+func (bdw *BoltDB) DeleteUserSession(ctx context.Context, sessionID string) error {
+	return bdw.updateUserData(ctx, func(userData *mTypes.UserData) error {
+		return common.DeleteUserSession(userData, sessionID)
+	})
+}
+
+// updateUserData applies update to the data of the current user, starting from empty data for a new user.
+func (bdw *BoltDB) updateUserData(ctx context.Context, update func(userData *mTypes.UserData) error) error {
+	userAc, err := reqCtx.UserAcFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if userAc.IsAnonymous() {
+		return zerr.ErrUserDataNotAllowed
+	}
+
+	userid := userAc.GetUsername()
+
+	return bdw.DB.Update(func(tx *bbolt.Tx) error {
+		var userData mTypes.UserData
+
+		err := bdw.getUserData(userid, tx, &userData)
+		if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+			return err
+		}
+
+		if err := update(&userData); err != nil {
+			return err
+		}
+
+		return bdw.setUserData(userid, tx, userData)
+	})
+}
+
 func (bdw *BoltDB) GetUserGroups(ctx context.Context) ([]string, error) {
 	userData, err := bdw.GetUserData(ctx)
 
@@ -68,7 +68,7 @@ func TestWrapperErrors(t *testing.T) {
 				err := setRepoMeta("repo", badProtoBlob, boltdbWrapper.DB)
 				So(err, ShouldBeNil)
 
-				err = boltdbWrapper.RemoveRepoReference("repo", "ref", imageMeta.Digest)
+				err = boltdbWrapper.RemoveRepoReference(ctx, "repo", "ref", imageMeta.Digest)
 				So(err, ShouldNotBeNil)
 			})
 
@@ -87,7 +87,7 @@ func TestWrapperErrors(t *testing.T) {
 				err = setImageMeta(imageMeta.Digest, badProtoBlob, boltdbWrapper.DB)
 				So(err, ShouldBeNil)
 
-				err = boltdbWrapper.RemoveRepoReference("repo", "ref", imageMeta.Digest)
+				err = boltdbWrapper.RemoveRepoReference(ctx, "repo", "ref", imageMeta.Digest)
 				So(err, ShouldNotBeNil)
 			})
 
@@ -98,7 +98,7 @@ func TestWrapperErrors(t *testing.T) {
 				err = setRepoBlobInfo("repo", badProtoBlob, boltdbWrapper.DB)
 				So(err, ShouldBeNil)
 
-				err = boltdbWrapper.RemoveRepoReference("repo", "ref", imageMeta.Digest)
+				err = boltdbWrapper.RemoveRepoReference(ctx, "repo", "ref", imageMeta.Digest)
 				So(err, ShouldNotBeNil)
 			})
 		})
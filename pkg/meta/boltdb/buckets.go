@@ -12,4 +12,5 @@ const (
 const (
 	RepoBlobsBuck       = "RepoBlobsMeta"
 	RepoLastUpdatedBuck = "RepoLastUpdated" // Sub-bucket
+	TagHistoryBuck      = "TagHistory"      // Sub-bucket
 )
@@ -1,6 +1,9 @@
 package common
 
 import (
+	"context"
+	"encoding/json"
+	"sort"
 	"strings"
 	"time"
 
@@ -12,6 +15,7 @@ import (
 	mConvert "zotregistry.dev/zot/pkg/meta/convert"
 	proto_go "zotregistry.dev/zot/pkg/meta/proto/gen"
 	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 )
 
 func SignatureAlreadyExists(signatureSlice []mTypes.SignatureInfo, sm mTypes.SignatureMetadata) bool {
@@ -429,3 +433,174 @@ func deref[T any](pointer *T, defaultVal T) T {
 
 	return defaultVal
 }
+
+// NewTagHistoryEntry returns a tag history entry made now by the user and client found in ctx.
+func NewTagHistoryEntry(ctx context.Context, action, digest, mediaType string) mTypes.TagHistoryEntry {
+	var userid string
+
+	userAc, err := reqCtx.UserAcFromContext(ctx)
+	if err == nil {
+		userid = userAc.GetUsername()
+	}
+
+	return mTypes.TagHistoryEntry{
+		Action:    action,
+		Digest:    digest,
+		MediaType: mediaType,
+		Timestamp: time.Now(),
+		User:      userid,
+		Client:    reqCtx.ClientFromContext(ctx),
+	}
+}
+
+// AppendTagHistory appends entry to the history of a tag, dropping its oldest entries past
+// mTypes.MaxTagHistoryEntries, and returns false if it doesn't change anything, for example
+// when the same manifest is pushed again or when the repo is parsed again at startup.
+func AppendTagHistory(entries []mTypes.TagHistoryEntry, entry mTypes.TagHistoryEntry,
+) ([]mTypes.TagHistoryEntry, bool) {
+	if len(entries) > 0 {
+		last := entries[len(entries)-1]
+
+		if last.Action == entry.Action && (entry.Action == mTypes.TagDeleted || last.Digest == entry.Digest) {
+			return entries, false
+		}
+	}
+
+	entries = append(entries, entry)
+
+	if len(entries) > mTypes.MaxTagHistoryEntries {
+		entries = entries[len(entries)-mTypes.MaxTagHistoryEntries:]
+	}
+
+	return entries, true
+}
+
+// UpdateTagHistory appends entry to the encoded history of a tag and returns the new encoding,
+// or nil if the history didn't change.
+func UpdateTagHistory(tagHistoryBlob []byte, entry mTypes.TagHistoryEntry) ([]byte, error) {
+	entries, err := UnmarshalTagHistory(tagHistoryBlob)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, updated := AppendTagHistory(entries, entry)
+	if !updated {
+		return nil, nil
+	}
+
+	return json.Marshal(entries)
+}
+
+// GetRemovedTags returns the tags removed from repoMeta when deleting reference, the tag itself
+// or all the tags pointing to the manifest if reference is a digest.
+func GetRemovedTags(repoMeta *proto_go.RepoMeta, reference string) map[mTypes.Tag]*proto_go.TagDescriptor {
+	removedTags := map[mTypes.Tag]*proto_go.TagDescriptor{}
+
+	for tag, descriptor := range repoMeta.Tags {
+		if tag == "" || descriptor.GetDigest() == "" {
+			continue
+		}
+
+		if tag == reference || descriptor.GetDigest() == reference {
+			removedTags[tag] = descriptor
+		}
+	}
+
+	return removedTags
+}
+
+// UnmarshalTagHistory decodes the history of a tag, a missing history being empty.
+func UnmarshalTagHistory(tagHistoryBlob []byte) ([]mTypes.TagHistoryEntry, error) {
+	entries := []mTypes.TagHistoryEntry{}
+
+	if len(tagHistoryBlob) == 0 {
+		return entries, nil
+	}
+
+	err := json.Unmarshal(tagHistoryBlob, &entries)
+
+	return entries, err
+}
+
+// AddUserSession tracks session in the user data, forgetting the sessions which expired.
+func AddUserSession(userData *mTypes.UserData, session mTypes.UserSession) {
+	now := time.Now()
+
+	for sessionID, userSession := range userData.Sessions {
+		if now.After(userSession.ExpiresAt) {
+			delete(userData.Sessions, sessionID)
+		}
+	}
+
+	if userData.Sessions == nil {
+		userData.Sessions = map[string]mTypes.UserSession{}
+	}
+
+	userData.Sessions[session.ID] = session
+}
+
+// GetUserSessions returns the sessions of the user which did not expire, from the oldest to the newest.
+func GetUserSessions(userData mTypes.UserData) []mTypes.UserSession {
+	now := time.Now()
+
+	sessions := make([]mTypes.UserSession, 0, len(userData.Sessions))
+
+	for _, session := range userData.Sessions {
+		if !now.After(session.ExpiresAt) {
+			sessions = append(sessions, session)
+		}
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+
+	return sessions
+}
+
+// UserSessionLastSeenInterval is the minimum time between two updates of the last seen time of a session
+// used from the same client ip, the requests of the ui not all writing in the DB.
+const UserSessionLastSeenInterval = 5 * time.Minute
+
+// GetUserSession returns the session, the revoked and the expired sessions are not found.
+func GetUserSession(userData mTypes.UserData, sessionID string) (mTypes.UserSession, error) {
+	session, ok := userData.Sessions[sessionID]
+	if !ok || time.Now().After(session.ExpiresAt) {
+		return mTypes.UserSession{}, zerr.ErrUserSessionNotFound
+	}
+
+	return session, nil
+}
+
+// IsUserSessionLastSeenStale returns whether or not a request of the session from clientIP is worth recording.
+func IsUserSessionLastSeenStale(session mTypes.UserSession, clientIP string) bool {
+	return session.ClientIP != clientIP || time.Since(session.LastSeen) >= UserSessionLastSeenInterval
+}
+
+// UpdateUserSessionLastSeen records that the session was seen now from clientIP and returns it,
+// the revoked and the expired sessions are not found.
+func UpdateUserSessionLastSeen(userData *mTypes.UserData, sessionID, clientIP string,
+) (mTypes.UserSession, error) {
+	session, err := GetUserSession(*userData, sessionID)
+	if err != nil {
+		return mTypes.UserSession{}, err
+	}
+
+	session.LastSeen = time.Now()
+	session.ClientIP = clientIP
+
+	userData.Sessions[sessionID] = session
+
+	return session, nil
+}
+
+// DeleteUserSession removes the session from the user data.
+func DeleteUserSession(userData *mTypes.UserData, sessionID string) error {
+	if _, ok := userData.Sessions[sessionID]; !ok {
+		return zerr.ErrUserSessionNotFound
+	}
+
+	delete(userData.Sessions, sessionID)
+
+	return nil
+}
@@ -423,6 +423,13 @@ func (dwr *DynamoDB) SetRepoReference(ctx context.Context, repo string, referenc
 			Digest:    imageMeta.Digest.String(),
 			MediaType: imageMeta.MediaType,
 		}
+
+		entry := common.NewTagHistoryEntry(ctx, mTypes.TagUpdated, imageMeta.Digest.String(), imageMeta.MediaType)
+
+		err = dwr.updateTagHistory(ctx, repo, reference, entry)
+		if err != nil {
+			return err
+		}
 	}
 
 	if _, ok := repoMeta.Statistics[imageMeta.Digest.String()]; !ok {
@@ -1079,7 +1086,27 @@ func (dwr *DynamoDB) SetRepoMeta(repo string, repoMeta mTypes.RepoMeta) error {
 }
 
 func (dwr *DynamoDB) DeleteRepoMeta(repo string) error {
-	_, err := dwr.Client.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
+	resp, err := dwr.Client.GetItem(context.Background(), &dynamodb.GetItemInput{
+		TableName: aws.String(dwr.RepoBlobsTablename),
+		Key: map[string]types.AttributeValue{
+			"TableKey": &types.AttributeValueMemberS{Value: repo},
+		},
+		ProjectionExpression: aws.String("TagHistoryTags"),
+	})
+	if err != nil {
+		return err
+	}
+
+	tagHistoryTags := []string{}
+
+	if resp.Item != nil && resp.Item["TagHistoryTags"] != nil {
+		err = attributevalue.Unmarshal(resp.Item["TagHistoryTags"], &tagHistoryTags)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = dwr.Client.TransactWriteItems(context.Background(), &dynamodb.TransactWriteItemsInput{
 		TransactItems: []types.TransactWriteItem{
 			{
 				Delete: &types.Delete{
@@ -1103,8 +1130,11 @@ func (dwr *DynamoDB) DeleteRepoMeta(repo string) error {
 			},
 		},
 	})
+	if err != nil {
+		return err
+	}
 
-	return err
+	return dwr.deleteTagHistories(context.Background(), repo, tagHistoryTags)
 }
 
 func (dwr *DynamoDB) GetReferrersInfo(repo string, referredDigest godigest.Digest, artifactTypes []string,
@@ -1375,11 +1405,10 @@ func (dwr *DynamoDB) FilterImageMeta(ctx context.Context, digests []string,
 	return results, nil
 }
 
-func (dwr *DynamoDB) RemoveRepoReference(repo, reference string, manifestDigest godigest.Digest,
+func (dwr *DynamoDB) RemoveRepoReference(ctx context.Context, repo, reference string,
+	manifestDigest godigest.Digest,
 ) error {
-	ctx := context.Background()
-
-	protoRepoMeta, err := dwr.getProtoRepoMeta(context.Background(), repo)
+	protoRepoMeta, err := dwr.getProtoRepoMeta(ctx, repo)
 	if err != nil {
 		if errors.Is(err, zerr.ErrRepoMetaNotFound) {
 			return nil
@@ -1425,6 +1454,8 @@ func (dwr *DynamoDB) RemoveRepoReference(repo, reference string, manifestDigest
 		protoRepoMeta.Referrers[referredDigest] = refInfo
 	}
 
+	removedTags := common.GetRemovedTags(protoRepoMeta, reference)
+
 	if !common.ReferenceIsDigest(reference) {
 		delete(protoRepoMeta.Tags, reference)
 	} else {
@@ -1472,10 +1503,196 @@ func (dwr *DynamoDB) RemoveRepoReference(repo, reference string, manifestDigest
 	}
 
 	err = dwr.setProtoRepoMeta(repo, protoRepoMeta) //nolint: contextcheck
+	if err != nil {
+		return err
+	}
+
+	for tag, desc := range removedTags {
+		entry := common.NewTagHistoryEntry(ctx, mTypes.TagDeleted, desc.Digest, desc.MediaType)
+
+		err = dwr.updateTagHistory(ctx, repo, tag, entry)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (dwr *DynamoDB) GetTagHistory(ctx context.Context, repo, tag string) ([]mTypes.TagHistoryEntry, error) {
+	tagHistoryBlob, _, err := dwr.getTagHistory(ctx, repo, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	if tagHistoryBlob == nil {
+		return nil, zerr.ErrTagHistoryNotFound
+	}
+
+	return common.UnmarshalTagHistory(tagHistoryBlob)
+}
+
+// getTagHistoryKey returns the key of the item holding the history of tag in the repo blobs table,
+// ':' being allowed in neither repo names nor tags.
+func getTagHistoryKey(repo, tag string) string {
+	return repo + ":" + tag
+}
+
+// getTagHistory returns the encoded history of tag and its version, nil and 0 if it has no history.
+func (dwr *DynamoDB) getTagHistory(ctx context.Context, repo, tag string) ([]byte, int, error) {
+	resp, err := dwr.Client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(dwr.RepoBlobsTablename),
+		Key: map[string]types.AttributeValue{
+			"TableKey": &types.AttributeValueMemberS{Value: getTagHistoryKey(repo, tag)},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.Item == nil || resp.Item["TagHistory"] == nil {
+		return nil, 0, nil
+	}
+
+	var (
+		tagHistoryBlob []byte
+		version        int
+	)
+
+	err = attributevalue.Unmarshal(resp.Item["TagHistory"], &tagHistoryBlob)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if resp.Item["Version"] != nil {
+		err = attributevalue.Unmarshal(resp.Item["Version"], &version)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return tagHistoryBlob, version, nil
+}
+
+// maxTagHistoryAttempts is how many times appending to the history of a tag is attempted
+// while other replicas keep updating it.
+const maxTagHistoryAttempts = 5
+
+// updateTagHistory appends entry to the history of tag, stored in an item of its own. The item is versioned
+// and only written if its version didn't change since it was read, so that the entries appended meanwhile
+// by other replicas sharing the table are not lost.
+func (dwr *DynamoDB) updateTagHistory(ctx context.Context, repo, tag string, entry mTypes.TagHistoryEntry,
+) error {
+	for range maxTagHistoryAttempts {
+		tagHistoryBlob, version, err := dwr.getTagHistory(ctx, repo, tag)
+		if err != nil {
+			return err
+		}
+
+		tagHistoryBlob, err = common.UpdateTagHistory(tagHistoryBlob, entry)
+		if err != nil || tagHistoryBlob == nil {
+			return err
+		}
+
+		if version == 0 {
+			// the tags having a history are listed in the item of the repo for DeleteRepoMeta to find them
+			err = dwr.addTagHistoryTag(ctx, repo, tag)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = dwr.putTagHistory(ctx, repo, tag, tagHistoryBlob, version)
+		if err == nil {
+			return nil
+		}
+
+		var conditionalCheckErr *types.ConditionalCheckFailedException
+		if !errors.As(err, &conditionalCheckErr) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("%w: repo %s tag %s", zerr.ErrTagHistoryConflict, repo, tag)
+}
+
+// putTagHistory writes the history of tag unless its version is no longer the one which was read.
+func (dwr *DynamoDB) putTagHistory(ctx context.Context, repo, tag string, tagHistoryBlob []byte, version int,
+) error {
+	thAttributeValue, err := attributevalue.Marshal(tagHistoryBlob)
+	if err != nil {
+		return err
+	}
+
+	newVersionAttributeValue, err := attributevalue.Marshal(version + 1)
+	if err != nil {
+		return err
+	}
+
+	putItemInput := &dynamodb.PutItemInput{
+		Item: map[string]types.AttributeValue{
+			"TableKey":   &types.AttributeValueMemberS{Value: getTagHistoryKey(repo, tag)},
+			"TagHistory": thAttributeValue,
+			"Version":    newVersionAttributeValue,
+		},
+		TableName:           aws.String(dwr.RepoBlobsTablename),
+		ConditionExpression: aws.String("attribute_not_exists(TableKey)"),
+	}
+
+	if version != 0 {
+		versionAttributeValue, err := attributevalue.Marshal(version)
+		if err != nil {
+			return err
+		}
+
+		putItemInput.ConditionExpression = aws.String("#V = :Version")
+		putItemInput.ExpressionAttributeNames = map[string]string{"#V": "Version"}
+		putItemInput.ExpressionAttributeValues = map[string]types.AttributeValue{":Version": versionAttributeValue}
+	}
+
+	_, err = dwr.Client.PutItem(ctx, putItemInput)
 
 	return err
 }
 
+func (dwr *DynamoDB) addTagHistoryTag(ctx context.Context, repo, tag string) error {
+	_, err := dwr.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		ExpressionAttributeNames: map[string]string{
+			"#THT": "TagHistoryTags",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":Tag": &types.AttributeValueMemberSS{Value: []string{tag}},
+		},
+		Key: map[string]types.AttributeValue{
+			"TableKey": &types.AttributeValueMemberS{
+				Value: repo,
+			},
+		},
+		TableName:        aws.String(dwr.RepoBlobsTablename),
+		UpdateExpression: aws.String("ADD #THT :Tag"),
+	})
+
+	return err
+}
+
+// deleteTagHistories deletes the histories of the tags of repo.
+func (dwr *DynamoDB) deleteTagHistories(ctx context.Context, repo string, tags []string) error {
+	for _, tag := range tags {
+		_, err := dwr.Client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			Key: map[string]types.AttributeValue{
+				"TableKey": &types.AttributeValueMemberS{Value: getTagHistoryKey(repo, tag)},
+			},
+			TableName: aws.String(dwr.RepoBlobsTablename),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func getUserStars(ctx context.Context, dwr *DynamoDB) []string {
 	starredRepos, err := dwr.GetStarredRepos(ctx)
 	if err != nil {
@@ -1668,6 +1885,156 @@ func (dwr DynamoDB) SetUserGroups(ctx context.Context, groups []string) error {
 	return dwr.SetUserData(ctx, userData)
 }
 
+func (dwr DynamoDB) GetServiceAccounts(ctx context.Context) (map[string]mTypes.UserData, error) {
+	serviceAccounts := map[string]mTypes.UserData{}
+
+	paginator := dynamodb.NewScanPaginator(dwr.Client, &dynamodb.ScanInput{
+		TableName: aws.String(dwr.UserDataTablename),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range page.Items {
+			var (
+				userid   string
+				userData mTypes.UserData
+			)
+
+			if err := attributevalue.Unmarshal(item["TableKey"], &userid); err != nil {
+				return nil, err
+			}
+
+			if err := attributevalue.Unmarshal(item["UserData"], &userData); err != nil {
+				return nil, err
+			}
+
+			if userData.ServiceAccount != nil {
+				serviceAccounts[userid] = userData
+			}
+		}
+	}
+
+	return serviceAccounts, nil
+}
+
+func (dwr DynamoDB) SetServiceAccount(ctx context.Context, serviceAccount mTypes.ServiceAccount) error {
+	userData, err := dwr.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		return err
+	}
+
+	userData.ServiceAccount = &serviceAccount
+
+	return dwr.SetUserData(ctx, userData)
+}
+
+func (dwr DynamoDB) AddUserSession(ctx context.Context, session mTypes.UserSession) error {
+	return dwr.updateUserData(ctx, func(userData *mTypes.UserData) error {
+		common.AddUserSession(userData, session)
+
+		return nil
+	})
+}
+
+func (dwr DynamoDB) GetUserSessions(ctx context.Context) ([]mTypes.UserSession, error) {
+	userData, err := dwr.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		return nil, err
+	}
+
+	return common.GetUserSessions(userData), nil
+}
+
+func (dwr DynamoDB) UpdateUserSessionLastSeen(ctx context.Context, sessionID, clientIP string,
+) (mTypes.UserSession, error) {
+	userAc, err := reqCtx.UserAcFromContext(ctx)
+	if err != nil {
+		return mTypes.UserSession{}, err
+	}
+
+	userData, err := dwr.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		return mTypes.UserSession{}, err
+	}
+
+	// most requests are not recorded, these only read the user data
+	session, err := common.GetUserSession(userData, sessionID)
+	if err != nil || !common.IsUserSessionLastSeenStale(session, clientIP) {
+		return session, err
+	}
+
+	session.LastSeen = time.Now()
+	session.ClientIP = clientIP
+
+	lastSeenAttributeValue, err := attributevalue.Marshal(session.LastSeen)
+	if err != nil {
+		return mTypes.UserSession{}, err
+	}
+
+	clientIPAttributeValue, err := attributevalue.Marshal(session.ClientIP)
+	if err != nil {
+		return mTypes.UserSession{}, err
+	}
+
+	// only the attributes of the session are updated, the other changes of the user data made meanwhile
+	// being kept, and the session is not restored if it was revoked meanwhile
+	_, err = dwr.Client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		ConditionExpression: aws.String("attribute_exists(#UP.#SS.#ID)"),
+		ExpressionAttributeNames: map[string]string{
+			"#UP": "UserData",
+			"#SS": "Sessions",
+			"#ID": sessionID,
+			"#LS": "LastSeen",
+			"#IP": "ClientIP",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":LastSeen": lastSeenAttributeValue,
+			":ClientIP": clientIPAttributeValue,
+		},
+		Key: map[string]types.AttributeValue{
+			"TableKey": &types.AttributeValueMemberS{
+				Value: userAc.GetUsername(),
+			},
+		},
+		TableName:        aws.String(dwr.UserDataTablename),
+		UpdateExpression: aws.String("SET #UP.#SS.#ID.#LS = :LastSeen, #UP.#SS.#ID.#IP = :ClientIP"),
+	})
+	if err != nil {
+		var conditionalCheckErr *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionalCheckErr) {
+			return mTypes.UserSession{}, zerr.ErrUserSessionNotFound
+		}
+
+		return mTypes.UserSession{}, err
+	}
+
+	return session, nil
+}
+
+func (dwr DynamoDB) DeleteUserSession(ctx context.Context, sessionID string) error {
+	return dwr.updateUserData(ctx, func(userData *mTypes.UserData) error {
+		return common.DeleteUserSession(userData, sessionID)
+	})
+}
+
+// updateUserData applies update to the data of the current user, starting from empty data for a new user.
+func (dwr DynamoDB) updateUserData(ctx context.Context, update func(userData *mTypes.UserData) error) error {
+	userData, err := dwr.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		return err
+	}
+
+	if err := update(&userData); err != nil {
+		return err
+	}
+
+	return dwr.SetUserData(ctx, userData)
+}
+
 func (dwr DynamoDB) GetUserGroups(ctx context.Context) ([]string, error) {
 	userData, err := dwr.GetUserData(ctx)
 
@@ -202,7 +202,7 @@ func TestWrapperErrors(t *testing.T) {
 				err := setRepoMeta("repo", badProtoBlob, dynamoWrapper)
 				So(err, ShouldBeNil)
 
-				err = dynamoWrapper.RemoveRepoReference("repo", "ref", imageMeta.Digest)
+				err = dynamoWrapper.RemoveRepoReference(ctx, "repo", "ref", imageMeta.Digest)
 				So(err, ShouldNotBeNil)
 			})
 
@@ -221,7 +221,7 @@ func TestWrapperErrors(t *testing.T) {
 				err = setImageMeta(imageMeta.Digest, badProtoBlob, dynamoWrapper)
 				So(err, ShouldBeNil)
 
-				err = dynamoWrapper.RemoveRepoReference("repo", "ref", imageMeta.Digest)
+				err = dynamoWrapper.RemoveRepoReference(ctx, "repo", "ref", imageMeta.Digest)
 				So(err, ShouldNotBeNil)
 			})
 
@@ -232,7 +232,7 @@ func TestWrapperErrors(t *testing.T) {
 				err = setRepoBlobInfo("repo", badProtoBlob, dynamoWrapper) //nolint: contextcheck
 				So(err, ShouldBeNil)
 
-				err = dynamoWrapper.RemoveRepoReference("repo", "ref", imageMeta.Digest) //nolint: contextcheck
+				err = dynamoWrapper.RemoveRepoReference(ctx, "repo", "ref", imageMeta.Digest) //nolint: contextcheck
 				So(err, ShouldNotBeNil)
 			})
 		})
@@ -15,9 +15,9 @@ import (
 
 // OnUpdateManifest is called when a new manifest is added. It updates metadb according to the type
 // of image pushed(normal images, signatues, etc.). In care of any errors, it makes sure to keep
-// consistency between metadb and the image store.
+// consistency between metadb and the image store. Once metadb is updated the event is sent to the notifier.
 func OnUpdateManifest(ctx context.Context, repo, reference, mediaType string, digest godigest.Digest, body []byte,
-	storeController storage.StoreController, metaDB mTypes.MetaDB, log log.Logger,
+	storeController storage.StoreController, metaDB mTypes.MetaDB, notifier mTypes.ManifestNotifier, log log.Logger,
 ) error {
 	if zcommon.IsReferrersTag(reference) {
 		return nil
@@ -40,14 +40,18 @@ func OnUpdateManifest(ctx context.Context, repo, reference, mediaType string, di
 		return err
 	}
 
+	if notifier != nil {
+		notifier.OnManifestUpdated(repo, reference, mediaType, digest, body)
+	}
+
 	return nil
 }
 
 // OnDeleteManifest is called when a manifest is deleted. It updates metadb according to the type
 // of image pushed(normal images, signatues, etc.). In care of any errors, it makes sure to keep
-// consistency between metadb and the image store.
-func OnDeleteManifest(repo, reference, mediaType string, digest godigest.Digest, manifestBlob []byte,
-	storeController storage.StoreController, metaDB mTypes.MetaDB, log log.Logger,
+// consistency between metadb and the image store. Once metadb is updated the event is sent to the notifier.
+func OnDeleteManifest(ctx context.Context, repo, reference, mediaType string, digest godigest.Digest, manifestBlob []byte,
+	storeController storage.StoreController, metaDB mTypes.MetaDB, notifier mTypes.ManifestNotifier, log log.Logger,
 ) error {
 	if zcommon.IsReferrersTag(reference) {
 		return nil
@@ -77,7 +81,7 @@ func OnDeleteManifest(repo, reference, mediaType string, digest godigest.Digest,
 			manageRepoMetaSuccessfully = false
 		}
 	} else {
-		err = metaDB.RemoveRepoReference(repo, reference, digest)
+		err = metaDB.RemoveRepoReference(ctx, repo, reference, digest)
 		if err != nil {
 			log.Info().Str("component", "metadb").Msg("restoring image store")
 
@@ -99,6 +103,10 @@ func OnDeleteManifest(repo, reference, mediaType string, digest godigest.Digest,
 		return err
 	}
 
+	if notifier != nil {
+		notifier.OnManifestDeleted(repo, reference, mediaType, digest, manifestBlob)
+	}
+
 	return nil
 }
 
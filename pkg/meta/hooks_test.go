@@ -43,7 +43,7 @@ func TestOnUpdateManifest(t *testing.T) {
 		So(err, ShouldBeNil)
 
 		err = meta.OnUpdateManifest(context.Background(), "repo", "tag1", ispec.MediaTypeImageManifest, image.Digest(),
-			image.ManifestDescriptor.Data, storeController, metaDB, log)
+			image.ManifestDescriptor.Data, storeController, metaDB, nil, log)
 		So(err, ShouldBeNil)
 
 		repoMeta, err := metaDB.GetRepoMeta(context.Background(), "repo")
@@ -62,12 +62,12 @@ func TestUpdateErrors(t *testing.T) {
 
 		Convey("IsReferrersTag true update", func() {
 			err := meta.OnUpdateManifest(context.Background(), "repo", "sha256-123", "digest", "media", []byte("bad"),
-				storeController, metaDB, log)
+				storeController, metaDB, nil, log)
 			So(err, ShouldBeNil)
 		})
 		Convey("IsReferrersTag true delete", func() {
-			err := meta.OnDeleteManifest("repo", "sha256-123", "digest", "media", []byte("bad"),
-				storeController, metaDB, log)
+			err := meta.OnDeleteManifest(context.Background(), "repo", "sha256-123", "digest", "media", []byte("bad"),
+				storeController, metaDB, nil, log)
 			So(err, ShouldBeNil)
 		})
 	})
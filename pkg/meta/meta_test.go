@@ -6,6 +6,7 @@ package meta_test
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path"
@@ -463,6 +464,130 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 				So(err, ShouldNotBeNil)
 			})
 
+			Convey("Test service accounts", func() {
+				userAc := reqCtx.NewUserAccessControl()
+				userAc.SetUsername("ci-bot")
+
+				ctx := userAc.DeriveContext(context.Background())
+
+				serviceAccounts, err := metaDB.GetServiceAccounts(ctx)
+				So(err, ShouldBeNil)
+				So(serviceAccounts, ShouldBeEmpty)
+
+				serviceAccount := mTypes.ServiceAccount{
+					Description: "pipelines",
+					Groups:      []string{"ci"},
+					CreatedBy:   "admin",
+				}
+
+				err = metaDB.SetServiceAccount(ctx, serviceAccount)
+				So(err, ShouldBeNil)
+
+				err = metaDB.AddUserAPIKey(ctx, hashKey1, &apiKeyDetails)
+				So(err, ShouldBeNil)
+
+				// the users which logged in are not listed
+				humanAc := reqCtx.NewUserAccessControl()
+				humanAc.SetUsername("alice")
+
+				err = metaDB.SetUserGroups(humanAc.DeriveContext(context.Background()), []string{"group1"})
+				So(err, ShouldBeNil)
+
+				// the api keys are kept when the service account is updated
+				serviceAccount.Disabled = true
+
+				err = metaDB.SetServiceAccount(ctx, serviceAccount)
+				So(err, ShouldBeNil)
+
+				serviceAccounts, err = metaDB.GetServiceAccounts(ctx)
+				So(err, ShouldBeNil)
+				So(serviceAccounts, ShouldHaveLength, 1)
+				So(*serviceAccounts["ci-bot"].ServiceAccount, ShouldResemble, serviceAccount)
+				So(serviceAccounts["ci-bot"].APIKeys, ShouldContainKey, hashKey1)
+
+				identity, err := metaDB.GetUserAPIKeyInfo(hashKey1)
+				So(err, ShouldBeNil)
+				So(identity, ShouldEqual, "ci-bot")
+
+				userAc.SetUsername("")
+
+				err = metaDB.SetServiceAccount(userAc.DeriveContext(context.Background()), serviceAccount)
+				So(err, ShouldNotBeNil)
+			})
+
+			Convey("Test user sessions", func() {
+				userAc := reqCtx.NewUserAccessControl()
+				userAc.SetUsername("test")
+
+				ctx := userAc.DeriveContext(context.Background())
+
+				now := time.Now().UTC().Round(time.Millisecond)
+
+				laptop := mTypes.UserSession{
+					ID:        "laptop",
+					UserAgent: "firefox",
+					ClientIP:  "10.0.0.1",
+					CreatedAt: now,
+					LastSeen:  now,
+					ExpiresAt: now.Add(time.Hour),
+				}
+				expired := mTypes.UserSession{
+					ID:        "expired",
+					CreatedAt: now.Add(-2 * time.Hour),
+					LastSeen:  now.Add(-2 * time.Hour),
+					ExpiresAt: now.Add(-time.Hour),
+				}
+
+				err := metaDB.AddUserSession(ctx, expired)
+				So(err, ShouldBeNil)
+
+				err = metaDB.AddUserSession(ctx, laptop)
+				So(err, ShouldBeNil)
+
+				sessions, err := metaDB.GetUserSessions(ctx)
+				So(err, ShouldBeNil)
+				So(sessions, ShouldHaveLength, 1)
+				So(sessions[0].ID, ShouldEqual, "laptop")
+
+				session, err := metaDB.UpdateUserSessionLastSeen(ctx, "laptop", "10.0.0.2")
+				So(err, ShouldBeNil)
+				So(session.ClientIP, ShouldEqual, "10.0.0.2")
+
+				// the requests following closely from the same client ip are not recorded
+				recentSession, err := metaDB.UpdateUserSessionLastSeen(ctx, "laptop", "10.0.0.2")
+				So(err, ShouldBeNil)
+				So(recentSession.LastSeen.Equal(session.LastSeen), ShouldBeTrue)
+
+				// the session is replaced when added again
+				session.Provider = "oidc"
+
+				err = metaDB.AddUserSession(ctx, session)
+				So(err, ShouldBeNil)
+
+				sessions, err = metaDB.GetUserSessions(ctx)
+				So(err, ShouldBeNil)
+				So(sessions, ShouldHaveLength, 1)
+				So(sessions[0].ClientIP, ShouldEqual, "10.0.0.2")
+				So(sessions[0].Provider, ShouldEqual, "oidc")
+				So(sessions[0].LastSeen.Before(now), ShouldBeFalse)
+
+				_, err = metaDB.UpdateUserSessionLastSeen(ctx, "expired", "10.0.0.2")
+				So(errors.Is(err, zerr.ErrUserSessionNotFound), ShouldBeTrue)
+
+				err = metaDB.DeleteUserSession(ctx, "laptop")
+				So(err, ShouldBeNil)
+
+				err = metaDB.DeleteUserSession(ctx, "laptop")
+				So(errors.Is(err, zerr.ErrUserSessionNotFound), ShouldBeTrue)
+
+				_, err = metaDB.UpdateUserSessionLastSeen(ctx, "laptop", "10.0.0.2")
+				So(errors.Is(err, zerr.ErrUserSessionNotFound), ShouldBeTrue)
+
+				sessions, err = metaDB.GetUserSessions(ctx)
+				So(err, ShouldBeNil)
+				So(sessions, ShouldBeEmpty)
+			})
+
 			Convey("Test API keys with short expiration date", func() {
 				expirationDate := time.Now().Add(1 * time.Second)
 				apiKeyDetails.ExpirationDate = expirationDate
@@ -679,7 +804,7 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 				err := metaDB.SetRepoReference(ctx, repo1, tag1, imgData1)
 				So(err, ShouldBeNil)
 
-				err = metaDB.RemoveRepoReference(repo1, tag1, imgData1.Digest)
+				err = metaDB.RemoveRepoReference(ctx, repo1, tag1, imgData1.Digest)
 				So(err, ShouldBeNil)
 
 				err = metaDB.SetRepoReference(ctx, repo1, tag1, imgData1)
@@ -814,7 +939,7 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 			So(err, ShouldBeNil)
 
 			Convey("Delete reference from repo", func() {
-				err = metaDB.RemoveRepoReference(repo, tag1, imageMeta1.Digest)
+				err = metaDB.RemoveRepoReference(ctx, repo, tag1, imageMeta1.Digest)
 				So(err, ShouldBeNil)
 
 				repoMeta, err := metaDB.GetRepoMeta(ctx, repo)
@@ -828,7 +953,7 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 			})
 
 			Convey("Delete a digest from repo", func() {
-				err = metaDB.RemoveRepoReference(repo, tag2, imageMeta2.Digest)
+				err = metaDB.RemoveRepoReference(ctx, repo, tag2, imageMeta2.Digest)
 				So(err, ShouldBeNil)
 
 				repoMeta, err := metaDB.GetRepoMeta(ctx, repo)
@@ -843,7 +968,7 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 
 			Convey("Delete inexistent reference from repo", func() {
 				inexistentDigest := godigest.FromBytes([]byte("inexistent"))
-				err := metaDB.RemoveRepoReference(repo, inexistentDigest.String(), inexistentDigest)
+				err := metaDB.RemoveRepoReference(ctx, repo, inexistentDigest.String(), inexistentDigest)
 				So(err, ShouldBeNil)
 
 				repoMeta, err := metaDB.GetRepoMeta(ctx, repo)
@@ -857,7 +982,7 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 			Convey("Delete reference from inexistent repo", func() {
 				inexistentDigest := godigest.FromBytes([]byte("inexistent"))
 
-				err := metaDB.RemoveRepoReference("InexistentRepo", inexistentDigest.String(), inexistentDigest)
+				err := metaDB.RemoveRepoReference(ctx, "InexistentRepo", inexistentDigest.String(), inexistentDigest)
 				So(err, ShouldBeNil)
 
 				repoMeta, err := metaDB.GetRepoMeta(ctx, repo)
@@ -869,6 +994,97 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 			})
 		})
 
+		Convey("Test tag history", func() {
+			var (
+				repo = "repo"
+				tag  = "tag"
+			)
+
+			userAc := reqCtx.NewUserAccessControl()
+			userAc.SetUsername("user")
+
+			ctx := reqCtx.WithClient(userAc.DeriveContext(context.Background()), "client")
+
+			image1 := CreateRandomImage()
+			image2 := CreateRandomImage()
+
+			_, err := metaDB.GetTagHistory(ctx, repo, tag)
+			So(errors.Is(err, zerr.ErrTagHistoryNotFound), ShouldBeTrue)
+
+			err = metaDB.SetRepoReference(ctx, repo, tag, image1.AsImageMeta())
+			So(err, ShouldBeNil)
+
+			// pushing the same manifest again does not change the history
+			err = metaDB.SetRepoReference(ctx, repo, tag, image1.AsImageMeta())
+			So(err, ShouldBeNil)
+
+			err = metaDB.SetRepoReference(ctx, repo, tag, image2.AsImageMeta())
+			So(err, ShouldBeNil)
+
+			// pushing by digest does not record any history
+			err = metaDB.SetRepoReference(ctx, repo, image1.DigestStr(), image1.AsImageMeta())
+			So(err, ShouldBeNil)
+
+			_, err = metaDB.GetTagHistory(ctx, repo, image1.DigestStr())
+			So(errors.Is(err, zerr.ErrTagHistoryNotFound), ShouldBeTrue)
+
+			err = metaDB.RemoveRepoReference(ctx, repo, tag, image2.Digest())
+			So(err, ShouldBeNil)
+
+			history, err := metaDB.GetTagHistory(ctx, repo, tag)
+			So(err, ShouldBeNil)
+			So(len(history), ShouldEqual, 3)
+
+			So(history[0].Action, ShouldEqual, mTypes.TagUpdated)
+			So(history[0].Digest, ShouldEqual, image1.DigestStr())
+			So(history[0].MediaType, ShouldEqual, ispec.MediaTypeImageManifest)
+			So(history[0].User, ShouldEqual, "user")
+			So(history[0].Client, ShouldEqual, "client")
+			So(history[0].Timestamp, ShouldNotBeZeroValue)
+
+			So(history[1].Action, ShouldEqual, mTypes.TagUpdated)
+			So(history[1].Digest, ShouldEqual, image2.DigestStr())
+
+			So(history[2].Action, ShouldEqual, mTypes.TagDeleted)
+			So(history[2].Digest, ShouldEqual, image2.DigestStr())
+
+			// the histories of the other tags are kept apart
+			err = metaDB.SetRepoReference(ctx, repo, "other", image1.AsImageMeta())
+			So(err, ShouldBeNil)
+
+			history, err = metaDB.GetTagHistory(ctx, repo, "other")
+			So(err, ShouldBeNil)
+			So(len(history), ShouldEqual, 1)
+
+			// only the newest entries are kept
+			for i := range mTypes.MaxTagHistoryEntries {
+				image := image1
+				if i%2 == 0 {
+					image = image2
+				}
+
+				err = metaDB.SetRepoReference(ctx, repo, tag, image.AsImageMeta())
+				So(err, ShouldBeNil)
+			}
+
+			history, err = metaDB.GetTagHistory(ctx, repo, tag)
+			So(err, ShouldBeNil)
+			So(len(history), ShouldEqual, mTypes.MaxTagHistoryEntries)
+			So(history[0].Action, ShouldEqual, mTypes.TagUpdated)
+			So(history[0].Digest, ShouldEqual, image2.DigestStr())
+			So(history[len(history)-1].Digest, ShouldEqual, image1.DigestStr())
+
+			// the history outlives the tag, but not the repo
+			err = metaDB.DeleteRepoMeta(repo)
+			So(err, ShouldBeNil)
+
+			_, err = metaDB.GetTagHistory(ctx, repo, tag)
+			So(errors.Is(err, zerr.ErrTagHistoryNotFound), ShouldBeTrue)
+
+			_, err = metaDB.GetTagHistory(ctx, repo, "other")
+			So(errors.Is(err, zerr.ErrTagHistoryNotFound), ShouldBeTrue)
+		})
+
 		Convey("Test GetMultipleRepoMeta", func() {
 			var (
 				repo1 = "repo1"
@@ -1779,7 +1995,7 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 				// We need to add a new reference and then remove it in order to obtain the empty repo
 				err = metaDB.SetRepoReference(ctx, repo2, tag3, image3.AsImageMeta())
 				So(err, ShouldBeNil)
-				err = metaDB.RemoveRepoReference(repo2, tag3, image3.Digest())
+				err = metaDB.RemoveRepoReference(ctx, repo2, tag3, image3.Digest())
 				So(err, ShouldBeNil)
 
 				repoMetaList, err := metaDB.SearchRepos(ctx, "")
@@ -2274,14 +2490,14 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 			So(err, ShouldBeNil)
 
 			// Delete the Referrers
-			err = metaDB.RemoveRepoReference("repo", artifact1.DigestStr(), artifact1.Digest())
+			err = metaDB.RemoveRepoReference(ctx, "repo", artifact1.DigestStr(), artifact1.Digest())
 			So(err, ShouldBeNil)
 
 			referrers, err = metaDB.GetReferrersInfo("repo", image1.Digest(), nil)
 			So(err, ShouldBeNil)
 			So(len(referrers), ShouldEqual, 1)
 
-			err = metaDB.RemoveRepoReference("repo", artifact2.DigestStr(), artifact2.Digest())
+			err = metaDB.RemoveRepoReference(ctx, "repo", artifact2.DigestStr(), artifact2.Digest())
 			So(err, ShouldBeNil)
 
 			referrers, err = metaDB.GetReferrersInfo("repo", image1.Digest(), nil)
@@ -2311,7 +2527,7 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 			So(err, ShouldBeNil)
 			So(len(repoMeta.Referrers[image.DigestStr()]), ShouldEqual, 1)
 
-			err = metaDB.RemoveRepoReference("repo", refTag, referrer.Digest())
+			err = metaDB.RemoveRepoReference(ctx, "repo", refTag, referrer.Digest())
 			So(err, ShouldBeNil)
 
 			// we still have the untagged manifest
@@ -2319,7 +2535,7 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 			So(err, ShouldBeNil)
 			So(len(repoMeta.Referrers[image.DigestStr()]), ShouldEqual, 1)
 
-			err = metaDB.RemoveRepoReference("repo", referrer.DigestStr(), referrer.Digest())
+			err = metaDB.RemoveRepoReference(ctx, "repo", referrer.DigestStr(), referrer.Digest())
 			So(err, ShouldBeNil)
 
 			repoMeta, err = metaDB.GetRepoMeta(ctx, "repo")
@@ -2350,7 +2566,7 @@ func RunMetaDBTests(t *testing.T, metaDB mTypes.MetaDB, preparationFuncs ...func
 			So(len(repoMeta.Referrers[image.DigestStr()]), ShouldEqual, 1)
 
 			// this should delete all references
-			err = metaDB.RemoveRepoReference("repo", referrer.DigestStr(), referrer.Digest())
+			err = metaDB.RemoveRepoReference(ctx, "repo", referrer.DigestStr(), referrer.Digest())
 			So(err, ShouldBeNil)
 
 			repoMeta, err = metaDB.GetRepoMeta(ctx, "repo")
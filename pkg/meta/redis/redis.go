@@ -34,6 +34,7 @@ const (
 	RepoMetaBucket        = "RepoMeta"
 	RepoBlobsBucket       = "RepoBlobsMeta"
 	RepoLastUpdatedBucket = "RepoLastUpdated"
+	TagHistoryBucket      = "TagHistory"
 	UserDataBucket        = "UserData"
 	VersionBucket         = "Version"
 	UserAPIKeysBucket     = "UserAPIKeys"
@@ -51,6 +52,7 @@ type RedisDB struct {
 	RepoMetaKey        string
 	RepoBlobsKey       string
 	RepoLastUpdatedKey string
+	TagHistoryKey      string
 	UserDataKey        string
 	VersionKey         string
 	UserAPIKeysKey     string
@@ -72,6 +74,7 @@ func New(client redis.UniversalClient, params DBDriverParameters, log log.Logger
 		RepoMetaKey:        join(params.KeyPrefix, RepoMetaBucket),
 		RepoBlobsKey:       join(params.KeyPrefix, RepoBlobsBucket),
 		RepoLastUpdatedKey: join(params.KeyPrefix, RepoLastUpdatedBucket),
+		TagHistoryKey:      join(params.KeyPrefix, TagHistoryBucket),
 		UserDataKey:        join(params.KeyPrefix, UserDataBucket),
 		VersionKey:         join(params.KeyPrefix, VersionBucket),
 		UserAPIKeysKey:     join(params.KeyPrefix, UserAPIKeysBucket),
@@ -374,6 +377,157 @@ func (rc *RedisDB) SetUserGroups(ctx context.Context, groups []string) error {
 	return err
 }
 
+func (rc *RedisDB) GetServiceAccounts(ctx context.Context) (map[string]mTypes.UserData, error) {
+	serviceAccounts := map[string]mTypes.UserData{}
+
+	userDataBlobs, err := rc.Client.HGetAll(ctx, rc.UserDataKey).Result()
+	if err != nil {
+		rc.Log.Error().Err(err).Str("hgetall", rc.UserDataKey).Msg("failed to get user data records")
+
+		return nil, fmt.Errorf("failed to get user data records: %w", err)
+	}
+
+	for userid, userDataBlob := range userDataBlobs {
+		var userData mTypes.UserData
+
+		if err := json.Unmarshal([]byte(userDataBlob), &userData); err != nil {
+			return nil, err
+		}
+
+		if userData.ServiceAccount != nil {
+			serviceAccounts[userid] = userData
+		}
+	}
+
+	return serviceAccounts, nil
+}
+
+func (rc *RedisDB) SetServiceAccount(ctx context.Context, serviceAccount mTypes.ServiceAccount) error {
+	userAc, err := reqCtx.UserAcFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if userAc.IsAnonymous() {
+		return zerr.ErrUserDataNotAllowed
+	}
+
+	userid := userAc.GetUsername()
+
+	err = rc.withRSLocks(ctx, []string{rc.getUserLockKey(userid)}, func() error {
+		userData, err := rc.GetUserData(ctx)
+		if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+			return err
+		}
+
+		userData.ServiceAccount = &serviceAccount
+
+		userDataBlob, err := json.Marshal(userData)
+		if err != nil {
+			return err
+		}
+
+		err = rc.Client.HSet(ctx, rc.UserDataKey, userid, userDataBlob).Err()
+		if err != nil {
+			rc.Log.Error().Err(err).Str("hset", rc.UserDataKey).Str("userid", userid).
+				Msg("failed to set user data record")
+
+			return fmt.Errorf("failed to set user data for identity %s: %w", userid, err)
+		}
+
+		return nil
+	})
+
+	return err
+}
+
+func (rc *RedisDB) AddUserSession(ctx context.Context, session mTypes.UserSession) error {
+	return rc.updateUserData(ctx, func(userData *mTypes.UserData) error {
+		common.AddUserSession(userData, session)
+
+		return nil
+	})
+}
+
+func (rc *RedisDB) GetUserSessions(ctx context.Context) ([]mTypes.UserSession, error) {
+	userData, err := rc.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		return nil, err
+	}
+
+	return common.GetUserSessions(userData), nil
+}
+
+func (rc *RedisDB) UpdateUserSessionLastSeen(ctx context.Context, sessionID, clientIP string,
+) (mTypes.UserSession, error) {
+	userData, err := rc.GetUserData(ctx)
+	if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+		return mTypes.UserSession{}, err
+	}
+
+	// most requests are not recorded, these only read the user data
+	session, err := common.GetUserSession(userData, sessionID)
+	if err != nil || !common.IsUserSessionLastSeenStale(session, clientIP) {
+		return session, err
+	}
+
+	err = rc.updateUserData(ctx, func(userData *mTypes.UserData) error {
+		var err error
+
+		session, err = common.UpdateUserSessionLastSeen(userData, sessionID, clientIP)
+
+		return err
+	})
+
+	return session, err
+}
+
+func (rc *RedisDB) DeleteUserSession(ctx context.Context, sessionID string) error {
+	return rc.updateUserData(ctx, func(userData *mTypes.UserData) error {
+		return common.DeleteUserSession(userData, sessionID)
+	})
+}
+
+// updateUserData applies update to the data of the current user, starting from empty data for a new user.
+func (rc *RedisDB) updateUserData(ctx context.Context, update func(userData *mTypes.UserData) error) error {
+	userAc, err := reqCtx.UserAcFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	if userAc.IsAnonymous() {
+		return zerr.ErrUserDataNotAllowed
+	}
+
+	userid := userAc.GetUsername()
+
+	return rc.withRSLocks(ctx, []string{rc.getUserLockKey(userid)}, func() error {
+		userData, err := rc.GetUserData(ctx)
+		if err != nil && !errors.Is(err, zerr.ErrUserDataNotFound) {
+			return err
+		}
+
+		if err := update(&userData); err != nil {
+			return err
+		}
+
+		userDataBlob, err := json.Marshal(userData)
+		if err != nil {
+			return err
+		}
+
+		err = rc.Client.HSet(ctx, rc.UserDataKey, userid, userDataBlob).Err()
+		if err != nil {
+			rc.Log.Error().Err(err).Str("hset", rc.UserDataKey).Str("userid", userid).
+				Msg("failed to set user data record")
+
+			return fmt.Errorf("failed to set user data for identity %s: %w", userid, err)
+		}
+
+		return nil
+	})
+}
+
 func (rc *RedisDB) GetUserGroups(ctx context.Context) ([]string, error) {
 	userData, err := rc.GetUserData(ctx)
 
@@ -798,11 +952,20 @@ func (rc *RedisDB) SetRepoReference(ctx context.Context, repo string,
 		}
 
 		// 3. Update tag
+		tagHistoryBlobs := map[string][]byte{}
+
 		if !common.ReferenceIsDigest(reference) {
 			protoRepoMeta.Tags[reference] = &proto_go.TagDescriptor{
 				Digest:    imageMeta.Digest.String(),
 				MediaType: imageMeta.MediaType,
 			}
+
+			entry := common.NewTagHistoryEntry(ctx, mTypes.TagUpdated, imageMeta.Digest.String(), imageMeta.MediaType)
+
+			err = rc.updateTagHistory(ctx, repo, reference, entry, tagHistoryBlobs)
+			if err != nil {
+				return err
+			}
 		}
 
 		if _, ok := protoRepoMeta.Statistics[imageMeta.Digest.String()]; !ok {
@@ -882,6 +1045,15 @@ func (rc *RedisDB) SetRepoReference(ctx context.Context, repo string,
 				return fmt.Errorf("failed to put repometa record for repo %s: %w", repo, err)
 			}
 
+			for field, tagHistoryBlob := range tagHistoryBlobs {
+				if err := txrp.HSet(ctx, rc.TagHistoryKey, field, tagHistoryBlob).Err(); err != nil {
+					rc.Log.Error().Err(err).Str("hset", rc.TagHistoryKey).Str("repo", repo).
+						Msg("failed to put tag history record")
+
+					return fmt.Errorf("failed to put tag history record for repo %s: %w", repo, err)
+				}
+			}
+
 			return nil
 		})
 
@@ -1653,7 +1825,12 @@ func (rc *RedisDB) DeleteRepoMeta(repo string) error {
 	ctx := context.Background()
 
 	err := rc.withRSLocks(ctx, []string{rc.getRepoLockKey(repo)}, func() error {
-		_, err := rc.Client.TxPipelined(ctx, func(txrp redis.Pipeliner) error {
+		tagHistoryFields, err := rc.getTagHistoryFields(ctx, repo)
+		if err != nil {
+			return err
+		}
+
+		_, err = rc.Client.TxPipelined(ctx, func(txrp redis.Pipeliner) error {
 			if err := txrp.HDel(ctx, rc.RepoMetaKey, repo).Err(); err != nil {
 				rc.Log.Error().Err(err).Str("hdel", rc.RepoMetaKey).Str("repo", repo).
 					Msg("failed to delete repo meta record")
@@ -1675,6 +1852,15 @@ func (rc *RedisDB) DeleteRepoMeta(repo string) error {
 				return fmt.Errorf("failed to delete repo last updated record for repo %s: %w", repo, err)
 			}
 
+			if len(tagHistoryFields) > 0 {
+				if err := txrp.HDel(ctx, rc.TagHistoryKey, tagHistoryFields...).Err(); err != nil {
+					rc.Log.Error().Err(err).Str("hdel", rc.TagHistoryKey).Str("repo", repo).
+						Msg("failed to delete tag history records")
+
+					return fmt.Errorf("failed to delete tag history records for repo %s: %w", repo, err)
+				}
+			}
+
 			return nil
 		})
 
@@ -1802,9 +1988,9 @@ pointing to it.
 If the reference is a digest then it will remove the digest from Statistics, Signatures and Referrers only
 if there are no tags pointing to the digest, otherwise it's noop.
 */
-func (rc *RedisDB) RemoveRepoReference(repo, reference string, manifestDigest godigest.Digest) error {
-	ctx := context.Background()
-
+func (rc *RedisDB) RemoveRepoReference(ctx context.Context, repo, reference string,
+	manifestDigest godigest.Digest,
+) error {
 	locks := []string{rc.getImageLockKey(manifestDigest.String()), rc.getRepoLockKey(repo)}
 	err := rc.withRSLocks(ctx, locks, func() error {
 		protoRepoMeta, err := rc.getProtoRepoMeta(ctx, repo)
@@ -1853,6 +2039,8 @@ func (rc *RedisDB) RemoveRepoReference(repo, reference string, manifestDigest go
 			protoRepoMeta.Referrers[referredDigest] = refInfo
 		}
 
+		removedTags := common.GetRemovedTags(protoRepoMeta, reference)
+
 		if !common.ReferenceIsDigest(reference) {
 			delete(protoRepoMeta.Tags, reference)
 		} else {
@@ -1864,6 +2052,17 @@ func (rc *RedisDB) RemoveRepoReference(repo, reference string, manifestDigest go
 			}
 		}
 
+		tagHistoryBlobs := map[string][]byte{}
+
+		for tag, desc := range removedTags {
+			entry := common.NewTagHistoryEntry(ctx, mTypes.TagDeleted, desc.Digest, desc.MediaType)
+
+			err = rc.updateTagHistory(ctx, repo, tag, entry, tagHistoryBlobs)
+			if err != nil {
+				return err
+			}
+		}
+
 		/* try to find at least one tag pointing to manifestDigest
 		if not found then we can also remove everything related to this digest */
 		var foundTag bool
@@ -1933,6 +2132,15 @@ func (rc *RedisDB) RemoveRepoReference(repo, reference string, manifestDigest go
 				return fmt.Errorf("failed to put repometa record for repo %s: %w", repo, err)
 			}
 
+			for field, tagHistoryBlob := range tagHistoryBlobs {
+				if err := txrp.HSet(ctx, rc.TagHistoryKey, field, tagHistoryBlob).Err(); err != nil {
+					rc.Log.Error().Err(err).Str("hset", rc.TagHistoryKey).Str("repo", repo).
+						Msg("failed to put tag history record")
+
+					return fmt.Errorf("failed to put tag history record for repo %s: %w", repo, err)
+				}
+			}
+
 			return nil
 		})
 
@@ -1942,6 +2150,75 @@ func (rc *RedisDB) RemoveRepoReference(repo, reference string, manifestDigest go
 	return err
 }
 
+func (rc *RedisDB) GetTagHistory(ctx context.Context, repo, tag string) ([]mTypes.TagHistoryEntry, error) {
+	tagHistoryBlob, err := rc.Client.HGet(ctx, rc.TagHistoryKey, join(repo, tag)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, zerr.ErrTagHistoryNotFound
+		}
+
+		rc.Log.Error().Err(err).Str("hget", rc.TagHistoryKey).Str("repo", repo).Str("tag", tag).
+			Msg("failed to get tag history record")
+
+		return nil, fmt.Errorf("failed to get tag history record for repo %s tag %s: %w", repo, tag, err)
+	}
+
+	return common.UnmarshalTagHistory(tagHistoryBlob)
+}
+
+// updateTagHistory appends entry to the history of tag and adds its new encoding to tagHistoryBlobs,
+// the history of each tag being stored in its own field, the repo and the tag joined.
+func (rc *RedisDB) updateTagHistory(ctx context.Context, repo, tag string, entry mTypes.TagHistoryEntry,
+	tagHistoryBlobs map[string][]byte,
+) error {
+	field := join(repo, tag)
+
+	tagHistoryBlob, err := rc.Client.HGet(ctx, rc.TagHistoryKey, field).Bytes()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		rc.Log.Error().Err(err).Str("hget", rc.TagHistoryKey).Str("repo", repo).Str("tag", tag).
+			Msg("failed to get tag history record")
+
+		return fmt.Errorf("failed to get tag history record for repo %s tag %s: %w", repo, tag, err)
+	}
+
+	tagHistoryBlob, err = common.UpdateTagHistory(tagHistoryBlob, entry)
+	if err != nil || tagHistoryBlob == nil {
+		return err
+	}
+
+	tagHistoryBlobs[field] = tagHistoryBlob
+
+	return nil
+}
+
+// getTagHistoryFields returns the fields holding the histories of the tags of repo,
+// ':' being allowed in neither repo names nor tags.
+func (rc *RedisDB) getTagHistoryFields(ctx context.Context, repo string) ([]string, error) {
+	fields := []string{}
+
+	var cursor uint64
+
+	for {
+		fieldsAndValues, nextCursor, err := rc.Client.HScan(ctx, rc.TagHistoryKey, cursor, join(repo, "*"), 0).Result()
+		if err != nil {
+			rc.Log.Error().Err(err).Str("hscan", rc.TagHistoryKey).Str("repo", repo).
+				Msg("failed to list tag history records")
+
+			return nil, fmt.Errorf("failed to list tag history records for repo %s: %w", repo, err)
+		}
+
+		for i := 0; i < len(fieldsAndValues); i += 2 {
+			fields = append(fields, fieldsAndValues[i])
+		}
+
+		if nextCursor == 0 {
+			return fields, nil
+		}
+
+		cursor = nextCursor
+	}
+}
+
 // ResetRepoReferences resets all layout specific data (tags, signatures, referrers, etc.) but keep user and image
 // specific metadata such as star count, downloads other statistics.
 func (rc *RedisDB) ResetRepoReferences(repo string) error {
@@ -2054,6 +2331,12 @@ func (rc *RedisDB) ResetDB() error {
 			return fmt.Errorf("failed to delete repo last updated bucket: %w", err)
 		}
 
+		if err := txrp.Del(ctx, rc.TagHistoryKey).Err(); err != nil {
+			rc.Log.Error().Err(err).Str("del", rc.TagHistoryKey).Msg("failed to delete tag history bucket")
+
+			return fmt.Errorf("failed to delete tag history bucket: %w", err)
+		}
+
 		if err := txrp.Del(ctx, rc.UserDataKey).Err(); err != nil {
 			rc.Log.Error().Err(err).Str("del", rc.UserDataKey).Msg("failed to delete user data bucket")
 
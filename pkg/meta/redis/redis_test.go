@@ -132,12 +132,28 @@ func TestRedisMocked(t *testing.T) {
 			So(err, ShouldBeNil)
 		})
 
+		Convey("ResetDB Del TagHistoryKey error", func() {
+			mock.ExpectTxPipeline()
+			mock.ExpectDel(metaDB.RepoMetaKey).SetVal(0)
+			mock.ExpectDel(metaDB.ImageMetaKey).SetVal(0)
+			mock.ExpectDel(metaDB.RepoBlobsKey).SetVal(0)
+			mock.ExpectDel(metaDB.RepoLastUpdatedKey).SetVal(0)
+			mock.ExpectDel(metaDB.TagHistoryKey).SetErr(ErrTestError)
+
+			err := metaDB.ResetDB()
+			So(err, ShouldNotBeNil)
+
+			err = mock.ExpectationsWereMet()
+			So(err, ShouldBeNil)
+		})
+
 		Convey("ResetDB Del UserDataKey error", func() {
 			mock.ExpectTxPipeline()
 			mock.ExpectDel(metaDB.RepoMetaKey).SetVal(0)
 			mock.ExpectDel(metaDB.ImageMetaKey).SetVal(0)
 			mock.ExpectDel(metaDB.RepoBlobsKey).SetVal(0)
 			mock.ExpectDel(metaDB.RepoLastUpdatedKey).SetVal(0)
+			mock.ExpectDel(metaDB.TagHistoryKey).SetVal(0)
 			mock.ExpectDel(metaDB.UserDataKey).SetErr(ErrTestError)
 
 			err := metaDB.ResetDB()
@@ -153,6 +169,7 @@ func TestRedisMocked(t *testing.T) {
 			mock.ExpectDel(metaDB.ImageMetaKey).SetVal(0)
 			mock.ExpectDel(metaDB.RepoBlobsKey).SetVal(0)
 			mock.ExpectDel(metaDB.RepoLastUpdatedKey).SetVal(0)
+			mock.ExpectDel(metaDB.TagHistoryKey).SetVal(0)
 			mock.ExpectDel(metaDB.UserDataKey).SetVal(0)
 			mock.ExpectDel(metaDB.UserAPIKeysKey).SetErr(ErrTestError)
 
@@ -169,6 +186,7 @@ func TestRedisMocked(t *testing.T) {
 			mock.ExpectDel(metaDB.ImageMetaKey).SetVal(0)
 			mock.ExpectDel(metaDB.RepoBlobsKey).SetVal(0)
 			mock.ExpectDel(metaDB.RepoLastUpdatedKey).SetVal(0)
+			mock.ExpectDel(metaDB.TagHistoryKey).SetVal(0)
 			mock.ExpectDel(metaDB.UserDataKey).SetVal(0)
 			mock.ExpectDel(metaDB.UserAPIKeysKey).SetVal(0)
 			mock.ExpectDel(metaDB.VersionKey).SetErr(ErrTestError)
@@ -183,6 +201,7 @@ func TestRedisMocked(t *testing.T) {
 		Convey("DeleteRepoMeta Del RepoMetaKey error", func() {
 			mock.Regexp().ExpectSetNX(metaDB.LocksKey+":Repo:repo", `.*`, 8*time.Second).
 				SetVal(true)
+			mock.ExpectHScan(metaDB.TagHistoryKey, 0, "repo:*", 0).SetVal([]string{}, 0)
 			mock.ExpectTxPipeline()
 			mock.ExpectHDel(metaDB.RepoMetaKey, "repo").SetErr(ErrTestError)
 
@@ -196,6 +215,7 @@ func TestRedisMocked(t *testing.T) {
 		Convey("DeleteRepoMeta Del RepoBlobsKey error", func() {
 			mock.Regexp().ExpectSetNX(metaDB.LocksKey+":Repo:repo", `.*`, 8*time.Second).
 				SetVal(true)
+			mock.ExpectHScan(metaDB.TagHistoryKey, 0, "repo:*", 0).SetVal([]string{}, 0)
 			mock.ExpectTxPipeline()
 			mock.ExpectHDel(metaDB.RepoMetaKey, "repo").SetVal(0)
 			mock.ExpectHDel(metaDB.RepoBlobsKey, "repo").SetErr(ErrTestError)
@@ -210,6 +230,7 @@ func TestRedisMocked(t *testing.T) {
 		Convey("DeleteRepoMeta Del RepoLastUpdatedKey error", func() {
 			mock.Regexp().ExpectSetNX(metaDB.LocksKey+":Repo:repo", `.*`, 8*time.Second).
 				SetVal(true)
+			mock.ExpectHScan(metaDB.TagHistoryKey, 0, "repo:*", 0).SetVal([]string{}, 0)
 			mock.ExpectTxPipeline()
 			mock.ExpectHDel(metaDB.RepoMetaKey, "repo").SetVal(0)
 			mock.ExpectHDel(metaDB.RepoBlobsKey, "repo").SetVal(0)
@@ -221,6 +242,38 @@ func TestRedisMocked(t *testing.T) {
 			err = mock.ExpectationsWereMet()
 			So(err, ShouldBeNil)
 		})
+
+		Convey("DeleteRepoMeta HScan TagHistoryKey error", func() {
+			mock.Regexp().ExpectSetNX(metaDB.LocksKey+":Repo:repo", `.*`, 8*time.Second).
+				SetVal(true)
+			mock.ExpectHScan(metaDB.TagHistoryKey, 0, "repo:*", 0).SetErr(ErrTestError)
+
+			err := metaDB.DeleteRepoMeta("repo")
+			So(err, ShouldNotBeNil)
+
+			err = mock.ExpectationsWereMet()
+			So(err, ShouldBeNil)
+		})
+
+		Convey("DeleteRepoMeta Del TagHistoryKey error", func() {
+			mock.Regexp().ExpectSetNX(metaDB.LocksKey+":Repo:repo", `.*`, 8*time.Second).
+				SetVal(true)
+			mock.ExpectHScan(metaDB.TagHistoryKey, 0, "repo:*", 0).
+				SetVal([]string{"repo:tag1", "[]"}, 1)
+			mock.ExpectHScan(metaDB.TagHistoryKey, 1, "repo:*", 0).
+				SetVal([]string{"repo:tag2", "[]"}, 0)
+			mock.ExpectTxPipeline()
+			mock.ExpectHDel(metaDB.RepoMetaKey, "repo").SetVal(0)
+			mock.ExpectHDel(metaDB.RepoBlobsKey, "repo").SetVal(0)
+			mock.ExpectHDel(metaDB.RepoLastUpdatedKey, "repo").SetVal(0)
+			mock.ExpectHDel(metaDB.TagHistoryKey, "repo:tag1", "repo:tag2").SetErr(ErrTestError)
+
+			err := metaDB.DeleteRepoMeta("repo")
+			So(err, ShouldNotBeNil)
+
+			err = mock.ExpectationsWereMet()
+			So(err, ShouldBeNil)
+		})
 	})
 }
 
@@ -490,7 +543,7 @@ func TestRedisUnreachable(t *testing.T) {
 		_, err = metaDB.FilterImageMeta(ctx, []string{digest.String()})
 		So(err, ShouldNotBeNil)
 
-		err = metaDB.RemoveRepoReference(repo, reference, digest)
+		err = metaDB.RemoveRepoReference(ctx, repo, reference, digest)
 		So(err, ShouldNotBeNil)
 
 		err = metaDB.ResetRepoReferences(repo)
@@ -595,7 +648,7 @@ func TestWrapperErrors(t *testing.T) {
 				err := setRepoMeta("repo", badProtoBlob, client)
 				So(err, ShouldBeNil)
 
-				err = metaDB.RemoveRepoReference("repo", "ref", imageMeta.Digest)
+				err = metaDB.RemoveRepoReference(ctx, "repo", "ref", imageMeta.Digest)
 				So(err, ShouldNotBeNil)
 			})
 
@@ -614,7 +667,7 @@ func TestWrapperErrors(t *testing.T) {
 				err = setImageMeta(imageMeta.Digest, badProtoBlob, client)
 				So(err, ShouldBeNil)
 
-				err = metaDB.RemoveRepoReference("repo", "ref", imageMeta.Digest)
+				err = metaDB.RemoveRepoReference(ctx, "repo", "ref", imageMeta.Digest)
 				So(err, ShouldNotBeNil)
 			})
 
@@ -625,7 +678,7 @@ func TestWrapperErrors(t *testing.T) {
 				err = setRepoBlobInfo("repo", badProtoBlob, client)
 				So(err, ShouldBeNil)
 
-				err = metaDB.RemoveRepoReference("repo", "ref", imageMeta.Digest)
+				err = metaDB.RemoveRepoReference(ctx, "repo", "ref", imageMeta.Digest)
 				So(err, ShouldNotBeNil)
 			})
 		})
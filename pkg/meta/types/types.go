@@ -58,6 +58,13 @@ func GetLatestImageDigests(repoMetaList []RepoMeta) []string {
 	return digests
 }
 
+// ManifestNotifier is notified of the manifests added to or removed from the repositories, once MetaDB is updated,
+// whatever did the write: the clients, sync or the garbage collection.
+type ManifestNotifier interface {
+	OnManifestUpdated(repo, reference, mediaType string, digest godigest.Digest, body []byte)
+	OnManifestDeleted(repo, reference, mediaType string, digest godigest.Digest, body []byte)
+}
+
 type MetaDB interface { //nolint:interfacebloat
 	UserDB
 
@@ -138,7 +145,11 @@ type MetaDB interface { //nolint:interfacebloat
 	   If the reference is a digest then it will remove the digest from Statistics, Signatures and Referrers only
 	   if there are no tags pointing to the digest, otherwise it's noop
 	*/
-	RemoveRepoReference(repo, reference string, manifestDigest godigest.Digest) error
+	RemoveRepoReference(ctx context.Context, repo, reference string, manifestDigest godigest.Digest) error
+
+	// GetTagHistory returns the last MaxTagHistoryEntries changes of a tag made through SetRepoReference
+	// and RemoveRepoReference, from the oldest to the newest
+	GetTagHistory(ctx context.Context, repo, tag string) ([]TagHistoryEntry, error)
 
 	// ResetRepoReferences resets all layout specific data (tags, signatures, referrers, etc.) but keep user and image
 	// specific metadata such as star count, downloads other statistics
@@ -196,6 +207,25 @@ type UserDB interface { //nolint:interfacebloat
 	UpdateUserAPIKeyLastUsed(ctx context.Context, hashedKey string) error
 
 	DeleteUserAPIKey(ctx context.Context, id string) error
+
+	// GetServiceAccounts returns the user data of the service accounts, by name
+	GetServiceAccounts(ctx context.Context) (map[string]UserData, error)
+
+	// SetServiceAccount makes the current user a service account, or updates it, keeping its api keys
+	SetServiceAccount(ctx context.Context, serviceAccount ServiceAccount) error
+
+	// AddUserSession tracks a web session of the current user, replacing the one with the same id
+	// and forgetting its expired sessions
+	AddUserSession(ctx context.Context, session UserSession) error
+
+	// GetUserSessions returns the web sessions of the current user which did not expire
+	GetUserSessions(ctx context.Context) ([]UserSession, error)
+
+	// UpdateUserSessionLastSeen records a request of a session, ErrUserSessionNotFound if it was revoked or expired
+	UpdateUserSessionLastSeen(ctx context.Context, sessionID, clientIP string) (UserSession, error)
+
+	// DeleteUserSession revokes a web session of the current user
+	DeleteUserSession(ctx context.Context, sessionID string) error
 }
 
 type (
@@ -341,6 +371,42 @@ type UserData struct {
 	BookmarkedRepos []string
 	Groups          []string
 	APIKeys         map[HashedAPIKey]APIKeyDetails
+	// set if the user is a service account, which can not log in and only authenticates with its api keys
+	ServiceAccount *ServiceAccount        `json:",omitempty"`
+	Sessions       map[string]UserSession `json:",omitempty"`
+}
+
+// UserSession is a web session of a user, tracked so that it can be listed and revoked.
+type UserSession struct {
+	ID        string    `json:"id"`
+	UserAgent string    `json:"userAgent"`
+	ClientIP  string    `json:"clientIP"`
+	CreatedAt time.Time `json:"createdAt"`
+	LastSeen  time.Time `json:"lastSeen"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// openid/oauth2 provider the user logged in with
+	Provider string `json:"provider,omitempty"`
+	// tokens of the provider, kept only if the session is re-validated with it
+	Token *UserSessionToken `json:"token,omitempty"`
+}
+
+// UserSessionToken holds the provider tokens of a session, used to re-validate it.
+type UserSessionToken struct {
+	Subject      string    `json:"subject"`
+	AccessToken  string    `json:"accessToken"`
+	RefreshToken string    `json:"refreshToken,omitempty"`
+	TokenType    string    `json:"tokenType"`
+	Expiry       time.Time `json:"expiry"`
+	ValidatedAt  time.Time `json:"validatedAt"`
+}
+
+// ServiceAccount is an identity created by an admin to own the api keys of automated clients.
+type ServiceAccount struct {
+	Description string    `json:"description"`
+	Groups      []string  `json:"groups"`
+	Disabled    bool      `json:"disabled"`
+	CreatedBy   string    `json:"createdBy"`
+	CreatedAt   time.Time `json:"createdAt"`
 }
 
 type Filter struct {
@@ -361,6 +427,24 @@ type FilterData struct {
 	IsBookmarked  bool
 }
 
+const (
+	TagUpdated = "updated"
+	TagDeleted = "deleted"
+)
+
+// MaxTagHistoryEntries is the number of changes kept in the history of a tag, the oldest ones being dropped.
+const MaxTagHistoryEntries = 100
+
+// TagHistoryEntry records a change of the manifest a tag points to.
+type TagHistoryEntry struct {
+	Action    string    `json:"action"`
+	Digest    string    `json:"digest"`
+	MediaType string    `json:"mediaType"`
+	Timestamp time.Time `json:"timestamp"`
+	User      string    `json:"user"`
+	Client    string    `json:"client"`
+}
+
 type APIKeyDetails struct {
 	CreatedAt      time.Time `json:"createdAt"`
 	ExpirationDate time.Time `json:"expirationDate"`
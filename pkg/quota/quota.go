@@ -0,0 +1,375 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	glob "github.com/bmatcuk/doublestar/v4"
+	docker "github.com/distribution/distribution/v3/manifest/schema2"
+	ispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	zcommon "zotregistry.dev/zot/pkg/common"
+	"zotregistry.dev/zot/pkg/extensions/monitoring"
+	zlog "zotregistry.dev/zot/pkg/log"
+	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	"zotregistry.dev/zot/pkg/storage"
+)
+
+const (
+	bytesResource  = "bytes"
+	imagesResource = "images"
+)
+
+// Usage is the live usage of a storage quota.
+type Usage struct {
+	Repositories []string `json:"repositories"`
+	MaxSize      int64    `json:"maxSize"`
+	MaxImages    int      `json:"maxImages"`
+	Size         int64    `json:"size"`
+	Images       int      `json:"images"`
+}
+
+// usageReloadInterval is how long the cached usage is trusted before it is read again from MetaDB,
+// catching up with the deletions done outside of the image stores, by GC and retention.
+const usageReloadInterval = 5 * time.Minute
+
+// repoUsage is the usage of a repository, as read from MetaDB plus the writes allowed since.
+type repoUsage struct {
+	size   int64
+	images int
+	// stale is set when the usage of the repository decreased, it is read again from MetaDB on its next check
+	stale bool
+}
+
+// Manager enforces the storage quotas configured for each store, as a write policy of the image stores.
+// Usage is kept per repository, loaded once from MetaDB, a repository's size being the size of the images
+// pushed to it, then updated with each write the manager allows. It is summed over all the repositories
+// of the same store matching any of the quota globs.
+type Manager struct {
+	config  *config.Config
+	metaDB  mTypes.MetaDB
+	metrics monitoring.MetricServer
+	log     zlog.Logger
+
+	lock     sync.Mutex
+	repos    map[string]*repoUsage // guarded by lock, nil until loaded
+	loadedAt time.Time
+}
+
+func NewManager(config *config.Config, metaDB mTypes.MetaDB, metrics monitoring.MetricServer,
+	log zlog.Logger,
+) *Manager {
+	return &Manager{
+		config:  config,
+		metaDB:  metaDB,
+		metrics: metrics,
+		log:     log,
+	}
+}
+
+// CheckPutBlob returns ErrStorageQuotaExceeded if adding a blob of the given size to repo
+// would exceed any of the quotas the repository is subject to.
+// The blob is only counted once referenced by a manifest.
+func (m *Manager) CheckPutBlob(repo string, size int64) error {
+	if m.metaDB == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, err := m.getRepoUsage(repo); err != nil {
+		return err
+	}
+
+	return m.check(repo, false, size)
+}
+
+// CheckPutManifest returns ErrStorageQuotaExceeded if pushing the manifest to repo would exceed any of the quotas
+// the repository is subject to. The size of an image is the size of its manifest, config and layers,
+// and a new tag counts as a new image.
+func (m *Manager) CheckPutManifest(repo, reference string, desc ispec.Descriptor, body []byte,
+	index ispec.Index,
+) error {
+	if m.metaDB == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, err := m.getRepoUsage(repo); err != nil {
+		return err
+	}
+
+	size, newTag := getManifestUsage(reference, desc, body, index)
+
+	return m.check(repo, newTag, size)
+}
+
+// ManifestPut adds the manifest pushed to repo to its usage, only the writes which succeeded being counted.
+func (m *Manager) ManifestPut(repo, reference string, desc ispec.Descriptor, body []byte, index ispec.Index) {
+	if m.metaDB == nil {
+		return
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	usage, err := m.getRepoUsage(repo)
+	if err != nil {
+		// the usage of repo is read again from MetaDB on its next check
+		return
+	}
+
+	size, newTag := getManifestUsage(reference, desc, body, index)
+
+	usage.size += size
+
+	if newTag {
+		usage.images++
+	}
+}
+
+// CheckDeleteManifest allows all deletions, the usage of the repository being read again on its next check.
+func (m *Manager) CheckDeleteManifest(repo, reference string, index ispec.Index) error {
+	if m.metaDB == nil {
+		return nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if usage, ok := m.repos[repo]; ok {
+		usage.stale = true
+	}
+
+	return nil
+}
+
+// GetUsage returns the live usage of all configured quotas, reading it again from MetaDB.
+func (m *Manager) GetUsage() ([]Usage, error) {
+	usages := []Usage{}
+
+	if m.metaDB == nil {
+		return usages, nil
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if err := m.load(); err != nil {
+		return nil, err
+	}
+
+	storePaths := []string{storage.DefaultStorePath}
+	for storePath := range m.config.Storage.SubPaths {
+		storePaths = append(storePaths, storePath)
+	}
+
+	for _, storePath := range storePaths {
+		for _, quota := range m.getStoreQuotas(storePath) {
+			usages = append(usages, m.getUsage(storePath, quota))
+		}
+	}
+
+	return usages, nil
+}
+
+// check returns ErrStorageQuotaExceeded if adding size bytes, and an image if newTag, to repo
+// would exceed any of its quotas, the caller MUST hold the lock and have loaded the usage of repo.
+func (m *Manager) check(repo string, newTag bool, size int64) error {
+	storePath := m.getStorePath(repo)
+
+	for _, quota := range m.getStoreQuotas(storePath) {
+		if !matchesRepo(quota, repo) {
+			continue
+		}
+
+		usage := m.getUsage(storePath, quota)
+
+		if quota.MaxSize > 0 && usage.Size+size > quota.MaxSize {
+			return newQuotaError(repo, quota, bytesResource,
+				fmt.Sprintf("%d bytes used out of %d, %d more bytes requested", usage.Size, quota.MaxSize, size))
+		}
+
+		if quota.MaxImages > 0 && newTag && usage.Images+1 > quota.MaxImages {
+			return newQuotaError(repo, quota, imagesResource,
+				fmt.Sprintf("%d images out of %d", usage.Images, quota.MaxImages))
+		}
+	}
+
+	return nil
+}
+
+// getRepoUsage returns the usage of repo, loading the usage of all repositories if it is too old
+// and reading the one of repo again if stale, the caller MUST hold the lock.
+func (m *Manager) getRepoUsage(repo string) (*repoUsage, error) {
+	if m.repos == nil || time.Since(m.loadedAt) > usageReloadInterval {
+		if err := m.load(); err != nil {
+			return nil, err
+		}
+	}
+
+	usage, ok := m.repos[repo]
+	if ok && !usage.stale {
+		return usage, nil
+	}
+
+	usage = &repoUsage{}
+
+	repoMeta, err := m.metaDB.GetRepoMeta(context.Background(), repo)
+	if err != nil && !errors.Is(err, zerr.ErrRepoMetaNotFound) {
+		m.log.Error().Err(err).Str("repository", repo).Msg("failed to get repo meta")
+
+		return nil, err
+	}
+
+	if err == nil {
+		usage.size = repoMeta.Size
+		usage.images = len(repoMeta.Tags)
+	}
+
+	m.repos[repo] = usage
+
+	return usage, nil
+}
+
+// load reads the usage of all the repositories from MetaDB, the caller MUST hold the lock.
+// A context without user access control is used, usage must include repositories the user can not read.
+func (m *Manager) load() error {
+	repoMetaList, err := m.metaDB.GetMultipleRepoMeta(context.Background(), func(repoMeta mTypes.RepoMeta) bool {
+		return true
+	})
+	if err != nil {
+		m.log.Error().Err(err).Msg("failed to get storage quota usage")
+
+		return err
+	}
+
+	repos := make(map[string]*repoUsage, len(repoMetaList))
+
+	for _, repoMeta := range repoMetaList {
+		repos[repoMeta.Name] = &repoUsage{size: repoMeta.Size, images: len(repoMeta.Tags)}
+	}
+
+	m.repos = repos
+	m.loadedAt = time.Now()
+
+	return nil
+}
+
+// getUsage sums the usage of the quota in the given store and updates its gauges, the caller MUST hold the lock.
+func (m *Manager) getUsage(storePath string, quota config.StorageQuota) Usage {
+	usage := Usage{
+		Repositories: quota.Repositories,
+		MaxSize:      quota.MaxSize,
+		MaxImages:    quota.MaxImages,
+	}
+
+	for repo, repoUsage := range m.repos {
+		if m.getStorePath(repo) == storePath && matchesRepo(quota, repo) {
+			usage.Size += repoUsage.size
+			usage.Images += repoUsage.images
+		}
+	}
+
+	label := strings.Join(quota.Repositories, ",")
+	monitoring.SetStorageQuota(m.metrics, label, bytesResource, usage.Size, quota.MaxSize)
+	monitoring.SetStorageQuota(m.metrics, label, imagesResource, int64(usage.Images), int64(quota.MaxImages))
+
+	return usage
+}
+
+// getManifestUsage returns the size added by pushing the manifest to a repository with the given index,
+// nothing if it is already there, and whether reference is a new tag.
+func getManifestUsage(reference string, desc ispec.Descriptor, body []byte, index ispec.Index) (int64, bool) {
+	newTag := zcommon.IsTag(reference)
+
+	var size int64 = -1
+
+	for _, current := range index.Manifests {
+		if current.Digest == desc.Digest {
+			size = 0
+		}
+
+		if newTag && current.Annotations[ispec.AnnotationRefName] == reference {
+			newTag = false
+		}
+	}
+
+	if size < 0 {
+		size = getImageSize(desc, body)
+	}
+
+	return size, newTag
+}
+
+// getImageSize returns the size of the manifest, plus the size of its config and layers for an image manifest,
+// the blobs referenced by an index being counted with their own manifests.
+func getImageSize(desc ispec.Descriptor, body []byte) int64 {
+	size := int64(len(body))
+
+	if desc.MediaType != ispec.MediaTypeImageManifest && desc.MediaType != docker.MediaTypeManifest {
+		return size
+	}
+
+	// docker manifests have the same layout
+	var manifest ispec.Manifest
+
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		// invalid manifests are rejected by the write itself
+		return size
+	}
+
+	size += manifest.Config.Size
+
+	for _, layer := range manifest.Layers {
+		size += layer.Size
+	}
+
+	return size
+}
+
+func (m *Manager) getStoreQuotas(storePath string) []config.StorageQuota {
+	if storePath == storage.DefaultStorePath {
+		return m.config.Storage.Quotas
+	}
+
+	return m.config.Storage.SubPaths[storePath].Quotas
+}
+
+func (m *Manager) getStorePath(repo string) string {
+	routePrefix := storage.GetRoutePrefix(repo)
+
+	if _, ok := m.config.Storage.SubPaths[routePrefix]; ok {
+		return routePrefix
+	}
+
+	return storage.DefaultStorePath
+}
+
+func matchesRepo(quota config.StorageQuota, repo string) bool {
+	for _, pattern := range quota.Repositories {
+		matched, err := glob.Match(pattern, repo)
+		if err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newQuotaError(repo string, quota config.StorageQuota, resource, usage string) error {
+	return zerr.NewError(zerr.ErrStorageQuotaExceeded).
+		AddDetail("name", repo).
+		AddDetail("quota", strings.Join(quota.Repositories, ",")).
+		AddDetail("reason", fmt.Sprintf("storage quota on %s exceeded: %s", resource, usage))
+}
@@ -0,0 +1,231 @@
+package quota_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	godigest "github.com/opencontainers/go-digest"
+	ispec "github.com/opencontainers/image-spec/specs-go/v1"
+	. "github.com/smartystreets/goconvey/convey"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	"zotregistry.dev/zot/pkg/extensions/monitoring"
+	"zotregistry.dev/zot/pkg/log"
+	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	"zotregistry.dev/zot/pkg/quota"
+	"zotregistry.dev/zot/pkg/test/mocks"
+)
+
+var ErrTestError = errors.New("test error")
+
+var (
+	digest1 = godigest.FromString("manifest1")
+	digest2 = godigest.FromString("manifest2")
+)
+
+func TestQuotaManager(t *testing.T) {
+	logger := log.NewLogger("debug", "")
+	metrics := monitoring.NewMetricsServer(false, logger)
+
+	repos := map[string]mTypes.RepoMeta{
+		"infra/a":     {Name: "infra/a", Size: 60, Tags: map[mTypes.Tag]mTypes.Descriptor{"v1": {}, "v2": {}}},
+		"infra/b":     {Name: "infra/b", Size: 30, Tags: map[mTypes.Tag]mTypes.Descriptor{"v1": {}}},
+		"other":       {Name: "other", Size: 1000, Tags: map[mTypes.Tag]mTypes.Descriptor{"v1": {}}},
+		"sub/infra/c": {Name: "sub/infra/c", Size: 1000, Tags: map[mTypes.Tag]mTypes.Descriptor{"v1": {}}},
+	}
+
+	metaDB := mocks.MetaDBMock{
+		GetMultipleRepoMetaFn: func(ctx context.Context, filter func(repoMeta mTypes.RepoMeta) bool,
+		) ([]mTypes.RepoMeta, error) {
+			found := []mTypes.RepoMeta{}
+
+			for _, repoMeta := range repos {
+				if filter(repoMeta) {
+					found = append(found, repoMeta)
+				}
+			}
+
+			return found, nil
+		},
+		GetRepoMetaFn: func(ctx context.Context, repo string) (mTypes.RepoMeta, error) {
+			repoMeta, ok := repos[repo]
+			if !ok {
+				return mTypes.RepoMeta{}, zerr.ErrRepoMetaNotFound
+			}
+
+			return repoMeta, nil
+		},
+	}
+
+	conf := config.New()
+	conf.Storage.Quotas = []config.StorageQuota{
+		{Repositories: []string{"infra/*"}, MaxSize: 100, MaxImages: 4},
+		{Repositories: []string{"infra/b"}, MaxSize: 35},
+	}
+	conf.Storage.SubPaths = map[string]config.StorageConfig{
+		"/sub": {Quotas: []config.StorageQuota{{Repositories: []string{"**"}, MaxImages: 1}}},
+	}
+
+	Convey("Test quota manager", t, func() {
+		manager := quota.NewManager(conf, metaDB, metrics, logger)
+
+		Convey("Usage is summed over matching repositories of the same storage", func() {
+			usages, err := manager.GetUsage()
+			So(err, ShouldBeNil)
+			So(usages, ShouldHaveLength, 3)
+
+			So(usages[0].Repositories, ShouldResemble, []string{"infra/*"})
+			So(usages[0].Size, ShouldEqual, 90)
+			So(usages[0].Images, ShouldEqual, 3)
+			So(usages[1].Size, ShouldEqual, 30)
+			So(usages[1].Images, ShouldEqual, 1)
+			So(usages[2].Repositories, ShouldResemble, []string{"**"})
+			So(usages[2].Images, ShouldEqual, 1)
+		})
+
+		Convey("Blobs are checked against every matching quota", func() {
+			So(manager.CheckPutBlob("infra/a", 10), ShouldBeNil)
+
+			err := manager.CheckPutBlob("infra/a", 11)
+			So(errors.Is(err, zerr.ErrStorageQuotaExceeded), ShouldBeTrue)
+			So(zerr.GetDetails(err)["quota"], ShouldEqual, "infra/*")
+
+			err = manager.CheckPutBlob("infra/b", 10)
+			So(errors.Is(err, zerr.ErrStorageQuotaExceeded), ShouldBeTrue)
+			So(zerr.GetDetails(err)["quota"], ShouldEqual, "infra/b")
+
+			// no quota matches
+			So(manager.CheckPutBlob("other", 1000), ShouldBeNil)
+		})
+
+		Convey("Only new tags count as new images", func() {
+			So(manager.CheckPutManifest("infra/a", "v3", ispec.Descriptor{}, nil, ispec.Index{}), ShouldBeNil)
+
+			// the manifests checked but not written are not counted
+			So(manager.CheckPutManifest("infra/new", "v1", ispec.Descriptor{}, nil, ispec.Index{}), ShouldBeNil)
+
+			manager.ManifestPut("infra/a", "v3", ispec.Descriptor{}, nil, ispec.Index{})
+
+			// the written manifest is counted without waiting for MetaDB
+			err := manager.CheckPutManifest("infra/new", "v1", ispec.Descriptor{}, nil, ispec.Index{})
+			So(errors.Is(err, zerr.ErrStorageQuotaExceeded), ShouldBeTrue)
+			So(zerr.GetDetails(err)["reason"], ShouldContainSubstring, "images")
+
+			// blobs are not images
+			So(manager.CheckPutBlob("infra/a", 1), ShouldBeNil)
+
+			// pushing an existing tag or by digest does not add an image
+			index := ispec.Index{Manifests: []ispec.Descriptor{
+				{Digest: digest1, Annotations: map[string]string{ispec.AnnotationRefName: "v1"}},
+			}}
+
+			So(manager.CheckPutManifest("infra/a", "v1", ispec.Descriptor{Digest: digest2}, nil, index), ShouldBeNil)
+			So(manager.CheckPutManifest("infra/a", digest2.String(), ispec.Descriptor{Digest: digest2}, nil, index),
+				ShouldBeNil)
+		})
+
+		Convey("Images are counted with their config and layers", func() {
+			body, err := json.Marshal(ispec.Manifest{
+				MediaType: ispec.MediaTypeImageManifest,
+				Config:    ispec.Descriptor{Size: 3},
+				Layers:    []ispec.Descriptor{{Size: 2}, {Size: 5}},
+			})
+			So(err, ShouldBeNil)
+
+			imageSize := int64(len(body)) + 10
+
+			conf := config.New()
+			conf.Storage.Quotas = []config.StorageQuota{{Repositories: []string{"img"}, MaxSize: imageSize + 10}}
+
+			manager := quota.NewManager(conf, mocks.MetaDBMock{}, metrics, logger)
+
+			desc := ispec.Descriptor{MediaType: ispec.MediaTypeImageManifest, Digest: digest1}
+			So(manager.CheckPutManifest("img", "v1", desc, body, ispec.Index{}), ShouldBeNil)
+			manager.ManifestPut("img", "v1", desc, body, ispec.Index{})
+
+			So(manager.CheckPutBlob("img", 10), ShouldBeNil)
+			err = manager.CheckPutBlob("img", 11)
+			So(errors.Is(err, zerr.ErrStorageQuotaExceeded), ShouldBeTrue)
+
+			// a manifest already in the repository takes no more space
+			index := ispec.Index{Manifests: []ispec.Descriptor{desc}}
+			So(manager.CheckPutManifest("img", "v2", desc, body, index), ShouldBeNil)
+
+			err = manager.CheckPutManifest("img", "v3", ispec.Descriptor{
+				MediaType: ispec.MediaTypeImageManifest, Digest: digest2,
+			}, body, index)
+			So(errors.Is(err, zerr.ErrStorageQuotaExceeded), ShouldBeTrue)
+
+			usages, err := manager.GetUsage()
+			So(err, ShouldBeNil)
+			So(usages, ShouldHaveLength, 1)
+		})
+
+		Convey("The usage of a repository is read again after a deletion", func() {
+			So(manager.CheckPutManifest("infra/a", "v3", ispec.Descriptor{}, nil, ispec.Index{}), ShouldBeNil)
+			manager.ManifestPut("infra/a", "v3", ispec.Descriptor{}, nil, ispec.Index{})
+
+			err := manager.CheckPutManifest("infra/b", "v2", ispec.Descriptor{}, nil, ispec.Index{})
+			So(errors.Is(err, zerr.ErrStorageQuotaExceeded), ShouldBeTrue)
+
+			infraA := repos["infra/a"]
+			repos["infra/a"] = mTypes.RepoMeta{Name: "infra/a", Tags: map[mTypes.Tag]mTypes.Descriptor{"v1": {}}}
+
+			defer func() { repos["infra/a"] = infraA }()
+
+			So(manager.CheckDeleteManifest("infra/a", "v2", ispec.Index{}), ShouldBeNil)
+			So(manager.CheckPutBlob("infra/a", 1), ShouldBeNil)
+			So(manager.CheckPutManifest("infra/b", "v2", ispec.Descriptor{}, nil, ispec.Index{}), ShouldBeNil)
+		})
+
+		Convey("Quotas of a substore only apply to its repositories", func() {
+			err := manager.CheckPutManifest("sub/infra/d", "v1", ispec.Descriptor{}, nil, ispec.Index{})
+			So(errors.Is(err, zerr.ErrStorageQuotaExceeded), ShouldBeTrue)
+
+			So(manager.CheckPutManifest("infra/a", "v3", ispec.Descriptor{}, nil, ispec.Index{}), ShouldBeNil)
+		})
+
+		Convey("MetaDB errors are returned", func() {
+			manager := quota.NewManager(conf, mocks.MetaDBMock{
+				GetMultipleRepoMetaFn: func(ctx context.Context, filter func(repoMeta mTypes.RepoMeta) bool,
+				) ([]mTypes.RepoMeta, error) {
+					return nil, ErrTestError
+				},
+			}, metrics, logger)
+
+			err := manager.CheckPutBlob("infra/a", 1)
+			So(errors.Is(err, ErrTestError), ShouldBeTrue)
+
+			_, err = manager.GetUsage()
+			So(err, ShouldNotBeNil)
+
+			manager = quota.NewManager(conf, mocks.MetaDBMock{
+				GetRepoMetaFn: func(ctx context.Context, repo string) (mTypes.RepoMeta, error) {
+					return mTypes.RepoMeta{}, ErrTestError
+				},
+			}, metrics, logger)
+
+			err = manager.CheckPutManifest("infra/a", "v3", ispec.Descriptor{}, nil, ispec.Index{})
+			So(errors.Is(err, ErrTestError), ShouldBeTrue)
+
+			So(func() { manager.ManifestPut("infra/a", "v3", ispec.Descriptor{}, nil, ispec.Index{}) }, ShouldNotPanic)
+		})
+
+		Convey("Nothing is enforced without MetaDB", func() {
+			manager := quota.NewManager(conf, nil, metrics, logger)
+
+			So(manager.CheckPutBlob("infra/a", 1000), ShouldBeNil)
+			So(manager.CheckPutManifest("infra/a", "v3", ispec.Descriptor{Size: 1000}, nil, ispec.Index{}),
+				ShouldBeNil)
+			So(manager.CheckDeleteManifest("infra/a", "v1", ispec.Index{}), ShouldBeNil)
+			manager.ManifestPut("infra/a", "v3", ispec.Descriptor{Size: 1000}, nil, ispec.Index{})
+
+			usages, err := manager.GetUsage()
+			So(err, ShouldBeNil)
+			So(usages, ShouldBeEmpty)
+		})
+	})
+}
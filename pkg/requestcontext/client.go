@@ -0,0 +1,46 @@
+package uac
+
+import (
+	"context"
+)
+
+// request-local context key.
+var clientCtxKey = Key(2) //nolint: gochecknoglobals
+
+// pointer needed for use in context.WithValue.
+func GetClientCtxKey() *Key {
+	return &clientCtxKey
+}
+
+// WithClient returns a derived context holding the client (user agent) which made the request.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, GetClientCtxKey(), client)
+}
+
+// ClientFromContext returns the client saved on the context with WithClient, if any.
+func ClientFromContext(ctx context.Context) string {
+	client, _ := ctx.Value(GetClientCtxKey()).(string)
+
+	return client
+}
+
+// request-local context key.
+var clientIPCtxKey = Key(3) //nolint: gochecknoglobals
+
+// pointer needed for use in context.WithValue.
+func GetClientIPCtxKey() *Key {
+	return &clientIPCtxKey
+}
+
+// WithClientIP returns a derived context holding the ip of the client which made the request,
+// when it is not the remote address of the request, like behind a reverse proxy.
+func WithClientIP(ctx context.Context, clientIP string) context.Context {
+	return context.WithValue(ctx, GetClientIPCtxKey(), clientIP)
+}
+
+// ClientIPFromContext returns the client ip saved on the context with WithClientIP, if any.
+func ClientIPFromContext(ctx context.Context) string {
+	clientIP, _ := ctx.Value(GetClientIPCtxKey()).(string)
+
+	return clientIP
+}
@@ -0,0 +1,25 @@
+package uac
+
+import (
+	"context"
+)
+
+// request-local context key.
+var requestIDCtxKey = Key(4) //nolint: gochecknoglobals
+
+// pointer needed for use in context.WithValue.
+func GetRequestIDCtxKey() *Key {
+	return &requestIDCtxKey
+}
+
+// WithRequestID returns a derived context holding the id of the request, given by the client or generated.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, GetRequestIDCtxKey(), requestID)
+}
+
+// RequestIDFromContext returns the request id saved on the context with WithRequestID, if any.
+func RequestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(GetRequestIDCtxKey()).(string)
+
+	return requestID
+}
@@ -0,0 +1,119 @@
+package uac
+
+import (
+	"fmt"
+	"strings"
+
+	glob "github.com/bmatcuk/doublestar/v4"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/constants"
+)
+
+const (
+	// RepositoryScope grants actions on the repositories matching a glob,
+	// e.g. "repository:team/*:read" or "repository:prod/app:create,update".
+	RepositoryScope = "repository"
+	// SearchScope grants access to the search extension.
+	SearchScope = "search"
+	// UserPrefsScope grants access to the user preferences extension.
+	UserPrefsScope = "userprefs"
+	// AdminScope keeps the admin privileges of its owner, the scoped api keys of admins lack them otherwise.
+	AdminScope = "admin"
+)
+
+// Scope is a parsed api key scope.
+type Scope struct {
+	Type    string
+	Pattern string
+	Actions []string
+}
+
+func scopeActions() []string {
+	return []string{
+		constants.ReadPermission,
+		constants.CreatePermission,
+		constants.UpdatePermission,
+		constants.DeletePermission,
+		constants.DetectManifestCollisionPermission,
+	}
+}
+
+// ParseScope parses a scope string using the "type[:pattern:actions]" grammar.
+func ParseScope(scope string) (Scope, error) {
+	switch scope {
+	case SearchScope, UserPrefsScope, AdminScope:
+		return Scope{Type: scope}, nil
+	}
+
+	if !strings.HasPrefix(scope, RepositoryScope+":") {
+		return Scope{}, fmt.Errorf("%w: %q", zerr.ErrInvalidAPIKeyScope, scope)
+	}
+
+	rest := strings.TrimPrefix(scope, RepositoryScope+":")
+
+	sep := strings.LastIndex(rest, ":")
+	if sep <= 0 || sep == len(rest)-1 {
+		return Scope{}, fmt.Errorf("%w: %q, expected repository:<glob>:<actions>", zerr.ErrInvalidAPIKeyScope, scope)
+	}
+
+	pattern := rest[:sep]
+	if !glob.ValidatePattern(pattern) {
+		return Scope{}, fmt.Errorf("%w: %q, invalid repository pattern", zerr.ErrInvalidAPIKeyScope, scope)
+	}
+
+	actions := strings.Split(rest[sep+1:], ",")
+	for _, action := range actions {
+		if !contains(scopeActions(), action) {
+			return Scope{}, fmt.Errorf("%w: %q, unknown action %q", zerr.ErrInvalidAPIKeyScope, scope, action)
+		}
+	}
+
+	return Scope{Type: RepositoryScope, Pattern: pattern, Actions: actions}, nil
+}
+
+// ParseScopes parses a list of scope strings, failing on the first invalid one.
+func ParseScopes(scopes []string) ([]Scope, error) {
+	parsed := make([]Scope, 0, len(scopes))
+
+	for _, scope := range scopes {
+		parsedScope, err := ParseScope(scope)
+		if err != nil {
+			return nil, err
+		}
+
+		parsed = append(parsed, parsedScope)
+	}
+
+	return parsed, nil
+}
+
+// String formats the scope using the grammar parsed by ParseScope.
+func (scope Scope) String() string {
+	if scope.Type != RepositoryScope {
+		return scope.Type
+	}
+
+	return scope.Type + ":" + scope.Pattern + ":" + strings.Join(scope.Actions, ",")
+}
+
+// allows returns true if the scope grants action on repository.
+func (scope Scope) allows(action, repository string) bool {
+	if scope.Type != RepositoryScope || !contains(scope.Actions, action) {
+		return false
+	}
+
+	matched, err := glob.Match(scope.Pattern, repository)
+
+	return err == nil && matched
+}
+
+func contains(elems []string, elem string) bool {
+	for _, e := range elems {
+		if e == elem {
+			return true
+		}
+	}
+
+	return false
+}
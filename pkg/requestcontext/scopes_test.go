@@ -0,0 +1,139 @@
+package uac_test
+
+import (
+	"errors"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/constants"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
+)
+
+func TestParseScopes(t *testing.T) {
+	Convey("Parse valid scopes", t, func() {
+		scopes, err := reqCtx.ParseScopes([]string{
+			"repository:team/*:read",
+			"repository:prod/app:create,update",
+			"search",
+			"userprefs",
+			"admin",
+		})
+		So(err, ShouldBeNil)
+		So(scopes, ShouldResemble, []reqCtx.Scope{
+			{Type: reqCtx.RepositoryScope, Pattern: "team/*", Actions: []string{"read"}},
+			{Type: reqCtx.RepositoryScope, Pattern: "prod/app", Actions: []string{"create", "update"}},
+			{Type: reqCtx.SearchScope},
+			{Type: reqCtx.UserPrefsScope},
+			{Type: reqCtx.AdminScope},
+		})
+	})
+
+	Convey("Parse invalid scopes", t, func() {
+		for _, scope := range []string{
+			"",
+			"test",
+			"repository",
+			"repository:",
+			"repository:team/*",
+			"repository:team/*:",
+			"repository::read",
+			"repository:team/*:write",
+			"repository:team/[:read",
+			"search:team",
+			"admin:team",
+		} {
+			_, err := reqCtx.ParseScope(scope)
+			So(errors.Is(err, zerr.ErrInvalidAPIKeyScope), ShouldBeTrue)
+		}
+
+		_, err := reqCtx.ParseScopes([]string{"search", "invalid"})
+		So(errors.Is(err, zerr.ErrInvalidAPIKeyScope), ShouldBeTrue)
+	})
+}
+
+func TestScopedUserAccessControl(t *testing.T) {
+	Convey("Unscoped user access control", t, func() {
+		userAc := reqCtx.NewUserAccessControl()
+		userAc.SetUsername("test")
+
+		So(userAc.IsScoped(), ShouldBeFalse)
+		So(userAc.HasScope(reqCtx.SearchScope), ShouldBeTrue)
+		So(userAc.ScopesAllow(constants.CreatePermission, "any/repo"), ShouldBeTrue)
+		So(userAc.Can(constants.CreatePermission, "any/repo"), ShouldBeTrue)
+	})
+
+	Convey("Scoped user access control", t, func() {
+		scopes, err := reqCtx.ParseScopes([]string{"repository:team/*:read", "userprefs"})
+		So(err, ShouldBeNil)
+
+		userAc := reqCtx.NewUserAccessControl()
+		userAc.SetUsername("test")
+		userAc.SetScopes(scopes)
+
+		So(userAc.IsScoped(), ShouldBeTrue)
+		So(userAc.HasScope(reqCtx.SearchScope), ShouldBeFalse)
+		So(userAc.HasScope(reqCtx.UserPrefsScope), ShouldBeTrue)
+		So(userAc.ScopesAllow(constants.ReadPermission, "team/app"), ShouldBeTrue)
+		So(userAc.ScopesAllow(constants.CreatePermission, "team/app"), ShouldBeFalse)
+		So(userAc.ScopesAllow(constants.ReadPermission, "other/app"), ShouldBeFalse)
+
+		// the scopes intersect with the policies
+		userAc.SetGlobPatterns(constants.ReadPermission, map[string]bool{"team/secret": false, "**": true})
+		userAc.SetIsAdmin(false)
+		So(userAc.Can(constants.ReadPermission, "team/app"), ShouldBeTrue)
+		So(userAc.Can(constants.ReadPermission, "team/secret"), ShouldBeFalse)
+
+		// admins are restricted too
+		userAc.SetIsAdmin(true)
+		So(userAc.Can(constants.ReadPermission, "team/app"), ShouldBeTrue)
+		So(userAc.Can(constants.DeletePermission, "team/app"), ShouldBeFalse)
+
+		// and they lose their admin privileges unless the admin scope was granted
+		So(userAc.IsAdmin(), ShouldBeFalse)
+
+		scopes, err = reqCtx.ParseScopes([]string{"repository:team/*:read", "admin"})
+		So(err, ShouldBeNil)
+
+		userAc.SetScopes(scopes)
+		So(userAc.IsAdmin(), ShouldBeTrue)
+		So(userAc.Can(constants.DeletePermission, "team/app"), ShouldBeFalse)
+	})
+
+	Convey("User access control with an authorizer", t, func() {
+		userAc := reqCtx.NewUserAccessControl()
+		userAc.SetUsername("test")
+		userAc.SetIsAdmin(false)
+		userAc.SetGlobPatterns(constants.UpdatePermission, map[string]bool{"**": true})
+
+		// without authorizer, the references are not checked
+		So(userAc.CanOnReference(constants.UpdatePermission, "app", "v1.0"), ShouldBeTrue)
+
+		userAc.SetAuthorizer(func(action, repository, reference string) bool {
+			return reference != "v1.0"
+		}, false)
+
+		So(userAc.CanOnReference(constants.UpdatePermission, "app", "v1.0"), ShouldBeFalse)
+		So(userAc.CanOnReference(constants.UpdatePermission, "app", "dev"), ShouldBeTrue)
+		So(userAc.Can(constants.UpdatePermission, "app"), ShouldBeTrue)
+
+		// unless delegated, Can keeps checking the glob patterns
+		userAc.SetAuthorizer(func(action, repository, reference string) bool {
+			return false
+		}, true)
+
+		So(userAc.Can(constants.UpdatePermission, "app"), ShouldBeFalse)
+
+		// the scopes still apply
+		scopes, err := reqCtx.ParseScopes([]string{"repository:app:read"})
+		So(err, ShouldBeNil)
+
+		userAc.SetScopes(scopes)
+		userAc.SetAuthorizer(func(action, repository, reference string) bool {
+			return true
+		}, false)
+
+		So(userAc.CanOnReference(constants.UpdatePermission, "app", "dev"), ShouldBeFalse)
+	})
+}
@@ -30,12 +30,22 @@ type UserAccessControl struct {
 type UserAuthzInfo struct {
 	// {action: {repo: bool}}
 	globPatterns map[string]map[string]bool
-	isAdmin      bool
+	// {action: [repo]}, denied whatever the other policies
+	deniedGlobPatterns map[string][]string
+	isAdmin            bool
+	// decides on the references of the repositories, enforcing the reference conditions of the policies
+	authorizer func(action, repository, reference string) bool
+	// decides instead of the glob patterns as well when the access control delegates its decisions
+	delegated bool
 }
 
 type UserAuthnInfo struct {
 	groups   []string
 	username string
+	// scopes restrict what the request can do, they are set only when authenticating with a scoped api key
+	scopes []Scope
+	// hash of the api key the request was authenticated with
+	apiKey string
 }
 
 func NewUserAccessControl() *UserAccessControl {
@@ -91,6 +101,79 @@ func (uac *UserAccessControl) GetGroups() []string {
 	return uac.authnInfo.groups
 }
 
+// SetScopes restricts the user's permissions to the given api key scopes.
+func (uac *UserAccessControl) SetScopes(scopes []Scope) {
+	if uac.authnInfo == nil {
+		uac.authnInfo = &UserAuthnInfo{}
+	}
+
+	uac.authnInfo.scopes = scopes
+}
+
+// GetScopes returns the api key scopes restricting the user's permissions, if any.
+func (uac *UserAccessControl) GetScopes() []Scope {
+	if uac.authnInfo == nil {
+		return nil
+	}
+
+	return uac.authnInfo.scopes
+}
+
+// SetAPIKey records the hash of the api key the request was authenticated with.
+func (uac *UserAccessControl) SetAPIKey(hashedKey string) {
+	if uac.authnInfo == nil {
+		uac.authnInfo = &UserAuthnInfo{}
+	}
+
+	uac.authnInfo.apiKey = hashedKey
+}
+
+// GetAPIKey returns the hash of the api key the request was authenticated with, if any.
+func (uac *UserAccessControl) GetAPIKey() string {
+	if uac.authnInfo == nil {
+		return ""
+	}
+
+	return uac.authnInfo.apiKey
+}
+
+// IsScoped returns whether or not the request was authenticated with a scoped api key.
+func (uac *UserAccessControl) IsScoped() bool {
+	return uac.authnInfo != nil && len(uac.authnInfo.scopes) > 0
+}
+
+// HasScope returns whether or not a non repository scope (search, userprefs, admin) was granted.
+// Requests which are not authenticated with a scoped api key have all scopes.
+func (uac *UserAccessControl) HasScope(scopeType string) bool {
+	if !uac.IsScoped() {
+		return true
+	}
+
+	for _, scope := range uac.authnInfo.scopes {
+		if scope.Type == scopeType {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ScopesAllow returns whether or not the api key scopes allow 'action' on 'repository'.
+// Requests which are not authenticated with a scoped api key are not restricted.
+func (uac *UserAccessControl) ScopesAllow(action, repository string) bool {
+	if !uac.IsScoped() {
+		return true
+	}
+
+	for _, scope := range uac.authnInfo.scopes {
+		if scope.allows(action, repository) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (uac *UserAccessControl) IsAnonymous() bool {
 	if uac.authnInfo == nil {
 		return true
@@ -99,7 +182,17 @@ func (uac *UserAccessControl) IsAnonymous() bool {
 	return uac.authnInfo.username == ""
 }
 
+// IsAdmin returns whether or not the user who made the request is an admin, the requests authenticated with
+// a scoped api key lacking the admin scope are not, whatever the user.
 func (uac *UserAccessControl) IsAdmin() bool {
+	if !uac.HasScope(AdminScope) {
+		return false
+	}
+
+	return uac.isAdmin()
+}
+
+func (uac *UserAccessControl) isAdmin() bool {
 	// if isAdmin was not set in authz.go then everybody is admin
 	if uac.authzInfo == nil {
 		return true
@@ -140,18 +233,76 @@ func UserAcFromContext(ctx context.Context) (*UserAccessControl, error) {
 
 func (uac *UserAccessControl) SetGlobPatterns(action string, patterns map[string]bool) {
 	if uac.authzInfo == nil {
-		uac.authzInfo = &UserAuthzInfo{
-			globPatterns: make(map[string]map[string]bool),
-		}
+		uac.authzInfo = &UserAuthzInfo{}
+	}
+
+	if uac.authzInfo.globPatterns == nil {
+		uac.authzInfo.globPatterns = make(map[string]map[string]bool)
 	}
 
 	uac.authzInfo.globPatterns[action] = patterns
 }
 
+func (uac *UserAccessControl) SetDeniedGlobPatterns(action string, patterns []string) {
+	if uac.authzInfo == nil {
+		uac.authzInfo = &UserAuthzInfo{}
+	}
+
+	if uac.authzInfo.deniedGlobPatterns == nil {
+		uac.authzInfo.deniedGlobPatterns = make(map[string][]string)
+	}
+
+	uac.authzInfo.deniedGlobPatterns[action] = patterns
+}
+
+// SetAuthorizer sets the function CanOnReference delegates its decisions to, which enforces the deny policies itself.
+// If delegated, Can delegates its decisions to authorizer too instead of checking the glob patterns.
+func (uac *UserAccessControl) SetAuthorizer(authorizer func(action, repository, reference string) bool,
+	delegated bool,
+) {
+	if uac.authzInfo == nil {
+		uac.authzInfo = &UserAuthzInfo{}
+	}
+
+	uac.authzInfo.authorizer = authorizer
+	uac.authzInfo.delegated = delegated
+}
+
+/*
+CanOnReference returns whether or not the user/anonymous who made the request has 'action' permission on
+'reference' (a tag or a digest) of 'repository', the policies having conditions on the references being enforced
+unlike with Can. Without an authorizer it is the same as Can.
+*/
+func (uac *UserAccessControl) CanOnReference(action, repository, reference string) bool {
+	if uac.authzInfo == nil || uac.authzInfo.authorizer == nil {
+		return uac.Can(action, repository)
+	}
+
+	if !uac.ScopesAllow(action, repository) {
+		return false
+	}
+
+	return uac.authzInfo.authorizer(action, repository, reference)
+}
+
 /*
 Can returns whether or not the user/anonymous who made the request has 'action' permission on 'repository'.
+If the request was authenticated with a scoped api key, the permission must also be granted by its scopes.
 */
 func (uac *UserAccessControl) Can(action, repository string) bool {
+	if !uac.ScopesAllow(action, repository) {
+		return false
+	}
+
+	if uac.authzInfo != nil && uac.authzInfo.authorizer != nil && uac.authzInfo.delegated {
+		return uac.authzInfo.authorizer(action, repository, "")
+	}
+
+	// deny policies take precedence over all the others, including the admin policy
+	if uac.isDenied(action, repository) {
+		return false
+	}
+
 	var defaultRet bool
 	if uac.isBehaviourAction(action) {
 		defaultRet = false
@@ -159,7 +310,8 @@ func (uac *UserAccessControl) Can(action, repository string) bool {
 		defaultRet = true
 	}
 
-	if uac.IsAdmin() {
+	// the scopes were already checked above
+	if uac.isAdmin() {
 		return defaultRet
 	}
 
@@ -191,6 +343,21 @@ func (uac *UserAccessControl) isMethodAction(action string) bool {
 	return false
 }
 
+// returns whether or not 'repository' matches any of the patterns on which the user is denied 'action'.
+func (uac *UserAccessControl) isDenied(action, repository string) bool {
+	if uac.authzInfo == nil {
+		return false
+	}
+
+	for _, pattern := range uac.authzInfo.deniedGlobPatterns[action] {
+		if matched, err := glob.Match(pattern, repository); err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
 // returns whether or not glob patterns have been set in authz.go.
 func (uac *UserAccessControl) areGlobPatternsSet() bool {
 	notSet := uac.authzInfo == nil || uac.authzInfo.globPatterns == nil
@@ -13,6 +13,7 @@ import (
 	zlog "zotregistry.dev/zot/pkg/log"
 	mTypes "zotregistry.dev/zot/pkg/meta/types"
 	"zotregistry.dev/zot/pkg/retention/types"
+	storageCommon "zotregistry.dev/zot/pkg/storage/common"
 )
 
 const (
@@ -21,6 +22,7 @@ const (
 	filteredByTagNames = "didn't meet any tag 'patterns' rules"
 	// reasons for retention.
 	retainedStrFormat = "retained by %s policy"
+	retainedImmutable = "retained by immutable tags policy"
 )
 
 type candidatesRules struct {
@@ -30,18 +32,22 @@ type candidatesRules struct {
 }
 
 type policyManager struct {
-	config   config.ImageRetention
-	regex    *RegexMatcher
-	log      zlog.Logger
-	auditLog *zlog.Logger
+	config        config.ImageRetention
+	immutableTags *storageCommon.ImmutableTags
+	regex         *RegexMatcher
+	log           zlog.Logger
+	auditLog      *zlog.Logger
 }
 
-func NewPolicyManager(config config.ImageRetention, log zlog.Logger, auditLog *zlog.Logger) policyManager {
+func NewPolicyManager(config config.ImageRetention, immutableTags *storageCommon.ImmutableTags,
+	log zlog.Logger, auditLog *zlog.Logger,
+) policyManager {
 	return policyManager{
-		config:   config,
-		regex:    NewRegexMatcher(),
-		log:      log,
-		auditLog: auditLog,
+		config:        config,
+		immutableTags: immutableTags,
+		regex:         NewRegexMatcher(),
+		log:           log,
+		auditLog:      auditLog,
 	}
 }
 
@@ -131,6 +137,23 @@ func (p policyManager) GetRetainedTags(ctx context.Context, repoMeta mTypes.Repo
 		}
 	}
 
+	// immutable tags are always retained and are not counted by the tag retention rules
+	mutableCandidates := make([]*types.Candidate, 0, len(candidates))
+
+	for _, candidate := range candidates {
+		if p.immutableTags.IsTagImmutable(repo, candidate.Tag) {
+			logAction(repo, "keep", retainedImmutable, candidate, p.config.DryRun, &p.log)
+
+			retainTags = append(retainTags, candidate.Tag)
+
+			continue
+		}
+
+		mutableCandidates = append(mutableCandidates, candidate)
+	}
+
+	candidates = mutableCandidates
+
 	// group all tags by tag policy
 	grouped := p.groupCandidatesByTagPolicy(repo, candidates)
 
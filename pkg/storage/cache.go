@@ -10,6 +10,7 @@ import (
 	"zotregistry.dev/zot/pkg/storage/cache"
 	"zotregistry.dev/zot/pkg/storage/constants"
 	storageTypes "zotregistry.dev/zot/pkg/storage/types"
+	"zotregistry.dev/zot/pkg/tracing"
 )
 
 func CreateCacheDatabaseDriver(storageConfig config.StorageConfig, log zlog.Logger) (storageTypes.Cache, error) {
@@ -67,6 +68,17 @@ func CreateCacheDatabaseDriver(storageConfig config.StorageConfig, log zlog.Logg
 	return nil, nil //nolint:nilnil
 }
 
+// createCacheDriver creates the cache driver of a storage, tracing its calls if tracing is enabled.
+func createCacheDriver(cfg *config.Config, storageConfig config.StorageConfig, log zlog.Logger,
+) (storageTypes.Cache, error) {
+	cacheDriver, err := CreateCacheDatabaseDriver(storageConfig, log)
+	if err != nil || !cfg.IsTracingEnabled() {
+		return cacheDriver, err
+	}
+
+	return tracing.Cache(cacheDriver), nil
+}
+
 func Create(dbtype string, parameters interface{}, log zlog.Logger) (storageTypes.Cache, error) {
 	switch dbtype {
 	case "boltdb":
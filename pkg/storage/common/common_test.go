@@ -13,6 +13,7 @@ import (
 	. "github.com/smartystreets/goconvey/convey"
 
 	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
 	"zotregistry.dev/zot/pkg/extensions/monitoring"
 	"zotregistry.dev/zot/pkg/log"
 	"zotregistry.dev/zot/pkg/storage"
@@ -497,6 +498,68 @@ func TestIsSignature(t *testing.T) {
 	})
 }
 
+func TestImmutableTags(t *testing.T) {
+	Convey("Immutable tags policies", t, func(c C) {
+		policies := []config.ImmutableTagsPolicy{
+			{Repositories: []string{"infra/**"}, Patterns: []string{`^v\d+\.\d+\.\d+$`}},
+			{Repositories: []string{"golden"}},
+		}
+
+		immutableTags, err := common.NewImmutableTags(policies)
+		So(err, ShouldBeNil)
+
+		var noImmutableTags *common.ImmutableTags
+
+		So(immutableTags.IsTagImmutable("infra/a/b", "v1.0.0"), ShouldBeTrue)
+		So(immutableTags.IsTagImmutable("infra/a/b", "latest"), ShouldBeFalse)
+		So(immutableTags.IsTagImmutable("other", "v1.0.0"), ShouldBeFalse)
+		So(immutableTags.IsTagImmutable("golden", "latest"), ShouldBeTrue)
+		So(noImmutableTags.IsTagImmutable("golden", "latest"), ShouldBeFalse)
+
+		_, err = common.NewImmutableTags([]config.ImmutableTagsPolicy{{Patterns: []string{"["}}})
+		So(errors.Is(err, zerr.ErrBadConfig), ShouldBeTrue)
+
+		digest1 := godigest.FromString("manifest1")
+		digest2 := godigest.FromString("manifest2")
+
+		index := ispec.Index{
+			Manifests: []ispec.Descriptor{
+				{Digest: digest1, Annotations: map[string]string{ispec.AnnotationRefName: "v1.0.0"}},
+				{Digest: digest1, Annotations: map[string]string{ispec.AnnotationRefName: "latest"}},
+				{Digest: digest2, Annotations: map[string]string{ispec.AnnotationRefName: "dev"}},
+			},
+		}
+
+		Convey("Existing immutable tags can not be re-pointed", func() {
+			desc1 := ispec.Descriptor{Digest: digest1}
+			desc2 := ispec.Descriptor{Digest: digest2}
+
+			err := immutableTags.CheckPutManifest("infra/a", "v1.0.0", desc2, nil, index)
+			So(errors.Is(err, zerr.ErrImmutableTag), ShouldBeTrue)
+			So(zerr.GetDetails(err)["tag"], ShouldEqual, "v1.0.0")
+
+			So(immutableTags.CheckPutManifest("infra/a", "v1.0.0", desc1, nil, index), ShouldBeNil)
+			So(immutableTags.CheckPutManifest("infra/a", "v2.0.0", desc2, nil, index), ShouldBeNil)
+			So(immutableTags.CheckPutManifest("infra/a", "latest", desc2, nil, index), ShouldBeNil)
+			So(immutableTags.CheckPutManifest("infra/a", digest2.String(), desc2, nil, index), ShouldBeNil)
+			So(immutableTags.CheckPutManifest("other", "v1.0.0", desc2, nil, index), ShouldBeNil)
+		})
+
+		Convey("Immutable tags can not be deleted by tag or by digest", func() {
+			err := immutableTags.CheckDeleteManifest("infra/a", "v1.0.0", index)
+			So(errors.Is(err, zerr.ErrImmutableTag), ShouldBeTrue)
+
+			err = immutableTags.CheckDeleteManifest("infra/a", digest1.String(), index)
+			So(errors.Is(err, zerr.ErrImmutableTag), ShouldBeTrue)
+
+			So(immutableTags.CheckDeleteManifest("infra/a", "latest", index), ShouldBeNil)
+			So(immutableTags.CheckDeleteManifest("infra/a", digest2.String(), index), ShouldBeNil)
+			So(immutableTags.CheckDeleteManifest("other", "v1.0.0", index), ShouldBeNil)
+			So(noImmutableTags.CheckDeleteManifest("infra/a", "v1.0.0", index), ShouldBeNil)
+		})
+	})
+}
+
 func TestDedupeGeneratorErrors(t *testing.T) {
 	log := log.Logger{Logger: zerolog.New(os.Stdout)}
 
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"fmt"
+	"regexp"
+
+	glob "github.com/bmatcuk/doublestar/v4"
+	ispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	zcommon "zotregistry.dev/zot/pkg/common"
+)
+
+// ImmutableTags enforces the immutable tags policies of a storage, their patterns being compiled once
+// when the configuration is loaded. A nil *ImmutableTags makes no tag immutable.
+type ImmutableTags struct {
+	policies []immutableTagsPolicy
+}
+
+type immutableTagsPolicy struct {
+	repositories []string
+	patterns     []*regexp.Regexp
+}
+
+// NewImmutableTags compiles the immutable tags policies of a storage, returning ErrBadConfig
+// if any of their patterns is invalid.
+func NewImmutableTags(policies []config.ImmutableTagsPolicy) (*ImmutableTags, error) {
+	if len(policies) == 0 {
+		return nil, nil //nolint: nilnil
+	}
+
+	immutableTags := &ImmutableTags{policies: make([]immutableTagsPolicy, 0, len(policies))}
+
+	for _, policy := range policies {
+		compiled := immutableTagsPolicy{repositories: policy.Repositories}
+
+		for _, pattern := range policy.Patterns {
+			regex, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("%w: immutable tags regex could not be compiled: %s: %w",
+					zerr.ErrBadConfig, pattern, err)
+			}
+
+			compiled.patterns = append(compiled.patterns, regex)
+		}
+
+		immutableTags.policies = append(immutableTags.policies, compiled)
+	}
+
+	return immutableTags, nil
+}
+
+// IsTagImmutable returns true if tag matches any of the immutable tags policies applying to repo.
+func (it *ImmutableTags) IsTagImmutable(repo, tag string) bool {
+	if it == nil {
+		return false
+	}
+
+	for _, policy := range it.policies {
+		if !matchesAnyGlob(policy.repositories, repo) {
+			continue
+		}
+
+		if len(policy.patterns) == 0 {
+			return true
+		}
+
+		for _, pattern := range policy.patterns {
+			if pattern.MatchString(tag) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// CheckPutManifest returns ErrImmutableTag if pushing desc would re-point an existing immutable tag,
+// pushing the same manifest again is allowed.
+func (it *ImmutableTags) CheckPutManifest(repo, reference string, desc ispec.Descriptor, body []byte,
+	index ispec.Index,
+) error {
+	if !zcommon.IsTag(reference) || !it.IsTagImmutable(repo, reference) {
+		return nil
+	}
+
+	current, ok := GetManifestDescByReference(index, reference)
+	if ok && current.Digest != desc.Digest {
+		return newImmutableTagError(repo, reference)
+	}
+
+	return nil
+}
+
+// ManifestPut has nothing to record, the tags are checked against the current index.
+func (it *ImmutableTags) ManifestPut(repo, reference string, desc ispec.Descriptor, body []byte,
+	index ispec.Index,
+) {
+}
+
+// CheckDeleteManifest returns ErrImmutableTag if deleting reference would remove an immutable tag,
+// deleting by digest removing all the tags of that manifest.
+func (it *ImmutableTags) CheckDeleteManifest(repo, reference string, index ispec.Index) error {
+	if it == nil {
+		return nil
+	}
+
+	for _, desc := range index.Manifests {
+		tag, ok := desc.Annotations[ispec.AnnotationRefName]
+		if !ok || (tag != reference && desc.Digest.String() != reference) {
+			continue
+		}
+
+		if it.IsTagImmutable(repo, tag) {
+			return newImmutableTagError(repo, tag)
+		}
+	}
+
+	return nil
+}
+
+// CheckPutBlob allows all blobs, tags only point to manifests.
+func (it *ImmutableTags) CheckPutBlob(repo string, size int64) error {
+	return nil
+}
+
+func matchesAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		matched, err := glob.Match(pattern, name)
+		if err == nil && matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func newImmutableTagError(repo, tag string) error {
+	return zerr.NewError(zerr.ErrImmutableTag).AddDetail("name", repo).AddDetail("tag", tag)
+}
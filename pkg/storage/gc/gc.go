@@ -38,15 +38,24 @@ type Options struct {
 	Delay time.Duration
 
 	ImageRetention config.ImageRetention
+
+	// returns the policies of the tags which are never removed, if set; looked up on each run,
+	// so that the config reloads apply
+	ImmutableTags func() *common.ImmutableTags
+
+	// notified of the removed manifests, if set
+	Notifier mTypes.ManifestNotifier
 }
 
 type GarbageCollect struct {
-	imgStore  types.ImageStore
-	opts      Options
-	metaDB    mTypes.MetaDB
-	policyMgr rTypes.PolicyManager
-	auditLog  *zlog.Logger
-	log       zlog.Logger
+	imgStore types.ImageStore
+	opts     Options
+	metaDB   mTypes.MetaDB
+	// the immutable tags policies of the current run, and the retention policies applying them
+	immutableTags *common.ImmutableTags
+	policyMgr     rTypes.PolicyManager
+	auditLog      *zlog.Logger
+	log           zlog.Logger
 }
 
 func NewGarbageCollect(imgStore types.ImageStore, metaDB mTypes.MetaDB, opts Options,
@@ -56,12 +65,24 @@ func NewGarbageCollect(imgStore types.ImageStore, metaDB mTypes.MetaDB, opts Opt
 		imgStore:  imgStore,
 		metaDB:    metaDB,
 		opts:      opts,
-		policyMgr: retention.NewPolicyManager(opts.ImageRetention, log, auditLog),
+		policyMgr: retention.NewPolicyManager(opts.ImageRetention, nil, log, auditLog),
 		auditLog:  auditLog,
 		log:       log,
 	}
 }
 
+// withCurrentImmutableTags returns a copy of gc applying the immutable tags policies currently configured.
+func (gc GarbageCollect) withCurrentImmutableTags() GarbageCollect {
+	if gc.opts.ImmutableTags == nil {
+		return gc
+	}
+
+	gc.immutableTags = gc.opts.ImmutableTags()
+	gc.policyMgr = retention.NewPolicyManager(gc.opts.ImageRetention, gc.immutableTags, gc.log, gc.auditLog)
+
+	return gc
+}
+
 /*
 CleanImageStorePeriodically runs a periodic garbage collect on the ImageStore provided in constructor,
 given an interval and a Scheduler.
@@ -85,7 +106,7 @@ func (gc GarbageCollect) CleanRepo(ctx context.Context, repo string) error {
 	gc.log.Info().Str("module", "gc").
 		Msg("executing gc of orphaned blobs for " + path.Join(gc.imgStore.RootDir(), repo))
 
-	if err := gc.cleanRepo(ctx, repo); err != nil {
+	if err := gc.withCurrentImmutableTags().cleanRepo(ctx, repo); err != nil {
 		errMessage := "failed to run GC for " + path.Join(gc.imgStore.RootDir(), repo)
 		gc.log.Error().Err(err).Str("module", "gc").Msg(errMessage)
 		gc.log.Info().Str("module", "gc").
@@ -108,8 +129,8 @@ func (gc GarbageCollect) cleanRepo(ctx context.Context, repo string) error {
 		return zerr.ErrRepoNotFound
 	}
 
-	gc.imgStore.Lock(&lockLatency)
-	defer gc.imgStore.Unlock(&lockLatency)
+	gc.imgStore.LockRepo(repo, &lockLatency)
+	defer gc.imgStore.UnlockRepo(repo, &lockLatency)
 
 	/* this index (which represents the index.json of this repo) is the root point from which we
 	search for dangling manifests/blobs
@@ -420,6 +441,13 @@ func (gc GarbageCollect) gcManifest(repo string, index *ispec.Index, desc ispec.
 func (gc GarbageCollect) removeManifest(repo string, index *ispec.Index,
 	desc ispec.Descriptor, reference string, signatureType string, subjectDigest godigest.Digest,
 ) (bool, error) {
+	if err := gc.immutableTags.CheckDeleteManifest(repo, reference, *index); err != nil {
+		gc.log.Info().Err(err).Str("module", "gc").Str("repository", repo).Str("reference", reference).
+			Str("decision", "keep").Str("reason", "immutable tag").Msg("skipping removal of manifest")
+
+		return false, nil
+	}
+
 	_, err := common.RemoveManifestDescByReference(index, reference, true)
 	if err != nil {
 		if errors.Is(err, zerr.ErrManifestConflict) {
@@ -447,7 +475,7 @@ func (gc GarbageCollect) removeManifest(repo string, index *ispec.Index,
 				return false, err
 			}
 		} else {
-			err := gc.metaDB.RemoveRepoReference(repo, reference, desc.Digest)
+			err := gc.metaDB.RemoveRepoReference(context.Background(), repo, reference, desc.Digest)
 			if err != nil {
 				gc.log.Error().Err(err).Str("module", "gc").Str("component", "metadb").
 					Msg("failed to remove repo reference in metaDB")
@@ -457,9 +485,29 @@ func (gc GarbageCollect) removeManifest(repo string, index *ispec.Index,
 		}
 	}
 
+	gc.notifyManifestDeleted(repo, reference, desc)
+
 	return true, nil
 }
 
+// notifyManifestDeleted notifies the manifests removed by gc and retention like the deleted ones,
+// its content being still in storage until the unreferenced blobs are removed.
+func (gc GarbageCollect) notifyManifestDeleted(repo, reference string, desc ispec.Descriptor) {
+	if gc.opts.Notifier == nil {
+		return
+	}
+
+	body, err := gc.imgStore.GetBlobContent(repo, desc.Digest)
+	if err != nil {
+		gc.log.Error().Err(err).Str("module", "gc").Str("repository", repo).Str("digest", desc.Digest.String()).
+			Msg("failed to read removed manifest, no event sent")
+
+		return
+	}
+
+	gc.opts.Notifier.OnManifestDeleted(repo, reference, desc.MediaType, desc.Digest, body)
+}
+
 func (gc GarbageCollect) removeUntaggedManifests(repo string, index *ispec.Index,
 	referenced map[godigest.Digest]bool,
 ) (bool, error) {
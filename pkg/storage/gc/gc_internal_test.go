@@ -334,6 +334,38 @@ func TestGarbageCollectWithMockedImageStore(t *testing.T) {
 			So(err, ShouldNotBeNil)
 		})
 
+		Convey("Immutable tags are skipped by gc.removeManifest()", func() {
+			immutableTags, err := common.NewImmutableTags([]config.ImmutableTagsPolicy{{Repositories: []string{"**"}}})
+			So(err, ShouldBeNil)
+
+			gcOptions.ImmutableTags = func() *common.ImmutableTags { return immutableTags }
+
+			gc := NewGarbageCollect(mocks.MockedImageStore{}, mocks.MetaDBMock{}, gcOptions, audit, log).
+				withCurrentImmutableTags()
+
+			desc := ispec.Descriptor{
+				Digest:      godigest.FromString("manifest"),
+				Annotations: map[string]string{ispec.AnnotationRefName: "tag"},
+			}
+			index := &ispec.Index{Manifests: []ispec.Descriptor{desc}}
+
+			gced, err := gc.removeManifest(repoName, index, desc, desc.Digest.String(), "", "")
+			So(err, ShouldBeNil)
+			So(gced, ShouldBeFalse)
+			So(index.Manifests, ShouldHaveLength, 1)
+
+			// the policies are looked up again on the next run
+			immutableTags = nil
+
+			gc = gc.withCurrentImmutableTags()
+			So(gc.immutableTags, ShouldBeNil)
+
+			gced, err = gc.removeManifest(repoName, index, desc, desc.Digest.String(), "", "")
+			So(err, ShouldBeNil)
+			So(gced, ShouldBeTrue)
+			So(index.Manifests, ShouldHaveLength, 0)
+		})
+
 		Convey("Error on metaDB in gc.cleanRepo()", func() {
 			gcOptions := Options{
 				Delay: storageConstants.DefaultGCDelay,
@@ -573,7 +605,8 @@ func TestGarbageCollectWithMockedImageStore(t *testing.T) {
 			}
 
 			metaDB := mocks.MetaDBMock{
-				RemoveRepoReferenceFn: func(repo, reference string, manifestDigest godigest.Digest) error {
+				RemoveRepoReferenceFn: func(ctx context.Context, repo, reference string, manifestDigest godigest.Digest,
+				) error {
 					return errGC
 				},
 			}
@@ -27,6 +27,7 @@ import (
 	"zotregistry.dev/zot/pkg/meta/dynamodb"
 	mTypes "zotregistry.dev/zot/pkg/meta/types"
 	"zotregistry.dev/zot/pkg/storage"
+	storageCommon "zotregistry.dev/zot/pkg/storage/common"
 	storageConstants "zotregistry.dev/zot/pkg/storage/constants"
 	"zotregistry.dev/zot/pkg/storage/gc"
 	"zotregistry.dev/zot/pkg/storage/local"
@@ -750,6 +751,92 @@ func TestGarbageCollectAndRetention(t *testing.T) {
 					So(tags, ShouldNotContain, "0.0.8")
 				})
 
+				Convey("retain immutable tags besides the 3 most recently pushed images", func() {
+					immutableTags, err := storageCommon.NewImmutableTags([]config.ImmutableTagsPolicy{
+						{
+							Repositories: []string{"retention"},
+							Patterns:     []string{`^0\.0\.[12]$`},
+						},
+					})
+					So(err, ShouldBeNil)
+
+					gc := gc.NewGarbageCollect(imgStore, metaDB, gc.Options{
+						Delay: storageConstants.DefaultGCDelay,
+						ImageRetention: config.ImageRetention{
+							Delay: storageConstants.DefaultRetentionDelay,
+							Policies: []config.RetentionPolicy{
+								{
+									Repositories:    []string{"**"},
+									DeleteReferrers: true,
+									DeleteUntagged:  &trueVal,
+									KeepTags: []config.KeepTagsPolicy{
+										{
+											Patterns:                []string{".*"},
+											MostRecentlyPushedCount: 3,
+										},
+									},
+								},
+							},
+						},
+						ImmutableTags: func() *storageCommon.ImmutableTags { return immutableTags },
+					}, audit, log)
+
+					err = gc.CleanRepo(ctx, "retention")
+					So(err, ShouldBeNil)
+
+					tags, err := imgStore.GetImageTags("retention")
+					So(err, ShouldBeNil)
+
+					So(tags, ShouldContain, "0.0.1")
+					So(tags, ShouldContain, "0.0.2")
+					So(tags, ShouldContain, "0.0.4")
+					So(tags, ShouldContain, "0.0.5")
+					So(tags, ShouldContain, "0.0.6")
+
+					So(tags, ShouldNotContain, "0.0.3")
+					So(tags, ShouldNotContain, "0.0.7")
+					So(tags, ShouldNotContain, "0.0.8")
+				})
+
+				Convey("gc does not remove immutable tags", func() {
+					immutableTags, err := storageCommon.NewImmutableTags([]config.ImmutableTagsPolicy{
+						{
+							Repositories: []string{"gc-test1"},
+						},
+					})
+					So(err, ShouldBeNil)
+
+					gc := gc.NewGarbageCollect(imgStore, metaDB, gc.Options{
+						Delay: 1 * time.Millisecond,
+						ImageRetention: config.ImageRetention{
+							Delay: 1 * time.Millisecond,
+							Policies: []config.RetentionPolicy{
+								{
+									Repositories:    []string{"gc-test1"},
+									DeleteReferrers: true,
+									DeleteUntagged:  &trueVal,
+									KeepTags: []config.KeepTagsPolicy{
+										{
+											Patterns: []string{"v1"}, // should not match any tag
+										},
+									},
+								},
+							},
+						},
+						ImmutableTags: func() *storageCommon.ImmutableTags { return immutableTags },
+					}, audit, log)
+
+					err = gc.CleanRepo(ctx, "gc-test1")
+					So(err, ShouldBeNil)
+
+					_, _, _, err = imgStore.GetImageManifest("gc-test1", gcTest1.DigestStr())
+					So(err, ShouldBeNil)
+
+					repos, err := imgStore.GetRepositories()
+					So(err, ShouldBeNil)
+					So(repos, ShouldContain, "gc-test1")
+				})
+
 				Convey("retain 3 most recently pulled images", func() {
 					gc := gc.NewGarbageCollect(imgStore, metaDB, gc.Options{
 						Delay: storageConstants.DefaultGCDelay,
@@ -1455,3 +1542,89 @@ func readTagsFromStorage(rootDir, repoName string, digest godigest.Digest) ([]st
 
 	return result, nil
 }
+
+type manifestNotification struct {
+	reference string
+	digest    godigest.Digest
+	deleted   bool
+}
+
+type recordingNotifier struct {
+	notifications []manifestNotification
+}
+
+func (rn *recordingNotifier) OnManifestUpdated(repo, reference, mediaType string, digest godigest.Digest,
+	body []byte,
+) {
+	rn.notifications = append(rn.notifications, manifestNotification{reference: reference, digest: digest})
+}
+
+func (rn *recordingNotifier) OnManifestDeleted(repo, reference, mediaType string, digest godigest.Digest,
+	body []byte,
+) {
+	rn.notifications = append(rn.notifications,
+		manifestNotification{reference: reference, digest: digest, deleted: true})
+}
+
+func TestGarbageCollectNotifications(t *testing.T) {
+	Convey("Manifests removed by gc and retention are notified", t, func() {
+		log := zlog.NewLogger("debug", "")
+		audit := zlog.NewAuditLogger("debug", "/dev/null")
+		metrics := monitoring.NewMetricsServer(false, log)
+
+		rootDir := t.TempDir()
+		imgStore := local.NewImageStore(rootDir, false, false, log, metrics, nil, nil, nil)
+
+		boltDriver, err := boltdb.GetBoltDriver(boltdb.DBParameters{RootDir: rootDir})
+		So(err, ShouldBeNil)
+
+		metaDB, err := boltdb.New(boltDriver, log)
+		So(err, ShouldBeNil)
+
+		storeController := storage.StoreController{DefaultStore: imgStore}
+
+		repoName := "repo"
+		keptImage := CreateRandomImage()
+		droppedImage := CreateRandomImage()
+		untaggedImage := CreateRandomImage()
+
+		So(WriteImageToFileSystem(keptImage, repoName, "keep", storeController), ShouldBeNil)
+		So(WriteImageToFileSystem(droppedImage, repoName, "drop", storeController), ShouldBeNil)
+		So(WriteImageToFileSystem(untaggedImage, repoName, untaggedImage.DigestStr(), storeController), ShouldBeNil)
+
+		err = meta.ParseStorage(metaDB, storeController, log) //nolint: contextcheck
+		So(err, ShouldBeNil)
+
+		trueVal := true
+		notifier := &recordingNotifier{}
+
+		gc := gc.NewGarbageCollect(imgStore, metaDB, gc.Options{
+			Delay: 1 * time.Millisecond,
+			ImageRetention: config.ImageRetention{
+				Delay: 1 * time.Millisecond,
+				Policies: []config.RetentionPolicy{
+					{
+						Repositories:   []string{"**"},
+						DeleteUntagged: &trueVal,
+						KeepTags:       []config.KeepTagsPolicy{{Patterns: []string{"keep"}}},
+					},
+				},
+			},
+			Notifier: notifier,
+		}, audit, log)
+
+		time.Sleep(10 * time.Millisecond)
+
+		err = gc.CleanRepo(context.Background(), repoName)
+		So(err, ShouldBeNil)
+
+		// the manifest of the removed tag is then removed as untagged
+		So(notifier.notifications, ShouldHaveLength, 3)
+		So(notifier.notifications, ShouldContain,
+			manifestNotification{reference: "drop", digest: droppedImage.Digest(), deleted: true})
+		So(notifier.notifications, ShouldContain,
+			manifestNotification{reference: droppedImage.DigestStr(), digest: droppedImage.Digest(), deleted: true})
+		So(notifier.notifications, ShouldContain,
+			manifestNotification{reference: untaggedImage.DigestStr(), digest: untaggedImage.Digest(), deleted: true})
+	})
+}
@@ -9,6 +9,7 @@ import (
 	"io"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -26,6 +27,7 @@ import (
 	syncConstants "zotregistry.dev/zot/pkg/extensions/sync/constants"
 	zlog "zotregistry.dev/zot/pkg/log"
 	zreg "zotregistry.dev/zot/pkg/regexp"
+	reqCtx "zotregistry.dev/zot/pkg/requestcontext"
 	"zotregistry.dev/zot/pkg/scheduler"
 	common "zotregistry.dev/zot/pkg/storage/common"
 	storageConstants "zotregistry.dev/zot/pkg/storage/constants"
@@ -42,14 +44,20 @@ const (
 type ImageStore struct {
 	rootDir     string
 	storeDriver storageTypes.Driver
-	lock        *sync.RWMutex
-	log         zlog.Logger
-	metrics     monitoring.MetricServer
-	cache       storageTypes.Cache
-	dedupe      bool
-	linter      common.Lint
-	commit      bool
-	compat      []compat.MediaCompatibility
+	lock        *ImageStoreLock
+	// serializes dedupe cache updates, which may move blobs across repositories, the blob readers
+	// holding it in read mode while resolving a deduped blob to its content and opening it
+	cacheLock *sync.RWMutex
+	log       zlog.Logger
+	metrics   monitoring.MetricServer
+	cache     storageTypes.Cache
+	dedupe    bool
+	linter    common.Lint
+	commit    bool
+	compat    []compat.MediaCompatibility
+	// protects writePolicies, which are replaced when the configuration is reloaded
+	policyLock    *sync.RWMutex
+	writePolicies []storageTypes.WritePolicy
 }
 
 func (is *ImageStore) Name() string {
@@ -80,7 +88,8 @@ func NewImageStore(rootDir string, cacheDir string, dedupe, commit bool, log zlo
 	imgStore := &ImageStore{
 		rootDir:     rootDir,
 		storeDriver: storeDriver,
-		lock:        &sync.RWMutex{},
+		lock:        NewImageStoreLock(),
+		cacheLock:   &sync.RWMutex{},
 		log:         log,
 		metrics:     metrics,
 		dedupe:      dedupe,
@@ -88,12 +97,132 @@ func NewImageStore(rootDir string, cacheDir string, dedupe, commit bool, log zlo
 		commit:      commit,
 		cache:       cacheDriver,
 		compat:      compat,
+		policyLock:  &sync.RWMutex{},
 	}
 
 	return imgStore
 }
 
-// RLock read-lock.
+// SetWritePolicies replaces the policies enforced on the writes to the repositories of the store.
+func (is *ImageStore) SetWritePolicies(policies ...storageTypes.WritePolicy) {
+	is.policyLock.Lock()
+	defer is.policyLock.Unlock()
+
+	is.writePolicies = policies
+}
+
+// WithoutWritePolicies returns a view of the store, sharing its content and its locks, on which the given
+// write policies, or all of them if none is given, are not enforced. It is used by the admins overriding
+// a policy and to restore a repository after a failed write.
+func (is *ImageStore) WithoutWritePolicies(policies ...storageTypes.WritePolicy) storageTypes.ImageStore {
+	view := *is
+	view.policyLock = &sync.RWMutex{}
+	view.writePolicies = nil
+
+	if len(policies) == 0 {
+		return &view
+	}
+
+	for _, policy := range is.getWritePolicies() {
+		if !slices.Contains(policies, policy) {
+			view.writePolicies = append(view.writePolicies, policy)
+		}
+	}
+
+	return &view
+}
+
+// WithRequestLogger returns a view of the store, sharing its content, its locks and its write policies, whose
+// events carry the id of the request handled with ctx. The events of its storage and cache drivers do not.
+func (is *ImageStore) WithRequestLogger(ctx context.Context) storageTypes.ImageStore {
+	if reqCtx.RequestIDFromContext(ctx) == "" {
+		return is
+	}
+
+	// the write policies are replaced under their lock when the configuration is reloaded
+	is.policyLock.RLock()
+	view := *is
+	is.policyLock.RUnlock()
+
+	view.policyLock = &sync.RWMutex{}
+	view.log = zlog.Ctx(ctx, is.log)
+
+	return &view
+}
+
+func (is *ImageStore) getWritePolicies() []storageTypes.WritePolicy {
+	is.policyLock.RLock()
+	defer is.policyLock.RUnlock()
+
+	return is.writePolicies
+}
+
+// checkPutManifest returns the error of the first write policy rejecting giving reference to desc in repo,
+// the caller MUST lock the repository.
+func (is *ImageStore) checkPutManifest(repo, reference string, desc ispec.Descriptor, body []byte) error {
+	policies := is.getWritePolicies()
+	if len(policies) == 0 {
+		return nil
+	}
+
+	index, err := common.GetIndex(is, repo, is.log)
+	if err != nil {
+		// nothing to protect in a repository without an index, the other errors are returned by the write itself
+		return nil //nolint: nilerr
+	}
+
+	for _, policy := range policies {
+		if err := policy.CheckPutManifest(repo, reference, desc, body, index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// manifestPut tells the write policies reference was given to desc in repo, index being the former index of repo,
+// the caller MUST lock the repository.
+func (is *ImageStore) manifestPut(repo, reference string, desc ispec.Descriptor, body []byte, index ispec.Index) {
+	for _, policy := range is.getWritePolicies() {
+		policy.ManifestPut(repo, reference, desc, body, index)
+	}
+}
+
+// checkDeleteManifest returns the error of the first write policy rejecting removing reference from repo,
+// the caller MUST lock the repository.
+func (is *ImageStore) checkDeleteManifest(repo, reference string) error {
+	policies := is.getWritePolicies()
+	if len(policies) == 0 {
+		return nil
+	}
+
+	index, err := common.GetIndex(is, repo, is.log)
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range policies {
+		if err := policy.CheckDeleteManifest(repo, reference, index); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkPutBlob returns the error of the first write policy rejecting adding a blob of the given size to repo,
+// the caller MUST lock the repository.
+func (is *ImageStore) checkPutBlob(repo string, size int64) error {
+	for _, policy := range is.getWritePolicies() {
+		if err := policy.CheckPutBlob(repo, size); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RLock read-lock, excludes the store-wide write-lock only.
 func (is *ImageStore) RLock(lockStart *time.Time) {
 	*lockStart = time.Now()
 
@@ -110,14 +239,14 @@ func (is *ImageStore) RUnlock(lockStart *time.Time) {
 	monitoring.ObserveStorageLockLatency(is.metrics, latency, is.RootDir(), storageConstants.RLOCK) // histogram
 }
 
-// Lock write-lock.
+// Lock store-wide write-lock, excludes all the other locks, including the repository ones.
 func (is *ImageStore) Lock(lockStart *time.Time) {
 	*lockStart = time.Now()
 
 	is.lock.Lock()
 }
 
-// Unlock write-unlock.
+// Unlock store-wide write-unlock.
 func (is *ImageStore) Unlock(lockStart *time.Time) {
 	is.lock.Unlock()
 
@@ -127,6 +256,57 @@ func (is *ImageStore) Unlock(lockStart *time.Time) {
 	monitoring.ObserveStorageLockLatency(is.metrics, latency, is.RootDir(), storageConstants.RWLOCK) // histogram
 }
 
+// RLockRepo read-lock for a single repository.
+func (is *ImageStore) RLockRepo(repo string, lockStart *time.Time) {
+	*lockStart = time.Now()
+
+	is.lock.RLockRepo(repo)
+}
+
+// RUnlockRepo read-unlock for a single repository.
+func (is *ImageStore) RUnlockRepo(repo string, lockStart *time.Time) {
+	is.lock.RUnlockRepo(repo)
+
+	lockEnd := time.Now()
+	// includes time spent in acquiring and holding a lock
+	latency := lockEnd.Sub(*lockStart)
+	monitoring.ObserveStorageLockLatency(is.metrics, latency, is.RootDir(), storageConstants.RLOCK) // histogram
+}
+
+// LockRepo write-lock for a single repository.
+func (is *ImageStore) LockRepo(repo string, lockStart *time.Time) {
+	*lockStart = time.Now()
+
+	is.lock.LockRepo(repo)
+}
+
+// UnlockRepo write-unlock for a single repository.
+func (is *ImageStore) UnlockRepo(repo string, lockStart *time.Time) {
+	is.lock.UnlockRepo(repo)
+
+	lockEnd := time.Now()
+	// includes time spent in acquiring and holding a lock
+	latency := lockEnd.Sub(*lockStart)
+	monitoring.ObserveStorageLockLatency(is.metrics, latency, is.RootDir(), storageConstants.RWLOCK) // histogram
+}
+
+// LockRepoWithSource write-lock for a repository and read-lock for the repository it takes content from.
+func (is *ImageStore) LockRepoWithSource(repo, srcRepo string, lockStart *time.Time) {
+	*lockStart = time.Now()
+
+	is.lock.LockRepoWithSource(repo, srcRepo)
+}
+
+// UnlockRepoWithSource unlocks both repositories locked by LockRepoWithSource.
+func (is *ImageStore) UnlockRepoWithSource(repo, srcRepo string, lockStart *time.Time) {
+	is.lock.UnlockRepoWithSource(repo, srcRepo)
+
+	lockEnd := time.Now()
+	// includes time spent in acquiring and holding a lock
+	latency := lockEnd.Sub(*lockStart)
+	monitoring.ObserveStorageLockLatency(is.metrics, latency, is.RootDir(), storageConstants.RWLOCK) // histogram
+}
+
 func (is *ImageStore) initRepo(name string) error {
 	repoDir := path.Join(is.rootDir, name)
 
@@ -203,8 +383,8 @@ func (is *ImageStore) initRepo(name string) error {
 func (is *ImageStore) InitRepo(name string) error {
 	var lockLatency time.Time
 
-	is.Lock(&lockLatency)
-	defer is.Unlock(&lockLatency)
+	is.LockRepo(name, &lockLatency)
+	defer is.UnlockRepo(name, &lockLatency)
 
 	return is.initRepo(name)
 }
@@ -478,8 +658,8 @@ func (is *ImageStore) GetImageTags(repo string) ([]string, error) {
 		return nil, zerr.ErrRepoNotFound
 	}
 
-	is.RLock(&lockLatency)
-	defer is.RUnlock(&lockLatency)
+	is.RLockRepo(repo, &lockLatency)
+	defer is.RUnlockRepo(repo, &lockLatency)
 
 	index, err := common.GetIndex(is, repo, is.log)
 	if err != nil {
@@ -500,9 +680,9 @@ func (is *ImageStore) GetImageManifest(repo, reference string) ([]byte, godigest
 
 	var err error
 
-	is.RLock(&lockLatency)
+	is.RLockRepo(repo, &lockLatency)
 	defer func() {
-		is.RUnlock(&lockLatency)
+		is.RUnlockRepo(repo, &lockLatency)
 
 		if err == nil {
 			monitoring.IncDownloadCounter(is.metrics, repo)
@@ -552,9 +732,9 @@ func (is *ImageStore) PutImageManifest(repo, reference, mediaType string, //noli
 
 	var err error
 
-	is.Lock(&lockLatency)
+	is.LockRepo(repo, &lockLatency)
 	defer func() {
-		is.Unlock(&lockLatency)
+		is.UnlockRepo(repo, &lockLatency)
 
 		if err == nil {
 			if is.storeDriver.Name() == storageConstants.LocalStorageDriverName {
@@ -576,6 +756,19 @@ func (is *ImageStore) PutImageManifest(repo, reference, mediaType string, //noli
 		refIsDigest = false
 	}
 
+	// create a new descriptor
+	desc := ispec.Descriptor{
+		MediaType: mediaType, Size: int64(len(body)), Digest: mDigest,
+	}
+
+	if !refIsDigest {
+		desc.Annotations = map[string]string{ispec.AnnotationRefName: reference}
+	}
+
+	if err = is.checkPutManifest(repo, reference, desc, body); err != nil {
+		return "", "", err
+	}
+
 	err = common.ValidateManifest(is, repo, reference, mediaType, body, is.compat, is.log)
 	if err != nil {
 		return mDigest, "", err
@@ -586,14 +779,8 @@ func (is *ImageStore) PutImageManifest(repo, reference, mediaType string, //noli
 		return "", "", err
 	}
 
-	// create a new descriptor
-	desc := ispec.Descriptor{
-		MediaType: mediaType, Size: int64(len(body)), Digest: mDigest,
-	}
-
-	if !refIsDigest {
-		desc.Annotations = map[string]string{ispec.AnnotationRefName: reference}
-	}
+	formerIndex := index
+	formerIndex.Manifests = slices.Clone(index.Manifests)
 
 	var subjectDigest godigest.Digest
 
@@ -682,6 +869,8 @@ func (is *ImageStore) PutImageManifest(repo, reference, mediaType string, //noli
 		return "", "", err
 	}
 
+	is.manifestPut(repo, reference, desc, body, formerIndex)
+
 	return mDigest, subjectDigest, nil
 }
 
@@ -694,8 +883,12 @@ func (is *ImageStore) DeleteImageManifest(repo, reference string, detectCollisio
 
 	var lockLatency time.Time
 
-	is.Lock(&lockLatency)
-	defer is.Unlock(&lockLatency)
+	is.LockRepo(repo, &lockLatency)
+	defer is.UnlockRepo(repo, &lockLatency)
+
+	if err := is.checkDeleteManifest(repo, reference); err != nil {
+		return err
+	}
 
 	err := is.deleteImageManifest(repo, reference, detectCollisions)
 	if err != nil {
@@ -1020,8 +1213,22 @@ func (is *ImageStore) FinishBlobUpload(repo, uuid string, body io.Reader, dstDig
 
 	var lockLatency time.Time
 
-	is.Lock(&lockLatency)
-	defer is.Unlock(&lockLatency)
+	is.LockRepo(repo, &lockLatency)
+	defer is.UnlockRepo(repo, &lockLatency)
+
+	if len(is.getWritePolicies()) > 0 {
+		binfo, err := is.storeDriver.Stat(src)
+		if err != nil {
+			is.log.Error().Err(err).Str("blob", src).Msg("failed to stat blob")
+
+			return zerr.ErrUploadNotFound
+		}
+
+		// the upload is left to the caller, as on the other errors
+		if err := is.checkPutBlob(repo, binfo.Size()); err != nil {
+			return err
+		}
+	}
 
 	if is.dedupe && fmt.Sprintf("%v", is.cache) != fmt.Sprintf("%v", nil) {
 		err = is.DedupeBlob(src, dstDigest, repo, dst)
@@ -1100,11 +1307,17 @@ func (is *ImageStore) FullBlobUpload(repo string, body io.Reader, dstDigest godi
 
 	var lockLatency time.Time
 
-	is.Lock(&lockLatency)
-	defer is.Unlock(&lockLatency)
+	is.LockRepo(repo, &lockLatency)
+	defer is.UnlockRepo(repo, &lockLatency)
 
 	dst := is.BlobPath(repo, dstDigest)
 
+	if err := is.checkPutBlob(repo, nbytes); err != nil {
+		_ = is.storeDriver.Delete(src)
+
+		return "", -1, err
+	}
+
 	if is.dedupe && fmt.Sprintf("%v", is.cache) != fmt.Sprintf("%v", nil) {
 		if err := is.DedupeBlob(src, dstDigest, repo, dst); err != nil {
 			is.log.Error().Err(err).Str("src", src).Str("dstDigest", dstDigest.String()).
@@ -1124,7 +1337,52 @@ func (is *ImageStore) FullBlobUpload(repo string, body io.Reader, dstDigest godi
 	return uuid, nbytes, nil
 }
 
+// DedupeBlob moves an uploaded blob to its final destination, linking it to an already existing copy if any.
+// The caller function MUST lock the destination repository from outside.
 func (is *ImageStore) DedupeBlob(src string, dstDigest godigest.Digest, dstRepo string, dst string) error {
+	done, blobSize, err := is.dedupeBlob(src, dstDigest, dst)
+	if err != nil || done {
+		return err
+	}
+
+	// dst already holds the content of the blob, replace it only if it is corrupted,
+	// the descriptors being read without holding the cache lock as the blob readers take it
+	if desc, err := common.GetBlobDescriptorFromRepo(is, dstRepo, dstDigest, is.log); err == nil &&
+		desc.Size != blobSize {
+		is.cacheLock.Lock()
+		defer is.cacheLock.Unlock()
+
+		if err := is.storeDriver.Move(src, dst); err != nil {
+			is.log.Error().Err(err).Str("src", src).Str("dst", dst).Str("component", "dedupe").
+				Msg("failed to rename blob")
+
+			return err
+		}
+
+		is.log.Debug().Str("src", src).Str("component", "dedupe").Msg("remove")
+
+		return nil
+	}
+
+	// remove temp blobupload
+	if err := is.storeDriver.Delete(src); err != nil {
+		is.log.Error().Err(err).Str("src", src).Str("component", "dedupe").
+			Msg("failed to remove blob")
+
+		return err
+	}
+
+	is.log.Debug().Str("src", src).Str("component", "dedupe").Msg("remove")
+
+	return nil
+}
+
+// dedupeBlob moves or links the uploaded blob to dst under the cache lock and returns true once done.
+// If dst already is the cache record of the blob, the upload is left in place and the size of dst is returned.
+func (is *ImageStore) dedupeBlob(src string, dstDigest godigest.Digest, dst string) (bool, int64, error) {
+	is.cacheLock.Lock()
+	defer is.cacheLock.Unlock()
+
 retry:
 	is.log.Debug().Str("src", src).Str("dstDigest", dstDigest.String()).Str("dst", dst).Msg("dedupe begin")
 
@@ -1132,7 +1390,7 @@ retry:
 	if err := inject.Error(err); err != nil && !errors.Is(err, zerr.ErrCacheMiss) {
 		is.log.Error().Err(err).Str("blobPath", dst).Str("component", "dedupe").Msg("failed to lookup blob record")
 
-		return err
+		return false, -1, err
 	}
 
 	if dstRecord == "" {
@@ -1141,7 +1399,7 @@ retry:
 			is.log.Error().Err(err).Str("blobPath", dst).Str("component", "dedupe").
 				Msg("failed to insert blob record")
 
-			return err
+			return false, -1, err
 		}
 
 		// move the blob from uploads to final dest
@@ -1149,79 +1407,66 @@ retry:
 			is.log.Error().Err(err).Str("src", src).Str("dst", dst).Str("component", "dedupe").
 				Msg("failed to rename blob")
 
-			return err
+			return false, -1, err
 		}
 
 		is.log.Debug().Str("src", src).Str("dst", dst).Str("component", "dedupe").Msg("rename")
-	} else {
-		// cache record exists, but due to GC and upgrades from older versions,
-		// disk content and cache records may go out of sync
-		if is.cache.UsesRelativePaths() {
-			dstRecord = path.Join(is.rootDir, dstRecord)
-		}
 
-		blobInfo, err := is.storeDriver.Stat(dstRecord)
-		if err != nil {
-			is.log.Error().Err(err).Str("blobPath", dstRecord).Str("component", "dedupe").Msg("failed to stat")
-			// the actual blob on disk may have been removed by GC, so sync the cache
-			err := is.cache.DeleteBlob(dstDigest, dstRecord)
-			if err = inject.Error(err); err != nil {
-				//nolint:lll
-				is.log.Error().Err(err).Str("dstDigest", dstDigest.String()).Str("dst", dst).
-					Str("component", "dedupe").Msg("failed to delete blob record")
-
-				return err
-			}
+		return true, -1, nil
+	}
 
-			goto retry
-		}
+	// cache record exists, but due to GC and upgrades from older versions,
+	// disk content and cache records may go out of sync
+	if is.cache.UsesRelativePaths() {
+		dstRecord = path.Join(is.rootDir, dstRecord)
+	}
 
-		// prevent overwrite original blob
-		if !is.storeDriver.SameFile(dst, dstRecord) {
-			if err := is.storeDriver.Link(dstRecord, dst); err != nil {
-				is.log.Error().Err(err).Str("blobPath", dstRecord).Str("component", "dedupe").
-					Msg("failed to link blobs")
+	blobInfo, err := is.storeDriver.Stat(dstRecord)
+	if err != nil {
+		is.log.Error().Err(err).Str("blobPath", dstRecord).Str("component", "dedupe").Msg("failed to stat")
+		// the actual blob on disk may have been removed by GC, so sync the cache
+		err := is.cache.DeleteBlob(dstDigest, dstRecord)
+		if err = inject.Error(err); err != nil {
+			//nolint:lll
+			is.log.Error().Err(err).Str("dstDigest", dstDigest.String()).Str("dst", dst).
+				Str("component", "dedupe").Msg("failed to delete blob record")
 
-				return err
-			}
+			return false, -1, err
+		}
 
-			if err := is.cache.PutBlob(dstDigest, dst); err != nil {
-				is.log.Error().Err(err).Str("blobPath", dst).Str("component", "dedupe").
-					Msg("failed to insert blob record")
+		goto retry
+	}
 
-				return err
-			}
-		} else {
-			// if it's same file then it was already uploaded, check if blob is corrupted
-			if desc, err := common.GetBlobDescriptorFromRepo(is, dstRepo, dstDigest, is.log); err == nil {
-				// blob corrupted, replace content
-				if desc.Size != blobInfo.Size() {
-					if err := is.storeDriver.Move(src, dst); err != nil {
-						is.log.Error().Err(err).Str("src", src).Str("dst", dst).Str("component", "dedupe").
-							Msg("failed to rename blob")
+	// prevent overwrite original blob
+	if is.storeDriver.SameFile(dst, dstRecord) {
+		return false, blobInfo.Size(), nil
+	}
 
-						return err
-					}
+	if err := is.storeDriver.Link(dstRecord, dst); err != nil {
+		is.log.Error().Err(err).Str("blobPath", dstRecord).Str("component", "dedupe").
+			Msg("failed to link blobs")
 
-					is.log.Debug().Str("src", src).Str("component", "dedupe").Msg("remove")
+		return false, -1, err
+	}
 
-					return nil
-				}
-			}
-		}
+	if err := is.cache.PutBlob(dstDigest, dst); err != nil {
+		is.log.Error().Err(err).Str("blobPath", dst).Str("component", "dedupe").
+			Msg("failed to insert blob record")
 
-		// remove temp blobupload
-		if err := is.storeDriver.Delete(src); err != nil {
-			is.log.Error().Err(err).Str("src", src).Str("component", "dedupe").
-				Msg("failed to remove blob")
+		return false, -1, err
+	}
 
-			return err
-		}
+	// remove temp blobupload
+	if err := is.storeDriver.Delete(src); err != nil {
+		is.log.Error().Err(err).Str("src", src).Str("component", "dedupe").
+			Msg("failed to remove blob")
 
-		is.log.Debug().Str("src", src).Str("component", "dedupe").Msg("remove")
+		return false, -1, err
 	}
 
-	return nil
+	is.log.Debug().Str("src", src).Str("component", "dedupe").Msg("remove")
+
+	return true, -1, nil
 }
 
 // DeleteBlobUpload deletes an existing blob upload that is currently in progress.
@@ -1303,11 +1548,11 @@ func (is *ImageStore) CheckBlob(repo string, digest godigest.Digest) (bool, int6
 	blobPath := is.BlobPath(repo, digest)
 
 	if is.dedupe && fmt.Sprintf("%v", is.cache) != fmt.Sprintf("%v", nil) {
-		is.Lock(&lockLatency)
-		defer is.Unlock(&lockLatency)
+		is.LockRepo(repo, &lockLatency)
+		defer is.UnlockRepo(repo, &lockLatency)
 	} else {
-		is.RLock(&lockLatency)
-		defer is.RUnlock(&lockLatency)
+		is.RLockRepo(repo, &lockLatency)
+		defer is.RUnlockRepo(repo, &lockLatency)
 	}
 
 	binfo, err := is.storeDriver.Stat(blobPath)
@@ -1329,6 +1574,10 @@ func (is *ImageStore) CheckBlob(repo string, digest godigest.Digest) (bool, int6
 	}
 	// otherwise is a 'deduped' blob (empty file)
 
+	// the content of the cache record may be moved to another repository in the meantime
+	is.cacheLock.Lock()
+	defer is.cacheLock.Unlock()
+
 	// Check blobs in cache
 	dstRecord, err := is.checkCacheBlob(digest)
 	if err != nil {
@@ -1337,6 +1586,21 @@ func (is *ImageStore) CheckBlob(repo string, digest godigest.Digest) (bool, int6
 		return false, -1, zerr.ErrBlobNotFound
 	}
 
+	// linking the deduped blob adds it to the repository, a write the policies must allow
+	if len(is.getWritePolicies()) > 0 {
+		binfo, err := is.storeDriver.Stat(dstRecord)
+		if err != nil {
+			return false, -1, zerr.ErrBlobNotFound
+		}
+
+		if err := is.checkPutBlob(repo, binfo.Size()); err != nil {
+			is.log.Info().Err(err).Str("repository", repo).Str("digest", digest.String()).
+				Msg("deduped blob not added to repository")
+
+			return false, -1, zerr.ErrBlobNotFound
+		}
+	}
+
 	blobSize, err := is.copyBlob(repo, blobPath, dstRecord)
 	if err != nil {
 		return false, -1, zerr.ErrBlobNotFound
@@ -1352,12 +1616,214 @@ func (is *ImageStore) CheckBlob(repo string, digest godigest.Digest) (bool, int6
 	return true, blobSize, nil
 }
 
+/*
+	MountBlob makes a blob of srcRepo available in repo without transferring its content again
+
+When dedupe is enabled, the blob is linked to its cache record like a deduped blob, without copying its content.
+Otherwise, or without a cache record, the blob is staged in an upload of repo, hard linked on local storage
+and copied inside the storage otherwise, then moved in place.
+Both repositories stay locked for the whole operation.
+*/
+func (is *ImageStore) MountBlob(repo, srcRepo string, digest godigest.Digest) (int64, error) {
+	var lockLatency time.Time
+
+	if err := digest.Validate(); err != nil {
+		return -1, err
+	}
+
+	if !zreg.FullNameRegexp.MatchString(srcRepo) {
+		return -1, zerr.ErrInvalidRepositoryName
+	}
+
+	if srcRepo == repo {
+		ok, blobSize, err := is.CheckBlob(repo, digest)
+		if err == nil && !ok {
+			err = zerr.ErrBlobNotFound
+		}
+
+		return blobSize, err
+	}
+
+	if err := is.InitRepo(repo); err != nil {
+		return -1, err
+	}
+
+	is.LockRepoWithSource(repo, srcRepo, &lockLatency)
+	defer is.UnlockRepoWithSource(repo, srcRepo, &lockLatency)
+
+	if is.dedupe && fmt.Sprintf("%v", is.cache) != fmt.Sprintf("%v", nil) {
+		mounted, blobSize, err := is.mountDedupedBlob(repo, srcRepo, digest)
+		if err != nil || mounted {
+			return blobSize, err
+		}
+	}
+
+	uploadPath, blobSize, err := is.stageMountedBlob(repo, srcRepo, digest)
+	if err != nil || uploadPath == "" {
+		return blobSize, err
+	}
+
+	blobPath := is.BlobPath(repo, digest)
+
+	_ = is.storeDriver.EnsureDir(path.Dir(blobPath))
+
+	if is.dedupe && fmt.Sprintf("%v", is.cache) != fmt.Sprintf("%v", nil) {
+		err = is.DedupeBlob(uploadPath, digest, repo, blobPath)
+	} else {
+		err = is.storeDriver.Move(uploadPath, blobPath)
+	}
+
+	if err != nil {
+		is.log.Error().Err(err).Str("src", uploadPath).Str("dst", blobPath).Msg("failed to mount blob")
+
+		_ = is.storeDriver.Delete(uploadPath)
+
+		return -1, err
+	}
+
+	return blobSize, nil
+}
+
+// mountDedupedBlob links the blob of srcRepo to its cache record in repo, like CheckBlob does for deduped blobs,
+// and returns true and the size of the blob once repo has it. It returns false if the blob has no usable
+// cache record, for the caller to stage its content instead.
+func (is *ImageStore) mountDedupedBlob(repo, srcRepo string, digest godigest.Digest) (bool, int64, error) {
+	is.cacheLock.Lock()
+	defer is.cacheLock.Unlock()
+
+	srcInfo, err := is.originalBlobInfo(srcRepo, digest)
+	if err != nil {
+		return false, -1, zerr.ErrBlobNotFound
+	}
+
+	// already present, nothing to mount
+	if binfo, err := is.originalBlobInfo(repo, digest); err == nil && binfo.Size() == srcInfo.Size() {
+		return true, binfo.Size(), nil
+	}
+
+	dstRecord, err := is.checkCacheBlob(digest)
+	if err != nil {
+		return false, -1, nil //nolint: nilerr // the content of the blob is staged instead
+	}
+
+	blobPath := is.BlobPath(repo, digest)
+
+	// the record itself is corrupted, it is replaced by the staged content
+	if is.storeDriver.SameFile(blobPath, dstRecord) {
+		return false, -1, nil
+	}
+
+	if err := is.checkPutBlob(repo, srcInfo.Size()); err != nil {
+		return false, -1, err
+	}
+
+	blobSize, err := is.copyBlob(repo, blobPath, dstRecord)
+	if err != nil {
+		return false, -1, err
+	}
+
+	if err := is.cache.PutBlob(digest, blobPath); err != nil {
+		is.log.Error().Err(err).Str("blobPath", blobPath).Str("component", "dedupe").Msg("failed to insert blob record")
+
+		return false, -1, err
+	}
+
+	return true, blobSize, nil
+}
+
+// stageMountedBlob stages the blob of srcRepo in an upload of repo and returns its path and the size of the blob,
+// or an empty path if repo already has it. The content of a deduped blob may live in a third repository,
+// the cache lock keeping it in place meanwhile.
+func (is *ImageStore) stageMountedBlob(repo, srcRepo string, digest godigest.Digest) (string, int64, error) {
+	is.cacheLock.RLock()
+	defer is.cacheLock.RUnlock()
+
+	srcInfo, err := is.originalBlobInfo(srcRepo, digest)
+	if err != nil {
+		return "", -1, zerr.ErrBlobNotFound
+	}
+
+	// already present, nothing to mount
+	if binfo, err := is.originalBlobInfo(repo, digest); err == nil && binfo.Size() == srcInfo.Size() {
+		return "", binfo.Size(), nil
+	}
+
+	if err := is.checkPutBlob(repo, srcInfo.Size()); err != nil {
+		return "", -1, err
+	}
+
+	uploadPath, err := is.stageBlob(repo, srcInfo.Path())
+	if err != nil {
+		return "", -1, err
+	}
+
+	return uploadPath, srcInfo.Size(), nil
+}
+
+// stageBlob makes the content of srcPath available in a new upload of repo, hard linked on local storage
+// and copied otherwise, so that a partial copy never ends up in the blobs directory.
+func (is *ImageStore) stageBlob(repo, srcPath string) (string, error) {
+	u, err := guuid.NewV4()
+	if err != nil {
+		return "", err
+	}
+
+	uploadPath := is.BlobUploadPath(repo, u.String())
+
+	if is.storeDriver.Name() == storageConstants.LocalStorageDriverName {
+		_ = is.storeDriver.EnsureDir(path.Dir(uploadPath))
+
+		if err := is.storeDriver.Link(srcPath, uploadPath); err != nil {
+			is.log.Error().Err(err).Str("blobPath", srcPath).Str("link", uploadPath).Msg("failed to hard link")
+
+			return "", zerr.ErrBlobNotFound
+		}
+
+		return uploadPath, nil
+	}
+
+	reader, err := is.storeDriver.Reader(srcPath, 0)
+	if err != nil {
+		is.log.Error().Err(err).Str("blob", srcPath).Msg("failed to open blob")
+
+		return "", zerr.ErrBlobNotFound
+	}
+
+	defer reader.Close()
+
+	writer, err := is.storeDriver.Writer(uploadPath, false)
+	if err != nil {
+		is.log.Error().Err(err).Str("blob", uploadPath).Msg("failed to open blob")
+
+		return "", err
+	}
+
+	defer writer.Close()
+
+	if _, err := io.Copy(writer, reader); err != nil {
+		_ = writer.Cancel(context.Background())
+
+		return "", err
+	}
+
+	if err := writer.Commit(context.Background()); err != nil {
+		is.log.Error().Err(err).Str("blob", uploadPath).Msg("failed to commit blob")
+
+		return "", err
+	}
+
+	return uploadPath, nil
+}
+
 // StatBlob verifies if a blob is present inside a repository. The caller function MUST lock from outside.
 func (is *ImageStore) StatBlob(repo string, digest godigest.Digest) (bool, int64, time.Time, error) {
 	if err := digest.Validate(); err != nil {
 		return false, -1, time.Time{}, err
 	}
 
+	is.cacheLock.RLock()
+	defer is.cacheLock.RUnlock()
+
 	binfo, err := is.originalBlobInfo(repo, digest)
 	if err != nil {
 		return false, -1, time.Time{}, err
@@ -1439,8 +1905,11 @@ func (is *ImageStore) GetBlobPartial(repo string, digest godigest.Digest, mediaT
 		return nil, -1, -1, err
 	}
 
-	is.RLock(&lockLatency)
-	defer is.RUnlock(&lockLatency)
+	is.RLockRepo(repo, &lockLatency)
+	defer is.RUnlockRepo(repo, &lockLatency)
+
+	is.cacheLock.RLock()
+	defer is.cacheLock.RUnlock()
 
 	binfo, err := is.originalBlobInfo(repo, digest)
 	if err != nil {
@@ -1516,8 +1985,11 @@ func (is *ImageStore) GetBlob(repo string, digest godigest.Digest, mediaType str
 		return nil, -1, err
 	}
 
-	is.RLock(&lockLatency)
-	defer is.RUnlock(&lockLatency)
+	is.RLockRepo(repo, &lockLatency)
+	defer is.RUnlockRepo(repo, &lockLatency)
+
+	is.cacheLock.RLock()
+	defer is.cacheLock.RUnlock()
 
 	binfo, err := is.originalBlobInfo(repo, digest)
 	if err != nil {
@@ -1542,6 +2014,9 @@ func (is *ImageStore) GetBlobContent(repo string, digest godigest.Digest) ([]byt
 		return []byte{}, err
 	}
 
+	is.cacheLock.RLock()
+	defer is.cacheLock.RUnlock()
+
 	binfo, err := is.originalBlobInfo(repo, digest)
 	if err != nil {
 		return nil, err
@@ -1563,12 +2038,19 @@ func (is *ImageStore) VerifyBlobDigestValue(repo string, digest godigest.Digest)
 		return err
 	}
 
+	is.cacheLock.RLock()
+
 	binfo, err := is.originalBlobInfo(repo, digest)
 	if err != nil {
+		is.cacheLock.RUnlock()
+
 		return err
 	}
 
 	blobReadCloser, err := is.storeDriver.Reader(binfo.Path(), 0)
+
+	is.cacheLock.RUnlock()
+
 	if err != nil {
 		return err
 	}
@@ -1593,8 +2075,8 @@ func (is *ImageStore) GetReferrers(repo string, gdigest godigest.Digest, artifac
 ) (ispec.Index, error) {
 	var lockLatency time.Time
 
-	is.RLock(&lockLatency)
-	defer is.RUnlock(&lockLatency)
+	is.RLockRepo(repo, &lockLatency)
+	defer is.RUnlockRepo(repo, &lockLatency)
 
 	return common.GetReferrers(is, repo, gdigest, artifactTypes, is.log)
 }
@@ -1667,8 +2149,8 @@ func (is *ImageStore) DeleteBlob(repo string, digest godigest.Digest) error {
 		return err
 	}
 
-	is.Lock(&lockLatency)
-	defer is.Unlock(&lockLatency)
+	is.LockRepo(repo, &lockLatency)
+	defer is.UnlockRepo(repo, &lockLatency)
 
 	return is.deleteBlob(repo, digest)
 }
@@ -1744,6 +2226,10 @@ func (is *ImageStore) deleteBlob(repo string, digest godigest.Digest) error {
 	}
 
 	if fmt.Sprintf("%v", is.cache) != fmt.Sprintf("%v", nil) {
+		// the blob content may be moved to another repository
+		is.cacheLock.Lock()
+		defer is.cacheLock.Unlock()
+
 		dstRecord, err := is.cache.GetBlob(digest)
 		if err != nil && !errors.Is(err, zerr.ErrCacheMiss) {
 			is.log.Error().Err(err).Str("blobPath", dstRecord).Str("component", "dedupe").
@@ -0,0 +1,137 @@
+package imagestore
+
+import (
+	"sync"
+)
+
+// ImageStoreLock is a two level lock.
+// The global lock is used by operations spanning all repositories and excludes every repository lock,
+// while repository locks only serialize operations on the same repository.
+// Repository locks are created on first use and reclaimed as soon as they are no longer held or awaited.
+type ImageStoreLock struct {
+	// global lock, repository lock holders hold it in read mode
+	global *sync.RWMutex
+	// protects repoLocks
+	internal  *sync.Mutex
+	repoLocks map[string]*repoLock
+}
+
+type repoLock struct {
+	lock *sync.RWMutex
+	// number of goroutines holding or waiting for this lock
+	refs int
+}
+
+func NewImageStoreLock() *ImageStoreLock {
+	return &ImageStoreLock{
+		global:    &sync.RWMutex{},
+		internal:  &sync.Mutex{},
+		repoLocks: map[string]*repoLock{},
+	}
+}
+
+func (sl *ImageStoreLock) RLock() {
+	sl.global.RLock()
+}
+
+func (sl *ImageStoreLock) RUnlock() {
+	sl.global.RUnlock()
+}
+
+func (sl *ImageStoreLock) Lock() {
+	sl.global.Lock()
+}
+
+func (sl *ImageStoreLock) Unlock() {
+	sl.global.Unlock()
+}
+
+func (sl *ImageStoreLock) RLockRepo(repo string) {
+	sl.global.RLock()
+
+	sl.acquireRepoLock(repo).RLock()
+}
+
+func (sl *ImageStoreLock) RUnlockRepo(repo string) {
+	sl.releaseRepoLock(repo, func(lock *sync.RWMutex) { lock.RUnlock() })
+
+	sl.global.RUnlock()
+}
+
+func (sl *ImageStoreLock) LockRepo(repo string) {
+	sl.global.RLock()
+
+	sl.acquireRepoLock(repo).Lock()
+}
+
+func (sl *ImageStoreLock) UnlockRepo(repo string) {
+	sl.releaseRepoLock(repo, func(lock *sync.RWMutex) { lock.Unlock() })
+
+	sl.global.RUnlock()
+}
+
+// LockRepoWithSource write-locks repo and read-locks srcRepo under a single hold of the global lock.
+// Repository locks are taken in name order, so concurrent operations between the same repositories
+// in opposite directions can't deadlock.
+func (sl *ImageStoreLock) LockRepoWithSource(repo, srcRepo string) {
+	sl.global.RLock()
+
+	if srcRepo < repo {
+		sl.acquireRepoLock(srcRepo).RLock()
+		sl.acquireRepoLock(repo).Lock()
+	} else {
+		sl.acquireRepoLock(repo).Lock()
+		sl.acquireRepoLock(srcRepo).RLock()
+	}
+}
+
+func (sl *ImageStoreLock) UnlockRepoWithSource(repo, srcRepo string) {
+	sl.releaseRepoLock(srcRepo, func(lock *sync.RWMutex) { lock.RUnlock() })
+	sl.releaseRepoLock(repo, func(lock *sync.RWMutex) { lock.Unlock() })
+
+	sl.global.RUnlock()
+}
+
+// repoLocksCount returns the number of repository locks currently allocated.
+func (sl *ImageStoreLock) repoLocksCount() int {
+	sl.internal.Lock()
+	defer sl.internal.Unlock()
+
+	return len(sl.repoLocks)
+}
+
+// acquireRepoLock returns the lock of the given repository, creating it if needed,
+// and registers the caller so the lock is not reclaimed while in use.
+func (sl *ImageStoreLock) acquireRepoLock(repo string) *sync.RWMutex {
+	sl.internal.Lock()
+	defer sl.internal.Unlock()
+
+	rlock, ok := sl.repoLocks[repo]
+	if !ok {
+		rlock = &repoLock{lock: &sync.RWMutex{}}
+		sl.repoLocks[repo] = rlock
+	}
+
+	rlock.refs++
+
+	return rlock.lock
+}
+
+// releaseRepoLock unlocks the lock of the given repository and reclaims it if nobody else uses it.
+func (sl *ImageStoreLock) releaseRepoLock(repo string, unlock func(lock *sync.RWMutex)) {
+	sl.internal.Lock()
+	defer sl.internal.Unlock()
+
+	rlock, ok := sl.repoLocks[repo]
+	if !ok {
+		// unlock of an unlocked repository, same as unlocking an unlocked mutex
+		panic("imagestore: unlock of unlocked repository " + repo)
+	}
+
+	unlock(rlock.lock)
+
+	rlock.refs--
+	if rlock.refs == 0 {
+		delete(sl.repoLocks, repo)
+	}
+}
@@ -0,0 +1,228 @@
+package imagestore
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestImageStoreLock(t *testing.T) {
+	Convey("Repository locks do not block each other", t, func() {
+		storeLock := NewImageStoreLock()
+
+		storeLock.LockRepo("repo1")
+
+		done := make(chan struct{})
+
+		go func() {
+			storeLock.LockRepo("repo2")
+			storeLock.UnlockRepo("repo2")
+
+			storeLock.RLockRepo("repo2")
+			storeLock.RUnlockRepo("repo2")
+
+			close(done)
+		}()
+
+		So(waitFor(done), ShouldBeTrue)
+
+		storeLock.UnlockRepo("repo1")
+		So(storeLock.repoLocksCount(), ShouldEqual, 0)
+	})
+
+	Convey("Repository write-lock excludes other locks on the same repository", t, func() {
+		storeLock := NewImageStoreLock()
+
+		storeLock.LockRepo("repo")
+
+		done := make(chan struct{})
+
+		go func() {
+			storeLock.RLockRepo("repo")
+			storeLock.RUnlockRepo("repo")
+
+			close(done)
+		}()
+
+		So(waitFor(done), ShouldBeFalse)
+		// held by this goroutine and awaited by the other one
+		So(storeLock.repoLocksCount(), ShouldEqual, 1)
+
+		storeLock.UnlockRepo("repo")
+
+		So(waitFor(done), ShouldBeTrue)
+		So(storeLock.repoLocksCount(), ShouldEqual, 0)
+	})
+
+	Convey("Repository read-locks are shared", t, func() {
+		storeLock := NewImageStoreLock()
+
+		storeLock.RLockRepo("repo")
+
+		done := make(chan struct{})
+
+		go func() {
+			storeLock.RLockRepo("repo")
+			storeLock.RUnlockRepo("repo")
+
+			close(done)
+		}()
+
+		So(waitFor(done), ShouldBeTrue)
+
+		storeLock.RUnlockRepo("repo")
+		So(storeLock.repoLocksCount(), ShouldEqual, 0)
+	})
+
+	Convey("Store-wide write-lock excludes repository locks", t, func() {
+		storeLock := NewImageStoreLock()
+
+		storeLock.Lock()
+
+		done := make(chan struct{})
+
+		go func() {
+			storeLock.LockRepo("repo")
+			storeLock.UnlockRepo("repo")
+
+			close(done)
+		}()
+
+		So(waitFor(done), ShouldBeFalse)
+
+		storeLock.Unlock()
+
+		So(waitFor(done), ShouldBeTrue)
+
+		storeLock.RLockRepo("repo")
+
+		locked := make(chan struct{})
+
+		go func() {
+			storeLock.Lock()
+			storeLock.Unlock()
+
+			close(locked)
+		}()
+
+		So(waitFor(locked), ShouldBeFalse)
+
+		storeLock.RUnlockRepo("repo")
+
+		So(waitFor(locked), ShouldBeTrue)
+	})
+
+	Convey("Repository locks are reclaimed under concurrent use", t, func() {
+		storeLock := NewImageStoreLock()
+
+		var wg sync.WaitGroup
+
+		counters := map[string]*int{"repo1": new(int), "repo2": new(int), "repo3": new(int)}
+
+		for range 10 {
+			for repo := range counters {
+				wg.Add(1)
+
+				go func(repo string) {
+					defer wg.Done()
+
+					for range 100 {
+						storeLock.LockRepo(repo)
+						(*counters[repo])++
+						storeLock.UnlockRepo(repo)
+					}
+				}(repo)
+			}
+		}
+
+		wg.Wait()
+
+		for _, counter := range counters {
+			So(*counter, ShouldEqual, 1000)
+		}
+
+		So(storeLock.repoLocksCount(), ShouldEqual, 0)
+	})
+
+	Convey("Locking a repository with its source", t, func() {
+		storeLock := NewImageStoreLock()
+
+		storeLock.LockRepoWithSource("repo1", "repo2")
+
+		// the source stays readable
+		done := make(chan struct{})
+
+		go func() {
+			storeLock.RLockRepo("repo2")
+			storeLock.RUnlockRepo("repo2")
+
+			close(done)
+		}()
+
+		So(waitFor(done), ShouldBeTrue)
+
+		// but can't be written
+		done = make(chan struct{})
+
+		go func() {
+			storeLock.LockRepo("repo2")
+			storeLock.UnlockRepo("repo2")
+
+			close(done)
+		}()
+
+		So(waitFor(done), ShouldBeFalse)
+
+		storeLock.UnlockRepoWithSource("repo1", "repo2")
+		So(waitFor(done), ShouldBeTrue)
+
+		// mounts in opposite directions don't deadlock
+		var wg sync.WaitGroup
+
+		for i := 0; i < 100; i++ {
+			wg.Add(2)
+
+			go func() {
+				defer wg.Done()
+
+				storeLock.LockRepoWithSource("repo1", "repo2")
+				storeLock.UnlockRepoWithSource("repo1", "repo2")
+			}()
+
+			go func() {
+				defer wg.Done()
+
+				storeLock.LockRepoWithSource("repo2", "repo1")
+				storeLock.UnlockRepoWithSource("repo2", "repo1")
+			}()
+		}
+
+		done = make(chan struct{})
+
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+
+		So(waitFor(done), ShouldBeTrue)
+		So(storeLock.repoLocksCount(), ShouldEqual, 0)
+	})
+
+	Convey("Unlocking an unlocked repository panics", t, func() {
+		storeLock := NewImageStoreLock()
+
+		So(func() { storeLock.UnlockRepo("repo") }, ShouldPanic)
+	})
+}
+
+// waitFor returns true if the channel is closed before a short timeout.
+func waitFor(done chan struct{}) bool {
+	select {
+	case <-done:
+		return true
+	case <-time.After(100 * time.Millisecond):
+		return false
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"zotregistry.dev/zot/pkg/scheduler"
 	"zotregistry.dev/zot/pkg/storage"
 	"zotregistry.dev/zot/pkg/storage/cache"
+	storageCommon "zotregistry.dev/zot/pkg/storage/common"
 	storageConstants "zotregistry.dev/zot/pkg/storage/constants"
 	"zotregistry.dev/zot/pkg/storage/gc"
 	"zotregistry.dev/zot/pkg/storage/local"
@@ -2666,6 +2667,75 @@ func TestInitRepo(t *testing.T) {
 	})
 }
 
+func TestWritePolicies(t *testing.T) {
+	Convey("Image store enforces its write policies", t, func() {
+		dir := t.TempDir()
+
+		log := zlog.Logger{Logger: zerolog.New(os.Stdout)}
+		metrics := monitoring.NewMetricsServer(false, log)
+		cacheDriver, _ := storage.Create("boltdb", cache.BoltDBDriverParameters{
+			RootDir:     dir,
+			Name:        "cache",
+			UseRelPaths: true,
+		}, log)
+
+		imgStore := local.NewImageStore(dir, true, true, log, metrics, nil, cacheDriver, nil)
+		storeController := storage.StoreController{DefaultStore: imgStore}
+
+		img1 := CreateRandomImage()
+		img2 := CreateRandomImage()
+
+		err := WriteImageToFileSystem(img1, repoName, tag, storeController)
+		So(err, ShouldBeNil)
+
+		err = WriteImageToFileSystem(img2, repoName, "latest", storeController)
+		So(err, ShouldBeNil)
+
+		immutableTags, err := storageCommon.NewImmutableTags([]config.ImmutableTagsPolicy{
+			{Repositories: []string{repoName}, Patterns: []string{`^\d+\.\d+$`}},
+		})
+		So(err, ShouldBeNil)
+
+		imgStore.SetWritePolicies(immutableTags)
+
+		_, _, err = imgStore.PutImageManifest(repoName, tag, ispec.MediaTypeImageManifest,
+			img2.ManifestDescriptor.Data)
+		So(errors.Is(err, zerr.ErrImmutableTag), ShouldBeTrue)
+
+		err = imgStore.DeleteImageManifest(repoName, img1.DigestStr(), false)
+		So(errors.Is(err, zerr.ErrImmutableTag), ShouldBeTrue)
+
+		// the rejected writes leave the repository unchanged
+		_, digest, _, err := imgStore.GetImageManifest(repoName, tag)
+		So(err, ShouldBeNil)
+		So(digest, ShouldEqual, img1.Digest())
+
+		_, _, err = imgStore.PutImageManifest(repoName, "latest", ispec.MediaTypeImageManifest,
+			img1.ManifestDescriptor.Data)
+		So(err, ShouldBeNil)
+
+		Convey("Views of the store can leave policies out", func() {
+			_, _, err = imgStore.WithoutWritePolicies(immutableTags).PutImageManifest(repoName, tag,
+				ispec.MediaTypeImageManifest, img2.ManifestDescriptor.Data)
+			So(err, ShouldBeNil)
+
+			// the store itself still enforces them
+			err = imgStore.DeleteImageManifest(repoName, tag, false)
+			So(errors.Is(err, zerr.ErrImmutableTag), ShouldBeTrue)
+
+			err = imgStore.WithoutWritePolicies().DeleteImageManifest(repoName, tag, false)
+			So(err, ShouldBeNil)
+		})
+
+		Convey("Policies are replaced", func() {
+			imgStore.SetWritePolicies()
+
+			err = imgStore.DeleteImageManifest(repoName, tag, false)
+			So(err, ShouldBeNil)
+		})
+	})
+}
+
 func TestValidateRepo(t *testing.T) {
 	Convey("Get error when unable to read directory", t, func() {
 		dir := t.TempDir()
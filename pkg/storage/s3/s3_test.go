@@ -12,6 +12,7 @@ import (
 	"os"
 	"path"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -2250,6 +2251,209 @@ func TestNextRepositoryMockStoreDriver(t *testing.T) {
 	})
 }
 
+func TestDedupeMoveMockStoreDriver(t *testing.T) {
+	testDir := t.TempDir()
+
+	Convey("Blob readers wait for the content of a deleted blob to be moved to its next duplicate", t, func() {
+		digest := godigest.FromString("blob")
+		origPath := path.Join(testDir, "repo1", "blobs", digest.Algorithm().String(), digest.Encoded())
+		dupPath := path.Join(testDir, "repo2", "blobs", digest.Algorithm().String(), digest.Encoded())
+
+		var (
+			lock        sync.Mutex
+			origDeleted bool
+			moved       bool
+		)
+
+		moveStarted := make(chan struct{})
+		moveDone := make(chan struct{})
+
+		storeDriver := &StorageDriverMock{
+			StatFn: func(ctx context.Context, path string) (driver.FileInfo, error) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				size := int64(fileInfoSize)
+				if path == dupPath && !moved {
+					size = 0
+				}
+
+				return &FileInfoMock{
+					SizeFn: func() int64 { return size },
+					PathFn: func() string { return path },
+				}, nil
+			},
+			MoveFn: func(ctx context.Context, sourcePath, destPath string) error {
+				close(moveStarted)
+				<-moveDone
+
+				lock.Lock()
+				defer lock.Unlock()
+
+				moved = true
+
+				return nil
+			},
+		}
+
+		cacheDriver := mocks.CacheMock{
+			GetBlobFn: func(digest godigest.Digest) (string, error) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				if origDeleted {
+					return dupPath, nil
+				}
+
+				return origPath, nil
+			},
+			HasBlobFn: func(digest godigest.Digest, path string) bool {
+				return true
+			},
+			DeleteBlobFn: func(digest godigest.Digest, path string) error {
+				lock.Lock()
+				defer lock.Unlock()
+
+				origDeleted = origDeleted || path == origPath
+
+				return nil
+			},
+		}
+
+		imgStore := createMockStorageWithMockCache(testDir, true, storeDriver, cacheDriver)
+
+		go func() {
+			_ = imgStore.DeleteBlob("repo1", digest)
+		}()
+
+		<-moveStarted
+
+		type blobInfo struct {
+			size int64
+			err  error
+		}
+
+		read := make(chan blobInfo)
+
+		go func() {
+			_, size, err := imgStore.GetBlob("repo2", digest, ispec.MediaTypeImageLayer)
+			read <- blobInfo{size, err}
+		}()
+
+		// the reader would otherwise find the empty duplicate, now the cache record of the blob
+		var info blobInfo
+
+		readEarly := false
+
+		select {
+		case info = <-read:
+			readEarly = true
+		case <-time.After(100 * time.Millisecond):
+		}
+
+		So(readEarly, ShouldBeFalse)
+
+		close(moveDone)
+
+		info = <-read
+		So(info.err, ShouldBeNil)
+		So(info.size, ShouldEqual, fileInfoSize)
+	})
+}
+
+func TestMountBlobMockStoreDriver(t *testing.T) {
+	testDir := t.TempDir()
+
+	Convey("Mounting a deduped blob doesn't copy its content", t, func() {
+		digest := godigest.FromString("blob")
+		origPath := path.Join(testDir, "repo1", "blobs", digest.Algorithm().String(), digest.Encoded())
+		dupPath := path.Join(testDir, "repo2", "blobs", digest.Algorithm().String(), digest.Encoded())
+
+		var (
+			lock        sync.Mutex
+			placeholder bool
+			opened      []string
+			recorded    []string
+		)
+
+		storeDriver := &StorageDriverMock{
+			StatFn: func(ctx context.Context, path string) (driver.FileInfo, error) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				size := int64(fileInfoSize)
+
+				if path == dupPath {
+					if !placeholder {
+						return nil, driver.PathNotFoundError{Path: path}
+					}
+
+					size = 0
+				}
+
+				return &FileInfoMock{
+					SizeFn: func() int64 { return size },
+					PathFn: func() string { return path },
+				}, nil
+			},
+			PutContentFn: func(ctx context.Context, path string, content []byte) error {
+				lock.Lock()
+				defer lock.Unlock()
+
+				placeholder = placeholder || (path == dupPath && len(content) == 0)
+
+				return nil
+			},
+			ReaderFn: func(ctx context.Context, path string, offset int64) (io.ReadCloser, error) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				opened = append(opened, path)
+
+				return io.NopCloser(strings.NewReader("")), nil
+			},
+			WriterFn: func(ctx context.Context, path string, isAppend bool) (driver.FileWriter, error) {
+				lock.Lock()
+				defer lock.Unlock()
+
+				opened = append(opened, path)
+
+				return &FileWriterMock{}, nil
+			},
+		}
+
+		cacheDriver := mocks.CacheMock{
+			GetBlobFn: func(digest godigest.Digest) (string, error) {
+				return origPath, nil
+			},
+			PutBlobFn: func(digest godigest.Digest, path string) error {
+				lock.Lock()
+				defer lock.Unlock()
+
+				recorded = append(recorded, path)
+
+				return nil
+			},
+		}
+
+		imgStore := createMockStorageWithMockCache(testDir, true, storeDriver, cacheDriver)
+
+		size, err := imgStore.MountBlob("repo2", "repo1", digest)
+		So(err, ShouldBeNil)
+		So(size, ShouldEqual, fileInfoSize)
+
+		So(placeholder, ShouldBeTrue)
+		So(recorded, ShouldResemble, []string{dupPath})
+		So(opened, ShouldBeEmpty)
+
+		// already mounted
+		size, err = imgStore.MountBlob("repo2", "repo1", digest)
+		So(err, ShouldBeNil)
+		So(size, ShouldEqual, fileInfoSize)
+		So(recorded, ShouldHaveLength, 1)
+	})
+}
+
 func TestRebuildDedupeMockStoreDriver(t *testing.T) {
 	uuid, err := guuid.NewV4()
 	if err != nil {
@@ -49,7 +49,7 @@ func New(config *config.Config, linter common.Lint, metrics monitoring.MetricSer
 	var defaultStore storageTypes.ImageStore
 
 	if config.Storage.StorageDriver == nil {
-		cacheDriver, err := CreateCacheDatabaseDriver(config.Storage.StorageConfig, log)
+		cacheDriver, err := createCacheDriver(config, config.Storage.StorageConfig, log)
 		if err != nil {
 			return storeController, err
 		}
@@ -84,7 +84,7 @@ func New(config *config.Config, linter common.Lint, metrics monitoring.MetricSer
 			rootDir = fmt.Sprintf("%v", config.Storage.StorageDriver["rootdirectory"])
 		}
 
-		cacheDriver, err := CreateCacheDatabaseDriver(config.Storage.StorageConfig, log)
+		cacheDriver, err := createCacheDriver(config, config.Storage.StorageConfig, log)
 		if err != nil {
 			return storeController, err
 		}
@@ -163,7 +163,7 @@ func getSubStore(cfg *config.Config, subPaths map[string]config.StorageConfig,
 			// add it to uniqueSubFiles
 			// Create a new image store and assign it to imgStoreMap
 			if isUnique {
-				cacheDriver, err := CreateCacheDatabaseDriver(storageConfig, log)
+				cacheDriver, err := createCacheDriver(cfg, storageConfig, log)
 				if err != nil {
 					return nil, err
 				}
@@ -199,7 +199,7 @@ func getSubStore(cfg *config.Config, subPaths map[string]config.StorageConfig,
 				rootDir = fmt.Sprintf("%v", cfg.Storage.StorageDriver["rootdirectory"])
 			}
 
-			cacheDriver, err := CreateCacheDatabaseDriver(storageConfig, log)
+			cacheDriver, err := createCacheDriver(cfg, storageConfig, log)
 			if err != nil {
 				log.Error().Err(err).Any("config", storageConfig).
 					Msg("failed to create storage driver")
@@ -261,6 +261,129 @@ func TestGetAllDedupeReposCandidates(t *testing.T) {
 	}
 }
 
+func TestMountBlob(t *testing.T) {
+	for _, testcase := range testCases {
+		testcase := testcase
+		t.Run(testcase.testCaseName, func(t *testing.T) {
+			var imgStore storageTypes.ImageStore
+
+			cacheDir := t.TempDir()
+
+			opts := createObjectStoreOpts{
+				rootDir:     cacheDir,
+				cacheDir:    cacheDir,
+				cacheType:   testcase.cacheType,
+				storageType: testcase.storageType,
+			}
+
+			if testcase.cacheType == storageConstants.RedisDriverName {
+				miniRedis := miniredis.RunT(t)
+				opts.miniRedisAddr = "redis://" + miniRedis.Addr()
+				defer DumpKeys(t, opts.miniRedisAddr)
+			}
+
+			if testcase.storageType == storageConstants.S3StorageDriverName {
+				tskip.SkipS3(t)
+
+				uuid, err := guuid.NewV4()
+				if err != nil {
+					panic(err)
+				}
+
+				testDir := path.Join("/oci-repo-test", uuid.String())
+				opts.rootDir = testDir
+
+				var store storageTypes.Driver
+				store, imgStore, _, _ = createObjectsStore(opts)
+				defer cleanupStorage(store, testDir)
+			} else {
+				_, imgStore, _, _ = createObjectsStore(opts)
+			}
+
+			Convey("Mount blobs across repositories", t, func() {
+				storeController := storage.StoreController{DefaultStore: imgStore}
+
+				image := CreateRandomImage()
+
+				err := WriteImageToFileSystem(image, "source", tag, storeController)
+				So(err, ShouldBeNil)
+
+				layerDigest := image.Manifest.Layers[0].Digest
+
+				size, err := imgStore.MountBlob("target", "source", layerDigest)
+				So(err, ShouldBeNil)
+				So(size, ShouldEqual, len(image.Layers[0]))
+
+				// mounting twice is a no-op
+				size, err = imgStore.MountBlob("target", "source", layerDigest)
+				So(err, ShouldBeNil)
+				So(size, ShouldEqual, len(image.Layers[0]))
+
+				// the mounted blob can be mounted in turn
+				size, err = imgStore.MountBlob("other", "target", layerDigest)
+				So(err, ShouldBeNil)
+				So(size, ShouldEqual, len(image.Layers[0]))
+
+				for _, repo := range []string{"target", "other"} {
+					blobContent, err := imgStore.GetBlobContent(repo, layerDigest)
+					So(err, ShouldBeNil)
+					So(blobContent, ShouldResemble, image.Layers[0])
+				}
+
+				// the source stays readable after the mounted copy is removed
+				err = imgStore.DeleteBlob("target", layerDigest)
+				So(err, ShouldBeNil)
+
+				blobContent, err := imgStore.GetBlobContent("source", layerDigest)
+				So(err, ShouldBeNil)
+				So(blobContent, ShouldResemble, image.Layers[0])
+
+				size, err = imgStore.MountBlob("source", "source", layerDigest)
+				So(err, ShouldBeNil)
+				So(size, ShouldEqual, len(image.Layers[0]))
+
+				_, err = imgStore.MountBlob("target", "source", godigest.FromString("missing"))
+				So(errors.Is(err, zerr.ErrBlobNotFound), ShouldBeTrue)
+
+				_, err = imgStore.MountBlob("target", "missing", layerDigest)
+				So(errors.Is(err, zerr.ErrBlobNotFound), ShouldBeTrue)
+
+				_, err = imgStore.MountBlob("target", "../source", layerDigest)
+				So(errors.Is(err, zerr.ErrInvalidRepositoryName), ShouldBeTrue)
+
+				_, err = imgStore.MountBlob("target", "source", "sha256:")
+				So(err, ShouldNotBeNil)
+			})
+		})
+	}
+
+	Convey("Mount blobs without dedupe", t, func() {
+		dir := t.TempDir()
+
+		log := zlog.Logger{Logger: zerolog.New(os.Stdout)}
+		metrics := monitoring.NewMetricsServer(false, log)
+		imgStore := imagestore.NewImageStore(dir, dir, false, false, log, metrics, nil, local.New(true), nil, nil)
+
+		image := CreateRandomImage()
+
+		err := WriteImageToFileSystem(image, "source", tag, storage.StoreController{DefaultStore: imgStore})
+		So(err, ShouldBeNil)
+
+		layerDigest := image.Manifest.Layers[0].Digest
+
+		size, err := imgStore.MountBlob("target", "source", layerDigest)
+		So(err, ShouldBeNil)
+		So(size, ShouldEqual, len(image.Layers[0]))
+
+		srcInfo, err := os.Stat(imgStore.BlobPath("source", layerDigest))
+		So(err, ShouldBeNil)
+
+		mountInfo, err := os.Stat(imgStore.BlobPath("target", layerDigest))
+		So(err, ShouldBeNil)
+		So(os.SameFile(srcInfo, mountInfo), ShouldBeTrue)
+	})
+}
+
 func TestStorageAPIs(t *testing.T) {
 	for _, testcase := range testCases {
 		testcase := testcase
@@ -20,6 +20,20 @@ type StoreController interface {
 	GetImageSubStores() map[string]ImageStore
 }
 
+// WritePolicy is enforced by the image stores under the lock of the repository being written,
+// the writes it rejects leaving the repository unchanged.
+type WritePolicy interface {
+	// CheckPutManifest is called before index, the current index of repo, is updated to give reference to desc,
+	// body being the content of the manifest. It must not assume the write will happen.
+	CheckPutManifest(repo, reference string, desc ispec.Descriptor, body []byte, index ispec.Index) error
+	// ManifestPut is called once index, the former index of repo, was updated to give reference to desc.
+	ManifestPut(repo, reference string, desc ispec.Descriptor, body []byte, index ispec.Index)
+	// CheckDeleteManifest is called before reference is removed from index, the current index of repo.
+	CheckDeleteManifest(repo, reference string, index ispec.Index) error
+	// CheckPutBlob is called before a blob of the given size is added to repo, uploaded or mounted.
+	CheckPutBlob(repo string, size int64) error
+}
+
 type ImageStore interface { //nolint:interfacebloat
 	Name() string
 	DirExists(d string) bool
@@ -28,6 +42,10 @@ type ImageStore interface { //nolint:interfacebloat
 	RUnlock(*time.Time)
 	Lock(*time.Time)
 	Unlock(*time.Time)
+	RLockRepo(repo string, lockStart *time.Time)
+	RUnlockRepo(repo string, lockStart *time.Time)
+	LockRepo(repo string, lockStart *time.Time)
+	UnlockRepo(repo string, lockStart *time.Time)
 	InitRepo(name string) error
 	ValidateRepo(name string) (bool, error)
 	GetRepositories() ([]string, error)
@@ -51,6 +69,7 @@ type ImageStore interface { //nolint:interfacebloat
 	DeleteBlobUpload(repo, uuid string) error
 	BlobPath(repo string, digest godigest.Digest) string
 	CheckBlob(repo string, digest godigest.Digest) (bool, int64, error)
+	MountBlob(repo, srcRepo string, digest godigest.Digest) (int64, error)
 	StatBlob(repo string, digest godigest.Digest) (bool, int64, time.Time, error)
 	GetBlob(repo string, digest godigest.Digest, mediaType string) (io.ReadCloser, int64, error)
 	GetBlobPartial(repo string, digest godigest.Digest, mediaType string, from, to int64,
@@ -69,6 +88,9 @@ type ImageStore interface { //nolint:interfacebloat
 	PopulateStorageMetrics(interval time.Duration, sch *scheduler.Scheduler)
 	VerifyBlobDigestValue(repo string, digest godigest.Digest) error
 	GetAllDedupeReposCandidates(digest godigest.Digest) ([]string, error)
+	SetWritePolicies(policies ...WritePolicy)
+	WithoutWritePolicies(policies ...WritePolicy) ImageStore
+	WithRequestLogger(ctx context.Context) ImageStore
 }
 
 type Driver interface { //nolint:interfacebloat
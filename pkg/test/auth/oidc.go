@@ -10,7 +10,8 @@ import (
 	"github.com/project-zot/mockoidc"
 )
 
-func MockOIDCRun() (*mockoidc.MockOIDC, error) {
+// MockOIDCRun starts a mock openid provider, the given middlewares wrapping its endpoints.
+func MockOIDCRun(middlewares ...func(http.Handler) http.Handler) (*mockoidc.MockOIDC, error) {
 	// Create a fresh RSA Private Key for token signing
 	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048) //nolint:mnd
 
@@ -36,10 +37,12 @@ func MockOIDCRun() (*mockoidc.MockOIDC, error) {
 		})
 	}
 
-	err := mockServer.AddMiddleware(bearerMiddleware)
-	if err != nil {
-		return mockServer, err
+	for _, middleware := range append([]func(http.Handler) http.Handler{bearerMiddleware}, middlewares...) {
+		if err := mockServer.AddMiddleware(middleware); err != nil {
+			return mockServer, err
+		}
 	}
+
 	// tlsConfig can be nil if you want HTTP
 	return mockServer, mockServer.Start(listener, nil)
 }
@@ -40,6 +40,7 @@ type MockedImageStore struct {
 	DeleteBlobUploadFn     func(repo string, uuid string) error
 	BlobPathFn             func(repo string, digest godigest.Digest) string
 	CheckBlobFn            func(repo string, digest godigest.Digest) (bool, int64, error)
+	MountBlobFn            func(repo, srcRepo string, digest godigest.Digest) (int64, error)
 	StatBlobFn             func(repo string, digest godigest.Digest) (bool, int64, time.Time, error)
 	GetBlobPartialFn       func(repo string, digest godigest.Digest, mediaType string, from, to int64,
 	) (io.ReadCloser, int64, int64, error)
@@ -84,6 +85,18 @@ func (is MockedImageStore) RUnlock(t *time.Time) {
 func (is MockedImageStore) RLock(t *time.Time) {
 }
 
+func (is MockedImageStore) LockRepo(repo string, t *time.Time) {
+}
+
+func (is MockedImageStore) UnlockRepo(repo string, t *time.Time) {
+}
+
+func (is MockedImageStore) RUnlockRepo(repo string, t *time.Time) {
+}
+
+func (is MockedImageStore) RLockRepo(repo string, t *time.Time) {
+}
+
 func (is MockedImageStore) Name() string {
 	if is.NameFn != nil {
 		return is.NameFn()
@@ -313,6 +326,14 @@ func (is MockedImageStore) CheckBlob(repo string, digest godigest.Digest) (bool,
 	return true, 0, nil
 }
 
+func (is MockedImageStore) MountBlob(repo, srcRepo string, digest godigest.Digest) (int64, error) {
+	if is.MountBlobFn != nil {
+		return is.MountBlobFn(repo, srcRepo, digest)
+	}
+
+	return 0, nil
+}
+
 func (is MockedImageStore) StatBlob(repo string, digest godigest.Digest) (bool, int64, time.Time, error) {
 	if is.StatBlobFn != nil {
 		return is.StatBlobFn(repo, digest)
@@ -458,3 +479,14 @@ func (is MockedImageStore) GetAllDedupeReposCandidates(digest godigest.Digest) (
 
 	return []string{}, nil
 }
+
+func (is MockedImageStore) SetWritePolicies(policies ...storageTypes.WritePolicy) {
+}
+
+func (is MockedImageStore) WithoutWritePolicies(policies ...storageTypes.WritePolicy) storageTypes.ImageStore {
+	return is
+}
+
+func (is MockedImageStore) WithRequestLogger(ctx context.Context) storageTypes.ImageStore {
+	return is
+}
@@ -44,6 +44,18 @@ type MetaDBMock struct {
 
 	DeleteUserAPIKeyFn func(ctx context.Context, id string) error
 
+	GetServiceAccountsFn func(ctx context.Context) (map[string]mTypes.UserData, error)
+
+	SetServiceAccountFn func(ctx context.Context, serviceAccount mTypes.ServiceAccount) error
+
+	AddUserSessionFn func(ctx context.Context, session mTypes.UserSession) error
+
+	GetUserSessionsFn func(ctx context.Context) ([]mTypes.UserSession, error)
+
+	UpdateUserSessionLastSeenFn func(ctx context.Context, sessionID, clientIP string) (mTypes.UserSession, error)
+
+	DeleteUserSessionFn func(ctx context.Context, sessionID string) error
+
 	PatchDBFn func() error
 
 	ImageTrustStoreFn func() mTypes.ImageTrustStore
@@ -94,7 +106,9 @@ type MetaDBMock struct {
 
 	FilterImageMetaFn func(ctx context.Context, digests []string) (map[string]mTypes.ImageMeta, error)
 
-	RemoveRepoReferenceFn func(repo, reference string, manifestDigest godigest.Digest) error
+	RemoveRepoReferenceFn func(ctx context.Context, repo, reference string, manifestDigest godigest.Digest) error
+
+	GetTagHistoryFn func(ctx context.Context, repo, tag string) ([]mTypes.TagHistoryEntry, error)
 
 	GetFullImageMetaFn func(ctx context.Context, repo string, tag string) (mTypes.FullImageMeta, error)
 
@@ -279,6 +293,55 @@ func (sdm MetaDBMock) DeleteUserAPIKey(ctx context.Context, id string) error {
 	return nil
 }
 
+func (sdm MetaDBMock) GetServiceAccounts(ctx context.Context) (map[string]mTypes.UserData, error) {
+	if sdm.GetServiceAccountsFn != nil {
+		return sdm.GetServiceAccountsFn(ctx)
+	}
+
+	return map[string]mTypes.UserData{}, nil
+}
+
+func (sdm MetaDBMock) SetServiceAccount(ctx context.Context, serviceAccount mTypes.ServiceAccount) error {
+	if sdm.SetServiceAccountFn != nil {
+		return sdm.SetServiceAccountFn(ctx, serviceAccount)
+	}
+
+	return nil
+}
+
+func (sdm MetaDBMock) AddUserSession(ctx context.Context, session mTypes.UserSession) error {
+	if sdm.AddUserSessionFn != nil {
+		return sdm.AddUserSessionFn(ctx, session)
+	}
+
+	return nil
+}
+
+func (sdm MetaDBMock) GetUserSessions(ctx context.Context) ([]mTypes.UserSession, error) {
+	if sdm.GetUserSessionsFn != nil {
+		return sdm.GetUserSessionsFn(ctx)
+	}
+
+	return []mTypes.UserSession{}, nil
+}
+
+func (sdm MetaDBMock) UpdateUserSessionLastSeen(ctx context.Context, sessionID, clientIP string,
+) (mTypes.UserSession, error) {
+	if sdm.UpdateUserSessionLastSeenFn != nil {
+		return sdm.UpdateUserSessionLastSeenFn(ctx, sessionID, clientIP)
+	}
+
+	return mTypes.UserSession{}, nil
+}
+
+func (sdm MetaDBMock) DeleteUserSession(ctx context.Context, sessionID string) error {
+	if sdm.DeleteUserSessionFn != nil {
+		return sdm.DeleteUserSessionFn(ctx, sessionID)
+	}
+
+	return nil
+}
+
 func (sdm MetaDBMock) SetImageMeta(digest godigest.Digest, imageMeta mTypes.ImageMeta) error {
 	if sdm.SetImageMetaFn != nil {
 		return sdm.SetImageMetaFn(digest, imageMeta)
@@ -437,14 +500,24 @@ func (sdm MetaDBMock) FilterImageMeta(ctx context.Context, digests []string,
 	return map[string]mTypes.ImageMeta{}, nil
 }
 
-func (sdm MetaDBMock) RemoveRepoReference(repo, reference string, manifestDigest godigest.Digest) error {
+func (sdm MetaDBMock) RemoveRepoReference(ctx context.Context, repo, reference string,
+	manifestDigest godigest.Digest,
+) error {
 	if sdm.RemoveRepoReferenceFn != nil {
-		return sdm.RemoveRepoReferenceFn(repo, reference, manifestDigest)
+		return sdm.RemoveRepoReferenceFn(ctx, repo, reference, manifestDigest)
 	}
 
 	return nil
 }
 
+func (sdm MetaDBMock) GetTagHistory(ctx context.Context, repo, tag string) ([]mTypes.TagHistoryEntry, error) {
+	if sdm.GetTagHistoryFn != nil {
+		return sdm.GetTagHistoryFn(ctx, repo, tag)
+	}
+
+	return []mTypes.TagHistoryEntry{}, nil
+}
+
 func (sdm MetaDBMock) GetFullImageMeta(ctx context.Context, repo string, tag string,
 ) (mTypes.FullImageMeta, error) {
 	if sdm.GetFullImageMetaFn != nil {
@@ -0,0 +1,69 @@
+package tracing
+
+import (
+	"context"
+
+	godigest "github.com/opencontainers/go-digest"
+
+	storageTypes "zotregistry.dev/zot/pkg/storage/types"
+)
+
+// cache traces the calls of a cache driver. The image stores call their drivers without a context,
+// so each call starts a trace of its own.
+type cache struct {
+	storageTypes.Cache
+}
+
+// Cache returns a cache driver tracing the calls of driver, nil if driver is nil.
+func Cache(driver storageTypes.Cache) storageTypes.Cache {
+	if driver == nil {
+		return nil
+	}
+
+	return &cache{Cache: driver}
+}
+
+func (c *cache) GetBlob(digest godigest.Digest) (string, error) {
+	_, span := Start(context.Background(), "Cache.GetBlob", CacheDriverKey.String(c.Name()),
+		DigestKey.String(digest.String()))
+	path, err := c.Cache.GetBlob(digest)
+	End(span, err)
+
+	return path, err
+}
+
+func (c *cache) GetAllBlobs(digest godigest.Digest) ([]string, error) {
+	_, span := Start(context.Background(), "Cache.GetAllBlobs", CacheDriverKey.String(c.Name()),
+		DigestKey.String(digest.String()))
+	paths, err := c.Cache.GetAllBlobs(digest)
+	End(span, err)
+
+	return paths, err
+}
+
+func (c *cache) PutBlob(digest godigest.Digest, path string) error {
+	_, span := Start(context.Background(), "Cache.PutBlob", CacheDriverKey.String(c.Name()),
+		DigestKey.String(digest.String()))
+	err := c.Cache.PutBlob(digest, path)
+	End(span, err)
+
+	return err
+}
+
+func (c *cache) HasBlob(digest godigest.Digest, path string) bool {
+	_, span := Start(context.Background(), "Cache.HasBlob", CacheDriverKey.String(c.Name()),
+		DigestKey.String(digest.String()))
+	ok := c.Cache.HasBlob(digest, path)
+	End(span, nil)
+
+	return ok
+}
+
+func (c *cache) DeleteBlob(digest godigest.Digest, path string) error {
+	_, span := Start(context.Background(), "Cache.DeleteBlob", CacheDriverKey.String(c.Name()),
+		DigestKey.String(digest.String()))
+	err := c.Cache.DeleteBlob(digest, path)
+	End(span, err)
+
+	return err
+}
@@ -0,0 +1,229 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"time"
+
+	godigest "github.com/opencontainers/go-digest"
+	ispec "github.com/opencontainers/image-spec/specs-go/v1"
+
+	storageTypes "zotregistry.dev/zot/pkg/storage/types"
+)
+
+// imageStore traces the operations of an image store made while handling a request,
+// as children of the span of the request.
+type imageStore struct {
+	storageTypes.ImageStore
+	ctx context.Context //nolint: containedctx // the image stores do not take a context
+}
+
+// ImageStore returns an image store tracing the content operations of store as children of the span of ctx.
+func ImageStore(ctx context.Context, store storageTypes.ImageStore) storageTypes.ImageStore {
+	return &imageStore{ImageStore: store, ctx: ctx}
+}
+
+func (is *imageStore) InitRepo(name string) error {
+	_, span := Start(is.ctx, "ImageStore.InitRepo", RepositoryKey.String(name))
+	err := is.ImageStore.InitRepo(name)
+	End(span, err)
+
+	return err
+}
+
+func (is *imageStore) GetRepositories() ([]string, error) {
+	_, span := Start(is.ctx, "ImageStore.GetRepositories")
+	repos, err := is.ImageStore.GetRepositories()
+	End(span, err)
+
+	return repos, err
+}
+
+func (is *imageStore) GetNextRepositories(repo string, maxEntries int, fn storageTypes.FilterRepoFunc,
+) ([]string, bool, error) {
+	_, span := Start(is.ctx, "ImageStore.GetNextRepositories", RepositoryKey.String(repo))
+	repos, more, err := is.ImageStore.GetNextRepositories(repo, maxEntries, fn)
+	End(span, err)
+
+	return repos, more, err
+}
+
+func (is *imageStore) GetImageTags(repo string) ([]string, error) {
+	_, span := Start(is.ctx, "ImageStore.GetImageTags", RepositoryKey.String(repo))
+	tags, err := is.ImageStore.GetImageTags(repo)
+	End(span, err)
+
+	return tags, err
+}
+
+func (is *imageStore) GetImageManifest(repo, reference string) ([]byte, godigest.Digest, string, error) {
+	_, span := Start(is.ctx, "ImageStore.GetImageManifest", RepositoryKey.String(repo),
+		ReferenceKey.String(reference))
+	content, digest, mediaType, err := is.ImageStore.GetImageManifest(repo, reference)
+	End(span, err)
+
+	return content, digest, mediaType, err
+}
+
+func (is *imageStore) PutImageManifest(repo, reference, mediaType string, body []byte,
+) (godigest.Digest, godigest.Digest, error) {
+	_, span := Start(is.ctx, "ImageStore.PutImageManifest", RepositoryKey.String(repo),
+		ReferenceKey.String(reference))
+	digest, subjectDigest, err := is.ImageStore.PutImageManifest(repo, reference, mediaType, body)
+	End(span, err)
+
+	return digest, subjectDigest, err
+}
+
+func (is *imageStore) DeleteImageManifest(repo, reference string, detectCollision bool) error {
+	_, span := Start(is.ctx, "ImageStore.DeleteImageManifest", RepositoryKey.String(repo),
+		ReferenceKey.String(reference))
+	err := is.ImageStore.DeleteImageManifest(repo, reference, detectCollision)
+	End(span, err)
+
+	return err
+}
+
+func (is *imageStore) NewBlobUpload(repo string) (string, error) {
+	_, span := Start(is.ctx, "ImageStore.NewBlobUpload", RepositoryKey.String(repo))
+	uuid, err := is.ImageStore.NewBlobUpload(repo)
+	End(span, err)
+
+	return uuid, err
+}
+
+func (is *imageStore) GetBlobUpload(repo, uuid string) (int64, error) {
+	_, span := Start(is.ctx, "ImageStore.GetBlobUpload", RepositoryKey.String(repo), UploadKey.String(uuid))
+	size, err := is.ImageStore.GetBlobUpload(repo, uuid)
+	End(span, err)
+
+	return size, err
+}
+
+func (is *imageStore) PutBlobChunkStreamed(repo, uuid string, body io.Reader) (int64, error) {
+	_, span := Start(is.ctx, "ImageStore.PutBlobChunkStreamed", RepositoryKey.String(repo), UploadKey.String(uuid))
+	size, err := is.ImageStore.PutBlobChunkStreamed(repo, uuid, body)
+	End(span, err)
+
+	return size, err
+}
+
+func (is *imageStore) PutBlobChunk(repo, uuid string, from, to int64, body io.Reader) (int64, error) {
+	_, span := Start(is.ctx, "ImageStore.PutBlobChunk", RepositoryKey.String(repo), UploadKey.String(uuid))
+	size, err := is.ImageStore.PutBlobChunk(repo, uuid, from, to, body)
+	End(span, err)
+
+	return size, err
+}
+
+func (is *imageStore) FinishBlobUpload(repo, uuid string, body io.Reader, digest godigest.Digest) error {
+	_, span := Start(is.ctx, "ImageStore.FinishBlobUpload", RepositoryKey.String(repo), UploadKey.String(uuid),
+		DigestKey.String(digest.String()))
+	err := is.ImageStore.FinishBlobUpload(repo, uuid, body, digest)
+	End(span, err)
+
+	return err
+}
+
+func (is *imageStore) FullBlobUpload(repo string, body io.Reader, digest godigest.Digest) (string, int64, error) {
+	_, span := Start(is.ctx, "ImageStore.FullBlobUpload", RepositoryKey.String(repo),
+		DigestKey.String(digest.String()))
+	uuid, size, err := is.ImageStore.FullBlobUpload(repo, body, digest)
+	End(span, err)
+
+	return uuid, size, err
+}
+
+func (is *imageStore) DeleteBlobUpload(repo, uuid string) error {
+	_, span := Start(is.ctx, "ImageStore.DeleteBlobUpload", RepositoryKey.String(repo), UploadKey.String(uuid))
+	err := is.ImageStore.DeleteBlobUpload(repo, uuid)
+	End(span, err)
+
+	return err
+}
+
+func (is *imageStore) CheckBlob(repo string, digest godigest.Digest) (bool, int64, error) {
+	_, span := Start(is.ctx, "ImageStore.CheckBlob", RepositoryKey.String(repo), DigestKey.String(digest.String()))
+	ok, size, err := is.ImageStore.CheckBlob(repo, digest)
+	End(span, err)
+
+	return ok, size, err
+}
+
+func (is *imageStore) MountBlob(repo, srcRepo string, digest godigest.Digest) (int64, error) {
+	_, span := Start(is.ctx, "ImageStore.MountBlob", RepositoryKey.String(repo), DigestKey.String(digest.String()))
+	size, err := is.ImageStore.MountBlob(repo, srcRepo, digest)
+	End(span, err)
+
+	return size, err
+}
+
+func (is *imageStore) StatBlob(repo string, digest godigest.Digest) (bool, int64, time.Time, error) {
+	_, span := Start(is.ctx, "ImageStore.StatBlob", RepositoryKey.String(repo), DigestKey.String(digest.String()))
+	ok, size, modTime, err := is.ImageStore.StatBlob(repo, digest)
+	End(span, err)
+
+	return ok, size, modTime, err
+}
+
+// GetBlob traces opening the blob, not reading it.
+func (is *imageStore) GetBlob(repo string, digest godigest.Digest, mediaType string) (io.ReadCloser, int64, error) {
+	_, span := Start(is.ctx, "ImageStore.GetBlob", RepositoryKey.String(repo), DigestKey.String(digest.String()))
+	blob, size, err := is.ImageStore.GetBlob(repo, digest, mediaType)
+	End(span, err)
+
+	return blob, size, err
+}
+
+func (is *imageStore) GetBlobPartial(repo string, digest godigest.Digest, mediaType string, from, to int64,
+) (io.ReadCloser, int64, int64, error) {
+	_, span := Start(is.ctx, "ImageStore.GetBlobPartial", RepositoryKey.String(repo),
+		DigestKey.String(digest.String()))
+	blob, size, blobSize, err := is.ImageStore.GetBlobPartial(repo, digest, mediaType, from, to)
+	End(span, err)
+
+	return blob, size, blobSize, err
+}
+
+func (is *imageStore) DeleteBlob(repo string, digest godigest.Digest) error {
+	_, span := Start(is.ctx, "ImageStore.DeleteBlob", RepositoryKey.String(repo), DigestKey.String(digest.String()))
+	err := is.ImageStore.DeleteBlob(repo, digest)
+	End(span, err)
+
+	return err
+}
+
+func (is *imageStore) GetIndexContent(repo string) ([]byte, error) {
+	_, span := Start(is.ctx, "ImageStore.GetIndexContent", RepositoryKey.String(repo))
+	content, err := is.ImageStore.GetIndexContent(repo)
+	End(span, err)
+
+	return content, err
+}
+
+func (is *imageStore) GetBlobContent(repo string, digest godigest.Digest) ([]byte, error) {
+	_, span := Start(is.ctx, "ImageStore.GetBlobContent", RepositoryKey.String(repo),
+		DigestKey.String(digest.String()))
+	content, err := is.ImageStore.GetBlobContent(repo, digest)
+	End(span, err)
+
+	return content, err
+}
+
+func (is *imageStore) GetReferrers(repo string, digest godigest.Digest, artifactTypes []string,
+) (ispec.Index, error) {
+	_, span := Start(is.ctx, "ImageStore.GetReferrers", RepositoryKey.String(repo),
+		DigestKey.String(digest.String()))
+	index, err := is.ImageStore.GetReferrers(repo, digest, artifactTypes)
+	End(span, err)
+
+	return index, err
+}
+
+func (is *imageStore) WithoutWritePolicies(policies ...storageTypes.WritePolicy) storageTypes.ImageStore {
+	return &imageStore{ImageStore: is.ImageStore.WithoutWritePolicies(policies...), ctx: is.ctx}
+}
+
+func (is *imageStore) WithRequestLogger(ctx context.Context) storageTypes.ImageStore {
+	return &imageStore{ImageStore: is.ImageStore.WithRequestLogger(ctx), ctx: is.ctx}
+}
@@ -0,0 +1,289 @@
+package tracing
+
+import (
+	"context"
+
+	godigest "github.com/opencontainers/go-digest"
+
+	mTypes "zotregistry.dev/zot/pkg/meta/types"
+)
+
+// metaDB traces the calls of a MetaDB made with a context, as children of its span.
+// The other calls are not traced, they would start traces of their own.
+type metaDB struct {
+	mTypes.MetaDB
+}
+
+// MetaDB returns a MetaDB tracing the calls of db made with a context.
+func MetaDB(db mTypes.MetaDB) mTypes.MetaDB {
+	return &metaDB{MetaDB: db}
+}
+
+func (db *metaDB) SetRepoReference(ctx context.Context, repo string, reference string,
+	imageMeta mTypes.ImageMeta,
+) error {
+	ctx, span := Start(ctx, "MetaDB.SetRepoReference", RepositoryKey.String(repo), ReferenceKey.String(reference))
+	err := db.MetaDB.SetRepoReference(ctx, repo, reference, imageMeta)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) SearchRepos(ctx context.Context, searchText string) ([]mTypes.RepoMeta, error) {
+	ctx, span := Start(ctx, "MetaDB.SearchRepos")
+	repos, err := db.MetaDB.SearchRepos(ctx, searchText)
+	End(span, err)
+
+	return repos, err
+}
+
+func (db *metaDB) SearchTags(ctx context.Context, searchText string) ([]mTypes.FullImageMeta, error) {
+	ctx, span := Start(ctx, "MetaDB.SearchTags")
+	images, err := db.MetaDB.SearchTags(ctx, searchText)
+	End(span, err)
+
+	return images, err
+}
+
+func (db *metaDB) FilterTags(ctx context.Context, filterRepoTag mTypes.FilterRepoTagFunc,
+	filterFunc mTypes.FilterFunc,
+) ([]mTypes.FullImageMeta, error) {
+	ctx, span := Start(ctx, "MetaDB.FilterTags")
+	images, err := db.MetaDB.FilterTags(ctx, filterRepoTag, filterFunc)
+	End(span, err)
+
+	return images, err
+}
+
+func (db *metaDB) FilterRepos(ctx context.Context, rankName mTypes.FilterRepoNameFunc,
+	filterFunc mTypes.FilterFullRepoFunc,
+) ([]mTypes.RepoMeta, error) {
+	ctx, span := Start(ctx, "MetaDB.FilterRepos")
+	repos, err := db.MetaDB.FilterRepos(ctx, rankName, filterFunc)
+	End(span, err)
+
+	return repos, err
+}
+
+func (db *metaDB) GetRepoMeta(ctx context.Context, repo string) (mTypes.RepoMeta, error) {
+	ctx, span := Start(ctx, "MetaDB.GetRepoMeta", RepositoryKey.String(repo))
+	repoMeta, err := db.MetaDB.GetRepoMeta(ctx, repo)
+	End(span, err)
+
+	return repoMeta, err
+}
+
+func (db *metaDB) GetFullImageMeta(ctx context.Context, repo string, tag string) (mTypes.FullImageMeta, error) {
+	ctx, span := Start(ctx, "MetaDB.GetFullImageMeta", RepositoryKey.String(repo), ReferenceKey.String(tag))
+	imageMeta, err := db.MetaDB.GetFullImageMeta(ctx, repo, tag)
+	End(span, err)
+
+	return imageMeta, err
+}
+
+func (db *metaDB) GetMultipleRepoMeta(ctx context.Context, filter func(repoMeta mTypes.RepoMeta) bool,
+) ([]mTypes.RepoMeta, error) {
+	ctx, span := Start(ctx, "MetaDB.GetMultipleRepoMeta")
+	repos, err := db.MetaDB.GetMultipleRepoMeta(ctx, filter)
+	End(span, err)
+
+	return repos, err
+}
+
+func (db *metaDB) UpdateSignaturesValidity(ctx context.Context, repo string, manifestDigest godigest.Digest) error {
+	ctx, span := Start(ctx, "MetaDB.UpdateSignaturesValidity", RepositoryKey.String(repo),
+		DigestKey.String(manifestDigest.String()))
+	err := db.MetaDB.UpdateSignaturesValidity(ctx, repo, manifestDigest)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) FilterImageMeta(ctx context.Context, digests []string,
+) (map[mTypes.ImageDigest]mTypes.ImageMeta, error) {
+	ctx, span := Start(ctx, "MetaDB.FilterImageMeta")
+	images, err := db.MetaDB.FilterImageMeta(ctx, digests)
+	End(span, err)
+
+	return images, err
+}
+
+func (db *metaDB) RemoveRepoReference(ctx context.Context, repo, reference string,
+	manifestDigest godigest.Digest,
+) error {
+	ctx, span := Start(ctx, "MetaDB.RemoveRepoReference", RepositoryKey.String(repo),
+		ReferenceKey.String(reference), DigestKey.String(manifestDigest.String()))
+	err := db.MetaDB.RemoveRepoReference(ctx, repo, reference, manifestDigest)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) GetTagHistory(ctx context.Context, repo, tag string) ([]mTypes.TagHistoryEntry, error) {
+	ctx, span := Start(ctx, "MetaDB.GetTagHistory", RepositoryKey.String(repo), ReferenceKey.String(tag))
+	history, err := db.MetaDB.GetTagHistory(ctx, repo, tag)
+	End(span, err)
+
+	return history, err
+}
+
+func (db *metaDB) GetStarredRepos(ctx context.Context) ([]string, error) {
+	ctx, span := Start(ctx, "MetaDB.GetStarredRepos")
+	repos, err := db.MetaDB.GetStarredRepos(ctx)
+	End(span, err)
+
+	return repos, err
+}
+
+func (db *metaDB) GetBookmarkedRepos(ctx context.Context) ([]string, error) {
+	ctx, span := Start(ctx, "MetaDB.GetBookmarkedRepos")
+	repos, err := db.MetaDB.GetBookmarkedRepos(ctx)
+	End(span, err)
+
+	return repos, err
+}
+
+func (db *metaDB) ToggleStarRepo(ctx context.Context, reponame string) (mTypes.ToggleState, error) {
+	ctx, span := Start(ctx, "MetaDB.ToggleStarRepo", RepositoryKey.String(reponame))
+	state, err := db.MetaDB.ToggleStarRepo(ctx, reponame)
+	End(span, err)
+
+	return state, err
+}
+
+func (db *metaDB) ToggleBookmarkRepo(ctx context.Context, reponame string) (mTypes.ToggleState, error) {
+	ctx, span := Start(ctx, "MetaDB.ToggleBookmarkRepo", RepositoryKey.String(reponame))
+	state, err := db.MetaDB.ToggleBookmarkRepo(ctx, reponame)
+	End(span, err)
+
+	return state, err
+}
+
+func (db *metaDB) GetUserData(ctx context.Context) (mTypes.UserData, error) {
+	ctx, span := Start(ctx, "MetaDB.GetUserData")
+	userData, err := db.MetaDB.GetUserData(ctx)
+	End(span, err)
+
+	return userData, err
+}
+
+func (db *metaDB) SetUserData(ctx context.Context, userData mTypes.UserData) error {
+	ctx, span := Start(ctx, "MetaDB.SetUserData")
+	err := db.MetaDB.SetUserData(ctx, userData)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) SetUserGroups(ctx context.Context, groups []string) error {
+	ctx, span := Start(ctx, "MetaDB.SetUserGroups")
+	err := db.MetaDB.SetUserGroups(ctx, groups)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) GetUserGroups(ctx context.Context) ([]string, error) {
+	ctx, span := Start(ctx, "MetaDB.GetUserGroups")
+	groups, err := db.MetaDB.GetUserGroups(ctx)
+	End(span, err)
+
+	return groups, err
+}
+
+func (db *metaDB) DeleteUserData(ctx context.Context) error {
+	ctx, span := Start(ctx, "MetaDB.DeleteUserData")
+	err := db.MetaDB.DeleteUserData(ctx)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) GetUserAPIKeys(ctx context.Context) ([]mTypes.APIKeyDetails, error) {
+	ctx, span := Start(ctx, "MetaDB.GetUserAPIKeys")
+	apiKeys, err := db.MetaDB.GetUserAPIKeys(ctx)
+	End(span, err)
+
+	return apiKeys, err
+}
+
+func (db *metaDB) AddUserAPIKey(ctx context.Context, hashedKey string, apiKeyDetails *mTypes.APIKeyDetails) error {
+	ctx, span := Start(ctx, "MetaDB.AddUserAPIKey")
+	err := db.MetaDB.AddUserAPIKey(ctx, hashedKey, apiKeyDetails)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) IsAPIKeyExpired(ctx context.Context, hashedKey string) (bool, error) {
+	ctx, span := Start(ctx, "MetaDB.IsAPIKeyExpired")
+	expired, err := db.MetaDB.IsAPIKeyExpired(ctx, hashedKey)
+	End(span, err)
+
+	return expired, err
+}
+
+func (db *metaDB) UpdateUserAPIKeyLastUsed(ctx context.Context, hashedKey string) error {
+	ctx, span := Start(ctx, "MetaDB.UpdateUserAPIKeyLastUsed")
+	err := db.MetaDB.UpdateUserAPIKeyLastUsed(ctx, hashedKey)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) DeleteUserAPIKey(ctx context.Context, id string) error {
+	ctx, span := Start(ctx, "MetaDB.DeleteUserAPIKey")
+	err := db.MetaDB.DeleteUserAPIKey(ctx, id)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) GetServiceAccounts(ctx context.Context) (map[string]mTypes.UserData, error) {
+	ctx, span := Start(ctx, "MetaDB.GetServiceAccounts")
+	serviceAccounts, err := db.MetaDB.GetServiceAccounts(ctx)
+	End(span, err)
+
+	return serviceAccounts, err
+}
+
+func (db *metaDB) SetServiceAccount(ctx context.Context, serviceAccount mTypes.ServiceAccount) error {
+	ctx, span := Start(ctx, "MetaDB.SetServiceAccount")
+	err := db.MetaDB.SetServiceAccount(ctx, serviceAccount)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) AddUserSession(ctx context.Context, session mTypes.UserSession) error {
+	ctx, span := Start(ctx, "MetaDB.AddUserSession")
+	err := db.MetaDB.AddUserSession(ctx, session)
+	End(span, err)
+
+	return err
+}
+
+func (db *metaDB) GetUserSessions(ctx context.Context) ([]mTypes.UserSession, error) {
+	ctx, span := Start(ctx, "MetaDB.GetUserSessions")
+	sessions, err := db.MetaDB.GetUserSessions(ctx)
+	End(span, err)
+
+	return sessions, err
+}
+
+func (db *metaDB) UpdateUserSessionLastSeen(ctx context.Context, sessionID, clientIP string,
+) (mTypes.UserSession, error) {
+	ctx, span := Start(ctx, "MetaDB.UpdateUserSessionLastSeen")
+	session, err := db.MetaDB.UpdateUserSessionLastSeen(ctx, sessionID, clientIP)
+	End(span, err)
+
+	return session, err
+}
+
+func (db *metaDB) DeleteUserSession(ctx context.Context, sessionID string) error {
+	ctx, span := Start(ctx, "MetaDB.DeleteUserSession")
+	err := db.MetaDB.DeleteUserSession(ctx, sessionID)
+	End(span, err)
+
+	return err
+}
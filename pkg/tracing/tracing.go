@@ -0,0 +1,186 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+)
+
+const (
+	instrumentationName = "zotregistry.dev/zot"
+	defaultServiceName  = "zot"
+	grpcProtocol        = "grpc"
+	httpProtocol        = "http"
+
+	RepositoryKey  = attribute.Key("zot.repository")
+	ReferenceKey   = attribute.Key("zot.reference")
+	DigestKey      = attribute.Key("zot.digest")
+	UploadKey      = attribute.Key("zot.upload")
+	CacheDriverKey = attribute.Key("zot.cache.driver")
+	MemberKey      = attribute.Key("zot.cluster.member")
+)
+
+// NewProvider returns a tracer provider exporting the traces to the collector of a tracing config.
+func NewProvider(ctx context.Context, tracingConfig *config.TracingConfig) (*sdktrace.TracerProvider, error) {
+	exporter, err := newExporter(ctx, tracingConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProviderWithExporter(tracingConfig, exporter), nil
+}
+
+// NewProviderWithExporter returns a tracer provider exporting the traces in batches with exporter.
+func NewProviderWithExporter(tracingConfig *config.TracingConfig, exporter sdktrace.SpanExporter,
+) *sdktrace.TracerProvider {
+	serviceName := tracingConfig.ServiceName
+	if serviceName == "" {
+		serviceName = defaultServiceName
+	}
+
+	attributes := []attribute.KeyValue{semconv.ServiceName(serviceName)}
+	if config.ReleaseTag != "" {
+		attributes = append(attributes, semconv.ServiceVersion(config.ReleaseTag))
+	}
+
+	// the default resource describes the host and the process
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(attributes...))
+	if err != nil {
+		res = resource.NewSchemaless(attributes...)
+	}
+
+	sampler := sdktrace.AlwaysSample()
+	if tracingConfig.SampleRatio > 0 && tracingConfig.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(tracingConfig.SampleRatio)
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+}
+
+func newExporter(ctx context.Context, tracingConfig *config.TracingConfig) (sdktrace.SpanExporter, error) {
+	switch tracingConfig.Protocol {
+	case "", grpcProtocol:
+		options := []otlptracegrpc.Option{
+			otlptracegrpc.WithEndpoint(tracingConfig.Endpoint),
+			otlptracegrpc.WithHeaders(tracingConfig.Headers),
+		}
+
+		if tracingConfig.Insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+
+		return otlptracegrpc.New(ctx, options...)
+	case httpProtocol:
+		options := []otlptracehttp.Option{
+			otlptracehttp.WithEndpoint(tracingConfig.Endpoint),
+			otlptracehttp.WithHeaders(tracingConfig.Headers),
+		}
+
+		if tracingConfig.Insecure {
+			options = append(options, otlptracehttp.WithInsecure())
+		}
+
+		return otlptracehttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("%w: unsupported tracing protocol %s", zerr.ErrBadConfig, tracingConfig.Protocol)
+	}
+}
+
+// Register makes provider trace the whole process, and the trace context propagate
+// from and to the other services with the W3C headers.
+func Register(provider trace.TracerProvider) {
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{},
+		propagation.Baggage{}))
+}
+
+// Start starts a span, child of the span of ctx if any. The span does nothing unless tracing is registered.
+func Start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attributes...))
+}
+
+// End ends a span, recording err if any.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// Handler traces the requests matched by the routes of a router, with spans named after their routes
+// and children of the spans of the clients, if any.
+func Handler() mux.MiddlewareFunc {
+	return otelhttp.NewMiddleware(defaultServiceName, otelhttp.WithSpanNameFormatter(routeName))
+}
+
+func routeName(_ string, request *http.Request) string {
+	if route := mux.CurrentRoute(request); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return request.Method + " " + stripPatterns(template)
+		}
+	}
+
+	return request.Method
+}
+
+// stripPatterns removes the patterns of the variables of a path template, e.g. /v2/{name:[a-z]+}/tags/list
+// becomes /v2/{name}/tags/list.
+func stripPatterns(template string) string {
+	var builder strings.Builder
+
+	depth := 0
+	inPattern := false
+
+	for _, char := range template {
+		switch {
+		case char == '{':
+			depth++
+		case char == '}':
+			depth--
+		case char == ':' && depth == 1 && !inPattern:
+			inPattern = true
+
+			continue
+		}
+
+		if inPattern {
+			if depth > 0 {
+				continue
+			}
+
+			inPattern = false
+		}
+
+		builder.WriteRune(char)
+	}
+
+	return builder.String()
+}
+
+// Transport traces the requests sent with base, propagating their trace context to the servers.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(base)
+}
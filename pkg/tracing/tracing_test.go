@@ -0,0 +1,225 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	godigest "github.com/opencontainers/go-digest"
+	. "github.com/smartystreets/goconvey/convey"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	zerr "zotregistry.dev/zot/errors"
+	"zotregistry.dev/zot/pkg/api/config"
+	mTypes "zotregistry.dev/zot/pkg/meta/types"
+	"zotregistry.dev/zot/pkg/test/mocks"
+	"zotregistry.dev/zot/pkg/tracing"
+)
+
+var errTest = errors.New("test error")
+
+func spanNamed(spans tracetest.SpanStubs, name string) *tracetest.SpanStub {
+	for idx := range spans {
+		if spans[idx].Name == name {
+			return &spans[idx]
+		}
+	}
+
+	return nil
+}
+
+func TestNewProvider(t *testing.T) {
+	Convey("Create tracer providers", t, func() {
+		for _, protocol := range []string{"", "grpc", "http"} {
+			provider, err := tracing.NewProvider(context.Background(), &config.TracingConfig{
+				Endpoint: "127.0.0.1:4317",
+				Protocol: protocol,
+				Insecure: true,
+				Headers:  map[string]string{"authorization": "Bearer secret"},
+			})
+			So(err, ShouldBeNil)
+			So(provider, ShouldNotBeNil)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			_ = provider.Shutdown(ctx)
+		}
+
+		_, err := tracing.NewProvider(context.Background(), &config.TracingConfig{
+			Endpoint: "127.0.0.1:9411",
+			Protocol: "zipkin",
+		})
+		So(errors.Is(err, zerr.ErrBadConfig), ShouldBeTrue)
+	})
+}
+
+func TestTracing(t *testing.T) {
+	Convey("Trace with an in-memory exporter", t, func() {
+		exporter := tracetest.NewInMemoryExporter()
+		provider := tracing.NewProviderWithExporter(&config.TracingConfig{ServiceName: "zot-test"}, exporter)
+		tracing.Register(provider)
+
+		defer func() {
+			_ = provider.Shutdown(context.Background())
+		}()
+
+		flush := func() tracetest.SpanStubs {
+			So(provider.ForceFlush(context.Background()), ShouldBeNil)
+
+			spans := exporter.GetSpans()
+			exporter.Reset()
+
+			return spans
+		}
+
+		Convey("Image store operations are children of the span of the request", func() {
+			ctx, parent := tracing.Start(context.Background(), "request")
+
+			imgStore := tracing.ImageStore(ctx, mocks.MockedImageStore{
+				InitRepoFn: func(name string) error {
+					return nil
+				},
+				GetImageTagsFn: func(repo string) ([]string, error) {
+					return nil, errTest
+				},
+			})
+
+			So(imgStore.InitRepo("alpine"), ShouldBeNil)
+			_, err := imgStore.GetImageTags("alpine")
+			So(err, ShouldEqual, errTest)
+			tracing.End(parent, nil)
+
+			spans := flush()
+			So(len(spans), ShouldEqual, 3)
+
+			initRepo := spanNamed(spans, "ImageStore.InitRepo")
+			So(initRepo, ShouldNotBeNil)
+			So(initRepo.Parent.SpanID(), ShouldEqual, parent.SpanContext().SpanID())
+			So(initRepo.Attributes, ShouldContain, tracing.RepositoryKey.String("alpine"))
+			So(initRepo.Status.Code, ShouldEqual, codes.Unset)
+
+			getImageTags := spanNamed(spans, "ImageStore.GetImageTags")
+			So(getImageTags, ShouldNotBeNil)
+			So(getImageTags.Status.Code, ShouldEqual, codes.Error)
+			So(getImageTags.Status.Description, ShouldEqual, errTest.Error())
+			So(len(getImageTags.Events), ShouldEqual, 1)
+
+			request := spanNamed(spans, "request")
+			So(request.Resource.String(), ShouldContainSubstring, "service.name=zot-test")
+		})
+
+		Convey("MetaDB calls are children of the span of their context", func() {
+			ctx, parent := tracing.Start(context.Background(), "request")
+
+			metaDB := tracing.MetaDB(mocks.MetaDBMock{
+				GetRepoMetaFn: func(ctx context.Context, repo string) (mTypes.RepoMeta, error) {
+					return mTypes.RepoMeta{Name: repo}, nil
+				},
+			})
+
+			repoMeta, err := metaDB.GetRepoMeta(ctx, "alpine")
+			So(err, ShouldBeNil)
+			So(repoMeta.Name, ShouldEqual, "alpine")
+			tracing.End(parent, nil)
+
+			getRepoMeta := spanNamed(flush(), "MetaDB.GetRepoMeta")
+			So(getRepoMeta, ShouldNotBeNil)
+			So(getRepoMeta.Parent.SpanID(), ShouldEqual, parent.SpanContext().SpanID())
+		})
+
+		Convey("Cache calls start traces of their own", func() {
+			So(tracing.Cache(nil), ShouldBeNil)
+
+			cacheDriver := tracing.Cache(&mocks.CacheMock{
+				NameFn: func() string { return "boltdb" },
+				GetBlobFn: func(digest godigest.Digest) (string, error) {
+					return "", errTest
+				},
+			})
+
+			digest := godigest.FromString("blob")
+			_, err := cacheDriver.GetBlob(digest)
+			So(err, ShouldEqual, errTest)
+			So(cacheDriver.Name(), ShouldEqual, "boltdb")
+
+			getBlob := spanNamed(flush(), "Cache.GetBlob")
+			So(getBlob, ShouldNotBeNil)
+			So(getBlob.Parent.IsValid(), ShouldBeFalse)
+			So(getBlob.Attributes, ShouldContain, tracing.CacheDriverKey.String("boltdb"))
+			So(getBlob.Attributes, ShouldContain, tracing.DigestKey.String(digest.String()))
+			So(getBlob.Status.Code, ShouldEqual, codes.Error)
+		})
+
+		Convey("Requests are named after their routes and continue the traces of the clients", func() {
+			var upstreamHeader http.Header
+
+			upstream := httptest.NewServer(http.HandlerFunc(func(response http.ResponseWriter, request *http.Request) {
+				upstreamHeader = request.Header.Clone()
+			}))
+			defer upstream.Close()
+
+			router := mux.NewRouter()
+			router.Use(tracing.Handler())
+			router.HandleFunc("/v2/{name:[a-z]{2,8}}/tags/list", func(response http.ResponseWriter, request *http.Request) {
+				client := &http.Client{Transport: tracing.Transport(http.DefaultTransport)}
+
+				req, err := http.NewRequestWithContext(request.Context(), http.MethodGet, upstream.URL, nil)
+				if err != nil {
+					response.WriteHeader(http.StatusInternalServerError)
+
+					return
+				}
+
+				resp, err := client.Do(req)
+				if err != nil {
+					response.WriteHeader(http.StatusBadGateway)
+
+					return
+				}
+
+				resp.Body.Close()
+			})
+
+			server := httptest.NewServer(router)
+			defer server.Close()
+
+			ctx, client := tracing.Start(context.Background(), "client")
+
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/v2/alpine/tags/list", nil)
+			So(err, ShouldBeNil)
+
+			resp, err := (&http.Client{Transport: tracing.Transport(http.DefaultTransport)}).Do(req)
+			So(err, ShouldBeNil)
+			resp.Body.Close()
+			So(resp.StatusCode, ShouldEqual, http.StatusOK)
+			tracing.End(client, nil)
+
+			spans := flush()
+
+			route := spanNamed(spans, "GET /v2/{name}/tags/list")
+			So(route, ShouldNotBeNil)
+			So(route.SpanKind.String(), ShouldEqual, "server")
+			So(route.SpanContext.TraceID(), ShouldEqual, client.SpanContext().TraceID())
+
+			// the upstream request carries the trace of the route
+			So(upstreamHeader.Get("Traceparent"), ShouldContainSubstring, client.SpanContext().TraceID().String())
+		})
+
+		Convey("Spans are not recorded once the provider is shut down", func() {
+			So(provider.Shutdown(context.Background()), ShouldBeNil)
+
+			_, span := tracing.Start(context.Background(), "after")
+			tracing.End(span, nil)
+
+			So(exporter.GetSpans(), ShouldBeEmpty)
+
+			tracing.Register(sdktrace.NewTracerProvider())
+		})
+	})
+}
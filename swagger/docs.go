@@ -165,42 +165,25 @@ const docTemplate = `{
                 }
             }
         },
-        "/v2/_zot/ext/notation": {
-            "post": {
-                "description": "Upload notation certificates for verifying signatures",
-                "consumes": [
-                    "application/octet-stream"
-                ],
+        "/v2/_zot/ext/mgmt/groups": {
+            "get": {
+                "description": "Get the access control groups of the groups file, available to admins only",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Upload notation certificates for verifying signatures",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "truststore type",
-                        "name": "truststoreType",
-                        "in": "query"
-                    },
-                    {
-                        "description": "Certificate content",
-                        "name": "requestBody",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "type": "string"
-                        }
-                    }
-                ],
+                "summary": "Get the access control groups",
                 "responses": {
                     "200": {
-                        "description": "ok",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "object",
+                            "additionalProperties": {
+                                "$ref": "#/definitions/extensions.GroupPayload"
+                            }
                         }
                     },
-                    "400": {
-                        "description": "bad request\".",
+                    "404": {
+                        "description": "not found",
                         "schema": {
                             "type": "string"
                         }
@@ -214,34 +197,32 @@ const docTemplate = `{
                 }
             }
         },
-        "/v2/_zot/ext/userprefs": {
+        "/v2/_zot/ext/mgmt/groups/{group}": {
             "put": {
-                "description": "Add bookmarks/stars info",
+                "description": "Create or replace a group of the groups file, available to admins only",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Add bookmarks/stars info",
+                "summary": "Set the users of an access control group",
                 "parameters": [
                     {
-                        "enum": [
-                            "toggleBookmark",
-                            "toggleStar"
-                        ],
                         "type": "string",
-                        "description": "specify action",
-                        "name": "action",
-                        "in": "query",
+                        "description": "group name",
+                        "name": "group",
+                        "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "repository name",
-                        "name": "repo",
-                        "in": "query",
-                        "required": true
+                        "description": "users of the group",
+                        "name": "users",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/extensions.GroupPayload"
+                        }
                     }
                 ],
                 "responses": {
@@ -252,13 +233,7 @@ const docTemplate = `{
                         }
                     },
                     "400": {
-                        "description": "bad request\".",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "403": {
-                        "description": "forbidden",
+                        "description": "bad request",
                         "schema": {
                             "type": "string"
                         }
@@ -270,52 +245,28 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "internal server error",
+                        "description": "internal server error\".",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            }
-        },
-        "/v2/{name}/blobs/uploads": {
-            "post": {
-                "description": "Create a new image blob/layer upload",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "summary": "Create image blob/layer upload",
+            },
+            "delete": {
+                "description": "Remove a group from the groups file, available to admins only",
+                "summary": "Delete an access control group",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "repository name",
-                        "name": "name",
+                        "description": "group name",
+                        "name": "group",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "accepted",
-                        "schema": {
-                            "type": "string"
-                        },
-                        "headers": {
-                            "Location": {
-                                "type": "string",
-                                "description": "/v2/{name}/blobs/uploads/{session_id}"
-                            },
-                            "Range": {
-                                "type": "string",
-                                "description": "0-0"
-                            }
-                        }
-                    },
-                    "401": {
-                        "description": "unauthorized",
+                    "200": {
+                        "description": "ok",
                         "schema": {
                             "type": "string"
                         }
@@ -327,7 +278,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "internal server error",
+                        "description": "internal server error\".",
                         "schema": {
                             "type": "string"
                         }
@@ -335,88 +286,92 @@ const docTemplate = `{
                 }
             }
         },
-        "/v2/{name}/blobs/uploads/{session_id}": {
+        "/v2/_zot/ext/mgmt/quotas": {
             "get": {
-                "description": "Get an image's blob/layer upload given a session_id",
+                "description": "Get the live usage of the configured storage quotas, available to admins only",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Get image blob/layer upload",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "repository name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "upload session_id",
-                        "name": "session_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
+                "summary": "Get storage quotas usage",
                 "responses": {
-                    "204": {
-                        "description": "no content",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "404": {
-                        "description": "not found",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/quota.Usage"
+                            }
                         }
                     },
                     "500": {
-                        "description": "internal server error",
+                        "description": "internal server error\".",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Update and finish an image's blob/layer upload given a digest",
+            }
+        },
+        "/v2/_zot/ext/mgmt/rollback": {
+            "post": {
+                "description": "Re-point a tag to an earlier digest from its history, as long as the manifest is still in storage",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update image blob/layer upload",
+                "summary": "Re-point a tag to a manifest it pointed to before",
                 "parameters": [
                     {
                         "type": "string",
                         "description": "repository name",
-                        "name": "name",
-                        "in": "path",
+                        "name": "repo",
+                        "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "upload session_id",
-                        "name": "session_id",
-                        "in": "path",
+                        "description": "tag to re-point",
+                        "name": "tag",
+                        "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "blob/layer digest",
+                        "description": "digest of a manifest the tag pointed to before",
                         "name": "digest",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "override the immutable tags policies, admins only",
+                        "name": "force",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "created",
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/types.TagHistoryEntry"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
@@ -428,43 +383,29 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "internal server error",
+                        "description": "internal server error\".",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            },
-            "delete": {
-                "description": "Delete an image's blob/layer given a digest",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/v2/_zot/ext/mgmt/users": {
+            "get": {
+                "description": "Get the names of the users of the htpasswd file, available to admins only",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete image blob/layer",
-                "parameters": [
-                    {
-                        "type": "string",
-                        "description": "repository name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "upload session_id",
-                        "name": "session_id",
-                        "in": "path",
-                        "required": true
-                    }
-                ],
+                "summary": "Get the htpasswd users",
                 "responses": {
                     "200": {
-                        "description": "ok",
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "type": "array",
+                            "items": {
+                                "type": "string"
+                            }
                         }
                     },
                     "404": {
@@ -474,53 +415,38 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "internal server error",
+                        "description": "internal server error\".",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
             },
-            "patch": {
-                "description": "Resume an image's blob/layer upload given an session_id",
+            "post": {
+                "description": "Add a user to the htpasswd file, available to admins only",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Resume image blob/layer upload",
+                "summary": "Create a htpasswd user",
                 "parameters": [
                     {
-                        "type": "string",
-                        "description": "repository name",
-                        "name": "name",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "upload session_id",
-                        "name": "session_id",
-                        "in": "path",
-                        "required": true
+                        "description": "username and password",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/extensions.UserPayload"
+                        }
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "accepted",
+                    "201": {
+                        "description": "created",
                         "schema": {
                             "type": "string"
-                        },
-                        "headers": {
-                            "Location": {
-                                "type": "string",
-                                "description": "/v2/{name}/blobs/uploads/{session_id}"
-                            },
-                            "Range": {
-                                "type": "string",
-                                "description": "0-128"
-                            }
                         }
                     },
                     "400": {
@@ -535,14 +461,14 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
-                    "416": {
-                        "description": "range not satisfiable",
+                    "409": {
+                        "description": "conflict",
                         "schema": {
                             "type": "string"
                         }
                     },
                     "500": {
-                        "description": "internal server error",
+                        "description": "internal server error\".",
                         "schema": {
                             "type": "string"
                         }
@@ -550,84 +476,218 @@ const docTemplate = `{
                 }
             }
         },
-        "/v2/{name}/blobs/{digest}": {
-            "get": {
-                "description": "Get an image's blob/layer given a digest",
+        "/v2/_zot/ext/mgmt/users/{username}": {
+            "put": {
+                "description": "Replace the password of a user of the htpasswd file, available to admins only",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/vnd.oci.image.layer.v1.tar+gzip"
+                    "application/json"
                 ],
-                "summary": "Get image blob/layer",
+                "summary": "Update the password of a htpasswd user",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "repository name",
-                        "name": "name",
+                        "description": "username",
+                        "name": "username",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "blob/layer digest",
-                        "name": "digest",
-                        "in": "path",
-                        "required": true
-                    }
+                        "description": "new password",
+                        "name": "user",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/extensions.UserPayload"
+                        }
+                    }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "ok",
                         "schema": {
-                            "$ref": "#/definitions/api.ImageManifest"
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error\".",
+                        "schema": {
+                            "type": "string"
                         }
                     }
                 }
             },
             "delete": {
-                "description": "Delete an image's blob/layer given a digest",
+                "description": "Remove a user from the htpasswd file, available to admins only",
+                "summary": "Delete a htpasswd user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "username",
+                        "name": "username",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error\".",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/_zot/ext/notation": {
+            "post": {
+                "description": "Upload notation certificates for verifying signatures",
+                "consumes": [
+                    "application/octet-stream"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Upload notation certificates for verifying signatures",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "truststore type",
+                        "name": "truststoreType",
+                        "in": "query"
+                    },
+                    {
+                        "description": "Certificate content",
+                        "name": "requestBody",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request\".",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error\".",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/_zot/ext/userprefs": {
+            "put": {
+                "description": "Add bookmarks/stars info",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete image blob/layer",
+                "summary": "Add bookmarks/stars info",
                 "parameters": [
                     {
+                        "enum": [
+                            "toggleBookmark",
+                            "toggleStar"
+                        ],
                         "type": "string",
-                        "description": "repository name",
-                        "name": "name",
-                        "in": "path",
+                        "description": "specify action",
+                        "name": "action",
+                        "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "blob/layer digest",
-                        "name": "digest",
-                        "in": "path",
+                        "description": "repository name",
+                        "name": "repo",
+                        "in": "query",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "202": {
-                        "description": "accepted",
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request\".",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
                         "schema": {
                             "type": "string"
                         }
                     }
                 }
-            },
-            "head": {
-                "description": "Check an image's blob/layer given a digest",
+            }
+        },
+        "/v2/{name}/blobs/uploads": {
+            "post": {
+                "description": "Create a new image blob/layer upload",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Check image blob/layer",
+                "summary": "Create image blob/layer upload",
                 "parameters": [
                     {
                         "type": "string",
@@ -638,37 +698,86 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "blob/layer digest",
-                        "name": "digest",
-                        "in": "path",
-                        "required": true
+                        "description": "digest of the blob to mount",
+                        "name": "mount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "repository to mount the blob from",
+                        "name": "from",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "created",
                         "schema": {
-                            "$ref": "#/definitions/api.ImageManifest"
+                            "type": "string"
                         },
                         "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "/v2/{name}/blobs/{digest}"
+                            },
                             "constants.DistContentDigestKey": {
                                 "type": "object"
                             }
                         }
+                    },
+                    "202": {
+                        "description": "accepted",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "/v2/{name}/blobs/uploads/{session_id}"
+                            },
+                            "Range": {
+                                "type": "string",
+                                "description": "0-0"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "denied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             }
         },
-        "/v2/{name}/manifests/{reference}": {
+        "/v2/{name}/blobs/uploads/{session_id}": {
             "get": {
-                "description": "Get an image's manifest given a reference or a digest",
+                "description": "Get an image's blob/layer upload given a session_id",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/vnd.oci.image.manifest.v1+json"
+                    "application/json"
                 ],
-                "summary": "Get image manifest",
+                "summary": "Get image blob/layer upload",
                 "parameters": [
                     {
                         "type": "string",
@@ -679,26 +788,931 @@ const docTemplate = `{
                     },
                     {
                         "type": "string",
-                        "description": "image reference or digest",
-                        "name": "reference",
+                        "description": "upload session_id",
+                        "name": "session_id",
                         "in": "path",
                         "required": true
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "204": {
+                        "description": "no content",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update and finish an image's blob/layer upload given a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update image blob/layer upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "upload session_id",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "blob/layer digest",
+                        "name": "digest",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "created",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "denied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an image's blob/layer given a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete image blob/layer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "upload session_id",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Resume an image's blob/layer upload given an session_id",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Resume image blob/layer upload",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "upload session_id",
+                        "name": "session_id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "accepted",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "Location": {
+                                "type": "string",
+                                "description": "/v2/{name}/blobs/uploads/{session_id}"
+                            },
+                            "Range": {
+                                "type": "string",
+                                "description": "0-128"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "denied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "416": {
+                        "description": "range not satisfiable",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/{name}/blobs/{digest}": {
+            "get": {
+                "description": "Get an image's blob/layer given a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/vnd.oci.image.layer.v1.tar+gzip"
+                ],
+                "summary": "Get image blob/layer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "blob/layer digest",
+                        "name": "digest",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ImageManifest"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an image's blob/layer given a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete image blob/layer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "blob/layer digest",
+                        "name": "digest",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "202": {
+                        "description": "accepted",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Check an image's blob/layer given a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Check image blob/layer",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "blob/layer digest",
+                        "name": "digest",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ImageManifest"
+                        },
+                        "headers": {
+                            "constants.DistContentDigestKey": {
+                                "type": "object"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/{name}/manifests/{reference}": {
+            "get": {
+                "description": "Get an image's manifest given a reference or a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/vnd.oci.image.manifest.v1+json"
+                ],
+                "summary": "Get image manifest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "image reference or digest",
+                        "name": "reference",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ImageManifest"
+                        },
+                        "headers": {
+                            "constants.DistContentDigestKey": {
+                                "type": "object"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Update an image's manifest given a reference or a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Update image manifest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "image reference or digest",
+                        "name": "reference",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "admin override of the immutable tags policies",
+                        "name": "force",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "created",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "denied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Delete an image's manifest given a reference or a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Delete image manifest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "image reference or digest",
+                        "name": "reference",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "admin override of the immutable tags policies",
+                        "name": "force",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "denied",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "head": {
+                "description": "Check an image's manifest given a reference or a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Check image manifest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "image reference or digest",
+                        "name": "reference",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        },
+                        "headers": {
+                            "constants.DistContentDigestKey": {
+                                "type": "object"
+                            }
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error\".",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/{name}/referrers/{digest}": {
+            "get": {
+                "description": "Get referrers given a digest",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/vnd.oci.image.index.v1+json"
+                ],
+                "summary": "Get referrers for a given digest",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "digest",
+                        "name": "digest",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "artifact type",
+                        "name": "artifactType",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ImageIndex"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/v2/{name}/tags/list": {
+            "get": {
+                "description": "List all image tags in a repository",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List image tags",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "repository name",
+                        "name": "name",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "limit entries for pagination",
+                        "name": "n",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "last tag value for pagination",
+                        "name": "last",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/common.ImageTags"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request\".",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/zot/auth/apikey": {
+            "get": {
+                "description": "Get list of all API keys for a logged in user",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Get list of API keys for the current user",
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Can create an api key for a logged in user, based on the provided label and scopes.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create an API key for the current user",
+                "parameters": [
+                    {
+                        "description": "api token id (UUID)",
+                        "name": "id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.APIKeyPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "created",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Revokes one current user API key based on given key ID",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Revokes one current user API key",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "api token id (UUID)",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/zot/auth/lockout": {
+            "delete": {
+                "description": "Lifts the lockout of a user or a client ip after too many authentication failures, admins only",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Clears an authentication lockout",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "locked out username",
+                        "name": "user",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "locked out client ip",
+                        "name": "ip",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "ok",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/zot/auth/logout": {
+            "post": {
+                "description": "Logout by removing current session",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Logout by removing current session",
+                "responses": {
+                    "200": {
+                        "description": "ok\".",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error\".",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/zot/auth/serviceaccounts": {
+            "get": {
+                "description": "List the service accounts with their api keys and when they were last used, admins only",
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "List the service accounts",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceAccountsResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Create an identity which can not log in and only authenticates with its api keys, admins only",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "summary": "Create a service account",
+                "parameters": [
+                    {
+                        "description": "service account",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceAccountPayload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceAccountInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
-                            "$ref": "#/definitions/api.ImageManifest"
-                        },
-                        "headers": {
-                            "constants.DistContentDigestKey": {
-                                "type": "object"
-                            }
+                            "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "not found",
+                    "409": {
+                        "description": "conflict",
                         "schema": {
                             "type": "string"
                         }
@@ -710,41 +1724,39 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "put": {
-                "description": "Update an image's manifest given a reference or a digest",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/zot/auth/serviceaccounts/{name}": {
+            "get": {
+                "description": "Get a service account with its api keys and when they were last used, admins only",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Update image manifest",
+                "summary": "Get a service account",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "repository name",
+                        "description": "service account name",
                         "name": "name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "image reference or digest",
-                        "name": "reference",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "created",
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceAccountInfo"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "400": {
-                        "description": "bad request",
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
@@ -764,28 +1776,18 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Delete an image's manifest given a reference or a digest",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Delete a service account and revoke all its api keys, admins only",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Delete image manifest",
+                "summary": "Delete a service account",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "repository name",
+                        "description": "service account name",
                         "name": "name",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "image reference or digest",
-                        "name": "reference",
-                        "in": "path",
-                        "required": true
                     }
                 ],
                 "responses": {
@@ -794,44 +1796,83 @@ const docTemplate = `{
                         "schema": {
                             "type": "string"
                         }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "internal server error",
+                        "schema": {
+                            "type": "string"
+                        }
                     }
                 }
             },
-            "head": {
-                "description": "Check an image's manifest given a reference or a digest",
+            "patch": {
+                "description": "Update the description and the groups of a service account, or disable it, admins only",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Check image manifest",
+                "summary": "Update a service account",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "repository name",
+                        "description": "service account name",
                         "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "image reference or digest",
-                        "name": "reference",
-                        "in": "path",
-                        "required": true
+                        "description": "changed fields",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceAccountUpdatePayload"
+                        }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "ok",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceAccountInfo"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
-                        },
-                        "headers": {
-                            "constants.DistContentDigestKey": {
-                                "type": "object"
-                            }
                         }
                     },
                     "404": {
@@ -841,7 +1882,7 @@ const docTemplate = `{
                         }
                     },
                     "500": {
-                        "description": "internal server error\".",
+                        "description": "internal server error",
                         "schema": {
                             "type": "string"
                         }
@@ -849,43 +1890,57 @@ const docTemplate = `{
                 }
             }
         },
-        "/v2/{name}/referrers/{digest}": {
-            "get": {
-                "description": "Get referrers given a digest",
+        "/zot/auth/serviceaccounts/{name}/apikey": {
+            "post": {
+                "description": "Create an api key owned by a service account, based on the provided label and scopes, admins only",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
-                    "application/vnd.oci.image.index.v1+json"
+                    "application/json"
                 ],
-                "summary": "Get referrers for a given digest",
+                "summary": "Create an API key for a service account",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "repository name",
+                        "description": "service account name",
                         "name": "name",
                         "in": "path",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "digest",
-                        "name": "digest",
-                        "in": "path",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "artifact type",
-                        "name": "artifactType",
-                        "in": "query"
+                        "description": "api token id (UUID)",
+                        "name": "id",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/api.APIKeyPayload"
+                        }
                     }
                 ],
                 "responses": {
-                    "200": {
-                        "description": "OK",
+                    "201": {
+                        "description": "created",
                         "schema": {
-                            "$ref": "#/definitions/api.ImageIndex"
+                            "type": "string"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "404": {
@@ -901,82 +1956,56 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/v2/{name}/tags/list": {
-            "get": {
-                "description": "List all image tags in a repository",
-                "consumes": [
-                    "application/json"
-                ],
+            },
+            "delete": {
+                "description": "Revokes one API key of a service account based on given key ID, admins only",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "List image tags",
+                "summary": "Revokes one API key of a service account",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "repository name",
+                        "description": "service account name",
                         "name": "name",
                         "in": "path",
                         "required": true
                     },
-                    {
-                        "type": "integer",
-                        "description": "limit entries for pagination",
-                        "name": "n",
-                        "in": "query",
-                        "required": true
-                    },
                     {
                         "type": "string",
-                        "description": "last tag value for pagination",
-                        "name": "last",
+                        "description": "api token id (UUID)",
+                        "name": "id",
                         "in": "query",
                         "required": true
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "OK",
+                        "description": "ok",
                         "schema": {
-                            "$ref": "#/definitions/common.ImageTags"
+                            "type": "string"
                         }
                     },
                     "400": {
-                        "description": "bad request\".",
+                        "description": "bad request",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "404": {
-                        "description": "not found",
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
                             "type": "string"
                         }
-                    }
-                }
-            }
-        },
-        "/zot/auth/apikey": {
-            "get": {
-                "description": "Get list of all API keys for a logged in user",
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
-                "summary": "Get list of API keys for the current user",
-                "responses": {
-                    "200": {
-                        "description": "ok",
+                    },
+                    "403": {
+                        "description": "forbidden",
                         "schema": {
                             "type": "string"
                         }
                     },
-                    "401": {
-                        "description": "unauthorized",
+                    "404": {
+                        "description": "not found",
                         "schema": {
                             "type": "string"
                         }
@@ -988,38 +2017,28 @@ const docTemplate = `{
                         }
                     }
                 }
-            },
-            "post": {
-                "description": "Can create an api key for a logged in user, based on the provided label and scopes.",
-                "consumes": [
-                    "application/json"
-                ],
+            }
+        },
+        "/zot/auth/sessions": {
+            "get": {
+                "description": "Get the active web sessions of the current user, or of any user for admins, with their device, ip and last seen time",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Create an API key for the current user",
+                "summary": "Get the active web sessions",
                 "parameters": [
                     {
-                        "description": "api token id (UUID)",
-                        "name": "id",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/api.APIKeyPayload"
-                        }
+                        "type": "string",
+                        "description": "username, admins only",
+                        "name": "user",
+                        "in": "query"
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "created",
-                        "schema": {
-                            "type": "string"
-                        }
-                    },
-                    "400": {
-                        "description": "bad request",
+                    "200": {
+                        "description": "OK",
                         "schema": {
-                            "type": "string"
+                            "$ref": "#/definitions/api.UserSessionsResponse"
                         }
                     },
                     "401": {
@@ -1028,6 +2047,12 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "internal server error",
                         "schema": {
@@ -1037,21 +2062,24 @@ const docTemplate = `{
                 }
             },
             "delete": {
-                "description": "Revokes one current user API key based on given key ID",
-                "consumes": [
-                    "application/json"
-                ],
+                "description": "Revoke a web session of the current user, or of any user for admins, logging that session out",
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Revokes one current user API key",
+                "summary": "Revoke a web session",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "api token id (UUID)",
+                        "description": "session id",
                         "name": "id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "username, admins only",
+                        "name": "user",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1073,6 +2101,18 @@ const docTemplate = `{
                             "type": "string"
                         }
                     },
+                    "403": {
+                        "description": "forbidden",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "not found",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
                     "500": {
                         "description": "internal server error",
                         "schema": {
@@ -1082,19 +2122,49 @@ const docTemplate = `{
                 }
             }
         },
-        "/zot/auth/logout": {
-            "post": {
-                "description": "Logout by removing current session",
+        "/zot/auth/token": {
+            "get": {
+                "description": "Issue a bearer token granting the authenticated user the requested access allowed by the policies.",
                 "consumes": [
                     "application/json"
                 ],
                 "produces": [
                     "application/json"
                 ],
-                "summary": "Logout by removing current session",
+                "summary": "Get a bearer token",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "service the token is requested for",
+                        "name": "service",
+                        "in": "query"
+                    },
+                    {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        },
+                        "collectionFormat": "multi",
+                        "description": "requested access, formatted as type:name:actions",
+                        "name": "scope",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
-                        "description": "ok\".",
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.TokenResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "bad request",
+                        "schema": {
+                            "$ref": "#/definitions/zotregistry_dev_zot_pkg_api_errors.Error"
+                        }
+                    },
+                    "401": {
+                        "description": "unauthorized",
                         "schema": {
                             "type": "string"
                         }
@@ -1235,6 +2305,143 @@ const docTemplate = `{
                 }
             }
         },
+        "api.ServiceAccountInfo": {
+            "type": "object",
+            "properties": {
+                "apiKeys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/types.APIKeyDetails"
+                    }
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "createdBy": {
+                    "type": "string"
+                },
+                "description": {
+                    "type": "string"
+                },
+                "disabled": {
+                    "type": "boolean"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "lastUsed": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ServiceAccountPayload": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "name": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ServiceAccountUpdatePayload": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "type": "string"
+                },
+                "disabled": {
+                    "type": "boolean"
+                },
+                "groups": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
+        "api.ServiceAccountsResponse": {
+            "type": "object",
+            "properties": {
+                "serviceAccounts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.ServiceAccountInfo"
+                    }
+                }
+            }
+        },
+        "api.TokenResponse": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer"
+                },
+                "issued_at": {
+                    "type": "string"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.UserSessionInfo": {
+            "type": "object",
+            "properties": {
+                "clientIP": {
+                    "type": "string"
+                },
+                "createdAt": {
+                    "type": "string"
+                },
+                "current": {
+                    "type": "boolean"
+                },
+                "expiresAt": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "lastSeen": {
+                    "type": "string"
+                },
+                "provider": {
+                    "type": "string"
+                },
+                "userAgent": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.UserSessionsResponse": {
+            "type": "object",
+            "properties": {
+                "sessions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/api.UserSessionInfo"
+                    }
+                }
+            }
+        },
         "common.ImageTags": {
             "type": "object",
             "properties": {
@@ -1305,6 +2512,17 @@ const docTemplate = `{
                 }
             }
         },
+        "extensions.GroupPayload": {
+            "type": "object",
+            "properties": {
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                }
+            }
+        },
         "extensions.HTPasswd": {
             "type": "object",
             "properties": {
@@ -1357,6 +2575,98 @@ const docTemplate = `{
                 }
             }
         },
+        "extensions.UserPayload": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string"
+                },
+                "username": {
+                    "type": "string"
+                }
+            }
+        },
+        "quota.Usage": {
+            "type": "object",
+            "properties": {
+                "images": {
+                    "type": "integer"
+                },
+                "maxImages": {
+                    "type": "integer"
+                },
+                "maxSize": {
+                    "type": "integer"
+                },
+                "repositories": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "size": {
+                    "type": "integer"
+                }
+            }
+        },
+        "types.APIKeyDetails": {
+            "type": "object",
+            "properties": {
+                "createdAt": {
+                    "type": "string"
+                },
+                "creatorUa": {
+                    "type": "string"
+                },
+                "expirationDate": {
+                    "type": "string"
+                },
+                "generatedBy": {
+                    "type": "string"
+                },
+                "isExpired": {
+                    "type": "boolean"
+                },
+                "label": {
+                    "type": "string"
+                },
+                "lastUsed": {
+                    "type": "string"
+                },
+                "scopes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "uuid": {
+                    "type": "string"
+                }
+            }
+        },
+        "types.TagHistoryEntry": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "type": "string"
+                },
+                "client": {
+                    "type": "string"
+                },
+                "digest": {
+                    "type": "string"
+                },
+                "mediaType": {
+                    "type": "string"
+                },
+                "timestamp": {
+                    "type": "string"
+                },
+                "user": {
+                    "type": "string"
+                }
+            }
+        },
         "v1.Descriptor": {
             "type": "object",
             "properties": {
@@ -1434,6 +2744,23 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "zotregistry_dev_zot_pkg_api_errors.Error": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "detail": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "string"
+                    }
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
         }
     }
 }`